@@ -0,0 +1,89 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package accesslog writes an access log of proxied requests in the nginx/Apache "combined" format, for analysis
+// tools that only ingest that.
+package accesslog
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/handlers"
+	"github.com/pb33f/wiretap/redact"
+)
+
+// Handler logs every request handled by next, a line per request in the combined format. URLs, referers and user
+// agents are redacted, and the user is left out if the Authorization header is.
+func Handler(out io.Writer, next http.Handler, redactor *redact.Redactor) http.Handler {
+	return handlers.CustomLoggingHandler(out, next, func(w io.Writer, params handlers.LogFormatterParams) {
+		_, _ = io.WriteString(w, CombinedLine(params, redactor))
+	})
+}
+
+// CombinedLine renders a request in the combined format, e.g.
+//
+//	127.0.0.1 - dave [10/Oct/2024:13:55:36 -0700] "GET /pets?limit=1 HTTP/1.1" 200 2326 "-" "curl/8.4.0"
+func CombinedLine(params handlers.LogFormatterParams, redactor *redact.Redactor) string {
+	req := params.Request
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	user := "-"
+	if name, _, ok := req.BasicAuth(); ok && name != "" && !redactor.HeaderRedacted("Authorization") {
+		user = name
+	} else if params.URL.User != nil && params.URL.User.Username() != "" {
+		user = params.URL.User.Username()
+	}
+	uri := req.RequestURI
+	if req.ProtoMajor == 2 && req.Method == http.MethodConnect {
+		uri = req.Host
+	}
+	if uri == "" {
+		uri = params.URL.RequestURI()
+	}
+	size := "-"
+	if params.Size > 0 {
+		size = strconv.Itoa(params.Size)
+	}
+
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"\n",
+		host, escape(user),
+		params.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
+		escape(req.Method), escape(redactor.Text(uri)), escape(req.Proto),
+		params.StatusCode, size,
+		escape(orDash(redactor.Text(req.Referer()))),
+		escape(orDash(redactor.Header("User-Agent", req.UserAgent()))))
+}
+
+// escape escapes quotes, backslashes and anything unprintable the way Apache does, so a line can't be broken up.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,50 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package accesslog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/handlers"
+	"github.com/pb33f/wiretap/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombinedLine(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/pets?limit=1&token=abc", nil)
+	req.RemoteAddr = "10.0.0.1:51234"
+	req.SetBasicAuth("dave", "s3cr3t")
+	req.Header.Set("Referer", "http://localhost/start")
+	req.Header.Set("User-Agent", `curl "quoted"`)
+	stamp := time.Date(2024, time.October, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600))
+
+	params := handlers.LogFormatterParams{Request: req, URL: *req.URL, TimeStamp: stamp, StatusCode: 200, Size: 2326}
+	assert.Equal(t, `10.0.0.1 - dave [10/Oct/2024:13:55:36 -0700] "GET /pets?limit=1&token=abc HTTP/1.1" 200 2326 "http://localhost/start" "curl \"quoted\""`+"\n",
+		CombinedLine(params, nil))
+
+	r, err := redact.New([]string{"Authorization"}, nil, []string{`token=([^&]+)`})
+	assert.NoError(t, err)
+	params.Size = 0
+	assert.Equal(t, `10.0.0.1 - - [10/Oct/2024:13:55:36 -0700] "GET /pets?limit=1&token=[REDACTED] HTTP/1.1" 200 - "http://localhost/start" "curl \"quoted\""`+"\n",
+		CombinedLine(params, r))
+
+	assert.Equal(t, `line\x0abreak`, escape("line\nbreak"))
+}
+
+func TestHandler(t *testing.T) {
+	var out bytes.Buffer
+	handler := Handler(&out, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/pets", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Regexp(t, `^192\.0\.2\.1 - - \[.+\] "POST /pets HTTP/1\.1" 201 5 "-" "-"\n$`, out.String())
+}
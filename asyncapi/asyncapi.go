@@ -0,0 +1,355 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package asyncapi reads the channels and messages of an AsyncAPI (2.x or 3.0) document, so websocket channels
+// can be mocked (and validated) the same way OpenAPI operations are.
+package asyncapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is an AsyncAPI document, reduced to what wiretap needs: channels and the messages sent over them.
+type Document struct {
+	Version  string
+	Title    string
+	Channels []*Channel
+}
+
+// Channel is an AsyncAPI channel. ServerMessages are sent by the application to its clients, ClientMessages are
+// sent by clients to the application.
+type Channel struct {
+	Name           string
+	Address        string
+	ServerMessages []*Message
+	ClientMessages []*Message
+}
+
+// Message is a message that can be sent over a channel.
+type Message struct {
+	Name        string
+	ContentType string
+	Payload     *base.Schema
+	Examples    [][]byte
+}
+
+// Parse reads an AsyncAPI document. Payload schemas are built with libopenapi, so they can be rendered and
+// validated like OpenAPI schemas.
+func Parse(specBytes []byte) (*Document, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(specBytes, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("AsyncAPI document is empty")
+	}
+	p := &parser{root: root.Content[0], schemas: make(map[string]*yaml.Node)}
+
+	doc := &Document{Version: scalar(child(p.root, "asyncapi"))}
+	if doc.Version == "" {
+		return nil, fmt.Errorf("not an AsyncAPI document, there is no 'asyncapi' version")
+	}
+	doc.Title = scalar(child(child(p.root, "info"), "title"))
+
+	if strings.HasPrefix(doc.Version, "2.") {
+		doc.Channels = p.parseV2()
+	} else {
+		doc.Channels = p.parseV3()
+	}
+	if err := p.buildSchemas(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// FindChannel returns the channel with an address that matches a path, nil if there isn't one. Parameters in
+// the address (e.g. `prices/{symbol}`) match any path segment.
+func (d *Document) FindChannel(path string) *Channel {
+	if d == nil {
+		return nil
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, channel := range d.Channels {
+		if matchAddress(strings.Split(strings.Trim(channel.Address, "/"), "/"), segments) {
+			return channel
+		}
+	}
+	return nil
+}
+
+func matchAddress(address, segments []string) bool {
+	if len(address) != len(segments) {
+		return false
+	}
+	for i, segment := range address {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") && segments[i] != "" {
+			continue
+		}
+		if segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type parser struct {
+	root     *yaml.Node
+	schemas  map[string]*yaml.Node
+	messages []*pendingMessage
+}
+
+// pendingMessage is a message waiting for its payload schema to be built.
+type pendingMessage struct {
+	message *Message
+	schema  string
+}
+
+// parseV2 reads AsyncAPI 2.x channels, where the 'subscribe' operation describes messages the application sends,
+// and 'publish' describes the messages it receives.
+func (p *parser) parseV2() []*Channel {
+	var channels []*Channel
+	forEach(child(p.root, "channels"), func(name string, node *yaml.Node) {
+		node = p.resolve(node)
+		channel := &Channel{Name: name, Address: name}
+		channel.ServerMessages = p.operationMessages(child(node, "subscribe"))
+		channel.ClientMessages = p.operationMessages(child(node, "publish"))
+		channels = append(channels, channel)
+	})
+	return channels
+}
+
+func (p *parser) operationMessages(operation *yaml.Node) []*Message {
+	message := child(p.resolve(operation), "message")
+	if message == nil {
+		return nil
+	}
+	if oneOf := child(p.resolve(message), "oneOf"); oneOf != nil {
+		var messages []*Message
+		for _, m := range oneOf.Content {
+			messages = append(messages, p.parseMessage("", m))
+		}
+		return messages
+	}
+	return []*Message{p.parseMessage("", message)}
+}
+
+// parseV3 reads AsyncAPI 3.0 channels, operations with a 'send' action describe messages the application sends,
+// operations with a 'receive' action describe the messages it receives.
+func (p *parser) parseV3() []*Channel {
+	var channels []*Channel
+	byRef := make(map[string]*Channel)
+	channelMessages := make(map[*Channel]map[string]*Message)
+	forEach(child(p.root, "channels"), func(name string, node *yaml.Node) {
+		node = p.resolve(node)
+		address := name
+		if a := child(node, "address"); a != nil && a.Tag != "!!null" {
+			address = a.Value
+		}
+		channel := &Channel{Name: name, Address: address}
+		messages := make(map[string]*Message)
+		forEach(child(node, "messages"), func(key string, m *yaml.Node) {
+			messages[key] = p.parseMessage(key, m)
+		})
+		channelMessages[channel] = messages
+		byRef["#/channels/"+escapePointer(name)] = channel
+		channels = append(channels, channel)
+	})
+
+	forEach(child(p.root, "operations"), func(_ string, node *yaml.Node) {
+		node = p.resolve(node)
+		channel := byRef[ref(child(node, "channel"))]
+		if channel == nil {
+			return
+		}
+		var messages []*Message
+		if list := child(node, "messages"); list != nil && len(list.Content) > 0 {
+			for _, m := range list.Content {
+				r := ref(m)
+				key := r[strings.LastIndex(r, "/")+1:]
+				if message := channelMessages[channel][unescapePointer(key)]; message != nil {
+					messages = append(messages, message)
+				}
+			}
+		} else {
+			messages = sortedMessages(channelMessages[channel])
+		}
+		switch scalar(child(node, "action")) {
+		case "send":
+			channel.ServerMessages = append(channel.ServerMessages, messages...)
+		case "receive":
+			channel.ClientMessages = append(channel.ClientMessages, messages...)
+		}
+	})
+	return channels
+}
+
+func (p *parser) parseMessage(name string, node *yaml.Node) *Message {
+	if r := ref(node); r != "" && name == "" {
+		name = unescapePointer(r[strings.LastIndex(r, "/")+1:])
+	}
+	node = p.resolve(node)
+	message := &Message{Name: name, ContentType: scalar(child(node, "contentType"))}
+	if n := scalar(child(node, "name")); n != "" {
+		message.Name = n
+	}
+	if message.ContentType == "" {
+		message.ContentType = scalar(child(p.root, "defaultContentType"))
+	}
+
+	if examples := child(node, "examples"); examples != nil {
+		for _, example := range examples.Content {
+			if payload := child(p.resolve(example), "payload"); payload != nil {
+				if b, err := nodeJSON(payload); err == nil {
+					message.Examples = append(message.Examples, b)
+				}
+			}
+		}
+	}
+
+	payload := p.resolve(child(node, "payload"))
+	if payload != nil && child(payload, "schemaFormat") != nil {
+		payload = p.resolve(child(payload, "schema")) // a multi-format schema (3.0)
+	}
+	if payload != nil {
+		key := "wiretap-message-" + strconv.Itoa(len(p.messages))
+		p.schemas[key] = payload
+		p.messages = append(p.messages, &pendingMessage{message: message, schema: key})
+	}
+	return message
+}
+
+// buildSchemas builds every payload schema with libopenapi, by wrapping them (and the schema components of the
+// AsyncAPI document) into an OpenAPI document. References to schema components resolve, because both
+// specifications keep them in the same place.
+func (p *parser) buildSchemas() error {
+	if len(p.messages) == 0 {
+		return nil
+	}
+	schemas := &yaml.Node{Kind: yaml.MappingNode}
+	forEach(child(child(p.root, "components"), "schemas"), func(name string, node *yaml.Node) {
+		schemas.Content = append(schemas.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, node)
+	})
+	keys := make([]string, 0, len(p.schemas))
+	for key := range p.schemas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		schemas.Content = append(schemas.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, p.schemas[key])
+	}
+
+	openapi := map[string]any{
+		"openapi":    "3.1.0",
+		"info":       map[string]any{"title": "AsyncAPI payloads", "version": "1.0.0"},
+		"paths":      map[string]any{},
+		"components": map[string]any{"schemas": schemas},
+	}
+	b, err := yaml.Marshal(openapi)
+	if err != nil {
+		return err
+	}
+	document, err := libopenapi.NewDocument(b)
+	if err != nil {
+		return err
+	}
+	model, errs := document.BuildV3Model()
+	if model == nil {
+		return fmt.Errorf("unable to build AsyncAPI payload schemas: %v", errs)
+	}
+	for _, pending := range p.messages {
+		if proxy := model.Model.Components.Schemas.GetOrZero(pending.schema); proxy != nil {
+			pending.message.Payload = proxy.Schema()
+		}
+	}
+	return nil
+}
+
+// resolve follows a local reference ($ref: '#/...'), any other node is returned as is.
+func (p *parser) resolve(node *yaml.Node) *yaml.Node {
+	for i := 0; i < 10 && node != nil; i++ {
+		r := ref(node)
+		if !strings.HasPrefix(r, "#/") {
+			return node
+		}
+		target := p.root
+		for _, token := range strings.Split(strings.TrimPrefix(r, "#/"), "/") {
+			target = child(target, unescapePointer(token))
+		}
+		if target == nil {
+			return node
+		}
+		node = target
+	}
+	return node
+}
+
+func child(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func forEach(node *yaml.Node, fn func(key string, value *yaml.Node)) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fn(node.Content[i].Value, node.Content[i+1])
+	}
+}
+
+func scalar(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+func ref(node *yaml.Node) string {
+	return scalar(child(node, "$ref"))
+}
+
+func sortedMessages(messages map[string]*Message) []*Message {
+	keys := make([]string, 0, len(messages))
+	for key := range messages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sorted := make([]*Message, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, messages[key])
+	}
+	return sorted
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func unescapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// nodeJSON converts a YAML node (an example) into JSON.
+func nodeJSON(node *yaml.Node) ([]byte, error) {
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
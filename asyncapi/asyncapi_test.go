@@ -0,0 +1,178 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package asyncapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_V2(t *testing.T) {
+	spec := `asyncapi: 2.6.0
+info:
+  title: Prices
+  version: 1.0.0
+defaultContentType: application/json
+channels:
+  prices/{symbol}:
+    subscribe:
+      message:
+        $ref: '#/components/messages/Price'
+    publish:
+      message:
+        name: Subscribe
+        payload:
+          type: object
+          properties:
+            action:
+              type: string
+components:
+  messages:
+    Price:
+      payload:
+        $ref: '#/components/schemas/Price'
+      examples:
+        - payload:
+            symbol: ACME
+            price: 12.5
+  schemas:
+    Price:
+      type: object
+      required: [symbol, price]
+      properties:
+        symbol:
+          type: string
+        price:
+          type: number`
+
+	doc, err := Parse([]byte(spec))
+	require.NoError(t, err)
+	assert.Equal(t, "Prices", doc.Title)
+	require.Len(t, doc.Channels, 1)
+
+	channel := doc.Channels[0]
+	require.Len(t, channel.ServerMessages, 1)
+	require.Len(t, channel.ClientMessages, 1)
+
+	price := channel.ServerMessages[0]
+	assert.Equal(t, "Price", price.Name)
+	assert.Equal(t, "application/json", price.ContentType)
+	require.NotNil(t, price.Payload)
+	assert.Equal(t, []string{"symbol", "price"}, price.Payload.Required)
+	require.Len(t, price.Examples, 1)
+	assert.JSONEq(t, `{"symbol":"ACME","price":12.5}`, string(price.Examples[0]))
+
+	assert.Equal(t, "Subscribe", channel.ClientMessages[0].Name)
+	assert.NotNil(t, channel.ClientMessages[0].Payload)
+}
+
+func TestParse_V3(t *testing.T) {
+	spec := `asyncapi: 3.0.0
+info:
+  title: Chat
+  version: 1.0.0
+channels:
+  room:
+    address: /rooms/{roomId}
+    messages:
+      chat:
+        payload:
+          type: object
+          properties:
+            text:
+              type: string
+      typing:
+        payload:
+          type: object
+operations:
+  sendChat:
+    action: send
+    channel:
+      $ref: '#/channels/room'
+    messages:
+      - $ref: '#/channels/room/messages/chat'
+  receiveTyping:
+    action: receive
+    channel:
+      $ref: '#/channels/room'`
+
+	doc, err := Parse([]byte(spec))
+	require.NoError(t, err)
+	require.Len(t, doc.Channels, 1)
+
+	channel := doc.Channels[0]
+	require.Len(t, channel.ServerMessages, 1)
+	assert.Equal(t, "chat", channel.ServerMessages[0].Name)
+	assert.NotNil(t, channel.ServerMessages[0].Payload.Properties.GetOrZero("text"))
+	assert.Len(t, channel.ClientMessages, 2)
+}
+
+func TestParse_NotAsyncAPI(t *testing.T) {
+	_, err := Parse([]byte("openapi: 3.1.0"))
+	assert.Error(t, err)
+}
+
+func TestDocument_FindChannel(t *testing.T) {
+	doc := &Document{Channels: []*Channel{
+		{Name: "prices", Address: "prices/{symbol}"},
+		{Name: "news", Address: "/news"},
+	}}
+	assert.Equal(t, "prices", doc.FindChannel("/prices/ACME").Name)
+	assert.Equal(t, "news", doc.FindChannel("/news").Name)
+	assert.Nil(t, doc.FindChannel("/prices"))
+	assert.Nil(t, doc.FindChannel("/prices/ACME/history"))
+
+	var missing *Document
+	assert.Nil(t, missing.FindChannel("/news"))
+}
+
+func TestChannel_ValidateMessages(t *testing.T) {
+	spec := `asyncapi: 2.6.0
+info:
+  title: Prices
+  version: 1.0.0
+channels:
+  prices:
+    subscribe:
+      message:
+        payload:
+          type: object
+          required: [price]
+          properties:
+            price:
+              type: number
+    publish:
+      message:
+        oneOf:
+          - name: Subscribe
+            payload:
+              type: object
+              required: [subscribe]
+          - name: Unsubscribe
+            payload:
+              type: object
+              required: [unsubscribe]`
+
+	doc, err := Parse([]byte(spec))
+	require.NoError(t, err)
+	channel := doc.FindChannel("/prices")
+
+	assert.Empty(t, channel.ValidateServerMessage([]byte(`{"price":1.5}`)))
+	violations := channel.ValidateServerMessage([]byte(`{"price":"cheap"}`))
+	require.Len(t, violations, 1)
+	assert.Equal(t, ValidationType, violations[0].ValidationType)
+	assert.Equal(t, ServerMessage, violations[0].ValidationSubType)
+
+	assert.Empty(t, channel.ValidateClientMessage([]byte(`{"unsubscribe":"ACME"}`)))
+	violations = channel.ValidateClientMessage([]byte(`{"hello":"there"}`))
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0].Reason, "Subscribe, Unsubscribe")
+
+	violations = channel.ValidateClientMessage([]byte(`not json`))
+	require.Len(t, violations, 1)
+	assert.Equal(t, ClientMessage, violations[0].ValidationSubType)
+}
@@ -0,0 +1,99 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package asyncapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/schema_validation"
+)
+
+// ValidationType is the validation type of violations found in websocket messages, the sub-type is the
+// direction the message was sent in.
+const ValidationType = "message"
+
+// Message directions, used as the validation sub-type of violations.
+const (
+	ClientMessage = "client"
+	ServerMessage = "server"
+)
+
+// ValidateClientMessage validates a message sent by a client against the messages the channel receives.
+func (c *Channel) ValidateClientMessage(payload []byte) []*errors.ValidationError {
+	return validateMessage(c, ClientMessage, c.ClientMessages, payload)
+}
+
+// ValidateServerMessage validates a message sent by the application against the messages the channel sends.
+func (c *Channel) ValidateServerMessage(payload []byte) []*errors.ValidationError {
+	return validateMessage(c, ServerMessage, c.ServerMessages, payload)
+}
+
+// validateMessage checks a JSON payload against the payload schemas of the messages that can be sent in one
+// direction. The payload is valid if it passes any of them. Messages without a JSON content type are skipped.
+func validateMessage(channel *Channel, direction string, messages []*Message, payload []byte) []*errors.ValidationError {
+	var candidates []*Message
+	for _, message := range messages {
+		if message.Payload != nil && isJSON(message.ContentType) {
+			candidates = append(candidates, message)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var decoded any
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return []*errors.ValidationError{{
+			Message:           fmt.Sprintf("%s message on channel '%s' is not valid JSON", direction, channel.Name),
+			Reason:            fmt.Sprintf("The message cannot be decoded: %s", err.Error()),
+			ValidationType:    ValidationType,
+			ValidationSubType: direction,
+			HowToFix:          "Ensure messages sent over the channel are JSON",
+		}}
+	}
+
+	validator := schema_validation.NewSchemaValidator()
+	var failed []*errors.ValidationError
+	var names []string
+	for _, message := range candidates {
+		valid, violations := validator.ValidateSchemaObject(message.Payload, decoded)
+		if valid {
+			return nil
+		}
+		failed = violations
+		names = append(names, message.Name)
+	}
+
+	// a single message has its schema violations reported, otherwise it's not clear which message was intended.
+	if len(candidates) == 1 {
+		for _, violation := range failed {
+			violation.Message = fmt.Sprintf("%s message '%s' on channel '%s' does not pass validation",
+				direction, candidates[0].Name, channel.Name)
+			violation.ValidationType = ValidationType
+			violation.ValidationSubType = direction
+		}
+		return failed
+	}
+	return []*errors.ValidationError{{
+		Message: fmt.Sprintf("%s message on channel '%s' does not match any message", direction, channel.Name),
+		Reason: fmt.Sprintf("The message does not pass validation against any of the messages of the channel: %s",
+			strings.Join(names, ", ")),
+		ValidationType:    ValidationType,
+		ValidationSubType: direction,
+		HowToFix:          "Ensure the message matches the payload schema of one of the messages of the channel",
+	}}
+}
+
+// isJSON checks if a message content type is JSON, messages without a content type are assumed to be JSON.
+func isJSON(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = strings.TrimSpace(contentType[:i])
+	}
+	return contentType == "" || contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
@@ -0,0 +1,94 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package audit keeps an append-only log of the changes made to wiretap while it's running (delays, variables,
+// specifications and so on), and who made them, so shared instances aren't mysteriously reconfigured.
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pb33f/ranch/model"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionMonitorConnected      = "monitor-connected"
+	ActionSessionStarted        = "session-started"
+	ActionDelayChanged          = "delay-changed"
+	ActionPathDelaysChanged     = "path-delays-changed"
+	ActionMockPathsChanged      = "mock-paths-changed"
+	ActionInterceptChanged      = "intercept-changed"
+	ActionRequestReleased       = "intercepted-request-released"
+	ActionRequestDropped        = "intercepted-request-dropped"
+	ActionVariablesChanged      = "variables-changed"
+	ActionSpecificationReloaded = "specification-reloaded"
+	ActionSpecificationPushed   = "specification-pushed"
+	ActionMockOverridesReloaded = "mock-overrides-reloaded"
+	ActionCapturePaused         = "capture-paused"
+	ActionCaptureResumed        = "capture-resumed"
+)
+
+// ClientWatcher is the client of changes made by wiretap itself, when a watched file changes.
+const ClientWatcher = "watcher"
+
+// Entry is a change, when it was made and by whom. Details describe the change, e.g. the old and new delay.
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	Client  string         `json:"client"`
+	Action  string         `json:"action"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Log appends entries to a file, a line of JSON per entry. A nil log records nothing.
+type Log struct {
+	file *os.File
+	lock sync.Mutex
+}
+
+// Open opens an audit log for appending, it's created if it doesn't exist. Entries are never rewritten.
+func Open(filename string) (*Log, error) {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{file: f}, nil
+}
+
+// Record appends an entry, now.
+func (l *Log) Record(client, action string, details map[string]any) error {
+	if l == nil {
+		return nil
+	}
+	line, err := json.Marshal(&Entry{Time: time.Now().UTC(), Client: client, Action: action, Details: details})
+	if err != nil {
+		return err
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the audit log.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.file.Close()
+}
+
+// Client identifies who sent a request to a service, the monitor connection it came in on (monitor connections
+// are recorded with the address they came from when they connect).
+func Client(request *model.Request) string {
+	if request != nil && request.BrokerDestination != nil && request.BrokerDestination.ConnectionId != "" {
+		return "monitor:" + request.BrokerDestination.ConnectionId
+	}
+	return "monitor"
+}
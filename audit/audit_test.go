@@ -0,0 +1,59 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pb33f/ranch/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_Record(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"action":"earlier"}`+"\n"), 0644))
+
+	l, err := Open(filename)
+	require.NoError(t, err)
+	require.NoError(t, l.Record("monitor:1234", ActionDelayChanged, map[string]any{"from": 0, "to": 500}))
+	require.NoError(t, l.Record(ClientWatcher, ActionSpecificationReloaded, nil))
+	require.NoError(t, l.Close())
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	var entries []*Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		entries = append(entries, &e)
+	}
+	require.Len(t, entries, 3)
+	assert.Equal(t, "earlier", entries[0].Action)
+	assert.Equal(t, "monitor:1234", entries[1].Client)
+	assert.Equal(t, ActionDelayChanged, entries[1].Action)
+	assert.Equal(t, float64(500), entries[1].Details["to"])
+	assert.False(t, entries[1].Time.IsZero())
+	assert.Equal(t, ClientWatcher, entries[2].Client)
+	assert.Nil(t, entries[2].Details)
+}
+
+func TestLog_Nil(t *testing.T) {
+	var l *Log
+	assert.NoError(t, l.Record("monitor", ActionDelayChanged, nil))
+	assert.NoError(t, l.Close())
+}
+
+func TestClient(t *testing.T) {
+	assert.Equal(t, "monitor", Client(nil))
+	assert.Equal(t, "monitor", Client(&model.Request{}))
+	assert.Equal(t, "monitor:abc", Client(&model.Request{BrokerDestination: &model.BrokerDestinationConfig{ConnectionId: "abc"}}))
+}
@@ -0,0 +1,48 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/plank/pkg/server"
+	"github.com/pb33f/ranch/stompserver"
+	"github.com/pb33f/wiretap/audit"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// auditMonitorConnections records every connection to the monitor in the audit log, where it came from when it
+// connects, and the session it was given once it starts. Changes made over a session are recorded against it.
+func auditMonitorConnections(wiretapConfig *shared.WiretapConfiguration, platformServer server.PlatformServer, endpoint string) {
+	if wiretapConfig.Auditor == nil {
+		return
+	}
+	platformServer.GetRouter().Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == endpoint {
+				host, _, err := net.SplitHostPort(r.RemoteAddr)
+				if err != nil {
+					host = r.RemoteAddr
+				}
+				_ = wiretapConfig.Auditor.Record(host, audit.ActionMonitorConnected,
+					map[string]any{"address": r.RemoteAddr, "userAgent": r.UserAgent()})
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	handler, err := bus.GetBus().ListenStream(bus.STOMP_SESSION_NOTIFY_CHANNEL)
+	if err != nil {
+		return
+	}
+	handler.Handle(func(message *model.Message) {
+		if event, ok := message.Payload.(*bus.StompSessionEvent); ok && event.EventType == stompserver.ConnectionStarting {
+			_ = wiretapConfig.Auditor.Record("monitor:"+event.Id, audit.ActionSessionStarted, nil)
+		}
+	}, func(err error) {})
+}
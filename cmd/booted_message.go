@@ -10,6 +10,7 @@ import (
 	"github.com/pb33f/ranch/plank/pkg/server"
 	"github.com/pb33f/wiretap/shared"
 	"github.com/pterm/pterm"
+	"net"
 )
 
 func bootedMessage(wiretapConfig *shared.WiretapConfiguration) {
@@ -21,13 +22,16 @@ func bootedMessage(wiretapConfig *shared.WiretapConfiguration) {
 			if !seen {
 				seen = true
 				pterm.Println()
-				protocol := "http"
+				protocol, monitorProtocol := "http", "http"
 				if wiretapConfig.CertificateKey != "" && wiretapConfig.Certificate != "" {
 					protocol = "https"
 				}
+				if wiretapConfig.MonitorTLS() {
+					monitorProtocol = "https"
+				}
 
-				b1 := pterm.DefaultBox.WithTitle(pterm.LightMagenta("API Gateway")).Sprint(fmt.Sprintf("%s://localhost:%s", protocol, wiretapConfig.Port))
-				b2 := pterm.DefaultBox.WithTitle(pterm.LightMagenta("Monitor UI")).Sprint(fmt.Sprintf("%s://localhost:%s", protocol, wiretapConfig.MonitorPort))
+				b1 := pterm.DefaultBox.WithTitle(pterm.LightMagenta("API Gateway")).Sprint(fmt.Sprintf("%s://%s", protocol, net.JoinHostPort(wiretapConfig.LocalHost(), wiretapConfig.Port)))
+				b2 := pterm.DefaultBox.WithTitle(pterm.LightMagenta("Monitor UI")).Sprint(fmt.Sprintf("%s://%s", monitorProtocol, net.JoinHostPort(wiretapConfig.LocalHost(), wiretapConfig.MonitorPort)))
 				b3 := pterm.DefaultBox.WithTitle(pterm.LightMagenta("Static files served from")).Sprint(wiretapConfig.StaticDir)
 
 				var pp *pterm.PanelPrinter
@@ -0,0 +1,171 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/plank/pkg/server"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+// ciCommand is a test suite run by wiretap in CI mode, wiretap stops when it's done.
+type ciCommand struct {
+	command  string
+	exitCode int
+	ran      bool
+	done     chan struct{}
+	cancel   context.CancelFunc
+}
+
+// runCICommand runs the test suite as soon as wiretap is online, and stops wiretap when the suite is done.
+func runCICommand(wiretapConfig *shared.WiretapConfiguration, sysChan chan os.Signal) *ciCommand {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &ciCommand{command: wiretapConfig.CICommand, done: make(chan struct{}), cancel: cancel}
+
+	handler, _ := bus.GetBus().ListenStream(server.RANCH_SERVER_ONLINE_CHANNEL)
+	started := false
+	handler.Handle(func(message *model.Message) {
+		if started {
+			return
+		}
+		started = true
+		go func() {
+			defer close(c.done)
+			shell, flag := "sh", "-c"
+			if runtime.GOOS == "windows" {
+				shell, flag = "cmd", "/C"
+			}
+			cmd := exec.CommandContext(ctx, shell, flag, c.command)
+			cmd.Stdout, cmd.Stderr, cmd.Stdin = os.Stdout, os.Stderr, os.Stdin
+
+			pterm.Info.Printf("Running CI command: %s\n", pterm.LightCyan(c.command))
+			err := cmd.Run()
+			c.ran = true
+			var exitErr *exec.ExitError
+			switch {
+			case errors.As(err, &exitErr):
+				c.exitCode = exitErr.ExitCode()
+			case err != nil:
+				pterm.Error.Printf("Unable to run CI command: %s\n", err.Error())
+				c.exitCode = 1
+			}
+			sysChan <- syscall.SIGTERM
+		}()
+	}, func(err error) {})
+	return c
+}
+
+// stop kills the test suite if wiretap was stopped before it was done, and waits for it to exit.
+func (c *ciCommand) stop() {
+	c.cancel()
+	<-c.done
+}
+
+// ciGate waits for validation to finish, writes a summary of the violations found, and returns the code wiretap
+// exits with. Exceeding a threshold exits with 1, otherwise a failed test suite exits with the code of the suite.
+func ciGate(wiretapConfig *shared.WiretapConfiguration, wtService *daemon.WiretapService, command *ciCommand) int {
+	if command != nil {
+		command.stop()
+	}
+
+	// the tally is only final once every validation running in the background is done.
+	dropped := wtService.WaitForValidation()
+	summary := &configModel.CISummary{
+		Violations:         wtService.ViolationCounts(),
+		Thresholds:         configModel.CIThresholds(wiretapConfig.CIThresholds),
+		DroppedValidations: dropped,
+	}
+	if wtService.HasSpecification() {
+		report := wtService.Coverage()
+		summary.Coverage = &configModel.CICoverage{
+			Operations: report.Operations,
+			Responses:  report.Responses,
+			Parameters: report.Parameters,
+		}
+	}
+	if report := wtService.Latency(); len(report.Paths) > 0 {
+		summary.Latency = report
+	}
+	for _, count := range summary.Violations {
+		summary.Total += count
+	}
+	summary.Exceeded = configModel.ExceededThresholds(summary.Violations, summary.Thresholds)
+	if summary.DroppedValidations > 0 {
+		summary.Exceeded = append(summary.Exceeded, "dropped")
+	}
+	if command != nil && command.ran {
+		summary.Command = command.command
+		summary.CommandExitCode = &command.exitCode
+	}
+	summary.Passed = len(summary.Exceeded) == 0 && (summary.CommandExitCode == nil || *summary.CommandExitCode == 0)
+
+	summaryFile := wiretapConfig.CISummaryFile
+	if summaryFile == "" {
+		summaryFile = shared.DefaultCISummaryFile
+	}
+	b, _ := json.MarshalIndent(summary, "", "  ")
+	if err := os.WriteFile(summaryFile, b, 0644); err != nil {
+		pterm.Error.Printf("Unable to write CI summary: %s\n", err.Error())
+	}
+
+	printCISummary(summary, summaryFile)
+
+	switch {
+	case len(summary.Exceeded) > 0:
+		return 1
+	case summary.CommandExitCode != nil && *summary.CommandExitCode != 0:
+		return *summary.CommandExitCode
+	}
+	return 0
+}
+
+func printCISummary(summary *configModel.CISummary, summaryFile string) {
+	pterm.Println()
+	data := pterm.TableData{{"Severity", "Violations", "Threshold"}}
+	for _, severity := range []string{shared.SeverityError, shared.SeverityWarn, shared.SeverityInfo} {
+		threshold := "-"
+		if max, ok := summary.Thresholds[severity]; ok {
+			threshold = fmt.Sprint(max)
+		}
+		data = append(data, []string{severity, fmt.Sprint(summary.Violations[severity]), threshold})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+	pterm.Println()
+
+	if summary.DroppedValidations > 0 {
+		pterm.Warning.Printf("%d validations were dropped, the validation queue was full\n", summary.DroppedValidations)
+	}
+	if summary.CommandExitCode != nil && *summary.CommandExitCode != 0 {
+		pterm.Error.Printf("CI command failed with exit code %d\n", *summary.CommandExitCode)
+	}
+	if len(summary.Exceeded) > 0 {
+		pterm.Error.Printf("Wiretap detected %d contract violations, thresholds exceeded: %s\n", summary.Total,
+			strings.Join(summary.Exceeded, ", "))
+	} else {
+		pterm.Success.Printf("Wiretap detected %d contract violations, all within thresholds\n", summary.Total)
+	}
+	if summary.Coverage != nil {
+		pterm.Printf("Specification coverage: %s of operations, %s of responses, %s of parameters\n",
+			pterm.LightCyan(fmt.Sprintf("%.2f%%", summary.Coverage.Operations.Percent)),
+			pterm.LightCyan(fmt.Sprintf("%.2f%%", summary.Coverage.Responses.Percent)),
+			pterm.LightCyan(fmt.Sprintf("%.2f%%", summary.Coverage.Parameters.Percent)))
+	}
+	pterm.Printf("CI summary saved to: %s\n", pterm.LightMagenta(summaryFile))
+	pterm.Println()
+}
@@ -0,0 +1,51 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pb33f/wiretap/coverage"
+	"github.com/pb33f/wiretap/report"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+// coverageFilename puts the coverage report next to the violation report, e.g. wiretap-report-coverage.json.
+func coverageFilename(reportFile string) string {
+	if reportFile == "" {
+		reportFile = "wiretap-report.json"
+	}
+	return strings.TrimSuffix(reportFile, filepath.Ext(reportFile)) + "-coverage.json"
+}
+
+// writeCoverageReport saves which parts of the specification were exercised, when there is a specification. A
+// human-readable HTML summary is saved next to the JSON report, e.g. wiretap-report-coverage.html.
+func writeCoverageReport(wiretapConfig *shared.WiretapConfiguration, coverageReport *coverage.Report) {
+	if len(coverageReport.Details) == 0 {
+		return
+	}
+	filename := coverageFilename(wiretapConfig.ReportFile)
+	b, _ := json.MarshalIndent(coverageReport, "", "  ")
+	if err := os.WriteFile(filename, b, 0644); err != nil {
+		pterm.Error.Printf("Unable to write coverage report: %s\n", err.Error())
+		return
+	}
+	pterm.Printf("Coverage report saved to: %s\n", pterm.LightMagenta(filename))
+
+	htmlFilename := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".html"
+	page, err := report.RenderCoverageHTML(coverageReport, wiretapConfig.Contract)
+	if err == nil {
+		err = os.WriteFile(htmlFilename, page, 0644)
+	}
+	if err != nil {
+		pterm.Error.Printf("Unable to write coverage summary: %s\n", err.Error())
+		return
+	}
+	pterm.Printf("Coverage summary saved to: %s\n", pterm.LightMagenta(htmlFilename))
+}
@@ -0,0 +1,153 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/session"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/validation"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	SilenceUsage: true,
+	Use:          "diff <session-a> <session-b>",
+	Short:        "Compare two captured sessions, operation by operation.",
+	Long: `Compare two captured sessions (HAR files or transaction stores), e.g. the same traffic sent to two versions
+of a backend. New violations, changed status codes and latency regressions are reported for each operation. When
+an OpenAPI specification is given, both sessions are validated against it and operations are named by their path
+templates, otherwise the violations captured with the sessions are compared.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		spec, _ := cmd.Flags().GetString("spec")
+		base, _ := cmd.Flags().GetString("base")
+		threshold, _ := cmd.Flags().GetFloat64("latency-threshold")
+		reportFilename, _ := cmd.Flags().GetString("report-filename")
+
+		sessions := make([][]*session.Exchange, len(args))
+		for i, filename := range args {
+			exchanges, err := session.Load(filename)
+			if err != nil {
+				pterm.Error.Printf("Cannot read session '%s': %s\n", filename, err.Error())
+				return err
+			}
+			sessions[i] = exchanges
+		}
+
+		var doc *v3.Document
+		if spec != "" {
+			libDoc, dErr := loadOpenAPISpec(spec, base)
+			if dErr != nil {
+				pterm.Error.Printf("Cannot load specification: %s\n", dErr.Error())
+				return dErr
+			}
+			model, errs := libDoc.BuildV3Model()
+			if model == nil {
+				pterm.Error.Printf("Cannot build OpenAPI model: %s\n", errors.Join(errs...))
+				return errors.Join(errs...)
+			}
+			doc = &model.Model
+			validator := validation.NewHttpValidator(doc)
+			for _, exchanges := range sessions {
+				for _, e := range exchanges {
+					e.Validate(validator, nil)
+				}
+			}
+		}
+
+		operation := func(e *session.Exchange) string {
+			path := e.URL
+			if u, err := url.Parse(e.URL); err == nil {
+				path = u.Path
+			}
+			if req, err := e.Request(); err == nil {
+				if template, _ := validation.LocateOperation(req, doc); template != "" {
+					path = template
+				}
+			}
+			return strings.ToUpper(e.Method) + " " + path
+		}
+
+		diff := session.Compare(sessions[0], sessions[1], operation, threshold)
+
+		pterm.Info.Printf("Comparing %s (%d %s) with %s (%d %s)\n",
+			pterm.LightCyan(args[0]), len(sessions[0]), shared.Pluralize(len(sessions[0]), "request", "requests"),
+			pterm.LightCyan(args[1]), len(sessions[1]), shared.Pluralize(len(sessions[1]), "request", "requests"))
+		pterm.Println()
+
+		table := pterm.TableData{{"Operation", "Requests", "Status codes", "p95 latency (ms)", "Violations"}}
+		for _, od := range diff.Operations {
+			codes := fmt.Sprintf("%s -> %s", statusCodes(od.StatusCodesA), statusCodes(od.StatusCodesB))
+			if od.StatusChanged {
+				codes = pterm.LightRed(codes)
+			}
+			latency := fmt.Sprintf("%g -> %g", od.LatencyA.P95, od.LatencyB.P95)
+			if od.LatencyRegression {
+				latency = pterm.LightRed(latency)
+			}
+			violations := fmt.Sprintf("+%d / -%d", len(od.NewViolations), len(od.ResolvedViolations))
+			if len(od.NewViolations) > 0 {
+				violations = pterm.LightRed(violations)
+			}
+			table = append(table, []string{od.Operation, fmt.Sprintf("%d -> %d", od.RequestsA, od.RequestsB),
+				codes, latency, violations})
+		}
+		_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+		pterm.Println()
+
+		for _, od := range diff.Operations {
+			for _, v := range od.NewViolations {
+				pterm.Printf("%s %s: %s\n", pterm.LightRed("new"), od.Operation, v)
+			}
+		}
+
+		summary := fmt.Sprintf("%d new %s, %d resolved, %d status code %s, %d latency %s",
+			diff.NewViolations, shared.Pluralize(diff.NewViolations, "violation", "violations"),
+			diff.ResolvedViolations,
+			diff.StatusChanges, shared.Pluralize(diff.StatusChanges, "change", "changes"),
+			diff.LatencyRegressions, shared.Pluralize(diff.LatencyRegressions, "regression", "regressions"))
+		if diff.Regressed() {
+			pterm.Warning.Println(summary)
+		} else {
+			pterm.Success.Println(summary)
+		}
+
+		report, _ := json.MarshalIndent(diff, "", "  ")
+		if err := os.WriteFile(reportFilename, report, 0664); err != nil {
+			pterm.Error.Printf("Cannot write diff report: %s\n", err.Error())
+			return err
+		}
+		pterm.Printf("Diff report saved to: %s\n", pterm.LightMagenta(reportFilename))
+		return nil
+	},
+}
+
+// statusCodes lists the status codes seen for an operation, e.g. 200,404.
+func statusCodes(codes map[int]int) string {
+	if len(codes) == 0 {
+		return "-"
+	}
+	sorted := make([]int, 0, len(codes))
+	for c := range codes {
+		sorted = append(sorted, c)
+	}
+	sort.Ints(sorted)
+	names := make([]string, len(sorted))
+	for i, c := range sorted {
+		names[i] = fmt.Sprint(c)
+	}
+	return strings.Join(names, ",")
+}
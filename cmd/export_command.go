@@ -0,0 +1,299 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/export"
+	"github.com/pb33f/wiretap/session"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// export formats.
+const (
+	exportPostman = "postman"
+	exportCurl    = "curl"
+	exportHAR     = "har"
+	exportJSON    = "json"
+)
+
+var exportCmd = &cobra.Command{
+	SilenceUsage: true,
+	Use:          "export <session>",
+	Short:        "Export a captured session, so it can be imported into other tools.",
+	Long: `Export a captured session (a HAR file or a transaction store) so it can be imported into other tools.
+The 'postman' format writes a Postman collection (a request per captured transaction, with the response as an
+example) and an environment derived from the variables in the wiretap configuration. Values of variables found
+in the captured traffic are replaced by references to them, and the origin most requests were sent to becomes
+{{baseUrl}}. The 'curl' format writes a shell script with a curl command per request, to reproduce a request
+outside wiretap. Requests can be selected by their position in the session (e.g. 1,4-6).`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		format, _ := cmd.Flags().GetString("format")
+		configFlag, _ := cmd.Flags().GetString("config")
+		filename, _ := cmd.Flags().GetString("filename")
+		environmentFilename, _ := cmd.Flags().GetString("environment-filename")
+		selection, _ := cmd.Flags().GetString("requests")
+
+		format = strings.ToLower(format)
+		switch format {
+		case exportPostman:
+			if filename == "" {
+				filename = "wiretap-postman.json"
+			}
+		case exportCurl:
+			if filename == "" {
+				filename = "wiretap-curl.sh"
+			}
+		default:
+			err := fmt.Errorf("unknown export format '%s', use '%s' or '%s'", format, exportPostman, exportCurl)
+			pterm.Error.Println(err.Error())
+			return err
+		}
+
+		var config shared.WiretapConfiguration
+		if configFlag != "" {
+			cBytes, err := os.ReadFile(configFlag)
+			if err != nil {
+				pterm.Error.Printf("Failed to read wiretap configuration '%s': %s\n", configFlag, err.Error())
+				return err
+			}
+			if err = yaml.Unmarshal(cBytes, &config); err != nil {
+				pterm.Error.Printf("Failed to parse wiretap configuration '%s': %s\n", configFlag, err.Error())
+				return err
+			}
+		}
+
+		exchanges, err := session.Load(args[0])
+		if err != nil {
+			pterm.Error.Printf("Cannot read session '%s': %s\n", args[0], err.Error())
+			return err
+		}
+		if selection != "" {
+			if exchanges, err = selectExchanges(exchanges, selection); err != nil {
+				pterm.Error.Printf("Cannot select requests: %s\n", err.Error())
+				return err
+			}
+		}
+
+		if format == exportCurl {
+			if err = os.WriteFile(filename, []byte(export.Curl(exchanges)), 0755); err != nil {
+				pterm.Error.Printf("Cannot write curl script: %s\n", err.Error())
+				return err
+			}
+			pterm.Printf("curl script saved to: %s\n", pterm.LightMagenta(filename))
+			pterm.Success.Printf("Exported %d %s\n", len(exchanges), shared.Pluralize(len(exchanges), "request", "requests"))
+			return nil
+		}
+
+		collection, environment := export.Postman(filepath.Base(args[0]), exchanges, config.Variables)
+		for _, out := range []struct {
+			filename string
+			value    any
+			what     string
+		}{{filename, collection, "Postman collection"}, {environmentFilename, environment, "Postman environment"}} {
+			data, _ := json.MarshalIndent(out.value, "", "  ")
+			if err = os.WriteFile(out.filename, data, 0664); err != nil {
+				pterm.Error.Printf("Cannot write %s: %s\n", out.what, err.Error())
+				return err
+			}
+			pterm.Printf("%s saved to: %s\n", out.what, pterm.LightMagenta(out.filename))
+		}
+		pterm.Success.Printf("Exported %d %s\n", len(collection.Item),
+			shared.Pluralize(len(collection.Item), "request", "requests"))
+		return nil
+	},
+}
+
+// selectExchanges picks exchanges by their position in a session (counting from 1), e.g. '1,4-6'.
+func selectExchanges(exchanges []*session.Exchange, selection string) ([]*session.Exchange, error) {
+	var selected []*session.Exchange
+	for _, part := range strings.Split(selection, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a request number or range", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("'%s' is not a request number or range", part)
+			}
+		}
+		if from < 1 || to > len(exchanges) || from > to {
+			return nil, fmt.Errorf("'%s' is out of range, the session has %d requests", part, len(exchanges))
+		}
+		selected = append(selected, exchanges[from-1:to]...)
+	}
+	return selected, nil
+}
+
+// monitorExchanges reads the transactions captured so far (or those asked for by id), as exchanges. Requests are
+// captured relative to wiretap, so they are sent back through it.
+func monitorExchanges(config *shared.WiretapConfiguration, wtService *daemon.WiretapService, ids []string) ([]*session.Exchange, error) {
+	transactions, err := wtService.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		transactions, _ = selectTransactions(transactions, ids)
+	}
+	return exchangesOf(config, transactions), nil
+}
+
+// selectTransactions picks transactions by id, in the order they were captured. The ids that weren't captured
+// are returned as missing.
+func selectTransactions(transactions []*daemon.HttpTransaction, ids []string) (selected []*daemon.HttpTransaction, missing []string) {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = false
+	}
+	for _, transaction := range transactions {
+		if found, ok := wanted[transaction.Id]; ok && !found {
+			wanted[transaction.Id] = true
+			selected = append(selected, transaction)
+		}
+	}
+	for _, id := range ids {
+		if !wanted[id] && !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+	return selected, missing
+}
+
+// exchangesOf converts captured transactions into exchanges, requests are sent back through wiretap.
+func exchangesOf(config *shared.WiretapConfiguration, transactions []*daemon.HttpTransaction) []*session.Exchange {
+	exchanges := session.FromTransactions(transactions)
+	origin := "http://" + net.JoinHostPort(config.LocalHost(), config.Port)
+	if config.Certificate != "" && config.CertificateKey != "" {
+		origin = "https://" + net.JoinHostPort(config.LocalHost(), config.Port)
+	}
+	for _, e := range exchanges {
+		if strings.HasPrefix(e.URL, "/") {
+			e.URL = origin + e.URL
+		}
+	}
+	return exchanges
+}
+
+// exportSelection is the transactions to export, and the format to export them in.
+type exportSelection struct {
+	Ids    []string `json:"ids"`
+	Format string   `json:"format"`
+}
+
+// handleExport serves the transactions asked for, by id, as a download in the format asked for: a HAR file, the
+// transactions as JSON, a curl script or a Postman collection. The ids and format are sent as JSON with a POST, or
+// as ?id= and ?format= with a GET. Exporting ids that haven't been captured is an error, rather than a smaller file.
+func handleExport(config *shared.WiretapConfiguration, wtService *daemon.WiretapService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var selection exportSelection
+		switch r.Method {
+		case http.MethodGet:
+			selection = exportSelection{Ids: r.URL.Query()["id"], Format: r.URL.Query().Get("format")}
+		case http.MethodPost:
+			if err := json.NewDecoder(r.Body).Decode(&selection); err != nil {
+				writeAPIError(w, http.StatusBadRequest, "the selection has to be sent as JSON: "+err.Error())
+				return
+			}
+		default:
+			writeAPIError(w, http.StatusMethodNotAllowed, "only GET and POST are supported")
+			return
+		}
+		format := strings.ToLower(selection.Format)
+		if !slices.Contains([]string{exportHAR, exportJSON, exportCurl, exportPostman}, format) {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("unknown export format '%s', use '%s', '%s', '%s' or '%s'",
+				selection.Format, exportHAR, exportJSON, exportCurl, exportPostman))
+			return
+		}
+		if len(selection.Ids) == 0 {
+			writeAPIError(w, http.StatusBadRequest, "the ids of the transactions to export are required")
+			return
+		}
+
+		transactions, err := wtService.Transactions()
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		transactions, missing := selectTransactions(transactions, selection.Ids)
+		if len(missing) > 0 {
+			writeAPIError(w, http.StatusNotFound, fmt.Sprintf("no transactions have been captured with ids '%s'",
+				strings.Join(missing, "', '")))
+			return
+		}
+
+		switch format {
+		case exportHAR:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", `attachment; filename="wiretap-export.har"`)
+			_ = json.NewEncoder(w).Encode(export.HAR(exchangesOf(config, transactions), config.Version))
+		case exportJSON:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", `attachment; filename="wiretap-export.json"`)
+			_ = json.NewEncoder(w).Encode(transactions)
+		case exportCurl:
+			w.Header().Set("Content-Type", "text/x-shellscript; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="wiretap-curl.sh"`)
+			_, _ = w.Write([]byte(export.Curl(exchangesOf(config, transactions))))
+		case exportPostman:
+			collection, _ := export.Postman("wiretap", exchangesOf(config, transactions), config.Variables)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", `attachment; filename="wiretap-postman.json"`)
+			_ = json.NewEncoder(w).Encode(collection)
+		}
+	}
+}
+
+// handlePostmanExport serves the transactions captured so far (or those asked for with ?id=) as a Postman
+// collection, or the environment that goes with it.
+func handlePostmanExport(config *shared.WiretapConfiguration, wtService *daemon.WiretapService, environment bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exchanges, err := monitorExchanges(config, wtService, r.URL.Query()["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		collection, env := export.Postman("wiretap", exchanges, config.Variables)
+		w.Header().Set("Content-Type", "application/json")
+		if environment {
+			w.Header().Set("Content-Disposition", `attachment; filename="wiretap-postman-environment.json"`)
+			_ = json.NewEncoder(w).Encode(env)
+			return
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="wiretap-postman.json"`)
+		_ = json.NewEncoder(w).Encode(collection)
+	}
+}
+
+// handleCurlExport serves the transactions captured so far (or those asked for with ?id=) as a curl script.
+func handleCurlExport(config *shared.WiretapConfiguration, wtService *daemon.WiretapService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exchanges, err := monitorExchanges(config, wtService, r.URL.Query()["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/x-shellscript; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="wiretap-curl.sh"`)
+		_, _ = w.Write([]byte(export.Curl(exchanges)))
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pb33f/wiretap/generate"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var generateCmd = &cobra.Command{
+	SilenceUsage: true,
+	Use:          "generate",
+	Short:        "Synthesize and send valid requests for an operation in the OpenAPI specification.",
+	Long: `Synthesize and send valid requests for an operation in the OpenAPI specification. Requests are sent to wiretap
+by default, so they flow through the compliance pipeline. Useful for seeding data and smoke-testing new endpoints.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		spec, _ := cmd.Flags().GetString("spec")
+		base, _ := cmd.Flags().GetString("base")
+		target, _ := cmd.Flags().GetString("url")
+		operationId, _ := cmd.Flags().GetString("operation")
+		count, _ := cmd.Flags().GetInt("count")
+		vary, _ := cmd.Flags().GetString("vary")
+		headers, _ := cmd.Flags().GetStringArray("header")
+
+		if spec == "" {
+			return errors.New("an OpenAPI specification is required to generate requests (use --spec)")
+		}
+		if operationId == "" {
+			return errors.New("an operation is required to generate requests (use --operation)")
+		}
+		if count < 1 {
+			count = 1
+		}
+
+		doc, err := loadOpenAPISpec(spec, base)
+		if err != nil {
+			pterm.Error.Printf("Cannot load specification: %s\n", err.Error())
+			return err
+		}
+		model, errs := doc.BuildV3Model()
+		if model == nil {
+			pterm.Error.Printf("Cannot build OpenAPI model: %s\n", errors.Join(errs...))
+			return errors.Join(errs...)
+		}
+
+		generator, err := generate.NewRequestGenerator(&model.Model, strings.ToLower(vary))
+		if err != nil {
+			return err
+		}
+		operation, err := generator.LocateOperation(operationId)
+		if err != nil {
+			pterm.Error.Println(err.Error())
+			return err
+		}
+
+		pterm.Info.Printf("Sending %d %s to %s %s via %s\n", count, shared.Pluralize(count, "request", "requests"),
+			pterm.LightCyan(operation.Method), pterm.LightMagenta(operation.Path), pterm.LightCyan(target))
+		pterm.Println()
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		codes := make(map[int]int)
+		failures := 0
+		for i := 0; i < count; i++ {
+			req, e := generator.GenerateRequest(operation, target)
+			if e != nil {
+				pterm.Error.Printf("Unable to generate request: %s\n", e.Error())
+				failures++
+				continue
+			}
+			for _, h := range headers {
+				if k, v, ok := strings.Cut(h, ":"); ok {
+					req.Header.Set(strings.TrimSpace(k), strings.TrimSpace(v))
+				}
+			}
+			resp, e := client.Do(req)
+			if e != nil {
+				pterm.Error.Printf("[%d] %s %s failed: %s\n", i+1, req.Method, req.URL.String(), e.Error())
+				failures++
+				continue
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			codes[resp.StatusCode]++
+
+			code := fmt.Sprint(resp.StatusCode)
+			if resp.StatusCode >= 400 {
+				code = pterm.LightRed(code)
+			} else {
+				code = pterm.LightGreen(code)
+			}
+			pterm.Printf("[%d] %s %s --> %s\n", i+1, req.Method, req.URL.String(), code)
+		}
+
+		pterm.Println()
+		var summary []string
+		var sorted []int
+		for c := range codes {
+			sorted = append(sorted, c)
+		}
+		sort.Ints(sorted)
+		for _, c := range sorted {
+			summary = append(summary, fmt.Sprintf("%d x %d", codes[c], c))
+		}
+		if failures > 0 {
+			summary = append(summary, fmt.Sprintf("%d failed", failures))
+		}
+		pterm.Success.Printf("Sent %d %s: %s\n", count, shared.Pluralize(count, "request", "requests"), strings.Join(summary, ", "))
+		return nil
+	},
+}
@@ -8,10 +8,15 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/handlers"
 	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/accesslog"
 	"github.com/pb33f/wiretap/daemon"
 	"github.com/pb33f/wiretap/shared"
 	"github.com/pterm/pterm"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"io"
 	"net/http"
+	"os"
 )
 
 func handleHttpTraffic(wiretapConfig *shared.WiretapConfiguration, wtService *daemon.WiretapService) {
@@ -33,16 +38,24 @@ func handleHttpTraffic(wiretapConfig *shared.WiretapConfiguration, wtService *da
 		// handle the index
 		mux.HandleFunc("/", handleTraffic)
 
+		// log every request in the combined format, if there is somewhere to write it.
+		handler := handlers.CompressHandler(mux)
+		if out := openAccessLog(wiretapConfig.AccessLog); out != nil {
+			handler = accesslog.Handler(out, handler, wiretapConfig.Redactor)
+		}
+
 		pterm.Info.Println(pterm.LightMagenta(fmt.Sprintf("API Gateway UI booting on port %s...", wiretapConfig.Port)))
 
 		var httpErr error
 		if wiretapConfig.CertificateKey != "" && wiretapConfig.Certificate != "" {
-			httpErr = http.ListenAndServeTLS(fmt.Sprintf(":%s", wiretapConfig.Port),
+			httpErr = http.ListenAndServeTLS(wiretapConfig.ListenAddress(wiretapConfig.Port),
 				wiretapConfig.Certificate,
 				wiretapConfig.CertificateKey,
-				handlers.CompressHandler(mux))
+				handler)
 		} else {
-			httpErr = http.ListenAndServe(fmt.Sprintf(":%s", wiretapConfig.Port), handlers.CompressHandler(mux))
+			// HTTP/2 is negotiated over TLS, without it clients can use HTTP/2 with prior knowledge (h2c).
+			httpErr = http.ListenAndServe(wiretapConfig.ListenAddress(wiretapConfig.Port),
+				h2c.NewHandler(handler, &http2.Server{}))
 		}
 
 		if httpErr != nil {
@@ -50,3 +63,19 @@ func handleHttpTraffic(wiretapConfig *shared.WiretapConfiguration, wtService *da
 		}
 	}()
 }
+
+// openAccessLog opens the access log for appending, '-' writes it to stdout. Nil is returned if there isn't one.
+func openAccessLog(filename string) io.Writer {
+	if filename == "" {
+		return nil
+	}
+	if filename == "-" {
+		return os.Stdout
+	}
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		pterm.Error.Printf("Cannot write access log: %s\n", err.Error())
+		return nil
+	}
+	return f
+}
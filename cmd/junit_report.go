@@ -0,0 +1,38 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/report"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+// junitFilename puts the JUnit report next to the violation report, e.g. wiretap-report.xml.
+func junitFilename(reportFile string) string {
+	if reportFile == "" {
+		reportFile = "wiretap-report.json"
+	}
+	return strings.TrimSuffix(reportFile, filepath.Ext(reportFile)) + ".xml"
+}
+
+// writeJUnitReport saves a JUnit report of the operations exercised, and the violations found in them.
+func writeJUnitReport(wiretapConfig *shared.WiretapConfiguration, wtService *daemon.WiretapService) {
+	filename := junitFilename(wiretapConfig.ReportFile)
+	b, err := report.MarshalJUnit(report.BuildJUnit(wtService.OperationResults(), wiretapConfig.Contract))
+	if err == nil {
+		err = os.WriteFile(filename, b, 0644)
+	}
+	if err != nil {
+		pterm.Error.Printf("Unable to write JUnit report: %s\n", err.Error())
+		return
+	}
+	pterm.Printf("JUnit report saved to: %s\n", pterm.LightMagenta(filename))
+}
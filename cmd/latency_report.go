@@ -0,0 +1,38 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pb33f/wiretap/latency"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+// latencyFilename puts the latency report next to the violation report, e.g. wiretap-report-latency.json.
+func latencyFilename(reportFile string) string {
+	if reportFile == "" {
+		reportFile = "wiretap-report.json"
+	}
+	return strings.TrimSuffix(reportFile, filepath.Ext(reportFile)) + "-latency.json"
+}
+
+// writeLatencyReport saves how long the upstream API took to respond for every path, when anything was proxied.
+func writeLatencyReport(wiretapConfig *shared.WiretapConfiguration, report *latency.Report) {
+	if len(report.Paths) == 0 {
+		return
+	}
+	filename := latencyFilename(wiretapConfig.ReportFile)
+	b, _ := json.MarshalIndent(report, "", "  ")
+	if err := os.WriteFile(filename, b, 0644); err != nil {
+		pterm.Error.Printf("Unable to write latency report: %s\n", err.Error())
+		return
+	}
+	pterm.Printf("Latency report saved to: %s\n", pterm.LightMagenta(filename))
+}
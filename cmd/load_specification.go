@@ -4,50 +4,56 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/pb33f/libopenapi"
 	"github.com/pb33f/libopenapi/datamodel"
+	"github.com/pb33f/wiretap/asyncapi"
+	"github.com/pb33f/wiretap/graphql"
+	"github.com/pb33f/wiretap/grpc"
+	"github.com/pb33f/wiretap/overlay"
+	"github.com/pb33f/wiretap/swagger"
 	"github.com/pterm/pterm"
+	"github.com/vektah/gqlparser/v2/ast"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"strings"
+	"time"
 )
 
-func loadOpenAPISpec(contract, base string) (libopenapi.Document, error) {
-	var specBytes []byte
-
-	if strings.HasPrefix(contract, "http://") || strings.HasPrefix(contract, "https://") {
-		if docUrl, err := url.Parse(contract); err == nil {
-			pterm.Info.Printf("Fetching OpenAPI Specification from URL: '%s'\n", docUrl.String())
-			resp, er := http.Get(docUrl.String())
-			if er != nil {
-				return nil, er
-			}
-			respBody, e := io.ReadAll(resp.Body)
-			if e != nil {
-				return nil, e
-			}
-			if len(respBody) > 0 {
-				specBytes = respBody
-			}
-		}
-	} else {
+func loadOpenAPISpec(contract, base string, overlays ...*overlay.Overlay) (libopenapi.Document, error) {
+	specBytes, err := readSpecification(contract, "OpenAPI")
+	if err != nil {
+		return nil, err
+	}
 
-		// not a URL, is it a file?
-		var er error
-		if _, er = os.Stat(contract); er != nil {
-			return nil, er
+	// overlays patch the specification before anything is built from it.
+	for _, o := range overlays {
+		var unmatched []string
+		specBytes, unmatched, err = o.Apply(specBytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to apply overlay '%s': %w", o.Info.Title, err)
 		}
-		specBytes, er = os.ReadFile(contract)
-		if er != nil {
-			return nil, er
+		for _, target := range unmatched {
+			pterm.Warning.Printf("Overlay '%s' target '%s' does not match anything in the specification\n",
+				o.Info.Title, target)
 		}
 	}
-	if len(specBytes) <= 0 {
-		return nil, fmt.Errorf("no bytes in OpenAPI Specification")
+
+	// swagger documents are converted, everything is built from OpenAPI 3.
+	if swagger.IsSwagger(specBytes) {
+		specBytes, err = swagger.Convert(specBytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert Swagger specification '%s': %w", contract, err)
+		}
+		pterm.Info.Printf("Swagger 2.0 specification '%s' converted to OpenAPI %s\n", contract, swagger.OpenAPIVersion)
 	}
 
 	docConfig := datamodel.NewDocumentConfiguration()
@@ -72,3 +78,122 @@ func loadOpenAPISpec(contract, base string) (libopenapi.Document, error) {
 
 	return libopenapi.NewDocumentWithConfiguration(specBytes, docConfig)
 }
+
+// loadOverlay loads an OpenAPI Overlay document, used to patch the specification when it's loaded.
+func loadOverlay(location string) (*overlay.Overlay, error) {
+	overlayBytes, err := readSpecification(location, "Overlay")
+	if err != nil {
+		return nil, err
+	}
+	return overlay.Parse(overlayBytes)
+}
+
+// loadAsyncAPISpec loads an AsyncAPI document, used to mock websocket channels.
+func loadAsyncAPISpec(location string) (*asyncapi.Document, error) {
+	specBytes, err := readSpecification(location, "AsyncAPI")
+	if err != nil {
+		return nil, err
+	}
+	return asyncapi.Parse(specBytes)
+}
+
+// loadGraphQLSchema loads a GraphQL schema from an SDL file, or by introspecting a GraphQL API. URLs of SDL
+// files (.graphql, .graphqls or .gql) are downloaded, any other URL is introspected.
+func loadGraphQLSchema(location string) (*ast.Schema, error) {
+	isURL := strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+	if isURL && !isSDLFile(location) {
+		pterm.Info.Printf("Introspecting GraphQL schema from URL: '%s'\n", location)
+		query, _ := json.Marshal(map[string]string{"query": graphql.IntrospectionQuery})
+		resp, err := http.Post(location, "application/json", bytes.NewReader(query))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		result, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.FromIntrospection(result)
+	}
+	sdl, err := readSpecification(location, "GraphQL")
+	if err != nil {
+		return nil, err
+	}
+	return graphql.LoadSchema(sdl, location)
+}
+
+// loadGRPCDescriptors loads the descriptors of gRPC services from a descriptor set, or using the server reflection
+// of a gRPC server. URLs of descriptor sets (.pb, .protoset or .desc) are downloaded, any other URL is reflected.
+func loadGRPCDescriptors(location string) (*protoregistry.Files, error) {
+	isURL := strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+	if isURL && !isDescriptorSet(location) {
+		pterm.Info.Printf("Reading gRPC descriptors from URL using server reflection: '%s'\n", location)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return grpc.Reflect(ctx, location)
+	}
+	set, err := readSpecification(location, "gRPC")
+	if err != nil {
+		return nil, err
+	}
+	return grpc.LoadDescriptorSet(set)
+}
+
+func isDescriptorSet(location string) bool {
+	if u, err := url.Parse(location); err == nil {
+		location = u.Path
+	}
+	switch strings.ToLower(path.Ext(location)) {
+	case ".pb", ".protoset", ".desc":
+		return true
+	}
+	return false
+}
+
+func isSDLFile(location string) bool {
+	if u, err := url.Parse(location); err == nil {
+		location = u.Path
+	}
+	switch strings.ToLower(path.Ext(location)) {
+	case ".graphql", ".graphqls", ".gql":
+		return true
+	}
+	return false
+}
+
+// readSpecification reads a specification from a URL or a file.
+func readSpecification(location, kind string) ([]byte, error) {
+	var specBytes []byte
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		if docUrl, err := url.Parse(location); err == nil {
+			pterm.Info.Printf("Fetching %s Specification from URL: '%s'\n", kind, docUrl.String())
+			resp, er := http.Get(docUrl.String())
+			if er != nil {
+				return nil, er
+			}
+			respBody, e := io.ReadAll(resp.Body)
+			if e != nil {
+				return nil, e
+			}
+			if len(respBody) > 0 {
+				specBytes = respBody
+			}
+		}
+	} else {
+
+		// not a URL, is it a file?
+		var er error
+		if _, er = os.Stat(location); er != nil {
+			return nil, er
+		}
+		specBytes, er = os.ReadFile(location)
+		if er != nil {
+			return nil, er
+		}
+	}
+	if len(specBytes) <= 0 {
+		return nil, fmt.Errorf("no bytes in %s Specification", kind)
+	}
+	return specBytes, nil
+}
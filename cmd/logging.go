@@ -0,0 +1,90 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+// parseLogLevel reads a log level, the default is warn (or debug, if debugging).
+func parseLogLevel(level string, debug bool) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "":
+		if debug {
+			return slog.LevelDebug, nil
+		}
+		return slog.LevelWarn, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level '%s', use debug, info, warn or error", level)
+}
+
+// newLogger creates the logger wiretap logs events with, to stdout. Text logs are colorful and for the terminal,
+// JSON logs are one event per line, for shipping somewhere else (the terminal output moves to stderr).
+func newLogger(out io.Writer, format string, level slog.Level) *slog.Logger {
+	if strings.EqualFold(format, shared.LogFormatJSON) {
+		return slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level}))
+	}
+
+	logLevel := pterm.LogLevelWarn
+	switch level {
+	case slog.LevelDebug:
+		logLevel = pterm.LogLevelDebug
+	case slog.LevelInfo:
+		logLevel = pterm.LogLevelInfo
+	case slog.LevelError:
+		logLevel = pterm.LogLevelError
+	}
+	ptermLog := &pterm.Logger{
+		Formatter:  pterm.LogFormatterColorful,
+		Writer:     out,
+		Level:      logLevel,
+		ShowTime:   true,
+		TimeFormat: "2006-01-02 15:04:05",
+		MaxWidth:   180,
+		KeyStyles: map[string]pterm.Style{
+			"error":  *pterm.NewStyle(pterm.FgRed, pterm.Bold),
+			"err":    *pterm.NewStyle(pterm.FgRed, pterm.Bold),
+			"caller": *pterm.NewStyle(pterm.FgGray, pterm.Bold),
+		},
+	}
+	return slog.New(pterm.NewSlogHandler(ptermLog))
+}
+
+// logConfiguration logs the decisions made about how wiretap runs, once everything has been loaded.
+func logConfiguration(config *shared.WiretapConfiguration) {
+	mode := "proxy"
+	if config.MockMode {
+		mode = "mock"
+	}
+	config.Logger.Info("[wiretap] configuration",
+		"mode", mode,
+		"spec", config.Contract,
+		"redirectURL", config.RedirectURL,
+		"port", config.Port,
+		"monitorPort", config.MonitorPort,
+		"webSocketPort", config.WebSocketPort,
+		"validationMode", config.ValidationMode,
+		"hardErrors", config.HardErrors,
+		"strictRequests", config.StrictRequests,
+		"strictResponses", config.StrictResponses,
+		"pathConfigurations", len(config.PathConfigurations),
+		"hosts", len(config.Hosts),
+		"streamReport", config.StreamReport,
+		"ci", config.CI)
+}
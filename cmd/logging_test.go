@@ -0,0 +1,103 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		debug bool
+		want  slog.Level
+		err   bool
+	}{
+		{"", false, slog.LevelWarn, false},
+		{"", true, slog.LevelDebug, false},
+		{"debug", false, slog.LevelDebug, false},
+		{" INFO ", false, slog.LevelInfo, false},
+		{"warning", false, slog.LevelWarn, false},
+		{"error", true, slog.LevelError, false},
+		{"loud", false, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			level, err := parseLogLevel(tt.level, tt.debug)
+			if tt.err {
+				assert.EqualError(t, err, "unknown log level 'loud', use debug, info, warn or error")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, level)
+		})
+	}
+}
+
+// logEverything logs an event at every level.
+func logEverything(logger *slog.Logger) {
+	logger.Debug("[wiretap] debugging", "path", "/pets")
+	logger.Info("[wiretap] informing", "path", "/pets")
+	logger.Warn("[wiretap] warning", "path", "/pets")
+	logger.Error("[wiretap] failing", "path", "/pets")
+}
+
+func TestNewLogger_Level(t *testing.T) {
+	tests := []struct {
+		level  slog.Level
+		logged []string
+	}{
+		{slog.LevelDebug, []string{"debugging", "informing", "warning", "failing"}},
+		{slog.LevelInfo, []string{"informing", "warning", "failing"}},
+		{slog.LevelWarn, []string{"warning", "failing"}},
+		{slog.LevelError, []string{"failing"}},
+	}
+	all := []string{"debugging", "informing", "warning", "failing"}
+	for _, format := range []string{shared.LogFormatText, shared.LogFormatJSON} {
+		for _, tt := range tests {
+			t.Run(format+" "+tt.level.String(), func(t *testing.T) {
+				var out bytes.Buffer
+				logEverything(newLogger(&out, format, tt.level))
+				for _, message := range all {
+					if slices.Contains(tt.logged, message) {
+						assert.Contains(t, out.String(), message)
+					} else {
+						assert.NotContains(t, out.String(), message)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestNewLogger_JSON(t *testing.T) {
+	var out bytes.Buffer
+	logEverything(newLogger(&out, "JSON", slog.LevelDebug))
+
+	// every event is a line of JSON, with the attributes as fields.
+	var events []map[string]any
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var event map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event), scanner.Text())
+		events = append(events, event)
+	}
+	require.Len(t, events, 4)
+	for i, level := range []string{"DEBUG", "INFO", "WARN", "ERROR"} {
+		assert.Equal(t, level, events[i]["level"])
+		assert.Equal(t, "/pets", events[i]["path"])
+		assert.NotEmpty(t, events[i]["time"])
+	}
+	assert.Equal(t, "[wiretap] failing", events[3]["msg"])
+}
@@ -0,0 +1,69 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/ranch/plank/pkg/server"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// requireMonitorAuth only lets requests through to the monitor that are authorized. Browsers that authenticate are
+// given a session cookie, so the websocket the monitor connects to is authorized too. The transactions API has a
+// token of its own.
+func requireMonitorAuth(wiretapConfig *shared.WiretapConfiguration, next http.Handler) http.Handler {
+	auth := wiretapConfig.MonitorAuth
+	if !auth.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !auth.Authorized(r) {
+			if auth.BasicAuth() {
+				w.Header().Set("WWW-Authenticate", `Basic realm="wiretap monitor", charset="UTF-8"`)
+			}
+			http.Error(w, "wiretap monitor: authentication required", http.StatusUnauthorized)
+			return
+		}
+		if cookie, err := r.Cookie(shared.MonitorSessionCookie); err != nil || cookie.Value != auth.Session() {
+			http.SetCookie(w, &http.Cookie{
+				Name:     shared.MonitorSessionCookie,
+				Value:    auth.Session(),
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   wiretapConfig.MonitorTLS(),
+				SameSite: http.SameSiteStrictMode,
+			})
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// protectMonitorConnections refuses websocket handshakes to the monitor that aren't authorized.
+func protectMonitorConnections(wiretapConfig *shared.WiretapConfiguration, platformServer server.PlatformServer, endpoint string) {
+	if !wiretapConfig.MonitorAuth.Enabled() {
+		return
+	}
+	platformServer.GetRouter().Use(guardMonitorConnections(wiretapConfig.MonitorAuth, endpoint))
+}
+
+// guardMonitorConnections only lets authorized requests to the websocket endpoint through, everything else is left
+// alone.
+func guardMonitorConnections(auth *shared.WiretapMonitorAuth, endpoint string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == endpoint && !auth.Authorized(r) {
+				http.Error(w, "wiretap monitor: authentication required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// monitorHandler is the monitor as it's served: its own endpoints, and the API behind the API token, all behind the
+// monitor auth.
+func monitorHandler(config *shared.WiretapConfiguration) http.Handler {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	mux := http.NewServeMux()
+	mux.Handle("/", ok)
+	mux.Handle("/api/transactions", requireAPIToken(config.APIToken, ok))
+	return requireMonitorAuth(config, mux)
+}
+
+func TestRequireMonitorAuth(t *testing.T) {
+	auth := &shared.WiretapMonitorAuth{Token: "monitor-token", Username: "pip", Password: "squeak"}
+	config := &shared.WiretapConfiguration{MonitorAuth: auth, APIToken: "api-token"}
+	stale := (&shared.WiretapMonitorAuth{Token: "monitor-token", Username: "pip", Password: "old"}).Session()
+
+	tests := []struct {
+		name      string
+		path      string
+		prepare   func(r *http.Request)
+		status    int
+		challenge bool
+		cookie    bool
+	}{
+		{"no credentials", "/", func(r *http.Request) {}, http.StatusUnauthorized, true, false},
+		{"wrong token", "/", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") },
+			http.StatusUnauthorized, true, false},
+		{"wrong token as a query parameter", "/?token=nope", func(r *http.Request) {},
+			http.StatusUnauthorized, true, false},
+		{"wrong username", "/", func(r *http.Request) { r.SetBasicAuth("pop", "squeak") },
+			http.StatusUnauthorized, true, false},
+		{"wrong password", "/", func(r *http.Request) { r.SetBasicAuth("pip", "squawk") },
+			http.StatusUnauthorized, true, false},
+		{"forged cookie", "/", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: shared.MonitorSessionCookie, Value: "0123456789abcdef"})
+		}, http.StatusUnauthorized, true, false},
+		{"stale cookie", "/", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: shared.MonitorSessionCookie, Value: stale})
+		}, http.StatusUnauthorized, true, false},
+		{"token", "/", func(r *http.Request) { r.Header.Set("Authorization", "Bearer monitor-token") },
+			http.StatusOK, false, true},
+		{"token as a query parameter", "/?token=monitor-token", func(r *http.Request) {},
+			http.StatusOK, false, true},
+		{"basic auth", "/", func(r *http.Request) { r.SetBasicAuth("pip", "squeak") }, http.StatusOK, false, true},
+		{"valid cookie", "/", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: shared.MonitorSessionCookie, Value: auth.Session()})
+		}, http.StatusOK, false, false},
+		{"api without a bearer token", "/api/transactions", func(r *http.Request) {},
+			http.StatusUnauthorized, false, false},
+		{"api with monitor credentials", "/api/transactions", func(r *http.Request) {
+			r.SetBasicAuth("pip", "squeak")
+			r.AddCookie(&http.Cookie{Name: shared.MonitorSessionCookie, Value: auth.Session()})
+		}, http.StatusUnauthorized, false, false},
+		{"api with the monitor token", "/api/transactions",
+			func(r *http.Request) { r.Header.Set("Authorization", "Bearer monitor-token") },
+			http.StatusUnauthorized, false, false},
+		{"api with the api token", "/api/transactions",
+			func(r *http.Request) { r.Header.Set("Authorization", "Bearer api-token") },
+			http.StatusOK, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			tt.prepare(r)
+			w := httptest.NewRecorder()
+			monitorHandler(config).ServeHTTP(w, r)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.challenge {
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+			}
+			var session *http.Cookie
+			for _, c := range w.Result().Cookies() {
+				if c.Name == shared.MonitorSessionCookie {
+					session = c
+				}
+			}
+			if !tt.cookie {
+				assert.Nil(t, session)
+				return
+			}
+			require.NotNil(t, session)
+			assert.Equal(t, auth.Session(), session.Value)
+			assert.True(t, session.HttpOnly)
+			assert.Equal(t, http.SameSiteStrictMode, session.SameSite)
+		})
+	}
+}
+
+func TestRequireMonitorAuth_Disabled(t *testing.T) {
+	w := httptest.NewRecorder()
+	monitorHandler(&shared.WiretapConfiguration{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+}
+
+func TestRequireMonitorAuth_TokenOnly(t *testing.T) {
+	config := &shared.WiretapConfiguration{MonitorAuth: &shared.WiretapMonitorAuth{Token: "monitor-token"}}
+
+	// basic auth isn't configured, so browsers aren't asked for it.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("", "monitor-token")
+	monitorHandler(config).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+}
+
+func TestGuardMonitorConnections(t *testing.T) {
+	auth := &shared.WiretapMonitorAuth{Token: "monitor-token", Username: "pip", Password: "squeak"}
+	guard := guardMonitorConnections(auth, "/ranch")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusSwitchingProtocols) }))
+
+	upgrade := func(path string, prepare func(r *http.Request)) int {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		prepare(r)
+		w := httptest.NewRecorder()
+		guard.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		prepare func(r *http.Request)
+		status  int
+	}{
+		{"no credentials", "/ranch", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"wrong token", "/ranch?token=nope", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"wrong basic credentials", "/ranch", func(r *http.Request) { r.SetBasicAuth("pip", "squawk") },
+			http.StatusUnauthorized},
+		{"forged cookie", "/ranch", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: shared.MonitorSessionCookie, Value: "forged"})
+		}, http.StatusUnauthorized},
+		{"token", "/ranch?token=monitor-token", func(r *http.Request) {}, http.StatusSwitchingProtocols},
+		{"bearer token", "/ranch", func(r *http.Request) { r.Header.Set("Authorization", "Bearer monitor-token") },
+			http.StatusSwitchingProtocols},
+		{"basic auth", "/ranch", func(r *http.Request) { r.SetBasicAuth("pip", "squeak") },
+			http.StatusSwitchingProtocols},
+		{"valid cookie", "/ranch", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: shared.MonitorSessionCookie, Value: auth.Session()})
+		}, http.StatusSwitchingProtocols},
+		{"other endpoints are left alone", "/elsewhere", func(r *http.Request) {}, http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.status, upgrade(tt.path, tt.prepare))
+		})
+	}
+}
+
+func TestRequireAPIToken(t *testing.T) {
+	handler := requireAPIToken("api-token",
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"not a bearer token", "api-token", http.StatusUnauthorized},
+		{"basic auth", "Basic YXBpLXRva2Vu", http.StatusUnauthorized},
+		{"token", "Bearer api-token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/transactions", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusUnauthorized {
+				assert.Equal(t, `Bearer realm="wiretap"`, w.Header().Get("WWW-Authenticate"))
+				assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"compress/flate"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/gorilla/websocket"
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/ranch/plank/pkg/server"
+	"github.com/pb33f/ranch/stompserver"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// monitorStreamListener accepts the websocket connections monitors stream transactions over, for the ranch broker.
+// Ranch brings its own listener, but it can't negotiate compression, and transactions with large bodies make the
+// monitor unusable over slow links without it.
+type monitorStreamListener struct {
+	upgrader    websocket.Upgrader
+	connections chan *monitorStreamConnection
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+// monitorStreamConnection is a monitor's websocket, STOMP frames are read from and written to it.
+type monitorStreamConnection struct {
+	conn *websocket.Conn
+}
+
+func (c *monitorStreamConnection) ReadFrame() (*frame.Frame, error) {
+	_, r, err := c.conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+	return frame.NewReader(r).Read()
+}
+
+func (c *monitorStreamConnection) WriteFrame(f *frame.Frame) error {
+	w, err := c.conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	if err = frame.NewWriter(w).Write(f); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (c *monitorStreamConnection) SetReadDeadline(t time.Time) {
+	_ = c.conn.SetReadDeadline(t)
+}
+
+func (c *monitorStreamConnection) Close() error {
+	return c.conn.Close()
+}
+
+func (l *monitorStreamListener) Accept() (stompserver.RawConnection, error) {
+	select {
+	case conn := <-l.connections:
+		return conn, nil
+	case <-l.closed:
+		return nil, errors.New("monitor stream listener is closed")
+	}
+}
+
+func (l *monitorStreamListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+// ServeHTTP upgrades a monitor's connection to a websocket, compressed (permessage-deflate) if the monitor asks.
+func (l *monitorStreamListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := l.upgrader
+	upgrader.Subprotocols = websocket.Subprotocols(r)
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // the upgrader has already responded with the error.
+	}
+	_ = conn.SetCompressionLevel(flate.BestSpeed)
+	select {
+	case l.connections <- &monitorStreamConnection{conn: conn}:
+	case <-l.closed:
+		_ = conn.Close()
+	}
+}
+
+// bindPlatformServer binds the ranch server (that monitors stream from) to the configured bind address. Ranch only
+// has a port, and listens on every interface, but its server is set up before it starts, so the address is changed.
+func bindPlatformServer(wiretapConfig *shared.WiretapConfiguration, platformServer server.PlatformServer) {
+	if wiretapConfig.BindAddress == "" {
+		return
+	}
+	v := reflect.ValueOf(platformServer)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	field := v.FieldByName("HttpServer")
+	if !field.IsValid() || !field.CanInterface() {
+		wiretapConfig.Logger.Warn("[wiretap] unable to bind the monitor websocket, it listens on every interface")
+		return
+	}
+	if httpServer, ok := field.Interface().(*http.Server); ok && httpServer != nil {
+		httpServer.Addr = wiretapConfig.ListenAddress(wiretapConfig.WebSocketPort)
+	}
+}
+
+// serveMonitorStream starts the ranch broker monitors connect to, at the fabric endpoint.
+func serveMonitorStream(wiretapConfig *shared.WiretapConfiguration, platformServer server.PlatformServer,
+	fabricConfig *server.FabricBrokerConfig) {
+	listener := &monitorStreamListener{
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:    1024,
+			WriteBufferSize:   1024,
+			EnableCompression: true,
+			CheckOrigin:       func(r *http.Request) bool { return true },
+		},
+		connections: make(chan *monitorStreamConnection),
+		closed:      make(chan struct{}),
+	}
+	platformServer.GetRouter().Handle(fabricConfig.FabricEndpoint, listener)
+	go func() {
+		// blocks until the broker is stopped.
+		if err := bus.GetBus().StartFabricEndpoint(listener, *fabricConfig.EndpointConfig); err != nil {
+			wiretapConfig.Logger.Error("[wiretap] unable to start the monitor stream", "error", err.Error())
+		}
+	}()
+}
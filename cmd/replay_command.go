@@ -0,0 +1,162 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/har"
+	"github.com/pb33f/wiretap/replay"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/store"
+	"github.com/pb33f/wiretap/validation"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	SilenceUsage: true,
+	Use:          "replay <session-file>",
+	Short:        "Re-send captured requests from a HAR file or transaction store against a target.",
+	Long: `Re-send captured requests from a HAR file, or a transaction store (see --store), against a target at the
+pace they were captured (or faster). Requests and responses are validated against the OpenAPI specification, and
+a fresh violation report is saved. Useful for regression-testing a new backend build with real traffic.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		spec, _ := cmd.Flags().GetString("spec")
+		base, _ := cmd.Flags().GetString("base")
+		target, _ := cmd.Flags().GetString("url")
+		speed, _ := cmd.Flags().GetFloat64("speed")
+		reportFilename, _ := cmd.Flags().GetString("report-filename")
+
+		targetURL, err := url.Parse(target)
+		if err != nil || targetURL.Scheme == "" || targetURL.Host == "" {
+			return fmt.Errorf("a target URL is required to replay requests against, e.g. http://localhost:8080 (use --url)")
+		}
+
+		requests, err := loadSession(args[0])
+		if err != nil {
+			pterm.Error.Printf("Cannot read session '%s': %s\n", args[0], err.Error())
+			return err
+		}
+		if len(requests) == 0 {
+			return fmt.Errorf("there are no requests in session '%s' to replay", args[0])
+		}
+
+		replayer := &replay.Replayer{Target: targetURL, Speed: speed}
+		if spec != "" {
+			doc, dErr := loadOpenAPISpec(spec, base)
+			if dErr != nil {
+				pterm.Error.Printf("Cannot load specification: %s\n", dErr.Error())
+				return dErr
+			}
+			model, errs := doc.BuildV3Model()
+			if model == nil {
+				pterm.Error.Printf("Cannot build OpenAPI model: %s\n", errors.Join(errs...))
+				return errors.Join(errs...)
+			}
+			replayer.Validator = validation.NewHttpValidator(&model.Model)
+		}
+
+		pace := "as fast as possible"
+		if speed > 0 {
+			pace = fmt.Sprintf("at %gx the captured pace", speed)
+		}
+		pterm.Info.Printf("Replaying %d %s against %s, %s\n", len(requests),
+			shared.Pluralize(len(requests), "request", "requests"), pterm.LightCyan(targetURL.String()), pace)
+		pterm.Println()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		violations := []*shared.Violation{}
+		codes := make(map[int]int)
+		failures, sent := 0, 0
+		replayer.Run(ctx, requests, func(result *replay.Result) {
+			sent++
+			if result.Err != nil {
+				pterm.Error.Printf("[%d] %s %s failed: %s\n", sent, result.Request.Method, result.URL, result.Err.Error())
+				failures++
+				return
+			}
+			codes[result.StatusCode]++
+			violations = append(violations, configModel.ClassifyViolations(result.Violations, nil)...)
+
+			code := fmt.Sprint(result.StatusCode)
+			if result.StatusCode >= 400 {
+				code = pterm.LightRed(code)
+			} else {
+				code = pterm.LightGreen(code)
+			}
+			line := fmt.Sprintf("[%d] %s %s --> %s (%s)", sent, result.Request.Method, result.URL, code,
+				result.Duration.Round(100*time.Microsecond))
+			if len(result.Violations) > 0 {
+				line += pterm.LightRed(fmt.Sprintf(", %d %s", len(result.Violations),
+					shared.Pluralize(len(result.Violations), "violation", "violations")))
+			}
+			pterm.Println(line)
+		})
+
+		pterm.Println()
+		var summary []string
+		var sorted []int
+		for c := range codes {
+			sorted = append(sorted, c)
+		}
+		sort.Ints(sorted)
+		for _, c := range sorted {
+			summary = append(summary, fmt.Sprintf("%d x %d", codes[c], c))
+		}
+		if failures > 0 {
+			summary = append(summary, fmt.Sprintf("%d failed", failures))
+		}
+		pterm.Success.Printf("Replayed %d %s: %s\n", sent, shared.Pluralize(sent, "request", "requests"),
+			strings.Join(summary, ", "))
+
+		if replayer.Validator != nil {
+			if err = writeViolationReport(reportFilename, violations); err != nil {
+				return err
+			}
+			pterm.Printf("Found %d %s, report saved to: %s\n", len(violations),
+				shared.Pluralize(len(violations), "violation", "violations"), pterm.LightMagenta(reportFilename))
+		}
+		return nil
+	},
+}
+
+// loadSession reads the requests captured in a HAR (or NDJSON-HAR) file, or a transaction store.
+func loadSession(filename string) ([]*replay.Request, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if harFile, hErr := har.BuildHAR(data); hErr == nil && len(harFile.Log.Entries) > 0 {
+		return replay.FromHAR(harFile), nil
+	}
+	if requests, ok := replay.FromNDJSON(data); ok {
+		return requests, nil
+	}
+	s, err := store.OpenReadOnly(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	transactions, err := daemon.ReadTransactions(s)
+	if err != nil {
+		return nil, err
+	}
+	return replay.FromTransactions(transactions), nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/store"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var reportCmd = &cobra.Command{
+	SilenceUsage: true,
+	Use:          "report",
+	Short:        "Regenerate the violation report from a transaction store.",
+	Long: `Regenerate the violation report from the transactions kept in a transaction store (see --store), long after
+wiretap has stopped. The report has the same format as a streamed report, and can be narrowed to violations of some
+severities, or requests to some paths.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		storeFile, _ := cmd.Flags().GetString("store")
+		reportFilename, _ := cmd.Flags().GetString("report-filename")
+		severities, _ := cmd.Flags().GetStringSlice("severity")
+		paths, _ := cmd.Flags().GetStringArray("path")
+
+		if storeFile == "" {
+			return errors.New("a transaction store is required to generate a report (use --store)")
+		}
+		s, err := store.OpenReadOnly(storeFile)
+		if err != nil {
+			pterm.Error.Println(err.Error())
+			return err
+		}
+		defer s.Close()
+
+		transactions, err := daemon.ReadTransactions(s)
+		if err != nil {
+			pterm.Error.Printf("Cannot read transactions: %s\n", err.Error())
+			return err
+		}
+		filter := &shared.WiretapReportFilter{Severities: severities, Paths: paths}
+		if err = filter.Compile(); err != nil {
+			pterm.Error.Println(err.Error())
+			return err
+		}
+		transactions = daemon.FilterTransactions(transactions, filter)
+		violations := []*shared.Violation{}
+		for _, transaction := range transactions {
+			violations = append(violations, transaction.RequestValidation...)
+			violations = append(violations, transaction.ResponseValidation...)
+		}
+
+		if err = writeViolationReport(reportFilename, violations); err != nil {
+			return err
+		}
+		pterm.Success.Printf("Report of %d %s from %d %s saved to: %s\n",
+			len(violations), shared.Pluralize(len(violations), "violation", "violations"),
+			len(transactions), shared.Pluralize(len(transactions), "transaction", "transactions"),
+			pterm.LightMagenta(reportFilename))
+		return nil
+	},
+}
+
+// writeViolationReport saves violations in the same format as a streamed report.
+func writeViolationReport(filename string, violations []*shared.Violation) error {
+	if violations == nil {
+		violations = []*shared.Violation{}
+	}
+	b, _ := json.MarshalIndent(violations, "", "  ")
+	if err := os.WriteFile(filename, b, 0644); err != nil {
+		pterm.Error.Printf("Unable to write report: %s\n", err.Error())
+		return err
+	}
+	return nil
+}
@@ -4,21 +4,33 @@
 package cmd
 
 import (
+	"crypto/tls"
 	"embed"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/pb33f/harhar"
 	"github.com/pb33f/libopenapi"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/audit"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/grpc"
 	"github.com/pb33f/wiretap/har"
+	"github.com/pb33f/wiretap/metrics"
+	"github.com/pb33f/wiretap/mock"
+	"github.com/pb33f/wiretap/redact"
 	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/tail"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"gopkg.in/yaml.v3"
-	"log/slog"
+	"net"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
 var (
@@ -34,6 +46,10 @@ var (
 		Long:         `wiretap is a tool for detecting API compliance against an OpenAPI contract, by sniffing network traffic.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 
+			// with JSON logs, stdout is kept for log events and the terminal output goes to stderr.
+			if format, _ := cmd.Flags().GetString("log-format"); strings.EqualFold(format, shared.LogFormatJSON) {
+				pterm.SetDefaultOutput(os.Stderr)
+			}
 			PrintBanner()
 
 			configFlag, _ := cmd.Flags().GetString("config")
@@ -73,19 +89,93 @@ var (
 			if keyFlag != "" {
 				certKey = keyFlag
 			}
+			monitorCert, _ := cmd.Flags().GetString("monitor-cert")
+			monitorKey, _ := cmd.Flags().GetString("monitor-key")
 			base, _ := cmd.Flags().GetString("base")
 			reportFilename, _ := cmd.Flags().GetString("report-filename")
 
 			harFlag, _ := cmd.Flags().GetString("har")
 			harValidate, _ := cmd.Flags().GetBool("har-validate")
 			harWhiteList, _ := cmd.Flags().GetStringArray("har-allow")
+			harPlayback, _ := cmd.Flags().GetBool("har-playback")
+			harRecord, _ := cmd.Flags().GetString("har-record")
+			harRecordFormat, _ := cmd.Flags().GetString("har-record-format")
+			harRecordMaxSize, _ := cmd.Flags().GetInt("har-record-max-size")
+			harRecordRotate, _ := cmd.Flags().GetInt("har-record-rotate")
+			transactionStore, _ := cmd.Flags().GetString("store")
+			accessLog, _ := cmd.Flags().GetString("access-log")
+			auditLog, _ := cmd.Flags().GetString("audit-log")
+			captureSampleRate, _ := cmd.Flags().GetFloat64("capture-sample-rate")
+			capturePaused, _ := cmd.Flags().GetBool("capture-paused")
+			maxTransactions, _ := cmd.Flags().GetInt("max-transactions")
+			apiToken, _ := cmd.Flags().GetString("api-token")
+			monitorToken, _ := cmd.Flags().GetString("monitor-token")
+			monitorUser, _ := cmd.Flags().GetString("monitor-user")
+			monitorPassword, _ := cmd.Flags().GetString("monitor-password")
+			maxCaptureMemory, _ := cmd.Flags().GetInt("max-capture-memory")
+			monitorMaxBody, _ := cmd.Flags().GetInt("monitor-max-body")
+			intercepts, _ := cmd.Flags().GetStringArray("intercept")
+			interceptTimeout, _ := cmd.Flags().GetInt("intercept-timeout")
+			junitReport, _ := cmd.Flags().GetBool("junit-report")
+			sarifReport, _ := cmd.Flags().GetBool("sarif-report")
+			reportRotation, _ := cmd.Flags().GetString("report-rotate")
+			reportRetention, _ := cmd.Flags().GetInt("report-retain")
 
 			debug, _ := cmd.Flags().GetBool("debug")
+			logFormat, _ := cmd.Flags().GetString("log-format")
+			logLevelFlag, _ := cmd.Flags().GetString("log-level")
 			mockMode, _ = cmd.Flags().GetBool("mock-mode")
+			mockStateful, _ := cmd.Flags().GetBool("mock-stateful")
+			mockSeed, _ := cmd.Flags().GetInt64("mock-seed")
+			mockErrorRate, _ := cmd.Flags().GetFloat64("mock-error-rate")
+			mockCallbacks, _ := cmd.Flags().GetBool("mock-callbacks")
+			mockUnionStrategy, _ := cmd.Flags().GetString("mock-union-strategy")
+			mockPagination, _ := cmd.Flags().GetBool("mock-pagination")
+			mockOverrides, _ := cmd.Flags().GetString("mock-overrides")
+			mockCallbackDelay, _ := cmd.Flags().GetInt("mock-callback-delay")
+			mockFallback, _ := cmd.Flags().GetBool("mock-fallback")
+			mockValidation, _ := cmd.Flags().GetString("mock-validation")
+			asyncAPI, _ := cmd.Flags().GetString("asyncapi")
+			asyncAPIInterval, _ := cmd.Flags().GetInt("asyncapi-interval")
+			graphQL, _ := cmd.Flags().GetString("graphql")
+			graphQLPath, _ := cmd.Flags().GetString("graphql-path")
+			gRPC, _ := cmd.Flags().GetString("grpc")
+			gRPCValidate, _ := cmd.Flags().GetBool("grpc-validate")
+			gRPCTranscode, _ := cmd.Flags().GetBool("grpc-transcode")
 			hardError, _ = cmd.Flags().GetBool("hard-validation")
 			hardErrorCode, _ = cmd.Flags().GetInt("hard-validation-code")
 			hardErrorReturnCode, _ = cmd.Flags().GetInt("hard-validation-return-code")
+			strictRequests, _ := cmd.Flags().GetBool("strict-requests")
+			strictResponses, _ := cmd.Flags().GetBool("strict-responses")
+			strictResponseCode, _ := cmd.Flags().GetInt("strict-response-code")
+			noValidationCache, _ := cmd.Flags().GetBool("no-validation-cache")
+			validatorHooks, _ := cmd.Flags().GetStringArray("validator-hook")
+			validationMode, _ := cmd.Flags().GetString("validation-mode")
+			validationWorkers, _ := cmd.Flags().GetInt("validation-workers")
 			streamReport, _ := cmd.Flags().GetBool("stream-report")
+			violationWindow, _ := cmd.Flags().GetInt("violation-window")
+			reportViolationsOnly, _ := cmd.Flags().GetBool("report-violations-only")
+			reportSeverities, _ := cmd.Flags().GetStringSlice("report-severity")
+			reportPaths, _ := cmd.Flags().GetStringArray("report-path")
+			tailFlag, _ := cmd.Flags().GetBool("tail")
+			tailFilters, _ := cmd.Flags().GetStringArray("tail-filter")
+			watchSpec, _ := cmd.Flags().GetBool("watch-spec")
+			specPollInterval, _ := cmd.Flags().GetInt("spec-poll-interval")
+			ciMode, _ := cmd.Flags().GetBool("ci")
+			ciCommand, _ := cmd.Flags().GetString("ci-command")
+			ciThresholds, _ := cmd.Flags().GetStringArray("ci-threshold")
+			ciSummary, _ := cmd.Flags().GetString("ci-summary")
+			candidate, _ := cmd.Flags().GetString("candidate")
+			serverVariables, _ := cmd.Flags().GetStringArray("server-variable")
+			overlays, _ := cmd.Flags().GetStringArray("overlay")
+			webhookPaths, _ := cmd.Flags().GetStringArray("webhook-path")
+			strictParameters, _ := cmd.Flags().GetBool("strict-parameters")
+			upstreamH2C, _ := cmd.Flags().GetBool("upstream-h2c")
+			upstreamProxy, _ := cmd.Flags().GetString("upstream-proxy")
+			clientCert, _ := cmd.Flags().GetString("client-cert")
+			clientKey, _ := cmd.Flags().GetString("client-key")
+			clientKeyPassphraseEnv, _ := cmd.Flags().GetString("client-key-passphrase-env")
+			allowHeaders, _ := cmd.Flags().GetStringArray("allow-header")
 
 			portFlag, _ := cmd.Flags().GetString("port")
 			if portFlag != "" {
@@ -99,6 +189,15 @@ var (
 				spec = specFlag
 			}
 
+			bindAddress, _ := cmd.Flags().GetString("bind-address")
+			metricsPort, _ := cmd.Flags().GetString("metrics-port")
+			adminPort, _ := cmd.Flags().GetString("admin-port")
+			adminAddress, _ := cmd.Flags().GetString("admin-address")
+			otlpEndpoint, _ := cmd.Flags().GetString("otlp-endpoint")
+			statsdAddress, _ := cmd.Flags().GetString("statsd")
+			statsdPrefix, _ := cmd.Flags().GetString("statsd-prefix")
+			statsdTags, _ := cmd.Flags().GetString("statsd-tags")
+			statsdFormat, _ := cmd.Flags().GetString("statsd-format")
 			monitorPortFlag, _ := cmd.Flags().GetString("monitor-port")
 			if monitorPortFlag != "" {
 				monitorPort = monitorPortFlag
@@ -181,6 +280,57 @@ var (
 						config.MockMode = true
 					}
 				}
+				if mockStateful {
+					config.MockModeStateful = true
+				}
+				if mockSeed != 0 {
+					config.MockSeed = mockSeed
+				}
+				if mockErrorRate > 0 {
+					config.MockErrorRate = mockErrorRate
+				}
+				if mockCallbacks {
+					config.MockCallbacks = true
+				}
+				if mockUnionStrategy != "" {
+					config.MockUnionStrategy = mockUnionStrategy
+				}
+				if mockPagination {
+					config.MockPagination = true
+				}
+				if mockOverrides != "" {
+					config.MockOverrides = mockOverrides
+				}
+				if mockCallbackDelay > 0 {
+					config.MockCallbackDelay = mockCallbackDelay
+				}
+				if mockFallback {
+					config.MockFallback = true
+				}
+				if mockValidation != "" {
+					config.MockValidation = mockValidation
+				}
+				if asyncAPI != "" {
+					config.AsyncAPI = asyncAPI
+				}
+				if asyncAPIInterval > 0 {
+					config.AsyncAPIInterval = asyncAPIInterval
+				}
+				if graphQL != "" {
+					config.GraphQL = graphQL
+				}
+				if graphQLPath != "" {
+					config.GraphQLPath = graphQLPath
+				}
+				if gRPC != "" {
+					config.GRPC = gRPC
+				}
+				if gRPCValidate {
+					config.GRPCValidate = true
+				}
+				if gRPCTranscode {
+					config.GRPCTranscode = true
+				}
 				if streamReport {
 					if !config.StreamReport {
 						config.StreamReport = true
@@ -203,6 +353,9 @@ var (
 				if len(harWhiteList) > 0 {
 					config.HARPathAllowList = harWhiteList
 				}
+				if harPlayback {
+					config.HARPlayback = true
+				}
 
 			} else {
 
@@ -211,6 +364,57 @@ var (
 				if mockMode {
 					config.MockMode = true
 				}
+				if mockStateful {
+					config.MockModeStateful = true
+				}
+				if mockSeed != 0 {
+					config.MockSeed = mockSeed
+				}
+				if mockErrorRate > 0 {
+					config.MockErrorRate = mockErrorRate
+				}
+				if mockCallbacks {
+					config.MockCallbacks = true
+				}
+				if mockUnionStrategy != "" {
+					config.MockUnionStrategy = mockUnionStrategy
+				}
+				if mockPagination {
+					config.MockPagination = true
+				}
+				if mockOverrides != "" {
+					config.MockOverrides = mockOverrides
+				}
+				if mockCallbackDelay > 0 {
+					config.MockCallbackDelay = mockCallbackDelay
+				}
+				if mockFallback {
+					config.MockFallback = true
+				}
+				if mockValidation != "" {
+					config.MockValidation = mockValidation
+				}
+				if asyncAPI != "" {
+					config.AsyncAPI = asyncAPI
+				}
+				if asyncAPIInterval > 0 {
+					config.AsyncAPIInterval = asyncAPIInterval
+				}
+				if graphQL != "" {
+					config.GraphQL = graphQL
+				}
+				if graphQLPath != "" {
+					config.GraphQLPath = graphQLPath
+				}
+				if gRPC != "" {
+					config.GRPC = gRPC
+				}
+				if gRPCValidate {
+					config.GRPCValidate = true
+				}
+				if gRPCTranscode {
+					config.GRPCTranscode = true
+				}
 				if streamReport {
 					config.StreamReport = true
 				}
@@ -221,9 +425,10 @@ var (
 				config.HAR = harFlag
 				config.HARValidate = harValidate
 				config.HARPathAllowList = harWhiteList
+				config.HARPlayback = harPlayback
 			}
 
-			if spec == "" {
+			if spec == "" && len(config.Contracts) == 0 && config.GraphQL == "" && config.GRPC == "" {
 				pterm.Println()
 				pterm.Warning.Println("No OpenAPI specification provided. " +
 					"Please provide a path to an OpenAPI specification using the --spec or -s flags. \n" +
@@ -241,7 +446,7 @@ var (
 				return nil
 			}
 
-			if !mockMode && redirectURL == "" && harFlag == "" {
+			if !mockMode && redirectURL == "" && harFlag == "" && len(config.Hosts) == 0 {
 				pterm.Println()
 				pterm.Error.Println("No redirect URL provided. " +
 					"Please provide a URL to redirect API traffic to using the --url or -u flags.")
@@ -290,6 +495,64 @@ var (
 			if config.WebSocketPort == "" {
 				config.WebSocketPort = wsPort
 			}
+			if metricsPort != "" {
+				config.MetricsPort = metricsPort
+			}
+			if adminPort != "" {
+				config.AdminPort = adminPort
+			}
+			if adminAddress != "" {
+				config.AdminAddress = adminAddress
+			}
+			if config.AdminAddress == "" {
+				config.AdminAddress = "127.0.0.1"
+			}
+			if bindAddress != "" {
+				config.BindAddress = bindAddress
+			}
+			if otlpEndpoint != "" {
+				config.OTLPEndpoint = otlpEndpoint
+			}
+			if config.OTLPEndpoint == "" {
+				config.OTLPEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+			}
+			if statsdAddress != "" {
+				config.StatsDAddress = statsdAddress
+			}
+			if statsdPrefix != "" {
+				config.StatsDPrefix = statsdPrefix
+			}
+			if statsdTags != "" {
+				config.StatsDTags = nil
+				for _, tag := range strings.Split(statsdTags, ",") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						config.StatsDTags = append(config.StatsDTags, tag)
+					}
+				}
+			}
+			if statsdFormat != "" {
+				config.StatsDFormat = statsdFormat
+			}
+			if logFormat != "" {
+				config.LogFormat = logFormat
+			}
+			if logLevelFlag != "" {
+				config.LogLevel = logLevelFlag
+			}
+			switch strings.ToLower(config.LogFormat) {
+			case "", shared.LogFormatText:
+			case shared.LogFormatJSON:
+				// stdout is for log events, everything for the terminal goes to stderr.
+				pterm.SetDefaultOutput(os.Stderr)
+			default:
+				pterm.Error.Printf("Unknown log format '%s', use 'text' or 'json'\n", config.LogFormat)
+				return fmt.Errorf("unknown log format '%s'", config.LogFormat)
+			}
+			logLevel, lErr := parseLogLevel(config.LogLevel, debug)
+			if lErr != nil {
+				pterm.Error.Println(lErr.Error())
+				return lErr
+			}
 			if config.WebSocketHost == "" {
 				config.WebSocketHost = wsHost
 			}
@@ -304,6 +567,223 @@ var (
 			if config.HardErrors || hardError {
 				config.HardErrors = true
 			}
+			if config.StrictRequests || strictRequests {
+				config.StrictRequests = true
+			}
+			if config.StrictResponses || strictResponses {
+				config.StrictResponses = true
+			}
+			if config.StrictResponses && config.StrictResponseCode <= 0 {
+				config.StrictResponseCode = strictResponseCode
+			}
+			if validationMode != "" {
+				config.ValidationMode = validationMode
+			}
+			if validationWorkers > 0 {
+				config.ValidationWorkers = validationWorkers
+			}
+			if len(validatorHooks) > 0 {
+				config.ValidatorHooks = append(config.ValidatorHooks, validatorHooks...)
+			}
+			if config.NoValidationCache || noValidationCache {
+				config.NoValidationCache = true
+			}
+			if config.WatchSpec || watchSpec {
+				config.WatchSpec = true
+			}
+			if specPollInterval > 0 {
+				config.SpecPollInterval = specPollInterval
+			}
+			if config.CI || ciMode {
+				config.CI = true
+			}
+			if ciCommand != "" {
+				config.CICommand = ciCommand
+			}
+			if ciSummary != "" {
+				config.CISummaryFile = ciSummary
+			}
+			if candidate != "" {
+				config.Candidate = candidate
+			}
+			if violationWindow > 0 {
+				config.ViolationWindow = violationWindow
+			}
+			if tailFlag {
+				config.Tail = true
+			}
+			if len(tailFilters) > 0 {
+				config.TailFilters = tailFilters
+			}
+			if reportViolationsOnly || len(reportSeverities) > 0 || len(reportPaths) > 0 {
+				if config.ReportFilter == nil {
+					config.ReportFilter = &shared.WiretapReportFilter{}
+				}
+				if reportViolationsOnly {
+					config.ReportFilter.ViolationsOnly = true
+				}
+				if len(reportSeverities) > 0 {
+					config.ReportFilter.Severities = reportSeverities
+				}
+				if len(reportPaths) > 0 {
+					config.ReportFilter.Paths = reportPaths
+				}
+			}
+			if harRecord != "" {
+				config.HARRecord = harRecord
+			}
+			if harRecordFormat != "" {
+				config.HARRecordFormat = harRecordFormat
+			}
+			if harRecordMaxSize > 0 {
+				config.HARRecordMaxSize = harRecordMaxSize
+			}
+			if harRecordRotate > 0 {
+				config.HARRecordRotate = harRecordRotate
+			}
+			if transactionStore != "" {
+				config.TransactionStore = transactionStore
+			}
+			if accessLog != "" {
+				config.AccessLog = accessLog
+			}
+			if auditLog != "" {
+				config.AuditLog = auditLog
+			}
+			if captureSampleRate > 0 {
+				config.CaptureSampleRate = captureSampleRate
+			}
+			if capturePaused {
+				config.CapturePaused = true
+			}
+			if maxTransactions > 0 {
+				config.MaxTransactions = maxTransactions
+			}
+			if apiToken != "" {
+				config.APIToken = apiToken
+			}
+			if monitorToken != "" || monitorUser != "" || monitorPassword != "" {
+				if config.MonitorAuth == nil {
+					config.MonitorAuth = &shared.WiretapMonitorAuth{}
+				}
+				if monitorToken != "" {
+					config.MonitorAuth.Token = monitorToken
+				}
+				if monitorUser != "" {
+					config.MonitorAuth.Username = monitorUser
+				}
+				if monitorPassword != "" {
+					config.MonitorAuth.Password = monitorPassword
+				}
+			}
+			if maxCaptureMemory > 0 {
+				config.MaxCaptureMemoryMB = maxCaptureMemory
+			}
+			if monitorMaxBody > 0 {
+				config.MonitorMaxBodyKB = monitorMaxBody
+			}
+			if len(intercepts) > 0 {
+				config.Intercept = append(config.Intercept, intercepts...)
+			}
+			if interceptTimeout != 0 {
+				config.InterceptTimeout = interceptTimeout
+			}
+			if junitReport {
+				config.JUnitReport = true
+			}
+			if sarifReport {
+				config.SARIFReport = true
+			}
+			if reportRotation != "" {
+				config.ReportRotation = reportRotation
+			}
+			if reportRetention > 0 {
+				config.ReportRetention = reportRetention
+			}
+			switch strings.ToLower(config.ReportRotation) {
+			case "", shared.RotateHourly, shared.RotateDaily:
+			default:
+				pterm.Error.Printf("Unknown report rotation '%s', use 'hourly' or 'daily'\n", config.ReportRotation)
+				return fmt.Errorf("unknown report rotation '%s'", config.ReportRotation)
+			}
+			switch strings.ToLower(config.HARRecordFormat) {
+			case "", shared.HARFormatHAR, shared.HARFormatNDJSON:
+			default:
+				pterm.Error.Printf("Unknown HAR recording format '%s', use 'har' or 'ndjson'\n", config.HARRecordFormat)
+				return fmt.Errorf("unknown HAR recording format '%s'", config.HARRecordFormat)
+			}
+			for _, threshold := range ciThresholds {
+				severity, max, tErr := configModel.ParseCIThreshold(threshold)
+				if tErr != nil {
+					pterm.Error.Println(tErr.Error())
+					return tErr
+				}
+				if config.CIThresholds == nil {
+					config.CIThresholds = make(map[string]int)
+				}
+				config.CIThresholds[severity] = max
+			}
+			if strictParameters {
+				config.StrictParameters = true
+			}
+			if upstreamH2C {
+				config.UpstreamH2C = true
+			}
+			if upstreamProxy != "" {
+				if config.UpstreamProxy == nil {
+					config.UpstreamProxy = &shared.WiretapUpstreamProxyConfig{}
+				}
+				config.UpstreamProxy.URL = upstreamProxy
+			}
+			if clientCert != "" || clientKey != "" {
+				if config.RedirectHost == "" {
+					pterm.Error.Println("A client certificate is presented to the redirect URL, please provide one " +
+						"using the --url or -u flags (or configure clientCertificates for each target)")
+					return nil
+				}
+				target := config.RedirectHost
+				if config.RedirectPort != "" {
+					target = net.JoinHostPort(config.RedirectHost, config.RedirectPort)
+				}
+				if config.ClientCertificates == nil {
+					config.ClientCertificates = make(map[string]*shared.WiretapClientCertConfig)
+				}
+				config.ClientCertificates[target] = &shared.WiretapClientCertConfig{
+					ClientCert:    clientCert,
+					ClientKey:     clientKey,
+					PassphraseEnv: clientKeyPassphraseEnv,
+				}
+			}
+			if len(allowHeaders) > 0 {
+				config.AllowedHeaders = append(config.AllowedHeaders, allowHeaders...)
+			}
+			for _, webhookPath := range webhookPaths {
+				prefix, webhook, ok := strings.Cut(webhookPath, "=")
+				if !ok || prefix == "" || webhook == "" {
+					wErr := fmt.Errorf("webhook path '%s' is not in the form 'path=webhook'", webhookPath)
+					pterm.Error.Println(wErr.Error())
+					return wErr
+				}
+				if config.WebhookPaths == nil {
+					config.WebhookPaths = make(map[string]string)
+				}
+				config.WebhookPaths[prefix] = webhook
+			}
+			if len(overlays) > 0 {
+				config.Overlays = append(config.Overlays, overlays...)
+			}
+			for _, variable := range serverVariables {
+				name, value, ok := strings.Cut(variable, "=")
+				if !ok || strings.TrimSpace(name) == "" {
+					vErr := fmt.Errorf("server variable '%s' is not in the form 'name=value'", variable)
+					pterm.Error.Println(vErr.Error())
+					return vErr
+				}
+				if config.ServerVariables == nil {
+					config.ServerVariables = make(map[string]string)
+				}
+				config.ServerVariables[strings.TrimSpace(name)] = value
+			}
 
 			// configure hard errors if set
 			if config.HardErrors && config.HardErrorCode <= 0 {
@@ -318,6 +798,10 @@ var (
 				config.Certificate = cert
 				config.CertificateKey = certKey
 			}
+			if monitorCert != "" || monitorKey != "" {
+				config.MonitorCertificate = monitorCert
+				config.MonitorCertificateKey = monitorKey
+			}
 
 			// variables
 			if len(config.Variables) > 0 {
@@ -333,11 +817,59 @@ var (
 				}
 			}
 
+			// hosts
+			if len(config.Hosts) > 0 {
+				config.CompileHosts()
+				printLoadedHostConfigurations(config.Hosts)
+			}
+
 			// path delays
-			if len(config.PathDelays) > 0 {
+			if len(config.PathDelays) > 0 || len(config.MockLatency) > 0 {
 				config.CompilePathDelays()
+			}
+			if len(config.PathDelays) > 0 {
 				printLoadedPathDelayConfigurations(config.PathDelays)
 			}
+			if len(config.MockLatency) > 0 {
+				printLoadedMockLatencyConfigurations(config.MockLatency)
+			}
+			if len(config.GraphQLDelays) > 0 {
+				printLoadedGraphQLDelayConfigurations(config.GraphQLDelays)
+			}
+
+			// paths switched between mock and proxy mode
+			if len(config.MockPaths) > 0 {
+				config.CompileMockPaths()
+				printLoadedMockPaths(config.MockPaths)
+			}
+
+			// a fraction of mocked responses are errors
+			if rErr := config.CheckMockErrorRates(); rErr != nil {
+				pterm.Error.Println(rErr.Error())
+				return rErr
+			}
+
+			// requests held until they are approved
+			if len(config.Intercept) > 0 {
+				for _, rule := range config.Intercept {
+					if iErr := config.CheckInterceptRule(rule); iErr != nil {
+						pterm.Error.Println(iErr.Error())
+						return iErr
+					}
+				}
+				if config.InterceptTimeout < 0 {
+					iErr := fmt.Errorf("the intercept timeout cannot be negative, %d is not valid", config.InterceptTimeout)
+					pterm.Error.Println(iErr.Error())
+					return iErr
+				}
+				config.CompileIntercepts()
+				printLoadedIntercepts(config.Intercept, config.InterceptTimeout)
+			}
+
+			// webhook calls
+			if len(config.WebhookPaths) > 0 {
+				printLoadedWebhookPaths(config.WebhookPaths)
+			}
 
 			// static headers
 			if config.Headers != nil && len(config.Headers.DropHeaders) > 0 {
@@ -368,13 +900,171 @@ var (
 				pterm.Println()
 			}
 
+			// suppressed violations
+			if len(config.Suppress) > 0 {
+				pterm.Printf("🔇 %d violation %s suppressed: %s\n", len(config.Suppress),
+					shared.Pluralize(len(config.Suppress), "rule", "rules"), pterm.LightMagenta(strings.Join(config.Suppress, ", ")))
+				pterm.Println()
+			}
+
+			// strict requests
+			if config.StrictRequests && !config.MockMode {
+				pterm.Printf("🚧 %s. Requests that fail validation are rejected with a %s and are not sent to the API.\n",
+					pterm.LightCyan("Strict request mode enabled"), pterm.LightRed(400))
+				pterm.Println()
+			}
+
+			// strict responses
+			if config.StrictResponses && !config.MockMode {
+				pterm.Printf("🚧 %s. Responses that fail validation are replaced by a %s with the violations.\n",
+					pterm.LightCyan("Strict response mode enabled"), pterm.LightRed(config.StrictResponseCode))
+				pterm.Println()
+			}
+
+			// inline validation
+			if strings.EqualFold(config.ValidationMode, shared.ValidationModeInline) && !config.MockMode {
+				pterm.Printf("🐢 %s. Requests and responses are validated before traffic continues.\n",
+					pterm.LightCyan("Inline validation enabled"))
+				pterm.Println()
+			}
+
+			// custom validators
+			if len(config.ValidatorHooks) > 0 {
+				pterm.Printf("🧩 %d %s configured.\n", len(config.ValidatorHooks),
+					pterm.LightCyan(shared.Pluralize(len(config.ValidatorHooks), "validator hook", "validator hooks")))
+				pterm.Println()
+			}
+
+			// CI mode
+			if config.CI {
+				var thresholds []string
+				for severity, max := range configModel.CIThresholds(config.CIThresholds) {
+					thresholds = append(thresholds, fmt.Sprintf("%s > %d", severity, max))
+				}
+				sort.Strings(thresholds)
+				pterm.Printf("🚦 %s. Wiretap fails when violations exceed: %s\n", pterm.LightCyan("CI mode enabled"),
+					pterm.LightRed(strings.Join(thresholds, ", ")))
+				if config.CICommand != "" {
+					pterm.Printf("🏁 Wiretap stops when '%s' is done.\n", pterm.LightMagenta(config.CICommand))
+				}
+				pterm.Println()
+			}
+
+			// unknown parameters
+			if config.StrictParameters {
+				pterm.Printf("🔎 %s. Query parameters and headers the specification doesn't declare are violations.\n",
+					pterm.LightCyan("Strict parameters enabled"))
+				if len(config.AllowedHeaders) > 0 {
+					pterm.Printf("✅ Allowed headers: %s\n", pterm.LightMagenta(strings.Join(config.AllowedHeaders, ", ")))
+				}
+				pterm.Println()
+			}
+
+			// server variables
+			if len(config.ServerVariables) > 0 {
+				var pinned []string
+				for name, value := range config.ServerVariables {
+					pinned = append(pinned, fmt.Sprintf("%s=%s", name, value))
+				}
+				sort.Strings(pinned)
+				pterm.Printf("🧭 %s: %s\n", pterm.LightCyan("Server variables pinned"), strings.Join(pinned, ", "))
+				pterm.Println()
+			}
+
+			// watching the specification
+			if config.WatchSpec && config.Contract != "" {
+				if strings.HasPrefix(config.Contract, "http://") || strings.HasPrefix(config.Contract, "https://") {
+					interval := config.SpecPollInterval
+					if interval <= 0 {
+						interval = shared.DefaultSpecPollInterval
+					}
+					pterm.Printf("👀 %s. The specification is polled for changes every %s.\n",
+						pterm.LightCyan("Specification reload enabled"), pterm.LightMagenta(fmt.Sprintf("%ds", interval)))
+				} else {
+					pterm.Printf("👀 %s. The specification is reloaded when the file changes.\n",
+						pterm.LightCyan("Specification reload enabled"))
+				}
+				pterm.Println()
+			}
+
 			// mock mode
 			if config.MockMode {
 				pterm.Printf("Ⓜ️ %s. All responses will be mocked and no traffic will be sent to the target API.\n",
 					pterm.LightCyan("Mock mode enabled"))
+				if config.MockModeStateful {
+					pterm.Printf("🗄️  %s. Resources created, updated and deleted are kept in memory.\n",
+						pterm.LightCyan("Stateful mocks enabled"))
+				}
+				if len(config.MockSequences) > 0 {
+					pterm.Printf("🔁 %d %s configured.\n", len(config.MockSequences),
+						pterm.LightCyan(shared.Pluralize(len(config.MockSequences), "mock sequence", "mock sequences")))
+				}
+				if config.MockErrorRate > 0 {
+					pterm.Printf("💥 %s. %s of mocked responses will be errors from the specification.\n",
+						pterm.LightCyan("Error injection enabled"), pterm.LightRed(fmt.Sprintf("%.0f%%", config.MockErrorRate*100)))
+				}
+				switch strings.ToLower(config.MockValidation) {
+				case shared.MockValidationWarn:
+					pterm.Printf("⚠️  %s. Invalid requests are mocked, violations are reported as warnings.\n",
+						pterm.LightCyan("Permissive mock validation"))
+				case shared.MockValidationIgnore:
+					pterm.Printf("🙈 %s. Requests are not validated before they are mocked.\n",
+						pterm.LightCyan("Mock validation disabled"))
+				}
+				if config.MockUnionStrategy != "" && config.MockUnionStrategy != shared.MockUnionFirst {
+					pterm.Printf("🔀 %s. Variants of oneOf / anyOf schemas are picked using the '%s' strategy.\n",
+						pterm.LightCyan("Polymorphic mocks enabled"), pterm.LightMagenta(config.MockUnionStrategy))
+				}
+				if config.MockOverrides != "" {
+					pterm.Printf("✍️  %s. Mocks are replaced by the files in '%s'.\n",
+						pterm.LightCyan("Mock overrides enabled"), pterm.LightMagenta(config.MockOverrides))
+				}
+				if config.MockPagination {
+					total := config.MockPaginationTotal
+					if total <= 0 {
+						total = mock.DefaultPaginationTotal
+					}
+					pterm.Printf("📄 %s. Paginated operations serve pages of a collection of %s items.\n",
+						pterm.LightCyan("Mock pagination enabled"), pterm.LightMagenta(total))
+				}
+				if config.MockCallbacks {
+					pterm.Printf("📣 %s. Callbacks fire %s after the response is mocked.\n",
+						pterm.LightCyan("Mock callbacks enabled"), pterm.LightMagenta(fmt.Sprintf("%dms", config.MockCallbackDelay)))
+				}
+				if len(config.MockWebhooks) > 0 {
+					pterm.Printf("🪝 %d %s configured.\n", len(config.MockWebhooks),
+						pterm.LightCyan(shared.Pluralize(len(config.MockWebhooks), "mock webhook", "mock webhooks")))
+				}
+				if config.MockSeed != 0 {
+					pterm.Printf("🎲 %s. Mocks and fake data are generated using seed %s.\n",
+						pterm.LightCyan("Deterministic mocks enabled"), pterm.LightMagenta(config.MockSeed))
+				}
+				pterm.Println()
+			}
+
+			// hybrid mode
+			if config.MockFallback && !config.MockMode {
+				pterm.Printf("🩹 %s. Requests the API answers with 404 or 501 (or cannot answer at all) are mocked.\n",
+					pterm.LightCyan("Hybrid mock mode enabled"))
 				pterm.Println()
 			}
 
+			// listening on a single interface?
+			if config.BindAddress != "" {
+				ip := net.ParseIP(config.BindAddress)
+				if ip == nil {
+					if _, lErr := net.LookupHost(config.BindAddress); lErr != nil {
+						pterm.Error.Printf("Cannot bind to '%s', it's not an IP address or a host that can be resolved\n",
+							config.BindAddress)
+						return nil
+					}
+				}
+				if ip == nil || !ip.IsUnspecified() {
+					pterm.Printf("📍 Listening on %s only\n", pterm.LightMagenta(config.BindAddress))
+					pterm.Println()
+				}
+			}
+
 			// using TLS?
 			if config.CertificateKey != "" && config.Certificate != "" {
 				pterm.Printf("🔐 Running over %s using certificate: %s and key: %s\n",
@@ -382,12 +1072,320 @@ var (
 				pterm.Println()
 			}
 
+			// calling the API over HTTP/2 without TLS?
+			if config.UpstreamH2C {
+				pterm.Printf("🚄 Calling %s targets over %s (HTTP/2 with prior knowledge)\n", pterm.LightCyan("http://"),
+					pterm.LightYellow("h2c"))
+				pterm.Println()
+			}
+
+			// calling the API through a forward proxy?
+			if config.UpstreamProxy != nil {
+				proxyURL, pErr := config.UpstreamProxy.ProxyURL()
+				if pErr != nil {
+					pterm.Error.Printf("Cannot use the upstream proxy: %s\n", pErr.Error())
+					return nil
+				}
+				pterm.Printf("🚇 Calling targets through proxy: %s\n", pterm.LightMagenta(proxyURL.Redacted()))
+				if len(config.UpstreamProxy.NoProxy) > 0 {
+					pterm.Printf("🚇 Calling directly: %s\n", pterm.LightCyan(strings.Join(config.UpstreamProxy.NoProxy, ", ")))
+				}
+				pterm.Println()
+			}
+
+			// presenting client certificates to targets that require mutual TLS?
+			if len(config.ClientCertificates) > 0 {
+				for target, cc := range config.ClientCertificates {
+					if _, cErr := cc.LoadCertificate(); cErr != nil {
+						pterm.Error.Printf("Cannot load the client certificate for %s: %s\n", target, cErr.Error())
+						return nil
+					}
+					pterm.Printf("🪪 Presenting client certificate: %s to %s\n", pterm.LightMagenta(cc.ClientCert),
+						pterm.LightCyan(target))
+				}
+				pterm.Println()
+			}
+
+			// serving the monitor with a certificate of its own?
+			if config.MonitorCertificate != "" || config.MonitorCertificateKey != "" {
+				if config.MonitorCertificate == "" || config.MonitorCertificateKey == "" {
+					pterm.Error.Println("The monitor needs both a certificate and a key to be served over TLS/HTTPS")
+					return nil
+				}
+				if _, tErr := tls.LoadX509KeyPair(config.MonitorCertificate, config.MonitorCertificateKey); tErr != nil {
+					pterm.Error.Printf("Cannot load the monitor's certificate and key: %s\n", tErr.Error())
+					return nil
+				}
+				pterm.Printf("🔐 Monitor UI running over %s using certificate: %s and key: %s\n",
+					pterm.LightYellow("TLS/HTTPS"), pterm.LightMagenta(config.MonitorCertificate),
+					pterm.LightCyan(config.MonitorCertificateKey))
+				pterm.Println()
+			}
+
 			// streaming violations?
 			if config.StreamReport {
 				pterm.Printf("⏩  Streaming API violations to file: %s\n", pterm.LightMagenta(config.ReportFile))
+				pterm.Printf("📐 Specification coverage is saved to: %s, when wiretap stops\n",
+					pterm.LightMagenta(coverageFilename(config.ReportFile)))
+				pterm.Println()
+			}
+
+			// rotating reports?
+			if config.ReportRotation != "" {
+				pterm.Printf("🗓️  The streamed report and recorded HAR file are rotated %s", pterm.LightMagenta(
+					strings.ToLower(config.ReportRotation)))
+				if config.ReportRetention > 0 {
+					pterm.Printf(", keeping the last %d", config.ReportRetention)
+				}
+				pterm.Println()
+				pterm.Println()
+			}
+
+			// JUnit report?
+			if config.JUnitReport {
+				pterm.Printf("🧪 JUnit report of violations by operation is saved to: %s, when wiretap stops\n",
+					pterm.LightMagenta(junitFilename(config.ReportFile)))
+				pterm.Println()
+			}
+
+			// SARIF report?
+			if config.SARIFReport {
+				pterm.Printf("🔬 SARIF report of violations, located in the specification, is saved to: %s, when wiretap stops\n",
+					pterm.LightMagenta(sarifFilename(config.ReportFile)))
+				pterm.Println()
+			}
+
+			// tracing?
+			if config.OTLPEndpoint != "" {
+				pterm.Printf("🛰️  Exporting traces to OpenTelemetry collector: %s\n", pterm.LightMagenta(config.OTLPEndpoint))
+				pterm.Println()
+			}
+
+			// pushing metrics?
+			if config.StatsDAddress != "" {
+				format := strings.ToLower(config.StatsDFormat)
+				if format == "" {
+					format = metrics.FormatDogStatsD
+				}
+				if format != metrics.FormatDogStatsD && format != metrics.FormatStatsD {
+					pterm.Error.Printf("Unknown statsd format '%s', use '%s' or '%s'\n", config.StatsDFormat,
+						metrics.FormatDogStatsD, metrics.FormatStatsD)
+					return nil
+				}
+				pterm.Printf("📈 Pushing metrics to %s: %s\n", format, pterm.LightMagenta(config.StatsDAddress))
+				pterm.Println()
+			}
+
+			// recording traffic?
+			if config.HARRecord != "" {
+				format := shared.HARFormatHAR
+				if strings.EqualFold(config.HARRecordFormat, shared.HARFormatNDJSON) {
+					format = shared.HARFormatNDJSON
+				}
+				pterm.Printf("📼 Recording traffic to %s file: %s", strings.ToUpper(format), pterm.LightMagenta(config.HARRecord))
+				if config.HARRecordMaxSize > 0 {
+					pterm.Printf(", rotated at %dMB", config.HARRecordMaxSize)
+				}
+				if config.HARRecordRotate > 0 {
+					pterm.Printf(", rotated every %d %s", config.HARRecordRotate,
+						shared.Pluralize(config.HARRecordRotate, "second", "seconds"))
+				}
+				pterm.Println()
+				pterm.Println()
+			}
+
+			// writing an access log?
+			if config.AccessLog != "" {
+				pterm.Printf("📒 Writing an access log (combined format) to: %s\n", pterm.LightMagenta(config.AccessLog))
+				pterm.Println()
+			}
+
+			// auditing changes made while running?
+			if config.AuditLog != "" {
+				auditor, aErr := audit.Open(config.AuditLog)
+				if aErr != nil {
+					pterm.Error.Printf("Cannot open audit log: %s\n", aErr.Error())
+					return nil
+				}
+				config.Auditor = auditor
+				pterm.Printf("🧾 Auditing runtime changes to: %s\n", pterm.LightMagenta(config.AuditLog))
+				pterm.Println()
+			}
+
+			// only capturing some of the traffic?
+			if config.CaptureSampleRate != 0 {
+				if config.CaptureSampleRate < 0 || config.CaptureSampleRate > 1 {
+					pterm.Error.Printf("Capture sample rate must be between 0 and 1, not %g\n", config.CaptureSampleRate)
+					return nil
+				}
+				if config.CaptureSampleRate < 1 {
+					pterm.Printf("🪣 Capturing %s of transactions, all traffic is still proxied and validated\n",
+						pterm.LightMagenta(fmt.Sprintf("%g%%", config.CaptureSampleRate*100)))
+					pterm.Println()
+				}
+			}
+
+			// starting with capture paused?
+			if config.CapturePaused {
+				pterm.Printf("⏸️  Capture is %s, traffic is proxied and validated but nothing is kept until it's resumed\n",
+					pterm.LightMagenta("paused"))
+				pterm.Println()
+			}
+
+			// only holding on to so many transactions?
+			if config.MaxTransactions < 0 || config.MaxCaptureMemoryMB < 0 {
+				pterm.Error.Println("The maximum number of transactions and capture memory cannot be negative")
+				return nil
+			}
+			if config.MaxTransactions > 0 || config.MaxCaptureMemoryMB > 0 {
+				var limits []string
+				if config.MaxTransactions > 0 {
+					limits = append(limits, fmt.Sprintf("%d transactions", config.MaxTransactions))
+				}
+				if config.MaxCaptureMemoryMB > 0 {
+					limits = append(limits, fmt.Sprintf("%dMB", config.MaxCaptureMemoryMB))
+				}
+				pterm.Printf("♻️  Keeping at most %s in memory, the oldest are evicted first\n",
+					pterm.LightMagenta(strings.Join(limits, " / ")))
+				pterm.Println()
+			}
+
+			// protecting the monitor?
+			if config.MonitorAuth != nil {
+				if (config.MonitorAuth.Username == "") != (config.MonitorAuth.Password == "") {
+					pterm.Error.Println("Monitor basic auth needs both a username and a password")
+					return nil
+				}
+				if config.MonitorAuth.Enabled() {
+					var methods []string
+					if config.MonitorAuth.Token != "" {
+						methods = append(methods, "a token")
+					}
+					if config.MonitorAuth.BasicAuth() {
+						methods = append(methods, "basic auth")
+					}
+					pterm.Printf("🛡️  Monitor and websocket protected by %s\n", pterm.LightMagenta(strings.Join(methods, " or ")))
+					pterm.Println()
+				}
+			}
+
+			// cutting large bodies down before they are streamed to the monitor?
+			if config.MonitorMaxBodyKB < 0 {
+				pterm.Error.Println("The maximum size of bodies streamed to the monitor cannot be negative")
+				return nil
+			}
+			if config.MonitorMaxBodyKB > 0 {
+				pterm.Printf("✂️  Bodies over %s are cut down before they are streamed to the monitor\n",
+					pterm.LightMagenta(fmt.Sprintf("%dKB", config.MonitorMaxBodyKB)))
+				pterm.Println()
+			}
+
+			// the API always needs a token, one is made up if it hasn't been configured.
+			if config.APIToken == "" {
+				config.APIToken = generateAPIToken()
+				pterm.Printf("🔑 API token (generated, set 'apiToken' to fix it): %s\n",
+					pterm.LightMagenta(config.APIToken))
+				pterm.Println()
+			}
+
+			// storing transactions?
+			if config.TransactionStore != "" {
+				pterm.Printf("🗄️  Storing transactions in: %s\n", pterm.LightMagenta(config.TransactionStore))
+				pterm.Println()
+			}
+
+			// aggregating violations?
+			if config.ViolationWindow > 0 {
+				pterm.Printf("🧮 Identical violations are aggregated over %s, repeats are reported once with a count\n",
+					pterm.LightMagenta(fmt.Sprintf("%d %s", config.ViolationWindow,
+						shared.Pluralize(config.ViolationWindow, "second", "seconds"))))
+				pterm.Println()
+			}
+
+			// narrowing what reports include?
+			if !config.ReportFilter.Empty() {
+				if fErr := config.ReportFilter.Compile(); fErr != nil {
+					pterm.Error.Printf("Cannot filter reports: %s\n", fErr.Error())
+					return nil
+				}
+				var narrowed []string
+				if config.ReportFilter.ViolationsOnly {
+					narrowed = append(narrowed, "transactions with violations")
+				}
+				if len(config.ReportFilter.Severities) > 0 {
+					narrowed = append(narrowed, "severities "+strings.Join(config.ReportFilter.Severities, ", "))
+				}
+				if len(config.ReportFilter.Paths) > 0 {
+					narrowed = append(narrowed, "paths "+strings.Join(config.ReportFilter.Paths, ", "))
+				}
+				pterm.Printf("🧹 Reports only include: %s\n", pterm.LightMagenta(strings.Join(narrowed, "; ")))
+				pterm.Println()
+			}
+
+			// printing transactions to the terminal?
+			if config.Tail {
+				if _, tErr := tail.ParseFilter(config.TailFilters...); tErr != nil {
+					pterm.Error.Printf("Cannot tail transactions: %s\n", tErr.Error())
+					return nil
+				}
+				pterm.Printf("📜 Tailing transactions to the terminal")
+				if len(config.TailFilters) > 0 {
+					pterm.Printf(", matching: %s", pterm.LightMagenta(strings.Join(config.TailFilters, " && ")))
+				}
+				pterm.Println()
+				pterm.Println()
+			}
+
+			// filing violations with issue trackers?
+			if len(config.IssueTrackers) > 0 {
+				for _, tracker := range config.IssueTrackers {
+					target := tracker.Repository
+					if target == "" {
+						target = tracker.Project
+					}
+					pterm.Printf("🎫 Filing API violations as issues in %s: %s\n",
+						pterm.LightCyan(tracker.Type), pterm.LightMagenta(target))
+				}
+				pterm.Println()
+			}
+
+			// notifying webhooks of violations?
+			if len(config.Notifications) > 0 {
+				for _, notification := range config.Notifications {
+					target := notification.URL
+					if u, uErr := url.Parse(notification.URL); uErr == nil && u.Host != "" {
+						target = u.Host // webhook URLs are often secrets, only the host is shown.
+					}
+					severity := notification.Severity
+					if severity == "" {
+						severity = shared.SeverityError
+					}
+					pterm.Printf("🔔 Notifying webhook %s of %s violations\n", pterm.LightMagenta(target),
+						pterm.LightCyan(severity))
+				}
 				pterm.Println()
 			}
 
+			// redacting secrets and personal data from captured traffic?
+			if config.Redact != nil {
+				redactor, rErr := redact.New(config.Redact.Headers, config.Redact.Paths, config.Redact.Patterns)
+				if rErr != nil {
+					pterm.Error.Printf("Cannot redact captured traffic: %s\n", rErr.Error())
+					return nil
+				}
+				config.Redactor = redactor
+				if redactor != nil {
+					pterm.Printf("🔏 Redacting captured traffic: %s, %s and %s\n",
+						pterm.LightMagenta(fmt.Sprintf("%d %s", len(config.Redact.Headers),
+							shared.Pluralize(len(config.Redact.Headers), "header", "headers"))),
+						pterm.LightMagenta(fmt.Sprintf("%d %s", len(config.Redact.Paths),
+							shared.Pluralize(len(config.Redact.Paths), "JSON path", "JSON paths"))),
+						pterm.LightMagenta(fmt.Sprintf("%d %s", len(config.Redact.Patterns),
+							shared.Pluralize(len(config.Redact.Patterns), "pattern", "patterns"))))
+					pterm.Println()
+				}
+			}
+
 			var harBytes []byte
 			var harFile *harhar.HAR
 
@@ -414,15 +1412,13 @@ var (
 					pterm.Error.Printf("Cannot parse HAR file: %s (%s)\n", config.HAR, fErr.Error())
 					return nil
 				}
-				pterm.Println()
 				config.HARFile = harFile
+				if config.HARPlayback {
+					pterm.Printf("⏯️  %s. %d recorded %s will be played back.\n", pterm.LightCyan("HAR playback enabled"),
+						len(harFile.Log.Entries), shared.Pluralize(len(harFile.Log.Entries), "response", "responses"))
+				}
+				pterm.Println()
 			}
-			// let's create a logger first.
-			logLevel := pterm.LogLevelWarn
-			if debug {
-				logLevel = pterm.LogLevelDebug
-			}
-
 			// check if we want to validate the HAR file against the OpenAPI spec.
 			// but only if we're not in mock mode and there is a spec provided
 			if config.HARValidate && !config.MockMode && config.Contract != "" {
@@ -453,22 +1449,8 @@ var (
 
 			}
 
-			ptermLog := &pterm.Logger{
-				Formatter:  pterm.LogFormatterColorful,
-				Writer:     os.Stdout,
-				Level:      logLevel,
-				ShowTime:   true,
-				TimeFormat: "2006-01-02 15:04:05",
-				MaxWidth:   180,
-				KeyStyles: map[string]pterm.Style{
-					"error":  *pterm.NewStyle(pterm.FgRed, pterm.Bold),
-					"err":    *pterm.NewStyle(pterm.FgRed, pterm.Bold),
-					"caller": *pterm.NewStyle(pterm.FgGray, pterm.Bold),
-				},
-			}
-
-			handler := pterm.NewSlogHandler(ptermLog)
-			config.Logger = slog.New(handler)
+			// let's create a logger.
+			config.Logger = newLogger(os.Stdout, config.LogFormat, logLevel)
 
 			// if we have a HAR file, we need to load it into the config
 			if harFile != nil {
@@ -479,8 +1461,18 @@ var (
 			var doc libopenapi.Document
 			var docModel *libopenapi.DocumentModel[v3.Document]
 			var err error
+			for _, location := range config.Overlays {
+				o, oErr := loadOverlay(location)
+				if oErr != nil {
+					pterm.Error.Printf("Cannot load overlay '%s': %s\n", location, oErr.Error())
+					return oErr
+				}
+				pterm.Info.Printf("Overlay: '%s' parsed and read, %d %s\n", location, len(o.Actions),
+					shared.Pluralize(len(o.Actions), "action", "actions"))
+				config.OverlayDocuments = append(config.OverlayDocuments, o)
+			}
 			if config.Contract != "" {
-				doc, err = loadOpenAPISpec(config.Contract, config.Base)
+				doc, err = loadOpenAPISpec(config.Contract, config.Base, config.OverlayDocuments...)
 				if err != nil {
 					return err
 				}
@@ -497,9 +1489,125 @@ var (
 				pterm.Info.Printf("OpenAPI Specification: '%s' parsed and read\n", config.Contract)
 			}
 
+			// load any specifications attached to hosts
+			for k, host := range config.Hosts {
+				if host.Spec == "" {
+					continue
+				}
+				host.Document, err = loadOpenAPISpec(host.Spec, config.Base)
+				if err != nil {
+					pterm.Error.Printf("Cannot load OpenAPI Specification '%s' for host '%s': %s\n", host.Spec, k, err.Error())
+					return err
+				}
+				pterm.Info.Printf("OpenAPI Specification: '%s' parsed and read for host '%s'\n", host.Spec, k)
+			}
+
+			// load any specifications attached to paths, globally and for hosts.
+			pathConfigs := make(map[string]*shared.WiretapPathConfig)
+			for k, path := range config.PathConfigurations {
+				pathConfigs[k] = path
+			}
+			for h, host := range config.Hosts {
+				for k, path := range host.PathConfigurations {
+					pathConfigs[h+k] = path
+				}
+			}
+			for k, path := range pathConfigs {
+				if path.Spec == "" {
+					continue
+				}
+				path.Document, err = loadOpenAPISpec(path.Spec, config.Base)
+				if err != nil {
+					pterm.Error.Printf("Cannot load OpenAPI Specification '%s' for path '%s': %s\n", path.Spec, k, err.Error())
+					return err
+				}
+				pterm.Info.Printf("OpenAPI Specification: '%s' parsed and read for path '%s'\n", path.Spec, k)
+			}
+
+			// load the specifications for path prefixes, each service behind wiretap can have its own.
+			if len(config.Contracts) > 0 {
+				config.ContractDocuments = make(map[string]libopenapi.Document)
+			}
+			for prefix, contract := range config.Contracts {
+				config.ContractDocuments[prefix], err = loadOpenAPISpec(contract, config.Base)
+				if err != nil {
+					pterm.Error.Printf("Cannot load OpenAPI Specification '%s' for prefix '%s': %s\n", contract, prefix, err.Error())
+					return err
+				}
+				pterm.Info.Printf("OpenAPI Specification: '%s' parsed and read for prefix '%s'\n", contract, prefix)
+			}
+
+			// load the candidate specification, the next version of the specification.
+			if config.Candidate != "" {
+				if doc == nil {
+					pterm.Error.Println("A candidate specification can only be used with a specification (--spec), it's the next version of it")
+					return fmt.Errorf("candidate specification without a specification")
+				}
+				config.CandidateDocument, err = loadOpenAPISpec(config.Candidate, config.Base)
+				if err != nil {
+					pterm.Error.Printf("Cannot load candidate OpenAPI Specification '%s': %s\n", config.Candidate, err.Error())
+					return err
+				}
+				pterm.Info.Printf("Candidate OpenAPI Specification: '%s' parsed and read, valid traffic that breaks it is reported (rule 'candidate')\n", config.Candidate)
+			}
+
+			// load the AsyncAPI document, its channels are mocked over websockets, or validated when proxied.
+			if config.AsyncAPI != "" {
+				config.AsyncAPIDocument, err = loadAsyncAPISpec(config.AsyncAPI)
+				if err != nil {
+					pterm.Error.Printf("Cannot load AsyncAPI Specification '%s': %s\n", config.AsyncAPI, err.Error())
+					return err
+				}
+				interval := config.AsyncAPIInterval
+				if interval <= 0 {
+					interval = shared.DefaultAsyncAPIInterval
+				}
+				pterm.Info.Printf("AsyncAPI Specification: '%s' parsed and read, %d %s mocked every %dms (or validated when proxied)\n",
+					config.AsyncAPI, len(config.AsyncAPIDocument.Channels),
+					shared.Pluralize(len(config.AsyncAPIDocument.Channels), "channel", "channels"), interval)
+			}
+
+			// load the GraphQL schema, requests to the GraphQL endpoint are validated against it.
+			if config.GraphQL != "" {
+				config.GraphQLSchema, err = loadGraphQLSchema(config.GraphQL)
+				if err != nil {
+					pterm.Error.Printf("Cannot load GraphQL schema '%s': %s\n", config.GraphQL, err.Error())
+					return err
+				}
+				if config.GraphQLPath == "" {
+					config.GraphQLPath = shared.DefaultGraphQLPath
+				}
+				pterm.Info.Printf("GraphQL schema: '%s' parsed and read, %d %s, validating requests to '%s'\n",
+					config.GraphQL, len(config.GraphQLSchema.Types),
+					shared.Pluralize(len(config.GraphQLSchema.Types), "type", "types"), config.GraphQLPath)
+			}
+
+			// load the gRPC descriptors, gRPC calls are decoded (and validated, if asked to) using them.
+			if config.GRPC != "" {
+				config.GRPCDescriptors, err = loadGRPCDescriptors(config.GRPC)
+				if err != nil {
+					pterm.Error.Printf("Cannot load gRPC descriptors '%s': %s\n", config.GRPC, err.Error())
+					return err
+				}
+				services := grpc.Services(config.GRPCDescriptors)
+				action := "decoding"
+				if config.GRPCValidate {
+					action = "decoding and validating"
+				}
+				pterm.Info.Printf("gRPC descriptors: '%s' read, %d %s, %s gRPC calls\n", config.GRPC, len(services),
+					shared.Pluralize(len(services), "service", "services"), action)
+				if config.GRPCTranscode {
+					printTranscodedRoutes(config.GRPCDescriptors)
+				}
+			} else if config.GRPCTranscode {
+				pterm.Error.Println("gRPC transcoding needs the descriptors of the gRPC API, set them with --grpc")
+				return fmt.Errorf("grpc transcoding without descriptors")
+			}
+
 			if !config.HARValidate {
 
 				// ready to boot, let's go!
+				logConfiguration(&config)
 				_, pErr := runWiretapService(&config, doc)
 
 				if pErr != nil {
@@ -601,29 +1709,147 @@ func Execute(version, commit, date string, fs embed.FS) {
 	FS = fs
 
 	rootCmd.Flags().StringP("url", "u", "", "Set the redirect URL for wiretap to send traffic to")
+	rootCmd.Flags().String("client-cert", "", "Present a client certificate to the redirect URL, for targets that require mutual TLS")
+	rootCmd.Flags().String("client-key", "", "The key of the client certificate presented to the redirect URL")
+	rootCmd.Flags().String("client-key-passphrase-env", "", "The environment variable holding the passphrase of an encrypted client key")
+	rootCmd.Flags().String("upstream-proxy", "", "Call targets through a forward proxy (http://, https:// or socks5://, credentials can be part of the URL), in place of HTTP_PROXY / HTTPS_PROXY")
+	rootCmd.Flags().Bool("upstream-h2c", false, "Call http:// targets over HTTP/2 without TLS (h2c), for APIs that refuse HTTP/1.1 (https:// targets use HTTP/2 when they offer it)")
 	rootCmd.Flags().IntP("delay", "d", 0, "Set a global delay for all API requests")
 	rootCmd.Flags().StringP("port", "p", "", "Set port on which to listen for HTTP traffic (default is 9090)")
 	rootCmd.Flags().StringP("monitor-port", "m", "", "Set port on which to serve the monitor UI (default is 9091)")
 	rootCmd.Flags().StringP("ws-port", "w", "", "Set port on which to serve the monitor UI websocket (default is 9092)")
+	rootCmd.Flags().String("bind-address", "", "Set the address (an interface, e.g. 127.0.0.1) the proxy, monitor UI, websocket and metrics ports are bound to (default is every interface)")
+	rootCmd.Flags().String("otlp-endpoint", "", "Export spans for proxied requests, validation and mocks to an OpenTelemetry collector (OTLP over HTTP, e.g. http://localhost:4318), defaults to OTEL_EXPORTER_OTLP_ENDPOINT")
+	rootCmd.Flags().String("admin-port", "", "Set a port on which to serve pprof profiles, goroutine dumps and runtime stats (at /debug/pprof, /debug/goroutines and /debug/runtime), off by default")
+	rootCmd.Flags().String("admin-address", "", "Set the address the admin port is bound to (default is 127.0.0.1, so it's only reachable locally)")
+	rootCmd.Flags().String("metrics-port", "", "Set a port on which to serve Prometheus metrics (at /metrics), they are always served by the monitor UI too")
+	rootCmd.Flags().String("statsd", "", "Push metrics to a StatsD or DogStatsD agent (host:port, default port is 8125), for environments that can't scrape Prometheus metrics")
+	rootCmd.Flags().String("statsd-prefix", "", "Prefix the names of metrics pushed to StatsD, e.g. 'myteam.'")
+	rootCmd.Flags().String("statsd-tags", "", "Tags to send with every metric pushed to DogStatsD, comma separated, e.g. 'env:ci,team:api'")
+	rootCmd.Flags().String("statsd-format", "", "Format of metrics pushed to StatsD, 'dogstatsd' (default, labels are sent as tags) or 'statsd' (labels are added to names)")
 	rootCmd.Flags().StringP("ws-host", "v", "localhost", "Set the backend hostname for wiretap, for remotely deployed service")
 	rootCmd.Flags().StringP("spec", "s", "", "Set the path to the OpenAPI specification to use")
 	rootCmd.Flags().StringP("static", "t", "", "Set the path to a directory of static files to serve")
 	rootCmd.Flags().StringP("static-index", "i", "index.html", "Set the index filename for static file serving (default is index.html)")
 	rootCmd.Flags().StringP("cert", "n", "", "Set the path to the TLS certificate to use for TLS/HTTPS")
 	rootCmd.Flags().StringP("key", "k", "", "Set the path to the TLS certificate key to use for TLS/HTTPS")
+	rootCmd.Flags().StringArray("intercept", nil, "Hold requests matching a path glob (optionally after a method, e.g. 'POST /pets/*') until they are approved, changed or dropped from the monitor UI or API, can use arg multiple times")
+	rootCmd.Flags().Int("intercept-timeout", 0, "Set how many seconds an intercepted request is held for before it's sent on unchanged (default is 60)")
+	rootCmd.Flags().String("monitor-cert", "", "Set the path to a TLS certificate to serve the monitor UI and websocket with (default is the certificate set with --cert)")
+	rootCmd.Flags().String("monitor-key", "", "Set the path to the key of the monitor's TLS certificate (default is the key set with --key)")
 	rootCmd.Flags().BoolP("hard-validation", "e", false, "Return a HTTP error for non-compliant request/response")
 	rootCmd.Flags().IntP("hard-validation-code", "q", 400, "Set a custom http error code for non-compliant requests when using the hard-error flag")
 	rootCmd.Flags().IntP("hard-validation-return-code", "y", 502, "Set a custom http error code for non-compliant responses when using the hard-error flag")
+	rootCmd.Flags().Bool("strict-requests", false, "Reject requests that fail validation with a 400 (401 / 403 for security requirements) and the violations, instead of sending them to the API")
+	rootCmd.Flags().String("validation-mode", "", "Set to 'inline' to validate requests and responses before traffic continues, the default ('async') validates in the background")
+	rootCmd.Flags().Int("validation-workers", 0, "Set the number of workers validating traffic in the background (default is one per CPU)")
+	rootCmd.Flags().StringArray("validator-hook", nil, "Add a command that validates every request and response, it reads JSON from stdin and writes a JSON array of violations, can use arg multiple times")
+	rootCmd.Flags().Bool("no-validation-cache", false, "Validate every request, instead of re-using the result of validating an identical request")
+	rootCmd.Flags().Bool("ci", false, "Run as a CI gate: violations are counted by severity, a summary is written when wiretap stops, and it exits with 1 if a threshold is exceeded")
+	rootCmd.Flags().String("ci-command", "", "Run a test suite against wiretap in CI mode, wiretap stops when it's done (and exits with its code if it fails)")
+	rootCmd.Flags().StringArray("ci-threshold", nil, "Set the maximum number of violations of a severity allowed in CI mode (e.g. 'warn=10'), defaults to 'error=0', can use arg multiple times")
+	rootCmd.Flags().String("ci-summary", "", "Filename for the summary written in CI mode (default is wiretap-ci-summary.json)")
+	rootCmd.Flags().String("candidate", "", "Path or URL to the next version of the specification, traffic that's valid today but breaks against it is reported as 'candidate' violations (e.g. 'candidate/parameter/query')")
+	rootCmd.Flags().Bool("strict-parameters", false, "Report query parameters and request headers the specification doesn't declare (rules 'parameter/unknownquery' and 'parameter/unknownheader'), standard headers are ignored")
+	rootCmd.Flags().StringArray("allow-header", nil, "Allow a request header that isn't declared by the specification when using strict parameters, can use arg multiple times")
+	rootCmd.Flags().StringArray("webhook-path", nil, "Validate calls made through wiretap to a path (and below it) as calls to a webhook of the specification, e.g. '/hooks/pets=newPet', can use arg multiple times")
+	rootCmd.Flags().StringArray("overlay", nil, "Apply an OpenAPI Overlay to the specification when it's loaded, before anything is built from it, can use arg multiple times (applied in order)")
+	rootCmd.Flags().StringArray("server-variable", nil, "Pin a variable of the specification's server URLs (e.g. 'version=2'), instead of matching its default and enum values, can use arg multiple times")
+	rootCmd.Flags().Bool("watch-spec", false, "Reload the OpenAPI specification when it changes, local files are watched and URLs are polled")
+	rootCmd.Flags().Int("spec-poll-interval", 0, "Set how often (in seconds) a specification URL is polled for changes when using the watch-spec flag (defaults to 60)")
+	rootCmd.Flags().Bool("strict-responses", false, "Replace responses that fail validation with an error carrying the violations, instead of sending them to the client")
+	rootCmd.Flags().Int("strict-response-code", 502, "Set the http status code used to replace responses that fail validation when using the strict-responses flag")
 	rootCmd.Flags().BoolP("mock-mode", "x", false, "Run in mock mode, responses are mocked and no traffic is sent to the target API (requires OpenAPI spec)")
+	rootCmd.Flags().Bool("mock-callbacks", false, "Fire the callbacks defined by mocked operations at the URL supplied by the client")
+	rootCmd.Flags().Int("mock-callback-delay", 0, "Delay (in milliseconds) before mocked callbacks and webhooks are fired")
+	rootCmd.Flags().Float64("mock-error-rate", 0, "Fraction (0-1) of mocked responses drawn from the 4xx/5xx responses of an operation instead of the success response")
+	rootCmd.Flags().String("mock-overrides", "", "Directory of hand-crafted mock bodies, named by operationId (or METHOD/path), that replace generated mocks")
+	rootCmd.Flags().Bool("mock-fallback", false, "Proxy requests to the API, and mock them when the API responds with 404 / 501 or cannot be reached")
+	rootCmd.Flags().String("asyncapi", "", "Set the path to an AsyncAPI specification, its channels are mocked as websockets, or validated when proxied")
+	rootCmd.Flags().String("graphql", "", "Set the path to a GraphQL schema (SDL), or the URL of a GraphQL API to introspect, GraphQL requests are validated against it")
+	rootCmd.Flags().String("graphql-path", "", "Set the path GraphQL requests are sent to (default is /graphql)")
+	rootCmd.Flags().String("grpc", "", "Set the path to a protobuf descriptor set (protoc --descriptor_set_out --include_imports), or the URL of a gRPC server to read descriptors from with server reflection, gRPC calls are decoded using them")
+	rootCmd.Flags().Bool("grpc-validate", false, "Validate gRPC messages against their types (unknown fields, missing required fields, message counts), as well as decoding them")
+	rootCmd.Flags().Bool("grpc-transcode", false, "Transcode RESTful JSON requests into gRPC calls, using the google.api.http routes of the methods described by --grpc (methods without routes are bound to POST /package.Service/Method)")
+	rootCmd.Flags().Int("asyncapi-interval", 0, "Interval (in milliseconds) between messages emitted by mocked AsyncAPI channels (default is 1000)")
+	rootCmd.Flags().String("mock-validation", "", "How invalid requests are handled when mocking: reject (default, 422 with violations), warn or ignore")
+	rootCmd.Flags().Bool("mock-pagination", false, "Serve consistent pages of a synthetic collection for operations with page, limit, offset or cursor parameters")
+	rootCmd.Flags().String("mock-union-strategy", "", "How the variant of oneOf / anyOf schemas is picked when mocking: first (default), random or rotate")
+	rootCmd.Flags().Int64("mock-seed", 0, "Seed the mock engine, so randomized mocks and fake data are the same across runs (0 is random)")
+	rootCmd.Flags().Bool("mock-stateful", false, "Persist resources written in mock mode (POST/PUT/PATCH/DELETE), so subsequent GET requests return them")
 	rootCmd.Flags().StringP("config", "c", "",
 		"Location of wiretap configuration file to use (default is .wiretap in current directory)")
 	rootCmd.Flags().StringP("base", "b", "", "Set a base path to resolve relative file references from, or a overriding base URL to resolve remote references from")
 	rootCmd.Flags().BoolP("debug", "l", false, "Enable debug logging")
+	rootCmd.Flags().String("log-format", "", "Set the format of logs, 'text' (default) or 'json' (one event per line on stdout, terminal output moves to stderr)")
+	rootCmd.Flags().String("log-level", "", "Set the level of logs, 'debug', 'info', 'warn' (default) or 'error'")
 	rootCmd.Flags().StringP("har", "z", "", "Load a HAR file instead of sniffing traffic")
 	rootCmd.Flags().BoolP("har-validate", "g", false, "Load a HAR file instead of sniffing traffic, and validate against the OpenAPI specification (requires -s)")
+	rootCmd.Flags().String("har-record", "", "Record completed transactions to a HAR file as they happen, so long sessions survive crashes")
+	rootCmd.Flags().String("har-record-format", "", "Format of the recorded HAR file, 'har' (default, kept valid after every entry) or 'ndjson' (an entry per line, for tailing)")
+	rootCmd.Flags().Int("har-record-max-size", 0, "Rotate the recorded HAR file when it reaches a size (in megabytes)")
+	rootCmd.Flags().Int("har-record-rotate", 0, "Rotate the recorded HAR file when it gets to an age (in seconds)")
+	rootCmd.Flags().String("monitor-token", "", "Token required to open the monitor and connect to its websocket (as a bearer token, or ?token= once in a browser)")
+	rootCmd.Flags().String("monitor-user", "", "Username required to open the monitor and connect to its websocket (basic auth)")
+	rootCmd.Flags().String("monitor-password", "", "Password required to open the monitor and connect to its websocket (basic auth)")
+	rootCmd.Flags().String("api-token", "", "Token required to query captured transactions over the API (/api/transactions on the monitor port), generated if not set")
+	rootCmd.Flags().Int("max-transactions", 0, "Keep at most this many transactions in memory, the oldest are evicted first")
+	rootCmd.Flags().Int("max-capture-memory", 0, "Keep at most this many megabytes of transactions in memory, the oldest are evicted first")
+	rootCmd.Flags().Int("monitor-max-body", 0, "Cut request and response bodies over this many kilobytes down before they are streamed to the monitor, they are still captured in full")
+	rootCmd.Flags().Bool("capture-paused", false, "Start with capture paused, traffic is proxied and validated but nothing is kept until capture is resumed (POST /api/capture/resume)")
+	rootCmd.Flags().Float64("capture-sample-rate", 0, "Only keep and broadcast this fraction of transactions (e.g. 0.1), all traffic is still proxied and validated")
+	rootCmd.Flags().String("audit-log", "", "Append every change made while running (delays, variables, specifications, mock overrides) and who made it, to an audit log")
+	rootCmd.Flags().String("access-log", "", "Write an access log of every proxied request in the nginx/Apache combined format to a file ('-' for stdout)")
+	rootCmd.Flags().String("store", "", "Keep captured transactions in a store file, so they survive restarts and reports can be regenerated from them")
+	rootCmd.Flags().Bool("har-playback", false, "Serve recorded responses from the HAR file for requests that match by method, path and query")
 	rootCmd.Flags().StringArrayP("har-allow", "j", nil, "Add a path to the HAR allow list, can use arg multiple times")
 	rootCmd.Flags().StringP("report-filename", "f", "wiretap-report.json", "Filename for any headless report generation output")
 	rootCmd.Flags().BoolP("stream-report", "a", false, "Stream violations to report JSON file as they occur (headless mode)")
+	rootCmd.Flags().String("report-rotate", "", "Rotate the streamed report and recorded HAR file 'hourly' or 'daily', rotated files are named after the hour or day they cover")
+	rootCmd.Flags().Int("report-retain", 0, "Keep only the most recent rotated reports and HAR files, older ones are removed (0 keeps them all)")
+	rootCmd.Flags().Bool("junit-report", false, "Save a JUnit XML report (a test case per operation, a failure per violation) next to the report JSON file when wiretap stops")
+	rootCmd.Flags().Bool("sarif-report", false, "Save a SARIF report (for code scanning, annotating the specification) next to the report JSON file when wiretap stops")
+	rootCmd.Flags().Bool("tail", false, "Print a line per transaction (method, status, latency, path and violations) to the terminal as it happens")
+	rootCmd.Flags().StringArray("tail-filter", nil, "Only tail transactions matching an expression, e.g. 'status >= 400', 'path ~ /pets/*' or 'violations > 0 && method = POST', can use arg multiple times")
+	rootCmd.Flags().Bool("report-violations-only", false, "Only include transactions with violations in reports")
+	rootCmd.Flags().StringSlice("report-severity", nil, "Only include violations of these severities (error, warn, info) in reports, comma separated")
+	rootCmd.Flags().StringArray("report-path", nil, "Only include requests to paths matching this glob (e.g. /pets/*) in reports, can use arg multiple times")
+	rootCmd.Flags().Int("violation-window", 0, "Aggregate identical violations (same operation, rule and field) over a window (in seconds), repeats are reported once with a count and first/last seen times when it closes")
+
+	generateCmd.Flags().StringP("spec", "s", "", "Set the path to the OpenAPI specification to use")
+	generateCmd.Flags().StringP("base", "b", "", "Set a base path to resolve relative file references from, or a overriding base URL to resolve remote references from")
+	generateCmd.Flags().StringP("url", "u", "http://localhost:9090", "Set the URL to send generated requests to, wiretap by default")
+	generateCmd.Flags().StringP("operation", "o", "", "Set the operationId of the operation to generate requests for")
+	generateCmd.Flags().IntP("count", "n", 1, "Set the number of requests to generate")
+	generateCmd.Flags().StringP("vary", "r", "required", "How requests vary: 'none' (use examples), 'required' (required fields only), 'all' (all fields)")
+	generateCmd.Flags().StringArrayP("header", "H", nil, "Add a header to every request (e.g. 'Authorization: Bearer 123'), can use arg multiple times")
+	rootCmd.AddCommand(generateCmd)
+
+	reportCmd.Flags().String("store", "", "Set the transaction store to generate the report from")
+	reportCmd.Flags().StringP("report-filename", "f", "wiretap-report.json", "Filename for the generated report")
+	reportCmd.Flags().StringSlice("severity", nil, "Only include violations of these severities (error, warn, info), comma separated")
+	reportCmd.Flags().StringArray("path", nil, "Only include requests to paths matching this glob (e.g. /pets/*), can use arg multiple times")
+	rootCmd.AddCommand(reportCmd)
+
+	replayCmd.Flags().StringP("spec", "s", "", "Set the path to the OpenAPI specification to validate replayed requests and responses against")
+	replayCmd.Flags().StringP("base", "b", "", "Set a base path to resolve relative file references from, or a overriding base URL to resolve remote references from")
+	replayCmd.Flags().StringP("url", "u", "", "Set the URL of the target to replay requests against, e.g. http://localhost:8080")
+	replayCmd.Flags().Float64("speed", 1, "Replay at a multiple of the captured pace (e.g. 2 is twice as fast), 0 replays as fast as possible")
+	replayCmd.Flags().StringP("report-filename", "f", "wiretap-replay-report.json", "Filename for the violation report")
+	rootCmd.AddCommand(replayCmd)
+
+	diffCmd.Flags().StringP("spec", "s", "", "Set the path to the OpenAPI specification to validate both sessions against")
+	diffCmd.Flags().StringP("base", "b", "", "Set a base path to resolve relative file references from, or a overriding base URL to resolve remote references from")
+	diffCmd.Flags().Float64("latency-threshold", 20, "Report a latency regression when the p95 latency of an operation is slower by more than this percentage")
+	diffCmd.Flags().StringP("report-filename", "f", "wiretap-diff.json", "Filename for the diff report")
+	rootCmd.AddCommand(diffCmd)
+
+	exportCmd.Flags().String("format", exportPostman, "Set the export format, 'postman' (a collection and an environment) or 'curl' (a shell script)")
+	exportCmd.Flags().StringP("config", "c", "", "Location of the wiretap configuration file to derive the environment from (its variables)")
+	exportCmd.Flags().StringP("filename", "f", "", "Filename for the exported collection or script (default is wiretap-postman.json or wiretap-curl.sh)")
+	exportCmd.Flags().StringP("requests", "r", "", "Select the requests to export by their position in the session, e.g. '1,4-6' (default is every request)")
+	exportCmd.Flags().StringP("environment-filename", "e", "wiretap-postman-environment.json", "Filename for the exported environment")
+	rootCmd.AddCommand(exportCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -651,6 +1877,38 @@ func printLoadedPathConfigurations(configs map[string]*shared.WiretapPathConfig)
 		if v.Auth != "" {
 			pterm.Printf("🔒 Basic authentication implemented for '%s'\n", pterm.LightMagenta(k))
 		}
+		if v.WebSocket != "" {
+			pterm.Printf("🔌 WebSocket upgrades set to '%s'\n", pterm.LightCyan(v.WebSocket))
+		}
+		if v.Cache != nil {
+			ttl := v.Cache.TTL
+			if ttl == "" {
+				ttl = shared.DefaultCacheTTL.String()
+			}
+			pterm.Printf("💾 GET responses cached for %s\n", pterm.LightCyan(ttl))
+		}
+		pterm.Println()
+	}
+}
+
+func printLoadedHostConfigurations(hosts map[string]*shared.WiretapHostConfig) {
+	pterm.Info.Printf("Loaded %d host %s:\n", len(hosts),
+		shared.Pluralize(len(hosts), "configuration", "configurations"))
+	pterm.Println()
+
+	for k, v := range hosts {
+		label := v.Label
+		if label == "" {
+			label = "(host and port)"
+		}
+		pterm.Printf("🌐 %s labeled as %s\n", pterm.LightMagenta(k), pterm.LightCyan(label))
+		if v.Spec != "" {
+			pterm.Printf("📄 Validated against '%s'\n", pterm.LightGreen(v.Spec))
+		}
+		if len(v.PathConfigurations) > 0 {
+			pterm.Printf("🛣️  %d path %s bundled\n", len(v.PathConfigurations),
+				shared.Pluralize(len(v.PathConfigurations), "configuration", "configurations"))
+		}
 		pterm.Println()
 	}
 }
@@ -666,6 +1924,83 @@ func printLoadedPathDelayConfigurations(pathDelays map[string]int) {
 
 }
 
+func printLoadedGraphQLDelayConfigurations(graphqlDelays map[string]int) {
+	pterm.Info.Printf("Loaded %d GraphQL operation %s:\n", len(graphqlDelays),
+		shared.Pluralize(len(graphqlDelays), "delay", "delays"))
+
+	for k, v := range graphqlDelays {
+		pterm.Printf("⏱️ %sms --> %s\n", pterm.LightCyan(v), pterm.LightMagenta(k))
+	}
+	pterm.Println()
+
+}
+
+func printLoadedMockLatencyConfigurations(latency map[string]*shared.WiretapLatencyConfig) {
+	pterm.Info.Printf("Loaded %d mock %s:\n", len(latency),
+		shared.Pluralize(len(latency), "latency", "latencies"))
+
+	for k, v := range latency {
+		switch strings.ToLower(v.Distribution) {
+		case shared.LatencyUniform:
+			pterm.Printf("⏱️ uniform %s-%sms --> %s\n", pterm.LightCyan(v.Min), pterm.LightCyan(v.Max), pterm.LightMagenta(k))
+		case shared.LatencyNormal:
+			pterm.Printf("⏱️ normal %sms ± %sms --> %s\n", pterm.LightCyan(v.Delay), pterm.LightCyan(v.StdDev), pterm.LightMagenta(k))
+		default:
+			pterm.Printf("⏱️ fixed %sms --> %s\n", pterm.LightCyan(v.Delay), pterm.LightMagenta(k))
+		}
+	}
+	pterm.Println()
+}
+
+func printLoadedMockPaths(mockPaths map[string]bool) {
+	pterm.Info.Printf("Loaded %d mock %s:\n", len(mockPaths), shared.Pluralize(len(mockPaths), "path", "paths"))
+
+	for k, v := range mockPaths {
+		mode := "proxy"
+		if v {
+			mode = "mock"
+		}
+		pterm.Printf("🎭 %s --> %s\n", pterm.LightCyan(mode), pterm.LightMagenta(k))
+	}
+	pterm.Println()
+}
+
+func printLoadedIntercepts(intercepts []string, timeout int) {
+	if timeout <= 0 {
+		timeout = 60
+	}
+	pterm.Info.Printf("Holding requests matching %d intercept %s, for up to %ds each:\n", len(intercepts),
+		shared.Pluralize(len(intercepts), "rule", "rules"), timeout)
+	for _, rule := range intercepts {
+		pterm.Printf("✋ %s\n", pterm.LightMagenta(rule))
+	}
+	pterm.Println()
+}
+
+func printTranscodedRoutes(files *protoregistry.Files) {
+	transcoder, errs := grpc.NewTranscoder(files)
+	for _, err := range errs {
+		pterm.Warning.Printf("gRPC route skipped: %s\n", err.Error())
+	}
+	routes := transcoder.Routes()
+	pterm.Info.Printf("Transcoding %d RESTful %s into gRPC calls:\n", len(routes),
+		shared.Pluralize(len(routes), "route", "routes"))
+	for _, route := range routes {
+		pterm.Printf("🧬 %s %s --> %s\n", pterm.LightCyan(route.HTTPMethod), pterm.LightMagenta(route.Template),
+			pterm.LightGreen(string(route.Method.FullName())))
+	}
+	pterm.Println()
+}
+
+func printLoadedWebhookPaths(webhookPaths map[string]string) {
+	pterm.Info.Printf("Loaded %d webhook %s:\n", len(webhookPaths),
+		shared.Pluralize(len(webhookPaths), "path", "paths"))
+	for k, v := range webhookPaths {
+		pterm.Printf("🪝 Calls to '%s' are validated as the webhook '%s'\n", pterm.LightMagenta(k), pterm.LightCyan(v))
+	}
+	pterm.Println()
+}
+
 func printLoadedVariables(variables map[string]string) {
 	pterm.Info.Printf("Loaded %d %s:\n", len(variables),
 		shared.Pluralize(len(variables), "variable", "variables"))
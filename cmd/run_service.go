@@ -34,21 +34,21 @@ func runWiretapService(wiretapConfig *shared.WiretapConfiguration, doc libopenap
 	// create a new ranch config.
 	ranchConfig, _ := server.CreateServerConfig()
 	ranchConfig.Port, _ = strconv.Atoi(wiretapConfig.WebSocketPort)
-	ranchConfig.FabricConfig.EndpointConfig.Heartbeat = 0
 	ranchConfig.Logger = wiretapConfig.Logger
 
 	// running TLS?
-	if wiretapConfig.CertificateKey != "" && wiretapConfig.Certificate != "" {
+	if certificate, key := wiretapConfig.MonitorCertificates(); certificate != "" {
 		tlsConfig := &server.TLSCertConfig{
-			CertFile:                  wiretapConfig.Certificate,
-			KeyFile:                   wiretapConfig.CertificateKey,
+			CertFile:                  certificate,
+			KeyFile:                   key,
 			SkipCertificateValidation: true,
 		}
 		ranchConfig.TLSCertConfig = tlsConfig
 	}
 
-	// create an application fabric configuration for the ranch server.
-	ranchConfig.FabricConfig = &server.FabricBrokerConfig{
+	// create an application fabric configuration for the broker, wiretap starts the broker itself (so monitors can
+	// stream compressed), rather than ranch.
+	fabricConfig := &server.FabricBrokerConfig{
 		FabricEndpoint: "/ranch",
 		EndpointConfig: &bus.EndpointConfig{
 			Heartbeat:             0,
@@ -59,20 +59,33 @@ func runWiretapService(wiretapConfig *shared.WiretapConfiguration, doc libopenap
 		},
 	}
 
+	ranchConfig.FabricConfig = nil
+	bus.GetBus().GetChannelManager().CreateChannel(bus.STOMP_SESSION_NOTIFY_CHANNEL)
+
 	// create an instance of ranch
 	platformServer := server.NewPlatformServer(ranchConfig)
+	bindPlatformServer(wiretapConfig, platformServer)
 
 	// create wiretap service
 	wtService := daemon.NewWiretapService(doc, wiretapConfig)
+	if wiretapConfig.Contract != "" {
+		wtService.SetSpecificationLoader(func() (libopenapi.Document, error) {
+			return loadOpenAPISpec(wiretapConfig.Contract, wiretapConfig.Base, wiretapConfig.OverlayDocuments...)
+		})
+		if wiretapConfig.WatchSpec {
+			wtService.WatchSpecification(wiretapConfig.Contract)
+		}
+	}
 
 	// register wiretap service
 	if err = platformServer.RegisterService(wtService, daemon.WiretapServiceChan); err != nil {
 		panic(err)
 	}
 
-	// register spec service
-	if err = platformServer.RegisterService(
-		specs.NewSpecService(doc), specs.SpecServiceChan); err != nil {
+	// register spec service, keep it up to date when the specification is reloaded.
+	specService := specs.NewSpecService(doc)
+	wtService.OnSpecificationChange(specService.SetDocument)
+	if err = platformServer.RegisterService(specService, specs.SpecServiceChan); err != nil {
 		panic(err)
 	}
 
@@ -84,7 +97,7 @@ func runWiretapService(wiretapConfig *shared.WiretapConfiguration, doc libopenap
 
 	// register report service
 	if err = platformServer.RegisterService(
-		report.NewReportService(), report.ReportServiceChan); err != nil {
+		report.NewReportService(wtService), report.ReportServiceChan); err != nil {
 		panic(err)
 	}
 
@@ -110,14 +123,68 @@ func runWiretapService(wiretapConfig *shared.WiretapConfiguration, doc libopenap
 	handleHttpTraffic(wiretapConfig, wtService)
 
 	// boot the monitor
-	serveMonitor(wiretapConfig)
+	serveMonitor(wiretapConfig, wtService)
+
+	// print transactions to the terminal as they happen, for those who never open the monitor.
+	tailTransactions(wiretapConfig, wtService)
+
+	// boot the metrics, if they have a port of their own, and push them if there is somewhere to push them.
+	serveMetrics(wiretapConfig, wtService)
+
+	// only let authorized monitors connect, and record who connects, if changes are being audited.
+	protectMonitorConnections(wiretapConfig, platformServer, fabricConfig.FabricEndpoint)
+	auditMonitorConnections(wiretapConfig, platformServer, fabricConfig.FabricEndpoint)
+
+	// boot the broker monitors stream transactions from.
+	serveMonitorStream(wiretapConfig, platformServer, fabricConfig)
+
+	// boot the admin endpoints (pprof, goroutines and runtime stats), if they have been asked for.
+	serveAdmin(wiretapConfig, wtService)
+	statsd := pushMetrics(wiretapConfig, wtService)
 
 	// if static dir is configured, monitor static content
 	if wiretapConfig.StaticDir != "" {
 		daemon.MonitorStatic(wiretapConfig)
 	}
 
+	// in CI mode, a test suite can be run against wiretap, wiretap stops when it's done.
+	var command *ciCommand
+	if wiretapConfig.CI && wiretapConfig.CICommand != "" {
+		command = runCICommand(wiretapConfig, sysChan)
+	}
+
 	// boot wiretap
 	platformServer.StartServer(sysChan)
+	_ = bus.GetBus().StopFabricEndpoint()
+
+	// coverage and upstream latency are reported alongside streamed violations, and in CI mode.
+	if wiretapConfig.StreamReport || wiretapConfig.CI {
+		wtService.WaitForValidation()
+		writeCoverageReport(wiretapConfig, wtService.Coverage())
+		writeLatencyReport(wiretapConfig, wtService.Latency())
+	}
+	if wiretapConfig.JUnitReport || wiretapConfig.SARIFReport {
+		wtService.WaitForValidation()
+		if wiretapConfig.JUnitReport {
+			writeJUnitReport(wiretapConfig, wtService)
+		}
+		if wiretapConfig.SARIFReport {
+			writeSARIFReport(wiretapConfig, wtService)
+		}
+	}
+
+	// send any spans and metrics that are left, close the recorded HAR file, the transaction store and the audit log.
+	wtService.StopTracing()
+	if statsd != nil {
+		statsd.Close()
+	}
+	wtService.StopHARRecording()
+	wtService.CloseTransactionStore()
+	_ = wiretapConfig.Auditor.Close()
+
+	// in CI mode, the violations found decide how wiretap exits.
+	if wiretapConfig.CI {
+		os.Exit(ciGate(wiretapConfig, wtService, command))
+	}
 	return platformServer, nil
 }
@@ -0,0 +1,39 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/report"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+// sarifFilename puts the SARIF report next to the violation report, e.g. wiretap-report.sarif.
+func sarifFilename(reportFile string) string {
+	if reportFile == "" {
+		reportFile = "wiretap-report.json"
+	}
+	return strings.TrimSuffix(reportFile, filepath.Ext(reportFile)) + ".sarif"
+}
+
+// writeSARIFReport saves a SARIF report of the violations found, located in the specification.
+func writeSARIFReport(wiretapConfig *shared.WiretapConfiguration, wtService *daemon.WiretapService) {
+	filename := sarifFilename(wiretapConfig.ReportFile)
+	b, err := report.MarshalSARIF(report.BuildSARIF(wtService.OperationResults(), wiretapConfig.Contract,
+		wiretapConfig.Version))
+	if err == nil {
+		err = os.WriteFile(filename, b, 0644)
+	}
+	if err != nil {
+		pterm.Error.Printf("Unable to write SARIF report: %s\n", err.Error())
+		return
+	}
+	pterm.Printf("SARIF report saved to: %s\n", pterm.LightMagenta(filename))
+}
@@ -0,0 +1,134 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+// runtimeStats is a snapshot of the memory, garbage collection and goroutines of wiretap, and how many
+// transactions it's holding on to.
+type runtimeStats struct {
+	Uptime        string     `json:"uptime"`
+	Goroutines    int        `json:"goroutines"`
+	Transactions  int        `json:"transactions"`
+	HeapAlloc     uint64     `json:"heapAllocBytes"`
+	HeapInuse     uint64     `json:"heapInuseBytes"`
+	HeapObjects   uint64     `json:"heapObjects"`
+	HeapReleased  uint64     `json:"heapReleasedBytes"`
+	StackInuse    uint64     `json:"stackInuseBytes"`
+	Sys           uint64     `json:"sysBytes"`
+	TotalAlloc    uint64     `json:"totalAllocBytes"`
+	NextGC        uint64     `json:"nextGCBytes"`
+	NumGC         uint32     `json:"numGC"`
+	LastGC        *time.Time `json:"lastGC,omitempty"`
+	PauseTotal    string     `json:"gcPauseTotal"`
+	RecentPauses  []string   `json:"gcRecentPauses,omitempty"`
+	GCCPUFraction float64    `json:"gcCPUFraction"`
+}
+
+// serveAdmin serves pprof profiles, goroutine dumps and runtime stats on an admin port of its own, to diagnose
+// memory growth during long capture sessions. It's opt-in, and only listens on localhost unless bound elsewhere.
+func serveAdmin(wiretapConfig *shared.WiretapConfiguration, wtService *daemon.WiretapService) {
+	listener, err := listenAdmin(wiretapConfig)
+	if err != nil {
+		pterm.Error.Printf("Cannot serve admin endpoints: %s\n", err.Error())
+		return
+	}
+	if listener == nil {
+		return
+	}
+	pterm.Info.Println(pterm.LightMagenta(fmt.Sprintf("Admin endpoints booting on %s...", listener.Addr())))
+	handler := adminHandler(time.Now(), wtService)
+	go func() {
+		if sErr := http.Serve(listener, handler); sErr != nil {
+			pterm.Error.Printf("Cannot serve admin endpoints: %s\n", sErr.Error())
+		}
+	}()
+}
+
+// listenAdmin binds the admin port, there is nothing to listen on (and no error) unless an admin port is configured.
+func listenAdmin(wiretapConfig *shared.WiretapConfiguration) (net.Listener, error) {
+	if wiretapConfig.AdminPort == "" {
+		return nil, nil
+	}
+	return net.Listen("tcp", net.JoinHostPort(wiretapConfig.AdminAddress, wiretapConfig.AdminPort))
+}
+
+// adminHandler serves the admin endpoints: pprof, goroutine dumps and runtime stats.
+func adminHandler(started time.Time, wtService *daemon.WiretapService) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// every goroutine, with its stack.
+	mux.HandleFunc("/debug/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		buf := make([]byte, 1<<20)
+		for {
+			n := runtime.Stack(buf, true)
+			if n < len(buf) {
+				_, _ = w.Write(buf[:n])
+				return
+			}
+			buf = make([]byte, len(buf)*2)
+		}
+	})
+
+	// memory and garbage collection stats, a POST collects garbage (and returns memory to the OS) first.
+	mux.HandleFunc("/debug/runtime", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			debug.FreeOSMemory()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(readRuntimeStats(started, wtService))
+	})
+	return mux
+}
+
+func readRuntimeStats(started time.Time, wtService *daemon.WiretapService) *runtimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+
+	stats := &runtimeStats{
+		Uptime:        time.Since(started).Round(time.Second).String(),
+		Goroutines:    runtime.NumGoroutine(),
+		Transactions:  wtService.TransactionCount(),
+		HeapAlloc:     mem.HeapAlloc,
+		HeapInuse:     mem.HeapInuse,
+		HeapObjects:   mem.HeapObjects,
+		HeapReleased:  mem.HeapReleased,
+		StackInuse:    mem.StackInuse,
+		Sys:           mem.Sys,
+		TotalAlloc:    mem.TotalAlloc,
+		NextGC:        mem.NextGC,
+		NumGC:         mem.NumGC,
+		PauseTotal:    gc.PauseTotal.String(),
+		GCCPUFraction: mem.GCCPUFraction,
+	}
+	if !gc.LastGC.IsZero() {
+		stats.LastGC = &gc.LastGC
+	}
+	for i := 0; i < len(gc.Pause) && i < 10; i++ {
+		stats.RecentPauses = append(stats.RecentPauses, gc.Pause[i].String())
+	}
+	return stats
+}
@@ -0,0 +1,71 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenAdmin_Disabled(t *testing.T) {
+	// nothing is bound unless an admin port is asked for, not even on localhost.
+	listener, err := listenAdmin(&shared.WiretapConfiguration{AdminAddress: "127.0.0.1"})
+	assert.NoError(t, err)
+	assert.Nil(t, listener)
+}
+
+func TestListenAdmin_Enabled(t *testing.T) {
+	listener, err := listenAdmin(&shared.WiretapConfiguration{AdminAddress: "127.0.0.1", AdminPort: "0"})
+	require.NoError(t, err)
+	require.NotNil(t, listener)
+	defer listener.Close()
+
+	host, _, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+
+	// the port is taken, so binding it again fails rather than serving somewhere else.
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	_, err = listenAdmin(&shared.WiretapConfiguration{AdminAddress: "127.0.0.1", AdminPort: port})
+	assert.Error(t, err)
+}
+
+func TestAdminHandler(t *testing.T) {
+	wtService := daemon.NewWiretapService(nil,
+		&shared.WiretapConfiguration{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	server := httptest.NewServer(adminHandler(time.Now(), wtService))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/debug/pprof/")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/debug/goroutines")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Contains(t, string(body), "goroutine")
+
+	resp, err = http.Post(server.URL+"/debug/runtime", "", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var stats runtimeStats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	assert.Positive(t, stats.Goroutines)
+	assert.Positive(t, stats.NumGC, "a POST collects garbage first")
+}
@@ -0,0 +1,62 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/metrics"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+// serveMetrics serves the Prometheus metrics on a port of their own, they are always served by the monitor too.
+func serveMetrics(wiretapConfig *shared.WiretapConfiguration, wtService *daemon.WiretapService) {
+	listener, err := listenMetrics(wiretapConfig)
+	if err != nil {
+		pterm.Error.Printf("Cannot serve metrics: %s\n", err.Error())
+		return
+	}
+	if listener == nil {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", wtService.Metrics())
+
+	pterm.Info.Println(pterm.LightMagenta(fmt.Sprintf("Metrics booting on port %s...", wiretapConfig.MetricsPort)))
+	go func() {
+		if sErr := http.Serve(listener, mux); sErr != nil {
+			pterm.Error.Printf("Cannot serve metrics: %s\n", sErr.Error())
+		}
+	}()
+}
+
+// listenMetrics binds the metrics port, at the bind address. There is nothing to listen on (and no error) unless a
+// metrics port is configured.
+func listenMetrics(wiretapConfig *shared.WiretapConfiguration) (net.Listener, error) {
+	if wiretapConfig.MetricsPort == "" {
+		return nil, nil
+	}
+	return net.Listen("tcp", wiretapConfig.ListenAddress(wiretapConfig.MetricsPort))
+}
+
+// pushMetrics pushes metrics to a StatsD (or DogStatsD) agent as they change, for environments that can't scrape.
+// The agent is returned so anything left can be sent when wiretap stops, nil if metrics aren't pushed.
+func pushMetrics(wiretapConfig *shared.WiretapConfiguration, wtService *daemon.WiretapService) *metrics.StatsD {
+	if wiretapConfig.StatsDAddress == "" {
+		return nil
+	}
+	statsd, err := metrics.NewStatsD(wiretapConfig.StatsDAddress, wiretapConfig.StatsDPrefix, wiretapConfig.StatsDTags,
+		wiretapConfig.StatsDFormat)
+	if err != nil {
+		pterm.Error.Printf("Cannot push metrics to StatsD: %s\n", err.Error())
+		return nil
+	}
+	wtService.PushMetrics(statsd)
+	return statsd
+}
@@ -0,0 +1,44 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenMetrics(t *testing.T) {
+	listener, err := listenMetrics(&shared.WiretapConfiguration{BindAddress: "127.0.0.1"})
+	assert.NoError(t, err)
+	assert.Nil(t, listener, "metrics have no port of their own unless one is configured")
+
+	tests := []struct {
+		bind string
+		host string
+	}{
+		{"", ""}, // every interface.
+		{"127.0.0.1", "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run("bind "+tt.bind, func(t *testing.T) {
+			listener, err := listenMetrics(&shared.WiretapConfiguration{BindAddress: tt.bind, MetricsPort: "0"})
+			require.NoError(t, err)
+			defer listener.Close()
+			host, _, _ := net.SplitHostPort(listener.Addr().String())
+			if tt.host == "" {
+				assert.True(t, net.ParseIP(host).IsUnspecified())
+			} else {
+				assert.Equal(t, tt.host, host)
+			}
+		})
+	}
+
+	_, err = listenMetrics(&shared.WiretapConfiguration{BindAddress: "192.0.2.1", MetricsPort: "0"})
+	assert.Error(t, err, "an address that isn't one of this machine's can't be bound")
+}
@@ -5,18 +5,21 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"github.com/gorilla/handlers"
+	"github.com/pb33f/wiretap/daemon"
 	"github.com/pb33f/wiretap/shared"
 	"github.com/pterm/pterm"
 	"io"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 )
 
-func serveMonitor(wiretapConfig *shared.WiretapConfiguration) {
+func serveMonitor(wiretapConfig *shared.WiretapConfiguration, wtService *daemon.WiretapService) {
 	go func() {
 		var err error
 		var staticFS = fs.FS(wiretapConfig.FS)
@@ -47,7 +50,7 @@ func serveMonitor(wiretapConfig *shared.WiretapConfiguration) {
 		indexString := string(bytes)
 
 		useTLS := "false"
-		if wiretapConfig.CertificateKey != "" && wiretapConfig.Certificate != "" {
+		if wiretapConfig.MonitorTLS() {
 			useTLS = "true"
 		}
 		// replace the port in the index.html file and serve it.
@@ -68,19 +71,64 @@ func serveMonitor(wiretapConfig *shared.WiretapConfiguration) {
 		// handle the index
 		mux.HandleFunc("/", handleIndex)
 
+		// coverage of the specification, so far.
+		mux.HandleFunc("/coverage", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(wtService.Coverage())
+		})
+
+		// how long the upstream API has taken to respond, so far.
+		mux.HandleFunc("/latency", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(wtService.Latency())
+		})
+
+		// the transactions captured so far (or those asked for with ?id=), as a Postman collection and environment,
+		// or a curl script.
+		mux.HandleFunc("/export/postman", handlePostmanExport(wiretapConfig, wtService, false))
+		mux.HandleFunc("/export/postman/environment", handlePostmanExport(wiretapConfig, wtService, true))
+		mux.HandleFunc("/export/curl", handleCurlExport(wiretapConfig, wtService))
+
+		// the transactions asked for, by id, in the format asked for (HAR, JSON, curl or Postman).
+		mux.HandleFunc("/export", handleExport(wiretapConfig, wtService))
+
+		// the captured transactions, for test harnesses to query (with the API token).
+		transactionsAPI := requireAPIToken(wiretapConfig.APIToken, handleTransactionsAPI(wtService))
+		mux.Handle("/api/transactions", transactionsAPI)
+		mux.Handle("/api/transactions/", transactionsAPI)
+		mux.Handle("/api/search", requireAPIToken(wiretapConfig.APIToken, handleSearchAPI(wtService)))
+
+		// transactions as they are captured, as server-sent events, for when the websocket can't be used.
+		mux.Handle("/api/stream", requireStreamAuth(wiretapConfig, handleStreamAPI(wtService)))
+
+		// pause and resume capture, while still proxying.
+		captureAPI := requireAPIToken(wiretapConfig.APIToken, handleCaptureAPI())
+		mux.Handle("/api/capture", captureAPI)
+		mux.Handle("/api/capture/", captureAPI)
+
+		// change the global and path delays, while running.
+		mux.Handle("/api/delays", requireAPIToken(wiretapConfig.APIToken, handleDelaysAPI()))
+
+		// switch paths between mock and proxy mode, while running.
+		mux.Handle("/api/mock", requireAPIToken(wiretapConfig.APIToken, handleMockAPI()))
+
+		// hold requests until they are approved, changed or dropped.
+		interceptAPI := requireAPIToken(wiretapConfig.APIToken, handleInterceptAPI(wtService))
+		mux.Handle("/api/intercept", interceptAPI)
+		mux.Handle("/api/intercept/", interceptAPI)
+
+		// metrics, for Prometheus to scrape.
+		mux.Handle("/metrics", wtService.Metrics())
+
 		// compress everything!
 		// handle the assets
 		mux.Handle("/assets/", http.StripPrefix("/assets", handlers.CompressHandler(fileServer)))
 
 		pterm.Info.Println(pterm.LightMagenta(fmt.Sprintf("Monitor UI booting on port %s...", wiretapConfig.MonitorPort)))
 
-		if wiretapConfig.CertificateKey != "" && wiretapConfig.Certificate != "" {
-			err = http.ListenAndServeTLS(fmt.Sprintf(":%s", wiretapConfig.MonitorPort),
-				wiretapConfig.Certificate,
-				wiretapConfig.CertificateKey,
-				handlers.CompressHandler(mux))
-		} else {
-			err = http.ListenAndServe(fmt.Sprintf(":%s", wiretapConfig.MonitorPort), mux)
+		listener, err := net.Listen("tcp", wiretapConfig.ListenAddress(wiretapConfig.MonitorPort))
+		if err == nil {
+			err = serveMonitorListener(wiretapConfig, listener, mux)
 		}
 
 		if err != nil {
@@ -88,3 +136,12 @@ func serveMonitor(wiretapConfig *shared.WiretapConfiguration) {
 		}
 	}()
 }
+
+// serveMonitorListener serves the monitor UI on a listener, over TLS if the monitor has a certificate (its own, or
+// the proxy's).
+func serveMonitorListener(wiretapConfig *shared.WiretapConfiguration, listener net.Listener, mux http.Handler) error {
+	if certificate, key := wiretapConfig.MonitorCertificates(); certificate != "" {
+		return http.ServeTLS(listener, requireMonitorAuth(wiretapConfig, handlers.CompressHandler(mux)), certificate, key)
+	}
+	return http.Serve(listener, requireMonitorAuth(wiretapConfig, mux))
+}
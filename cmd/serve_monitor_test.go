@@ -0,0 +1,101 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCertificate writes a self-signed certificate (and its key) for 127.0.0.1 to a temporary directory.
+func testCertificate(t *testing.T, name string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestServeMonitorListener(t *testing.T) {
+	proxyCert, proxyKey := testCertificate(t, "proxy")
+	monitorCert, monitorKey := testCertificate(t, "monitor")
+
+	tests := []struct {
+		name   string
+		config *shared.WiretapConfiguration
+		served string // the certificate the monitor is served with, plain HTTP if there isn't one.
+	}{
+		{"plain", &shared.WiretapConfiguration{}, ""},
+		{"the proxy's certificate", &shared.WiretapConfiguration{Certificate: proxyCert, CertificateKey: proxyKey},
+			"proxy"},
+		{"its own certificate", &shared.WiretapConfiguration{Certificate: proxyCert, CertificateKey: proxyKey,
+			MonitorCertificate: monitorCert, MonitorCertificateKey: monitorKey}, "monitor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.BindAddress, tt.config.MonitorPort = "127.0.0.1", "0"
+			listener, err := net.Listen("tcp", tt.config.ListenAddress(tt.config.MonitorPort))
+			require.NoError(t, err)
+			defer listener.Close()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "monitor")
+			})
+			go func() { _ = serveMonitorListener(tt.config, listener, mux) }()
+
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+			scheme := "http"
+			if tt.served != "" {
+				scheme = "https"
+			}
+			resp, err := client.Get(scheme + "://" + listener.Addr().String() + "/")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, "monitor", string(body))
+
+			if tt.served == "" {
+				assert.Nil(t, resp.TLS)
+				return
+			}
+			require.NotNil(t, resp.TLS)
+			assert.Equal(t, tt.served, resp.TLS.PeerCertificates[0].Subject.CommonName)
+		})
+	}
+}
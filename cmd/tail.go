@@ -0,0 +1,29 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/tail"
+	"github.com/pterm/pterm"
+)
+
+// tailTransactions prints a line per transaction to the terminal as it completes, if tailing is on.
+func tailTransactions(wiretapConfig *shared.WiretapConfiguration, wtService *daemon.WiretapService) {
+	if !wiretapConfig.Tail {
+		return
+	}
+	filter, err := tail.ParseFilter(wiretapConfig.TailFilters...)
+	if err != nil {
+		pterm.Error.Printf("Cannot tail transactions: %s\n", err.Error())
+		return
+	}
+	wtService.OnTransaction(func(transaction *daemon.HttpTransaction) {
+		if entry := tail.FromTransaction(transaction); filter.Match(entry) {
+			pterm.Println(tail.Format(entry))
+		}
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// streamKeepAlive is how often an idle stream is sent a comment, so proxies don't close it.
+const streamKeepAlive = 15 * time.Second
+
+// requireStreamAuth lets requests through to the stream that carry the API token, or that the monitor would let
+// through. The stream stands in for the monitor's websocket, so it's no more (or less) open than the websocket is.
+func requireStreamAuth(wiretapConfig *shared.WiretapConfiguration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(bearer), []byte(wiretapConfig.APIToken)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !wiretapConfig.MonitorAuth.Authorized(r) {
+			writeAPIError(w, http.StatusUnauthorized, "the API token, or the monitor's credentials, are required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleStreamAPI streams transactions as they are captured, as server-sent events, for clients that can't open a
+// websocket (some proxies refuse the upgrade). Every event is a transaction (or the request or response half of
+// one), as JSON. The stream is filtered like a monitor subscription: path (a glob), method, status, violationsOnly,
+// q (a search expression) and backfill (how many recent transactions to send first).
+func handleStreamAPI(wtService *daemon.WiretapService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, "only GET is supported")
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeAPIError(w, http.StatusInternalServerError, "streaming isn't supported by this connection")
+			return
+		}
+		filter, err := parseStreamFilter(r)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		transactions, err := wtService.StreamTransactions(r.Context(), "stream:"+r.RemoteAddr, filter)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no") // stop nginx from buffering the stream.
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprint(w, "retry: 3000\n\n")
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				if _, err = fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case transaction := <-transactions:
+				data, mErr := json.Marshal(transaction)
+				if mErr != nil {
+					continue
+				}
+				if _, err = fmt.Fprintf(w, "event: transaction\ndata: %s\n\n", data); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// parseStreamFilter reads the filter of a stream from its URL parameters.
+func parseStreamFilter(r *http.Request) (*daemon.TransactionFilter, error) {
+	values := r.URL.Query()
+	filter := &daemon.TransactionFilter{Search: values.Get("q"), Path: values.Get("path")}
+	for _, method := range values["method"] {
+		filter.Methods = append(filter.Methods, strings.Split(method, ",")...)
+	}
+	for _, status := range values["status"] {
+		filter.Statuses = append(filter.Statuses, strings.Split(status, ",")...)
+	}
+	if v := values.Get("violationsOnly"); v != "" {
+		violationsOnly, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("violationsOnly has to be true or false, not '%s'", v)
+		}
+		filter.ViolationsOnly = violationsOnly
+	}
+	if v := values.Get("backfill"); v != "" {
+		backfill, err := strconv.Atoi(v)
+		if err != nil || backfill < 0 {
+			return nil, fmt.Errorf("backfill has to be a number of transactions, not '%s'", v)
+		}
+		filter.Backfill = backfill
+	}
+	return filter, nil
+}
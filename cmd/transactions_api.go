@@ -0,0 +1,338 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/wiretap/controls"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// generateAPIToken creates a random token for the API, when one hasn't been configured.
+func generateAPIToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requireAPIToken only lets requests through that carry the API token, as a bearer token.
+func requireAPIToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="wiretap"`)
+			writeAPIError(w, http.StatusUnauthorized, "a valid API token is required, send it as 'Authorization: Bearer <token>'")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiClientID identifies who sent a request to the API, for the audit log: the address it came from.
+func apiClientID(r *http.Request) string {
+	client := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		client = host
+	}
+	return "api:" + client
+}
+
+// handleTransactionsAPI serves the captured transactions, a page of them matching a query at /api/transactions,
+// or a single transaction at /api/transactions/<id>. A POST to /api/transactions/<id>/resend sends a captured
+// request again, with any changes to make to it.
+func handleTransactionsAPI(wtService *daemon.WiretapService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := strings.CutSuffix(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/transactions"), "/"), "/resend"); ok {
+			handleResend(wtService, id, w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, "only GET is supported")
+			return
+		}
+		transactions, err := wtService.Transactions()
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		if id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/transactions"), "/"); id != "" {
+			for _, transaction := range transactions {
+				if transaction.Id == id {
+					_ = json.NewEncoder(w).Encode(transaction)
+					return
+				}
+			}
+			writeAPIError(w, http.StatusNotFound, "no transaction has been captured with id '"+id+"'")
+			return
+		}
+
+		query, qErr := daemon.ParseTransactionQuery(r.URL.Query())
+		if qErr != nil {
+			writeAPIError(w, http.StatusBadRequest, qErr.Error())
+			return
+		}
+		_ = json.NewEncoder(w).Encode(query.Apply(transactions))
+	}
+}
+
+// handleResend sends a captured request again, the changes to make to it (method, path, headers, body and target)
+// are the body of the request. The response is what came back, and the id of the new transaction.
+func handleResend(wtService *daemon.WiretapService, id string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "requests are re-sent with POST")
+		return
+	}
+	var resend daemon.ResendTransaction
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&resend); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "changes have to be sent as JSON: "+err.Error())
+			return
+		}
+	}
+	resend.Id = id
+	response, err := wtService.Resend(r.Context(), &resend)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// handleSearchAPI serves a page of the captured transactions matching a search expression, given as q. The other
+// parameters of /api/transactions can be used alongside it.
+func handleSearchAPI(wtService *daemon.WiretapService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, "only GET is supported")
+			return
+		}
+		if strings.TrimSpace(r.URL.Query().Get("q")) == "" {
+			writeAPIError(w, http.StatusBadRequest, "a search is required, e.g. ?q=method:POST path:/users/* status:>=500")
+			return
+		}
+		query, err := daemon.ParseTransactionQuery(r.URL.Query())
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		page, err := wtService.SearchTransactions(query)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(page)
+	}
+}
+
+// captureState is the response of the capture API.
+type captureState struct {
+	Paused bool `json:"paused"`
+}
+
+// handleCaptureAPI reports if capture is paused at /api/capture, and pauses or resumes it with a POST to
+// /api/capture/pause or /api/capture/resume. Traffic is proxied and validated either way.
+func handleCaptureAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/capture"), "/")
+		var config *shared.WiretapConfiguration
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+			config = bus.GetBus().GetStoreManager().GetStore(controls.ControlServiceChan).
+				GetValue(shared.ConfigKey).(*shared.WiretapConfiguration)
+		case (action == "pause" || action == "resume") && r.Method == http.MethodPost:
+			config = controls.SetCapturePaused(action == "pause", apiClientID(r))
+		case action == "" || action == "pause" || action == "resume":
+			writeAPIError(w, http.StatusMethodNotAllowed, "read the capture state with GET, pause or resume it with POST")
+			return
+		default:
+			writeAPIError(w, http.StatusNotFound, "use /api/capture, /api/capture/pause or /api/capture/resume")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&captureState{Paused: config.IsCapturePaused()})
+	}
+}
+
+// mockState is the response of the mock API.
+type mockState struct {
+	MockMode  bool            `json:"mockMode"`
+	MockPaths map[string]bool `json:"mockPaths"`
+}
+
+// handleMockAPI reports the mock mode and the paths switched to mock (true) or proxy (false) mode at /api/mock.
+// A PUT switches paths, sent as mockPaths, and paths sent as reset go back to the mock mode of everything else.
+// A DELETE resets every path.
+func handleMockAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := apiClientID(r)
+
+		config := bus.GetBus().GetStoreManager().GetStore(controls.ControlServiceChan).
+			GetValue(shared.ConfigKey).(*shared.WiretapConfiguration)
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var change controls.MockPathsChange
+			if dErr := json.NewDecoder(r.Body).Decode(&change); dErr != nil {
+				writeAPIError(w, http.StatusBadRequest, "mock paths have to be sent as JSON: "+dErr.Error())
+				return
+			}
+			config, err = controls.SetMockPaths(&change, client)
+		case http.MethodDelete:
+			config, err = controls.SetMockPaths(&controls.MockPathsChange{ResetAll: true}, client)
+		default:
+			writeAPIError(w, http.StatusMethodNotAllowed, "read mock paths with GET, switch them with PUT, reset them with DELETE")
+			return
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&mockState{MockMode: config.MockMode, MockPaths: config.GetMockPaths()})
+	}
+}
+
+// delayState is the response of the delays API, and (with replace) what is sent to change delays.
+type delayState struct {
+	GlobalDelay *int           `json:"globalDelay,omitempty"`
+	PathDelays  map[string]int `json:"pathDelays"`
+	Replace     bool           `json:"replace,omitempty"`
+}
+
+// handleDelaysAPI reports the global and path delays at /api/delays. A PUT changes them: the global delay if one is
+// sent, and the path delays sent (a delay of zero removes a path, replace removes every path not sent). A DELETE
+// removes every delay.
+func handleDelaysAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := apiClientID(r)
+
+		config := bus.GetBus().GetStoreManager().GetStore(controls.ControlServiceChan).
+			GetValue(shared.ConfigKey).(*shared.WiretapConfiguration)
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var change delayState
+			if dErr := json.NewDecoder(r.Body).Decode(&change); dErr != nil {
+				writeAPIError(w, http.StatusBadRequest, "delays have to be sent as JSON: "+dErr.Error())
+				return
+			}
+			if change.GlobalDelay != nil {
+				config, err = controls.SetGlobalDelay(*change.GlobalDelay, client)
+			}
+			if err == nil && (len(change.PathDelays) > 0 || change.Replace) {
+				config, err = controls.SetPathDelays(
+					&controls.PathDelaysChange{PathDelays: change.PathDelays, Replace: change.Replace}, client)
+			}
+		case http.MethodDelete:
+			_, _ = controls.SetGlobalDelay(0, client)
+			config, err = controls.SetPathDelays(&controls.PathDelaysChange{Replace: true}, client)
+		default:
+			writeAPIError(w, http.StatusMethodNotAllowed, "read delays with GET, change them with PUT, remove them with DELETE")
+			return
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		globalDelay := config.GetGlobalAPIDelay()
+		_ = json.NewEncoder(w).Encode(&delayState{GlobalDelay: &globalDelay, PathDelays: config.GetPathDelays()})
+	}
+}
+
+// interceptState is the response of the intercept API, and (without held) what is sent to change the rules.
+type interceptState struct {
+	Intercept []string                     `json:"intercept"`
+	Timeout   *int                         `json:"timeout,omitempty"`
+	Held      []*daemon.InterceptedRequest `json:"held"`
+}
+
+// handleInterceptAPI reports the intercept rules and the requests being held at /api/intercept. A PUT replaces the
+// rules (and the timeout, if one is sent), a DELETE removes every rule. A POST to /api/intercept/<id>/approve sends a
+// held request on, with any changes to make to it as the body, and /api/intercept/<id>/drop drops it.
+func handleInterceptAPI(wtService *daemon.WiretapService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := apiClientID(r)
+
+		if action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/intercept"), "/"); action != "" {
+			id, verb, _ := strings.Cut(action, "/")
+			if verb != "approve" && verb != "drop" {
+				writeAPIError(w, http.StatusNotFound, "use /api/intercept/<id>/approve or /api/intercept/<id>/drop")
+				return
+			}
+			if r.Method != http.MethodPost {
+				writeAPIError(w, http.StatusMethodNotAllowed, "held requests are approved or dropped with POST")
+				return
+			}
+			var decision daemon.InterceptDecision
+			if verb == "approve" && r.ContentLength != 0 {
+				if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+					writeAPIError(w, http.StatusBadRequest, "changes have to be sent as JSON: "+err.Error())
+					return
+				}
+			}
+			decision.Id, decision.Drop = id, verb == "drop"
+			if err := wtService.ReleaseIntercepted(&decision, client); err != nil {
+				writeAPIError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		config := bus.GetBus().GetStoreManager().GetStore(controls.ControlServiceChan).
+			GetValue(shared.ConfigKey).(*shared.WiretapConfiguration)
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var change interceptState
+			if dErr := json.NewDecoder(r.Body).Decode(&change); dErr != nil {
+				writeAPIError(w, http.StatusBadRequest, "intercept rules have to be sent as JSON: "+dErr.Error())
+				return
+			}
+			config, err = controls.SetIntercept(
+				&controls.InterceptChange{Intercept: change.Intercept, Timeout: change.Timeout}, client)
+		case http.MethodDelete:
+			config, err = controls.SetIntercept(&controls.InterceptChange{}, client)
+		default:
+			writeAPIError(w, http.StatusMethodNotAllowed,
+				"read intercept rules with GET, replace them with PUT, remove them with DELETE")
+			return
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		rules, timeout := config.GetIntercept()
+		_ = json.NewEncoder(w).Encode(&interceptState{Intercept: rules, Timeout: &timeout, Held: wtService.Intercepted()})
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_, _ = w.Write(shared.MarshalError(&shared.WiretapError{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}))
+}
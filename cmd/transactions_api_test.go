@@ -0,0 +1,88 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/wiretap/audit"
+	"github.com/pb33f/wiretap/controls"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIClientID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/capture", nil)
+	r.RemoteAddr = "192.0.2.1:51234"
+	assert.Equal(t, "api:192.0.2.1", apiClientID(r))
+
+	r.RemoteAddr = "[2001:db8::1]:51234"
+	assert.Equal(t, "api:2001:db8::1", apiClientID(r))
+
+	r.RemoteAddr = "pipe"
+	assert.Equal(t, "api:pipe", apiClientID(r))
+}
+
+func TestHandleCaptureAPI(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+	auditor, err := audit.Open(auditFile)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = auditor.Close() })
+
+	config := &shared.WiretapConfiguration{Auditor: auditor}
+	controls.NewControlsService()
+	bus.GetBus().GetStoreManager().GetStore(controls.ControlServiceChan).Put(shared.ConfigKey, config, nil)
+
+	capture := func(method, action string) (int, bool) {
+		r := httptest.NewRequest(method, "/api/capture"+action, nil)
+		r.RemoteAddr = "192.0.2.1:51234"
+		w := httptest.NewRecorder()
+		handleCaptureAPI()(w, r)
+		var state captureState
+		_ = json.NewDecoder(w.Body).Decode(&state)
+		return w.Code, state.Paused
+	}
+
+	tests := []struct {
+		method string
+		action string
+		code   int
+		paused bool
+	}{
+		{http.MethodGet, "", http.StatusOK, false},
+		{http.MethodPost, "/pause", http.StatusOK, true},
+		{http.MethodGet, "", http.StatusOK, true},
+		{http.MethodPost, "/pause", http.StatusOK, true},
+		{http.MethodPost, "/resume", http.StatusOK, false},
+		{http.MethodGet, "/pause", http.StatusMethodNotAllowed, false},
+		{http.MethodPost, "/stop", http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		code, paused := capture(tt.method, tt.action)
+		assert.Equal(t, tt.code, code, "%s /api/capture%s", tt.method, tt.action)
+		assert.Equal(t, tt.paused, paused, "%s /api/capture%s", tt.method, tt.action)
+		assert.Equal(t, tt.paused, config.CapturePaused, "%s /api/capture%s", tt.method, tt.action)
+	}
+
+	// only changes are audited, and who made them.
+	b, err := os.ReadFile(auditFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 2)
+	for i, action := range []string{audit.ActionCapturePaused, audit.ActionCaptureResumed} {
+		var entry audit.Entry
+		require.NoError(t, json.Unmarshal([]byte(lines[i]), &entry))
+		assert.Equal(t, action, entry.Action)
+		assert.Equal(t, "api:192.0.2.1", entry.Client)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/wiretap/coverage"
+	"github.com/pb33f/wiretap/latency"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// CISummary is written when wiretap stops in CI mode, it's the outcome of the run.
+type CISummary struct {
+	Passed             bool            `json:"passed"`
+	Violations         map[string]int  `json:"violations"`
+	Total              int             `json:"total"`
+	Thresholds         map[string]int  `json:"thresholds"`
+	Exceeded           []string        `json:"exceeded,omitempty"`
+	DroppedValidations int64           `json:"droppedValidations,omitempty"`
+	Command            string          `json:"command,omitempty"`
+	CommandExitCode    *int            `json:"commandExitCode,omitempty"`
+	Coverage           *CICoverage     `json:"coverage,omitempty"`
+	Latency            *latency.Report `json:"latency,omitempty"`
+}
+
+// CICoverage is how much of the specification was exercised during a CI run.
+type CICoverage struct {
+	Operations coverage.Summary `json:"operations"`
+	Responses  coverage.Summary `json:"responses"`
+	Parameters coverage.Summary `json:"parameters"`
+}
+
+// CIThresholds returns the maximum number of violations allowed for each severity. Severities without a threshold
+// are not limited, if there are no thresholds at all, a single error-severity violation fails the run.
+func CIThresholds(thresholds map[string]int) map[string]int {
+	normalized := make(map[string]int)
+	for severity, max := range thresholds {
+		normalized[validSeverity(strings.ToLower(strings.TrimSpace(severity)))] = max
+	}
+	if len(normalized) == 0 {
+		normalized[shared.SeverityError] = 0
+	}
+	return normalized
+}
+
+// ParseCIThreshold reads a threshold in the form 'severity=max', e.g. 'warn=10'.
+func ParseCIThreshold(threshold string) (string, int, error) {
+	severity, max, ok := strings.Cut(threshold, "=")
+	if !ok {
+		return "", 0, fmt.Errorf("threshold '%s' is not in the form 'severity=max'", threshold)
+	}
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	switch severity {
+	case shared.SeverityError, shared.SeverityWarn, "warning", shared.SeverityInfo:
+	default:
+		return "", 0, fmt.Errorf("threshold '%s' has an unknown severity, use error, warn or info", threshold)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(max))
+	if err != nil || n < 0 {
+		return "", 0, fmt.Errorf("threshold '%s' must allow zero or more violations", threshold)
+	}
+	return validSeverity(severity), n, nil
+}
+
+// ExceededThresholds returns the severities (sorted) that have more violations than their threshold allows.
+func ExceededThresholds(counts map[string]int, thresholds map[string]int) []string {
+	var exceeded []string
+	for severity, max := range thresholds {
+		if counts[severity] > max {
+			exceeded = append(exceeded, severity)
+		}
+	}
+	sort.Strings(exceeded)
+	return exceeded
+}
@@ -0,0 +1,37 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package config
+
+import (
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCIThresholds(t *testing.T) {
+	assert.Equal(t, map[string]int{shared.SeverityError: 0}, CIThresholds(nil))
+	assert.Equal(t, map[string]int{shared.SeverityWarn: 10}, CIThresholds(map[string]int{" Warning ": 10}))
+
+	counts := map[string]int{shared.SeverityError: 1, shared.SeverityWarn: 10, shared.SeverityInfo: 99}
+	assert.Equal(t, []string{shared.SeverityError}, ExceededThresholds(counts, CIThresholds(nil)))
+	assert.Empty(t, ExceededThresholds(counts, map[string]int{shared.SeverityError: 1, shared.SeverityWarn: 10}))
+	assert.Equal(t, []string{shared.SeverityError, shared.SeverityInfo},
+		ExceededThresholds(counts, map[string]int{shared.SeverityError: 0, shared.SeverityInfo: 5}))
+}
+
+func TestParseCIThreshold(t *testing.T) {
+	severity, max, err := ParseCIThreshold("warning=5")
+	assert.NoError(t, err)
+	assert.Equal(t, shared.SeverityWarn, severity)
+	assert.Equal(t, 5, max)
+
+	_, _, err = ParseCIThreshold("error")
+	assert.Error(t, err)
+	_, _, err = ParseCIThreshold("fatal=1")
+	assert.Error(t, err)
+	_, _, err = ParseCIThreshold("error=-1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package config
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// FindHost locates the host configuration for a destination (host or host:port). Patterns are checked against
+// the destination with and without the port, so '*.api.example.com' and 'localhost:8080' both work.
+func FindHost(destination string, configuration *shared.WiretapConfiguration) *shared.WiretapHostConfig {
+	destination = strings.ToLower(destination)
+	hostname := destination
+	if h, _, err := net.SplitHostPort(destination); err == nil {
+		hostname = h
+	}
+	compiledHosts := configuration.GetCompiledHosts()
+	for key := range compiledHosts {
+		compiled := compiledHosts[key]
+		if compiled.CompiledHost.Match(destination) || compiled.CompiledHost.Match(hostname) {
+			return compiled.HostConfig
+		}
+	}
+	return nil
+}
+
+// FindHostPaths returns the path configurations bundled with a host configuration that match a path.
+func FindHostPaths(path string, host *shared.WiretapHostConfig) []*shared.WiretapPathConfig {
+	if host == nil {
+		return nil
+	}
+	var foundConfigurations []*shared.WiretapPathConfig
+	compiledPaths := host.GetCompiledPaths()
+	for key := range compiledPaths {
+		if compiledPaths[key].CompiledKey.Match(path) {
+			foundConfigurations = append(foundConfigurations, compiledPaths[key].PathConfig)
+		}
+	}
+	return foundConfigurations
+}
@@ -0,0 +1,41 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package config
+
+import (
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFindHost(t *testing.T) {
+
+	config := `
+hosts:
+  '*.api.example.com':
+    label: example
+    paths:
+      /orders/**:
+        websocket: deny
+  'localhost:8080':
+    label: local`
+
+	var wcConfig shared.WiretapConfiguration
+	assert.NoError(t, yaml.Unmarshal([]byte(config), &wcConfig))
+	wcConfig.CompileHosts()
+
+	host := FindHost("eu.api.example.com:443", &wcConfig)
+	assert.NotNil(t, host)
+	assert.Equal(t, "example", host.Label)
+	assert.Len(t, FindHostPaths("/orders/123", host), 1)
+	assert.Len(t, FindHostPaths("/customers/123", host), 0)
+
+	assert.Nil(t, FindHost("a.eu.api.example.com", &wcConfig))
+	assert.Nil(t, FindHost("api.example.com", &wcConfig))
+	assert.Equal(t, "local", FindHost("localhost:8080", &wcConfig).Label)
+	assert.Nil(t, FindHost("localhost:9090", &wcConfig))
+}
@@ -6,29 +6,151 @@ package config
 import (
 	"fmt"
 	"github.com/pb33f/wiretap/shared"
+	"sort"
 	"strings"
 )
 
 func FindPaths(path string, configuration *shared.WiretapConfiguration) []*shared.WiretapPathConfig {
 	var foundConfigurations []*shared.WiretapPathConfig
-	for key := range configuration.CompiledPaths {
-		if configuration.CompiledPaths[key].CompiledKey.Match(path) {
-			foundConfigurations = append(foundConfigurations, configuration.CompiledPaths[key].PathConfig)
+	compiledPaths := configuration.GetCompiledPaths()
+	for key := range compiledPaths {
+		if compiledPaths[key].CompiledKey.Match(path) {
+			foundConfigurations = append(foundConfigurations, compiledPaths[key].PathConfig)
 		}
 	}
 	return foundConfigurations
 }
 
+// FindPathKey returns the key of the first path configuration (in key order) that matches a path, an empty string
+// if none match.
+func FindPathKey(path string, compiled map[string]*shared.CompiledPath) string {
+	var keys []string
+	for key := range compiled {
+		if compiled[key].CompiledKey.Match(path) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	return keys[0]
+}
+
+// ValidationScope works out if requests and responses are validated for the matched path configurations. Both are
+// validated by default, when several paths set a scope, only what every one of them validates is validated.
+func ValidationScope(paths []*shared.WiretapPathConfig) (request, response bool) {
+	request, response = true, true
+	for _, path := range paths {
+		switch strings.ToLower(path.Validation) {
+		case "", shared.ValidationBoth:
+		case shared.ValidationRequest:
+			response = false
+		case shared.ValidationResponse:
+			request = false
+		case shared.ValidationNone:
+			request, response = false, false
+		}
+	}
+	return request, response
+}
+
+// FindContractPrefix returns the longest path prefix with its own specification that a path falls under, an
+// empty string if there is none.
+func FindContractPrefix(path string, configuration *shared.WiretapConfiguration) string {
+	found := ""
+	for prefix := range configuration.Contracts {
+		trimmed := strings.TrimSuffix(prefix, "/")
+		if (path == trimmed || strings.HasPrefix(path, trimmed+"/")) && len(prefix) > len(found) {
+			found = prefix
+		}
+	}
+	return found
+}
+
+// FindWebhook returns the name of the webhook that calls to a path are made to, using the longest path prefix
+// configured for a webhook. An empty string is returned if the path isn't a webhook call.
+func FindWebhook(path string, configuration *shared.WiretapConfiguration) string {
+	found, name := "", ""
+	for prefix, webhook := range configuration.WebhookPaths {
+		trimmed := strings.TrimSuffix(prefix, "/")
+		if (path == trimmed || strings.HasPrefix(path, trimmed+"/")) && len(prefix) > len(found) {
+			found, name = prefix, webhook
+		}
+	}
+	return name
+}
+
 func FindPathDelay(path string, configuration *shared.WiretapConfiguration) int {
 	var foundMatch int
-	for key := range configuration.CompiledPathDelays {
-		if configuration.CompiledPathDelays[key].CompiledPathDelay.Match(path) {
-			foundMatch = configuration.CompiledPathDelays[key].PathDelayValue
+	pathDelays := configuration.GetCompiledPathDelays()
+	for key := range pathDelays {
+		if pathDelays[key].CompiledPathDelay.Match(path) {
+			foundMatch = pathDelays[key].PathDelayValue
 		}
 	}
 	return foundMatch
 }
 
+// FindMockMode checks if a path has been switched to mock (true) or proxy (false) mode, regardless of the mock mode
+// of everything else. The most specific path that matches (the one with the most characters that aren't wildcards)
+// wins, false is returned for found if no path matches.
+func FindMockMode(path string, configuration *shared.WiretapConfiguration) (mock bool, found bool) {
+	var best string
+	bestLiteral := -1
+	for key, compiled := range configuration.GetCompiledMockPaths() {
+		if !compiled.CompiledMockPath.Match(path) {
+			continue
+		}
+		literal := len(key) - strings.Count(key, "*") - strings.Count(key, "?")
+		if literal > bestLiteral || (literal == bestLiteral && key < best) {
+			best, bestLiteral, found, mock = key, literal, true, compiled.Mock
+		}
+	}
+	return mock, found
+}
+
+// FindIntercept checks if a request is held until it's approved, the rule that holds it is returned. Rules for an
+// operation (with a method) win over rules for a path, false is returned for found if no rule matches.
+func FindIntercept(method, path string, configuration *shared.WiretapConfiguration) (rule string, found bool) {
+	var match *shared.CompiledIntercept
+	for _, compiled := range configuration.GetCompiledIntercepts() {
+		if compiled.Method != "" && compiled.Method != strings.ToUpper(method) {
+			continue
+		}
+		if compiled.CompiledPath.Match(path) && (match == nil || (match.Method == "" && compiled.Method != "")) {
+			match = compiled
+		}
+	}
+	if match == nil {
+		return "", false
+	}
+	return match.Rule, true
+}
+
+// FindMockLatency returns a delay for a mock response, sampled from the latency distribution configured
+// for the method and path. If nothing is configured, zero is returned.
+func FindMockLatency(method, path string, configuration *shared.WiretapConfiguration) int {
+	var found *shared.CompiledPathDelay
+	mockLatency := configuration.GetCompiledMockLatency()
+	for key := range mockLatency {
+		compiled := mockLatency[key]
+		if compiled.Method != "" && compiled.Method != strings.ToUpper(method) {
+			continue
+		}
+		if compiled.CompiledPathDelay.Match(path) {
+			// operations (with a method) win over paths.
+			if found == nil || (found.Method == "" && compiled.Method != "") {
+				found = compiled
+			}
+		}
+	}
+	if found == nil {
+		return 0
+	}
+	return found.Delay()
+}
+
 func RewritePath(path string, configuration *shared.WiretapConfiguration) string {
 	paths := FindPaths(path, configuration)
 	var replaced string = path
@@ -214,3 +214,156 @@ func TestLocatePathDelay(t *testing.T) {
 	assert.Equal(t, 0, delay)
 
 }
+
+func TestFindMockLatency(t *testing.T) {
+
+	config := `mockLatency:
+  /pets/**:
+    delay: 100
+  GET /pets/*:
+    distribution: uniform
+    min: 200
+    max: 300
+  POST /orders:
+    distribution: normal
+    delay: 500
+    stdDev: 1000
+    min: 400
+    max: 600`
+
+	var c shared.WiretapConfiguration
+	_ = yaml.Unmarshal([]byte(config), &c)
+
+	c.CompilePathDelays()
+
+	// operations win over paths.
+	for i := 0; i < 20; i++ {
+		delay := FindMockLatency("get", "/pets/123", &c)
+		assert.GreaterOrEqual(t, delay, 200)
+		assert.LessOrEqual(t, delay, 300)
+
+		delay = FindMockLatency("POST", "/orders", &c)
+		assert.GreaterOrEqual(t, delay, 400)
+		assert.LessOrEqual(t, delay, 600)
+	}
+
+	assert.Equal(t, 100, FindMockLatency("DELETE", "/pets/123", &c))
+	assert.Equal(t, 0, FindMockLatency("GET", "/orders", &c))
+}
+
+func TestValidationScope(t *testing.T) {
+
+	config := `paths:
+  /legacy/**:
+    validation: none
+  /reports/**:
+    validation: request
+  /reports/daily/**:
+    validation: response
+  /events/**:
+    validation: Response`
+
+	var c shared.WiretapConfiguration
+	_ = yaml.Unmarshal([]byte(config), &c)
+
+	c.CompilePaths()
+
+	request, response := ValidationScope(FindPaths("/pets/123", &c))
+	assert.True(t, request)
+	assert.True(t, response)
+
+	request, response = ValidationScope(FindPaths("/legacy/orders", &c))
+	assert.False(t, request)
+	assert.False(t, response)
+
+	request, response = ValidationScope(FindPaths("/reports/weekly", &c))
+	assert.True(t, request)
+	assert.False(t, response)
+
+	request, response = ValidationScope(FindPaths("/events/stream", &c))
+	assert.False(t, request)
+	assert.True(t, response)
+
+	// overlapping paths only validate what they all agree on.
+	request, response = ValidationScope(FindPaths("/reports/daily/1", &c))
+	assert.False(t, request)
+	assert.False(t, response)
+}
+
+func TestFindContractPrefix(t *testing.T) {
+
+	config := `contracts:
+  /orders: orders.yaml
+  /orders/archive/: archive.yaml
+  /: everything.yaml`
+
+	var c shared.WiretapConfiguration
+	_ = yaml.Unmarshal([]byte(config), &c)
+
+	assert.Equal(t, "/orders", FindContractPrefix("/orders", &c))
+	assert.Equal(t, "/orders", FindContractPrefix("/orders/123", &c))
+	assert.Equal(t, "/orders/archive/", FindContractPrefix("/orders/archive/2023", &c))
+	assert.Equal(t, "/", FindContractPrefix("/ordersmissing", &c))
+
+	delete(c.Contracts, "/")
+	assert.Equal(t, "", FindContractPrefix("/users", &c))
+}
+
+func TestFindWebhook(t *testing.T) {
+
+	config := `webhookPaths:
+  /hooks: anything
+  /hooks/pets/: newPet`
+
+	var c shared.WiretapConfiguration
+	_ = yaml.Unmarshal([]byte(config), &c)
+
+	assert.Equal(t, "newPet", FindWebhook("/hooks/pets", &c))
+	assert.Equal(t, "newPet", FindWebhook("/hooks/pets/123", &c))
+	assert.Equal(t, "anything", FindWebhook("/hooks/orders", &c))
+	assert.Equal(t, "", FindWebhook("/hooksmissing", &c))
+}
+
+func TestFindMockMode(t *testing.T) {
+
+	config := `mockPaths:
+  /pets/*: true
+  /pets/1: false`
+
+	var c shared.WiretapConfiguration
+	_ = yaml.Unmarshal([]byte(config), &c)
+	c.CompileMockPaths()
+
+	mock, found := FindMockMode("/pets/2", &c)
+	assert.True(t, found)
+	assert.True(t, mock)
+
+	mock, found = FindMockMode("/pets/1", &c)
+	assert.True(t, found)
+	assert.False(t, mock)
+
+	_, found = FindMockMode("/orders", &c)
+	assert.False(t, found)
+}
+
+func TestFindIntercept(t *testing.T) {
+
+	config := `intercept:
+  - /pets/*
+  - post /pets/*`
+
+	var c shared.WiretapConfiguration
+	_ = yaml.Unmarshal([]byte(config), &c)
+	c.CompileIntercepts()
+
+	rule, found := FindIntercept("POST", "/pets/1", &c)
+	assert.True(t, found)
+	assert.Equal(t, "post /pets/*", rule)
+
+	rule, found = FindIntercept("GET", "/pets/1", &c)
+	assert.True(t, found)
+	assert.Equal(t, "/pets/*", rule)
+
+	_, found = FindIntercept("POST", "/orders", &c)
+	assert.False(t, found)
+}
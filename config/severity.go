@@ -0,0 +1,69 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package config
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// ViolationSeverity classifies a violation as an error, warning or info, using the configured severities. Rules are
+// matched like suppression rules, a rule identifier wins over a validation type, which wins over a message glob.
+// Violations that match nothing are errors.
+func ViolationSeverity(violation *errors.ValidationError, severities map[string]string) string {
+	if len(severities) == 0 {
+		return shared.SeverityError
+	}
+	rules := make(map[string]string, len(severities))
+	for rule, severity := range severities {
+		rules[strings.ToLower(strings.TrimSpace(rule))] = strings.ToLower(severity)
+	}
+	if severity, ok := rules[ViolationRule(violation)]; ok {
+		return validSeverity(severity)
+	}
+	if severity, ok := rules[strings.ToLower(violation.ValidationType)]; ok {
+		return validSeverity(severity)
+	}
+	globs := make([]string, 0, len(rules))
+	for rule := range rules {
+		globs = append(globs, rule)
+	}
+	sort.Strings(globs)
+	message := strings.ToLower(violation.Message)
+	for _, rule := range globs {
+		if g := compileRule(rule); g != nil && g.Match(message) {
+			return validSeverity(rules[rule])
+		}
+	}
+	return shared.SeverityError
+}
+
+// ClassifyViolations attaches a severity to every violation.
+func ClassifyViolations(violations []*errors.ValidationError, severities map[string]string) []*shared.Violation {
+	if len(violations) == 0 {
+		return nil
+	}
+	classified := make([]*shared.Violation, len(violations))
+	for i, violation := range violations {
+		classified[i] = &shared.Violation{
+			ValidationError: violation,
+			Severity:        ViolationSeverity(violation, severities),
+		}
+	}
+	return classified
+}
+
+func validSeverity(severity string) string {
+	switch severity {
+	case shared.SeverityWarn, "warning":
+		return shared.SeverityWarn
+	case shared.SeverityInfo:
+		return shared.SeverityInfo
+	}
+	return shared.SeverityError
+}
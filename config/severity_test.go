@@ -0,0 +1,41 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package config
+
+import (
+	"testing"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViolationSeverity(t *testing.T) {
+	severities := map[string]string{
+		"parameter":        "warn",
+		"parameter/cookie": "info",
+		"*deprecated*":     "Info",
+		"response/schema":  "bogus",
+	}
+	header := &errors.ValidationError{ValidationType: "parameter", ValidationSubType: "header"}
+	cookie := &errors.ValidationError{ValidationType: "parameter", ValidationSubType: "cookie"}
+	body := &errors.ValidationError{ValidationType: "requestBody", ValidationSubType: "schema",
+		Message: "Field 'legacy' is deprecated"}
+	response := &errors.ValidationError{ValidationType: "response", ValidationSubType: "schema"}
+	path := &errors.ValidationError{ValidationType: "path", ValidationSubType: "missing"}
+
+	assert.Equal(t, shared.SeverityWarn, ViolationSeverity(header, severities))
+	assert.Equal(t, shared.SeverityInfo, ViolationSeverity(cookie, severities))
+	assert.Equal(t, shared.SeverityInfo, ViolationSeverity(body, severities))
+	assert.Equal(t, shared.SeverityError, ViolationSeverity(response, severities))
+	assert.Equal(t, shared.SeverityError, ViolationSeverity(path, severities))
+	assert.Equal(t, shared.SeverityError, ViolationSeverity(header, nil))
+
+	classified := ClassifyViolations([]*errors.ValidationError{header, path}, severities)
+	assert.Len(t, classified, 2)
+	assert.Equal(t, shared.SeverityWarn, classified[0].Severity)
+	assert.Same(t, header, classified[0].ValidationError)
+	assert.Nil(t, ClassifyViolations(nil, severities))
+}
@@ -0,0 +1,71 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package config
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gobwas/glob"
+	"github.com/pb33f/libopenapi-validator/errors"
+)
+
+// ruleGlobs caches compiled suppression and severity rules, they are matched against every violation.
+var ruleGlobs sync.Map
+
+// ViolationRule returns the identifier of the rule a violation broke, its validation type and subtype,
+// e.g. `parameter/header` or `response/schema`.
+func ViolationRule(violation *errors.ValidationError) string {
+	if violation.ValidationSubType == "" {
+		return strings.ToLower(violation.ValidationType)
+	}
+	return strings.ToLower(violation.ValidationType + "/" + violation.ValidationSubType)
+}
+
+// SuppressViolations removes the violations that match any of the suppression rules. A rule matches a violation
+// by rule identifier (e.g. `parameter/header`), by validation type (e.g. `parameter`), or by a glob matched
+// against the message (e.g. `*enum*status*`). Matching is case-insensitive.
+func SuppressViolations(violations []*errors.ValidationError, rules []string) []*errors.ValidationError {
+	if len(rules) == 0 || len(violations) == 0 {
+		return violations
+	}
+	var kept []*errors.ValidationError
+	for _, violation := range violations {
+		if !suppressed(violation, rules) {
+			kept = append(kept, violation)
+		}
+	}
+	return kept
+}
+
+func suppressed(violation *errors.ValidationError, rules []string) bool {
+	rule := ViolationRule(violation)
+	message := strings.ToLower(violation.Message)
+	for _, r := range rules {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" {
+			continue
+		}
+		if r == rule || r == strings.ToLower(violation.ValidationType) {
+			return true
+		}
+		if g := compileRule(r); g != nil && g.Match(message) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileRule(rule string) glob.Glob {
+	if g, ok := ruleGlobs.Load(rule); ok {
+		return g.(glob.Glob)
+	}
+	g, err := glob.Compile(rule)
+	if err != nil {
+		return nil
+	}
+	ruleGlobs.Store(rule, g)
+	return g
+}
@@ -0,0 +1,31 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package config
+
+import (
+	"testing"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuppressViolations(t *testing.T) {
+	header := &errors.ValidationError{ValidationType: "parameter", ValidationSubType: "header",
+		Message: "Header parameter 'X-Trace' is missing"}
+	query := &errors.ValidationError{ValidationType: "parameter", ValidationSubType: "query",
+		Message: "Query parameter 'limit' is not a valid integer"}
+	enum := &errors.ValidationError{ValidationType: "response", ValidationSubType: "schema",
+		Message: "value must be one of 'active', 'inactive' (enum mismatch on field status)"}
+	violations := []*errors.ValidationError{header, query, enum}
+
+	assert.Equal(t, "parameter/header", ViolationRule(header))
+
+	assert.Equal(t, violations, SuppressViolations(violations, nil))
+	assert.Equal(t, []*errors.ValidationError{query, enum}, SuppressViolations(violations, []string{"Parameter/Header"}))
+	assert.Equal(t, []*errors.ValidationError{enum}, SuppressViolations(violations, []string{"parameter"}))
+	assert.Equal(t, []*errors.ValidationError{header, query},
+		SuppressViolations(violations, []string{"*enum mismatch on field status*"}))
+	assert.Nil(t, SuppressViolations(violations, []string{"parameter", "response/schema"}))
+}
@@ -4,18 +4,39 @@
 package controls
 
 import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/gobwas/glob"
+	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pb33f/ranch/bus"
 	"github.com/pb33f/ranch/model"
 	"github.com/pb33f/ranch/service"
+	"github.com/pb33f/wiretap/audit"
 	"github.com/pb33f/wiretap/shared"
 )
 
 const (
-	ControlServiceChan = "controls"
-	ChangeDelayRequest = "change-delay-request"
+	ControlServiceChan      = "controls"
+	ControlsChangeChan      = "wiretap-controls-change"
+	ChangeDelayRequest      = "change-delay-request"
+	ChangePathDelaysRequest = "change-path-delays-request"
+	ClearDelaysRequest      = "clear-delays-request"
+	ChangeMockPathsRequest  = "change-mock-paths-request"
+	ChangeInterceptRequest  = "change-intercept-request"
+	SpecStatusRequest       = "get-spec-status"
+	ChangeVariablesRequest  = "change-variables-request"
+	PauseCaptureRequest     = "pause-capture-request"
+	ResumeCaptureRequest    = "resume-capture-request"
 )
 
+// changeLock makes changes one at a time, they are asked for by the monitor and the API at once.
+var changeLock sync.Mutex
+
 type ControlService struct {
 	controlsStore bus.BusStore
 }
@@ -24,6 +45,33 @@ type ChangeGlobalDelayRequest struct {
 	Delay int `json:"delay,omitempty"`
 }
 
+// PathDelaysChange sets or changes the delays of paths, a delay of zero removes the delay of a path. Replace removes
+// the delays of every path that isn't in PathDelays.
+type PathDelaysChange struct {
+	PathDelays map[string]int `json:"pathDelays,omitempty"`
+	Replace    bool           `json:"replace,omitempty"`
+}
+
+// MockPathsChange switches paths to mock (true) or proxy (false) mode, whatever the mock mode of everything else is.
+// Paths in Reset (or every path, with ResetAll) go back to the mock mode of everything else.
+type MockPathsChange struct {
+	MockPaths map[string]bool `json:"mockPaths,omitempty"`
+	Reset     []string        `json:"reset,omitempty"`
+	ResetAll  bool            `json:"resetAll,omitempty"`
+}
+
+// InterceptChange replaces the rules for the requests that are held until they are approved, path globs optionally
+// prefixed with a method (e.g. 'POST /pets/*'). Timeout (in seconds) is changed if it's set, held requests are sent on
+// unchanged once it passes.
+type InterceptChange struct {
+	Intercept []string `json:"intercept"`
+	Timeout   *int     `json:"timeout,omitempty"`
+}
+
+type ChangeGlobalVariablesRequest struct {
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
 type ControlResponse struct {
 	Config *shared.WiretapConfiguration `json:"config,omitempty"`
 }
@@ -36,10 +84,34 @@ func NewControlsService() *ControlService {
 	}
 }
 
+// Init creates the channel monitors are told about changes on, when they are made at runtime.
+func (cs *ControlService) Init(core service.FabricServiceCore) error {
+	channel := core.Bus().GetChannelManager().CreateChannel(ControlsChangeChan)
+	channel.SetGalactic(ControlsChangeChan)
+	return nil
+}
+
 func (cs *ControlService) HandleServiceRequest(request *model.Request, core service.FabricServiceCore) {
 	switch request.RequestCommand {
 	case ChangeDelayRequest:
 		cs.changeDelay(request, core)
+	case ChangePathDelaysRequest:
+		cs.changePathDelays(request, core)
+	case ChangeMockPathsRequest:
+		cs.changeMockPaths(request, core)
+	case ChangeInterceptRequest:
+		cs.changeIntercept(request, core)
+	case ClearDelaysRequest:
+		_, _ = SetGlobalDelay(0, audit.Client(request))
+		config, _ := SetPathDelays(&PathDelaysChange{Replace: true}, audit.Client(request))
+		core.SendResponse(request, &ControlResponse{config})
+	case ChangeVariablesRequest:
+		cs.changeVariables(request, core)
+	case PauseCaptureRequest, ResumeCaptureRequest:
+		config := SetCapturePaused(request.RequestCommand == PauseCaptureRequest, audit.Client(request))
+		core.SendResponse(request, &ControlResponse{config})
+	case SpecStatusRequest:
+		cs.specStatus(request, core)
 	default:
 		core.HandleUnknownRequest(request)
 	}
@@ -53,18 +125,275 @@ func (cs *ControlService) changeDelay(request *model.Request, core service.Fabri
 		var r ChangeGlobalDelayRequest
 		_ = mapstructure.Decode(dl, &r)
 
-		// extract state from store.
+		// update if valid.
+		config, _ := SetGlobalDelay(r.Delay, audit.Client(request))
+		core.SendResponse(request, &ControlResponse{config})
+
+	} else {
+		core.SendErrorResponse(request, 400, "Invalid delay value")
+	}
+}
+
+// changePathDelays sets, changes or removes the delays of paths.
+func (cs *ControlService) changePathDelays(request *model.Request, core service.FabricServiceCore) {
+	if dl, ok := request.Payload.(map[string]interface{}); ok {
+		var r PathDelaysChange
+		_ = mapstructure.Decode(dl, &r)
+		config, err := SetPathDelays(&r, audit.Client(request))
+		if err != nil {
+			core.SendErrorResponse(request, 400, err.Error())
+			return
+		}
+		core.SendResponse(request, &ControlResponse{config})
+	} else {
+		core.SendErrorResponse(request, 400, "Invalid path delays")
+	}
+}
+
+// changeMockPaths switches paths between mock and proxy mode.
+func (cs *ControlService) changeMockPaths(request *model.Request, core service.FabricServiceCore) {
+	if dl, ok := request.Payload.(map[string]interface{}); ok {
+		var r MockPathsChange
+		_ = mapstructure.Decode(dl, &r)
+		config, err := SetMockPaths(&r, audit.Client(request))
+		if err != nil {
+			core.SendErrorResponse(request, 400, err.Error())
+			return
+		}
+		core.SendResponse(request, &ControlResponse{config})
+	} else {
+		core.SendErrorResponse(request, 400, "Invalid mock paths")
+	}
+}
+
+// changeIntercept replaces the rules for the requests that are held until they are approved.
+func (cs *ControlService) changeIntercept(request *model.Request, core service.FabricServiceCore) {
+	if dl, ok := request.Payload.(map[string]interface{}); ok {
+		var r InterceptChange
+		_ = mapstructure.Decode(dl, &r)
+		config, err := SetIntercept(&r, audit.Client(request))
+		if err != nil {
+			core.SendErrorResponse(request, 400, err.Error())
+			return
+		}
+		core.SendResponse(request, &ControlResponse{config})
+	} else {
+		core.SendErrorResponse(request, 400, "Invalid intercept rules")
+	}
+}
+
+// SetGlobalDelay sets the delay (in milliseconds) applied to every response, a delay of zero removes it. Client is
+// who asked for it, for the audit log.
+func SetGlobalDelay(delay int, client string) (*shared.WiretapConfiguration, error) {
+	controlsStore := bus.GetBus().GetStoreManager().GetStore(ControlServiceChan)
+	config := controlsStore.GetValue(shared.ConfigKey).(*shared.WiretapConfiguration)
+	if delay < 0 {
+		return config, fmt.Errorf("delay has to be zero or more milliseconds, not %d", delay)
+	}
+	changeLock.Lock()
+	defer changeLock.Unlock()
+	if current := config.GetGlobalAPIDelay(); current != delay {
+		_ = config.Auditor.Record(client, audit.ActionDelayChanged, map[string]any{"from": current, "to": delay})
+		config.SetGlobalAPIDelay(delay)
+		controlsStore.Put(shared.ConfigKey, config, nil)
+		broadcastChange(config)
+	}
+	return config, nil
+}
+
+// SetPathDelays sets, changes or removes the delays of paths, they apply to the next request. Nothing is changed if
+// any delay is negative, or any path isn't a valid glob. Client is who asked for it, for the audit log.
+func SetPathDelays(change *PathDelaysChange, client string) (*shared.WiretapConfiguration, error) {
+	controlsStore := bus.GetBus().GetStoreManager().GetStore(ControlServiceChan)
+	config := controlsStore.GetValue(shared.ConfigKey).(*shared.WiretapConfiguration)
+	changeLock.Lock()
+	defer changeLock.Unlock()
+
+	current := config.GetPathDelays()
+	delays := make(map[string]int)
+	if !change.Replace {
+		maps.Copy(delays, current)
+	}
+	for path, delay := range change.PathDelays {
+		if delay < 0 {
+			return config, fmt.Errorf("delay of '%s' has to be zero or more milliseconds, not %d", path, delay)
+		}
+		if _, err := glob.Compile(config.ReplaceWithVariables(path)); err != nil {
+			return config, fmt.Errorf("path '%s' isn't a valid glob: %s", path, err.Error())
+		}
+		if delay == 0 {
+			delete(delays, path)
+		} else {
+			delays[path] = delay
+		}
+	}
+	if !maps.Equal(delays, current) {
+		_ = config.Auditor.Record(client, audit.ActionPathDelaysChanged,
+			map[string]any{"from": current, "to": delays})
+		config.SetPathDelays(delays)
+		controlsStore.Put(shared.ConfigKey, config, nil)
+		broadcastChange(config)
+	}
+	return config, nil
+}
+
+// SetMockPaths switches paths between mock and proxy mode, they apply to the next request. Nothing is changed if any
+// path isn't a valid glob. Client is who asked for it, for the audit log.
+func SetMockPaths(change *MockPathsChange, client string) (*shared.WiretapConfiguration, error) {
+	controlsStore := bus.GetBus().GetStoreManager().GetStore(ControlServiceChan)
+	config := controlsStore.GetValue(shared.ConfigKey).(*shared.WiretapConfiguration)
+	changeLock.Lock()
+	defer changeLock.Unlock()
+
+	current := config.GetMockPaths()
+	paths := make(map[string]bool)
+	if !change.ResetAll {
+		maps.Copy(paths, current)
+	}
+	for _, path := range change.Reset {
+		delete(paths, path)
+	}
+	for path, mock := range change.MockPaths {
+		if _, err := glob.Compile(config.ReplaceWithVariables(path)); err != nil {
+			return config, fmt.Errorf("path '%s' isn't a valid glob: %s", path, err.Error())
+		}
+		paths[path] = mock
+	}
+	if !maps.Equal(paths, current) {
+		_ = config.Auditor.Record(client, audit.ActionMockPathsChanged,
+			map[string]any{"from": current, "to": paths})
+		config.SetMockPaths(paths)
+		controlsStore.Put(shared.ConfigKey, config, nil)
+		broadcastChange(config)
+	}
+	return config, nil
+}
+
+// SetIntercept replaces the rules for the requests that are held until they are approved, they apply to the next
+// request. Requests already held stay held. Nothing is changed if a rule isn't a valid glob, or the timeout is
+// negative. Client is who asked for it, for the audit log.
+func SetIntercept(change *InterceptChange, client string) (*shared.WiretapConfiguration, error) {
+	controlsStore := bus.GetBus().GetStoreManager().GetStore(ControlServiceChan)
+	config := controlsStore.GetValue(shared.ConfigKey).(*shared.WiretapConfiguration)
+	changeLock.Lock()
+	defer changeLock.Unlock()
+
+	for _, rule := range change.Intercept {
+		if err := config.CheckInterceptRule(rule); err != nil {
+			return config, err
+		}
+	}
+	rules, timeout := config.GetIntercept()
+	currentTimeout := timeout
+	if change.Timeout != nil {
+		if *change.Timeout < 0 {
+			return config, fmt.Errorf("the intercept timeout cannot be negative, %d is not valid", *change.Timeout)
+		}
+		timeout = *change.Timeout
+	}
+	if !slices.Equal(change.Intercept, rules) || timeout != currentTimeout {
+		_ = config.Auditor.Record(client, audit.ActionInterceptChanged, map[string]any{
+			"from": rules, "to": change.Intercept, "timeout": timeout})
+		config.SetIntercept(change.Intercept, timeout)
+		controlsStore.Put(shared.ConfigKey, config, nil)
+		broadcastChange(config)
+	}
+	return config, nil
+}
+
+// changeVariables replaces the configured variables, and re-compiles everything that depends on them.
+func (cs *ControlService) changeVariables(request *model.Request, core service.FabricServiceCore) {
+
+	if dl, ok := request.Payload.(map[string]interface{}); ok {
+
+		var r ChangeGlobalVariablesRequest
+		_ = mapstructure.Decode(dl, &r)
+
 		controls := cs.controlsStore.GetValue(shared.ConfigKey)
 		config := controls.(*shared.WiretapConfiguration)
+		changeLock.Lock()
+		defer changeLock.Unlock()
 
-		// update if valid.
-		if r.Delay >= 0 {
-			config.GlobalAPIDelay = r.Delay
-			cs.controlsStore.Put(shared.ConfigKey, config, nil)
-		}
+		// only names are recorded, values could be secrets.
+		_ = config.Auditor.Record(audit.Client(request), audit.ActionVariablesChanged,
+			changedVariables(config.Variables, r.Variables))
+		config.Variables = r.Variables
+		config.CompileVariables()
+		config.CompilePathDelays()
+		config.CompileMockPaths()
+		config.CompileIntercepts()
+		config.CompileHosts()
+		cs.controlsStore.Put(shared.ConfigKey, config, nil)
+		broadcastChange(config)
 		core.SendResponse(request, &ControlResponse{config})
 
 	} else {
-		core.SendErrorResponse(request, 400, "Invalid delay value")
+		core.SendErrorResponse(request, 400, "Invalid variables")
+	}
+}
+
+// SetCapturePaused pauses (or resumes) capturing transactions, while paused traffic is still proxied and validated,
+// but nothing is kept, broadcast to the monitor or recorded. Client is who asked for it, for the audit log.
+func SetCapturePaused(paused bool, client string) *shared.WiretapConfiguration {
+	controlsStore := bus.GetBus().GetStoreManager().GetStore(ControlServiceChan)
+	config := controlsStore.GetValue(shared.ConfigKey).(*shared.WiretapConfiguration)
+	changeLock.Lock()
+	defer changeLock.Unlock()
+	if config.IsCapturePaused() != paused {
+		action := audit.ActionCaptureResumed
+		if paused {
+			action = audit.ActionCapturePaused
+		}
+		_ = config.Auditor.Record(client, action, nil)
+		config.SetCapturePaused(paused)
+		controlsStore.Put(shared.ConfigKey, config, nil)
+		broadcastChange(config)
+	}
+	return config
+}
+
+// broadcastChange tells every connected monitor about a change made at runtime, so they all show the same thing.
+func broadcastChange(config *shared.WiretapConfiguration) {
+	channel, err := bus.GetBus().GetChannelManager().GetChannel(ControlsChangeChan)
+	if err != nil {
+		return
+	}
+	id, _ := uuid.NewUUID()
+	channel.Send(&model.Message{
+		Id:          &id,
+		Channel:     ControlsChangeChan,
+		Destination: ControlsChangeChan,
+		Payload:     &ControlResponse{config},
+		Direction:   model.ResponseDir,
+	})
+}
+
+// changedVariables names the variables that were added, removed or changed.
+func changedVariables(before, after map[string]string) map[string]any {
+	var added, removed, changed []string
+	for name, value := range after {
+		if previous, ok := before[name]; !ok {
+			added = append(added, name)
+		} else if previous != value {
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return map[string]any{"added": added, "removed": removed, "changed": changed}
+}
+
+// specStatus returns the health of the specification, including any compile errors from the last reload.
+func (cs *ControlService) specStatus(request *model.Request, core service.FabricServiceCore) {
+	if status, ok := cs.controlsStore.GetValue(shared.SpecStatusKey).(*shared.WiretapSpecStatus); ok {
+		core.SendResponse(request, status)
+		return
 	}
+	core.SendResponse(request, &shared.WiretapSpecStatus{Healthy: true})
 }
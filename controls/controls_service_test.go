@@ -0,0 +1,169 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package controls_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/controls"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig(pathDelays map[string]int) *shared.WiretapConfiguration {
+	config := &shared.WiretapConfiguration{GlobalAPIDelay: 100, PathDelays: pathDelays}
+	config.CompilePathDelays()
+	controls.NewControlsService()
+	bus.GetBus().GetStoreManager().GetStore(controls.ControlServiceChan).Put(shared.ConfigKey, config, nil)
+	return config
+}
+
+func TestSetPathDelays(t *testing.T) {
+	config := testConfig(map[string]int{"/pets/*": 200})
+
+	// delays are added and changed, a delay of zero removes one.
+	_, err := controls.SetPathDelays(&controls.PathDelaysChange{PathDelays: map[string]int{"/toys/**": 300, "/pets/*": 250}}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"/pets/*": 250, "/toys/**": 300}, config.PathDelays)
+	assert.Equal(t, 250, configModel.FindPathDelay("/pets/1", config))
+	assert.Equal(t, 300, configModel.FindPathDelay("/toys/balls/2", config))
+
+	_, err = controls.SetPathDelays(&controls.PathDelaysChange{PathDelays: map[string]int{"/toys/**": 0}}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"/pets/*": 250}, config.PathDelays)
+	assert.Equal(t, 0, configModel.FindPathDelay("/toys/balls/2", config))
+
+	// nothing changes if a delay is negative, or a path isn't a glob.
+	_, err = controls.SetPathDelays(&controls.PathDelaysChange{PathDelays: map[string]int{"/toys": 10, "/pets/*": -1}}, "test")
+	assert.Error(t, err)
+	_, err = controls.SetPathDelays(&controls.PathDelaysChange{PathDelays: map[string]int{"/toys/[": 10}}, "test")
+	assert.Error(t, err)
+	assert.Equal(t, map[string]int{"/pets/*": 250}, config.PathDelays)
+	assert.Equal(t, 250, configModel.FindPathDelay("/pets/1", config))
+
+	// replacing removes every delay that isn't in the change.
+	_, err = controls.SetPathDelays(&controls.PathDelaysChange{PathDelays: map[string]int{"/toys": 10}, Replace: true}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"/toys": 10}, config.PathDelays)
+	assert.Equal(t, 0, configModel.FindPathDelay("/pets/1", config))
+	assert.Equal(t, 10, configModel.FindPathDelay("/toys", config))
+}
+
+func TestClearDelays(t *testing.T) {
+	config := testConfig(map[string]int{"/pets/*": 200})
+
+	_, err := controls.SetGlobalDelay(0, "test")
+	require.NoError(t, err)
+	_, err = controls.SetPathDelays(&controls.PathDelaysChange{Replace: true}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 0, config.GlobalAPIDelay)
+	assert.Empty(t, config.PathDelays)
+	assert.Equal(t, 0, configModel.FindPathDelay("/pets/1", config))
+}
+
+func TestSetGlobalDelay(t *testing.T) {
+	config := testConfig(nil)
+
+	// the delay is set, changed and cleared, and a negative one changes nothing.
+	for _, delay := range []int{250, 500, -1, 0} {
+		changed, err := controls.SetGlobalDelay(delay, "test")
+		if delay < 0 {
+			assert.Error(t, err)
+			assert.Equal(t, 500, config.GlobalAPIDelay)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, delay, changed.GlobalAPIDelay)
+		assert.Equal(t, delay, config.GlobalAPIDelay)
+	}
+}
+
+func TestSetPathDelays_Recompiled(t *testing.T) {
+	config := testConfig(map[string]int{"/pets/*": 200})
+	before := config.GetCompiledPathDelays()
+
+	// a change compiles new delays and swaps them in, what requests already have is never changed under them.
+	_, err := controls.SetPathDelays(&controls.PathDelaysChange{PathDelays: map[string]int{"/pets/*": 300, "/toys": 10}}, "test")
+	require.NoError(t, err)
+	after := config.GetCompiledPathDelays()
+	require.Len(t, before, 1)
+	assert.Equal(t, 200, before["/pets/*"].PathDelayValue)
+	require.Len(t, after, 2)
+	assert.Equal(t, 300, after["/pets/*"].PathDelayValue)
+
+	// nothing is recompiled when nothing changes.
+	_, err = controls.SetPathDelays(&controls.PathDelaysChange{PathDelays: map[string]int{"/toys": 10}}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%p", after), fmt.Sprintf("%p", config.GetCompiledPathDelays()))
+}
+
+func TestSetPathDelays_UnderTraffic(t *testing.T) {
+	config := testConfig(map[string]int{"/pets/*": 200})
+
+	// delays are looked up for every request, while they are being changed.
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					configModel.FindPathDelay("/pets/1", config)
+					config.ReplaceWithVariables("/pets/${id}")
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		delays := map[string]int{"/pets/*": i + 1}
+		for j := 0; j < 10; j++ {
+			delays[fmt.Sprintf("/path/%d/%d", i, j)] = j + 1
+		}
+		_, err := controls.SetPathDelays(&controls.PathDelaysChange{PathDelays: delays, Replace: true}, "test")
+		require.NoError(t, err)
+		config.Variables = map[string]string{"id": fmt.Sprint(i)}
+		config.CompileVariables()
+	}
+	close(done)
+	wg.Wait()
+	assert.Equal(t, 200, configModel.FindPathDelay("/pets/1", config))
+}
+
+// recordingCore keeps the responses the controls service sends.
+type recordingCore struct {
+	service.FabricServiceCore
+	sent []*controls.ControlResponse
+}
+
+func (rc *recordingCore) SendResponse(_ *model.Request, payload interface{}) {
+	rc.sent = append(rc.sent, payload.(*controls.ControlResponse))
+}
+
+func TestCapturePaused(t *testing.T) {
+	config := testConfig(nil)
+	cs := controls.NewControlsService()
+	core := &recordingCore{}
+	monitor := &model.BrokerDestinationConfig{ConnectionId: "abc"}
+
+	for _, command := range []string{controls.PauseCaptureRequest, controls.PauseCaptureRequest,
+		controls.ResumeCaptureRequest} {
+		cs.HandleServiceRequest(&model.Request{RequestCommand: command, BrokerDestination: monitor}, core)
+		assert.Equal(t, command == controls.PauseCaptureRequest, config.CapturePaused)
+		require.NotEmpty(t, core.sent)
+		assert.Equal(t, config.CapturePaused, core.sent[len(core.sent)-1].Config.CapturePaused)
+	}
+	assert.Len(t, core.sent, 3)
+}
@@ -0,0 +1,267 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package coverage tracks which parts of a specification are exercised by traffic: operations, the response codes
+// defined for them, and their parameters.
+package coverage
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// Tracker counts how often every operation, response code and parameter of a specification is seen.
+type Tracker struct {
+	doc        *v3.Document
+	operations []*operation
+	lookup     map[*v3.Operation]*operation
+	lock       sync.Mutex
+}
+
+type operation struct {
+	path       string
+	method     string
+	definition *v3.Operation
+	requests   int
+	codes      map[int]int
+	responses  []*hits
+	undefined  map[string]int
+	parameters []*hits
+}
+
+// hits counts how often something defined in the specification was seen, the key is used to match it.
+type hits struct {
+	name  string
+	key   string
+	count int
+}
+
+// NewTracker creates a tracker for every operation of a specification, nothing has been seen yet.
+func NewTracker(doc *v3.Document) *Tracker {
+	t := &Tracker{doc: doc, lookup: make(map[*v3.Operation]*operation)}
+	if doc == nil || doc.Paths == nil || doc.Paths.PathItems == nil {
+		return t
+	}
+	for pathPairs := doc.Paths.PathItems.First(); pathPairs != nil; pathPairs = pathPairs.Next() {
+		pathItem := pathPairs.Value()
+		for opPairs := pathItem.GetOperations().First(); opPairs != nil; opPairs = opPairs.Next() {
+			op := &operation{
+				path:       pathPairs.Key(),
+				method:     strings.ToUpper(opPairs.Key()),
+				definition: opPairs.Value(),
+				codes:      make(map[int]int),
+				undefined:  make(map[string]int),
+			}
+			if responses := op.definition.Responses; responses != nil {
+				if responses.Codes != nil {
+					for codePairs := responses.Codes.First(); codePairs != nil; codePairs = codePairs.Next() {
+						op.responses = append(op.responses, &hits{name: codePairs.Key(),
+							key: strings.ToUpper(codePairs.Key())})
+					}
+				}
+				if responses.Default != nil {
+					op.responses = append(op.responses, &hits{name: "default", key: "default"})
+				}
+			}
+			// operation parameters override path parameters with the same name and location.
+			seen := make(map[string]bool)
+			for _, p := range append(append([]*v3.Parameter(nil), op.definition.Parameters...), pathItem.Parameters...) {
+				key := strings.ToLower(p.In) + ":" + p.Name
+				if strings.EqualFold(p.In, helpers.Header) {
+					key = strings.ToLower(key)
+				}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				op.parameters = append(op.parameters, &hits{name: fmt.Sprintf("%s (%s)", p.Name, p.In), key: key})
+			}
+			t.operations = append(t.operations, op)
+			t.lookup[op.definition] = op
+		}
+	}
+	return t
+}
+
+// find locates the operation a request maps to, nil if there isn't one.
+func (t *Tracker) find(request *http.Request) *operation {
+	if t == nil || t.doc == nil || request == nil {
+		return nil
+	}
+	pathItem, _, _ := paths.FindPath(request, t.doc)
+	if pathItem == nil {
+		return nil
+	}
+	definition := helpers.ExtractOperation(request, pathItem)
+	if definition == nil {
+		return nil
+	}
+	return t.lookup[definition]
+}
+
+// RecordRequest marks the operation a request maps to as exercised, along with the parameters it sends.
+func (t *Tracker) RecordRequest(request *http.Request) {
+	op := t.find(request)
+	if op == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	op.requests++
+	query := request.URL.Query()
+	for _, p := range op.parameters {
+		location, name, _ := strings.Cut(p.key, ":")
+		switch location {
+		case helpers.Path:
+			p.count++ // the request could not have matched without it.
+		case helpers.Query:
+			if _, ok := query[name]; ok {
+				p.count++
+			}
+		case helpers.Header:
+			if request.Header.Get(name) != "" {
+				p.count++
+			}
+		case helpers.Cookie:
+			if _, err := request.Cookie(name); err == nil {
+				p.count++
+			}
+		}
+	}
+}
+
+// RecordResponse marks the response code a response maps to as exercised. Codes are matched exactly first, then
+// by range (e.g. 2XX), then by the default response. Codes that match nothing are recorded as undefined.
+func (t *Tracker) RecordResponse(request *http.Request, response *http.Response) {
+	if response == nil {
+		return
+	}
+	op := t.find(request)
+	if op == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	op.codes[response.StatusCode]++
+	code := fmt.Sprint(response.StatusCode)
+	for _, key := range []string{code, code[:1] + "XX", "default"} {
+		for _, r := range op.responses {
+			if r.key == key {
+				r.count++
+				return
+			}
+		}
+	}
+	op.undefined[code]++
+}
+
+// Summary is the coverage of one kind of thing in the specification.
+type Summary struct {
+	Total   int     `json:"total"`
+	Covered int     `json:"covered"`
+	Percent float64 `json:"percent"`
+}
+
+// Item is something defined in the specification, and how often it was seen.
+type Item struct {
+	Name string `json:"name"`
+	Hits int    `json:"hits"`
+}
+
+// OperationReport is the coverage of a single operation.
+type OperationReport struct {
+	Path                string         `json:"path"`
+	Method              string         `json:"method"`
+	OperationId         string         `json:"operationId,omitempty"`
+	Requests            int            `json:"requests"`
+	StatusCodes         map[string]int `json:"statusCodes,omitempty"`
+	Responses           []*Item        `json:"responses,omitempty"`
+	UndefinedResponses  map[string]int `json:"undefinedResponses,omitempty"`
+	Parameters          []*Item        `json:"parameters,omitempty"`
+	UncoveredResponses  []string       `json:"uncoveredResponses,omitempty"`
+	UncoveredParameters []string       `json:"uncoveredParameters,omitempty"`
+}
+
+// Report is the coverage of a specification.
+type Report struct {
+	Operations Summary            `json:"operations"`
+	Responses  Summary            `json:"responses"`
+	Parameters Summary            `json:"parameters"`
+	Untouched  []string           `json:"untouched,omitempty"`
+	Details    []*OperationReport `json:"details"`
+}
+
+// Report returns the coverage so far, operations are in the order they are defined in the specification.
+func (t *Tracker) Report() *Report {
+	report := &Report{Details: []*OperationReport{}}
+	if t == nil {
+		return report
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, op := range t.operations {
+		detail := &OperationReport{
+			Path:        op.path,
+			Method:      op.method,
+			OperationId: op.definition.OperationId,
+			Requests:    op.requests,
+		}
+		report.Operations.Total++
+		if op.requests > 0 {
+			report.Operations.Covered++
+		} else {
+			report.Untouched = append(report.Untouched, op.method+" "+op.path)
+		}
+		if len(op.codes) > 0 {
+			detail.StatusCodes = make(map[string]int, len(op.codes))
+			for code, count := range op.codes {
+				detail.StatusCodes[fmt.Sprint(code)] = count
+			}
+		}
+		for _, r := range op.responses {
+			detail.Responses = append(detail.Responses, &Item{Name: r.name, Hits: r.count})
+			report.Responses.Total++
+			if r.count > 0 {
+				report.Responses.Covered++
+			} else {
+				detail.UncoveredResponses = append(detail.UncoveredResponses, r.name)
+			}
+		}
+		for _, p := range op.parameters {
+			detail.Parameters = append(detail.Parameters, &Item{Name: p.name, Hits: p.count})
+			report.Parameters.Total++
+			if p.count > 0 {
+				report.Parameters.Covered++
+			} else {
+				detail.UncoveredParameters = append(detail.UncoveredParameters, p.name)
+			}
+		}
+		if len(op.undefined) > 0 {
+			detail.UndefinedResponses = make(map[string]int, len(op.undefined))
+			for code, count := range op.undefined {
+				detail.UndefinedResponses[code] = count
+			}
+		}
+		report.Details = append(report.Details, detail)
+	}
+	for _, s := range []*Summary{&report.Operations, &report.Responses, &report.Parameters} {
+		s.Percent = percent(s.Covered, s.Total)
+	}
+	return report
+}
+
+// percent is rounded to two decimal places, nothing to cover is fully covered.
+func percent(covered, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return math.Round(float64(covered)/float64(total)*10000) / 100
+}
@@ -0,0 +1,98 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package coverage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var coverageSpec = `openapi: 3.1.0
+info:
+  title: coverage
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+        - name: X-Trace
+          in: header
+          schema:
+            type: string
+      responses:
+        '200':
+          description: ok
+        4XX:
+          description: bad
+    post:
+      responses:
+        '201':
+          description: created
+  /pets/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      responses:
+        '200':
+          description: ok
+        default:
+          description: error`
+
+func TestTracker_Report(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(coverageSpec))
+	m, _ := d.BuildV3Model()
+	tracker := NewTracker(&m.Model)
+
+	list := httptest.NewRequest(http.MethodGet, "/pets?limit=10", nil)
+	tracker.RecordRequest(list)
+	tracker.RecordResponse(list, &http.Response{StatusCode: 200})
+	tracker.RecordResponse(list, &http.Response{StatusCode: 404})
+	tracker.RecordResponse(list, &http.Response{StatusCode: 500})
+
+	pet := httptest.NewRequest(http.MethodGet, "/pets/fluffy", nil)
+	tracker.RecordRequest(pet)
+	tracker.RecordResponse(pet, &http.Response{StatusCode: 503})
+
+	tracker.RecordRequest(httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	report := tracker.Report()
+	assert.Equal(t, Summary{Total: 3, Covered: 2, Percent: 66.67}, report.Operations)
+	assert.Equal(t, Summary{Total: 5, Covered: 3, Percent: 60}, report.Responses)
+	assert.Equal(t, Summary{Total: 3, Covered: 2, Percent: 66.67}, report.Parameters)
+
+	assert.Len(t, report.Details, 3)
+	listReport := report.Details[0]
+	assert.Equal(t, "listPets", listReport.OperationId)
+	assert.Equal(t, 1, listReport.Requests)
+	assert.Equal(t, []*Item{{Name: "200", Hits: 1}, {Name: "4XX", Hits: 1}}, listReport.Responses)
+	assert.Equal(t, map[string]int{"500": 1}, listReport.UndefinedResponses)
+	assert.Equal(t, map[string]int{"200": 1, "404": 1, "500": 1}, listReport.StatusCodes)
+	assert.Equal(t, []string{"POST /pets"}, report.Untouched)
+	assert.Equal(t, []string{"X-Trace (header)"}, listReport.UncoveredParameters)
+	assert.Equal(t, []string{"201"}, report.Details[1].UncoveredResponses)
+	assert.Equal(t, []*Item{{Name: "id (path)", Hits: 1}}, report.Details[2].Parameters)
+	assert.Equal(t, []string{"200"}, report.Details[2].UncoveredResponses)
+}
+
+func TestTracker_NoSpecification(t *testing.T) {
+	tracker := NewTracker(nil)
+	tracker.RecordRequest(httptest.NewRequest(http.MethodGet, "/pets", nil))
+	report := tracker.Report()
+	assert.Empty(t, report.Details)
+	assert.Equal(t, float64(100), report.Operations.Percent)
+}
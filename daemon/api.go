@@ -4,11 +4,12 @@
 package daemon
 
 import (
-	"crypto/tls"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 
 	"github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/grpc"
 	"github.com/pterm/pterm"
 
 	"github.com/pb33f/wiretap/shared"
@@ -16,47 +17,75 @@ import (
 
 type wiretapTransport struct {
 	capturedCookieHeaders []string
+	capturedRawHeaders    []*HttpHeader
 	originalTransport     http.RoundTripper
+	h2c                   bool
 }
 
 func newWiretapTransport() *wiretapTransport {
-	// Disable ssl cert checks
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	return &wiretapTransport{
-		originalTransport: http.DefaultTransport,
+		originalTransport: upstreamTransport,
 	}
 }
 
 func (c *wiretapTransport) RoundTrip(r *http.Request) (*http.Response, error) {
-	resp, err := c.originalTransport.RoundTrip(r)
+	// keep track of the connection used, so the raw headers can be pulled from it.
+	var recorder *headerRecordingConn
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if rc, ok := info.Conn.(*headerRecordingConn); ok {
+				recorder = rc
+			}
+		},
+	}
+	r = r.WithContext(httptrace.WithClientTrace(r.Context(), trace))
+
+	transport := c.originalTransport
+	if c.h2c && r.URL.Scheme == "http" {
+		transport = upstreamH2CTransport
+	} else if target := findClientCertTarget(r.URL); target != nil && r.URL.Scheme == "https" {
+		transport = target.transport
+	}
+	resp, err := transport.RoundTrip(r)
 	if resp != nil {
 		cookie := resp.Header.Get("Set-Cookie")
 		if cookie != "" {
 			c.capturedCookieHeaders = append(c.capturedCookieHeaders, cookie)
 		}
+		if recorder != nil {
+			c.capturedRawHeaders = recorder.RecordedHeaders()
+		}
 	}
 	return resp, err
 }
 
-func (ws *WiretapService) callAPI(req *http.Request) (*http.Response, error) {
-
-	tr := newWiretapTransport()
-	client := &http.Client{Transport: tr}
+func (ws *WiretapService) callAPI(req *http.Request) (*http.Response, []*HttpHeader, error) {
 
 	configStore, _ := ws.controlsStore.Get(shared.ConfigKey)
 
 	// create a new request from the original request, but replace the path
 	wiretapConfig := configStore.(*shared.WiretapConfiguration)
 
-	// lookup path and determine if we need to redirect it.
-	replaced := config.RewritePath(req.URL.Path, wiretapConfig)
-	if replaced != req.URL.Path {
-		newUrl, _ := url.Parse(replaced)
-		if req.URL.RawQuery != "" {
-			newUrl.RawQuery = req.URL.RawQuery
+	tr := newWiretapTransport()
+	tr.h2c = wiretapConfig.UpstreamH2C
+	client := &http.Client{Transport: tr}
+
+	// RESTful requests for a gRPC API are sent as gRPC calls (over HTTP/2), their path is fixed by the method.
+	var route *grpc.Route
+	if ws.grpcTranscoder != nil {
+		var values map[string]string
+		if route, values = ws.grpcTranscoder.Match(req); route != nil {
+			call, err := ws.grpcTranscoder.TranscodeRequest(req, route, values)
+			if err != nil {
+				return grpc.ErrorResponse(req, err), nil, nil
+			}
+			req, tr.h2c = call, true
 		}
-		pterm.Info.Printf("[wiretap] Re-writing path '%s' to '%s'\n", req.URL.String(), newUrl.String())
-		req.URL = newUrl
+	}
+
+	// lookup path and determine if we need to redirect it.
+	if route == nil {
+		rewriteRequestURL(req, wiretapConfig)
 	}
 
 	// re-write referer
@@ -72,7 +101,7 @@ func (ws *WiretapService) callAPI(req *http.Request) (*http.Response, error) {
 	resp, err := client.Do(req)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(tr.capturedCookieHeaders) > 0 {
@@ -80,5 +109,24 @@ func (ws *WiretapService) callAPI(req *http.Request) (*http.Response, error) {
 			resp.Header.Set("Set-Cookie", tr.capturedCookieHeaders[0])
 		}
 	}
-	return resp, nil
+	if route != nil {
+		resp = ws.grpcTranscoder.TranscodeResponse(resp, route)
+	}
+	return resp, tr.capturedRawHeaders, nil
+}
+
+// rewriteRequestURL looks up any path configuration for the request and re-writes the URL to the target.
+func rewriteRequestURL(req *http.Request, wiretapConfig *shared.WiretapConfiguration) {
+	replaced := config.RewritePath(req.URL.Path, wiretapConfig)
+	if replaced != req.URL.Path {
+		newUrl, _ := url.Parse(replaced)
+		if req.URL.RawQuery != "" {
+			newUrl.RawQuery = req.URL.RawQuery
+		}
+		pterm.Info.Printf("[wiretap] Re-writing path '%s' to '%s'\n", req.URL.String(), newUrl.String())
+		if wiretapConfig.Logger != nil {
+			wiretapConfig.Logger.Info("[wiretap] request path rewritten", "from", req.URL.String(), "to", newUrl.String())
+		}
+		req.URL = newUrl
+	}
 }
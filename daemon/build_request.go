@@ -140,6 +140,8 @@ func BuildHttpTransaction(build HttpTransactionConfig) *HttpTransaction {
 		}
 	}
 
+	destination, label := labelRequest(build.OriginalRequest, cf)
+
 	return &HttpTransaction{
 		Id: build.ID.String(),
 		Request: &HttpRequest{
@@ -151,6 +153,10 @@ func BuildHttpTransaction(build HttpTransactionConfig) *HttpTransaction {
 			DroppedHeaders:  dropHeaders,
 			InjectedHeaders: injectHeaders,
 			OriginalPath:    build.NewRequest.URL.Path,
+			Destination:     destination,
+			Label:           label,
+			Webhook:         config.FindWebhook(build.OriginalRequest.URL.Path, cf),
+			ResendOf:        resendOf(build.OriginalRequest),
 			Cookies:         cookies,
 			Headers:         headers,
 			Body:            string(requestBody),
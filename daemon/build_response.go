@@ -25,6 +25,7 @@ func CloneExistingResponse(r *http.Response) *http.Response {
 	resp := &http.Response{
 		StatusCode: r.StatusCode,
 		Header:     r.Header,
+		Trailer:    r.Trailer,
 	}
 	if r.Body != nil {
 		resp.Body = io.NopCloser(bytes.NewBuffer(b))
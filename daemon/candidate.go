@@ -0,0 +1,64 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// CandidateValidation is the validation type of breaking changes, violations of the candidate specification by
+// traffic that is valid against the specification. Their subtype is the rule broken in the candidate, so the
+// rule identifiers look like `candidate/parameter/query` or `candidate/response/schema`.
+const CandidateValidation = "candidate"
+
+// usesCandidate checks if traffic validated by a validator is also validated against the candidate specification,
+// the candidate is the next version of the main specification, so it doesn't apply to anything else.
+func (ws *WiretapService) usesCandidate(validator validation.HttpValidator) bool {
+	if ws.candidateValidator == nil {
+		return false
+	}
+	ws.specLock.RLock()
+	defer ws.specLock.RUnlock()
+	return validator == ws.validator
+}
+
+// validateCandidateRequest validates a request that passed against the specification, against the candidate.
+func (ws *WiretapService) validateCandidateRequest(request *http.Request) []*errors.ValidationError {
+	_, violations := ws.candidateValidator.ValidateHttpRequest(request)
+	return breakingChanges(violations)
+}
+
+// validateCandidateResponse validates a response that passed against the specification, against the candidate.
+// Missing paths have been reported for the request already.
+func (ws *WiretapService) validateCandidateResponse(request *http.Request, response *http.Response) []*errors.ValidationError {
+	_, violations := ws.candidateValidator.ValidateHttpResponse(request, response)
+	var found []*errors.ValidationError
+	for _, v := range violations {
+		if !v.IsPathMissingError() {
+			found = append(found, v)
+		}
+	}
+	return breakingChanges(found)
+}
+
+// breakingChanges turns violations of the candidate specification into breaking changes. Spec lines point into
+// the candidate.
+func breakingChanges(violations []*errors.ValidationError) []*errors.ValidationError {
+	var changes []*errors.ValidationError
+	for _, v := range violations {
+		change := *v
+		change.ValidationType = CandidateValidation
+		change.ValidationSubType = configModel.ViolationRule(v)
+		change.Message = fmt.Sprintf("Breaking change: %s", v.Message)
+		change.Reason = fmt.Sprintf("Valid today, but not against the candidate specification. %s", v.Reason)
+		changes = append(changes, &change)
+	}
+	return changes
+}
@@ -0,0 +1,31 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakingChanges(t *testing.T) {
+	violation := &errors.ValidationError{
+		ValidationType:    "parameter",
+		ValidationSubType: "header",
+		Message:           "Header parameter 'X-Tenant' is missing",
+		Reason:            "The header parameter 'X-Tenant' is defined as being required",
+		SpecLine:          8,
+	}
+	changes := breakingChanges([]*errors.ValidationError{violation})
+
+	assert.Len(t, changes, 1)
+	assert.Equal(t, CandidateValidation, changes[0].ValidationType)
+	assert.Equal(t, "parameter/header", changes[0].ValidationSubType)
+	assert.Equal(t, "Breaking change: Header parameter 'X-Tenant' is missing", changes[0].Message)
+	assert.Equal(t, 8, changes[0].SpecLine)
+	assert.Equal(t, "parameter", violation.ValidationType)
+	assert.Empty(t, breakingChanges(nil))
+}
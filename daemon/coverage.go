@@ -0,0 +1,20 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"github.com/pb33f/wiretap/coverage"
+)
+
+// Coverage reports which operations, response codes and parameters of the specification have been exercised.
+// Coverage starts over when the specification is reloaded.
+func (ws *WiretapService) Coverage() *coverage.Report {
+	return ws.currentCoverage().Report()
+}
+
+// HasSpecification checks if an OpenAPI specification is being served.
+func (ws *WiretapService) HasSpecification() bool {
+	return ws.currentDocModel() != nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/grpc"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// requestDestination returns the host and port a request is destined for. Requests sent to a forward proxy carry
+// an absolute URL, requests sent to a transparent proxy only have the Host header to go on.
+func requestDestination(r *http.Request) string {
+	host := r.Host
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if r.URL.IsAbs() {
+		host = r.URL.Host
+		scheme = r.URL.Scheme
+	}
+	if host == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		port := "80"
+		if scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(host, port)
+	}
+	return strings.ToLower(host)
+}
+
+// labelRequest returns the destination and label for a request. Requests forwarded on to the host they were
+// destined for are labeled with the label of the host configuration (or the host and port if there isn't one),
+// everything else is labeled with the host and port of the redirect URL.
+func labelRequest(r *http.Request, config *shared.WiretapConfiguration) (string, string) {
+	host := configModel.FindHost(requestDestination(r), config)
+	if _, hostname, port, ok := forwardTarget(r, host, config); ok {
+		destination := net.JoinHostPort(hostname, port)
+		if host != nil && host.Label != "" {
+			return destination, host.Label
+		}
+		return destination, destination
+	}
+	if config.RedirectHost == "" {
+		return "", ""
+	}
+	port := config.RedirectPort
+	if port == "" {
+		port = "80"
+		if config.RedirectProtocol == "https" {
+			port = "443"
+		}
+	}
+	destination := net.JoinHostPort(config.RedirectHost, port)
+	return destination, destination
+}
+
+// forwardTarget determines if a request should be sent on to the host it was destined for, instead of the redirect
+// URL. That's the case for hosts with a configuration, and for forward proxy requests when there is no redirect URL.
+func forwardTarget(r *http.Request, host *shared.WiretapHostConfig,
+	config *shared.WiretapConfiguration) (protocol, hostname, port string, ok bool) {
+
+	if host == nil && (!r.URL.IsAbs() || config.RedirectHost != "") {
+		return "", "", "", false
+	}
+	hostname, port, err := net.SplitHostPort(requestDestination(r))
+	if err != nil {
+		return "", "", "", false
+	}
+	protocol = "http"
+	if (host != nil && host.Secure) || r.URL.Scheme == "https" {
+		protocol = "https"
+
+		// no port was asked for, so use the default for https.
+		requested := r.Host
+		if r.URL.IsAbs() {
+			requested = r.URL.Host
+		}
+		if _, _, e := net.SplitHostPort(requested); e != nil {
+			port = "443"
+		}
+	}
+	return protocol, hostname, port, true
+}
+
+// locateValidator returns the validator for a request. Requests to the GraphQL endpoint are validated against
+// the GraphQL schema. Requests to hosts with their own specification are validated against that, then requests
+// for paths (or path prefixes) with their own specification, everything else uses the main specification.
+func (ws *WiretapService) locateValidator(r *http.Request) validation.HttpValidator {
+	if ws.graphqlValidator != nil && isGraphQLRequest(r, ws.config) {
+		return ws.graphqlValidator
+	}
+	if ws.grpcValidator != nil && grpc.IsGRPC(r.Header.Get("Content-Type")) {
+		if !ws.config.GRPCValidate {
+			return nil // decoded to be shown, but not validated.
+		}
+		return ws.grpcValidator
+	}
+	if webhook := configModel.FindWebhook(r.URL.Path, ws.config); webhook != "" {
+		ws.specLock.RLock()
+		webhooks := ws.webhookValidator
+		ws.specLock.RUnlock()
+		if webhooks.Has(webhook) {
+			return webhooks.For(webhook)
+		}
+	}
+	if len(ws.hostValidators) > 0 {
+		if host := configModel.FindHost(requestDestination(r), ws.config); host != nil {
+			if v, ok := ws.hostValidators[host]; ok {
+				return v
+			}
+		}
+	}
+	if len(ws.pathValidators) > 0 {
+		for _, path := range ws.matchedPathConfigs(r) {
+			if v, ok := ws.pathValidators[path]; ok {
+				return v
+			}
+		}
+	}
+	if len(ws.prefixValidators) > 0 {
+		if v, ok := ws.prefixValidators[configModel.FindContractPrefix(r.URL.Path, ws.config)]; ok {
+			return v
+		}
+	}
+	ws.specLock.RLock()
+	defer ws.specLock.RUnlock()
+	if ws.document != nil && ws.docModel != nil {
+		return ws.validator
+	}
+	return nil
+}
+
+// matchedPathConfigs returns the path configurations that match a request, globally and for the host the
+// request is for.
+func (ws *WiretapService) matchedPathConfigs(r *http.Request) []*shared.WiretapPathConfig {
+	paths := configModel.FindPaths(r.URL.Path, ws.config)
+	if host := configModel.FindHost(requestDestination(r), ws.config); host != nil {
+		paths = append(paths, configModel.FindHostPaths(r.URL.Path, host)...)
+	}
+	return paths
+}
+
+// validationScope works out if the request and the response are validated, using the path configurations that
+// match the request.
+func (ws *WiretapService) validationScope(r *http.Request) (request, response bool) {
+	return configModel.ValidationScope(ws.matchedPathConfigs(r))
+}
+
+// suppressViolations removes the violations suppressed globally, or by a path configuration that matches the request.
+func (ws *WiretapService) suppressViolations(r *http.Request,
+	violations []*errors.ValidationError) []*errors.ValidationError {
+
+	if len(violations) == 0 {
+		return violations
+	}
+	rules := ws.config.Suppress
+	for _, path := range ws.matchedPathConfigs(r) {
+		rules = append(rules[:len(rules):len(rules)], path.Suppress...)
+	}
+	return configModel.SuppressViolations(violations, rules)
+}
+
+// classifyViolations attaches the configured severity to every violation.
+func (ws *WiretapService) classifyViolations(violations []*errors.ValidationError) []*shared.Violation {
+	return configModel.ClassifyViolations(violations, ws.config.Severity)
+}
+
+// errorViolations returns the violations with an error severity, warnings and info are never acted upon.
+func (ws *WiretapService) errorViolations(violations []*errors.ValidationError) []*errors.ValidationError {
+	if len(ws.config.Severity) == 0 {
+		return violations
+	}
+	var errs []*errors.ValidationError
+	for _, violation := range violations {
+		if configModel.ViolationSeverity(violation, ws.config.Severity) == shared.SeverityError {
+			errs = append(errs, violation)
+		}
+	}
+	return errs
+}
+
+// isGraphQLRequest checks if a request is sent to the GraphQL endpoint.
+func isGraphQLRequest(r *http.Request, config *shared.WiretapConfiguration) bool {
+	graphqlPath := config.GraphQLPath
+	if graphqlPath == "" {
+		graphqlPath = shared.DefaultGraphQLPath
+	}
+	return strings.TrimSuffix(r.URL.Path, "/") == strings.TrimSuffix(graphqlPath, "/")
+}
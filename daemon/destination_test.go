@@ -0,0 +1,51 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelRequest(t *testing.T) {
+	config := &shared.WiretapConfiguration{
+		RedirectHost:     "api.pb33f.io",
+		RedirectProtocol: "https",
+		Hosts: map[string]*shared.WiretapHostConfig{
+			"*.api.example.com": {Label: "example", Secure: true},
+		},
+	}
+	config.CompileHosts()
+
+	// transparent proxy, configured host.
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	r.Host = "eu.api.example.com"
+	destination, label := labelRequest(r, config)
+	assert.Equal(t, "eu.api.example.com:443", destination)
+	assert.Equal(t, "example", label)
+
+	protocol, host, port, ok := forwardTarget(r, config.CompiledHosts["*.api.example.com"].HostConfig, config)
+	assert.True(t, ok)
+	assert.Equal(t, "https", protocol)
+	assert.Equal(t, "eu.api.example.com", host)
+	assert.Equal(t, "443", port)
+
+	// everything else goes to the redirect URL.
+	r = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	destination, label = labelRequest(r, config)
+	assert.Equal(t, "api.pb33f.io:443", destination)
+	assert.Equal(t, destination, label)
+
+	// forward proxy requests without a redirect URL are sent on.
+	config.RedirectHost = ""
+	r = httptest.NewRequest(http.MethodGet, "http://localhost:8080/orders", nil)
+	destination, label = labelRequest(r, config)
+	assert.Equal(t, "localhost:8080", destination)
+	assert.Equal(t, "localhost:8080", label)
+}
@@ -4,7 +4,9 @@
 package daemon
 
 import (
-	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/graphql"
+	"github.com/pb33f/wiretap/grpc"
+	"github.com/pb33f/wiretap/shared"
 	"net/textproto"
 	"time"
 )
@@ -22,6 +24,12 @@ type HttpCookie struct {
 	HttpOnly bool `json:"httpOnly,omitempty"`
 }
 
+// HttpHeader is a single header exactly as it was sent on the wire.
+type HttpHeader struct {
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
 type HttpRequest struct {
 	Timestamp       int64                  `json:"timestamp,omitempty"`
 	URL             string                 `json:"url,omitempty"`
@@ -29,29 +37,45 @@ type HttpRequest struct {
 	Host            string                 `json:"host,omitempty"`
 	Path            string                 `json:"path,omitempty"`
 	OriginalPath    string                 `json:"originalPath,omitempty"`
+	Destination     string                 `json:"destination,omitempty"`
+	Label           string                 `json:"label,omitempty"`
+	Webhook         string                 `json:"webhook,omitempty"`
+	ResendOf        string                 `json:"resendOf,omitempty"`
 	DroppedHeaders  []string               `json:"droppedHeaders,omitempty"`
 	InjectedHeaders map[string]string      `json:"injectedHeaders,omitempty"`
 	Query           string                 `json:"query,omitempty"`
 	Headers         map[string]any         `json:"headers,omitempty"`
 	Body            string                 `json:"requestBody,omitempty"`
+	BodySize        int                    `json:"bodySize,omitempty"`
+	BodyTruncated   bool                   `json:"bodyTruncated,omitempty"`
+	BodyEncoding    string                 `json:"bodyEncoding,omitempty"`
 	Cookies         map[string]*HttpCookie `json:"cookies,omitempty"`
+	GraphQL         []*graphql.Operation   `json:"graphql,omitempty"`
+	GRPC            *grpc.Payload          `json:"grpc,omitempty"`
 }
 
 type HttpResponse struct {
-	Timestamp  int64                  `json:"timestamp,omitempty"`
-	Headers    map[string]any         `json:"headers,omitempty"`
-	StatusCode int                    `json:"statusCode,omitempty"`
-	Body       string                 `json:"responseBody,omitempty"`
-	Cookies    map[string]*HttpCookie `json:"cookies,omitempty"`
-	Time       time.Time              `json:"-"`
+	Timestamp     int64                  `json:"timestamp,omitempty"`
+	Headers       map[string]any         `json:"headers,omitempty"`
+	StatusCode    int                    `json:"statusCode,omitempty"`
+	Body          string                 `json:"responseBody,omitempty"`
+	BodySize      int                    `json:"bodySize,omitempty"`
+	BodyTruncated bool                   `json:"bodyTruncated,omitempty"`
+	BodyEncoding  string                 `json:"bodyEncoding,omitempty"`
+	Cookies       map[string]*HttpCookie `json:"cookies,omitempty"`
+	Latency       float64                `json:"upstreamLatency,omitempty"`
+	GRPC          *grpc.Payload          `json:"grpc,omitempty"`
+	Events        []*ServerSentEvent     `json:"events,omitempty"`
+	Frames        []*WebSocketFrame      `json:"frames,omitempty"`
+	Time          time.Time              `json:"-"`
 }
 
 type HttpTransaction struct {
-	Request            *HttpRequest              `json:"httpRequest,omitempty"`
-	RequestValidation  []*errors.ValidationError `json:"requestValidation,omitempty"`
-	Response           *HttpResponse             `json:"httpResponse,omitempty"`
-	ResponseValidation []*errors.ValidationError `json:"responseValidation,omitempty"`
-	Id                 string                    `json:"id,omitempty"`
+	Request            *HttpRequest        `json:"httpRequest,omitempty"`
+	RequestValidation  []*shared.Violation `json:"requestValidation,omitempty"`
+	Response           *HttpResponse       `json:"httpResponse,omitempty"`
+	ResponseValidation []*shared.Violation `json:"responseValidation,omitempty"`
+	Id                 string              `json:"id,omitempty"`
 }
 
 type FormPart struct {
@@ -0,0 +1,64 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/graphql"
+	"github.com/pb33f/wiretap/shared"
+)
+
+type graphqlOperationsKey struct{}
+
+// readGraphQLOperations reads the operations of a request to the GraphQL endpoint once, before anything else reads
+// its body, and keeps them with the request. Nothing is read unless there is a GraphQL endpoint configured.
+func readGraphQLOperations(request *model.Request, config *shared.WiretapConfiguration) {
+	if config.GraphQLPath == "" || !isGraphQLRequest(request.HttpRequest, config) {
+		return
+	}
+	if operations := graphql.ReadOperations(request.HttpRequest); len(operations) > 0 {
+		request.HttpRequest = request.HttpRequest.WithContext(
+			context.WithValue(request.HttpRequest.Context(), graphqlOperationsKey{}, operations))
+	}
+}
+
+// graphqlOperations returns the GraphQL operations a request runs, nil if it isn't a GraphQL request.
+func graphqlOperations(r *http.Request) []*graphql.Operation {
+	operations, _ := r.Context().Value(graphqlOperationsKey{}).([]*graphql.Operation)
+	return operations
+}
+
+// decodeGraphQLRequest adds the operations a GraphQL request runs to its transaction, to be shown.
+func decodeGraphQLRequest(transaction *HttpTransaction, request *http.Request) {
+	if transaction.Request != nil {
+		transaction.Request.GraphQL = graphqlOperations(request)
+	}
+}
+
+// graphqlOperationPath is the path of a GraphQL request in reports, each operation is one of its own:
+// '/graphql query GetPets'. The path is returned as it is for any other request.
+func graphqlOperationPath(r *http.Request, path string) string {
+	if operations := graphqlOperations(r); len(operations) > 0 {
+		return path + " " + graphql.DescribeOperations(operations)
+	}
+	return path
+}
+
+// findGraphQLDelay returns the delay configured for the operations of a GraphQL request, by name ('GetPets') or
+// type and name ('query GetPets'). The longest delay of a batch is used, zero if there isn't one.
+func findGraphQLDelay(r *http.Request, config *shared.WiretapConfiguration) int {
+	delay := 0
+	for _, op := range graphqlOperations(r) {
+		for _, key := range []string{op.Name, op.String()} {
+			if d, ok := config.GraphQLDelays[key]; ok && key != "" && d > delay {
+				delay = d
+			}
+		}
+	}
+	return delay
+}
@@ -0,0 +1,45 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphQLOperations(t *testing.T) {
+	config := &shared.WiretapConfiguration{
+		GraphQLPath:   "/graphql",
+		GraphQLDelays: map[string]int{"GetPets": 100, "mutation AddPet": 300},
+	}
+	read := func(path, body string) *http.Request {
+		r, _ := http.NewRequest(http.MethodPost, "http://localhost"+path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		request := &model.Request{HttpRequest: r}
+		readGraphQLOperations(request, config)
+		return request.HttpRequest
+	}
+
+	r := read("/graphql", `{"query":"query GetPets { pets { name } }"}`)
+	assert.Equal(t, "/graphql query GetPets", graphqlOperationPath(r, "/graphql"))
+	assert.Equal(t, 100, findGraphQLDelay(r, config))
+
+	r = read("/graphql", `[{"query":"query GetPets { pets { name } }"},{"query":"mutation AddPet { add }"}]`)
+	assert.Equal(t, "/graphql query GetPets, mutation AddPet", graphqlOperationPath(r, "/graphql"))
+	assert.Equal(t, 300, findGraphQLDelay(r, config))
+
+	r = read("/graphql", `{"query":"{ pets { name } }"}`)
+	assert.Equal(t, "/graphql query (anonymous)", graphqlOperationPath(r, "/graphql"))
+	assert.Equal(t, 0, findGraphQLDelay(r, config))
+
+	r = read("/pets", `{"query":"query GetPets { pets { name } }"}`)
+	assert.Equal(t, "/pets", graphqlOperationPath(r, "/pets"))
+	assert.Equal(t, 0, findGraphQLDelay(r, config))
+}
@@ -0,0 +1,32 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/pb33f/ranch/model"
+)
+
+// buildResponse builds the response of a transaction, the messages of gRPC responses are decoded to be shown
+// when there are descriptors for the services called. Event streams are split into their events.
+func (ws *WiretapService) buildResponse(request *model.Request, response *http.Response) *HttpTransaction {
+	transaction := BuildResponse(request, response)
+	if isEventStream(response) {
+		transaction.Response.Events = parseServerSentEvents([]byte(transaction.Response.Body))
+	}
+	if ws.grpcValidator != nil {
+		transaction.Response.GRPC = ws.grpcValidator.DecodeResponse(request.HttpRequest, response)
+	}
+	return transaction
+}
+
+// decodeGRPCRequest decodes the messages of a gRPC request to be shown, when there are descriptors for the
+// services called.
+func (ws *WiretapService) decodeGRPCRequest(transaction *HttpTransaction, request *http.Request) {
+	if ws.grpcValidator != nil && transaction.Request != nil {
+		transaction.Request.GRPC = ws.grpcValidator.DecodeRequest(request)
+	}
+}
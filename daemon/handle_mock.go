@@ -10,36 +10,80 @@ import (
 	"github.com/pb33f/ranch/model"
 	configModel "github.com/pb33f/wiretap/config"
 	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/tracing"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// MockHeader forces a single request to be mocked (`X-Wiretap-Mock: true`), even when wiretap is proxying traffic.
+const MockHeader = "X-Wiretap-Mock"
+
+// mockRequested checks if the client asked for a request to be mocked, using the MockHeader. Requests are only
+// mocked when there is a specification to mock them with, they are proxied otherwise.
+func (ws *WiretapService) mockRequested(r *http.Request) bool {
+	requested, _ := strconv.ParseBool(r.Header.Get(MockHeader))
+	return requested && ws.currentDocModel() != nil
+}
+
+// mockPath checks if a path is mocked, paths can be switched between mock and proxy mode regardless of the mock mode
+// of everything else. Paths are only switched to mock mode when there is a specification to mock them with.
+func (ws *WiretapService) mockPath(path string) bool {
+	if mock, found := configModel.FindMockMode(path, ws.config); found && (!mock || ws.currentDocModel() != nil) {
+		return mock
+	}
+	return ws.config.MockMode
+}
+
+// handleMockRequest serves a mocked response for a request. The request is validated unless it already was, which
+// is the case when a proxied request falls back to a mock.
 func (ws *WiretapService) handleMockRequest(
-	request *model.Request, config *shared.WiretapConfiguration, newReq *http.Request) {
+	request *model.Request, config *shared.WiretapConfiguration, newReq *http.Request, validate bool) {
 	// dip out early if we're in mock mode.
-	delay := configModel.FindPathDelay(request.HttpRequest.URL.Path, config)
+	delay := configModel.FindMockLatency(request.HttpRequest.Method, request.HttpRequest.URL.Path, config)
+	if delay <= 0 {
+		delay = findGraphQLDelay(request.HttpRequest, config)
+	}
+	if delay <= 0 {
+		delay = configModel.FindPathDelay(request.HttpRequest.URL.Path, config)
+	}
 	if delay > 0 {
 		time.Sleep(time.Duration(delay) * time.Millisecond) // simulate a slow response, configured for path.
-	} else {
-		if config.GlobalAPIDelay > 0 {
-			time.Sleep(time.Duration(config.GlobalAPIDelay) * time.Millisecond) // simulate a slow response, all paths.
-		}
+	} else if global := config.GetGlobalAPIDelay(); global > 0 {
+		time.Sleep(time.Duration(global) * time.Millisecond) // simulate a slow response, all paths.
+	}
+
+	// callbacks can refer to the request body, so hold on to it.
+	var requestBody []byte
+	if ws.mockCallbacksEnabled() && request.HttpRequest.Body != nil {
+		requestBody, _ = io.ReadAll(request.HttpRequest.Body)
+		_ = request.HttpRequest.Body.Close()
+		request.HttpRequest.Body = io.NopCloser(bytes.NewReader(requestBody))
 	}
 
 	// build a mock based on the request.
-	mock, mockStatus, mockErr := ws.mockEngine.GenerateResponse(request.HttpRequest)
+	span := ws.startSpan(request.HttpRequest, "wiretap mock", tracing.SpanKindInternal)
+	engine := ws.currentMockEngine()
+	mock, mockStatus, mockHeaders, mockErr := engine.GenerateResponseWithHeaders(request.HttpRequest)
+	span.SetAttribute("http.response.status_code", mockStatus)
+	span.SetError(mockErr)
+	span.End()
 
 	// validate http request.
-	ws.ValidateRequest(request, newReq)
+	if validate {
+		violations := ws.ValidateRequest(request, newReq)
+		if len(violations) > 0 && strings.EqualFold(config.MockValidation, shared.MockValidationWarn) {
+			config.Logger.Warn("[wiretap] mocking invalid request", "url", newReq.URL.String(),
+				"violations", len(violations))
+		}
+	}
 
 	// sleep for a few ms, this prevents responses from being sent out of order.
 	time.Sleep(5 * time.Millisecond)
 
-	// wiretap needs to work from anywhere, so allow everything.
-	headers := make(map[string]any)
-	setCORSHeaders(headers)
-	headers["Content-Type"] = "application/json"
+	headers := mockResponseHeaders(mockHeaders)
 
 	buff := bytes.NewBuffer(mock)
 
@@ -47,13 +91,11 @@ func (ws *WiretapService) handleMockRequest(
 	resp := &http.Response{
 		StatusCode: mockStatus,
 		Body:       io.NopCloser(buff),
+		Header:     headers.Clone(),
 	}
-	header := http.Header{}
-	resp.Header = header
 	// write headers
 	for k, v := range headers {
-		request.HttpResponseWriter.Header().Set(k, fmt.Sprint(v))
-		header.Add(k, fmt.Sprint(v))
+		request.HttpResponseWriter.Header()[k] = v
 	}
 
 	// if there was an error building the mock, return a 404
@@ -69,12 +111,12 @@ func (ws *WiretapService) handleMockRequest(
 		return
 	}
 
-	// if the mock exists, but there was an error, return the error
+	// if the mock exists, but there was an error, the engine has described the problem (a 422 with the violations
+	// of an invalid request, for instance), return that.
 	if mockErr != nil && len(mock) > 0 {
 		config.Logger.Warn("[wiretap] mock mode request problem", "url", newReq.URL.String(), "code", mockStatus, "violation", mockErr.Error())
 		request.HttpResponseWriter.WriteHeader(mockStatus)
-		wtError := shared.GenerateError("unable to serve mocked response", mockStatus, mockErr.Error(), "", nil)
-		_, _ = request.HttpResponseWriter.Write(shared.MarshalError(wtError))
+		_, _ = request.HttpResponseWriter.Write(mock)
 
 		// validate response async
 		resp.StatusCode = mockStatus
@@ -84,8 +126,14 @@ func (ws *WiretapService) handleMockRequest(
 
 	// validate response async
 	resp.StatusCode = mockStatus
+	ws.currentCoverage().RecordResponse(request.HttpRequest, resp)
 	go ws.broadcastResponse(request, resp)
 
+	// fire any callbacks / webhooks for the operation.
+	if ws.mockCallbacksEnabled() && mockStatus >= 200 && mockStatus < 300 {
+		go ws.fireMockCallbacks(engine, request.HttpRequest, requestBody, mock, mockStatus)
+	}
+
 	// if the mock is empty
 	request.HttpResponseWriter.WriteHeader(mockStatus)
 	_, errs := request.HttpResponseWriter.Write(mock)
@@ -94,3 +142,32 @@ func (ws *WiretapService) handleMockRequest(
 	}
 	return
 }
+
+// mockResponseHeaders are the headers sent with a mock: every value of the headers the mock engine generated, and
+// CORS headers (wiretap needs to work from anywhere, so allow everything).
+func mockResponseHeaders(mockHeaders http.Header) http.Header {
+	cors := make(map[string]any)
+	setCORSHeaders(cors)
+	headers := http.Header{}
+	for k, v := range cors {
+		headers.Set(k, fmt.Sprint(v))
+	}
+	for k, values := range mockHeaders {
+		headers.Del(k)
+		for _, value := range values {
+			headers.Add(k, value)
+		}
+	}
+	return headers
+}
+
+// mockErrorRate returns the fraction of mocked responses that should be errors for a request, a rate configured
+// for the path wins over the global rate.
+func (ws *WiretapService) mockErrorRate(r *http.Request) float64 {
+	for _, path := range configModel.FindPaths(r.URL.Path, ws.config) {
+		if path.MockErrorRate != nil {
+			return *path.MockErrorRate
+		}
+	}
+	return ws.config.MockErrorRate
+}
@@ -0,0 +1,177 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// invalidPet is a request the pets specification doesn't allow, a pet needs a name.
+func invalidPet() *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader(`{"age":3}`))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func TestHandleMockRequest_Validation(t *testing.T) {
+	tests := []struct {
+		mode      string
+		code      int
+		validated bool
+	}{
+		{"", http.StatusUnprocessableEntity, true},
+		{shared.MockValidationReject, http.StatusUnprocessableEntity, true},
+		{shared.MockValidationWarn, http.StatusCreated, true},
+		{shared.MockValidationIgnore, http.StatusCreated, false},
+	}
+	for _, tt := range tests {
+		t.Run("mode "+tt.mode, func(t *testing.T) {
+			ws := newTestService(t, petsSpec, &shared.WiretapConfiguration{MockMode: true, MockValidation: tt.mode}, nil)
+			w := serveTestRequest(ws, invalidPet())
+			assert.Equal(t, tt.code, w.Code)
+
+			if tt.code == http.StatusUnprocessableEntity {
+				// the violations are sent back, not just the status.
+				var wtError shared.WiretapError
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &wtError))
+				assert.Equal(t, http.StatusUnprocessableEntity, wtError.Status)
+				assert.Contains(t, wtError.Title, "Invalid request")
+				violations, ok := wtError.Payload.([]any)
+				require.True(t, ok)
+				require.NotEmpty(t, violations)
+				assert.Contains(t, violations[0].(map[string]any)["reason"], "schema")
+			} else {
+				assert.JSONEq(t, `{"name":"dave"}`, w.Body.String())
+			}
+
+			// the request is kept either way, violations are only found when it's validated.
+			transactions, err := ws.Transactions()
+			require.NoError(t, err)
+			require.Len(t, transactions, 1)
+			require.NotNil(t, transactions[0].Request)
+			if tt.validated {
+				assert.NotEmpty(t, transactions[0].RequestValidation)
+			} else {
+				assert.Empty(t, transactions[0].RequestValidation)
+			}
+		})
+	}
+}
+
+func TestMockErrorRate(t *testing.T) {
+	never := 0.0
+	config := &shared.WiretapConfiguration{MockMode: true, MockErrorRate: 1,
+		PathConfigurations: map[string]*shared.WiretapPathConfig{"/secure": {MockErrorRate: &never}}}
+	config.CompilePaths()
+	ws := newTestService(t, petsSpec, config, nil)
+
+	// the global rate applies, unless the path has its own.
+	assert.Equal(t, 1.0, ws.mockErrorRate(httptest.NewRequest(http.MethodGet, "/pets", nil)))
+	assert.Equal(t, 0.0, ws.mockErrorRate(httptest.NewRequest(http.MethodGet, "/secure", nil)))
+
+	for i := 0; i < 20; i++ {
+		w := serveTestRequest(ws, httptest.NewRequest(http.MethodGet, "/pets", nil))
+		assert.Contains(t, []int{http.StatusNotFound, http.StatusInternalServerError}, w.Code)
+
+		r := httptest.NewRequest(http.MethodGet, "/secure", nil)
+		r.Header.Set("X-API-Key", "secret")
+		assert.Equal(t, http.StatusOK, serveTestRequest(ws, r).Code)
+	}
+}
+
+func TestMockResponseHeaders(t *testing.T) {
+	mockHeaders := http.Header{}
+	mockHeaders.Add("Set-Cookie", "session=abc")
+	mockHeaders.Add("Set-Cookie", "theme=dark")
+	mockHeaders.Set("Access-Control-Allow-Origin", "https://pb33f.io")
+
+	headers := mockResponseHeaders(mockHeaders)
+	assert.Equal(t, []string{"session=abc", "theme=dark"}, headers.Values("Set-Cookie"))
+	assert.Equal(t, []string{"https://pb33f.io"}, headers.Values("Access-Control-Allow-Origin"))
+	assert.Equal(t, "*", headers.Get("Access-Control-Allow-Headers"))
+}
+
+func TestHandleMockRequest_Pagination(t *testing.T) {
+	ws := newTestService(t, petsSpec, &shared.WiretapConfiguration{MockMode: true, MockPagination: true}, nil)
+
+	page := func(query string) ([]any, http.Header) {
+		w := serveTestRequest(ws, httptest.NewRequest(http.MethodGet, "/pets?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var pets []any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pets))
+		return pets, w.Header()
+	}
+
+	first, headers := page("limit=5")
+	assert.Len(t, first, 5)
+	assert.Equal(t, "100", headers.Get("X-Total-Count"))
+	assert.Contains(t, headers.Get("Link"), `<http://example.com/pets?limit=5>; rel="first"`)
+	assert.Contains(t, headers.Get("Link"), `rel="next"`)
+	assert.Equal(t, "*", headers.Get("Access-Control-Allow-Origin"))
+
+	// the collection is the same every time it's paged through.
+	again, _ := page("limit=5")
+	assert.Equal(t, first, again)
+}
+
+func TestMockHeader(t *testing.T) {
+	upstream := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"name":"upstream"}]`))
+	})
+	ws := newTestService(t, petsSpec, &shared.WiretapConfiguration{}, upstream)
+
+	tests := []struct {
+		header string
+		mocked bool
+	}{
+		{"true", true},
+		{"1", true},
+		{"false", false},
+		{"", false},
+		{"perhaps", false},
+	}
+	for _, tt := range tests {
+		t.Run("header "+tt.header, func(t *testing.T) {
+			before := upstream.calls.Load()
+			r := httptest.NewRequest(http.MethodGet, "/pets", nil)
+			if tt.header != "" {
+				r.Header.Set(MockHeader, tt.header)
+			}
+			w := serveTestRequest(ws, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+			if tt.mocked {
+				assert.JSONEq(t, `[{"name":"dave"}]`, w.Body.String())
+				assert.Equal(t, before, upstream.calls.Load(), "mocked requests must never reach the API")
+			} else {
+				assert.JSONEq(t, `[{"name":"upstream"}]`, w.Body.String())
+				assert.Equal(t, before+1, upstream.calls.Load())
+			}
+		})
+	}
+}
+
+func TestMockHeader_NoSpecification(t *testing.T) {
+	upstream := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`upstream`))
+	})
+	ws := newTestService(t, "", &shared.WiretapConfiguration{}, upstream)
+
+	// there is nothing to mock with, so the request is proxied.
+	r := httptest.NewRequest(http.MethodGet, "/pets", nil)
+	r.Header.Set(MockHeader, "true")
+	w := serveTestRequest(ws, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "upstream", w.Body.String())
+	assert.Equal(t, int32(1), upstream.calls.Load())
+}
@@ -5,11 +5,11 @@ package daemon
 
 import (
 	_ "embed"
-	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 	"time"
 
@@ -74,6 +74,7 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 				ws.config.Logger.Info("[wiretap] static file request", "url", request.HttpRequest.URL.String(), "code", 200)
 
 				// serve it.
+				setResponseSource(request, SourceStatic)
 				http.ServeFile(request.HttpResponseWriter, request.HttpRequest, tmpFile.Name())
 				return
 			}
@@ -82,6 +83,7 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 
 				ws.config.Logger.Info("[wiretap] static file request", "url", request.HttpRequest.URL.String(), "code", 200)
 
+				setResponseSource(request, SourceStatic)
 				http.ServeFile(request.HttpResponseWriter, request.HttpRequest, fp)
 				return
 			}
@@ -93,6 +95,14 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 	configStore, _ := ws.controlsStore.Get(shared.ConfigKey)
 	config := configStore.(*shared.WiretapConfiguration)
 
+	// intercepted requests are held until they are released, and may be changed (or dropped) before they go on.
+	if !ws.intercept(request, config) {
+		return
+	}
+
+	// GraphQL requests are told apart by the operations they run, not just their path.
+	readGraphQLOperations(request, config)
+
 	if config.Headers == nil || len(config.Headers.DropHeaders) == 0 {
 		config.Headers = &shared.WiretapHeaderConfig{
 			DropHeaders: []string{},
@@ -108,8 +118,12 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 		injectHeaders = config.Headers.InjectHeaders
 	}
 
-	// now add path specific headers.
+	// now add path specific headers, paths bundled with a host configuration win over global paths.
 	matchedPaths := configModel.FindPaths(request.HttpRequest.URL.Path, config)
+	hostConfig := configModel.FindHost(requestDestination(request.HttpRequest), config)
+	if hostPaths := configModel.FindHostPaths(request.HttpRequest.URL.Path, hostConfig); len(hostPaths) > 0 {
+		matchedPaths = hostPaths
+	}
 	auth := ""
 	if len(matchedPaths) > 0 {
 		for _, path := range matchedPaths {
@@ -126,55 +140,132 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 		}
 	}
 
+	// traffic for configured hosts (or forward proxy traffic) is sent on to where it was going.
+	protocol, host, port, basePath := config.RedirectProtocol, config.RedirectHost, config.RedirectPort, config.RedirectBasePath
+	if fProtocol, fHost, fPort, ok := forwardTarget(request.HttpRequest, hostConfig, config); ok {
+		protocol, host, port, basePath = fProtocol, fHost, fPort, ""
+	}
+	if rProtocol, rHost, rPort, rBasePath, ok := resendTarget(request.HttpRequest); ok {
+		protocol, host, port, basePath = rProtocol, rHost, rPort, rBasePath
+	}
+
 	newReq := CloneExistingRequest(CloneRequest{
 		Request:       request.HttpRequest,
-		Protocol:      config.RedirectProtocol,
-		Host:          config.RedirectHost,
-		Port:          config.RedirectPort,
+		Protocol:      protocol,
+		Host:          host,
+		Port:          port,
 		DropHeaders:   dropHeaders,
 		InjectHeaders: injectHeaders,
 		Auth:          auth,
-		Variables:     config.CompiledVariables,
+		Variables:     config.GetCompiledVariables(),
 	})
 
 	apiRequest := CloneExistingRequest(CloneRequest{
 		Request:       request.HttpRequest,
-		Protocol:      config.RedirectProtocol,
-		Host:          config.RedirectHost,
-		BasePath:      config.RedirectBasePath,
-		Port:          config.RedirectPort,
+		Protocol:      protocol,
+		Host:          host,
+		BasePath:      basePath,
+		Port:          port,
 		DropHeaders:   dropHeaders,
 		InjectHeaders: injectHeaders,
 		Auth:          auth,
-		Variables:     config.CompiledVariables,
+		Variables:     config.GetCompiledVariables(),
 	})
 
+	// websocket upgrades may be denied or tunneled straight through, depending on the path configuration.
+	if isWebSocketUpgrade(request.HttpRequest) {
+		if ws.handleWebSocketUpgrade(request, config, matchedPaths, apiRequest) {
+			return
+		}
+	}
+
 	var requestErrors []*errors.ValidationError
 	var responseErrors []*errors.ValidationError
 
 	ws.config.Logger.Info("[wiretap] handling API request", "url", request.HttpRequest.URL.String())
 
-	// check if we're going to fail hard on validation errors. (default is to skip this)
-	if ws.config.HardErrors && !ws.config.MockMode {
+	// recorded responses are played back from the HAR file, before anything is mocked or called.
+	playbackResponse := ws.harPlayback.find(request.HttpRequest)
+	mockMode := (ws.mockPath(request.HttpRequest.URL.Path) || ws.mockRequested(request.HttpRequest)) && playbackResponse == nil
+
+	// check if we're going to fail hard on validation errors, or validate inline. (default is to skip this)
+	if (ws.config.HardErrors || ws.config.StrictRequests || ws.inlineValidation()) && !mockMode {
 
 		// validate the request synchronously
 		requestErrors = ws.ValidateRequest(request, newReq)
 
 	} else {
 		// validate the request asynchronously
-		if !ws.config.MockMode {
-			go ws.ValidateRequest(request, newReq)
+		if !mockMode {
+			ws.validationPool.submit(func() { ws.ValidateRequest(request, newReq) },
+				func() { ws.recordRequest(request, newReq, nil, false) })
+		}
+	}
+
+	// in strict mode, invalid requests never make it to the API.
+	if ws.config.StrictRequests && !mockMode {
+		if blocking := ws.blockingViolations(requestErrors); len(blocking) > 0 {
+			ws.rejectInvalidRequest(request, config, blocking)
+			return
 		}
 	}
 
 	// short-circuit if we're using mock mode, there is no API call to make.
-	if ws.config.MockMode {
-		ws.handleMockRequest(request, config, newReq)
+	if mockMode {
+		setResponseSource(request, SourceMock)
+
+		// requests aren't validated before they are mocked when validation is ignored, they are still kept.
+		validate := !strings.EqualFold(config.MockValidation, shared.MockValidationIgnore)
+		if !validate {
+			ws.recordRequest(request, newReq, nil, false)
+		}
+		ws.handleMockRequest(request, config, newReq, validate)
 		return
 	}
 
-	// call the API being requested.
-	returnedResponse, returnedError = ws.callAPI(apiRequest)
+	// call the API being requested, unless there is a recorded or cached response for it.
+	var rawHeaders []*HttpHeader
+	var upstreamLatency time.Duration
+	cacheConfig := locateCacheConfig(request.HttpRequest, matchedPaths)
+	cacheStatus := ""
+	returnedResponse = playbackResponse
+	if returnedResponse != nil {
+		setResponseSource(request, SourcePlayback)
+	}
+	if cacheConfig != nil && returnedResponse == nil {
+		returnedResponse, rawHeaders = ws.responseCache.get(request.HttpRequest, cacheConfig)
+		cacheStatus = "HIT"
+		if returnedResponse != nil {
+			setResponseSource(request, SourceCache)
+		}
+	}
+	if returnedResponse == nil {
+		upstream := ws.startUpstreamSpan(request, apiRequest)
+		called := time.Now()
+		returnedResponse, rawHeaders, returnedError = ws.callAPI(apiRequest)
+		upstreamLatency = time.Since(called)
+		ws.recordUpstream(upstream, returnedResponse, returnedError)
+		if cacheConfig != nil {
+			cacheStatus = "MISS"
+			if returnedError == nil && !isEventStream(returnedResponse) {
+				ws.responseCache.put(request.HttpRequest, cacheConfig, returnedResponse, rawHeaders)
+			}
+		}
+	}
+
+	// in hybrid mode, operations the API is missing (or an API that is down) are served by the mock engine.
+	if playbackResponse == nil && ws.mockFallback(request.HttpRequest, returnedResponse, returnedError) {
+		code := 0
+		if returnedResponse != nil {
+			code = returnedResponse.StatusCode
+			_ = returnedResponse.Body.Close()
+		}
+		config.Logger.Info("[wiretap] API unable to serve request, falling back to mock", "url",
+			request.HttpRequest.URL.String(), "code", code)
+		setResponseSource(request, SourceFallback)
+		ws.handleMockRequest(request, config, newReq, false)
+		return
+	}
 
 	if returnedResponse == nil && returnedError != nil {
 		config.Logger.Info("[wiretap] request failed", "url", apiRequest.URL.String(), "code", 500,
@@ -187,39 +278,72 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 
 	} else {
 
-		// check if we're going to fail hard on validation errors. (default is to skip this)
-		if ws.config.HardErrors {
+		// only responses from the upstream API count towards its latency.
+		if upstreamLatency > 0 {
+			ws.recordLatency(request, upstreamLatency)
+		}
+
+		// event streams never end by themselves, events are sent to the client as they arrive.
+		if isEventStream(returnedResponse) {
+			ws.streamEvents(request, returnedResponse, rawHeaders, playbackResponse != nil)
+			return
+		}
+
+		// check if we're going to fail hard on validation errors, or validate inline. (default is to skip this)
+		if ws.config.HardErrors || ws.config.StrictResponses || ws.inlineValidation() {
 			// validate response
-			responseErrors = ws.ValidateResponse(request, CloneExistingResponse(returnedResponse))
+			responseErrors = ws.validateUpstreamResponse(request, CloneExistingResponse(returnedResponse), rawHeaders)
 		} else {
 			// validate response async
-			go ws.ValidateResponse(request, CloneExistingResponse(returnedResponse))
+			clonedResponse := CloneExistingResponse(returnedResponse)
+			ws.validationPool.submit(func() { ws.validateUpstreamResponse(request, clonedResponse, rawHeaders) },
+				func() { ws.recordResponse(request, clonedResponse, nil, false) })
 		}
 	}
 
-	// check if this path has a delay set.
-	delay := configModel.FindPathDelay(request.HttpRequest.URL.Path, config)
+	// check if this path (or GraphQL operation) has a delay set.
+	delay := findGraphQLDelay(request.HttpRequest, config)
+	if delay <= 0 {
+		delay = configModel.FindPathDelay(request.HttpRequest.URL.Path, config)
+	}
 	if delay > 0 {
 		time.Sleep(time.Duration(delay) * time.Millisecond) // simulate a slow response, configured for path.
-	} else {
-		if config.GlobalAPIDelay > 0 {
-			time.Sleep(time.Duration(config.GlobalAPIDelay) * time.Millisecond) // simulate a slow response.
-		}
+	} else if global := config.GetGlobalAPIDelay(); global > 0 {
+		time.Sleep(time.Duration(global) * time.Millisecond) // simulate a slow response.
 	}
 
 	body, _ := io.ReadAll(returnedResponse.Body)
-	headers := ExtractHeaders(returnedResponse)
+
+	// in strict mode, responses that break the contract never make it to the client.
+	if config.StrictResponses {
+		if blocking := ws.blockingViolations(responseErrors); len(blocking) > 0 {
+			ws.replaceInvalidResponse(request, config, returnedResponse, blocking)
+			return
+		}
+	}
 
 	// wiretap needs to work from anywhere, so allow everything.
-	setCORSHeaders(headers)
+	corsHeaders := make(map[string]any)
+	setCORSHeaders(corsHeaders)
+	if cacheStatus != "" {
+		corsHeaders[CacheHeader] = cacheStatus
+	}
+	if playbackResponse != nil {
+		corsHeaders[PlaybackHeader] = "HIT"
+	}
 
-	// write headers
-	for k, v := range headers {
-		request.HttpResponseWriter.Header().Set(k, fmt.Sprint(v))
+	// write headers, exactly as the upstream sent them.
+	writeResponseHeaders(request.HttpResponseWriter, returnedResponse, rawHeaders, corsHeaders)
+	if playbackResponse != nil {
+		config.Logger.Info("[wiretap] request played back from HAR", "url", request.HttpRequest.URL.String(), "code", returnedResponse.StatusCode)
+	} else if cacheStatus == "HIT" {
+		config.Logger.Info("[wiretap] request served from cache", "url", request.HttpRequest.URL.String(), "code", returnedResponse.StatusCode)
+	} else {
+		config.Logger.Info("[wiretap] request completed", "url", request.HttpRequest.URL.String(), "code", returnedResponse.StatusCode)
 	}
-	config.Logger.Info("[wiretap] request completed", "url", request.HttpRequest.URL.String(), "code", returnedResponse.StatusCode)
 
-	// if there are validation errors, set an error code
+	// if there are validation errors, set an error code, warnings and info don't count.
+	requestErrors, responseErrors = ws.errorViolations(requestErrors), ws.errorViolations(responseErrors)
 	requestCode := config.HardErrorCode
 	returnCode := config.HardErrorReturnCode
 
@@ -234,6 +358,7 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 		request.HttpResponseWriter.WriteHeader(returnedResponse.StatusCode)
 	}
 	_, _ = request.HttpResponseWriter.Write(body)
+	writeResponseTrailers(request.HttpResponseWriter, returnedResponse)
 }
 
 func setCORSHeaders(headers map[string]any) {
@@ -0,0 +1,178 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/require"
+)
+
+// petsSpec is the contract most request handling tests are run against.
+var petsSpec = `openapi: 3.1.0
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-API-Key
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            maximum: 10
+      responses:
+        '200':
+          description: pets
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: object
+                  required: [name]
+                  properties:
+                    name:
+                      type: string
+              example:
+                - name: dave
+        '404':
+          description: no pets
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  message:
+                    type: string
+              example:
+                message: no pets
+        '500':
+          description: broken
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  message:
+                    type: string
+              example:
+                message: broken
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+      responses:
+        '201':
+          description: created
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  name:
+                    type: string
+              example:
+                name: dave
+        '422':
+          description: invalid pet
+          content:
+            application/json:
+              schema:
+                type: object
+  /secure:
+    get:
+      security:
+        - apiKey: []
+      responses:
+        '200':
+          description: secure
+          content:
+            application/json:
+              schema:
+                type: object
+              example:
+                secure: true`
+
+// testUpstream is the API behind wiretap in request handling tests, it counts the requests it's sent.
+type testUpstream struct {
+	*httptest.Server
+	calls atomic.Int32
+}
+
+func newTestUpstream(t *testing.T, handler http.HandlerFunc) *testUpstream {
+	upstream := &testUpstream{}
+	upstream.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream.calls.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+// newTestService creates a wiretap service for a specification, sending traffic to the upstream (if there is one).
+// Validation is inline, so every result is known once a request has been handled. Violations are streamed to a
+// report in a temporary directory, as something has to be listening for them.
+func newTestService(t *testing.T, spec string, config *shared.WiretapConfiguration, upstream *testUpstream) *WiretapService {
+	if config.Logger == nil {
+		config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if config.ReportFile == "" {
+		config.ReportFile = filepath.Join(t.TempDir(), "wiretap-report.json")
+	}
+	if config.ValidationMode == "" {
+		config.ValidationMode = shared.ValidationModeInline
+	}
+	if upstream != nil {
+		target, _ := url.Parse(upstream.URL)
+		config.RedirectProtocol, config.RedirectHost, config.RedirectPort = target.Scheme, target.Hostname(), target.Port()
+	}
+	var doc libopenapi.Document
+	if spec != "" {
+		var err error
+		doc, err = libopenapi.NewDocument([]byte(spec))
+		require.NoError(t, err)
+	}
+	ws := NewWiretapService(doc, config)
+	ws.controlsStore.Put(shared.ConfigKey, config, nil)
+
+	// the stores are shared by every service in the process, transactions of other tests mustn't be seen, and
+	// mustn't be seen by other tests.
+	ws.transactionStore.Reset()
+	t.Cleanup(ws.transactionStore.Reset)
+	ws.broadcastChan = bus.NewChannel(WiretapBroadcastChan)
+	return ws
+}
+
+// serveTestRequest sends a request through wiretap, the way the HTTP handler does.
+func serveTestRequest(ws *WiretapService, r *http.Request) *httptest.ResponseRecorder {
+	id := uuid.New()
+	w := httptest.NewRecorder()
+	ws.handleHttpRequest(&model.Request{Id: &id, HttpRequest: r, HttpResponseWriter: w})
+	return w
+}
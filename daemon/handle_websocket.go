@@ -0,0 +1,189 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// isWebSocketUpgrade checks if a request is asking to be upgraded to a websocket connection.
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// locateWebSocketMode returns the websocket mode of the first matched path configuration, the default is 'allow'
+func locateWebSocketMode(matchedPaths []*shared.WiretapPathConfig) string {
+	if len(matchedPaths) > 0 && matchedPaths[0].WebSocket != "" {
+		return strings.ToLower(matchedPaths[0].WebSocket)
+	}
+	return shared.WebSocketAllow
+}
+
+// handleWebSocketUpgrade will deny or proxy a websocket upgrade request, depending on the mode configured for the path.
+// Allowed upgrades are proxied, unless wiretap is mocking. returns true if the request was handled, false if wiretap
+// should continue to handle it like any other request.
+func (ws *WiretapService) handleWebSocketUpgrade(request *model.Request, config *shared.WiretapConfiguration,
+	matchedPaths []*shared.WiretapPathConfig, apiRequest *http.Request) bool {
+
+	mode := locateWebSocketMode(matchedPaths)
+
+	// channels in the AsyncAPI document are mocked, unless the path is denied, or proxied to a real API.
+	if channel := config.AsyncAPIDocument.FindChannel(request.HttpRequest.URL.Path); channel != nil &&
+		mode != shared.WebSocketDeny && (mode != shared.WebSocketProxy || config.MockMode) {
+		if err := ws.serveAsyncMock(request, config, channel); err != nil {
+			config.Logger.Error("[wiretap] websocket mock failed", "url", request.HttpRequest.URL.String(),
+				"error", err.Error())
+		}
+		return true
+	}
+
+	// there is nothing to proxy to in mock mode, allowed upgrades are left to the mock engine.
+	if mode == shared.WebSocketProxy && config.MockMode {
+		mode = shared.WebSocketDeny
+	}
+	if mode == shared.WebSocketAllow && !config.MockMode {
+		mode = shared.WebSocketProxy
+	}
+
+	switch mode {
+	case shared.WebSocketDeny:
+		config.Logger.Info("[wiretap] websocket upgrade denied", "url", request.HttpRequest.URL.String(), "code", 426)
+		request.HttpResponseWriter.Header().Set("Content-Type", "application/json")
+		request.HttpResponseWriter.WriteHeader(http.StatusUpgradeRequired)
+		wtError := shared.GenerateError("WebSocket upgrade denied", http.StatusUpgradeRequired,
+			fmt.Sprintf("websocket connections are not permitted on path '%s'", request.HttpRequest.URL.Path), "", nil)
+		_, _ = request.HttpResponseWriter.Write(shared.MarshalError(wtError))
+		return true
+
+	case shared.WebSocketProxy:
+		if err := ws.proxyWebSocket(request, config, apiRequest); err != nil {
+			config.Logger.Error("[wiretap] websocket proxy failed", "url", apiRequest.URL.String(), "code", 502,
+				"error", err.Error())
+		}
+		return true
+	}
+	return false
+}
+
+// proxyWebSocket hijacks the client connection and tunnels it through to the target, once the upgrade request
+// has been sent upstream, wiretap just pipes bytes in both directions until either side hangs up.
+func (ws *WiretapService) proxyWebSocket(request *model.Request, config *shared.WiretapConfiguration,
+	apiRequest *http.Request) error {
+
+	rewriteRequestURL(apiRequest, config)
+
+	secure := apiRequest.URL.Scheme == "https" || apiRequest.URL.Scheme == "wss"
+	host := apiRequest.URL.Host
+	if apiRequest.URL.Port() == "" {
+		if secure {
+			host = net.JoinHostPort(apiRequest.URL.Hostname(), "443")
+		} else {
+			host = net.JoinHostPort(apiRequest.URL.Hostname(), "80")
+		}
+	}
+
+	upstream, err := dialUpstream(apiRequest.Context(), host, secure)
+	if err == nil && secure {
+		tlsConfig := &tls.Config{InsecureSkipVerify: true, ServerName: apiRequest.URL.Hostname()}
+		if target := findClientCertTarget(apiRequest.URL); target != nil {
+			tlsConfig.Certificates = target.certificates
+		}
+		tlsConn := tls.Client(upstream, tlsConfig)
+		if err = tlsConn.HandshakeContext(apiRequest.Context()); err != nil {
+			_ = upstream.Close()
+		}
+		upstream = tlsConn
+	}
+	if err != nil {
+		request.HttpResponseWriter.WriteHeader(http.StatusBadGateway)
+		wtError := shared.GenerateError("Unable to proxy websocket", http.StatusBadGateway, err.Error(), "", nil)
+		_, _ = request.HttpResponseWriter.Write(shared.MarshalError(wtError))
+		return err
+	}
+
+	hijacker, ok := request.HttpResponseWriter.(http.Hijacker)
+	if !ok {
+		_ = upstream.Close()
+		request.HttpResponseWriter.WriteHeader(http.StatusInternalServerError)
+		return fmt.Errorf("connection cannot be hijacked")
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		_ = upstream.Close()
+		return err
+	}
+
+	// the upgrade headers are hop-by-hop, make sure they survive the trip.
+	apiRequest.Header.Set("Connection", "Upgrade")
+	apiRequest.Header.Set("Upgrade", "websocket")
+	apiRequest.Host = apiRequest.URL.Host
+	if err = apiRequest.Write(upstream); err != nil {
+		_ = upstream.Close()
+		_ = client.Close()
+		return err
+	}
+
+	config.Logger.Info("[wiretap] websocket proxied", "url", apiRequest.URL.String())
+
+	// the upgrade request is the transaction, the messages relayed over the connection belong to it.
+	transaction := BuildHttpTransaction(HttpTransactionConfig{
+		OriginalRequest:   request.HttpRequest,
+		NewRequest:        apiRequest,
+		ID:                request.Id,
+		TransactionConfig: config,
+	})
+	ws.keepTransaction(transaction)
+	ws.persistTransaction(transaction)
+	ws.broadcastRequest(request, transaction)
+
+	// messages are captured as they are piped through, and validated on channels in the AsyncAPI document.
+	capture := ws.captureFrames(request)
+	channel := config.AsyncAPIDocument.FindChannel(request.HttpRequest.URL.Path)
+	clientMessages, clientTee := io.Pipe()
+	serverMessages, serverTee := io.Pipe()
+	fromClient := io.TeeReader(buffered.Reader, clientTee)
+	fromUpstream := io.TeeReader(upstream, serverTee)
+	var inspecting sync.WaitGroup
+	inspecting.Add(2)
+	go func() {
+		defer inspecting.Done()
+		ws.inspectClientMessages(request, channel, capture, clientMessages)
+	}()
+	go func() {
+		defer inspecting.Done()
+		ws.inspectServerMessages(request, apiRequest, channel, capture, serverMessages)
+	}()
+	defer func() {
+		_ = clientTee.Close()
+		_ = serverTee.Close()
+		inspecting.Wait()
+		capture.finish()
+		config.Logger.Info("[wiretap] websocket closed", "url", apiRequest.URL.String())
+	}()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, fromClient)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, fromUpstream)
+		done <- struct{}{}
+	}()
+	<-done
+	_ = upstream.Close()
+	_ = client.Close()
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsWebSocketUpgrade(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "http://localhost/socket", nil)
+	assert.False(t, isWebSocketUpgrade(r))
+
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	r.Header.Set("Upgrade", "WebSocket")
+	assert.True(t, isWebSocketUpgrade(r))
+}
+
+func TestLocateWebSocketMode(t *testing.T) {
+	assert.Equal(t, shared.WebSocketAllow, locateWebSocketMode(nil))
+	assert.Equal(t, shared.WebSocketAllow, locateWebSocketMode([]*shared.WiretapPathConfig{{}}))
+	assert.Equal(t, shared.WebSocketDeny,
+		locateWebSocketMode([]*shared.WiretapPathConfig{{WebSocket: "Deny"}}))
+}
+
+func TestReadWebSocketMessages(t *testing.T) {
+	mask := []byte{1, 2, 3, 4}
+	masked := func(fin bool, opcode byte, payload string) []byte {
+		b := opcode
+		if fin {
+			b |= 0x80
+		}
+		frame := append([]byte{b, 0x80 | byte(len(payload))}, mask...)
+		for i := range payload {
+			frame = append(frame, payload[i]^mask[i%4])
+		}
+		return frame
+	}
+
+	var stream []byte
+	stream = append(stream, masked(false, wsText, `{"price":`)...)
+	stream = append(stream, 0x89, 0x00) // a ping in between fragments.
+	stream = append(stream, masked(true, wsContinuation, `1.5}`)...)
+	stream = append(stream, 0x81, 0x02, 'h', 'i') // unmasked, like server frames.
+	stream = append(stream, 0xc1, 0x01, 'x')      // compressed, skipped.
+	stream = append(stream, 0x88, 0x00)
+
+	var messages []string
+	err := readWebSocketMessages(bytes.NewReader(stream), func(opcode byte, payload []byte) {
+		messages = append(messages, string(payload))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"price":1.5}`, "hi"}, messages)
+}
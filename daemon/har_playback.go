@@ -0,0 +1,112 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pb33f/harhar"
+)
+
+// PlaybackHeader is set on responses played back from a HAR file.
+const PlaybackHeader = "X-Wiretap-Playback"
+
+// harPlayback serves recorded responses from a HAR file. Requests are matched by method, path and query.
+// If the same request was recorded more than once, the responses are played back in order, and the last
+// one is repeated.
+type harPlayback struct {
+	entries   map[string][]*harhar.Response
+	positions map[string]int
+	lock      sync.Mutex
+}
+
+func newHARPlayback(har *harhar.HAR) *harPlayback {
+	hp := &harPlayback{
+		entries:   make(map[string][]*harhar.Response),
+		positions: make(map[string]int),
+	}
+	if har == nil {
+		return hp
+	}
+	for i := range har.Log.Entries {
+		entry := &har.Log.Entries[i]
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			continue
+		}
+		key := playbackKey(entry.Request.Method, u)
+		hp.entries[key] = append(hp.entries[key], &entry.Response)
+	}
+	return hp
+}
+
+// find returns the next recorded response for a request, or nil if the request was never recorded.
+func (hp *harPlayback) find(r *http.Request) *http.Response {
+	if hp == nil {
+		return nil
+	}
+	key := playbackKey(r.Method, r.URL)
+	hp.lock.Lock()
+	recorded := hp.entries[key]
+	if len(recorded) == 0 {
+		hp.lock.Unlock()
+		return nil
+	}
+	position := hp.positions[key]
+	if position < len(recorded)-1 {
+		hp.positions[key] = position + 1
+	}
+	hp.lock.Unlock()
+	return buildPlaybackResponse(recorded[position])
+}
+
+// playbackKey builds a key from the method, path and (sorted) query of a request.
+func playbackKey(method string, u *url.URL) string {
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return strings.ToUpper(method) + " " + path + "?" + u.Query().Encode()
+}
+
+// buildPlaybackResponse turns a recorded response back into an http.Response. Bodies are stored decoded in a HAR
+// file, so any headers describing the encoding (or length) of the original body are dropped.
+func buildPlaybackResponse(recorded *harhar.Response) *http.Response {
+	body := []byte(recorded.Body.Content)
+	if strings.EqualFold(recorded.Body.Encoding, "base64") {
+		if decoded, err := base64.StdEncoding.DecodeString(recorded.Body.Content); err == nil {
+			body = decoded
+		}
+	}
+	header := http.Header{}
+	for _, h := range recorded.Headers {
+		switch strings.ToLower(h.Name) {
+		case "content-length", "content-encoding", "transfer-encoding":
+			continue
+		}
+		header.Add(h.Name, h.Value)
+	}
+	if header.Get("Content-Type") == "" && recorded.Body.MIMEType != "" {
+		header.Set("Content-Type", recorded.Body.MIMEType)
+	}
+	status := recorded.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		StatusCode:    status,
+		Status:        recorded.StatusText,
+		Proto:         recorded.HTTPVersion,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
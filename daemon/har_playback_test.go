@@ -0,0 +1,59 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/harhar"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHARPlayback_Find(t *testing.T) {
+	har := &harhar.HAR{Log: harhar.Log{Entries: []harhar.Entry{
+		{
+			Request: harhar.Request{Method: "GET", URL: "https://api.pb33f.io/pets?b=2&a=1"},
+			Response: harhar.Response{StatusCode: 200, Headers: []harhar.NameValuePair{
+				{Name: "Content-Type", Value: "application/json"},
+				{Name: "Content-Encoding", Value: "gzip"},
+			}, Body: harhar.BodyResponseType{Content: `{"name":"first"}`}},
+		},
+		{
+			Request: harhar.Request{Method: "GET", URL: "https://api.pb33f.io/pets?a=1&b=2"},
+			Response: harhar.Response{StatusCode: 200, Body: harhar.BodyResponseType{
+				MIMEType: "application/json", Content: "eyJuYW1lIjoic2Vjb25kIn0=", Encoding: "base64"}},
+		},
+	}}}
+	hp := newHARPlayback(har)
+
+	body := func(resp *http.Response) string {
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	// recorded responses are played back in order, query order does not matter.
+	resp := hp.find(httptest.NewRequest(http.MethodGet, "/pets?a=1&b=2", nil))
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, `{"name":"first"}`, body(resp))
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+
+	resp = hp.find(httptest.NewRequest(http.MethodGet, "/pets?b=2&a=1", nil))
+	assert.Equal(t, `{"name":"second"}`, body(resp))
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	// the last response is repeated.
+	resp = hp.find(httptest.NewRequest(http.MethodGet, "/pets?b=2&a=1", nil))
+	assert.Equal(t, `{"name":"second"}`, body(resp))
+
+	// anything not recorded is not played back.
+	assert.Nil(t, hp.find(httptest.NewRequest(http.MethodPost, "/pets?b=2&a=1", nil)))
+	assert.Nil(t, hp.find(httptest.NewRequest(http.MethodGet, "/pets", nil)))
+
+	var disabled *harPlayback
+	assert.Nil(t, disabled.find(httptest.NewRequest(http.MethodGet, "/pets", nil)))
+}
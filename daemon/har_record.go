@@ -0,0 +1,244 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pb33f/harhar"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// harClosing ends a HAR file, it's rewritten after every entry so the file is always a valid HAR.
+const harClosing = "\n]}}\n"
+
+// harRecorder appends every completed transaction to a HAR file as it happens, so nothing is lost if wiretap
+// stops unexpectedly. HAR files are kept valid after every entry, NDJSON files have one entry per line (and can be
+// tailed). Files are rotated when they reach a size, or get to an age, the rotated file is renamed with the time
+// it was rotated. Files can also be rotated hourly or daily, named after the hour (or day) they cover.
+type harRecorder struct {
+	filename string
+	ndjson   bool
+	maxSize  int64
+	maxAge   time.Duration
+	version  string
+	file     *os.File
+	size     int64
+	entries  int
+	opened   time.Time
+	schedule *rotationSchedule
+	lock     sync.Mutex
+}
+
+func newHARRecorder(config *shared.WiretapConfiguration) (*harRecorder, error) {
+	hr := &harRecorder{
+		filename: config.HARRecord,
+		ndjson:   strings.EqualFold(config.HARRecordFormat, shared.HARFormatNDJSON),
+		maxSize:  int64(config.HARRecordMaxSize) * 1024 * 1024,
+		maxAge:   time.Duration(config.HARRecordRotate) * time.Second,
+		version:  config.Version,
+		schedule: newRotationSchedule(config),
+	}
+	if err := hr.open(); err != nil {
+		return nil, err
+	}
+	return hr, nil
+}
+
+func (hr *harRecorder) open() error {
+	f, err := os.OpenFile(hr.filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	hr.file, hr.size, hr.entries, hr.opened = f, 0, 0, time.Now()
+	if hr.ndjson {
+		return nil
+	}
+	creator, _ := json.Marshal(harhar.Creator{Name: "wiretap", Version: hr.version})
+	n, err := fmt.Fprintf(f, `{"log":{"version":"1.2","creator":%s,"entries":[%s`, creator, harClosing)
+	hr.size = int64(n)
+	return err
+}
+
+// record writes an entry, rotating the file first if it's too big or too old.
+func (hr *harRecorder) record(entry *harhar.Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	hr.lock.Lock()
+	defer hr.lock.Unlock()
+	if hr.file == nil {
+		return fmt.Errorf("HAR recording has stopped")
+	}
+	if hr.entries > 0 {
+		if hr.schedule.due(hr.opened, time.Now()) {
+			err = hr.rotate(true)
+		} else if (hr.maxSize > 0 && hr.size+int64(len(data)) > hr.maxSize) ||
+			(hr.maxAge > 0 && time.Since(hr.opened) >= hr.maxAge) {
+			err = hr.rotate(false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if hr.ndjson {
+		n, wErr := hr.file.Write(append(data, '\n'))
+		hr.size += int64(n)
+		hr.entries++
+		return wErr
+	}
+
+	// overwrite the closing of the file with the entry, and close it again.
+	offset := hr.size - int64(len(harClosing))
+	separator := "\n"
+	if hr.entries > 0 {
+		separator = ",\n"
+	}
+	n, wErr := hr.file.WriteAt([]byte(separator+string(data)+harClosing), offset)
+	hr.size = offset + int64(n)
+	hr.entries++
+	return wErr
+}
+
+// rotate renames the file and opens a new one. Scheduled rotations are named after the period the file covers,
+// the others after the time they were rotated.
+func (hr *harRecorder) rotate(scheduled bool) error {
+	_ = hr.file.Close()
+	var err error
+	if scheduled {
+		err = hr.schedule.rotate(hr.filename, hr.opened)
+	} else if err = os.Rename(hr.filename, rotatedFilename(hr.filename,
+		time.Now().Format("20060102-150405.000"))); err == nil {
+		err = pruneRotated(hr.filename, hr.schedule.retain)
+	}
+	if err != nil {
+		hr.file = nil
+		return err
+	}
+	return hr.open()
+}
+
+func (hr *harRecorder) close() {
+	hr.lock.Lock()
+	defer hr.lock.Unlock()
+	if hr.file != nil {
+		_ = hr.file.Close()
+		hr.file = nil
+	}
+}
+
+// recordHAR records a completed transaction, if wiretap is recording.
+func (ws *WiretapService) recordHAR(request *http.Request, requestBody []byte, recorder *responseRecorder,
+	start time.Time) {
+	if ws.harRecorder == nil || ws.config.IsCapturePaused() {
+		return
+	}
+	code := recorder.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	entry := buildHAREntry(request, requestBody, code, recorder.Header(), recorder.body.Bytes(), start, time.Since(start))
+	ws.redactHAREntry(entry)
+	if err := ws.harRecorder.record(entry); err != nil {
+		ws.config.Logger.Warn("[wiretap] unable to record HAR entry", "file", ws.harRecorder.filename, "error", err.Error())
+	}
+}
+
+// StopHARRecording closes the HAR file being recorded.
+func (ws *WiretapService) StopHARRecording() {
+	if ws.harRecorder != nil {
+		ws.harRecorder.close()
+	}
+}
+
+// buildHAREntry builds a HAR entry for a request, and the response sent to the client.
+func buildHAREntry(request *http.Request, requestBody []byte, code int, responseHeaders http.Header,
+	responseBody []byte, start time.Time, duration time.Duration) *harhar.Entry {
+
+	u := *request.URL
+	if u.Host == "" {
+		u.Host = request.Host
+	}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+		if request.TLS != nil {
+			u.Scheme = "https"
+		}
+	}
+
+	harRequest := harhar.Request{
+		Method:      request.Method,
+		URL:         u.String(),
+		HTTPVersion: request.Proto,
+		Cookies:     []harhar.Cookie{},
+		Headers:     harPairs(request.Header),
+		QueryParams: harPairs(u.Query()),
+		HeadersSize: -1,
+		BodySize:    len(requestBody),
+	}
+	if len(requestBody) > 0 {
+		harRequest.Body = harhar.BodyType{MIMEType: mimeType(request.Header), Content: string(requestBody)}
+	}
+
+	content := harhar.BodyResponseType{Size: len(responseBody), MIMEType: mimeType(responseHeaders)}
+	if utf8.Valid(responseBody) {
+		content.Content = string(responseBody)
+	} else {
+		content.Content, content.Encoding = base64.StdEncoding.EncodeToString(responseBody), "base64"
+	}
+
+	ms := float64(duration.Microseconds()) / 1000
+	return &harhar.Entry{
+		Start:   start.Format(time.RFC3339Nano),
+		Time:    ms,
+		Request: harRequest,
+		Response: harhar.Response{
+			StatusCode:  code,
+			StatusText:  http.StatusText(code),
+			HTTPVersion: request.Proto,
+			RedirectURL: responseHeaders.Get("Location"),
+			Cookies:     []harhar.Cookie{},
+			Headers:     harPairs(responseHeaders),
+			Body:        content,
+			HeadersSize: -1,
+			BodySize:    len(responseBody),
+		},
+		Timings: harhar.Timings{Send: 0, Wait: ms, Receive: 0},
+	}
+}
+
+// harPairs lists headers or query parameters, sorted by name.
+func harPairs(values map[string][]string) []harhar.NameValuePair {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]harhar.NameValuePair, 0, len(values))
+	for _, name := range names {
+		for _, value := range values[name] {
+			pairs = append(pairs, harhar.NameValuePair{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+func mimeType(header http.Header) string {
+	if ct := header.Get("Content-Type"); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,67 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pb33f/harhar"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testHAREntry(path string) *harhar.Entry {
+	request := httptest.NewRequest(http.MethodPost, "http://localhost:9090"+path+"?limit=1", nil)
+	request.Header.Set("Content-Type", "application/json")
+	headers := http.Header{"Content-Type": []string{"application/json"}}
+	return buildHAREntry(request, []byte(`{"name":"pb33f"}`), http.StatusCreated, headers, []byte(`{"id":1}`),
+		time.Now(), 5*time.Millisecond)
+}
+
+func TestHARRecorder_ValidAfterEveryEntry(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "traffic.har")
+	hr, err := newHARRecorder(&shared.WiretapConfiguration{HARRecord: filename, Version: "test"})
+	require.NoError(t, err)
+	defer hr.close()
+
+	for i, path := range []string{"/pets", "/pets/1"} {
+		require.NoError(t, hr.record(testHAREntry(path)))
+
+		data, _ := os.ReadFile(filename)
+		var har harhar.HAR
+		require.NoError(t, json.Unmarshal(data, &har))
+		assert.Len(t, har.Log.Entries, i+1)
+		assert.Equal(t, "wiretap", har.Log.Creator.Name)
+	}
+}
+
+func TestHARRecorder_NDJSONRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "traffic.ndjson")
+	hr, err := newHARRecorder(&shared.WiretapConfiguration{HARRecord: filename,
+		HARRecordFormat: shared.HARFormatNDJSON})
+	require.NoError(t, err)
+	defer hr.close()
+	hr.maxSize = 1 // every entry after the first is too big for the file.
+
+	require.NoError(t, hr.record(testHAREntry("/pets")))
+	require.NoError(t, hr.record(testHAREntry("/pets/1")))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "traffic-*.ndjson"))
+	assert.Len(t, files, 1)
+
+	data, _ := os.ReadFile(filename)
+	var entry harhar.Entry
+	require.NoError(t, json.Unmarshal(data, &entry))
+	assert.Equal(t, "http://localhost:9090/pets/1?limit=1", entry.Request.URL)
+	assert.Equal(t, 201, entry.Response.StatusCode)
+}
@@ -0,0 +1,292 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+	"github.com/pb33f/wiretap/audit"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// intercepted requests are listed, and released, by monitors. Monitors are told when a request is held (or released)
+// on the intercept channel.
+const (
+	WiretapInterceptChan      = "wiretap-intercept"
+	GetInterceptedRequest     = "get-intercepted"
+	ReleaseInterceptedRequest = "release-intercepted"
+)
+
+// defaultInterceptTimeout is how long a request is held for, when a timeout hasn't been configured.
+const defaultInterceptTimeout = 60 * time.Second
+
+// InterceptedRequest is a request held until it's approved, or dropped. Headers and body are redacted, like they
+// are when a request is captured. Path is the path and query the request was sent with.
+type InterceptedRequest struct {
+	Id      string            `json:"id"`
+	Rule    string            `json:"rule"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	HeldAt  time.Time         `json:"heldAt"`
+	Expires time.Time         `json:"expires"`
+}
+
+// InterceptDecision sends a held request on (with any changes made to it), or drops it. Path replaces the path (and
+// query) of the request, headers are set (or removed, with an empty value), and Body replaces the body. Anything
+// that isn't changed is sent as it was held, not as it was shown (redacted).
+type InterceptDecision struct {
+	Id      string            `json:"id"`
+	Drop    bool              `json:"drop,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    *string           `json:"body,omitempty"`
+}
+
+// InterceptEvent tells monitors a request has been held, or released (approved or dropped, or it timed out).
+type InterceptEvent struct {
+	Held     *InterceptedRequest `json:"held,omitempty"`
+	Released string              `json:"released,omitempty"`
+	Dropped  bool                `json:"dropped,omitempty"`
+	TimedOut bool                `json:"timedOut,omitempty"`
+}
+
+// interceptor keeps the requests being held, with the channel each is waiting for its decision on.
+type interceptor struct {
+	lock sync.Mutex
+	held map[string]*heldRequest
+}
+
+type heldRequest struct {
+	request  *InterceptedRequest
+	decision chan *InterceptDecision
+}
+
+// intercept holds a request that matches an intercept rule, until it's released or the timeout passes. False is
+// returned if the request was dropped (the client has been responded to), otherwise any changes have been made to
+// the request, and it carries on as if it had been sent that way.
+func (ws *WiretapService) intercept(request *model.Request, config *shared.WiretapConfiguration) bool {
+	r := request.HttpRequest
+	rule, found := configModel.FindIntercept(r.Method, r.URL.Path, config)
+	if !found {
+		return true
+	}
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+		_ = r.Body.Close()
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	timeout := defaultInterceptTimeout
+	if _, seconds := config.GetIntercept(); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	id := request.Id
+	if id == nil {
+		newId, _ := uuid.NewUUID()
+		id = &newId
+	}
+	redactor := ws.redactor()
+	held := &heldRequest{
+		request: &InterceptedRequest{
+			Id:      id.String(),
+			Rule:    rule,
+			Method:  r.Method,
+			Path:    redactor.Text(r.URL.RequestURI()),
+			Headers: make(map[string]string, len(r.Header)),
+			Body:    string(redactor.Body(body)),
+			HeldAt:  time.Now(),
+			Expires: time.Now().Add(timeout),
+		},
+		decision: make(chan *InterceptDecision, 1),
+	}
+	for name := range r.Header {
+		held.request.Headers[name] = redactor.Header(name, r.Header.Get(name))
+	}
+
+	ws.intercepted.lock.Lock()
+	if ws.intercepted.held == nil {
+		ws.intercepted.held = make(map[string]*heldRequest)
+	}
+	ws.intercepted.held[held.request.Id] = held
+	ws.intercepted.lock.Unlock()
+	ws.config.Logger.Info("[wiretap] request intercepted, held until it's released", "url", r.URL.String(),
+		"rule", rule, "id", held.request.Id)
+	ws.sendInterceptEvent(&InterceptEvent{Held: held.request})
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var decision *InterceptDecision
+	select {
+	case decision = <-held.decision:
+	case <-timer.C:
+	case <-r.Context().Done():
+		decision = &InterceptDecision{Drop: true} // the client has gone, there is no one to respond to.
+	}
+
+	ws.intercepted.lock.Lock()
+	_, stillHeld := ws.intercepted.held[held.request.Id]
+	delete(ws.intercepted.held, held.request.Id)
+	ws.intercepted.lock.Unlock()
+	if decision == nil && !stillHeld {
+		// released as the timeout passed, the decision is on its way.
+		decision = <-held.decision
+	}
+	ws.sendInterceptEvent(&InterceptEvent{Released: held.request.Id, Dropped: decision != nil && decision.Drop,
+		TimedOut: decision == nil})
+
+	if decision == nil {
+		ws.config.Logger.Info("[wiretap] intercepted request timed out, sending it on", "id", held.request.Id)
+		return true
+	}
+	if decision.Drop {
+		ws.dropInterceptedRequest(request, held.request.Id)
+		return false
+	}
+	if err := editRequest(r, decision); err != nil {
+		// changes are checked when the request is released, so this shouldn't happen.
+		ws.config.Logger.Error("[wiretap] unable to change intercepted request", "id", held.request.Id,
+			"error", err.Error())
+	}
+	return true
+}
+
+// Intercepted lists the requests being held, the longest held first.
+func (ws *WiretapService) Intercepted() []*InterceptedRequest {
+	ws.intercepted.lock.Lock()
+	held := make([]*InterceptedRequest, 0, len(ws.intercepted.held))
+	for _, h := range ws.intercepted.held {
+		held = append(held, h.request)
+	}
+	ws.intercepted.lock.Unlock()
+	sort.Slice(held, func(i, j int) bool { return held[i].HeldAt.Before(held[j].HeldAt) })
+	return held
+}
+
+// ReleaseIntercepted sends a held request on, with any changes made to it, or drops it. Client is who released it,
+// for the audit log.
+func (ws *WiretapService) ReleaseIntercepted(decision *InterceptDecision, client string) error {
+	if !decision.Drop {
+		// check the changes before the request is released, so they can be fixed.
+		check, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if err := editRequest(check, decision); err != nil {
+			return err
+		}
+	}
+	ws.intercepted.lock.Lock()
+	held, ok := ws.intercepted.held[decision.Id]
+	if ok {
+		delete(ws.intercepted.held, decision.Id)
+	}
+	ws.intercepted.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("no request is being held with id '%s'", decision.Id)
+	}
+
+	action := audit.ActionRequestReleased
+	if decision.Drop {
+		action = audit.ActionRequestDropped
+	}
+	_ = ws.config.Auditor.Record(client, action, map[string]any{"id": decision.Id, "rule": held.request.Rule,
+		"method": held.request.Method, "path": held.request.Path,
+		"changed": decision.Method != "" || decision.Path != "" || len(decision.Headers) > 0 || decision.Body != nil})
+	held.decision <- decision
+	return nil
+}
+
+// editRequest makes the changes to a held request.
+func editRequest(r *http.Request, decision *InterceptDecision) error {
+	if decision.Method != "" {
+		r.Method = strings.ToUpper(decision.Method)
+	}
+	if decision.Path != "" {
+		u, err := url.ParseRequestURI(decision.Path)
+		if err != nil || u.IsAbs() {
+			return fmt.Errorf("path has to be a path (with any query), not '%s'", decision.Path)
+		}
+		r.URL.Path, r.URL.RawPath, r.URL.RawQuery = u.Path, u.RawPath, u.RawQuery
+		r.RequestURI = r.URL.RequestURI()
+	}
+	for k, v := range decision.Headers {
+		if v == "" {
+			r.Header.Del(k)
+		} else {
+			r.Header.Set(k, v)
+		}
+	}
+	if decision.Body != nil {
+		r.Body = io.NopCloser(bytes.NewBufferString(*decision.Body))
+		r.ContentLength = int64(len(*decision.Body))
+		r.Header.Del("Content-Length")
+	}
+	return nil
+}
+
+// dropInterceptedRequest responds to the client of a dropped request, it never reaches the API.
+func (ws *WiretapService) dropInterceptedRequest(request *model.Request, id string) {
+	ws.config.Logger.Info("[wiretap] intercepted request dropped", "url", request.HttpRequest.URL.String(), "id", id)
+	if request.HttpRequest.Context().Err() != nil {
+		return
+	}
+	wtError := shared.GenerateError("Request dropped", http.StatusBadGateway,
+		"The request was intercepted and dropped by wiretap, it was not sent to the API.", "", nil)
+	headers := make(map[string]any)
+	setCORSHeaders(headers)
+	for k, v := range headers {
+		request.HttpResponseWriter.Header().Set(k, fmt.Sprint(v))
+	}
+	request.HttpResponseWriter.Header().Set("Content-Type", "application/problem+json")
+	request.HttpResponseWriter.WriteHeader(http.StatusBadGateway)
+	_, _ = request.HttpResponseWriter.Write(shared.MarshalError(wtError))
+}
+
+// sendInterceptEvent tells monitors a request has been held, or released.
+func (ws *WiretapService) sendInterceptEvent(event *InterceptEvent) {
+	if ws.interceptChan == nil {
+		return
+	}
+	id, _ := uuid.NewUUID()
+	ws.interceptChan.Send(&model.Message{
+		Id:          &id,
+		Channel:     WiretapInterceptChan,
+		Destination: WiretapInterceptChan,
+		Payload:     event,
+		Direction:   model.ResponseDir,
+	})
+}
+
+// getIntercepted sends a monitor the requests being held.
+func (ws *WiretapService) getIntercepted(request *model.Request, core service.FabricServiceCore) {
+	core.SendResponse(request, ws.Intercepted())
+}
+
+// releaseIntercepted sends a held request on (or drops it) for a monitor.
+func (ws *WiretapService) releaseIntercepted(request *model.Request, core service.FabricServiceCore) {
+	var decision InterceptDecision
+	if dl, ok := request.Payload.(map[string]interface{}); ok {
+		_ = mapstructure.Decode(dl, &decision)
+	}
+	if err := ws.ReleaseIntercepted(&decision, audit.Client(request)); err != nil {
+		core.SendErrorResponse(request, 400, err.Error())
+		return
+	}
+	core.SendResponse(request, ws.Intercepted())
+}
@@ -0,0 +1,80 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntercept(t *testing.T) {
+	config := &shared.WiretapConfiguration{Intercept: []string{"POST /pets/*"}, InterceptTimeout: 1,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	config.CompileIntercepts()
+	ws := NewWiretapService(nil, config)
+
+	hold := func(method, path string) (chan bool, *httptest.ResponseRecorder, *http.Request) {
+		r := httptest.NewRequest(method, path, strings.NewReader(`{"name":"dave"}`))
+		r.Header.Set("X-Test", "one")
+		w := httptest.NewRecorder()
+		released := make(chan bool, 1)
+		go func() {
+			released <- ws.intercept(&model.Request{HttpRequest: r, HttpResponseWriter: w}, config)
+		}()
+		return released, w, r
+	}
+	waitForHeld := func() *InterceptedRequest {
+		assert.Eventually(t, func() bool { return len(ws.Intercepted()) == 1 }, time.Second, 5*time.Millisecond)
+		return ws.Intercepted()[0]
+	}
+
+	// not matching a rule, nothing is held.
+	released, _, _ := hold("GET", "/pets/1")
+	assert.True(t, <-released)
+
+	// approved, with changes.
+	released, _, r := hold("POST", "/pets/1")
+	held := waitForHeld()
+	assert.Equal(t, "POST /pets/*", held.Rule)
+	assert.Equal(t, `{"name":"dave"}`, held.Body)
+	assert.Equal(t, "one", held.Headers["X-Test"])
+	changed := `{"name":"quobix"}`
+	assert.NoError(t, ws.ReleaseIntercepted(&InterceptDecision{Id: held.Id, Path: "/pets/2?limit=1",
+		Headers: map[string]string{"X-Test": ""}, Body: &changed}, "test"))
+	assert.True(t, <-released)
+	assert.Equal(t, "/pets/2?limit=1", r.URL.RequestURI())
+	assert.Empty(t, r.Header.Get("X-Test"))
+	body, _ := io.ReadAll(r.Body)
+	assert.Equal(t, changed, string(body))
+	assert.Empty(t, ws.Intercepted())
+	assert.Error(t, ws.ReleaseIntercepted(&InterceptDecision{Id: held.Id}, "test"))
+
+	// changes that can't be made are rejected, and the request is still held.
+	released, w, _ := hold("POST", "/pets/1")
+	held = waitForHeld()
+	assert.Error(t, ws.ReleaseIntercepted(&InterceptDecision{Id: held.Id, Path: "https://example.com/pets"}, "test"))
+	assert.Len(t, ws.Intercepted(), 1)
+
+	// dropped, the client is told and it never carries on.
+	assert.NoError(t, ws.ReleaseIntercepted(&InterceptDecision{Id: held.Id, Drop: true}, "test"))
+	assert.False(t, <-released)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+
+	// not released before the timeout, it carries on unchanged.
+	released, _, r = hold("POST", "/pets/1")
+	waitForHeld()
+	assert.True(t, <-released)
+	assert.Equal(t, "/pets/1", r.URL.RequestURI())
+	assert.Empty(t, ws.Intercepted())
+}
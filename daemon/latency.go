@@ -0,0 +1,71 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pb33f/ranch/model"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/latency"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// upstreamLatency tracks how long the upstream API takes to respond. The latency of a request is held until its
+// response is validated, so it's kept with the transaction.
+type upstreamLatency struct {
+	tracker *latency.Tracker
+	pending sync.Map
+}
+
+// Latency reports how long the upstream API took to respond, grouped by the path configuration requests matched.
+func (ws *WiretapService) Latency() *latency.Report {
+	if ws.latency == nil {
+		return latency.NewTracker().Report()
+	}
+	return ws.latency.tracker.Report()
+}
+
+// latencyPath groups a request by the path configuration it matches (for the host it's for), or by the path in
+// the specification it maps to, everything else is grouped together.
+func (ws *WiretapService) latencyPath(r *http.Request) string {
+	if host := configModel.FindHost(requestDestination(r), ws.config); host != nil {
+		if key := configModel.FindPathKey(r.URL.Path, host.GetCompiledPaths()); key != "" {
+			return key
+		}
+	}
+	if key := configModel.FindPathKey(r.URL.Path, ws.config.GetCompiledPaths()); key != "" {
+		return key
+	}
+	if path, _ := validation.LocateOperation(r, ws.currentDocModel()); path != "" {
+		return path
+	}
+	if operations := graphqlOperations(r); len(operations) > 0 {
+		return graphqlOperationPath(r, r.URL.Path)
+	}
+	return "*"
+}
+
+// recordLatency records how long the upstream API took to respond to a request.
+func (ws *WiretapService) recordLatency(request *model.Request, duration time.Duration) {
+	if ws.latency == nil {
+		return
+	}
+	ws.latency.tracker.Record(ws.latencyPath(request.HttpRequest), duration)
+	ws.latency.pending.Store(request.Id.String(), duration)
+}
+
+// takeLatency returns the upstream latency recorded for a request (in milliseconds), zero if there isn't one.
+func (ws *WiretapService) takeLatency(request *model.Request) float64 {
+	if ws.latency == nil {
+		return 0
+	}
+	if d, ok := ws.latency.pending.LoadAndDelete(request.Id.String()); ok {
+		return float64(d.(time.Duration)) / float64(time.Millisecond)
+	}
+	return 0
+}
@@ -0,0 +1,179 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/metrics"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/tracing"
+)
+
+// Where the response to a request came from, the source label of request metrics.
+const (
+	SourceProxy     = "proxy"
+	SourceMock      = "mock"
+	SourceFallback  = "fallback"
+	SourceCache     = "cache"
+	SourcePlayback  = "playback"
+	SourceStatic    = "static"
+	SourceWebSocket = "websocket"
+)
+
+// serviceMetrics are the metrics wiretap keeps about the traffic it handles.
+type serviceMetrics struct {
+	registry         *metrics.Registry
+	requests         *metrics.Counter
+	duration         *metrics.Histogram
+	violations       *metrics.Counter
+	upstreamRequests *metrics.Counter
+	upstreamErrors   *metrics.Counter
+}
+
+func newServiceMetrics() *serviceMetrics {
+	registry := metrics.NewRegistry()
+	m := &serviceMetrics{
+		registry: registry,
+		requests: registry.Counter("wiretap_requests_total",
+			"Requests handled by wiretap, by method, response code and where the response came from.",
+			"method", "code", "source"),
+		duration: registry.Histogram("wiretap_request_duration_seconds",
+			"Time taken to respond to requests, by where the response came from.", metrics.DefaultBuckets, "source"),
+		violations: registry.Counter("wiretap_violations_total",
+			"Violations of the contract found in requests and responses, by severity.", "severity"),
+		upstreamRequests: registry.Counter("wiretap_upstream_requests_total",
+			"Requests proxied to the upstream API."),
+		upstreamErrors: registry.Counter("wiretap_upstream_errors_total",
+			"Requests proxied to the upstream API that failed, because it couldn't be reached or it responded with a 5xx.",
+			"reason"),
+	}
+	for _, severity := range []string{shared.SeverityError, shared.SeverityWarn, shared.SeverityInfo} {
+		m.violations.Add(0, severity)
+	}
+	m.upstreamRequests.Add(0)
+	return m
+}
+
+// Metrics returns the metrics wiretap keeps, to be served to Prometheus.
+func (ws *WiretapService) Metrics() http.Handler {
+	return ws.metrics.registry
+}
+
+// PushMetrics sends every change to a metric to a sink, as well as keeping it to be scraped.
+func (ws *WiretapService) PushMetrics(sink metrics.Sink) {
+	ws.metrics.registry.AddSink(sink)
+}
+
+// recordUpstream counts a call to the upstream API, and whether it failed, and ends its span.
+func (ws *WiretapService) recordUpstream(span *tracing.Span, response *http.Response, err error) {
+	if response != nil {
+		span.SetAttribute("http.response.status_code", response.StatusCode)
+	}
+	span.SetError(err)
+	span.End()
+
+	ws.metrics.upstreamRequests.Inc()
+	switch {
+	case err != nil:
+		ws.metrics.upstreamErrors.Inc("unreachable")
+	case response != nil && response.StatusCode >= 500:
+		ws.metrics.upstreamErrors.Inc("5xx")
+	}
+}
+
+// setResponseSource records where the response to a request came from, requests are proxied unless set.
+func setResponseSource(request *model.Request, source string) {
+	if recorder, ok := request.HttpResponseWriter.(*responseRecorder); ok {
+		recorder.source = source
+	}
+}
+
+// measure handles a request, and records its response code, source and how long it took. When transactions are
+// recorded to a HAR file, the request body is read up front and the response is copied as it's written.
+func (ws *WiretapService) measure(request *model.Request, handle func(request *model.Request)) {
+	recorder := &responseRecorder{ResponseWriter: request.HttpResponseWriter, source: SourceProxy}
+	var requestBody []byte
+	if ws.harRecorder != nil {
+		recorder.body = &bytes.Buffer{}
+		if request.HttpRequest.Body != nil {
+			requestBody, _ = io.ReadAll(request.HttpRequest.Body)
+			_ = request.HttpRequest.Body.Close()
+			request.HttpRequest.Body = io.NopCloser(bytes.NewReader(requestBody))
+		}
+	}
+	request.HttpResponseWriter = recorder
+	start := time.Now()
+	handle(request)
+	request.HttpResponseWriter = recorder.ResponseWriter
+	if !recorder.hijacked {
+		ws.recordHAR(request.HttpRequest, requestBody, recorder, start)
+	}
+
+	code := recorder.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	if recorder.hijacked {
+		code, recorder.source = http.StatusSwitchingProtocols, SourceWebSocket
+	}
+	ws.metrics.requests.Inc(request.HttpRequest.Method, strconv.Itoa(code), recorder.source)
+	ws.metrics.duration.Observe(time.Since(start).Seconds(), recorder.source)
+
+	span := tracing.SpanFromContext(request.HttpRequest.Context())
+	span.SetAttribute("http.response.status_code", code)
+	span.SetAttribute("wiretap.source", recorder.source)
+}
+
+// responseRecorder captures the response code written to a client, and the body if it's being recorded.
+type responseRecorder struct {
+	http.ResponseWriter
+	code     int
+	source   string
+	hijacked bool
+	body     *bytes.Buffer
+}
+
+func (mr *responseRecorder) WriteHeader(code int) {
+	if mr.code == 0 {
+		mr.code = code
+	}
+	mr.ResponseWriter.WriteHeader(code)
+}
+
+func (mr *responseRecorder) Write(b []byte) (int, error) {
+	if mr.code == 0 {
+		mr.code = http.StatusOK
+	}
+	if mr.body != nil {
+		mr.body.Write(b)
+	}
+	return mr.ResponseWriter.Write(b)
+}
+
+// Hijack hands over the connection, websockets are tunneled.
+func (mr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := mr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("connection cannot be hijacked")
+	}
+	mr.hijacked = true
+	return hijacker.Hijack()
+}
+
+// Flush sends anything buffered to the client.
+func (mr *responseRecorder) Flush() {
+	if flusher, ok := mr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
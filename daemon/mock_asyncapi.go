@@ -0,0 +1,75 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/asyncapi"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// asyncUpgrader upgrades connections to mocked AsyncAPI channels, wiretap needs to work from anywhere,
+// so every origin is allowed.
+var asyncUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// serveAsyncMock upgrades a request to a websocket and emits mocked messages for an AsyncAPI channel, on the
+// configured interval, until the client hangs up. Messages sent by the client are read and discarded.
+func (ws *WiretapService) serveAsyncMock(request *model.Request, config *shared.WiretapConfiguration,
+	channel *asyncapi.Channel) error {
+
+	conn, err := asyncUpgrader.Upgrade(request.HttpResponseWriter, request.HttpRequest, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	config.Logger.Info("[wiretap] websocket mocked from AsyncAPI channel", "url",
+		request.HttpRequest.URL.String(), "channel", channel.Name)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if len(channel.ServerMessages) == 0 {
+		<-closed
+		return nil
+	}
+
+	interval := config.AsyncAPIInterval
+	if interval <= 0 {
+		interval = shared.DefaultAsyncAPIInterval
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Millisecond)
+	defer ticker.Stop()
+
+	// each message of the channel is sent in turn, starting straight away.
+	for i := 0; ; i++ {
+		message := channel.ServerMessages[i%len(channel.ServerMessages)]
+		payload, err := ws.currentMockEngine().GenerateMessage(message)
+		if err != nil {
+			config.Logger.Warn("[wiretap] unable to mock AsyncAPI message", "channel", channel.Name,
+				"message", message.Name, "error", err.Error())
+		} else if err = conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+			return nil
+		}
+		select {
+		case <-closed:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pb33f/wiretap/mock"
+)
+
+var callbackClient = &http.Client{Timeout: 30 * time.Second}
+
+// mockCallbacksEnabled checks if callbacks or webhooks should be fired for mocked responses.
+func (ws *WiretapService) mockCallbacksEnabled() bool {
+	return ws.config.MockCallbacks || len(ws.config.MockWebhooks) > 0
+}
+
+// fireMockCallbacks fires the callbacks (and configured webhooks) of a mocked operation, once the configured
+// delay has passed. Callbacks are fired one at a time, failures are logged and otherwise ignored.
+func (ws *WiretapService) fireMockCallbacks(engine *mock.ResponseMockEngine, request *http.Request,
+	requestBody, responseBody []byte, status int) {
+
+	var callbacks []*http.Request
+	if ws.config.MockCallbacks {
+		callbacks = engine.BuildCallbacks(request, requestBody, responseBody, status)
+	}
+	callbacks = append(callbacks,
+		engine.BuildWebhooks(request, requestBody, responseBody, status, ws.config.MockWebhooks)...)
+	if len(callbacks) == 0 {
+		return
+	}
+	if ws.config.MockCallbackDelay > 0 {
+		time.Sleep(time.Duration(ws.config.MockCallbackDelay) * time.Millisecond)
+	}
+	for _, callback := range callbacks {
+		resp, err := callbackClient.Do(callback)
+		if err != nil {
+			ws.config.Logger.Warn("[wiretap] mock callback failed", "method", callback.Method,
+				"url", callback.URL.String(), "error", err.Error())
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		ws.config.Logger.Info("[wiretap] mock callback fired", "method", callback.Method,
+			"url", callback.URL.String(), "code", resp.StatusCode)
+	}
+}
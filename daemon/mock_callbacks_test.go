@@ -0,0 +1,134 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var callbackSpec = `openapi: 3.1.0
+paths:
+  /subscriptions:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [callbackUrl]
+              properties:
+                callbackUrl:
+                  type: string
+                tag:
+                  type: integer
+      responses:
+        '201':
+          content:
+            application/json:
+              example:
+                id: sub-1
+        '409':
+          content:
+            application/json:
+              example:
+                message: already subscribed
+        '422':
+          content:
+            application/json:
+              schema:
+                type: object
+      callbacks:
+        onEvent:
+          '{$request.body#/callbackUrl}/events/{$response.body#/id}':
+            post:
+              requestBody:
+                content:
+                  application/json:
+                    schema:
+                      type: object
+                    example:
+                      event: created
+              responses:
+                '200':
+                  description: received`
+
+// callbackTarget is where callbacks are sent, it passes on every request it receives.
+func callbackTarget(t *testing.T) (*httptest.Server, chan *http.Request) {
+	received := make(chan *http.Request, 10)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		received <- r
+	}))
+	t.Cleanup(target.Close)
+	return target, received
+}
+
+// subscribe asks for callbacks to be sent to a target, with any other properties of the subscription.
+func subscribe(target, properties string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/subscriptions",
+		strings.NewReader(`{"callbackUrl":"`+target+`"`+properties+`}`))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func TestFireMockCallbacks(t *testing.T) {
+	target, received := callbackTarget(t)
+	ws := newTestService(t, callbackSpec, &shared.WiretapConfiguration{MockMode: true, MockCallbacks: true}, nil)
+
+	w := serveTestRequest(ws, subscribe(target.URL, ""))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// the URL is resolved from the request and the mocked response, the body is mocked from the callback.
+	select {
+	case callback := <-received:
+		assert.Equal(t, http.MethodPost, callback.Method)
+		assert.Equal(t, "/events/sub-1", callback.URL.Path)
+		assert.Equal(t, "application/json", callback.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(callback.Body)
+		assert.JSONEq(t, `{"event":"created"}`, string(body))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the callback was never fired")
+	}
+}
+
+func TestFireMockCallbacks_OnlySuccess(t *testing.T) {
+	target, received := callbackTarget(t)
+
+	tests := []struct {
+		name    string
+		config  *shared.WiretapConfiguration
+		request *http.Request
+		codes   []int
+	}{
+		{"disabled", &shared.WiretapConfiguration{MockMode: true}, subscribe(target.URL, ""), []int{http.StatusCreated}},
+		{"error injected", &shared.WiretapConfiguration{MockMode: true, MockCallbacks: true, MockErrorRate: 1},
+			subscribe(target.URL, ""), []int{http.StatusConflict, http.StatusUnprocessableEntity}},
+		{"invalid request", &shared.WiretapConfiguration{MockMode: true, MockCallbacks: true}, subscribe(target.URL, `,"tag":"news"`),
+			[]int{http.StatusUnprocessableEntity}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := newTestService(t, callbackSpec, tt.config, nil)
+			w := serveTestRequest(ws, tt.request)
+			require.Contains(t, tt.codes, w.Code)
+			select {
+			case callback := <-received:
+				t.Fatalf("a callback was fired to %s", callback.URL.Path)
+			case <-time.After(200 * time.Millisecond):
+			}
+		})
+	}
+}
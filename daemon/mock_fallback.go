@@ -0,0 +1,30 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/pb33f/libopenapi-validator/paths"
+)
+
+// mockFallback checks if a proxied request should be mocked instead, when running in hybrid mode. Requests fall
+// back to a mock when the API could not be reached, or it does not implement the operation yet (404 / 501) and the
+// specification does.
+func (ws *WiretapService) mockFallback(request *http.Request, response *http.Response, err error) bool {
+	if !ws.config.MockFallback {
+		return false
+	}
+	docModel := ws.currentDocModel()
+	if docModel == nil {
+		return false
+	}
+	if err == nil && (response == nil ||
+		(response.StatusCode != http.StatusNotFound && response.StatusCode != http.StatusNotImplemented)) {
+		return false
+	}
+	pathItem, _, _ := paths.FindPath(request, docModel)
+	return pathItem != nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		upstream int
+		down     bool
+		code     int
+		mocked   bool
+	}{
+		{"not found", "/pets", http.StatusNotFound, false, http.StatusOK, true},
+		{"not implemented", "/pets", http.StatusNotImplemented, false, http.StatusOK, true},
+		{"unreachable", "/pets", 0, true, http.StatusOK, true},
+		{"server error", "/pets", http.StatusInternalServerError, false, http.StatusInternalServerError, false},
+		{"ok", "/pets", http.StatusOK, false, http.StatusOK, false},
+		{"path missing from the specification", "/unknown", http.StatusNotFound, false, http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.upstream)
+				_, _ = w.Write([]byte(`[{"name":"upstream"}]`))
+			})
+			ws := newTestService(t, petsSpec, &shared.WiretapConfiguration{MockFallback: true}, upstream)
+			if tt.down {
+				upstream.Close()
+			}
+
+			w := serveTestRequest(ws, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.code, w.Code)
+			if tt.mocked {
+				assert.JSONEq(t, `[{"name":"dave"}]`, w.Body.String())
+			} else {
+				assert.JSONEq(t, `[{"name":"upstream"}]`, w.Body.String())
+			}
+			if !tt.down {
+				assert.Equal(t, int32(1), upstream.calls.Load(), "the API is always asked first")
+			}
+		})
+	}
+}
+
+func TestMockFallback_Disabled(t *testing.T) {
+	upstream := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	ws := newTestService(t, petsSpec, &shared.WiretapConfiguration{}, upstream)
+	w := serveTestRequest(ws, httptest.NewRequest(http.MethodGet, "/pets", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Body.String())
+}
@@ -0,0 +1,69 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io/fs"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pb33f/wiretap/audit"
+	"github.com/pb33f/wiretap/mock"
+)
+
+// loadMockOverrides loads the mock overrides directory, and watches it for changes. Overrides are reloaded
+// every time a file is written, created or removed, so payloads can be edited while wiretap is running.
+func (ws *WiretapService) loadMockOverrides() {
+	overrides, err := mock.LoadOverrides(ws.config.MockOverrides)
+	if err != nil {
+		ws.config.Logger.Warn("[wiretap] unable to load mock overrides", "dir", ws.config.MockOverrides,
+			"error", err.Error())
+	}
+	ws.mockOverrides = overrides
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		ws.config.Logger.Warn("[wiretap] unable to watch mock overrides", "error", err.Error())
+		return
+	}
+	_ = filepath.WalkDir(ws.config.MockOverrides, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			_ = watcher.Add(path)
+		}
+		return nil
+	})
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+					continue
+				}
+				if event.Has(fsnotify.Create) {
+					_ = watcher.Add(event.Name) // new directories are watched too, files are ignored.
+				}
+				if rErr := overrides.Reload(); rErr != nil {
+					_ = ws.config.Auditor.Record(audit.ClientWatcher, audit.ActionMockOverridesReloaded,
+						map[string]any{"file": event.Name, "error": rErr.Error()})
+					ws.config.Logger.Warn("[wiretap] unable to reload mock overrides", "error", rErr.Error())
+					continue
+				}
+				_ = ws.config.Auditor.Record(audit.ClientWatcher, audit.ActionMockOverridesReloaded,
+					map[string]any{"file": event.Name, "overrides": overrides.Len()})
+				ws.config.Logger.Info("[wiretap] mock overrides reloaded", "file", event.Name, "overrides", overrides.Len())
+			case wErr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				ws.config.Logger.Warn("[wiretap] error watching mock overrides", "error", wErr.Error())
+			}
+		}
+	}()
+}
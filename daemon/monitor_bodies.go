@@ -0,0 +1,52 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/base64"
+	"unicode/utf8"
+)
+
+// BodyEncodingBase64 is the encoding of a summarized body that wasn't text.
+const BodyEncodingBase64 = "base64"
+
+// summarizeBody cuts a body down to the limit (in bytes), for the monitor. Text is cut at a character, anything
+// else is base64 encoded, so the monitor can still show how it starts. Bodies within the limit are left alone.
+func summarizeBody(body string, limit int) (summary, encoding string, truncated bool) {
+	if limit <= 0 || len(body) <= limit {
+		return body, "", false
+	}
+	if !utf8.ValidString(body) {
+		return base64.StdEncoding.EncodeToString([]byte(body[:limit*3/4])), BodyEncodingBase64, true
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return body[:cut], "", true
+}
+
+// summarizeBodies cuts the bodies of a transaction sent to the monitor down to the configured limit, the full
+// bodies are still kept (and stored).
+func (ws *WiretapService) summarizeBodies(transaction *HttpTransaction) *HttpTransaction {
+	limit := ws.config.MonitorMaxBodyKB * 1024
+	if limit <= 0 || transaction == nil {
+		return transaction
+	}
+	summarized := *transaction
+	if req := transaction.Request; req != nil && len(req.Body) > limit {
+		sr := *req
+		sr.BodySize = len(req.Body)
+		sr.Body, sr.BodyEncoding, sr.BodyTruncated = summarizeBody(req.Body, limit)
+		summarized.Request = &sr
+	}
+	if resp := transaction.Response; resp != nil && len(resp.Body) > limit {
+		sr := *resp
+		sr.BodySize = len(resp.Body)
+		sr.Body, sr.BodyEncoding, sr.BodyTruncated = summarizeBody(resp.Body, limit)
+		summarized.Response = &sr
+	}
+	return &summarized
+}
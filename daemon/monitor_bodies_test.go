@@ -0,0 +1,47 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeBody(t *testing.T) {
+	summary, encoding, truncated := summarizeBody("short", 10)
+	assert.Equal(t, "short", summary)
+	assert.Empty(t, encoding)
+	assert.False(t, truncated)
+
+	// text is cut at a character, not in the middle of one.
+	summary, encoding, truncated = summarizeBody("héllo wörld", 2)
+	assert.Equal(t, "h", summary)
+	assert.Empty(t, encoding)
+	assert.True(t, truncated)
+
+	summary, encoding, truncated = summarizeBody(string([]byte{0xff, 0xfe, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05}), 4)
+	assert.Equal(t, "//4A", summary)
+	assert.Equal(t, BodyEncodingBase64, encoding)
+	assert.True(t, truncated)
+}
+
+func TestWiretapService_SummarizeBodies(t *testing.T) {
+	ws := NewWiretapService(nil, &shared.WiretapConfiguration{MonitorMaxBodyKB: 1})
+	transaction := &HttpTransaction{Request: &HttpRequest{Body: "{}"},
+		Response: &HttpResponse{Body: strings.Repeat("a", 4096)}}
+
+	summarized := ws.summarizeBodies(transaction)
+	assert.Equal(t, "{}", summarized.Request.Body)
+	assert.False(t, summarized.Request.BodyTruncated)
+	assert.Len(t, summarized.Response.Body, 1024)
+	assert.Equal(t, 4096, summarized.Response.BodySize)
+	assert.True(t, summarized.Response.BodyTruncated)
+
+	// the captured transaction keeps the whole body.
+	assert.Len(t, transaction.Response.Body, 4096)
+}
@@ -0,0 +1,36 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/notify"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// notifyViolations adds violations to the next notification sent to any configured webhooks.
+func (ws *WiretapService) notifyViolations(request *http.Request, violations []*errors.ValidationError) {
+	if ws.notifier == nil || len(violations) == 0 {
+		return
+	}
+	path, _ := validation.LocateOperation(request, ws.currentDocModel())
+	if path == "" {
+		path = ws.redactor().Text(request.URL.Path)
+	}
+	items := make([]*notify.Item, 0, len(violations))
+	for _, v := range violations {
+		items = append(items, &notify.Item{
+			Method:   request.Method,
+			Path:     path,
+			Rule:     configModel.ViolationRule(v),
+			Severity: configModel.ViolationSeverity(v, ws.config.Severity),
+			Message:  v.Message,
+		})
+	}
+	ws.notifier.Notify(items)
+}
@@ -0,0 +1,107 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// OperationResult is every request seen for an operation, and the distinct violations found in them. Violations
+// seen more than once have the number of times they were seen.
+type OperationResult struct {
+	Method     string
+	Path       string
+	Requests   int
+	Violations []*shared.Violation
+}
+
+// operationResults keeps the results of every operation exercised, for reports built per operation.
+type operationResults struct {
+	results    map[string]*OperationResult
+	violations map[string]*shared.Violation
+	lock       sync.Mutex
+}
+
+func newOperationResults() *operationResults {
+	return &operationResults{
+		results:    make(map[string]*OperationResult),
+		violations: make(map[string]*shared.Violation),
+	}
+}
+
+// recordOperationResult records the violations found for a request (or its response), requests are only counted
+// when they are validated, so they aren't counted twice.
+func (ws *WiretapService) recordOperationResult(request *http.Request, violations []*errors.ValidationError,
+	countRequest bool) {
+
+	if ws.operationResults == nil {
+		return
+	}
+	path, _ := validation.LocateOperation(request, ws.currentDocModel())
+	if path == "" {
+		path = request.URL.Path
+	}
+	path = graphqlOperationPath(request, path)
+	operation := request.Method + " " + path
+
+	or := ws.operationResults
+	or.lock.Lock()
+	defer or.lock.Unlock()
+	result, ok := or.results[operation]
+	if !ok {
+		result = &OperationResult{Method: request.Method, Path: path}
+		or.results[operation] = result
+	}
+	if countRequest {
+		result.Requests++
+	}
+	for _, v := range violations {
+		key := violationKey(operation, v)
+		if seen, found := or.violations[key]; found {
+			seen.Count++
+			continue
+		}
+		violation := &shared.Violation{
+			ValidationError: v,
+			Severity:        configModel.ViolationSeverity(v, ws.config.Severity),
+			Count:           1,
+		}
+		or.violations[key] = violation
+		result.Violations = append(result.Violations, violation)
+	}
+}
+
+// OperationResults returns the results of every operation exercised, sorted by path and method.
+func (ws *WiretapService) OperationResults() []*OperationResult {
+	if ws.operationResults == nil {
+		return nil
+	}
+	ws.operationResults.lock.Lock()
+	defer ws.operationResults.lock.Unlock()
+	results := make([]*OperationResult, 0, len(ws.operationResults.results))
+	for _, result := range ws.operationResults.results {
+		r := *result
+		r.Violations = make([]*shared.Violation, len(result.Violations))
+		for i, v := range result.Violations {
+			copied := *v
+			r.Violations[i] = &copied
+		}
+		results = append(results, &r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Path != results[j].Path {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].Method < results[j].Method
+	})
+	return results
+}
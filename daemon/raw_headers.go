@@ -0,0 +1,205 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// maxRecordedHeaderBytes stops a misbehaving upstream from making wiretap buffer forever.
+const maxRecordedHeaderBytes = 1 << 20
+
+// upstreamTransport is used for all calls to the target API. Connections are wrapped so the exact
+// header lines sent back by the upstream (casing and duplicates included) can be captured, because
+// net/http canonicalizes everything before we get a chance to look at it. HTTP/2 is used with targets
+// that offer it over TLS, those connections aren't wrapped (HTTP/2 headers are always lower case).
+var upstreamTransport = buildUpstreamTransport(nil)
+
+// buildUpstreamTransport builds a transport for calls to target APIs, presenting the client certificates (if any)
+// to targets that ask for one.
+func buildUpstreamTransport(certificates []tls.Certificate) *http.Transport {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+
+	// Disable ssl cert checks
+	tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true, Certificates: certificates}
+
+	// HTTP/2 is negotiated over TLS, the transport needs the TLS connection (not a wrapped one) to use it.
+	tr.ForceAttemptHTTP2 = true
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &headerRecordingConn{Conn: conn}, nil
+	}
+	tr.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		host, _, _ := net.SplitHostPort(addr)
+		tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: host,
+			Certificates: certificates, NextProtos: []string{http2.NextProtoTLS, "http/1.1"}})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		if tlsConn.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+			return tlsConn, nil
+		}
+		return &headerRecordingConn{Conn: tlsConn}, nil
+	}
+	return tr
+}
+
+// headerRecordingConn records the header block of every response read from the connection. Recording starts
+// every time a request is written, and stops once the end of the header block has been seen. Once a proxy has
+// opened a tunnel (with CONNECT) the connection carries TLS, so there is nothing left to record.
+type headerRecordingConn struct {
+	net.Conn
+	lock       sync.Mutex
+	recording  bool
+	connecting bool
+	tunnelled  bool
+	buffer     []byte
+	headers    []*HttpHeader
+}
+
+func (c *headerRecordingConn) Write(b []byte) (int, error) {
+	c.lock.Lock()
+	if !c.tunnelled {
+		c.recording = true
+		c.connecting = bytes.HasPrefix(b, []byte(http.MethodConnect+" "))
+		c.buffer = c.buffer[:0]
+		c.headers = nil
+	}
+	c.lock.Unlock()
+	return c.Conn.Write(b)
+}
+
+func (c *headerRecordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.lock.Lock()
+		if c.recording {
+			c.buffer = append(c.buffer, b[:n]...)
+			c.scanBuffer()
+		}
+		c.lock.Unlock()
+	}
+	return n, err
+}
+
+func (c *headerRecordingConn) scanBuffer() {
+	for c.recording {
+		idx := bytes.Index(c.buffer, []byte("\r\n\r\n"))
+		if idx < 0 {
+			if len(c.buffer) > maxRecordedHeaderBytes {
+				c.recording = false
+				c.buffer = nil
+			}
+			return
+		}
+		block := c.buffer[:idx]
+		c.buffer = c.buffer[idx+4:]
+
+		// informational responses (100 continue etc.) are followed by the real response.
+		if bytes.HasPrefix(block, []byte("HTTP/1.1 1")) || bytes.HasPrefix(block, []byte("HTTP/1.0 1")) {
+			continue
+		}
+		c.recording = false
+		c.buffer = nil
+
+		// the proxy's answer to CONNECT isn't the API's, a tunnel is only ever TLS from here on.
+		if c.connecting {
+			c.connecting = false
+			c.tunnelled = bytes.HasPrefix(block, []byte("HTTP/1.1 2")) || bytes.HasPrefix(block, []byte("HTTP/1.0 2"))
+			return
+		}
+		c.headers = parseRawHeaders(block)
+	}
+}
+
+// RecordedHeaders returns the header lines of the last response read from the connection.
+func (c *headerRecordingConn) RecordedHeaders() []*HttpHeader {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.headers
+}
+
+// parseRawHeaders parses a raw HTTP/1.x response header block, retaining casing, order and duplicates.
+func parseRawHeaders(block []byte) []*HttpHeader {
+	if !bytes.HasPrefix(block, []byte("HTTP/")) {
+		return nil // not something we can read (encrypted, or not HTTP at all)
+	}
+	lines := strings.Split(string(block), "\r\n")
+	var headers []*HttpHeader
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		// obsolete line folding, append to the previous header.
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		headers = append(headers, &HttpHeader{Name: name, Value: strings.TrimSpace(value)})
+	}
+	return headers
+}
+
+// writeResponseHeaders copies response headers to the client. If the raw headers sent by the upstream are known,
+// they are written exactly as received, so clients sensitive to casing and duplicates see what the upstream sent.
+// Raw headers no longer present in the response (hop-by-hop headers, or content-encoding for decompressed
+// bodies) are skipped. Any overrides are always set last and replace upstream values.
+func writeResponseHeaders(w http.ResponseWriter, response *http.Response, rawHeaders []*HttpHeader,
+	overrides map[string]any) {
+	if len(rawHeaders) == 0 {
+		headers := ExtractHeaders(response)
+		for k, v := range overrides {
+			headers[k] = v
+		}
+		for k, v := range headers {
+			w.Header().Set(k, fmt.Sprint(v))
+		}
+		return
+	}
+	for _, h := range rawHeaders {
+		if _, ok := overrides[http.CanonicalHeaderKey(h.Name)]; ok {
+			continue
+		}
+		if _, ok := response.Header[http.CanonicalHeaderKey(h.Name)]; !ok {
+			continue
+		}
+		// assigning to the map directly retains the casing, Add/Set would canonicalize the key.
+		w.Header()[h.Name] = append(w.Header()[h.Name], h.Value)
+	}
+	// a nil canonical entry stops net/http from adding its own version of a non-canonical header.
+	for _, h := range rawHeaders {
+		canonical := http.CanonicalHeaderKey(h.Name)
+		if _, ok := w.Header()[canonical]; !ok && canonical != h.Name {
+			w.Header()[canonical] = nil
+		}
+	}
+	for k, v := range overrides {
+		w.Header().Set(k, fmt.Sprint(v))
+	}
+}
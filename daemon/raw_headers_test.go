@@ -0,0 +1,191 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRawHeaders(t *testing.T) {
+	block := "HTTP/1.1 200 OK\r\ncontent-type: application/json\r\nX-CUSTOM: one\r\nX-Custom: two\r\n  folded"
+	headers := parseRawHeaders([]byte(block))
+	assert.Len(t, headers, 3)
+	assert.Equal(t, "content-type", headers[0].Name)
+	assert.Equal(t, "X-CUSTOM", headers[1].Name)
+	assert.Equal(t, "two folded", headers[2].Value)
+
+	assert.Nil(t, parseRawHeaders([]byte("\x16\x03\x01garbage")))
+}
+
+func TestWriteResponseHeaders_PreserveCasing(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Add("Content-Type", "application/json")
+	resp.Header.Add("X-Custom", "one")
+	resp.Header.Add("X-Custom", "two")
+
+	raw := []*HttpHeader{
+		{Name: "content-type", Value: "application/json"},
+		{Name: "X-CUSTOM", Value: "one"},
+		{Name: "x-custom", Value: "two"},
+		{Name: "Transfer-Encoding", Value: "chunked"},
+	}
+	w := httptest.NewRecorder()
+	writeResponseHeaders(w, resp, raw, map[string]any{"Access-Control-Allow-Origin": "*"})
+
+	assert.Equal(t, []string{"application/json"}, w.Header()["content-type"])
+	assert.Equal(t, []string{"one"}, w.Header()["X-CUSTOM"])
+	assert.Equal(t, []string{"two"}, w.Header()["x-custom"])
+	assert.Nil(t, w.Header()["Transfer-Encoding"])
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCheckDuplicateHeaders(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Add("Content-Type", "application/json")
+	assert.Len(t, checkDuplicateHeaders(resp, nil), 0)
+
+	// without raw headers (played back, or HTTP/2), the parsed headers are all there is.
+	resp.Header.Add("content-type", "text/plain")
+	violations := checkDuplicateHeaders(resp, nil)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "Duplicate 'Content-Type' header found in response", violations[0].Message)
+
+	// raw headers are counted whatever their casing.
+	raw := []*HttpHeader{{Name: "content-length", Value: "2"}, {Name: "Content-LENGTH", Value: "2"},
+		{Name: "Content-Type", Value: "application/json"}}
+	violations = checkDuplicateHeaders(&http.Response{Header: http.Header{}}, raw)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "Duplicate 'Content-Length' header found in response", violations[0].Message)
+	assert.Contains(t, violations[0].Reason, "2 'Content-Length' headers (2, 2)")
+}
+
+func TestCheckDuplicateHeaders_FromUpstream(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, e := listener.Accept()
+		if e != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = http.ReadRequest(bufio.NewReader(conn))
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\ncontent-type: text/html\r\n" +
+			"Content-Length: 2\r\ncontent-length: 2\r\n\r\nok"))
+	}()
+
+	tr := newWiretapTransport()
+	req, _ := http.NewRequest(http.MethodGet, "http://"+listener.Addr().String()+"/", nil)
+	resp, err := tr.RoundTrip(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	// net/http has already merged the identical Content-Length headers.
+	assert.Len(t, resp.Header.Values("Content-Length"), 1)
+	assert.Len(t, checkDuplicateHeaders(resp, nil), 1)
+
+	violations := checkDuplicateHeaders(resp, tr.capturedRawHeaders)
+	assert.Len(t, violations, 2)
+	assert.Equal(t, "Duplicate 'Content-Type' header found in response", violations[0].Message)
+	assert.Equal(t, "Duplicate 'Content-Length' header found in response", violations[1].Message)
+}
+
+func TestWiretapTransport_CapturesRawHeaders(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, e := listener.Accept()
+		if e != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = http.ReadRequest(bufio.NewReader(conn))
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\ncontent-type: text/plain\r\nX-Snake_Case: yes\r\n" +
+			"Content-Length: 2\r\n\r\nok"))
+	}()
+
+	tr := newWiretapTransport()
+	req, _ := http.NewRequest(http.MethodGet, "http://"+listener.Addr().String()+"/", nil)
+	resp, err := tr.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Len(t, tr.capturedRawHeaders, 3)
+	assert.Equal(t, "content-type", tr.capturedRawHeaders[0].Name)
+	assert.Equal(t, "X-Snake_Case", tr.capturedRawHeaders[1].Name)
+}
+
+func TestHeaderRecordingConn_Tunnel(t *testing.T) {
+	// the target is only reachable through an HTTP proxy, over TLS.
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Snake_Case", "yes")
+		_, _ = w.Write([]byte("pets"))
+	}))
+	defer target.Close()
+	forward := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+		go func() { _, _ = io.Copy(upstream, conn) }()
+		_, _ = io.Copy(conn, upstream)
+	}))
+	defer forward.Close()
+
+	// keep hold of the connection to the proxy, to see what it has recorded.
+	var recorder *headerRecordingConn
+	tr := buildUpstreamTransport(nil)
+	dial := tr.DialContext
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		recorder, _ = conn.(*headerRecordingConn)
+		return conn, err
+	}
+	proxyURL, _ := url.Parse(forward.URL)
+	tr.Proxy = http.ProxyURL(proxyURL)
+	defer tr.CloseIdleConnections()
+
+	for i := 0; i < 2; i++ {
+		resp, err := (&wiretapTransport{originalTransport: tr}).RoundTrip(
+			httptest.NewRequest(http.MethodGet, target.URL+"/pets", nil))
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, "pets", string(body))
+	}
+
+	// once the tunnel is open, TLS is all that's written and read, none of it is buffered looking for headers.
+	require.NotNil(t, recorder)
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	assert.True(t, recorder.tunnelled)
+	assert.False(t, recorder.recording)
+	assert.Empty(t, recorder.buffer)
+	assert.Empty(t, recorder.headers)
+}
@@ -0,0 +1,189 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/pb33f/harhar"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/grpc"
+	"github.com/pb33f/wiretap/redact"
+)
+
+// redactor masks secrets and personal data in captured traffic, nil if nothing is redacted.
+func (ws *WiretapService) redactor() *redact.Redactor {
+	if ws.config == nil {
+		return nil
+	}
+	return ws.config.Redactor
+}
+
+// redactTransaction returns a copy of a transaction with secrets and personal data masked, before it's shown in the
+// monitor, stored or reported. The transaction itself isn't changed.
+func (ws *WiretapService) redactTransaction(transaction *HttpTransaction) *HttpTransaction {
+	r := ws.redactor()
+	if r == nil || transaction == nil {
+		return transaction
+	}
+	redacted := *transaction
+	if req := transaction.Request; req != nil {
+		rr := *req
+		rr.URL = r.Text(req.URL)
+		rr.Path = r.Text(req.Path)
+		rr.OriginalPath = r.Text(req.OriginalPath)
+		rr.Query = r.Text(req.Query)
+		rr.Headers = redactHeaderMap(r, req.Headers)
+		rr.Body = string(r.Body([]byte(req.Body)))
+		rr.Cookies = redactCookies(r, req.Cookies, "Cookie")
+		if req.InjectedHeaders != nil {
+			rr.InjectedHeaders = make(map[string]string, len(req.InjectedHeaders))
+			for name, value := range req.InjectedHeaders {
+				rr.InjectedHeaders[name] = r.Header(name, value)
+			}
+		}
+		rr.GRPC = redactGRPC(r, req.GRPC)
+		redacted.Request = &rr
+	}
+	if resp := transaction.Response; resp != nil {
+		rr := *resp
+		rr.Headers = redactHeaderMap(r, resp.Headers)
+		rr.Body = string(r.Body([]byte(resp.Body)))
+		rr.Cookies = redactCookies(r, resp.Cookies, "Set-Cookie")
+		rr.GRPC = redactGRPC(r, resp.GRPC)
+		rr.Events = redactEvents(r, resp.Events)
+		rr.Frames = redactFrames(r, resp.Frames)
+		redacted.Response = &rr
+	}
+	return &redacted
+}
+
+// redactGRPC returns a copy of decoded gRPC messages, masked like any other JSON body.
+func redactGRPC(r *redact.Redactor, payload *grpc.Payload) *grpc.Payload {
+	if payload == nil {
+		return nil
+	}
+	redacted := *payload
+	redacted.Messages = make([]json.RawMessage, len(payload.Messages))
+	for i, message := range payload.Messages {
+		redacted.Messages[i] = r.Body(message)
+	}
+	return &redacted
+}
+
+// redactEvents returns copies of server-sent events, the data of each is masked like any other body.
+func redactEvents(r *redact.Redactor, events []*ServerSentEvent) []*ServerSentEvent {
+	if events == nil {
+		return nil
+	}
+	redacted := make([]*ServerSentEvent, len(events))
+	for i, event := range events {
+		e := *event
+		e.Data = string(r.Body([]byte(event.Data)))
+		redacted[i] = &e
+	}
+	return redacted
+}
+
+// redactFrames returns copies of websocket messages, text messages are masked like any other body.
+func redactFrames(r *redact.Redactor, frames []*WebSocketFrame) []*WebSocketFrame {
+	if frames == nil {
+		return nil
+	}
+	redacted := make([]*WebSocketFrame, len(frames))
+	for i, frame := range frames {
+		f := *frame
+		if f.Encoding == "" {
+			f.Data = string(r.Body([]byte(frame.Data)))
+		}
+		redacted[i] = &f
+	}
+	return redacted
+}
+
+// redactViolations returns copies of violations with secrets and personal data masked, violations can quote the
+// values (and objects) that broke the contract.
+func (ws *WiretapService) redactViolations(violations []*errors.ValidationError) []*errors.ValidationError {
+	r := ws.redactor()
+	if r == nil || len(violations) == 0 {
+		return violations
+	}
+	redacted := make([]*errors.ValidationError, len(violations))
+	for i, v := range violations {
+		rv := *v
+		rv.Message = r.Text(v.Message)
+		rv.Reason = r.Text(v.Reason)
+		if len(v.SchemaValidationErrors) > 0 {
+			rv.SchemaValidationErrors = make([]*errors.SchemaValidationFailure, len(v.SchemaValidationErrors))
+			for j, f := range v.SchemaValidationErrors {
+				rf := *f
+				rf.Reason = r.Text(f.Reason)
+				rf.ReferenceObject = string(r.Body([]byte(f.ReferenceObject)))
+				rv.SchemaValidationErrors[j] = &rf
+			}
+		}
+		redacted[i] = &rv
+	}
+	return redacted
+}
+
+// redactHAREntry masks secrets and personal data in a HAR entry before it's recorded.
+func (ws *WiretapService) redactHAREntry(entry *harhar.Entry) {
+	r := ws.redactor()
+	if r == nil {
+		return
+	}
+	entry.Request.URL = r.Text(entry.Request.URL)
+	if u, err := url.Parse(entry.Request.URL); err == nil {
+		entry.Request.QueryParams = harPairs(u.Query())
+	}
+	redactPairs(r, entry.Request.Headers)
+	entry.Request.Body.Content = string(r.Body([]byte(entry.Request.Body.Content)))
+	redactPairs(r, entry.Response.Headers)
+	entry.Response.RedirectURL = r.Text(entry.Response.RedirectURL)
+	if entry.Response.Body.Encoding != "base64" {
+		entry.Response.Body.Content = string(r.Body([]byte(entry.Response.Body.Content)))
+	}
+}
+
+func redactHeaderMap(r *redact.Redactor, headers map[string]any) map[string]any {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string]any, len(headers))
+	for name, value := range headers {
+		if s, ok := value.(string); ok {
+			value = r.Header(name, s)
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// redactCookies masks every cookie if the header they came in is redacted, otherwise any patterns they match.
+func redactCookies(r *redact.Redactor, cookies map[string]*HttpCookie, header string) map[string]*HttpCookie {
+	if cookies == nil {
+		return nil
+	}
+	redacted := make(map[string]*HttpCookie, len(cookies))
+	for name, c := range cookies {
+		rc := *c
+		if r.HeaderRedacted(header) {
+			rc.Value = redact.Mask
+		} else {
+			rc.Value = r.Text(c.Value)
+		}
+		redacted[name] = &rc
+	}
+	return redacted
+}
+
+// redactPairs masks header values in place.
+func redactPairs(r *redact.Redactor, pairs []harhar.NameValuePair) {
+	for i := range pairs {
+		pairs[i].Value = r.Header(pairs[i].Name, pairs[i].Value)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// FilterTransactions returns the transactions a report includes. Transactions to paths that aren't reported are
+// left out, violations of severities that aren't reported are removed (from copies), and when only violations are
+// reported (or only some severities are) transactions left without violations are left out too.
+func FilterTransactions(transactions []*HttpTransaction, filter *shared.WiretapReportFilter) []*HttpTransaction {
+	if filter.Empty() {
+		return transactions
+	}
+	violationsOnly := filter.ViolationsOnly || len(filter.Severities) > 0
+	filtered := make([]*HttpTransaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		if req := transaction.Request; req != nil && !filter.MatchPath(req.Path) && !filter.MatchPath(req.OriginalPath) {
+			continue
+		}
+		kept := *transaction
+		kept.RequestValidation = filterViolations(transaction.RequestValidation, filter)
+		kept.ResponseValidation = filterViolations(transaction.ResponseValidation, filter)
+		if violationsOnly && len(kept.RequestValidation) == 0 && len(kept.ResponseValidation) == 0 {
+			continue
+		}
+		filtered = append(filtered, &kept)
+	}
+	return filtered
+}
+
+func filterViolations(violations []*shared.Violation, filter *shared.WiretapReportFilter) []*shared.Violation {
+	if filter == nil || len(filter.Severities) == 0 {
+		return violations
+	}
+	var kept []*shared.Violation
+	for _, v := range violations {
+		if filter.MatchSeverity(v.Severity) {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// streamReported streams the violations found for a request to the report, if requests to its path and violations
+// of their severity are reported.
+func (ws *WiretapService) streamReported(request *http.Request, violations []*errors.ValidationError) {
+	filter := ws.config.ReportFilter
+	if !filter.MatchPath(request.URL.Path) {
+		return
+	}
+	if filter != nil && len(filter.Severities) > 0 {
+		var kept []*errors.ValidationError
+		for _, v := range violations {
+			if filter.MatchSeverity(configModel.ViolationSeverity(v, ws.config.Severity)) {
+				kept = append(kept, v)
+			}
+		}
+		violations = kept
+	}
+	if len(violations) > 0 {
+		ws.streamChan <- violations
+	}
+}
+
+// ReportTransactions returns the captured transactions a report includes, narrowed by a filter, or by the report
+// filter configured if there isn't one.
+func (ws *WiretapService) ReportTransactions(filter *shared.WiretapReportFilter) ([]*HttpTransaction, error) {
+	if filter == nil {
+		filter = ws.config.ReportFilter
+	}
+	transactions, err := ws.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	return FilterTransactions(transactions, filter), nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterTransactions(t *testing.T) {
+	violation := func(severity string) *shared.Violation {
+		return &shared.Violation{ValidationError: &errors.ValidationError{Message: severity}, Severity: severity}
+	}
+	transactions := []*HttpTransaction{
+		{Id: "healthy", Request: &HttpRequest{Path: "/pets"}},
+		{Id: "error", Request: &HttpRequest{Path: "/pets/1"}, ResponseValidation: []*shared.Violation{violation(shared.SeverityError)}},
+		{Id: "mixed", Request: &HttpRequest{Path: "/toys"},
+			RequestValidation: []*shared.Violation{violation(shared.SeverityWarn), violation(shared.SeverityError)}},
+		{Id: "info", Request: &HttpRequest{Path: "/pets/2"}, RequestValidation: []*shared.Violation{violation(shared.SeverityInfo)}},
+	}
+	ids := func(filtered []*HttpTransaction) []string {
+		var names []string
+		for _, transaction := range filtered {
+			names = append(names, transaction.Id)
+		}
+		return names
+	}
+	filter := func(rf *shared.WiretapReportFilter) []*HttpTransaction {
+		assert.NoError(t, rf.Compile())
+		return FilterTransactions(transactions, rf)
+	}
+
+	assert.Equal(t, transactions, FilterTransactions(transactions, nil))
+	assert.Equal(t, []string{"error", "mixed", "info"}, ids(filter(&shared.WiretapReportFilter{ViolationsOnly: true})))
+	assert.Equal(t, []string{"healthy", "error", "info"}, ids(filter(&shared.WiretapReportFilter{Paths: []string{"/pets*"}})))
+
+	errs := filter(&shared.WiretapReportFilter{Severities: []string{"error"}})
+	assert.Equal(t, []string{"error", "mixed"}, ids(errs))
+	assert.Len(t, errs[1].RequestValidation, 1)
+	assert.Len(t, transactions[2].RequestValidation, 2, "the transaction itself isn't changed")
+
+	assert.Equal(t, []string{"info"}, ids(filter(&shared.WiretapReportFilter{Severities: []string{"info", "warn"},
+		Paths: []string{"/pets/*"}})))
+
+	assert.Error(t, (&shared.WiretapReportFilter{Paths: []string{"/pets/[a"}}).Compile())
+}
@@ -0,0 +1,54 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/issues"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// reportIssues sends violations off to any configured issue trackers, the transaction is used as a sample.
+func (ws *WiretapService) reportIssues(request *http.Request, violations []*errors.ValidationError,
+	transaction *HttpTransaction) {
+
+	if ws.issueService == nil || len(violations) == 0 {
+		return
+	}
+	transaction = ws.redactTransaction(transaction)
+
+	path := request.URL.Path
+	operationId := ""
+	template, op := validation.LocateOperation(request, ws.currentDocModel())
+	if template != "" {
+		path = template
+	}
+	if op != nil {
+		operationId = op.OperationId
+	}
+
+	var sample string
+	if transaction != nil {
+		if b, err := json.MarshalIndent(transaction, "", "  "); err == nil {
+			sample = string(b)
+		}
+	}
+
+	for _, v := range violations {
+		if v.IsPathMissingError() {
+			continue // nothing to file against.
+		}
+		ws.issueService.Report(&issues.Violation{
+			Method:            request.Method,
+			Path:              path,
+			OperationId:       operationId,
+			Error:             v,
+			SampleTransaction: sample,
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// CacheHeader is sent back to the client, so it's clear when a response was served from the cache.
+const CacheHeader = "X-Wiretap-Cache"
+
+type cachedResponse struct {
+	statusCode int
+	header     http.Header
+	rawHeaders []*HttpHeader
+	body       []byte
+	expires    time.Time
+}
+
+// cacheSweepInterval is how often expired responses are swept from the cache, those that are never asked for
+// again would otherwise be held on to forever.
+const cacheSweepInterval = time.Minute
+
+// responseCache holds upstream responses in memory, for paths that have caching enabled.
+type responseCache struct {
+	entries map[string]*cachedResponse
+	swept   time.Time
+	lock    sync.RWMutex
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]*cachedResponse)}
+}
+
+// locateCacheConfig returns the cache configuration of the first matched path, if the request can be cached.
+func locateCacheConfig(request *http.Request, matchedPaths []*shared.WiretapPathConfig) *shared.WiretapCacheConfig {
+	if request.Method != http.MethodGet || len(matchedPaths) == 0 {
+		return nil
+	}
+	return matchedPaths[0].Cache
+}
+
+// buildCacheKey creates a key for the request, from where it's going and the configured cache keys. Requests for
+// different hosts never share an entry, even when they share a path configuration.
+func buildCacheKey(request *http.Request, cacheConfig *shared.WiretapCacheConfig) string {
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+	if request.URL.IsAbs() {
+		scheme = request.URL.Scheme
+	}
+	var key strings.Builder
+	key.WriteString(request.Method + "|" + scheme + "://" + requestDestination(request))
+	for _, k := range cacheConfig.Keys {
+		switch {
+		case k == shared.CacheKeyPath:
+			key.WriteString("|" + request.URL.Path)
+		case k == shared.CacheKeyQuery:
+			// re-encoding sorts the query, so parameter order does not matter.
+			key.WriteString("|" + request.URL.Query().Encode())
+		case strings.HasPrefix(k, shared.CacheKeyHeaderPrefix):
+			name := strings.TrimPrefix(k, shared.CacheKeyHeaderPrefix)
+			key.WriteString(fmt.Sprintf("|%s=%s", strings.ToLower(name), request.Header.Get(name)))
+		}
+	}
+	return key.String()
+}
+
+// get returns a copy of a cached response, if one exists and has not expired.
+func (rc *responseCache) get(request *http.Request, cacheConfig *shared.WiretapCacheConfig) (*http.Response, []*HttpHeader) {
+	if cacheConfig.HonorCacheControl && hasCacheDirective(request.Header, "no-cache", "no-store") {
+		return nil, nil
+	}
+	key := buildCacheKey(request, cacheConfig)
+	rc.lock.RLock()
+	entry := rc.entries[key]
+	rc.lock.RUnlock()
+	if entry == nil {
+		return nil, nil
+	}
+	if time.Now().After(entry.expires) {
+		rc.lock.Lock()
+		delete(rc.entries, key)
+		rc.lock.Unlock()
+		return nil, nil
+	}
+	return &http.Response{
+		StatusCode:    entry.statusCode,
+		Status:        fmt.Sprintf("%d %s", entry.statusCode, http.StatusText(entry.statusCode)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(entry.body)),
+		ContentLength: int64(len(entry.body)),
+		Request:       request,
+	}, entry.rawHeaders
+}
+
+// put stores a successful response in the cache, the body of the response is read and replaced.
+func (rc *responseCache) put(request *http.Request, cacheConfig *shared.WiretapCacheConfig,
+	response *http.Response, rawHeaders []*HttpHeader) {
+
+	if response == nil || response.StatusCode < 200 || response.StatusCode > 299 {
+		return
+	}
+	ttl := cacheConfig.CompiledTTL
+	if ttl <= 0 {
+		ttl = shared.DefaultCacheTTL
+	}
+	if cacheConfig.HonorCacheControl {
+		if hasCacheDirective(response.Header, "no-store", "no-cache", "private") {
+			return
+		}
+		if maxAge, ok := cacheMaxAge(response.Header); ok {
+			if maxAge <= 0 {
+				return
+			}
+			if maxAge < ttl {
+				ttl = maxAge
+			}
+		}
+	}
+
+	var body []byte
+	if response.Body != nil {
+		body, _ = io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		response.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	now := time.Now()
+	rc.lock.Lock()
+	if now.Sub(rc.swept) >= cacheSweepInterval {
+		rc.sweep(now)
+	}
+	rc.entries[buildCacheKey(request, cacheConfig)] = &cachedResponse{
+		statusCode: response.StatusCode,
+		header:     response.Header.Clone(),
+		rawHeaders: rawHeaders,
+		body:       body,
+		expires:    now.Add(ttl),
+	}
+	rc.lock.Unlock()
+}
+
+// sweep removes every expired response, the cache must be locked.
+func (rc *responseCache) sweep(now time.Time) {
+	for key, entry := range rc.entries {
+		if now.After(entry.expires) {
+			delete(rc.entries, key)
+		}
+	}
+	rc.swept = now
+}
+
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, v := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+			directives[strings.ToLower(name)] = strings.Trim(value, "\"")
+		}
+	}
+	return directives
+}
+
+func hasCacheDirective(header http.Header, names ...string) bool {
+	directives := parseCacheControl(header)
+	for _, n := range names {
+		if _, ok := directives[n]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheMaxAge(header http.Header) (time.Duration, bool) {
+	directives := parseCacheControl(header)
+	for _, n := range []string{"s-maxage", "max-age"} {
+		if v, ok := directives[n]; ok {
+			if seconds, err := strconv.Atoi(v); err == nil {
+				return time.Duration(seconds) * time.Second, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,136 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildCacheableResponse(body string, cacheControl string) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	if cacheControl != "" {
+		header.Set("Cache-Control", cacheControl)
+	}
+	return &http.Response{StatusCode: 200, Header: header, Body: io.NopCloser(bytes.NewBufferString(body))}
+}
+
+func TestResponseCache_KeysAndExpiry(t *testing.T) {
+	pc := &shared.WiretapPathConfig{Cache: &shared.WiretapCacheConfig{TTL: "50ms"}}
+	pc.Compile("/pets")
+	assert.Equal(t, []string{shared.CacheKeyPath, shared.CacheKeyQuery}, pc.Cache.Keys)
+
+	rc := newResponseCache()
+	req := httptest.NewRequest(http.MethodGet, "/pets?b=2&a=1", nil)
+	resp := buildCacheableResponse(`{"pet":"cat"}`, "")
+	rc.put(req, pc.Cache, resp, nil)
+
+	// the original body must still be readable.
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"pet":"cat"}`, string(b))
+
+	// query order does not matter.
+	cached, _ := rc.get(httptest.NewRequest(http.MethodGet, "/pets?a=1&b=2", nil), pc.Cache)
+	assert.NotNil(t, cached)
+	b, _ = io.ReadAll(cached.Body)
+	assert.Equal(t, `{"pet":"cat"}`, string(b))
+
+	cached, _ = rc.get(httptest.NewRequest(http.MethodGet, "/pets?a=2", nil), pc.Cache)
+	assert.Nil(t, cached)
+
+	time.Sleep(60 * time.Millisecond)
+	cached, _ = rc.get(req, pc.Cache)
+	assert.Nil(t, cached)
+}
+
+func TestResponseCache_Sweep(t *testing.T) {
+	short := &shared.WiretapCacheConfig{Keys: []string{shared.CacheKeyPath, shared.CacheKeyQuery},
+		CompiledTTL: 10 * time.Millisecond}
+	long := &shared.WiretapCacheConfig{Keys: []string{shared.CacheKeyPath, shared.CacheKeyQuery},
+		CompiledTTL: time.Minute}
+	rc := newResponseCache()
+	for _, query := range []string{"a=1", "a=2", "a=3"} {
+		rc.put(httptest.NewRequest(http.MethodGet, "/pets?"+query, nil), short, buildCacheableResponse(`[]`, ""), nil)
+	}
+	rc.put(httptest.NewRequest(http.MethodGet, "/pets?a=4", nil), long, buildCacheableResponse(`[]`, ""), nil)
+	assert.Len(t, rc.entries, 4)
+
+	// expired responses that are never asked for again are swept, those still fresh are kept.
+	time.Sleep(20 * time.Millisecond)
+	rc.swept = time.Time{}
+	rc.put(httptest.NewRequest(http.MethodGet, "/pets?a=5", nil), long, buildCacheableResponse(`[]`, ""), nil)
+	assert.Len(t, rc.entries, 2)
+	cached, _ := rc.get(httptest.NewRequest(http.MethodGet, "/pets?a=4", nil), long)
+	assert.NotNil(t, cached)
+
+	// sweeping is only done every so often, not on every response.
+	rc.put(httptest.NewRequest(http.MethodGet, "/pets?a=6", nil), short, buildCacheableResponse(`[]`, ""), nil)
+	time.Sleep(20 * time.Millisecond)
+	rc.put(httptest.NewRequest(http.MethodGet, "/pets?a=7", nil), long, buildCacheableResponse(`[]`, ""), nil)
+	assert.Len(t, rc.entries, 4)
+}
+
+func TestResponseCache_CacheControl(t *testing.T) {
+	ignore := &shared.WiretapCacheConfig{Keys: []string{shared.CacheKeyPath}, CompiledTTL: time.Minute}
+	honor := &shared.WiretapCacheConfig{Keys: []string{shared.CacheKeyPath}, CompiledTTL: time.Minute,
+		HonorCacheControl: true}
+
+	req := httptest.NewRequest(http.MethodGet, "/pets", nil)
+
+	rc := newResponseCache()
+	rc.put(req, ignore, buildCacheableResponse("{}", "no-store"), nil)
+	cached, _ := rc.get(req, ignore)
+	assert.NotNil(t, cached)
+
+	rc = newResponseCache()
+	rc.put(req, honor, buildCacheableResponse("{}", "no-store"), nil)
+	cached, _ = rc.get(req, honor)
+	assert.Nil(t, cached)
+
+	rc.put(req, honor, buildCacheableResponse("{}", "max-age=60"), nil)
+	noCache := httptest.NewRequest(http.MethodGet, "/pets", nil)
+	noCache.Header.Set("Cache-Control", "no-cache")
+	cached, _ = rc.get(noCache, honor)
+	assert.Nil(t, cached)
+	cached, _ = rc.get(req, honor)
+	assert.NotNil(t, cached)
+}
+
+func TestResponseCache_Hosts(t *testing.T) {
+	config := &shared.WiretapCacheConfig{Keys: []string{shared.CacheKeyPath}, CompiledTTL: time.Minute}
+	rc := newResponseCache()
+
+	cats := httptest.NewRequest(http.MethodGet, "http://cats.example.com/pets", nil)
+	dogs := httptest.NewRequest(http.MethodGet, "http://dogs.example.com/pets", nil)
+	rc.put(cats, config, buildCacheableResponse(`{"pet":"cat"}`, ""), nil)
+
+	// the same path on another host is another API.
+	cached, _ := rc.get(dogs, config)
+	assert.Nil(t, cached)
+
+	rc.put(dogs, config, buildCacheableResponse(`{"pet":"dog"}`, ""), nil)
+	for request, body := range map[*http.Request]string{cats: `{"pet":"cat"}`, dogs: `{"pet":"dog"}`} {
+		cached, _ = rc.get(request, config)
+		assert.NotNil(t, cached)
+		b, _ := io.ReadAll(cached.Body)
+		assert.Equal(t, body, string(b))
+	}
+
+	// and so is another scheme, or port.
+	cached, _ = rc.get(httptest.NewRequest(http.MethodGet, "https://cats.example.com/pets", nil), config)
+	assert.Nil(t, cached)
+	cached, _ = rc.get(httptest.NewRequest(http.MethodGet, "http://cats.example.com:8080/pets", nil), config)
+	assert.Nil(t, cached)
+	cached, _ = rc.get(httptest.NewRequest(http.MethodGet, "http://CATS.example.com:80/pets", nil), config)
+	assert.NotNil(t, cached)
+}
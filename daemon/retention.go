@@ -0,0 +1,145 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// evictionInterval is how often evictions are reported to the monitor, at most.
+const evictionInterval = time.Second
+
+// transactionOverhead is roughly what a transaction costs, before its URL, headers and bodies are counted.
+const transactionOverhead = 512
+
+// retention keeps track of the order transactions were kept in and roughly how much memory they use, so the oldest
+// can be evicted when there are too many. It's guarded by the transaction lock.
+type retention struct {
+	order    []string
+	sizes    map[string]int
+	bytes    int
+	evicted  int
+	reason   string
+	reported bool
+}
+
+// retain counts a transaction (or a half of one) being kept, and returns the ids of the oldest transactions that
+// have to be evicted to stay within the limits. The transaction just kept is never evicted.
+func (r *retention) retain(id string, size, maxTransactions, maxBytes int) []string {
+	if r.sizes == nil {
+		r.sizes = make(map[string]int)
+	}
+	previous, known := r.sizes[id]
+	if !known {
+		r.order = append(r.order, id)
+	}
+	r.sizes[id] = size
+	r.bytes += size - previous
+
+	var evict []string
+	for len(r.order) > 1 {
+		switch {
+		case maxTransactions > 0 && len(r.order) > maxTransactions:
+			r.reason = shared.EvictedMaxTransactions
+		case maxBytes > 0 && r.bytes > maxBytes:
+			r.reason = shared.EvictedMaxCaptureMemory
+		default:
+			return evict
+		}
+		oldest := r.order[0]
+		if oldest == id {
+			// the transaction being kept is the oldest, move it to the back rather than lose it.
+			r.order = append(r.order[1:], id)
+			continue
+		}
+		r.order = r.order[1:]
+		r.bytes -= r.sizes[oldest]
+		delete(r.sizes, oldest)
+		r.evicted++
+		evict = append(evict, oldest)
+	}
+	return evict
+}
+
+// transactionSize estimates how much memory a transaction uses.
+func transactionSize(transaction *HttpTransaction) int {
+	size := transactionOverhead
+	if req := transaction.Request; req != nil {
+		size += len(req.URL) + len(req.Path) + len(req.Query) + len(req.Body) + headersSize(req.Headers)
+	}
+	if resp := transaction.Response; resp != nil {
+		size += len(resp.Body) + headersSize(resp.Headers)
+	}
+	for _, violations := range [][]*shared.Violation{transaction.RequestValidation, transaction.ResponseValidation} {
+		for _, v := range violations {
+			if v != nil && v.ValidationError != nil {
+				size += len(v.Message) + len(v.Reason)
+			}
+		}
+	}
+	return size
+}
+
+func headersSize(headers map[string]any) int {
+	size := 0
+	for name, value := range headers {
+		size += len(name)
+		switch v := value.(type) {
+		case string:
+			size += len(v)
+		case []string:
+			for _, s := range v {
+				size += len(s)
+			}
+		}
+	}
+	return size
+}
+
+// evictTransactions drops transactions from the in-memory store, and schedules a report to the monitor so it knows
+// the history has been truncated. Reports are sent at most once per evictionInterval, however much is evicted.
+// The transaction lock must be held.
+func (ws *WiretapService) evictTransactions(ids []string) {
+	for _, id := range ids {
+		ws.transactionStore.Remove(id, nil)
+	}
+	if len(ids) > 0 && !ws.retention.reported {
+		ws.retention.reported = true
+		time.AfterFunc(evictionInterval, ws.reportEviction)
+	}
+}
+
+// reportEviction tells the monitor how many transactions have been evicted, and how many are left.
+func (ws *WiretapService) reportEviction() {
+	ws.transactionLock.Lock()
+	status := &shared.WiretapRetentionStatus{
+		Evicted:       ws.retention.evicted,
+		Retained:      len(ws.retention.order),
+		RetainedBytes: ws.retention.bytes,
+		Reason:        ws.retention.reason,
+		EvictedAt:     time.Now(),
+	}
+	ws.retention.reported = false
+	ws.transactionLock.Unlock()
+
+	if ws.controlsStore != nil {
+		ws.controlsStore.Put(shared.RetentionStatusKey, status, nil)
+	}
+	if ws.retentionChan == nil {
+		return
+	}
+	id, _ := uuid.NewUUID()
+	ws.retentionChan.Send(&model.Message{
+		Id:          &id,
+		Channel:     WiretapRetentionChan,
+		Destination: WiretapRetentionChan,
+		Payload:     status,
+		Direction:   model.ResponseDir,
+	})
+}
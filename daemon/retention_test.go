@@ -0,0 +1,38 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetention_MaxTransactions(t *testing.T) {
+	var r retention
+	assert.Empty(t, r.retain("a", 10, 2, 0))
+	assert.Empty(t, r.retain("b", 10, 2, 0))
+	assert.Empty(t, r.retain("a", 20, 2, 0)) // the response half of a kept transaction.
+	assert.Equal(t, []string{"a"}, r.retain("c", 10, 2, 0))
+	assert.Equal(t, []string{"b"}, r.retain("d", 10, 2, 0))
+	assert.Equal(t, []string{"c", "d"}, r.order)
+	assert.Equal(t, 20, r.bytes)
+	assert.Equal(t, 2, r.evicted)
+	assert.Equal(t, shared.EvictedMaxTransactions, r.reason)
+}
+
+func TestRetention_MaxBytes(t *testing.T) {
+	var r retention
+	assert.Empty(t, r.retain("a", 40, 0, 100))
+	assert.Empty(t, r.retain("b", 40, 0, 100))
+	assert.Equal(t, []string{"a", "b"}, r.retain("c", 90, 0, 100))
+	assert.Equal(t, 90, r.bytes)
+
+	// a transaction bigger than the limit is still kept, on its own.
+	assert.Equal(t, []string{"c"}, r.retain("d", 500, 0, 100))
+	assert.Equal(t, []string{"d"}, r.order)
+	assert.Equal(t, shared.EvictedMaxCaptureMemory, r.reason)
+}
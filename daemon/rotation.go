@@ -0,0 +1,108 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// rotationSchedule rotates output files hourly or daily, rotated files are named after the hour (or day) they
+// cover, and only the most recent are kept.
+type rotationSchedule struct {
+	period string
+	retain int
+}
+
+func newRotationSchedule(config *shared.WiretapConfiguration) *rotationSchedule {
+	return &rotationSchedule{period: strings.ToLower(config.ReportRotation), retain: config.ReportRetention}
+}
+
+// due checks if a file opened at a time belongs to an earlier period than now.
+func (rs *rotationSchedule) due(opened, now time.Time) bool {
+	if rs == nil || rs.period == "" {
+		return false
+	}
+	return !rs.start(opened).Equal(rs.start(now))
+}
+
+// start returns the start of the period a time is in.
+func (rs *rotationSchedule) start(t time.Time) time.Time {
+	switch rs.period {
+	case shared.RotateHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case shared.RotateDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return t
+}
+
+// stamp names the period a time is in, e.g. 2024-01-31 or 2024-01-31T14.
+func (rs *rotationSchedule) stamp(t time.Time) string {
+	if rs.period == shared.RotateHourly {
+		return t.Format("2006-01-02T15")
+	}
+	return t.Format("2006-01-02")
+}
+
+// rotatedFilename inserts a stamp before the extension of a file, e.g. wiretap-report-2024-01-31.json.
+func rotatedFilename(filename, stamp string) string {
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(filename, ext), stamp, ext)
+}
+
+// rotate renames a file after the period it was opened in, and removes the oldest rotated files beyond the
+// number retained. A file already rotated for the period (e.g. after a restart) isn't overwritten.
+func (rs *rotationSchedule) rotate(filename string, opened time.Time) error {
+	rotated := rotatedFilename(filename, rs.stamp(opened))
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rotated); os.IsNotExist(err) {
+			break
+		}
+		rotated = rotatedFilename(filename, fmt.Sprintf("%s.%d", rs.stamp(opened), i))
+	}
+	if err := os.Rename(filename, rotated); err != nil {
+		return err
+	}
+	return pruneRotated(filename, rs.retain)
+}
+
+// pruneRotated removes the oldest rotated copies of a file, so only the most recent are kept. Nothing is removed
+// if retain is zero.
+func pruneRotated(filename string, retain int) error {
+	if retain <= 0 {
+		return nil
+	}
+	ext := filepath.Ext(filename)
+	rotated, err := filepath.Glob(strings.TrimSuffix(filename, ext) + "-[0-9]*" + ext)
+	if err != nil || len(rotated) <= retain {
+		return err
+	}
+	modified := make(map[string]time.Time, len(rotated))
+	for _, name := range rotated {
+		if fi, sErr := os.Stat(name); sErr == nil {
+			modified[name] = fi.ModTime()
+		}
+	}
+	sort.Slice(rotated, func(i, j int) bool {
+		return modified[rotated[i]].After(modified[rotated[j]])
+	})
+	var errs []string
+	for _, name := range rotated[retain:] {
+		if rErr := os.Remove(name); rErr != nil {
+			errs = append(errs, rErr.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot remove rotated files: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotationSchedule_Due(t *testing.T) {
+	opened := time.Date(2024, 1, 31, 14, 59, 0, 0, time.UTC)
+	hourly := &rotationSchedule{period: shared.RotateHourly}
+	daily := &rotationSchedule{period: shared.RotateDaily}
+
+	assert.False(t, hourly.due(opened, opened.Add(30*time.Second)))
+	assert.True(t, hourly.due(opened, opened.Add(2*time.Minute)))
+	assert.False(t, daily.due(opened, opened.Add(2*time.Hour)))
+	assert.True(t, daily.due(opened, opened.Add(10*time.Hour)))
+	assert.False(t, (&rotationSchedule{}).due(opened, opened.Add(48*time.Hour)))
+
+	assert.Equal(t, "2024-01-31T14", hourly.stamp(opened))
+	assert.Equal(t, "wiretap-report-2024-01-31.json", rotatedFilename("wiretap-report.json", daily.stamp(opened)))
+}
+
+func TestRotationSchedule_RotateAndRetain(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "wiretap-report.json")
+	coverage := filepath.Join(dir, "wiretap-report-coverage.json")
+	require.NoError(t, os.WriteFile(coverage, []byte("{}"), 0644))
+
+	schedule := &rotationSchedule{period: shared.RotateDaily, retain: 2}
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	for day := 0; day < 4; day++ {
+		require.NoError(t, os.WriteFile(filename, []byte("[]"), 0644))
+		opened := start.AddDate(0, 0, day)
+		require.NoError(t, schedule.rotate(filename, opened))
+		_ = os.Chtimes(rotatedFilename(filename, schedule.stamp(opened)), opened, opened)
+	}
+
+	rotated, _ := filepath.Glob(filepath.Join(dir, "wiretap-report-2*.json"))
+	assert.Len(t, rotated, 2)
+	assert.FileExists(t, coverage)
+	assert.FileExists(t, filepath.Join(dir, "wiretap-report-2024-01-04.json"))
+}
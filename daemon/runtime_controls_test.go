@@ -0,0 +1,79 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pb33f/wiretap/controls"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRuntimeControls_Concurrent changes everything controls can change at runtime, while requests are handled.
+// It's only meaningful with -race.
+func TestRuntimeControls_Concurrent(t *testing.T) {
+	upstream := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"name":"upstream"}]`))
+	})
+	ws := newTestService(t, petsSpec, &shared.WiretapConfiguration{
+		Headers: &shared.WiretapHeaderConfig{DropHeaders: []string{"X-Internal"}}}, upstream)
+
+	done := make(chan struct{})
+	var changes sync.WaitGroup
+	changes.Add(1)
+	go func() {
+		defer changes.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			_, err := controls.SetGlobalDelay(i%2, "test")
+			require.NoError(t, err)
+			_, err = controls.SetPathDelays(&controls.PathDelaysChange{
+				PathDelays: map[string]int{"/pets": i % 2}, Replace: true}, "test")
+			require.NoError(t, err)
+			_, err = controls.SetMockPaths(&controls.MockPathsChange{MockPaths: map[string]bool{"/pets": i%2 == 0}},
+				"test")
+			require.NoError(t, err)
+			timeout := i % 5
+			_, err = controls.SetIntercept(&controls.InterceptChange{
+				Intercept: []string{fmt.Sprintf("DELETE /held/%d", i%3)}, Timeout: &timeout}, "test")
+			require.NoError(t, err)
+			controls.SetCapturePaused(i%2 == 0, "test")
+		}
+	}()
+
+	var requests sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			for i := 0; i < 20; i++ {
+				w := serveTestRequest(ws, httptest.NewRequest(http.MethodGet, "/pets", nil))
+				assert.Equal(t, http.StatusOK, w.Code)
+			}
+		}()
+	}
+	requests.Wait()
+	close(done)
+	changes.Wait()
+
+	// whatever was last asked for is what's in use.
+	controls.SetCapturePaused(false, "test")
+	_, _ = controls.SetMockPaths(&controls.MockPathsChange{ResetAll: true}, "test")
+	_, _ = controls.SetGlobalDelay(0, "test")
+	assert.False(t, ws.config.IsCapturePaused())
+	assert.Empty(t, ws.config.GetMockPaths())
+	assert.Zero(t, ws.config.GetGlobalAPIDelay())
+}
@@ -0,0 +1,37 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/pb33f/ranch/model"
+)
+
+// captureSampled decides if a transaction is kept and broadcast, when only a fraction of them are captured. The
+// decision is made from the transaction id, so the request and the response of a transaction are always captured
+// together. Everything is still proxied and validated, sampling only applies to what is held on to.
+func captureSampled(id string, rate float64) bool {
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	return float64(h.Sum32())/math.MaxUint32 < rate
+}
+
+// capturing checks if a transaction is captured, it isn't if capture has been paused or it's not in the sample.
+func (ws *WiretapService) capturing(id string) bool {
+	return !ws.config.IsCapturePaused() && captureSampled(id, ws.config.CaptureSampleRate)
+}
+
+// captureRequest checks if a request is captured.
+func (ws *WiretapService) captureRequest(request *model.Request) bool {
+	if request == nil || request.Id == nil {
+		return !ws.config.IsCapturePaused()
+	}
+	return ws.capturing(request.Id.String())
+}
@@ -0,0 +1,28 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureSampled(t *testing.T) {
+	assert.True(t, captureSampled("anything", 0))
+	assert.True(t, captureSampled("anything", 1))
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		id := uuid.NewString()
+		sampled := captureSampled(id, 0.1)
+		assert.Equal(t, sampled, captureSampled(id, 0.1)) // the same transaction is always sampled the same way.
+		if sampled {
+			kept++
+		}
+	}
+	assert.InDelta(t, 1000, kept, 150)
+}
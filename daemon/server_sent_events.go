@@ -0,0 +1,164 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pb33f/ranch/model"
+)
+
+// maxCapturedStream is the most of an event stream kept for the monitor, later events are still sent to the client.
+const maxCapturedStream = 16 << 20
+
+// eventBroadcastInterval is how often the monitor is sent the events of a stream that is still open.
+const eventBroadcastInterval = 250 * time.Millisecond
+
+// ServerSentEvent is an event read from a text/event-stream response.
+type ServerSentEvent struct {
+	Id    string `json:"id,omitempty"`
+	Event string `json:"event,omitempty"`
+	Data  string `json:"data"`
+	Retry int    `json:"retry,omitempty"`
+}
+
+// isEventStream checks if a response is a stream of server-sent events.
+func isEventStream(response *http.Response) bool {
+	if response == nil {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// streamEvents sends an event stream to the client as it arrives, flushing after every read. The stream is
+// captured as it goes; the monitor is kept up to date with its events while it's open, and the response is
+// validated once it ends. Streams are never held back, so strict mode cannot replace them.
+func (ws *WiretapService) streamEvents(request *model.Request, response *http.Response, rawHeaders []*HttpHeader,
+	playback bool) {
+
+	defer response.Body.Close()
+	w := request.HttpResponseWriter
+	flusher, _ := w.(http.Flusher)
+
+	corsHeaders := make(map[string]any)
+	setCORSHeaders(corsHeaders)
+	if playback {
+		corsHeaders[PlaybackHeader] = "HIT"
+	}
+	writeResponseHeaders(w, response, rawHeaders, corsHeaders)
+	w.WriteHeader(response.StatusCode)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	ws.config.Logger.Info("[wiretap] streaming events", "url", request.HttpRequest.URL.String(),
+		"code", response.StatusCode)
+
+	var lock sync.Mutex
+	var captured []byte
+	dirty := true
+	snapshot := func() *http.Response {
+		lock.Lock()
+		defer lock.Unlock()
+		dirty = false
+		clone := *response
+		clone.Body = io.NopCloser(bytes.NewReader(bytes.Clone(captured)))
+		return &clone
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(eventBroadcastInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				lock.Lock()
+				send := dirty
+				lock.Unlock()
+				if send {
+					ws.broadcastResponse(request, snapshot())
+				}
+			}
+		}
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := response.Body.Read(buf)
+		if n > 0 {
+			if _, wErr := w.Write(buf[:n]); wErr != nil {
+				break // the client has gone.
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			lock.Lock()
+			if len(captured)+n <= maxCapturedStream {
+				captured = append(captured, buf[:n]...)
+				dirty = true
+			}
+			lock.Unlock()
+		}
+		if err != nil {
+			break
+		}
+	}
+	close(done)
+	writeResponseTrailers(w, response)
+	ws.config.Logger.Info("[wiretap] event stream closed", "url", request.HttpRequest.URL.String())
+
+	final := snapshot()
+	ws.validationPool.submit(func() { ws.validateUpstreamResponse(request, final, rawHeaders) },
+		func() { ws.recordResponse(request, final, nil, false) })
+}
+
+// parseServerSentEvents splits a captured event stream into its events. Comments are left out, and so is an
+// event that hasn't been finished by a blank line yet.
+func parseServerSentEvents(stream []byte) []*ServerSentEvent {
+	var events []*ServerSentEvent
+	var data []string
+	event := &ServerSentEvent{}
+	text := strings.ReplaceAll(strings.ReplaceAll(string(stream), "\r\n", "\n"), "\r", "\n")
+	for {
+		line, rest, found := strings.Cut(text, "\n")
+		if !found {
+			return events
+		}
+		text = rest
+		if line == "" {
+			if data != nil {
+				event.Data = strings.Join(data, "\n")
+				events = append(events, event)
+			}
+			event, data = &ServerSentEvent{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "event":
+			event.Event = value
+		case "id":
+			event.Id = value
+		case "retry":
+			event.Retry, _ = strconv.Atoi(value)
+		}
+	}
+}
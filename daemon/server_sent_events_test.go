@@ -0,0 +1,28 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseServerSentEvents(t *testing.T) {
+	stream := ": keep alive\r\nevent: tick\r\nid: 1\r\ndata: one\r\ndata:two\r\n\r\n" +
+		"retry: 3000\ndata: {\"ok\":true}\n\n" +
+		"id: 3\n\n" +
+		"data: unfinished\n"
+
+	events := parseServerSentEvents([]byte(stream))
+	assert.Len(t, events, 2)
+	assert.Equal(t, &ServerSentEvent{Id: "1", Event: "tick", Data: "one\ntwo"}, events[0])
+	assert.Equal(t, &ServerSentEvent{Data: `{"ok":true}`, Retry: 3000}, events[1])
+
+	assert.True(t, isEventStream(&http.Response{Header: http.Header{"Content-Type": {"text/event-stream; charset=utf-8"}}}))
+	assert.False(t, isEventStream(&http.Response{Header: http.Header{"Content-Type": {"application/json"}}}))
+	assert.False(t, isEventStream(nil))
+}
@@ -0,0 +1,85 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// maxServerExpansions stops a server with many enumerated variables turning into an enormous number of servers.
+const maxServerExpansions = 64
+
+var serverVariablePattern = regexp.MustCompile(`{([^{}]+)}`)
+
+// resolveServers replaces the variables in the server URLs of a specification, so requests are matched against
+// base paths like /api/v{version}. A pinned value is used for a variable if there is one, otherwise a server is
+// added for every value of its enum (the default first), or the default is used. Variables that can't be resolved
+// are left alone. Resolving is done in place, and a resolved specification is left as it is.
+func resolveServers(doc *v3.Document, pinned map[string]string) {
+	if doc == nil {
+		return
+	}
+	var servers []*v3.Server
+	for _, server := range doc.Servers {
+		if server == nil || !strings.Contains(server.URL, "{") {
+			servers = append(servers, server)
+			continue
+		}
+		urls := []string{server.URL}
+		seen := make(map[string]bool)
+		for _, match := range serverVariablePattern.FindAllStringSubmatch(server.URL, -1) {
+			if seen[match[1]] {
+				continue
+			}
+			seen[match[1]] = true
+			values := serverVariableValues(server, match[1], pinned)
+			if len(values) == 0 {
+				continue
+			}
+			var expanded []string
+			for _, url := range urls {
+				for _, value := range values {
+					if len(expanded) < maxServerExpansions {
+						expanded = append(expanded, strings.ReplaceAll(url, match[0], value))
+					}
+				}
+			}
+			urls = expanded
+		}
+		server.URL = urls[0]
+		servers = append(servers, server)
+		for _, url := range urls[1:] {
+			servers = append(servers, &v3.Server{URL: url, Description: server.Description})
+		}
+	}
+	doc.Servers = servers
+}
+
+// serverVariableValues returns the values a server variable can take, in the order they should be matched.
+func serverVariableValues(server *v3.Server, name string, pinned map[string]string) []string {
+	if value, ok := pinned[name]; ok {
+		return []string{value}
+	}
+	if server.Variables == nil {
+		return nil
+	}
+	variable := server.Variables.GetOrZero(name)
+	if variable == nil {
+		return nil
+	}
+	var values []string
+	if variable.Default != "" {
+		values = append(values, variable.Default)
+	}
+	for _, value := range variable.Enum {
+		if value != variable.Default {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,74 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+var serversSpec = `openapi: 3.1.0
+servers:
+  - url: https://{env}.example.com/api/v{version}
+    variables:
+      env:
+        default: prod
+      version:
+        default: "2"
+        enum: ["1", "2"]
+  - url: /static
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: OK`
+
+func buildServersModel(t *testing.T) *v3.Document {
+	doc, err := libopenapi.NewDocument([]byte(serversSpec))
+	assert.NoError(t, err)
+	m, errs := doc.BuildV3Model()
+	assert.Empty(t, errs)
+	return &m.Model
+}
+
+func serverURLs(doc *v3.Document) []string {
+	var urls []string
+	for _, s := range doc.Servers {
+		urls = append(urls, s.URL)
+	}
+	return urls
+}
+
+func TestResolveServers(t *testing.T) {
+	doc := buildServersModel(t)
+	resolveServers(doc, nil)
+	assert.Equal(t, []string{
+		"https://prod.example.com/api/v2",
+		"https://prod.example.com/api/v1",
+		"/static",
+	}, serverURLs(doc))
+
+	// resolving again changes nothing.
+	resolveServers(doc, nil)
+	assert.Len(t, doc.Servers, 3)
+
+	for _, path := range []string{"/api/v1/pets", "/api/v2/pets"} {
+		request, _ := http.NewRequest(http.MethodGet, "https://prod.example.com"+path, nil)
+		_, operation := validation.LocateOperation(request, doc)
+		assert.NotNil(t, operation, path)
+	}
+}
+
+func TestResolveServers_Pinned(t *testing.T) {
+	doc := buildServersModel(t)
+	resolveServers(doc, map[string]string{"version": "3", "env": "staging"})
+	assert.Equal(t, []string{"https://staging.example.com/api/v3", "/static"}, serverURLs(doc))
+}
@@ -0,0 +1,214 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+	"github.com/pb33f/wiretap/audit"
+	"github.com/pb33f/wiretap/coverage"
+	"github.com/pb33f/wiretap/mock"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/swagger"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// PushSpecification is the payload of a push-spec request, the spec is the content of the specification.
+type PushSpecification struct {
+	Spec string `json:"spec"`
+}
+
+// SpecificationLoader loads the specification wiretap is serving, it's called every time the specification is reloaded.
+type SpecificationLoader func() (libopenapi.Document, error)
+
+// SetSpecificationLoader sets the loader used by ReloadSpecification.
+func (ws *WiretapService) SetSpecificationLoader(loader SpecificationLoader) {
+	ws.specLoader = loader
+}
+
+// OnSpecificationChange registers a listener that is called with the new document after every successful reload.
+func (ws *WiretapService) OnSpecificationChange(listener func(document libopenapi.Document)) {
+	ws.specListeners = append(ws.specListeners, listener)
+}
+
+// ReloadSpecification loads the specification again and swaps it in. If the specification cannot be loaded or
+// compiled, the previous specification keeps being served and the failure is reported to the monitor.
+func (ws *WiretapService) ReloadSpecification() error {
+	if ws.specLoader == nil {
+		return fmt.Errorf("no specification has been configured, there is nothing to reload")
+	}
+	document, err := ws.specLoader()
+	if err != nil {
+		ws.specificationFailed([]error{err})
+		return err
+	}
+	return ws.ApplySpecification(document)
+}
+
+// ApplySpecification compiles a document and, if successful, replaces the specification used for validation
+// and mocking. If the document fails to compile, nothing is replaced. Resources stored by stateful mocks, and how far
+// clients have got through mock sequences, are kept.
+func (ws *WiretapService) ApplySpecification(document libopenapi.Document) error {
+	if document == nil {
+		err := fmt.Errorf("specification is empty")
+		ws.specificationFailed([]error{err})
+		return err
+	}
+	m, errs := document.BuildV3Model()
+	if m == nil {
+		if len(errs) == 0 {
+			errs = []error{fmt.Errorf("specification could not be compiled into an OpenAPI 3 model")}
+		}
+		ws.specificationFailed(errs)
+		return errors.Join(errs...)
+	}
+
+	docModel := &m.Model
+	mockEngine := ws.buildMockEngine(docModel)
+	mockEngine.KeepState(ws.currentMockEngine())
+	ws.specLock.Lock()
+	ws.document = document
+	ws.docModel = docModel
+	ws.validator = newValidator(docModel, ws.config)
+	ws.webhookValidator = validation.NewWebhookValidator(docModel)
+	ws.mockEngine = mockEngine
+	ws.coverageTracker = coverage.NewTracker(docModel)
+	ws.specLock.Unlock()
+
+	ws.updateSpecStatus(&shared.WiretapSpecStatus{Healthy: true})
+	for _, listener := range ws.specListeners {
+		listener(document)
+	}
+	return nil
+}
+
+// pushSpecification replaces the specification with one sent over the control API, instead of loading it again.
+func (ws *WiretapService) pushSpecification(request *model.Request, core service.FabricServiceCore) {
+	var r PushSpecification
+	if dl, ok := request.Payload.(map[string]interface{}); ok {
+		_ = mapstructure.Decode(dl, &r)
+	}
+	if r.Spec == "" {
+		core.SendErrorResponse(request, 400, "Invalid specification, spec cannot be empty")
+		return
+	}
+	specBytes := []byte(r.Spec)
+	if swagger.IsSwagger(specBytes) {
+		converted, cErr := swagger.Convert(specBytes)
+		if cErr != nil {
+			ws.specificationFailed([]error{cErr})
+			ws.auditSpecification(audit.Client(request), audit.ActionSpecificationPushed, cErr)
+			core.SendErrorResponse(request, 422, cErr.Error())
+			return
+		}
+		specBytes = converted
+	}
+	document, err := libopenapi.NewDocument(specBytes)
+	if err != nil {
+		ws.specificationFailed([]error{err})
+	} else {
+		err = ws.ApplySpecification(document)
+	}
+	ws.auditSpecification(audit.Client(request), audit.ActionSpecificationPushed, err)
+	if err != nil {
+		core.SendErrorResponse(request, 422, err.Error())
+		return
+	}
+	core.SendResponse(request, &shared.WiretapSpecStatus{Healthy: true})
+}
+
+// auditSpecification records a specification being replaced (or an attempt to), in the audit log.
+func (ws *WiretapService) auditSpecification(client, action string, err error) {
+	details := map[string]any{"healthy": err == nil}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	_ = ws.config.Auditor.Record(client, action, details)
+}
+
+func (ws *WiretapService) buildMockEngine(docModel *v3.Document) *mock.ResponseMockEngine {
+	engine := mock.NewMockEngine(docModel, ws.config.MockModePretty)
+	if ws.config.MockModeStateful {
+		engine.SetStateful()
+	}
+	engine.SetErrorRate(ws.mockErrorRate)
+	if ws.config.MockValidation != "" {
+		engine.SetRequestValidation(ws.config.MockValidation)
+	}
+	if ws.mockOverrides != nil {
+		engine.SetOverrides(ws.mockOverrides)
+	}
+	if ws.config.MockPagination {
+		engine.SetPagination(ws.config.MockPaginationTotal)
+	}
+	if ws.config.MockUnionStrategy != "" {
+		engine.SetUnionStrategy(ws.config.MockUnionStrategy)
+	}
+	if len(ws.config.MockSequences) > 0 {
+		engine.SetSequences(ws.config.MockSequences)
+	}
+	if ws.config.MockSeed != 0 {
+		engine.SetSeed(ws.config.MockSeed)
+	}
+	return engine
+}
+
+func (ws *WiretapService) specificationFailed(errs []error) {
+	now := time.Now()
+	status := &shared.WiretapSpecStatus{FailedAt: &now}
+	for _, err := range errs {
+		status.Errors = append(status.Errors, err.Error())
+	}
+	if ws.config != nil && ws.config.Logger != nil {
+		ws.config.Logger.Warn("[wiretap] specification failed to compile, continuing with the previous specification",
+			"errors", errors.Join(errs...).Error())
+	}
+	ws.updateSpecStatus(status)
+}
+
+// updateSpecStatus stores the status for the control API, and broadcasts it to the monitor. Failures are shown
+// as a banner, which stays up until a healthy status is received.
+func (ws *WiretapService) updateSpecStatus(status *shared.WiretapSpecStatus) {
+	if ws.controlsStore != nil {
+		ws.controlsStore.Put(shared.SpecStatusKey, status, nil)
+	}
+	if ws.specStatusChan == nil {
+		return
+	}
+	id, _ := uuid.NewUUID()
+	ws.specStatusChan.Send(&model.Message{
+		Id:          &id,
+		Channel:     WiretapSpecStatusChan,
+		Destination: WiretapSpecStatusChan,
+		Payload:     status,
+		Direction:   model.ResponseDir,
+	})
+}
+
+func (ws *WiretapService) currentDocModel() *v3.Document {
+	ws.specLock.RLock()
+	defer ws.specLock.RUnlock()
+	return ws.docModel
+}
+
+func (ws *WiretapService) currentCoverage() *coverage.Tracker {
+	ws.specLock.RLock()
+	defer ws.specLock.RUnlock()
+	return ws.coverageTracker
+}
+
+func (ws *WiretapService) currentMockEngine() *mock.ResponseMockEngine {
+	ws.specLock.RLock()
+	defer ws.specLock.RUnlock()
+	return ws.mockEngine
+}
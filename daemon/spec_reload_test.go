@@ -0,0 +1,107 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySpecification_KeepsPreviousOnFailure(t *testing.T) {
+	spec := `openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: OK`
+
+	doc, _ := libopenapi.NewDocument([]byte(spec))
+	ws := NewWiretapService(doc, &shared.WiretapConfiguration{})
+	previous := ws.currentDocModel()
+	assert.NotNil(t, previous)
+
+	// swagger documents cannot be compiled into an OpenAPI 3 model.
+	broken, _ := libopenapi.NewDocument([]byte(`swagger: "2.0"
+paths: {}`))
+	assert.Error(t, ws.ApplySpecification(broken))
+	assert.Same(t, previous, ws.currentDocModel())
+
+	status := ws.controlsStore.GetValue(shared.SpecStatusKey).(*shared.WiretapSpecStatus)
+	assert.False(t, status.Healthy)
+	assert.NotEmpty(t, status.Errors)
+	assert.NotNil(t, status.FailedAt)
+
+	// a good specification clears the failure.
+	var reloaded libopenapi.Document
+	ws.OnSpecificationChange(func(d libopenapi.Document) { reloaded = d })
+	ws.SetSpecificationLoader(func() (libopenapi.Document, error) {
+		return libopenapi.NewDocument([]byte(spec))
+	})
+	assert.NoError(t, ws.ReloadSpecification())
+	assert.NotSame(t, previous, ws.currentDocModel())
+	assert.NotNil(t, reloaded)
+
+	status = ws.controlsStore.GetValue(shared.SpecStatusKey).(*shared.WiretapSpecStatus)
+	assert.True(t, status.Healthy)
+	assert.Empty(t, status.Errors)
+}
+
+func TestRemoteSpec_Changed(t *testing.T) {
+	spec := "openapi: 3.1.0"
+	etag := `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(spec))
+	}))
+	defer server.Close()
+
+	remote := &remoteSpec{location: server.URL, client: server.Client()}
+
+	// the first fetch is the specification that's already loaded.
+	changed, err := remote.changed()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// not modified.
+	changed, err = remote.changed()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// a new version.
+	spec = "openapi: 3.1.0\ninfo:\n  title: v2"
+	etag = `"v2"`
+	changed, err = remote.changed()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+}
+
+func TestApplySpecification_KeepsMockState(t *testing.T) {
+	ws := newTestService(t, petsSpec, &shared.WiretapConfiguration{MockMode: true, MockModeStateful: true}, nil)
+	r := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader(`{"name":"pip"}`))
+	r.Header.Set("Content-Type", "application/json")
+	require.Equal(t, http.StatusCreated, serveTestRequest(ws, r).Code)
+
+	// the pet that was created is still there once the specification has been reloaded.
+	doc, _ := libopenapi.NewDocument([]byte(petsSpec))
+	previous := ws.currentMockEngine()
+	require.NoError(t, ws.ApplySpecification(doc))
+	assert.NotSame(t, previous, ws.currentMockEngine())
+
+	w := serveTestRequest(ws, httptest.NewRequest(http.MethodGet, "/pets", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"pip"`)
+}
@@ -0,0 +1,161 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pb33f/wiretap/audit"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// specWatchSettle is how long a local specification has to stay unchanged before it's reloaded, editors and
+// generators often write a file in several steps.
+const specWatchSettle = 250 * time.Millisecond
+
+// WatchSpecification reloads the specification when it changes. A remote specification (a URL) is polled on
+// an interval, using conditional requests, a local file is watched for changes.
+func (ws *WiretapService) WatchSpecification(location string) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		interval := ws.config.SpecPollInterval
+		if interval <= 0 {
+			interval = shared.DefaultSpecPollInterval
+		}
+		go ws.pollSpecification(&remoteSpec{location: location, client: &http.Client{Timeout: 30 * time.Second}},
+			time.Duration(interval)*time.Second)
+		return
+	}
+	ws.watchSpecificationFile(location)
+}
+
+// remoteSpec keeps track of what was last fetched from a remote specification.
+type remoteSpec struct {
+	location     string
+	client       *http.Client
+	etag         string
+	lastModified string
+	checksum     []byte
+}
+
+// changed fetches the specification, and reports if it has changed since the last time it was fetched. The
+// ETag and Last-Modified headers of the last response are sent back, so unchanged specifications aren't
+// downloaded again. Servers that ignore them are covered by comparing the content.
+func (r *remoteSpec) changed() (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, r.location, nil)
+	if err != nil {
+		return false, err
+	}
+	if r.etag != "" {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+	if r.lastModified != "" {
+		req.Header.Set("If-Modified-Since", r.lastModified)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected response polling specification: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	r.etag = resp.Header.Get("ETag")
+	r.lastModified = resp.Header.Get("Last-Modified")
+
+	sum := sha256.Sum256(body)
+	first := r.checksum == nil
+	changed := !bytes.Equal(r.checksum, sum[:])
+	r.checksum = sum[:]
+	return changed && !first, nil
+}
+
+func (ws *WiretapService) pollSpecification(remote *remoteSpec, interval time.Duration) {
+	// the first fetch records the version of the specification that's already loaded.
+	if _, err := remote.changed(); err != nil {
+		ws.config.Logger.Warn("[wiretap] unable to poll specification", "url", remote.location, "error", err.Error())
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		changed, err := remote.changed()
+		if err != nil {
+			ws.config.Logger.Warn("[wiretap] unable to poll specification", "url", remote.location, "error", err.Error())
+			continue
+		}
+		if changed {
+			ws.reloadChangedSpecification(remote.location)
+		}
+	}
+}
+
+// watchSpecificationFile watches the directory of a local specification, rather than the file itself, because
+// a lot of tools replace a file (by writing a new one and renaming it) instead of writing to it.
+func (ws *WiretapService) watchSpecificationFile(location string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		ws.config.Logger.Warn("[wiretap] unable to watch specification", "file", location, "error", err.Error())
+		return
+	}
+	location = filepath.Clean(location)
+	if err = watcher.Add(filepath.Dir(location)); err != nil {
+		ws.config.Logger.Warn("[wiretap] unable to watch specification", "file", location, "error", err.Error())
+		_ = watcher.Close()
+		return
+	}
+
+	var settle *time.Timer
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != location || !(event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) {
+					continue
+				}
+				if settle != nil {
+					settle.Stop()
+				}
+				settle = time.AfterFunc(specWatchSettle, func() {
+					ws.reloadChangedSpecification(location)
+				})
+			case wErr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				ws.config.Logger.Warn("[wiretap] error watching specification", "error", wErr.Error())
+			}
+		}
+	}()
+}
+
+func (ws *WiretapService) reloadChangedSpecification(location string) {
+	err := ws.ReloadSpecification()
+	ws.auditSpecification(audit.ClientWatcher, audit.ActionSpecificationReloaded, err)
+	if err != nil {
+		ws.config.Logger.Warn("[wiretap] specification changed, but could not be reloaded", "spec", location,
+			"error", err.Error())
+		return
+	}
+	ws.config.Logger.Info("[wiretap] specification changed and has been reloaded", "spec", location)
+}
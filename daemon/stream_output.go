@@ -7,9 +7,11 @@ import (
 	"fmt"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
 	"github.com/pterm/pterm"
 	"os"
 	"sync"
+	"time"
 )
 
 func (ws *WiretapService) listenForValidationErrors() {
@@ -25,36 +27,62 @@ func (ws *WiretapService) listenForValidationErrors() {
 		return
 	}
 
+	// the report can be rotated hourly or daily, the rotated report is named after the period it covers.
+	schedule := newRotationSchedule(ws.config)
+	opened := time.Now()
+
 	go func() {
-		defer f.Close()
+		defer func() { f.Close() }()
 		if _, e := f.WriteString("[]"); e != nil {
 			pterm.Error.Println("cannot write violation to stream: " + err.Error())
 		}
+		write := func(violations []*shared.Violation) {
+			lock.Lock()
+
+			fi, _ := f.Stat()
+			if now := time.Now(); fi.Size() > 2 && schedule.due(opened, now) {
+				_ = f.Close()
+				if e := schedule.rotate(ws.reportFile, opened); e != nil {
+					pterm.Error.Println("cannot rotate violation report: " + e.Error())
+				}
+				if f, err = os.OpenFile(ws.reportFile, os.O_APPEND|os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); err != nil {
+					pterm.Error.Println("cannot stream violations: " + err.Error())
+					lock.Unlock()
+					return
+				}
+				_, _ = f.WriteString("[]")
+				opened = now
+				fi, _ = f.Stat()
+			}
+			_ = os.Truncate(ws.reportFile, fi.Size()-1)
+			if fi.Size() > 2 {
+				_, _ = f.WriteString(",\n")
+			}
+
+			for i, v := range violations {
+				bytes, _ := json.Marshal(v)
+				if _, e := f.WriteString(fmt.Sprintf("%s", bytes)); e != nil {
+					pterm.Error.Println("cannot write violation to stream: " + err.Error())
+				}
+				if i > len(violations)-1 {
+					_, _ = f.WriteString(",\n")
+				}
+			}
+			_, _ = f.WriteString("]")
+			lock.Unlock()
+		}
 		for {
 			select {
 			case violations := <-ws.streamChan:
-
 				if ws.stream {
-					lock.Lock()
-
-					fi, _ := f.Stat()
-					_ = os.Truncate(ws.reportFile, fi.Size()-1)
-					if fi.Size() > 2 {
-						_, _ = f.WriteString(",\n")
-					}
 					ws.streamViolations = append(ws.streamViolations, violations...)
+					write(ws.classifyViolations(violations))
+				}
 
-					for i, v := range violations {
-						bytes, _ := json.Marshal(v)
-						if _, e := f.WriteString(fmt.Sprintf("%s", bytes)); e != nil {
-							pterm.Error.Println("cannot write violation to stream: " + err.Error())
-						}
-						if i > len(violations)-1 {
-							_, _ = f.WriteString(",\n")
-						}
-					}
-					_, _ = f.WriteString("]")
-					lock.Unlock()
+			// violations repeated within an aggregation window, reported once with a count.
+			case aggregated := <-ws.aggregateChan:
+				if aggregated = filterViolations(aggregated, ws.config.ReportFilter); ws.stream && len(aggregated) > 0 {
+					write(aggregated)
 				}
 			}
 		}
@@ -0,0 +1,105 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// blockingViolations returns the violations that strict modes act on, only errors count. Requests for paths the
+// specification does not describe are not blocked, there is no contract to enforce.
+func (ws *WiretapService) blockingViolations(violations []*errors.ValidationError) []*errors.ValidationError {
+	var blocking []*errors.ValidationError
+	for _, v := range ws.errorViolations(violations) {
+		if !v.IsPathMissingError() {
+			blocking = append(blocking, v)
+		}
+	}
+	return blocking
+}
+
+// rejectionCode is the status code an invalid request is rejected with. Requests that only break security
+// requirements are unauthorized (401), or forbidden (403) if they are authenticated but lack scopes.
+func rejectionCode(violations []*errors.ValidationError) int {
+	code := http.StatusForbidden
+	for _, v := range violations {
+		if v.ValidationType != validation.SecurityValidationType {
+			return http.StatusBadRequest
+		}
+		if v.ValidationSubType != validation.SecurityScopes {
+			code = http.StatusUnauthorized
+		}
+	}
+	return code
+}
+
+// rejectInvalidRequest answers a request that failed validation with a 400 and the violations, instead of
+// forwarding it to the API. Requests that fail security requirements are answered with a 401 or 403.
+func (ws *WiretapService) rejectInvalidRequest(request *model.Request, config *shared.WiretapConfiguration,
+	violations []*errors.ValidationError) {
+
+	code := rejectionCode(violations)
+	config.Logger.Info("[wiretap] invalid request rejected", "url", request.HttpRequest.URL.String(),
+		"code", code, "violations", len(violations))
+
+	wtError := shared.GenerateError("Invalid request", code,
+		fmt.Sprintf("The request failed validation against the OpenAPI specification with %d %s, "+
+			"it was not sent to the API. Check payload for validation errors.", len(violations),
+			shared.Pluralize(len(violations), "violation", "violations")), "", violations)
+	body := shared.MarshalError(wtError)
+
+	headers := make(map[string]any)
+	setCORSHeaders(headers)
+	headers["Content-Type"] = "application/json"
+
+	resp := &http.Response{
+		StatusCode: code,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+	for k, v := range headers {
+		request.HttpResponseWriter.Header().Set(k, fmt.Sprint(v))
+		resp.Header.Set(k, fmt.Sprint(v))
+	}
+	go ws.broadcastResponse(request, resp)
+
+	request.HttpResponseWriter.WriteHeader(code)
+	_, _ = request.HttpResponseWriter.Write(body)
+}
+
+// replaceInvalidResponse answers a request with the violations of the response the API sent, instead of the
+// response itself, using the configured status code (502 by default).
+func (ws *WiretapService) replaceInvalidResponse(request *model.Request, config *shared.WiretapConfiguration,
+	response *http.Response, violations []*errors.ValidationError) {
+
+	code := config.StrictResponseCode
+	if code <= 0 {
+		code = http.StatusBadGateway
+	}
+	config.Logger.Info("[wiretap] invalid response replaced", "url", request.HttpRequest.URL.String(),
+		"code", code, "upstreamCode", response.StatusCode, "violations", len(violations))
+
+	wtError := shared.GenerateError("Invalid response", code,
+		fmt.Sprintf("The API responded with a %d that failed validation against the OpenAPI specification with "+
+			"%d %s. Check payload for validation errors.", response.StatusCode, len(violations),
+			shared.Pluralize(len(violations), "violation", "violations")), "", violations)
+
+	headers := make(map[string]any)
+	setCORSHeaders(headers)
+	headers["Content-Type"] = "application/json"
+	for k, v := range headers {
+		request.HttpResponseWriter.Header().Set(k, fmt.Sprint(v))
+	}
+	request.HttpResponseWriter.WriteHeader(code)
+	_, _ = request.HttpResponseWriter.Write(shared.MarshalError(wtError))
+}
@@ -0,0 +1,152 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejectionCode(t *testing.T) {
+	security := func(subType string) *errors.ValidationError {
+		return &errors.ValidationError{ValidationType: validation.SecurityValidationType, ValidationSubType: subType}
+	}
+	body := &errors.ValidationError{ValidationType: "request", ValidationSubType: "body"}
+
+	tests := []struct {
+		name       string
+		violations []*errors.ValidationError
+		code       int
+	}{
+		{"contract", []*errors.ValidationError{body}, http.StatusBadRequest},
+		{"missing credentials", []*errors.ValidationError{security(validation.SecurityMissing)}, http.StatusUnauthorized},
+		{"missing scopes", []*errors.ValidationError{security(validation.SecurityScopes)}, http.StatusForbidden},
+		{"missing credentials and scopes", []*errors.ValidationError{security(validation.SecurityScopes),
+			security(validation.SecurityMissing)}, http.StatusUnauthorized},
+		{"security and contract", []*errors.ValidationError{security(validation.SecurityMissing), body},
+			http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, rejectionCode(tt.violations))
+		})
+	}
+}
+
+func TestBlockingViolations(t *testing.T) {
+	ws := newTestService(t, "", &shared.WiretapConfiguration{}, nil)
+	missing := &errors.ValidationError{ValidationType: "path", ValidationSubType: "missing"}
+	body := &errors.ValidationError{ValidationType: "request", ValidationSubType: "body"}
+
+	assert.Empty(t, ws.blockingViolations([]*errors.ValidationError{missing}))
+	assert.Equal(t, []*errors.ValidationError{body}, ws.blockingViolations([]*errors.ValidationError{missing, body}))
+
+	// warnings are never acted upon.
+	ws.config.Severity = map[string]string{"request/body": shared.SeverityWarn}
+	assert.Empty(t, ws.blockingViolations([]*errors.ValidationError{body}))
+}
+
+func TestStrictRequests(t *testing.T) {
+	upstream := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"name":"dave"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name":"dave"}]`))
+	})
+	ws := newTestService(t, petsSpec, &shared.WiretapConfiguration{StrictRequests: true}, upstream)
+
+	tests := []struct {
+		name      string
+		request   func() *http.Request
+		code      int
+		forwarded bool
+	}{
+		{"valid", func() *http.Request { return httptest.NewRequest(http.MethodGet, "/pets?limit=5", nil) },
+			http.StatusOK, true},
+		{"invalid query", func() *http.Request { return httptest.NewRequest(http.MethodGet, "/pets?limit=lots", nil) },
+			http.StatusBadRequest, false},
+		{"invalid body", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader(`{"age":3}`))
+			r.Header.Set("Content-Type", "application/json")
+			return r
+		}, http.StatusBadRequest, false},
+		{"missing credentials", func() *http.Request { return httptest.NewRequest(http.MethodGet, "/secure", nil) },
+			http.StatusUnauthorized, false},
+		{"path missing from the specification", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/unknown", nil)
+		}, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := upstream.calls.Load()
+			w := serveTestRequest(ws, tt.request())
+			assert.Equal(t, tt.code, w.Code)
+			if tt.forwarded {
+				assert.Equal(t, before+1, upstream.calls.Load())
+				return
+			}
+			assert.Equal(t, before, upstream.calls.Load(), "rejected requests must never reach the API")
+			var wtError shared.WiretapError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &wtError))
+			assert.Equal(t, tt.code, wtError.Status)
+			assert.Equal(t, "Invalid request", wtError.Title)
+		})
+	}
+}
+
+func TestStrictResponses(t *testing.T) {
+	body := `[{"name":"dave"}]`
+	upstream := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Upstream", "yes")
+		_, _ = w.Write([]byte(body))
+	})
+
+	tests := []struct {
+		name       string
+		configured int
+		upstream   string
+		code       int
+		replaced   bool
+	}{
+		{"valid", 0, `[{"name":"dave"}]`, http.StatusOK, false},
+		{"invalid", 0, `[{"age":3}]`, http.StatusBadGateway, true},
+		{"invalid, with a configured code", http.StatusInternalServerError, `[{"age":3}]`,
+			http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body = tt.upstream
+			ws := newTestService(t, petsSpec, &shared.WiretapConfiguration{StrictResponses: true,
+				StrictResponseCode: tt.configured}, upstream)
+			w := serveTestRequest(ws, httptest.NewRequest(http.MethodGet, "/pets", nil))
+			assert.Equal(t, tt.code, w.Code)
+			if !tt.replaced {
+				assert.Equal(t, tt.upstream, w.Body.String())
+				assert.Equal(t, "yes", w.Header().Get("X-Upstream"))
+				return
+			}
+			assert.NotEqual(t, tt.upstream, w.Body.String())
+			assert.Empty(t, w.Header().Get("X-Upstream"))
+			var wtError shared.WiretapError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &wtError))
+			assert.Equal(t, tt.code, wtError.Status)
+			assert.Equal(t, "Invalid response", wtError.Title)
+			assert.Contains(t, wtError.Detail, "The API responded with a 200")
+		})
+	}
+}
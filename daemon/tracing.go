@@ -0,0 +1,36 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/tracing"
+)
+
+// startSpan starts a span for work done on a request, as a child of the span of the request. Without tracing
+// configured, there is no span.
+func (ws *WiretapService) startSpan(request *http.Request, name string, kind tracing.SpanKind) *tracing.Span {
+	if ws.tracer == nil || request == nil {
+		return nil
+	}
+	_, span := ws.tracer.Start(request.Context(), name, kind)
+	return span
+}
+
+// startUpstreamSpan starts a span for a call to the upstream API, and passes the trace on to the API.
+func (ws *WiretapService) startUpstreamSpan(request *model.Request, apiRequest *http.Request) *tracing.Span {
+	span := ws.startSpan(request.HttpRequest, "wiretap upstream", tracing.SpanKindClient)
+	span.SetAttribute("http.request.method", apiRequest.Method)
+	span.SetAttribute("url.full", ws.redactor().Text(apiRequest.URL.String()))
+	span.Inject(apiRequest.Header)
+	return span
+}
+
+// StopTracing sends any spans that haven't been exported yet.
+func (ws *WiretapService) StopTracing() {
+	ws.tracer.Close()
+}
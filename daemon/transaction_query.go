@@ -0,0 +1,247 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobwas/glob"
+)
+
+// MaxTransactionPageSize is the most transactions a query returns at once.
+const MaxTransactionPageSize = 1000
+
+// TransactionQuery selects captured transactions, every condition that is set has to match. A transaction matches
+// the methods and statuses if it matches any one of them.
+type TransactionQuery struct {
+	Path          glob.Glob
+	Methods       []string
+	Statuses      [][2]int
+	Since         time.Time
+	HasViolations *bool
+	Search        *SearchExpression
+	TransactionPage
+}
+
+// TransactionFilter is what a monitor client sends to only be sent the transactions it's interested in. Search is a
+// search expression, see ParseSearchExpression. Backfill is how many of the most recent matching transactions are
+// sent first, so a client doesn't start with nothing.
+type TransactionFilter struct {
+	Search         string   `json:"search,omitempty"`
+	Path           string   `json:"path,omitempty"`
+	Methods        []string `json:"methods,omitempty"`
+	Statuses       []string `json:"statuses,omitempty"`
+	ViolationsOnly bool     `json:"violationsOnly,omitempty"`
+	Backfill       int      `json:"backfill,omitempty"`
+}
+
+// Compile turns a filter into a query that transactions can be matched with.
+func (tf *TransactionFilter) Compile() (*TransactionQuery, error) {
+	q := &TransactionQuery{}
+	if tf.Search != "" {
+		expression, err := ParseSearchExpression(tf.Search)
+		if err != nil {
+			return nil, err
+		}
+		q.Search = expression
+	}
+	if err := q.setPath(tf.Path); err != nil {
+		return nil, err
+	}
+	if err := q.setMethodsAndStatuses(tf.Methods, tf.Statuses); err != nil {
+		return nil, err
+	}
+	if tf.ViolationsOnly {
+		violationsOnly := true
+		q.HasViolations = &violationsOnly
+	}
+	return q, nil
+}
+
+// ParseTransactionQuery reads a query from URL parameters: path (a glob, e.g. /pets/*), method, status (a code,
+// a class such as 4xx, or a range such as 400-499), since (RFC3339, unix milliseconds or a duration ago, e.g. 5m),
+// hasViolations (true or false), q (a search expression, see ParseSearchExpression), offset and limit. Methods and
+// statuses can be repeated, or separated by commas.
+func ParseTransactionQuery(values url.Values) (*TransactionQuery, error) {
+	q := &TransactionQuery{TransactionPage: TransactionPage{Limit: DefaultTransactionPageSize}}
+	if search := values.Get("q"); search != "" {
+		expression, err := ParseSearchExpression(search)
+		if err != nil {
+			return nil, err
+		}
+		q.Search = expression
+	}
+	if err := q.setPath(values.Get("path")); err != nil {
+		return nil, err
+	}
+	if err := q.setMethodsAndStatuses(splitValues(values["method"]), splitValues(values["status"])); err != nil {
+		return nil, err
+	}
+	if since := values.Get("since"); since != "" {
+		t, err := parseSince(since, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		q.Since = t
+	}
+	if hasViolations := values.Get("hasViolations"); hasViolations != "" {
+		b, err := strconv.ParseBool(hasViolations)
+		if err != nil {
+			return nil, fmt.Errorf("hasViolations has to be true or false, not '%s'", hasViolations)
+		}
+		q.HasViolations = &b
+	}
+	if offset := values.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("offset has to be zero or more, not '%s'", offset)
+		}
+		q.Offset = n
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("limit has to be greater than zero, not '%s'", limit)
+		}
+		q.Limit = n
+	}
+	if q.Limit > MaxTransactionPageSize {
+		q.Limit = MaxTransactionPageSize
+	}
+	return q, nil
+}
+
+func (q *TransactionQuery) setPath(path string) error {
+	if path == "" {
+		return nil
+	}
+	g, err := glob.Compile(path, '/')
+	if err != nil {
+		return fmt.Errorf("invalid path glob '%s': %w", path, err)
+	}
+	q.Path = g
+	return nil
+}
+
+func (q *TransactionQuery) setMethodsAndStatuses(methods, statuses []string) error {
+	for _, method := range methods {
+		q.Methods = append(q.Methods, strings.ToUpper(method))
+	}
+	for _, status := range statuses {
+		from, to, err := statusRange(status)
+		if err != nil {
+			return err
+		}
+		q.Statuses = append(q.Statuses, [2]int{from, to})
+	}
+	return nil
+}
+
+// splitValues splits every value by commas, empty values are dropped.
+func splitValues(values []string) []string {
+	var split []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				split = append(split, v)
+			}
+		}
+	}
+	return split
+}
+
+// statusRange reads a status code, class (4xx) or range (400-499) into the codes it covers.
+func statusRange(status string) (int, int, error) {
+	s := strings.ToLower(strings.TrimSpace(status))
+	if len(s) == 3 && s[0] >= '1' && s[0] <= '5' && s[1:] == "xx" {
+		from := int(s[0]-'0') * 100
+		return from, from + 99, nil
+	}
+	first, last, isRange := strings.Cut(s, "-")
+	from, err := strconv.Atoi(first)
+	to := from
+	if err == nil && isRange {
+		to, err = strconv.Atoi(last)
+	}
+	if err != nil || from > to {
+		return 0, 0, fmt.Errorf("status has to be a code (200), a class (4xx) or a range (400-499), not '%s'", status)
+	}
+	return from, to, nil
+}
+
+// parseSince reads a point in time, as RFC3339, unix milliseconds or a duration before now.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	if ms, err := strconv.ParseInt(since, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	if d, err := time.ParseDuration(since); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("since has to be RFC3339, unix milliseconds or a duration (5m), not '%s'", since)
+}
+
+// Match checks if a transaction is selected by the query.
+func (q *TransactionQuery) Match(transaction *HttpTransaction) bool {
+	req := transaction.Request
+	if req == nil {
+		return false
+	}
+	if q.Path != nil && !q.Path.Match(req.Path) {
+		return false
+	}
+	if len(q.Methods) > 0 && !slices.Contains(q.Methods, strings.ToUpper(req.Method)) {
+		return false
+	}
+	if len(q.Statuses) > 0 {
+		if transaction.Response == nil {
+			return false
+		}
+		code := transaction.Response.StatusCode
+		if !slices.ContainsFunc(q.Statuses, func(r [2]int) bool { return code >= r[0] && code <= r[1] }) {
+			return false
+		}
+	}
+	if !q.Since.IsZero() && req.Timestamp < q.Since.UnixMilli() {
+		return false
+	}
+	if q.HasViolations != nil {
+		violations := len(transaction.RequestValidation)+len(transaction.ResponseValidation) > 0
+		if violations != *q.HasViolations {
+			return false
+		}
+	}
+	if q.Search != nil && !q.Search.Match(transaction) {
+		return false
+	}
+	return true
+}
+
+// Apply selects the transactions that match the query, and returns the page asked for. Total is the number of
+// transactions that match.
+func (q *TransactionQuery) Apply(transactions []*HttpTransaction) *TransactionPageResponse {
+	var matched []*HttpTransaction
+	for _, transaction := range transactions {
+		if q.Match(transaction) {
+			matched = append(matched, transaction)
+		}
+	}
+	page := &TransactionPageResponse{Total: len(matched), Offset: q.Offset, Transactions: []*HttpTransaction{}}
+	if q.Offset < len(matched) {
+		to := q.Offset + q.Limit
+		if to > len(matched) {
+			to = len(matched)
+		}
+		page.Transactions = matched[q.Offset:to]
+	}
+	return page
+}
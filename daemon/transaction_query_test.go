@@ -0,0 +1,97 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionQuery(t *testing.T) {
+	now := time.Now()
+	transaction := func(id, method, path string, status int, ago time.Duration, violations int) *HttpTransaction {
+		tx := &HttpTransaction{
+			Id:       id,
+			Request:  &HttpRequest{Method: method, Path: path, Timestamp: now.Add(-ago).UnixMilli()},
+			Response: &HttpResponse{StatusCode: status},
+		}
+		for i := 0; i < violations; i++ {
+			tx.ResponseValidation = append(tx.ResponseValidation, &shared.Violation{})
+		}
+		return tx
+	}
+	transactions := []*HttpTransaction{
+		transaction("1", "GET", "/pets/1", 200, time.Hour, 0),
+		transaction("2", "POST", "/pets", 422, 10*time.Minute, 2),
+		transaction("3", "GET", "/pets/2", 404, time.Minute, 1),
+		transaction("4", "GET", "/toys/1", 500, time.Second, 0),
+		{Id: "5", Request: &HttpRequest{Method: "GET", Path: "/pets/3", Timestamp: now.UnixMilli()}},
+	}
+	ids := func(query string) []string {
+		values, _ := url.ParseQuery(query)
+		q, err := ParseTransactionQuery(values)
+		require.NoError(t, err)
+		var found []string
+		for _, tx := range q.Apply(transactions).Transactions {
+			found = append(found, tx.Id)
+		}
+		return found
+	}
+
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, ids(""))
+	assert.Equal(t, []string{"1", "3", "5"}, ids("path=/pets/*"))
+	assert.Equal(t, []string{"2"}, ids("method=post"))
+	assert.Equal(t, []string{"2", "3"}, ids("status=4xx"))
+	assert.Equal(t, []string{"2", "3", "4"}, ids("status=404-599"))
+	assert.Equal(t, []string{"3"}, ids("status=404-421"))
+	assert.Equal(t, []string{"4"}, ids("status=500"))
+	assert.Equal(t, []string{"3", "4", "5"}, ids("since=5m"))
+	assert.Equal(t, []string{"2", "3"}, ids("hasViolations=true"))
+	assert.Equal(t, []string{"3"}, ids("hasViolations=true&path=/pets/*"))
+	assert.Equal(t, []string{"2", "3"}, ids("offset=1&limit=2"))
+	assert.Empty(t, ids("offset=10"))
+
+	values, _ := url.ParseQuery("path=/pets/*&limit=1")
+	q, _ := ParseTransactionQuery(values)
+	assert.Equal(t, 3, q.Apply(transactions).Total)
+
+	for _, bad := range []string{"status=abc", "status=500-400", "since=yesterday", "hasViolations=maybe", "limit=0", "offset=-1"} {
+		values, _ := url.ParseQuery(bad)
+		_, err := ParseTransactionQuery(values)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestTransactionFilter_Compile(t *testing.T) {
+	q, err := (&TransactionFilter{Path: "/pets/**", Methods: []string{"get", "delete"},
+		Statuses: []string{"2xx", "404"}, ViolationsOnly: true}).Compile()
+	require.NoError(t, err)
+
+	violation := []*shared.Violation{{}}
+	assert.True(t, q.Match(&HttpTransaction{Request: &HttpRequest{Method: "GET", Path: "/pets/1/toys"},
+		Response: &HttpResponse{StatusCode: 404}, RequestValidation: violation}))
+	assert.False(t, q.Match(&HttpTransaction{Request: &HttpRequest{Method: "POST", Path: "/pets/1"},
+		Response: &HttpResponse{StatusCode: 200}, RequestValidation: violation}))
+	assert.False(t, q.Match(&HttpTransaction{Request: &HttpRequest{Method: "GET", Path: "/pets/1"},
+		Response: &HttpResponse{StatusCode: 500}, ResponseValidation: violation}))
+	assert.False(t, q.Match(&HttpTransaction{Request: &HttpRequest{Method: "GET", Path: "/pets/1"},
+		Response: &HttpResponse{StatusCode: 200}}))
+
+	// a status can't be matched until there is a response.
+	assert.False(t, q.Match(&HttpTransaction{Request: &HttpRequest{Method: "GET", Path: "/pets/1"},
+		RequestValidation: violation}))
+
+	empty, err := (&TransactionFilter{}).Compile()
+	require.NoError(t, err)
+	assert.True(t, empty.Match(&HttpTransaction{Request: &HttpRequest{Method: "GET", Path: "/"}}))
+
+	_, err = (&TransactionFilter{Path: "[unclosed"}).Compile()
+	assert.Error(t, err)
+}
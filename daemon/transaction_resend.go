@@ -0,0 +1,187 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+)
+
+// ResendTransactionRequest re-sends a captured request, with any changes made to it.
+const ResendTransactionRequest = "resend-transaction"
+
+// ResendTransaction is a captured transaction to send again, and the changes to make to its request. Path replaces
+// the path (and query) of the request, headers are set (or removed, with an empty value), and Target sends the
+// request to another API (e.g. https://staging.example.com) instead of where wiretap would send it.
+type ResendTransaction struct {
+	Id      string            `json:"id"`
+	Method  string            `json:"method,omitempty"`
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    *string           `json:"body,omitempty"`
+	Target  string            `json:"target,omitempty"`
+}
+
+// ResendResponse is the response to a re-sent request. Id is the new transaction the request was captured as,
+// ResendOf is the transaction that was re-sent.
+type ResendResponse struct {
+	Id         string            `json:"id"`
+	ResendOf   string            `json:"resendOf"`
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+// resendKey holds the re-sent transaction (and the target, if there is one) in the context of a re-sent request.
+type resendKey struct{}
+
+type resendContext struct {
+	id     string
+	target *url.URL
+}
+
+// hopHeaders are set again when a request is sent, they are never copied from a captured request.
+var hopHeaders = []string{"Host", "Content-Length", "Connection", "Transfer-Encoding", "Accept-Encoding"}
+
+// Resend sends a captured request again, with any changes made to it. The request goes through everything a request
+// sent to wiretap does (validation, mocking, delays and so on), and is captured as a new transaction, linked to the
+// one it was re-sent from. Captured headers that were redacted are sent redacted, unless they are changed.
+func (ws *WiretapService) Resend(ctx context.Context, resend *ResendTransaction) (*ResendResponse, error) {
+	transactions, err := ws.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	var captured *HttpRequest
+	for _, transaction := range transactions {
+		if transaction.Id == resend.Id {
+			captured = transaction.Request
+			break
+		}
+	}
+	if captured == nil {
+		return nil, fmt.Errorf("no request has been captured with id '%s'", resend.Id)
+	}
+
+	r, err := resendRequest(ctx, captured, resend)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := uuid.NewUUID()
+	recorder := httptest.NewRecorder()
+	ws.HandleHttpRequest(&model.Request{Id: &id, HttpRequest: r, HttpResponseWriter: recorder})
+
+	response := &ResendResponse{
+		Id:         id.String(),
+		ResendOf:   resend.Id,
+		StatusCode: recorder.Code,
+		Headers:    make(map[string]string),
+		Body:       recorder.Body.String(),
+	}
+	for k := range recorder.Header() {
+		response.Headers[k] = recorder.Header().Get(k)
+	}
+	return response, nil
+}
+
+// resendRequest rebuilds a captured request, as it was sent to wiretap, with the changes to make to it.
+func resendRequest(ctx context.Context, captured *HttpRequest, resend *ResendTransaction) (*http.Request, error) {
+	method := captured.Method
+	if resend.Method != "" {
+		method = strings.ToUpper(resend.Method)
+	}
+	path := captured.OriginalPath
+	if path == "" {
+		path = captured.Path
+	}
+	if captured.Query != "" {
+		path += "?" + captured.Query
+	}
+	if resend.Path != "" {
+		path = resend.Path
+	}
+	body := captured.Body
+	if resend.Body != nil {
+		body = *resend.Body
+	}
+
+	resendCtx := &resendContext{id: resend.Id}
+	if resend.Target != "" {
+		target, err := url.Parse(resend.Target)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return nil, fmt.Errorf("target has to be an http or https URL, not '%s'", resend.Target)
+		}
+		resendCtx.target = target
+	}
+
+	r, err := http.NewRequestWithContext(context.WithValue(ctx, resendKey{}, resendCtx), method, path,
+		io.NopCloser(bytes.NewBufferString(body)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build request: %w", err)
+	}
+	r.RequestURI = r.URL.RequestURI()
+	r.RemoteAddr = "127.0.0.1:0"
+	for k, v := range captured.Headers {
+		r.Header.Set(k, fmt.Sprint(v))
+	}
+	for _, h := range hopHeaders {
+		r.Header.Del(h)
+	}
+	for k, v := range resend.Headers {
+		if v == "" {
+			r.Header.Del(k)
+		} else {
+			r.Header.Set(k, v)
+		}
+	}
+	if host := r.Header.Get("Host"); host != "" {
+		r.Host = host
+		r.Header.Del("Host")
+	}
+	return r, nil
+}
+
+// resendOf is the transaction a request is re-sending, if it is re-sending one.
+func resendOf(r *http.Request) string {
+	if rc, ok := r.Context().Value(resendKey{}).(*resendContext); ok {
+		return rc.id
+	}
+	return ""
+}
+
+// resendTarget is where a re-sent request has been asked to be sent, instead of where wiretap would send it.
+func resendTarget(r *http.Request) (protocol, host, port, basePath string, ok bool) {
+	rc, isResend := r.Context().Value(resendKey{}).(*resendContext)
+	if !isResend || rc.target == nil {
+		return "", "", "", "", false
+	}
+	return rc.target.Scheme, rc.target.Hostname(), rc.target.Port(), strings.TrimSuffix(rc.target.Path, "/"), true
+}
+
+// resendTransaction re-sends a captured request for the monitor, it's sent the response.
+func (ws *WiretapService) resendTransaction(request *model.Request, core service.FabricServiceCore) {
+	var resend ResendTransaction
+	if dl, ok := request.Payload.(map[string]interface{}); ok {
+		_ = mapstructure.Decode(dl, &resend)
+	}
+	go func() {
+		response, err := ws.Resend(context.Background(), &resend)
+		if err != nil {
+			core.SendErrorResponse(request, 400, err.Error())
+			return
+		}
+		core.SendResponse(request, response)
+	}()
+}
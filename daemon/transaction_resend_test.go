@@ -0,0 +1,49 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResendRequest(t *testing.T) {
+	captured := &HttpRequest{Method: "POST", Path: "/v1/pets", OriginalPath: "/pets", Query: "limit=3",
+		Body: `{"name":"dave"}`, Headers: map[string]any{"X-Test": "one", "Content-Length": "15", "Accept": "*/*"}}
+
+	r, err := resendRequest(context.Background(), captured, &ResendTransaction{Id: "abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "POST", r.Method)
+	assert.Equal(t, "/pets?limit=3", r.URL.String())
+	assert.Equal(t, "one", r.Header.Get("X-Test"))
+	assert.Empty(t, r.Header.Get("Content-Length"))
+	body, _ := io.ReadAll(r.Body)
+	assert.Equal(t, `{"name":"dave"}`, string(body))
+	assert.Equal(t, "abc", resendOf(r))
+	_, _, _, _, ok := resendTarget(r)
+	assert.False(t, ok)
+
+	changed := `{"name":"quobix"}`
+	r, err = resendRequest(context.Background(), captured, &ResendTransaction{Id: "abc", Method: "put",
+		Path: "/pets/1", Headers: map[string]string{"X-Test": "two", "Accept": "", "Host": "api.example.com"},
+		Body: &changed, Target: "https://staging.example.com:8443/api/"})
+	assert.NoError(t, err)
+	assert.Equal(t, "PUT", r.Method)
+	assert.Equal(t, "/pets/1", r.URL.String())
+	assert.Equal(t, "two", r.Header.Get("X-Test"))
+	assert.Empty(t, r.Header.Get("Accept"))
+	assert.Equal(t, "api.example.com", r.Host)
+	body, _ = io.ReadAll(r.Body)
+	assert.Equal(t, changed, string(body))
+	protocol, host, port, basePath, ok := resendTarget(r)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"https", "staging.example.com", "8443", "/api"}, []string{protocol, host, port, basePath})
+
+	_, err = resendRequest(context.Background(), captured, &ResendTransaction{Id: "abc", Target: "ftp://nope"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,315 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gobwas/glob"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// SearchTransactionsRequest searches the captured transactions with a search expression.
+const SearchTransactionsRequest = "search-transactions"
+
+// TransactionSearch is the payload of a search-transactions request.
+type TransactionSearch struct {
+	Query           string `json:"query"`
+	TransactionPage `mapstructure:",squash"`
+}
+
+// searchFields are the fields a search expression can match, anything else is searched for as text.
+var searchFields = []string{"id", "method", "path", "host", "query", "label", "status", "latency", "header",
+	"body", "violation", "violations"}
+
+// numericFields are compared as numbers.
+var numericFields = []string{"status", "latency", "violations"}
+
+// searchTerm is a single condition of a search expression.
+type searchTerm struct {
+	field  string
+	op     string // ':' matches (a glob, or a number), '~' contains, or a comparison: '>', '>=', '<', '<='.
+	value  string
+	negate bool
+	glob   glob.Glob
+	from   float64
+	to     float64
+}
+
+// SearchExpression is a parsed search, every term has to match. Terms are written as field:value, field~"text" or
+// field:>=number, prefixed with '-' to exclude what they match. Words without a field are searched for in the path
+// and the bodies of transactions.
+//
+//	method:POST path:/users/* status:>=500 body~"quota"
+type SearchExpression struct {
+	terms []*searchTerm
+}
+
+// ParseSearchExpression reads a search expression. Fields are: id, method, path, host, query, label and header
+// (globs, or text with ~), status (a code, class, range or comparison), latency (upstream milliseconds), body
+// (request or response), violation (text in a violation message) and violations (true, false or a count).
+func ParseSearchExpression(expression string) (*SearchExpression, error) {
+	tokens, err := tokenizeSearch(expression)
+	if err != nil {
+		return nil, err
+	}
+	search := &SearchExpression{}
+	for _, token := range tokens {
+		term, tErr := parseSearchTerm(token)
+		if tErr != nil {
+			return nil, tErr
+		}
+		search.terms = append(search.terms, term)
+	}
+	return search, nil
+}
+
+// tokenizeSearch splits an expression by whitespace, text in double quotes is kept together (with \" for a quote).
+func tokenizeSearch(expression string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	quoted, inToken := false, false
+	for i := 0; i < len(expression); i++ {
+		c := expression[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(expression):
+			i++
+			token.WriteByte(expression[i])
+		case c == '"':
+			quoted = !quoted
+			inToken = true
+		case !quoted && (c == ' ' || c == '\t' || c == '\n'):
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteByte(c)
+			inToken = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("search has an unclosed quote: %s", expression)
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+func parseSearchTerm(token string) (*searchTerm, error) {
+	term := &searchTerm{op: "~"}
+	if strings.HasPrefix(token, "-") && len(token) > 1 {
+		term.negate = true
+		token = token[1:]
+	}
+	if i := strings.IndexAny(token, ":~<>"); i > 0 && slices.Contains(searchFields, strings.ToLower(token[:i])) {
+		term.field = strings.ToLower(token[:i])
+		term.op, term.value = string(token[i]), token[i+1:]
+		if term.op == ":" {
+			for _, op := range []string{">=", "<=", ">", "<"} {
+				if v, ok := strings.CutPrefix(term.value, op); ok {
+					term.op, term.value = op, v
+					break
+				}
+			}
+		} else if term.op != "~" {
+			if v, ok := strings.CutPrefix(term.value, "="); ok {
+				term.op, term.value = term.op+"=", v
+			}
+		}
+	} else {
+		term.value = token
+	}
+	if term.value == "" {
+		return nil, fmt.Errorf("search term '%s' has nothing to match", token)
+	}
+
+	numeric := slices.Contains(numericFields, term.field)
+	switch {
+	case term.op == "~":
+		if numeric {
+			return nil, fmt.Errorf("%s can't be searched for text, compare it instead (e.g. %s:>=1)", term.field, term.field)
+		}
+		term.value = strings.ToLower(term.value)
+	case numeric:
+		return term, term.parseNumber()
+	case term.op != ":":
+		return nil, fmt.Errorf("%s can't be compared, only status, latency and violations can", term.field)
+	case term.field == "method":
+		term.value = strings.ToUpper(term.value)
+	case term.field == "body" || term.field == "violation":
+		term.op, term.value = "~", strings.ToLower(term.value)
+	default:
+		var separators []rune
+		if term.field == "path" {
+			separators = append(separators, '/')
+		}
+		g, err := glob.Compile(term.value, separators...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob in search term '%s': %w", token, err)
+		}
+		term.glob = g
+	}
+	return term, nil
+}
+
+// parseNumber reads the value of a numeric term, status also accepts a class (4xx) or range (400-499), and
+// violations also accepts true or false.
+func (st *searchTerm) parseNumber() error {
+	if st.op == ":" {
+		if st.field == "status" {
+			from, to, err := statusRange(st.value)
+			st.from, st.to = float64(from), float64(to)
+			return err
+		}
+		if st.field == "violations" {
+			if b, err := strconv.ParseBool(st.value); err == nil {
+				st.op, st.from = ">=", 1
+				if !b {
+					st.op, st.from = "<", 1
+				}
+				return nil
+			}
+		}
+	}
+	n, err := strconv.ParseFloat(st.value, 64)
+	if err != nil {
+		return fmt.Errorf("%s has to be compared with a number, not '%s'", st.field, st.value)
+	}
+	st.from, st.to = n, n
+	return nil
+}
+
+// Match checks if a transaction matches every term of the search.
+func (se *SearchExpression) Match(transaction *HttpTransaction) bool {
+	for _, term := range se.terms {
+		if term.match(transaction) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (st *searchTerm) match(transaction *HttpTransaction) bool {
+	req, resp := transaction.Request, transaction.Response
+	if req == nil {
+		req = &HttpRequest{}
+	}
+	switch st.field {
+	case "status":
+		return resp != nil && st.compare(float64(resp.StatusCode))
+	case "latency":
+		return resp != nil && resp.Latency > 0 && st.compare(resp.Latency)
+	case "violations":
+		return st.compare(float64(len(transaction.RequestValidation) + len(transaction.ResponseValidation)))
+	case "method":
+		return strings.EqualFold(req.Method, st.value)
+	case "header":
+		return st.matchHeaders(req.Headers) || (resp != nil && st.matchHeaders(resp.Headers))
+	case "violation":
+		for _, violations := range [][]*shared.Violation{transaction.RequestValidation, transaction.ResponseValidation} {
+			for _, violation := range violations {
+				if violation != nil && violation.ValidationError != nil &&
+					(st.matchText(violation.Message) || st.matchText(violation.Reason)) {
+					return true
+				}
+			}
+		}
+		return false
+	case "body":
+		return st.matchText(req.Body) || (resp != nil && st.matchText(resp.Body))
+	case "":
+		return st.matchText(req.Path) || st.matchText(req.Body) || (resp != nil && st.matchText(resp.Body))
+	}
+	var value string
+	switch st.field {
+	case "id":
+		value = transaction.Id
+	case "path":
+		value = req.Path
+	case "host":
+		value = req.Host
+	case "query":
+		value = req.Query
+	case "label":
+		value = req.Label
+	}
+	return st.matchText(value)
+}
+
+// compare checks a number against the term, a match (':') is within the range the term covers.
+func (st *searchTerm) compare(n float64) bool {
+	switch st.op {
+	case ">":
+		return n > st.from
+	case ">=":
+		return n >= st.from
+	case "<":
+		return n < st.from
+	case "<=":
+		return n <= st.from
+	}
+	return n >= st.from && n <= st.to
+}
+
+// matchText checks if text matches the glob of the term, or contains its value (ignoring case).
+func (st *searchTerm) matchText(text string) bool {
+	if st.glob != nil {
+		return st.glob.Match(text)
+	}
+	return text != "" && strings.Contains(strings.ToLower(text), st.value)
+}
+
+// matchHeaders checks header values, and names as 'name: value' so a header can be searched for by both.
+func (st *searchTerm) matchHeaders(headers map[string]any) bool {
+	for name, value := range headers {
+		if st.matchText(fmt.Sprintf("%s: %v", name, value)) || st.matchText(fmt.Sprint(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchTransactions evaluates a query over the captured transactions, those in the transaction store if there is
+// one, otherwise those in memory.
+func (ws *WiretapService) SearchTransactions(query *TransactionQuery) (*TransactionPageResponse, error) {
+	transactions, err := ws.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	return query.Apply(transactions), nil
+}
+
+// searchTransactions sends a page of the transactions matching a search to the monitor.
+func (ws *WiretapService) searchTransactions(request *model.Request, core service.FabricServiceCore) {
+	var search TransactionSearch
+	if dl, ok := request.Payload.(map[string]interface{}); ok {
+		_ = mapstructure.Decode(dl, &search)
+	}
+	expression, err := ParseSearchExpression(search.Query)
+	if err != nil {
+		core.SendErrorResponse(request, 400, err.Error())
+		return
+	}
+	query := &TransactionQuery{Search: expression, TransactionPage: search.TransactionPage}
+	if query.Limit <= 0 {
+		query.Limit = DefaultTransactionPageSize
+	}
+	query.Limit = min(query.Limit, MaxTransactionPageSize)
+	page, err := ws.SearchTransactions(query)
+	if err != nil {
+		core.SendErrorResponse(request, 500, err.Error())
+		return
+	}
+	core.SendResponse(request, page)
+}
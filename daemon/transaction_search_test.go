@@ -0,0 +1,59 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTransactions() []*HttpTransaction {
+	return []*HttpTransaction{
+		{Id: "1", Request: &HttpRequest{Method: "POST", Path: "/users/1", Body: `{"name":"dave"}`},
+			Response: &HttpResponse{StatusCode: 503, Body: `{"error":"Quota exceeded"}`, Latency: 250}},
+		{Id: "2", Request: &HttpRequest{Method: "POST", Path: "/users/2",
+			Headers: map[string]any{"Content-Type": "application/json"}},
+			Response: &HttpResponse{StatusCode: 201, Latency: 20}},
+		{Id: "3", Request: &HttpRequest{Method: "GET", Path: "/users/1/pets"},
+			Response: &HttpResponse{StatusCode: 500, Body: "quota"},
+			ResponseValidation: []*shared.Violation{{ValidationError: &errors.ValidationError{
+				Message: "GET / 500 operation response content type 'text/plain' does not exist"}}}},
+		{Id: "4", Request: &HttpRequest{Method: "DELETE", Path: "/pets/1"}},
+	}
+}
+
+func searchIds(t *testing.T, expression string) []string {
+	search, err := ParseSearchExpression(expression)
+	assert.NoError(t, err)
+	ids := []string{}
+	for _, transaction := range searchTransactions() {
+		if search.Match(transaction) {
+			ids = append(ids, transaction.Id)
+		}
+	}
+	return ids
+}
+
+func TestParseSearchExpression(t *testing.T) {
+	assert.Equal(t, []string{"1"}, searchIds(t, `method:POST path:/users/* status:>=500 body~"quota"`))
+	assert.Equal(t, []string{"1", "2", "3", "4"}, searchIds(t, ""))
+	assert.Equal(t, []string{"1", "3"}, searchIds(t, "status:5xx"))
+	assert.Equal(t, []string{"2"}, searchIds(t, "status<500"))
+	assert.Equal(t, []string{"2"}, searchIds(t, "method:post -status:500-599"))
+	assert.Equal(t, []string{"1", "3"}, searchIds(t, "QUOTA"))
+	assert.Equal(t, []string{"1"}, searchIds(t, "latency:>100"))
+	assert.Equal(t, []string{"2"}, searchIds(t, `header:"Content-Type: application/*"`))
+	assert.Equal(t, []string{"3"}, searchIds(t, `violations:true violation~"content type"`))
+	assert.Equal(t, []string{"1", "2", "4"}, searchIds(t, "violations:0"))
+	assert.Equal(t, []string{"4"}, searchIds(t, "path:/pets/*"))
+
+	for _, bad := range []string{`body~"quota`, "status:lots", "path:>1", "latency~fast", "method:"} {
+		_, err := ParseSearchExpression(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+	"github.com/pb33f/wiretap/store"
+)
+
+// GetTransactionsRequest pages through the transactions kept in the transaction store.
+const GetTransactionsRequest = "get-transactions"
+
+// DefaultTransactionPageSize is the number of transactions in a page, if no limit is asked for.
+const DefaultTransactionPageSize = 100
+
+// TransactionPage is the payload of a get-transactions request.
+type TransactionPage struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// TransactionPageResponse is a page of stored transactions, total is the number of transactions stored.
+type TransactionPageResponse struct {
+	Total        int                `json:"total"`
+	Offset       int                `json:"offset"`
+	Transactions []*HttpTransaction `json:"transactions"`
+}
+
+// transactionPersistence keeps completed transactions in a store on disk. A request and its response are
+// validated separately, whichever is stored second is merged into the transaction stored by the first.
+type transactionPersistence struct {
+	store *store.Store
+	lock  sync.Mutex
+}
+
+func (tp *transactionPersistence) merge(transaction *HttpTransaction) error {
+	tp.lock.Lock()
+	defer tp.lock.Unlock()
+	var stored HttpTransaction
+	found, err := tp.store.Get(transaction.Id, &stored)
+	if err != nil || !found {
+		return tp.store.Put(transaction.Id, transaction)
+	}
+	mergeTransaction(&stored, transaction)
+	return tp.store.Put(transaction.Id, &stored)
+}
+
+// mergeTransaction copies the half of a transaction that has been validated into the half stored before it.
+func mergeTransaction(stored, transaction *HttpTransaction) {
+	if transaction.Request != nil {
+		stored.Request = transaction.Request
+		stored.RequestValidation = transaction.RequestValidation
+	}
+	if transaction.Response != nil {
+		stored.Response = transaction.Response
+		stored.ResponseValidation = transaction.ResponseValidation
+	}
+}
+
+// keepTransaction keeps a transaction (or the request or response half of one) in memory, for reports and exports.
+func (ws *WiretapService) keepTransaction(transaction *HttpTransaction) {
+	if transaction == nil || transaction.Id == "" || !ws.capturing(transaction.Id) {
+		return
+	}
+	transaction = ws.redactTransaction(transaction)
+	ws.transactionLock.Lock()
+	completed := false
+	if kept, ok := ws.transactionStore.Get(transaction.Id); ok {
+		if existing, k := kept.(*HttpTransaction); k {
+			merged := *existing
+			mergeTransaction(&merged, transaction)
+			transaction = &merged
+			completed = merged.Request != nil && merged.Response != nil
+		}
+	}
+	ws.transactionStore.Put(transaction.Id, transaction, nil)
+	if ws.config.MaxTransactions > 0 || ws.config.MaxCaptureMemoryMB > 0 {
+		ws.evictTransactions(ws.retention.retain(transaction.Id, transactionSize(transaction),
+			ws.config.MaxTransactions, ws.config.MaxCaptureMemoryMB*1024*1024))
+	}
+	ws.transactionLock.Unlock()
+
+	if completed {
+		for _, hook := range ws.transactionHooks {
+			hook(transaction)
+		}
+	}
+}
+
+// OnTransaction registers a listener that is called with every transaction once both its request and its response
+// have been validated.
+func (ws *WiretapService) OnTransaction(listener func(transaction *HttpTransaction)) {
+	ws.transactionHooks = append(ws.transactionHooks, listener)
+}
+
+// persistTransaction stores a transaction (or the request or response half of one), if there is a store.
+func (ws *WiretapService) persistTransaction(transaction *HttpTransaction) {
+	if ws.persistence == nil || transaction == nil || transaction.Id == "" || !ws.capturing(transaction.Id) {
+		return
+	}
+	if err := ws.persistence.merge(ws.redactTransaction(transaction)); err != nil {
+		ws.config.Logger.Warn("[wiretap] unable to store transaction", "id", transaction.Id, "error", err.Error())
+	}
+}
+
+// StoredTransactions reads every transaction in the transaction store, false is returned if there isn't one.
+func (ws *WiretapService) StoredTransactions() ([]*HttpTransaction, bool, error) {
+	if ws.persistence == nil {
+		return nil, false, nil
+	}
+	transactions, err := ReadTransactions(ws.persistence.store)
+	return transactions, true, err
+}
+
+// Transactions returns every captured transaction, from the transaction store if there is one, otherwise from
+// memory, in the order they were captured.
+func (ws *WiretapService) Transactions() ([]*HttpTransaction, error) {
+	if stored, ok, err := ws.StoredTransactions(); ok {
+		return stored, err
+	}
+	values := ws.transactionStore.AllValues()
+	transactions := make([]*HttpTransaction, 0, len(values))
+	for _, value := range values {
+		if transaction, ok := value.(*HttpTransaction); ok {
+			transactions = append(transactions, transaction)
+		}
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return captured(transactions[i]) < captured(transactions[j])
+	})
+	return transactions, nil
+}
+
+// captured is when a transaction was captured, its request (or response, if the request hasn't been kept).
+func captured(transaction *HttpTransaction) int64 {
+	if transaction.Request != nil {
+		return transaction.Request.Timestamp
+	}
+	if transaction.Response != nil {
+		return transaction.Response.Timestamp
+	}
+	return 0
+}
+
+// TransactionCount is the number of transactions kept in memory.
+func (ws *WiretapService) TransactionCount() int {
+	return len(ws.transactionStore.AllValues())
+}
+
+// ReadTransactions reads every transaction in a store, in the order they were captured.
+func ReadTransactions(s *store.Store) ([]*HttpTransaction, error) {
+	var transactions []*HttpTransaction
+	err := s.Each(func(_ string, value json.RawMessage) error {
+		var transaction HttpTransaction
+		if err := json.Unmarshal(value, &transaction); err != nil {
+			return err
+		}
+		transactions = append(transactions, &transaction)
+		return nil
+	})
+	return transactions, err
+}
+
+// CloseTransactionStore compacts and closes the transaction store.
+func (ws *WiretapService) CloseTransactionStore() {
+	if ws.persistence == nil {
+		return
+	}
+	if err := ws.persistence.store.Close(); err != nil {
+		ws.config.Logger.Warn("[wiretap] unable to close transaction store", "error", err.Error())
+	}
+}
+
+// getTransactions sends a page of stored transactions to the monitor.
+func (ws *WiretapService) getTransactions(request *model.Request, core service.FabricServiceCore) {
+	if ws.persistence == nil {
+		core.SendErrorResponse(request, 404, "No transaction store has been configured")
+		return
+	}
+	var page TransactionPage
+	if dl, ok := request.Payload.(map[string]interface{}); ok {
+		_ = mapstructure.Decode(dl, &page)
+	}
+	if page.Limit <= 0 {
+		page.Limit = DefaultTransactionPageSize
+	}
+	values, err := ws.persistence.store.Page(page.Offset, page.Limit)
+	if err != nil {
+		core.SendErrorResponse(request, 500, err.Error())
+		return
+	}
+	response := &TransactionPageResponse{
+		Total:        ws.persistence.store.Count(),
+		Offset:       page.Offset,
+		Transactions: make([]*HttpTransaction, 0, len(values)),
+	}
+	for _, value := range values {
+		var transaction HttpTransaction
+		if json.Unmarshal(value, &transaction) == nil {
+			response.Transactions = append(response.Transactions, &transaction)
+		}
+	}
+	core.SendResponse(request, response)
+}
@@ -0,0 +1,229 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+	"github.com/pb33f/ranch/stompserver"
+)
+
+// commands to filter the transactions a monitor client is sent.
+const (
+	SubscribeTransactionsRequest   = "subscribe-transactions"
+	UnsubscribeTransactionsRequest = "unsubscribe-transactions"
+)
+
+// subscriptionQueueSize is how many transactions can wait to be sent to a client, before they are dropped. It's also
+// the most transactions a client can ask to be backfilled.
+const subscriptionQueueSize = 512
+
+// transactionSubscription is a monitor client that only wants some transactions. Matching transactions are sent
+// as responses to the request it subscribed with, to that client alone. Every client has a queue of its own, so a
+// slow client falls behind (and loses transactions once its queue is full) without holding up anyone else.
+// Clients that aren't connected to the broker (streams) have no request, they read their queue themselves.
+type transactionSubscription struct {
+	client  string
+	request *model.Request
+	query   *TransactionQuery
+	queue   chan *HttpTransaction
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+func newTransactionSubscription(request *model.Request, query *TransactionQuery) *transactionSubscription {
+	ts := &transactionSubscription{
+		request: request,
+		query:   query,
+		queue:   make(chan *HttpTransaction, subscriptionQueueSize),
+		done:    make(chan struct{}),
+	}
+	if request != nil && request.BrokerDestination != nil {
+		ts.client = request.BrokerDestination.ConnectionId
+	}
+	return ts
+}
+
+// send delivers queued transactions to the client, until the subscription is closed.
+func (ts *transactionSubscription) send(core service.FabricServiceCore) {
+	for {
+		select {
+		case transaction := <-ts.queue:
+			core.SendResponse(ts.request, transaction)
+		case <-ts.done:
+			return
+		}
+	}
+}
+
+// enqueue queues a transaction for the client, false is returned if the queue is full and it was dropped.
+func (ts *transactionSubscription) enqueue(transaction *HttpTransaction) bool {
+	select {
+	case ts.queue <- transaction:
+		return true
+	default:
+		ts.dropped.Add(1)
+		return false
+	}
+}
+
+// subscribeTransactions filters the transactions sent to a monitor client, it replaces any filter the client already
+// has. Clients that filter stop listening to the broadcast channel, and listen for responses to their subscription.
+func (ws *WiretapService) subscribeTransactions(request *model.Request, core service.FabricServiceCore) {
+	if request.BrokerDestination == nil || request.BrokerDestination.ConnectionId == "" {
+		core.SendErrorResponse(request, 400, "Only monitor clients can subscribe to transactions")
+		return
+	}
+	var filter TransactionFilter
+	if dl, ok := request.Payload.(map[string]interface{}); ok {
+		_ = mapstructure.Decode(dl, &filter)
+	}
+	query, err := filter.Compile()
+	if err != nil {
+		core.SendErrorResponse(request, 400, err.Error())
+		return
+	}
+	subscription := newTransactionSubscription(request, query)
+	ws.backfillSubscription(subscription, filter.Backfill)
+	ws.addSubscription(subscription)
+	go subscription.send(core)
+}
+
+// StreamTransactions subscribes to the transactions matching a filter, for clients that aren't connected to the
+// broker, like the SSE feed. Transactions are delivered on the channel (which is never closed) until the context
+// is done. The client is a name for the subscriber, used when it falls behind.
+func (ws *WiretapService) StreamTransactions(ctx context.Context, client string,
+	filter *TransactionFilter) (<-chan *HttpTransaction, error) {
+	query, err := filter.Compile()
+	if err != nil {
+		return nil, err
+	}
+	id, _ := uuid.NewUUID()
+	subscription := newTransactionSubscription(nil, query)
+	subscription.client = client + " (" + id.String() + ")"
+	ws.backfillSubscription(subscription, filter.Backfill)
+	ws.addSubscription(subscription)
+	go func() {
+		<-ctx.Done()
+		ws.forgetSubscription(subscription.client)
+	}()
+	return subscription.queue, nil
+}
+
+// backfillSubscription queues the most recent transactions that match a subscription, so they go first.
+func (ws *WiretapService) backfillSubscription(subscription *transactionSubscription, backfill int) {
+	if backfill <= 0 {
+		return
+	}
+	transactions, err := ws.Transactions()
+	if err != nil {
+		return
+	}
+	var matched []*HttpTransaction
+	for _, transaction := range transactions {
+		if subscription.query.Match(transaction) {
+			matched = append(matched, transaction)
+		}
+	}
+	backfill = min(backfill, subscriptionQueueSize)
+	if len(matched) > backfill {
+		matched = matched[len(matched)-backfill:]
+	}
+	for _, transaction := range matched {
+		subscription.enqueue(ws.summarizeBodies(transaction))
+	}
+}
+
+// addSubscription starts publishing transactions to a subscription, replacing any the client already has.
+func (ws *WiretapService) addSubscription(subscription *transactionSubscription) {
+	ws.subscriptionLock.Lock()
+	defer ws.subscriptionLock.Unlock()
+	if ws.subscriptions == nil {
+		ws.subscriptions = make(map[string]*transactionSubscription)
+	}
+	if previous, ok := ws.subscriptions[subscription.client]; ok {
+		close(previous.done)
+	}
+	ws.subscriptions[subscription.client] = subscription
+}
+
+// unsubscribeTransactions removes the filter of a monitor client, it goes back to the broadcast channel.
+func (ws *WiretapService) unsubscribeTransactions(request *model.Request, core service.FabricServiceCore) {
+	if request.BrokerDestination != nil {
+		ws.forgetSubscription(request.BrokerDestination.ConnectionId)
+	}
+	core.SendResponse(request, &TransactionFilter{})
+}
+
+func (ws *WiretapService) forgetSubscription(connectionId string) {
+	ws.subscriptionLock.Lock()
+	if subscription, ok := ws.subscriptions[connectionId]; ok {
+		close(subscription.done)
+		delete(ws.subscriptions, connectionId)
+	}
+	ws.subscriptionLock.Unlock()
+}
+
+// forgetClosedSubscriptions drops the filters of monitor clients once they disconnect.
+func (ws *WiretapService) forgetClosedSubscriptions(eventBus bus.EventBus) {
+	handler, err := eventBus.ListenStream(bus.STOMP_SESSION_NOTIFY_CHANNEL)
+	if err != nil {
+		return
+	}
+	handler.Handle(func(message *model.Message) {
+		if event, ok := message.Payload.(*bus.StompSessionEvent); ok && event.EventType == stompserver.ConnectionClosed {
+			ws.forgetSubscription(event.Id)
+		}
+	}, func(err error) {})
+}
+
+// publishTransaction sends a transaction being broadcast (the request or the response half of one) to every
+// subscribed client whose filter it matches. Filters are matched against everything known about the transaction
+// so far, so a response is matched with the request it belongs to.
+func (ws *WiretapService) publishTransaction(request *model.Request, transaction *HttpTransaction) {
+	ws.subscriptionLock.RLock()
+	if len(ws.subscriptions) == 0 {
+		ws.subscriptionLock.RUnlock()
+		return
+	}
+	subscriptions := make([]*transactionSubscription, 0, len(ws.subscriptions))
+	for _, subscription := range ws.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	ws.subscriptionLock.RUnlock()
+
+	known := transaction
+	if kept, ok := ws.transactionStore.Get(transaction.Id); ok {
+		if k, isTransaction := kept.(*HttpTransaction); isTransaction {
+			merged := *k
+			mergeTransaction(&merged, transaction)
+			known = &merged
+		}
+	}
+	if known.Request == nil && request != nil && request.HttpRequest != nil {
+		// a response that isn't kept, like a mocked one, still belongs to its request.
+		withRequest := *known
+		withRequest.Request = &HttpRequest{
+			Method: request.HttpRequest.Method,
+			URL:    request.HttpRequest.URL.String(),
+			Path:   request.HttpRequest.URL.Path,
+			Query:  request.HttpRequest.URL.RawQuery,
+		}
+		known = &withRequest
+	}
+	summarized := ws.summarizeBodies(known)
+	for _, subscription := range subscriptions {
+		if subscription.query.Match(known) && !subscription.enqueue(summarized) && subscription.dropped.Load() == 1 {
+			ws.config.Logger.Warn("[wiretap] monitor client is too slow, transactions sent to it are being dropped",
+				"client", subscription.client)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingCore keeps the responses sent to monitor clients.
+type recordingCore struct {
+	service.FabricServiceCore
+	lock sync.Mutex
+	sent []*HttpTransaction
+}
+
+func (rc *recordingCore) SendResponse(_ *model.Request, payload interface{}) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	rc.sent = append(rc.sent, payload.(*HttpTransaction))
+}
+
+func (rc *recordingCore) paths() []string {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	var paths []string
+	for _, transaction := range rc.sent {
+		paths = append(paths, transaction.Request.Path)
+	}
+	return paths
+}
+
+func TestSubscribeTransactions_Backfill(t *testing.T) {
+	ws := NewWiretapService(nil, &shared.WiretapConfiguration{})
+	for i, path := range []string{"/pets/1", "/toys/1", "/pets/2", "/pets/3"} {
+		ws.keepTransaction(&HttpTransaction{Id: path, Request: &HttpRequest{Method: "GET", Path: path,
+			Timestamp: int64(i + 1)}})
+	}
+
+	core := &recordingCore{}
+	ws.serviceCore = core
+	request := &model.Request{BrokerDestination: &model.BrokerDestinationConfig{ConnectionId: "tab"},
+		Payload: map[string]interface{}{"path": "/pets/*", "backfill": float64(2)}}
+	ws.subscribeTransactions(request, core)
+	assert.Eventually(t, func() bool { return len(core.paths()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"/pets/2", "/pets/3"}, core.paths())
+
+	ws.publishTransaction(nil, &HttpTransaction{Id: "a", Request: &HttpRequest{Method: "GET", Path: "/toys/2"}})
+	ws.publishTransaction(nil, &HttpTransaction{Id: "b", Request: &HttpRequest{Method: "GET", Path: "/pets/4"}})
+	assert.Eventually(t, func() bool { return len(core.paths()) == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "/pets/4", core.paths()[2])
+
+	ws.forgetSubscription("tab")
+	assert.Empty(t, ws.subscriptions)
+}
+
+func TestTransactionSubscription_SlowClient(t *testing.T) {
+	subscription := newTransactionSubscription(&model.Request{}, &TransactionQuery{})
+	for i := 0; i < subscriptionQueueSize; i++ {
+		assert.True(t, subscription.enqueue(&HttpTransaction{}))
+	}
+	// nothing is sending, so the queue is full and the next transactions are dropped rather than waited on.
+	assert.False(t, subscription.enqueue(&HttpTransaction{}))
+	assert.False(t, subscription.enqueue(&HttpTransaction{}))
+	assert.Equal(t, int64(2), subscription.dropped.Load())
+}
+
+func TestStreamTransactions(t *testing.T) {
+	ws := NewWiretapService(nil, &shared.WiretapConfiguration{})
+	ws.keepTransaction(&HttpTransaction{Id: "old", Request: &HttpRequest{Method: "POST", Path: "/pets"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := ws.StreamTransactions(ctx, "test", &TransactionFilter{Methods: []string{"POST"}, Backfill: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, "old", (<-stream).Id)
+
+	ws.publishTransaction(nil, &HttpTransaction{Id: "a", Request: &HttpRequest{Method: "GET", Path: "/pets"}})
+	ws.publishTransaction(nil, &HttpTransaction{Id: "b", Request: &HttpRequest{Method: "POST", Path: "/pets"}})
+	select {
+	case transaction := <-stream:
+		assert.Equal(t, "b", transaction.Id)
+	case <-time.After(time.Second):
+		t.Fatal("the transaction wasn't streamed")
+	}
+
+	// the subscription goes once the stream is done with.
+	cancel()
+	assert.Eventually(t, func() bool {
+		ws.subscriptionLock.RLock()
+		defer ws.subscriptionLock.RUnlock()
+		return len(ws.subscriptions) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = ws.StreamTransactions(context.Background(), "test", &TransactionFilter{Path: "/pets/["})
+	assert.Error(t, err)
+}
@@ -0,0 +1,33 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+// upstreamH2CTransport calls targets over HTTP/2 without TLS (h2c, with prior knowledge), for APIs that refuse
+// HTTP/1.1 (gRPC gateways). There is no way to ask a target if it speaks h2c, so it has to be switched on.
+var upstreamH2CTransport = &http2.Transport{
+	AllowHTTP: true,
+	DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+		return dialUpstream(ctx, addr, false)
+	},
+}
+
+// writeResponseTrailers sends the trailers of an upstream response to the client, after the body. Trailers carry
+// the status of gRPC calls, a response without them isn't complete.
+func writeResponseTrailers(w http.ResponseWriter, response *http.Response) {
+	for k, v := range response.Trailer {
+		for _, value := range v {
+			w.Header().Add(http.TrailerPrefix+k, value)
+		}
+	}
+}
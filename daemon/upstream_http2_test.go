@@ -0,0 +1,62 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestUpstreamHTTP2(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Grpc-Status")
+		_, _ = w.Write([]byte(r.Proto))
+		w.Header().Set("Grpc-Status", "0")
+	})
+
+	// over TLS, HTTP/2 is used when the target offers it.
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	r, _ := http.NewRequest(http.MethodGet, tlsServer.URL, nil)
+	resp, err := newWiretapTransport().RoundTrip(r)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "HTTP/2.0", string(body))
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+
+	// trailers reach the client after the body.
+	w := httptest.NewRecorder()
+	_, _ = w.Write(body)
+	writeResponseTrailers(w, resp)
+	assert.Equal(t, "0", w.Result().Trailer.Get("Grpc-Status"))
+
+	// without TLS, HTTP/1.1 is used unless h2c is switched on.
+	server := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer server.Close()
+
+	r, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err = newWiretapTransport().RoundTrip(r)
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, "HTTP/1.1", string(body))
+
+	tr := newWiretapTransport()
+	tr.h2c = true
+	r, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err = tr.RoundTrip(r)
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, "HTTP/2.0", string(body))
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+}
@@ -0,0 +1,71 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// clientCertTarget is a target that requires mutual TLS, with the certificate presented to it and the transport
+// used to call it.
+type clientCertTarget struct {
+	certificates []tls.Certificate
+	transport    *http.Transport
+}
+
+// clientCertTargets are the targets that require mutual TLS, keyed by host and port, or just host.
+var clientCertTargets map[string]*clientCertTarget
+
+// useClientCertificates loads the client certificates of the targets that require mutual TLS. Every target gets a
+// transport of its own, so connections presenting one certificate are never reused to call another target.
+func useClientCertificates(certificates map[string]*shared.WiretapClientCertConfig) error {
+	targets := make(map[string]*clientCertTarget, len(certificates))
+	for target, cc := range certificates {
+		certificate, err := cc.LoadCertificate()
+		if err != nil {
+			return fmt.Errorf("unable to load client certificate for '%s': %s", target, err.Error())
+		}
+		tr := buildUpstreamTransport([]tls.Certificate{certificate})
+		tr.Proxy = func(r *http.Request) (*url.URL, error) {
+			if upstreamTransport.Proxy == nil {
+				return nil, nil
+			}
+			return upstreamTransport.Proxy(r)
+		}
+		targets[strings.ToLower(target)] = &clientCertTarget{
+			certificates: []tls.Certificate{certificate},
+			transport:    tr,
+		}
+	}
+	clientCertTargets = targets
+	return nil
+}
+
+// findClientCertTarget returns the target requiring mutual TLS a URL is for, nil if there isn't one. A target
+// configured with a port is preferred over one configured with just the host.
+func findClientCertTarget(u *url.URL) *clientCertTarget {
+	if len(clientCertTargets) == 0 {
+		return nil
+	}
+	hostname := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" || u.Scheme == "wss" {
+			port = "443"
+		}
+	}
+	if target, ok := clientCertTargets[net.JoinHostPort(hostname, port)]; ok {
+		return target
+	}
+	return clientCertTargets[hostname]
+}
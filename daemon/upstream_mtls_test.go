@@ -0,0 +1,92 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamMutualTLS(t *testing.T) {
+	// the client certificate, with its key encrypted.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "wiretap"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	encrypted, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", keyDER, []byte("s3cret"), x509.PEMCipherAES256)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(encrypted), 0600))
+
+	// the target only answers clients that present a certificate.
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	defer func() { clientCertTargets = nil }()
+
+	r, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	_, err = newWiretapTransport().RoundTrip(r)
+	assert.Error(t, err)
+
+	certificates := map[string]*shared.WiretapClientCertConfig{
+		target.Host: {ClientCert: certFile, ClientKey: keyFile, PassphraseEnv: "WIRETAP_TEST_PASSPHRASE"},
+	}
+	assert.Error(t, useClientCertificates(certificates))
+
+	t.Setenv("WIRETAP_TEST_PASSPHRASE", "wrong")
+	assert.Error(t, useClientCertificates(certificates))
+
+	t.Setenv("WIRETAP_TEST_PASSPHRASE", "s3cret")
+	require.NoError(t, useClientCertificates(certificates))
+
+	r, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := newWiretapTransport().RoundTrip(r)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "wiretap", string(body))
+
+	// targets are matched by host and port, or just the host.
+	assert.NotNil(t, findClientCertTarget(target))
+	assert.Nil(t, findClientCertTarget(&url.URL{Scheme: "https", Host: "127.0.0.1"}))
+	require.NoError(t, useClientCertificates(map[string]*shared.WiretapClientCertConfig{
+		"127.0.0.1": {ClientCert: certFile, ClientKey: keyFile, PassphraseEnv: "WIRETAP_TEST_PASSPHRASE"},
+	}))
+	assert.NotNil(t, findClientCertTarget(&url.URL{Scheme: "wss", Host: "127.0.0.1"}))
+}
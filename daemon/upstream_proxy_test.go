@@ -0,0 +1,100 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamProxy(t *testing.T) {
+	// the target answers whatever is written to it with 'pong'.
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer target.Close()
+	go func() {
+		for {
+			conn, aErr := target.Accept()
+			if aErr != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 4)
+				if _, rErr := io.ReadFull(conn, buf); rErr == nil {
+					_, _ = conn.Write([]byte("pong"))
+				}
+			}()
+		}
+	}()
+
+	// the proxy only lets authenticated clients through, to the target whatever they ask for.
+	connected := make(chan string, 1)
+	forward, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer forward.Close()
+	go func() {
+		conn, aErr := forward.Accept()
+		if aErr != nil {
+			return
+		}
+		defer conn.Close()
+		r, rErr := http.ReadRequest(bufio.NewReader(conn))
+		if rErr != nil {
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != "Basic d2lyZTp0YXA=" {
+			_, _ = conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+			return
+		}
+		connected <- r.Method + " " + r.Host
+		upstream, dErr := net.Dial("tcp", target.Addr().String())
+		if dErr != nil {
+			return
+		}
+		defer upstream.Close()
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+		go func() { _, _ = io.Copy(upstream, conn) }()
+		_, _ = io.Copy(conn, upstream)
+	}()
+
+	original := upstreamTransport.Proxy
+	defer func() { upstreamTransport.Proxy = original }()
+
+	assert.Error(t, useUpstreamProxy(&shared.WiretapUpstreamProxyConfig{URL: "ftp://proxy:21"}))
+	require.NoError(t, useUpstreamProxy(&shared.WiretapUpstreamProxyConfig{
+		URL:      "http://" + forward.Addr().String(),
+		Username: "wire",
+		Password: "tap",
+		NoProxy:  []string{"direct.example"},
+	}))
+
+	conn, err := dialUpstream(context.Background(), "pets.example:80", false)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "CONNECT pets.example:80", <-connected)
+	_, _ = conn.Write([]byte("ping"))
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	assert.NoError(t, err)
+	assert.Equal(t, "pong", string(reply))
+
+	// hosts that are called directly never reach the proxy.
+	proxyURL, err := upstreamTransport.Proxy(httptest.NewRequest(http.MethodGet, "http://direct.example/pets", nil))
+	assert.NoError(t, err)
+	assert.Nil(t, proxyURL)
+	proxyURL, err = upstreamTransport.Proxy(httptest.NewRequest(http.MethodGet, "https://pets.example/pets", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "wire", proxyURL.User.Username())
+}
@@ -4,20 +4,101 @@
 package daemon
 
 import (
+	"fmt"
 	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
 	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/tracing"
+	"github.com/pb33f/wiretap/validation"
 	"net/http"
+	"strings"
 )
 
+// newValidator creates a validator for a specification. Results of request validation are cached, so identical
+// requests are only validated once, unless the cache has been turned off. The variables in the server URLs of the
+// specification are resolved first, so operations are found behind base paths that use them.
+func newValidator(doc *v3.Document, config *shared.WiretapConfiguration) validation.HttpValidator {
+	resolveServers(doc, config.ServerVariables)
+	validator := validation.NewHttpValidator(doc)
+	if !config.NoValidationCache {
+		validator = validation.NewCachingValidator(validator, doc, config.ValidationCacheSize)
+	}
+	// unknown parameters are checked outside the cache, it doesn't tell requests apart by every header.
+	if config.StrictParameters {
+		allowed := append([]string(nil), config.AllowedHeaders...)
+		if config.Headers != nil {
+			for name := range config.Headers.InjectHeaders {
+				allowed = append(allowed, name)
+			}
+		}
+		validator = validation.NewUnknownParameterValidator(validator, doc, allowed)
+	}
+	return validator
+}
+
+// logViolations logs every violation reported for a request, as an event of its own.
+func (ws *WiretapService) logViolations(request *http.Request, violations []*errors.ValidationError) {
+	for _, v := range ws.classifyViolations(violations) {
+		ws.config.Logger.Info("[wiretap] violation", "method", request.Method,
+			"url", ws.redactor().Text(request.URL.String()), "type", v.ValidationType, "subType", v.ValidationSubType,
+			"severity", v.Severity, "message", v.Message, "specLine", v.SpecLine, "specColumn", v.SpecCol)
+	}
+}
+
+// inlineValidation checks if requests and responses are validated before traffic is allowed to continue.
+func (ws *WiretapService) inlineValidation() bool {
+	return strings.EqualFold(ws.config.ValidationMode, shared.ValidationModeInline)
+}
+
+// singletonHeaders can only be sent once in a response, clients pick one at random (or fail) if there are more.
+var singletonHeaders = []string{"Content-Type", "Content-Length"}
+
+// checkDuplicateHeaders looks for singleton headers that have been sent more than once by the upstream. net/http
+// merges identical Content-Length headers (and refuses different ones) before the response is seen, so the raw header
+// lines read from the upstream are counted when they are known, the parsed headers otherwise.
+func checkDuplicateHeaders(response *http.Response, rawHeaders []*HttpHeader) []*errors.ValidationError {
+	if response == nil {
+		return nil
+	}
+	var violations []*errors.ValidationError
+	for _, h := range singletonHeaders {
+		var values []string
+		if len(rawHeaders) > 0 {
+			for _, raw := range rawHeaders {
+				if strings.EqualFold(raw.Name, h) {
+					values = append(values, raw.Value)
+				}
+			}
+		} else {
+			values = response.Header.Values(h)
+		}
+		if len(values) > 1 {
+			violations = append(violations, &errors.ValidationError{
+				Message:           fmt.Sprintf("Duplicate '%s' header found in response", h),
+				Reason:            fmt.Sprintf("The response contains %d '%s' headers (%s), only a single value is allowed", len(values), h, strings.Join(values, ", ")),
+				ValidationType:    "response",
+				ValidationSubType: "header",
+				HowToFix:          fmt.Sprintf("Ensure the service only sends a single '%s' header", h),
+			})
+		}
+	}
+	return violations
+}
+
 func (ws *WiretapService) ValidateResponse(
 	request *model.Request,
 	returnedResponse *http.Response) []*errors.ValidationError {
+	return ws.validateUpstreamResponse(request, returnedResponse, nil)
+}
 
-	var validationErrors []*errors.ValidationError
+// validateUpstreamResponse validates a response, with the raw header lines the upstream sent it with (if known).
+func (ws *WiretapService) validateUpstreamResponse(request *model.Request, returnedResponse *http.Response,
+	rawHeaders []*HttpHeader) []*errors.ValidationError {
 
-	if ws.document != nil && ws.docModel != nil {
-		_, validationErrors = ws.validator.ValidateHttpResponse(request.HttpRequest, returnedResponse)
-	}
+	span := ws.startSpan(request.HttpRequest, "wiretap validate response", tracing.SpanKindInternal)
+	defer span.End()
+	validationErrors := ws.validateResponse(request, returnedResponse, rawHeaders)
 
 	// wipe out any path not found errors, they are not relevant to the response.
 	var cleanedErrors []*errors.ValidationError
@@ -26,32 +107,117 @@ func (ws *WiretapService) ValidateResponse(
 			cleanedErrors = append(cleanedErrors, validationErrors[x])
 		}
 	}
+	span.SetAttribute("wiretap.violations", len(cleanedErrors))
+	ws.recordResponse(request, returnedResponse, cleanedErrors, true)
+	return validationErrors
+}
+
+// validateResponse checks a response against the contract and any custom rules.
+func (ws *WiretapService) validateResponse(request *model.Request,
+	returnedResponse *http.Response, rawHeaders []*HttpHeader) []*errors.ValidationError {
+
+	var validationErrors []*errors.ValidationError
 
-	transaction := BuildResponse(request, returnedResponse)
+	// paths can be configured to skip response validation.
+	if _, validateResponse := ws.validationScope(request.HttpRequest); validateResponse {
+		if validator := ws.locateValidator(request.HttpRequest); validator != nil {
+			_, validationErrors = validator.ValidateHttpResponse(request.HttpRequest, returnedResponse)
+
+			// responses that are valid today, may not be against the candidate specification.
+			if len(validationErrors) == 0 && ws.usesCandidate(validator) {
+				validationErrors = ws.validateCandidateResponse(request.HttpRequest, returnedResponse)
+			}
+		}
+
+		// duplicated singleton headers are a violation, regardless of the contract.
+		validationErrors = append(validationErrors, checkDuplicateHeaders(returnedResponse, rawHeaders)...)
+
+		// custom rules, on top of the contract.
+		if len(ws.customValidators) > 0 {
+			validationErrors = append(validationErrors, validation.RunCustomResponseValidators(ws.customValidators,
+				request.HttpRequest, returnedResponse, validation.MatchOperation(request.HttpRequest, ws.currentDocModel()))...)
+		}
+	}
+	return ws.redactViolations(ws.suppressViolations(request.HttpRequest, validationErrors))
+}
+
+// recordResponse keeps and broadcasts a response, with the violations found. Responses that were not validated
+// (the validation queue was full) are kept all the same, they just don't count towards results.
+func (ws *WiretapService) recordResponse(request *model.Request, returnedResponse *http.Response,
+	cleanedErrors []*errors.ValidationError, validated bool) {
+
+	ws.currentCoverage().RecordResponse(request.HttpRequest, returnedResponse)
+	transaction := ws.buildResponse(request, returnedResponse)
+	transaction.Response.Latency = ws.takeLatency(request)
 	if len(cleanedErrors) > 0 {
-		transaction.ResponseValidation = cleanedErrors
+		transaction.ResponseValidation = ws.classifyViolations(cleanedErrors)
+	}
+	ws.keepTransaction(transaction)
+	ws.persistTransaction(transaction)
+	if !validated {
+		ws.broadcastResponse(request, returnedResponse)
+		return
 	}
-	ws.transactionStore.Put(request.Id.String(), transaction, nil)
 
 	if len(cleanedErrors) > 0 {
-		ws.streamChan <- cleanedErrors
-		ws.broadcastResponseValidationErrors(request, returnedResponse, cleanedErrors)
+		ws.tallyViolations(cleanedErrors)
+	}
+	ws.recordOperationResult(request.HttpRequest, cleanedErrors, false)
+
+	// repeats of violations already reported in the aggregation window are only counted.
+	if reported := ws.aggregateViolations(request.HttpRequest, cleanedErrors); len(reported) > 0 {
+		ws.streamReported(request.HttpRequest, reported)
+		ws.logViolations(request.HttpRequest, reported)
+		ws.notifyViolations(request.HttpRequest, reported)
+		ws.reportIssues(request.HttpRequest, reported, &HttpTransaction{
+			Request: &HttpRequest{
+				Method: request.HttpRequest.Method,
+				URL:    request.HttpRequest.URL.String(),
+				Path:   request.HttpRequest.URL.Path,
+				Query:  request.HttpRequest.URL.RawQuery,
+			},
+			Response: transaction.Response,
+		})
+		ws.broadcastResponseValidationErrors(request, returnedResponse, reported)
 	} else {
 		ws.broadcastResponse(request, returnedResponse)
 	}
-	return validationErrors
 }
 
 func (ws *WiretapService) ValidateRequest(
 	modelRequest *model.Request,
 	httpRequest *http.Request) []*errors.ValidationError {
 
+	span := ws.startSpan(modelRequest.HttpRequest, "wiretap validate request", tracing.SpanKindInternal)
+	defer span.End()
+	cleanedErrors := ws.validateRequest(modelRequest, httpRequest)
+	span.SetAttribute("wiretap.violations", len(cleanedErrors))
+	ws.recordRequest(modelRequest, httpRequest, cleanedErrors, true)
+	return cleanedErrors
+}
+
+// validateRequest checks a request against the contract and any custom rules. A missing path is only reported once.
+func (ws *WiretapService) validateRequest(modelRequest *model.Request,
+	httpRequest *http.Request) []*errors.ValidationError {
+
 	var validationErrors, cleanedErrors []*errors.ValidationError
+	if validateRequest, _ := ws.validationScope(modelRequest.HttpRequest); validateRequest {
+		if validator := ws.locateValidator(modelRequest.HttpRequest); validator != nil {
+			_, validationErrors = validator.ValidateHttpRequest(httpRequest)
+
+			// requests that are valid today, may not be against the candidate specification.
+			if len(validationErrors) == 0 && ws.usesCandidate(validator) {
+				validationErrors = ws.validateCandidateRequest(httpRequest)
+			}
+		}
 
-	if ws.document != nil && ws.docModel != nil {
-		validator := ws.validator
-		_, validationErrors = validator.ValidateHttpRequest(httpRequest)
+		// custom rules, on top of the contract.
+		if len(ws.customValidators) > 0 {
+			validationErrors = append(validationErrors, validation.RunCustomRequestValidators(ws.customValidators,
+				httpRequest, validation.MatchOperation(httpRequest, ws.currentDocModel()))...)
+		}
 	}
+	validationErrors = ws.redactViolations(ws.suppressViolations(modelRequest.HttpRequest, validationErrors))
 
 	pm := false
 	for i := range validationErrors {
@@ -64,7 +230,15 @@ func (ws *WiretapService) ValidateRequest(
 			cleanedErrors = append(cleanedErrors, validationErrors[i])
 		}
 	}
-	// record results
+	return cleanedErrors
+}
+
+// recordRequest keeps and broadcasts a request, with the violations found. Requests that were not validated
+// (the validation queue was full) are kept all the same, they just don't count towards results.
+func (ws *WiretapService) recordRequest(modelRequest *model.Request, httpRequest *http.Request,
+	cleanedErrors []*errors.ValidationError, validated bool) {
+
+	ws.currentCoverage().RecordRequest(modelRequest.HttpRequest)
 	buildTransConfig := HttpTransactionConfig{
 		OriginalRequest:   modelRequest.HttpRequest,
 		NewRequest:        httpRequest,
@@ -73,17 +247,32 @@ func (ws *WiretapService) ValidateRequest(
 	}
 
 	transaction := BuildHttpTransaction(buildTransConfig)
+	ws.decodeGRPCRequest(transaction, httpRequest)
+	decodeGraphQLRequest(transaction, modelRequest.HttpRequest)
 	if len(cleanedErrors) > 0 {
-		transaction.RequestValidation = cleanedErrors
+		transaction.RequestValidation = ws.classifyViolations(cleanedErrors)
+	}
+	ws.keepTransaction(transaction)
+	ws.persistTransaction(transaction)
+	if !validated {
+		ws.broadcastRequest(modelRequest, transaction)
+		return
 	}
-	ws.transactionStore.Put(modelRequest.Id.String(), modelRequest, nil)
 
-	// broadcast what we found.
 	if len(cleanedErrors) > 0 {
-		ws.streamChan <- cleanedErrors
-		ws.broadcastRequestValidationErrors(modelRequest, cleanedErrors, transaction)
+		ws.tallyViolations(cleanedErrors)
+	}
+	ws.recordOperationResult(modelRequest.HttpRequest, cleanedErrors, true)
+
+	// broadcast what we found, repeats of violations already reported in the aggregation window are only counted.
+	if reported := ws.aggregateViolations(modelRequest.HttpRequest, cleanedErrors); len(reported) > 0 {
+		ws.streamReported(modelRequest.HttpRequest, reported)
+		ws.logViolations(httpRequest, reported)
+		ws.notifyViolations(httpRequest, reported)
+		ws.reportIssues(httpRequest, reported, transaction)
+		ws.broadcastRequestValidationErrors(modelRequest, reported, transaction)
 	} else {
+		transaction.RequestValidation = nil
 		ws.broadcastRequest(modelRequest, transaction)
 	}
-	return cleanedErrors
 }
@@ -0,0 +1,66 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultValidationQueue is how many validations can wait for a worker, before new ones are skipped.
+const DefaultValidationQueue = 1024
+
+// validationPool runs validation in the background, on a fixed number of workers, so traffic is never held up
+// by validation, and a burst of traffic can't start an unbounded number of validations.
+type validationPool struct {
+	jobs    chan func()
+	pending sync.WaitGroup
+	dropped atomic.Int64
+	logger  *slog.Logger
+}
+
+// newValidationPool starts a pool of workers, by default there is one per CPU.
+func newValidationPool(workers, queue int, logger *slog.Logger) *validationPool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if queue <= 0 {
+		queue = DefaultValidationQueue
+	}
+	pool := &validationPool{jobs: make(chan func(), queue), logger: logger}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range pool.jobs {
+				job()
+				pool.pending.Done()
+			}
+		}()
+	}
+	return pool
+}
+
+// submit queues a validation. If the queue is full, the validation is skipped rather than slowing down traffic,
+// and skipped is run in its place (right away), so what was to be validated is still recorded.
+func (p *validationPool) submit(job func(), skipped func()) {
+	if p == nil {
+		go job()
+		return
+	}
+	p.pending.Add(1)
+	select {
+	case p.jobs <- job:
+	default:
+		p.pending.Done()
+		dropped := p.dropped.Add(1)
+		if p.logger != nil {
+			p.logger.Warn("[wiretap] validation queue is full, validation skipped", "dropped", dropped)
+		}
+		if skipped != nil {
+			skipped()
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationPool_Submit(t *testing.T) {
+	pool := newValidationPool(2, 10, nil)
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	count := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		pool.submit(func() {
+			defer wg.Done()
+			lock.Lock()
+			count++
+			lock.Unlock()
+		}, nil)
+	}
+	wg.Wait()
+	assert.Equal(t, 10, count)
+}
+
+func TestValidationPool_Full(t *testing.T) {
+	block := make(chan struct{})
+	pool := newValidationPool(1, 1, nil)
+	pool.submit(func() { <-block }, nil) // taken by the worker.
+	skipped := 0
+	for i := 0; i < 5; i++ {
+		pool.submit(func() {}, func() { skipped++ })
+	}
+	assert.GreaterOrEqual(t, pool.dropped.Load(), int64(3))
+	assert.Equal(t, int(pool.dropped.Load()), skipped)
+	close(block)
+}
+
+func TestValidationPool_FullKeepsTransactions(t *testing.T) {
+	config := &shared.WiretapConfiguration{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ws := NewWiretapService(nil, config)
+	ws.broadcastChan = bus.NewChannel(WiretapBroadcastChan)
+
+	// the worker is busy, and the queue is full.
+	block := make(chan struct{})
+	defer close(block)
+	ws.validationPool = newValidationPool(1, 1, nil)
+	ws.validationPool.submit(func() { <-block }, nil)
+	for len(ws.validationPool.jobs) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ws.validationPool.submit(func() { <-block }, nil)
+
+	id, _ := uuid.NewUUID()
+	httpRequest := httptest.NewRequest(http.MethodGet, "http://localhost/pets", nil)
+	request := &model.Request{Id: &id, HttpRequest: httpRequest}
+	response := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: httpRequest}
+	ws.validationPool.submit(func() { ws.ValidateRequest(request, httpRequest) },
+		func() { ws.recordRequest(request, httpRequest, nil, false) })
+	ws.validationPool.submit(func() { ws.ValidateResponse(request, response) },
+		func() { ws.recordResponse(request, response, nil, false) })
+
+	// neither half was validated, both are kept.
+	assert.Equal(t, int64(2), ws.validationPool.dropped.Load())
+	kept, ok := ws.transactionStore.Get(id.String())
+	require.True(t, ok)
+	transaction := kept.(*HttpTransaction)
+	assert.Equal(t, "/pets", transaction.Request.Path)
+	require.NotNil(t, transaction.Response)
+	assert.Equal(t, http.StatusOK, transaction.Response.StatusCode)
+}
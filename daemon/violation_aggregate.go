@@ -0,0 +1,131 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// aggregatedViolation is a violation seen (at least) once in a window, with every time it was seen since.
+type aggregatedViolation struct {
+	violation *errors.ValidationError
+	count     int
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// violationAggregator collapses identical violations (same operation, rule and field) seen within a window. The
+// first violation of a window is reported as it happens, repeats are only counted, and reported as a single
+// violation (with the count, and when it was first and last seen) when the window closes.
+type violationAggregator struct {
+	window     time.Duration
+	violations map[string]*aggregatedViolation
+	lock       sync.Mutex
+}
+
+func newViolationAggregator(window time.Duration) *violationAggregator {
+	return &violationAggregator{window: window, violations: make(map[string]*aggregatedViolation)}
+}
+
+// add records the violations of a request for an operation, and returns the ones that open a new window.
+func (va *violationAggregator) add(operation string, violations []*errors.ValidationError, now time.Time) []*errors.ValidationError {
+	va.lock.Lock()
+	defer va.lock.Unlock()
+	var fresh []*errors.ValidationError
+	for _, v := range violations {
+		key := violationKey(operation, v)
+		if seen, ok := va.violations[key]; ok && now.Sub(seen.firstSeen) < va.window {
+			seen.count++
+			seen.lastSeen = now
+			continue
+		}
+		va.violations[key] = &aggregatedViolation{violation: v, count: 1, firstSeen: now, lastSeen: now}
+		fresh = append(fresh, v)
+	}
+	return fresh
+}
+
+// expired closes the windows that are over, and returns the violations that were repeated within them.
+func (va *violationAggregator) expired(now time.Time) []*aggregatedViolation {
+	va.lock.Lock()
+	defer va.lock.Unlock()
+	var repeated []*aggregatedViolation
+	for key, seen := range va.violations {
+		if now.Sub(seen.firstSeen) < va.window {
+			continue
+		}
+		delete(va.violations, key)
+		if seen.count > 1 {
+			repeated = append(repeated, seen)
+		}
+	}
+	sort.Slice(repeated, func(i, j int) bool {
+		return repeated[i].firstSeen.Before(repeated[j].firstSeen)
+	})
+	return repeated
+}
+
+// violationKey identifies a violation by the operation, the rule broken and the field that broke it. Schema
+// violations are located by the failing fields, everything else by the message (which names the parameter).
+func violationKey(operation string, v *errors.ValidationError) string {
+	field := v.Message
+	if len(v.SchemaValidationErrors) > 0 {
+		locations := make([]string, 0, len(v.SchemaValidationErrors))
+		for _, failure := range v.SchemaValidationErrors {
+			locations = append(locations, failure.Location)
+		}
+		sort.Strings(locations)
+		field = strings.Join(locations, ",")
+	}
+	return operation + " " + configModel.ViolationRule(v) + " " + field
+}
+
+// aggregateViolations returns the violations of a request that should be reported now, repeats of violations
+// already reported in the aggregation window are only counted. Without a window, everything is reported.
+func (ws *WiretapService) aggregateViolations(request *http.Request, violations []*errors.ValidationError) []*errors.ValidationError {
+	if ws.aggregator == nil || len(violations) == 0 {
+		return violations
+	}
+	operation, _ := validation.LocateOperation(request, ws.currentDocModel())
+	if operation == "" {
+		operation = request.URL.Path
+	}
+	return ws.aggregator.add(request.Method+" "+operation, violations, time.Now())
+}
+
+// flushAggregatedViolations reports the violations repeated in closed windows, every time the window passes.
+func (ws *WiretapService) flushAggregatedViolations() {
+	ticker := time.NewTicker(ws.aggregator.window)
+	go func() {
+		defer ticker.Stop()
+		for now := range ticker.C {
+			repeated := ws.aggregator.expired(now)
+			if len(repeated) == 0 {
+				continue
+			}
+			aggregated := make([]*shared.Violation, 0, len(repeated))
+			for _, seen := range repeated {
+				firstSeen, lastSeen := seen.firstSeen, seen.lastSeen
+				aggregated = append(aggregated, &shared.Violation{
+					ValidationError: seen.violation,
+					Severity:        configModel.ViolationSeverity(seen.violation, ws.config.Severity),
+					Count:           seen.count,
+					FirstSeen:       &firstSeen,
+					LastSeen:        &lastSeen,
+				})
+			}
+			ws.aggregateChan <- aggregated
+		}
+	}()
+}
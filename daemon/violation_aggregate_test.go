@@ -0,0 +1,38 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViolationAggregator(t *testing.T) {
+	missing := &errors.ValidationError{ValidationType: "parameter", ValidationSubType: "query", Message: "Query parameter 'limit' is missing"}
+	invalid := &errors.ValidationError{ValidationType: "parameter", ValidationSubType: "query", Message: "Query parameter 'limit' is not a valid number"}
+
+	va := newViolationAggregator(time.Minute)
+	start := time.Now()
+
+	assert.Len(t, va.add("GET /pets", []*errors.ValidationError{missing}, start), 1)
+	assert.Empty(t, va.add("GET /pets", []*errors.ValidationError{missing}, start.Add(time.Second)))
+	assert.Len(t, va.add("GET /pets", []*errors.ValidationError{missing, invalid}, start.Add(2*time.Second)), 1)
+	assert.Len(t, va.add("POST /pets", []*errors.ValidationError{missing}, start.Add(2*time.Second)), 1)
+
+	assert.Empty(t, va.expired(start.Add(30*time.Second)))
+
+	repeated := va.expired(start.Add(time.Minute))
+	assert.Len(t, repeated, 1)
+	assert.Equal(t, missing, repeated[0].violation)
+	assert.Equal(t, 3, repeated[0].count)
+	assert.Equal(t, start, repeated[0].firstSeen)
+	assert.Equal(t, start.Add(2*time.Second), repeated[0].lastSeen)
+
+	// the window has closed, so the violation is reported again.
+	assert.Len(t, va.add("GET /pets", []*errors.ValidationError{missing}, start.Add(time.Minute)), 1)
+}
@@ -0,0 +1,53 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	configModel "github.com/pb33f/wiretap/config"
+)
+
+// violationTally counts every violation reported, by severity.
+type violationTally struct {
+	counts map[string]int
+	lock   sync.Mutex
+}
+
+// tallyViolations counts violations by their configured severity.
+func (ws *WiretapService) tallyViolations(violations []*errors.ValidationError) {
+	ws.tally.lock.Lock()
+	defer ws.tally.lock.Unlock()
+	if ws.tally.counts == nil {
+		ws.tally.counts = make(map[string]int)
+	}
+	for _, violation := range violations {
+		severity := configModel.ViolationSeverity(violation, ws.config.Severity)
+		ws.tally.counts[severity]++
+		ws.metrics.violations.Inc(severity)
+	}
+}
+
+// ViolationCounts returns the number of violations reported so far, by severity.
+func (ws *WiretapService) ViolationCounts() map[string]int {
+	ws.tally.lock.Lock()
+	defer ws.tally.lock.Unlock()
+	counts := make(map[string]int, len(ws.tally.counts))
+	for severity, count := range ws.tally.counts {
+		counts[severity] = count
+	}
+	return counts
+}
+
+// WaitForValidation blocks until every validation running in the background is done, and returns how many
+// validations were dropped because the queue was full.
+func (ws *WiretapService) WaitForValidation() int64 {
+	if ws.validationPool == nil {
+		return 0
+	}
+	ws.validationPool.pending.Wait()
+	return ws.validationPool.dropped.Load()
+}
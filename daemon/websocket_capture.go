@@ -0,0 +1,122 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pb33f/ranch/model"
+)
+
+// maxCapturedFrame is the most of a websocket message shown in the monitor, the rest of it is cut off.
+const maxCapturedFrame = 64 << 10
+
+// maxCapturedFrames is the most messages of a websocket connection kept, later messages are still relayed.
+const maxCapturedFrames = 1000
+
+// WebSocketFrame is a message relayed over a proxied websocket, captured as part of its upgrade transaction.
+// Binary messages are base64 encoded.
+type WebSocketFrame struct {
+	Timestamp int64  `json:"timestamp"`
+	Direction string `json:"direction"`
+	Type      string `json:"type"`
+	Data      string `json:"data,omitempty"`
+	Encoding  string `json:"encoding,omitempty"`
+	Size      int    `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// frameCapture collects the messages of a proxied websocket, and keeps the monitor up to date with them while
+// the connection is open.
+type frameCapture struct {
+	ws       *WiretapService
+	request  *model.Request
+	lock     sync.Mutex
+	response *http.Response
+	frames   []*WebSocketFrame
+	dirty    bool
+	done     chan struct{}
+}
+
+// captureFrames starts capturing the messages of a proxied websocket, until finish is called.
+func (ws *WiretapService) captureFrames(request *model.Request) *frameCapture {
+	c := &frameCapture{ws: ws, request: request, done: make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(eventBroadcastInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				c.broadcast(false)
+			}
+		}
+	}()
+	return c
+}
+
+// upgraded records the response of the API to the upgrade request.
+func (c *frameCapture) upgraded(response *http.Response) {
+	upgrade := *response
+	upgrade.Body = nil // the connection is the body, it's relayed as messages.
+	c.lock.Lock()
+	c.response, c.dirty = &upgrade, true
+	c.lock.Unlock()
+}
+
+// add records a message, messages past maxCapturedFrames are left out.
+func (c *frameCapture) add(direction string, opcode byte, payload []byte) {
+	frame := &WebSocketFrame{
+		Timestamp: time.Now().UnixMilli(),
+		Direction: direction,
+		Type:      "text",
+		Size:      len(payload),
+	}
+	if len(payload) > maxCapturedFrame {
+		payload, frame.Truncated = payload[:maxCapturedFrame], true
+	}
+	if opcode == wsBinary {
+		frame.Type, frame.Encoding = "binary", "base64"
+		frame.Data = base64.StdEncoding.EncodeToString(payload)
+	} else {
+		frame.Data = string(payload)
+	}
+	c.lock.Lock()
+	if len(c.frames) < maxCapturedFrames {
+		c.frames = append(c.frames, frame)
+		c.dirty = true
+	}
+	c.lock.Unlock()
+}
+
+// broadcast sends the upgrade response, with the messages captured so far, to the monitor. Unless forced, nothing
+// is sent if nothing has changed.
+func (c *frameCapture) broadcast(force bool) *HttpTransaction {
+	c.lock.Lock()
+	if (!c.dirty && !force) || c.response == nil {
+		c.lock.Unlock()
+		return nil
+	}
+	c.dirty = false
+	transaction := BuildResponse(c.request, c.response)
+	transaction.Response.Frames = append([]*WebSocketFrame(nil), c.frames...)
+	c.lock.Unlock()
+
+	c.ws.broadcastFrames(c.request, transaction)
+	return transaction
+}
+
+// finish stops capturing, the transaction is kept with every message captured.
+func (c *frameCapture) finish() {
+	close(c.done)
+	if transaction := c.broadcast(true); transaction != nil {
+		c.ws.keepTransaction(transaction)
+		c.ws.persistTransaction(transaction)
+	}
+}
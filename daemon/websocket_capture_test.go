@@ -0,0 +1,91 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyWebSocketCapturesFrames(t *testing.T) {
+	// the API echoes every message it's sent.
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			kind, message, rErr := conn.ReadMessage()
+			if rErr != nil {
+				return
+			}
+			_ = conn.WriteMessage(kind, message)
+		}
+	}))
+	defer api.Close()
+	target, _ := url.Parse(api.URL)
+
+	config := &shared.WiretapConfiguration{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ws := NewWiretapService(nil, config)
+	ws.broadcastChan = bus.NewChannel(WiretapBroadcastChan)
+	id, _ := uuid.NewUUID()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiRequest := r.Clone(r.Context())
+		apiRequest.URL.Scheme, apiRequest.URL.Host, apiRequest.RequestURI = "http", target.Host, ""
+		assert.NoError(t, ws.proxyWebSocket(&model.Request{Id: &id, HttpRequest: r, HttpResponseWriter: w},
+			config, apiRequest))
+	}))
+	defer proxy.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(proxy.URL, "http", "ws", 1)+"/socket", nil)
+	assert.NoError(t, err)
+	big := strings.Repeat("x", maxCapturedFrame+10)
+	for _, message := range []struct {
+		kind int
+		data string
+	}{{websocket.TextMessage, `{"hello":"there"}`}, {websocket.BinaryMessage, "\x00\x01"}, {websocket.TextMessage, big}} {
+		assert.NoError(t, conn.WriteMessage(message.kind, []byte(message.data)))
+		_, echoed, rErr := conn.ReadMessage()
+		assert.NoError(t, rErr)
+		assert.Equal(t, message.data, string(echoed))
+	}
+	_ = conn.Close()
+
+	var transaction *HttpTransaction
+	assert.Eventually(t, func() bool {
+		kept, ok := ws.transactionStore.Get(id.String())
+		if !ok {
+			return false
+		}
+		transaction = kept.(*HttpTransaction)
+		return transaction.Response != nil && len(transaction.Response.Frames) == 6
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "/socket", transaction.Request.Path)
+	assert.Equal(t, http.StatusSwitchingProtocols, transaction.Response.StatusCode)
+	frames := transaction.Response.Frames
+	assert.Equal(t, "client", frames[0].Direction)
+	assert.Equal(t, `{"hello":"there"}`, frames[0].Data)
+	assert.Equal(t, "server", frames[1].Direction)
+	assert.Equal(t, "binary", frames[2].Type)
+	assert.Equal(t, "AAE=", frames[2].Data)
+	assert.True(t, frames[4].Truncated)
+	assert.Equal(t, maxCapturedFrame+10, frames[4].Size)
+	assert.Len(t, frames[4].Data, maxCapturedFrame)
+}
@@ -0,0 +1,100 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// websocket opcodes, see RFC 6455 section 5.2
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+)
+
+// maxInspectedMessage is the largest websocket message wiretap will inspect, bigger messages are skipped.
+const maxInspectedMessage = 16 << 20
+
+// readWebSocketMessages reads websocket frames from a stream and calls fn with every complete text or binary
+// message. Fragmented messages are reassembled and masked frames are unmasked. Compressed messages, and
+// messages larger than maxInspectedMessage are skipped. Returns when the stream ends or a close frame is read.
+func readWebSocketMessages(r io.Reader, fn func(opcode byte, payload []byte)) error {
+	var message []byte
+	var opcode byte
+	var skip bool
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header[:2]); err != nil {
+			return err
+		}
+		fin := header[0]&0x80 != 0
+		compressed := header[0]&0x40 != 0
+		op := header[0] & 0x0f
+		masked := header[1]&0x80 != 0
+
+		length := uint64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			if _, err := io.ReadFull(r, header[:2]); err != nil {
+				return err
+			}
+			length = uint64(binary.BigEndian.Uint16(header[:2]))
+		case 127:
+			if _, err := io.ReadFull(r, header[:8]); err != nil {
+				return err
+			}
+			length = binary.BigEndian.Uint64(header[:8])
+		}
+
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(r, mask[:]); err != nil {
+				return err
+			}
+		}
+
+		// control frames can arrive in between the fragments of a message, they are never inspected.
+		if op >= wsClose {
+			if op == wsClose {
+				return nil
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if op != wsContinuation {
+			opcode, message, skip = op, nil, compressed
+		}
+		if skip || uint64(len(message))+length > maxInspectedMessage {
+			skip = true
+			if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+				return err
+			}
+		} else {
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return err
+			}
+			if masked {
+				for i := range payload {
+					payload[i] ^= mask[i%4]
+				}
+			}
+			message = append(message, payload...)
+		}
+
+		if fin {
+			if !skip && (opcode == wsText || opcode == wsBinary) {
+				fn(opcode, message)
+			}
+			opcode, message, skip = 0, nil, false
+		}
+	}
+}
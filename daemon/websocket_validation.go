@@ -0,0 +1,111 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/asyncapi"
+)
+
+// inspectClientMessages captures the messages a client sends over a proxied websocket, and validates them against
+// the messages the AsyncAPI channel receives, if there is one. The stream is always read to the end, so the proxy
+// never stalls.
+func (ws *WiretapService) inspectClientMessages(request *model.Request, channel *asyncapi.Channel,
+	capture *frameCapture, r io.Reader) {
+
+	defer io.Copy(io.Discard, r)
+	_ = readWebSocketMessages(r, func(opcode byte, payload []byte) {
+		capture.add(asyncapi.ClientMessage, opcode, payload)
+		if channel != nil && opcode == wsText {
+			ws.reportWebSocketViolations(request, asyncapi.ClientMessage, payload,
+				channel.ValidateClientMessage(payload))
+		}
+	})
+}
+
+// inspectServerMessages captures the messages the API sends over a proxied websocket, and validates them against
+// the messages the AsyncAPI channel sends, if there is one. The stream starts with the response to the upgrade
+// request, which is captured as the response of the transaction.
+func (ws *WiretapService) inspectServerMessages(request *model.Request, apiRequest *http.Request,
+	channel *asyncapi.Channel, capture *frameCapture, r io.Reader) {
+
+	defer io.Copy(io.Discard, r)
+	br := bufio.NewReader(r)
+	resp, err := http.ReadResponse(br, apiRequest)
+	if err != nil {
+		return
+	}
+	capture.upgraded(resp)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return
+	}
+	_ = readWebSocketMessages(br, func(opcode byte, payload []byte) {
+		capture.add(asyncapi.ServerMessage, opcode, payload)
+		if channel != nil && opcode == wsText {
+			ws.reportWebSocketViolations(request, asyncapi.ServerMessage, payload,
+				channel.ValidateServerMessage(payload))
+		}
+	})
+}
+
+// reportWebSocketViolations sends the violations of a websocket message to the monitor, the same way HTTP
+// violations are. Every message is a transaction of its own; client messages are shown as the request body,
+// server messages as the response body.
+func (ws *WiretapService) reportWebSocketViolations(request *model.Request, direction string, payload []byte,
+	violations []*errors.ValidationError) {
+
+	violations = ws.redactViolations(ws.suppressViolations(request.HttpRequest, violations))
+	if len(violations) == 0 {
+		return
+	}
+
+	id, _ := uuid.NewUUID()
+	now := time.Now()
+	transaction := &HttpTransaction{
+		Id: id.String(),
+		Request: &HttpRequest{
+			Timestamp: now.UnixMilli(),
+			URL:       request.HttpRequest.URL.String(),
+			Method:    request.HttpRequest.Method,
+			Host:      request.HttpRequest.Host,
+			Path:      request.HttpRequest.URL.Path,
+			Query:     request.HttpRequest.URL.RawQuery,
+		},
+	}
+	if direction == asyncapi.ClientMessage {
+		transaction.Request.Body = string(payload)
+		transaction.RequestValidation = ws.classifyViolations(violations)
+	} else {
+		transaction.Response = &HttpResponse{
+			Timestamp:  now.UnixMilli(),
+			StatusCode: http.StatusSwitchingProtocols,
+			Body:       string(payload),
+		}
+		transaction.ResponseValidation = ws.classifyViolations(violations)
+	}
+
+	ws.config.Logger.Warn("[wiretap] websocket message failed validation", "url", request.HttpRequest.URL.String(),
+		"direction", direction, "violations", len(violations))
+
+	ws.streamReported(request.HttpRequest, violations)
+	ws.tallyViolations(violations)
+	ws.notifyViolations(request.HttpRequest, violations)
+	ws.reportIssues(request.HttpRequest, violations, transaction)
+	msgId, _ := uuid.NewUUID()
+	ws.broadcastChan.Send(&model.Message{
+		Id:          &msgId,
+		Channel:     WiretapBroadcastChan,
+		Destination: WiretapBroadcastChan,
+		Payload:     ws.redactTransaction(transaction),
+		Direction:   model.ResponseDir,
+	})
+}
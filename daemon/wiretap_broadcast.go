@@ -15,45 +15,63 @@ import (
 
 func (ws *WiretapService) broadcastRequestValidationErrors(request *model.Request,
 	errors []*errors.ValidationError, transaction *HttpTransaction) {
+	if !ws.captureRequest(request) {
+		return
+	}
 	id, _ := uuid.NewUUID()
 	ht := transaction
-	ht.RequestValidation = errors
+	ht.RequestValidation = ws.classifyViolations(errors)
 
+	payload := ws.redactTransaction(ht)
 	ws.broadcastChan.Send(&model.Message{
 		Id:            &id,
 		DestinationId: request.Id,
 		Channel:       WiretapBroadcastChan,
 		Destination:   WiretapBroadcastChan,
-		Payload:       ht,
+		Payload:       ws.summarizeBodies(payload),
 		Direction:     model.ResponseDir,
 	})
+	ws.publishTransaction(request, payload)
 }
 
 func (ws *WiretapService) broadcastRequest(request *model.Request, transaction *HttpTransaction) {
+	if !ws.captureRequest(request) {
+		return
+	}
 	id, _ := uuid.NewUUID()
+	payload := ws.redactTransaction(transaction)
 	ws.broadcastChan.Send(&model.Message{
 		Id:            &id,
 		DestinationId: request.Id,
 		Channel:       WiretapBroadcastChan,
 		Destination:   WiretapBroadcastChan,
-		Payload:       transaction,
+		Payload:       ws.summarizeBodies(payload),
 		Direction:     model.ResponseDir,
 	})
+	ws.publishTransaction(request, payload)
 }
 
 func (ws *WiretapService) broadcastResponse(request *model.Request, response *http.Response) {
+	if !ws.captureRequest(request) {
+		return
+	}
 	id, _ := uuid.NewUUID()
+	payload := ws.redactTransaction(ws.buildResponse(request, response))
 	ws.broadcastChan.Send(&model.Message{
 		Id:            &id,
 		DestinationId: request.Id,
 		Channel:       WiretapBroadcastChan,
 		Destination:   WiretapBroadcastChan,
-		Payload:       BuildResponse(request, response),
+		Payload:       ws.summarizeBodies(payload),
 		Direction:     model.ResponseDir,
 	})
+	ws.publishTransaction(request, payload)
 }
 
 func (ws *WiretapService) broadcastResponseError(request *model.Request, response *http.Response, err error) {
+	if !ws.captureRequest(request) {
+		return
+	}
 	id, _ := uuid.NewUUID()
 	title := "Response Error"
 	code := 500
@@ -71,29 +89,54 @@ func (ws *WiretapService) broadcastResponseError(request *model.Request, respons
 	resp := BuildResponse(request, response)
 	resp.Response.Body = string(respBodyString)
 
+	payload := ws.redactTransaction(resp)
 	ws.broadcastChan.Send(&model.Message{
 		Id:            &id,
 		DestinationId: request.Id,
 		Error:         err,
 		Channel:       WiretapBroadcastChan,
 		Destination:   WiretapBroadcastChan,
-		Payload:       resp,
+		Payload:       ws.summarizeBodies(payload),
 		Direction:     model.ResponseDir,
 	})
+	ws.publishTransaction(request, payload)
 }
 
 func (ws *WiretapService) broadcastResponseValidationErrors(request *model.Request, response *http.Response, errors []*errors.ValidationError) {
+	if !ws.captureRequest(request) {
+		return
+	}
 	id, _ := uuid.NewUUID()
 
-	ht := BuildResponse(request, response)
-	ht.ResponseValidation = errors
+	ht := ws.buildResponse(request, response)
+	ht.ResponseValidation = ws.classifyViolations(errors)
+
+	payload := ws.redactTransaction(ht)
+	ws.broadcastChan.Send(&model.Message{
+		Id:            &id,
+		DestinationId: request.Id,
+		Channel:       WiretapBroadcastChan,
+		Destination:   WiretapBroadcastChan,
+		Payload:       ws.summarizeBodies(payload),
+		Direction:     model.ResponseDir,
+	})
+	ws.publishTransaction(request, payload)
+}
 
+// broadcastFrames sends the transaction of a proxied websocket to the monitor, with the messages relayed so far.
+func (ws *WiretapService) broadcastFrames(request *model.Request, transaction *HttpTransaction) {
+	if !ws.captureRequest(request) {
+		return
+	}
+	id, _ := uuid.NewUUID()
+	payload := ws.redactTransaction(transaction)
 	ws.broadcastChan.Send(&model.Message{
 		Id:            &id,
 		DestinationId: request.Id,
 		Channel:       WiretapBroadcastChan,
 		Destination:   WiretapBroadcastChan,
-		Payload:       ht,
+		Payload:       ws.summarizeBodies(payload),
 		Direction:     model.ResponseDir,
 	})
+	ws.publishTransaction(request, payload)
 }
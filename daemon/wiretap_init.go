@@ -17,8 +17,24 @@ func (ws *WiretapService) Init(core service.FabricServiceCore) error {
 	staticChan := eventBus.GetChannelManager().CreateChannel(WiretapStaticChangeChan)
 	staticChan.SetGalactic(WiretapStaticChangeChan)
 
+	// create spec status channel and set it to galactic
+	specStatusChan := eventBus.GetChannelManager().CreateChannel(WiretapSpecStatusChan)
+	specStatusChan.SetGalactic(WiretapSpecStatusChan)
+
+	// create retention channel and set it to galactic, the monitor is told when transactions are evicted.
+	retentionChan := eventBus.GetChannelManager().CreateChannel(WiretapRetentionChan)
+	retentionChan.SetGalactic(WiretapRetentionChan)
+
+	// create intercept channel and set it to galactic, the monitor is told when requests are held and released.
+	interceptChan := eventBus.GetChannelManager().CreateChannel(WiretapInterceptChan)
+	interceptChan.SetGalactic(WiretapInterceptChan)
+
 	ws.broadcastChan = channel
+	ws.interceptChan = interceptChan
+	ws.retentionChan = retentionChan
+	ws.specStatusChan = specStatusChan
 	ws.bus = eventBus
+	ws.forgetClosedSubscriptions(eventBus)
 	core.SetDefaultJSONHeaders()
 	return nil
 }
@@ -10,11 +10,22 @@ import (
 	"github.com/pb33f/ranch/bus"
 	"github.com/pb33f/ranch/model"
 	"github.com/pb33f/ranch/service"
+	"github.com/pb33f/wiretap/audit"
 	"github.com/pb33f/wiretap/controls"
+	"github.com/pb33f/wiretap/coverage"
+	"github.com/pb33f/wiretap/graphql"
+	"github.com/pb33f/wiretap/grpc"
+	"github.com/pb33f/wiretap/issues"
+	"github.com/pb33f/wiretap/latency"
 	"github.com/pb33f/wiretap/mock"
+	"github.com/pb33f/wiretap/notify"
 	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/store"
+	"github.com/pb33f/wiretap/tracing"
 	"github.com/pb33f/wiretap/validation"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -22,26 +33,67 @@ const (
 	WiretapServiceChan      = "wiretap"
 	WiretapBroadcastChan    = "wiretap-broadcast"
 	WiretapStaticChangeChan = "wiretap-static-change"
+	WiretapSpecStatusChan   = "wiretap-spec-status"
+	WiretapRetentionChan    = "wiretap-retention"
 	IncomingHttpRequest     = "incoming-http-request"
+	ReloadSpecRequest       = "reload-spec"
+	PushSpecRequest         = "push-spec"
 )
 
 type WiretapService struct {
-	transport        *http.Transport
-	document         libopenapi.Document
-	docModel         *v3.Document
-	serviceCore      service.FabricServiceCore
-	broadcastChan    *bus.Channel
-	bus              bus.EventBus
-	controlsStore    bus.BusStore
-	transactionStore bus.BusStore
-	config           *shared.WiretapConfiguration
-	fs               http.Handler
-	mockEngine       *mock.ResponseMockEngine
-	validator        validation.HttpValidator
-	stream           bool
-	streamChan       chan []*errors.ValidationError
-	streamViolations []*errors.ValidationError
-	reportFile       string
+	transport          *http.Transport
+	document           libopenapi.Document
+	docModel           *v3.Document
+	serviceCore        service.FabricServiceCore
+	broadcastChan      *bus.Channel
+	bus                bus.EventBus
+	controlsStore      bus.BusStore
+	transactionStore   bus.BusStore
+	transactionLock    sync.Mutex
+	transactionHooks   []func(transaction *HttpTransaction)
+	retention          retention
+	retentionChan      *bus.Channel
+	subscriptions      map[string]*transactionSubscription
+	intercepted        interceptor
+	interceptChan      *bus.Channel
+	subscriptionLock   sync.RWMutex
+	config             *shared.WiretapConfiguration
+	fs                 http.Handler
+	mockEngine         *mock.ResponseMockEngine
+	validator          validation.HttpValidator
+	stream             bool
+	streamChan         chan []*errors.ValidationError
+	streamViolations   []*errors.ValidationError
+	aggregator         *violationAggregator
+	aggregateChan      chan []*shared.Violation
+	reportFile         string
+	issueService       *issues.IssueService
+	responseCache      *responseCache
+	hostValidators     map[*shared.WiretapHostConfig]validation.HttpValidator
+	pathValidators     map[*shared.WiretapPathConfig]validation.HttpValidator
+	prefixValidators   map[string]validation.HttpValidator
+	graphqlValidator   validation.HttpValidator
+	grpcValidator      *grpc.Validator
+	grpcTranscoder     *grpc.Transcoder
+	webhookValidator   *validation.WebhookValidator
+	candidateValidator validation.HttpValidator
+	validationPool     *validationPool
+	customValidators   []validation.CustomValidator
+	tally              violationTally
+	coverageTracker    *coverage.Tracker
+	latency            *upstreamLatency
+	harPlayback        *harPlayback
+	mockOverrides      *mock.Overrides
+	specLock           sync.RWMutex
+	specLoader         SpecificationLoader
+	specListeners      []func(document libopenapi.Document)
+	specStatusChan     *bus.Channel
+	metrics            *serviceMetrics
+	tracer             *tracing.Tracer
+	harRecorder        *harRecorder
+	persistence        *transactionPersistence
+	operationResults   *operationResults
+	notifier           *notify.Notifier
 }
 
 func NewWiretapService(document libopenapi.Document, config *shared.WiretapConfiguration) *WiretapService {
@@ -61,6 +113,9 @@ func NewWiretapService(document libopenapi.Document, config *shared.WiretapConfi
 		transport:        tr,
 		controlsStore:    controlsStore,
 		transactionStore: transactionStore,
+		responseCache:    newResponseCache(),
+		metrics:          newServiceMetrics(),
+		latency:          &upstreamLatency{tracker: latency.NewTracker()},
 	}
 	if document != nil {
 		m, _ := document.BuildV3Model()
@@ -69,15 +124,168 @@ func NewWiretapService(document libopenapi.Document, config *shared.WiretapConfi
 		wts.docModel = docModel
 
 		// create a new validator
-		wts.validator = validation.NewHttpValidator(docModel)
+		wts.validator = newValidator(docModel, config)
+		wts.webhookValidator = validation.NewWebhookValidator(docModel)
 	}
 
-	// create a new mock engine
-	wts.mockEngine = mock.NewMockEngine(wts.docModel, config.MockModePretty)
+	// the next version of the specification, traffic valid against the specification is validated against it too.
+	if config.CandidateDocument != nil && wts.validator != nil {
+		if m, _ := config.CandidateDocument.BuildV3Model(); m != nil {
+			wts.candidateValidator = newValidator(&m.Model, config)
+		}
+	}
+
+	// keep track of what parts of the specification are exercised.
+	wts.coverageTracker = coverage.NewTracker(wts.docModel)
+
+	// hosts with their own specification get their own validator.
+	wts.hostValidators = make(map[*shared.WiretapHostConfig]validation.HttpValidator)
+	for _, host := range config.Hosts {
+		if host.Document != nil {
+			if m, _ := host.Document.BuildV3Model(); m != nil {
+				wts.hostValidators[host] = newValidator(&m.Model, config)
+			}
+		}
+	}
+
+	// paths and path prefixes with their own specification get their own validator too.
+	wts.pathValidators = make(map[*shared.WiretapPathConfig]validation.HttpValidator)
+	pathConfigs := make([]*shared.WiretapPathConfig, 0, len(config.PathConfigurations))
+	for _, path := range config.PathConfigurations {
+		pathConfigs = append(pathConfigs, path)
+	}
+	for _, host := range config.Hosts {
+		for _, path := range host.PathConfigurations {
+			pathConfigs = append(pathConfigs, path)
+		}
+	}
+	for _, path := range pathConfigs {
+		if path.Document != nil {
+			if m, _ := path.Document.BuildV3Model(); m != nil {
+				wts.pathValidators[path] = newValidator(&m.Model, config)
+			}
+		}
+	}
+	wts.prefixValidators = make(map[string]validation.HttpValidator)
+	for prefix, document := range config.ContractDocuments {
+		if m, _ := document.BuildV3Model(); m != nil {
+			wts.prefixValidators[prefix] = newValidator(&m.Model, config)
+		}
+	}
+
+	// requests to the GraphQL endpoint are validated against the GraphQL schema.
+	if config.GraphQLSchema != nil {
+		wts.graphqlValidator = graphql.NewValidator(config.GraphQLSchema)
+	}
+
+	// gRPC calls are decoded using the descriptors of the services, and validated against them if asked to.
+	if config.GRPCDescriptors != nil {
+		wts.grpcValidator = grpc.NewValidator(config.GRPCDescriptors)
+	}
+
+	// RESTful requests are transcoded into gRPC calls for a gRPC API, routes that cannot be read were reported at boot.
+	if config.GRPCDescriptors != nil && config.GRPCTranscode {
+		wts.grpcTranscoder, _ = grpc.NewTranscoder(config.GRPCDescriptors)
+	}
+
+	// the target API is called through a forward proxy, the proxy was checked at boot.
+	if config.UpstreamProxy != nil {
+		if err := useUpstreamProxy(config.UpstreamProxy); err != nil {
+			config.Logger.Error("[wiretap] unable to use upstream proxy", "error", err.Error())
+		}
+	}
+
+	// targets that require mutual TLS are presented with a client certificate, certificates were checked at boot.
+	if len(config.ClientCertificates) > 0 {
+		if err := useClientCertificates(config.ClientCertificates); err != nil {
+			config.Logger.Error("[wiretap] unable to use client certificates", "error", err.Error())
+		}
+	}
+
+	// custom validators are compiled in, or run as hooks.
+	wts.customValidators = validation.RegisteredCustomValidators()
+	for _, command := range config.ValidatorHooks {
+		hook, err := validation.NewExecHook(command, 0)
+		if err != nil {
+			config.Logger.Error("[wiretap] unable to use validator hook", "hook", command, "error", err.Error())
+			continue
+		}
+		wts.customValidators = append(wts.customValidators, hook)
+	}
+
+	// validation runs in the background, unless it's inline.
+	if !strings.EqualFold(config.ValidationMode, shared.ValidationModeInline) {
+		wts.validationPool = newValidationPool(config.ValidationWorkers, config.ValidationQueue, config.Logger)
+	}
 
 	// hard-wire the config, change this later if needed.
 	wts.config = config
 
+	// hand-crafted mocks replace generated ones.
+	if config.MockOverrides != "" {
+		wts.loadMockOverrides()
+	}
+
+	// create a new mock engine
+	wts.mockEngine = wts.buildMockEngine(wts.docModel)
+
+	// play back recorded responses from the HAR file.
+	if config.HARPlayback && config.HARFile != nil {
+		wts.harPlayback = newHARPlayback(config.HARFile)
+	}
+
+	// file violations with any configured issue trackers.
+	if len(config.IssueTrackers) > 0 {
+		var specBytes []byte
+		if document != nil {
+			specBytes = *document.GetSpecInfo().SpecBytes
+		}
+		wts.issueService = issues.NewIssueService(config, specBytes)
+	}
+
+	// webhooks are notified of violations as they happen.
+	if len(config.Notifications) > 0 {
+		wts.notifier = notify.NewNotifier(config)
+	}
+
+	// spans are exported to an OpenTelemetry collector.
+	if config.OTLPEndpoint != "" {
+		wts.tracer = tracing.NewTracer(config.OTLPEndpoint, "wiretap", config.Version)
+	}
+
+	// completed transactions are recorded to a HAR file, as they happen.
+	if config.HARRecord != "" {
+		recorder, err := newHARRecorder(config)
+		if err != nil {
+			config.Logger.Error("[wiretap] unable to record HAR file", "file", config.HARRecord, "error", err.Error())
+		} else {
+			wts.harRecorder = recorder
+		}
+	}
+
+	// transactions are kept on disk, so they survive restarts.
+	if config.TransactionStore != "" {
+		s, err := store.Open(config.TransactionStore)
+		if err != nil {
+			config.Logger.Error("[wiretap] unable to open transaction store", "file", config.TransactionStore,
+				"error", err.Error())
+		} else {
+			wts.persistence = &transactionPersistence{store: s}
+		}
+	}
+
+	// results are kept per operation, for the JUnit and SARIF reports.
+	if config.JUnitReport || config.SARIFReport {
+		wts.operationResults = newOperationResults()
+	}
+
+	// identical violations within a window are collapsed into one.
+	if config.ViolationWindow > 0 {
+		wts.aggregator = newViolationAggregator(time.Duration(config.ViolationWindow) * time.Second)
+		wts.aggregateChan = make(chan []*shared.Violation)
+		wts.flushAggregatedViolations()
+	}
+
 	// listen for violations
 	wts.listenForValidationErrors()
 
@@ -89,6 +297,30 @@ func (ws *WiretapService) HandleServiceRequest(request *model.Request, core serv
 	switch request.RequestCommand {
 	case IncomingHttpRequest:
 		ws.handleHttpRequest(request)
+	case ReloadSpecRequest:
+		err := ws.ReloadSpecification()
+		ws.auditSpecification(audit.Client(request), audit.ActionSpecificationReloaded, err)
+		if err != nil {
+			core.SendErrorResponse(request, 422, err.Error())
+		} else {
+			core.SendResponse(request, &shared.WiretapSpecStatus{Healthy: true})
+		}
+	case PushSpecRequest:
+		ws.pushSpecification(request, core)
+	case GetTransactionsRequest:
+		ws.getTransactions(request, core)
+	case SearchTransactionsRequest:
+		ws.searchTransactions(request, core)
+	case ResendTransactionRequest:
+		ws.resendTransaction(request, core)
+	case SubscribeTransactionsRequest:
+		ws.subscribeTransactions(request, core)
+	case UnsubscribeTransactionsRequest:
+		ws.unsubscribeTransactions(request, core)
+	case GetInterceptedRequest:
+		ws.getIntercepted(request, core)
+	case ReleaseInterceptedRequest:
+		ws.releaseIntercepted(request, core)
 	default:
 		core.HandleUnknownRequest(request)
 	}
@@ -96,5 +328,12 @@ func (ws *WiretapService) HandleServiceRequest(request *model.Request, core serv
 
 func (ws *WiretapService) HandleHttpRequest(request *model.Request) {
 
-	ws.handleHttpRequest(request)
+	// join the trace of the caller, if wiretap is tracing.
+	var span *tracing.Span
+	request.HttpRequest, span = ws.tracer.StartRequest(request.HttpRequest, "wiretap "+request.HttpRequest.Method)
+	span.SetAttribute("http.request.method", request.HttpRequest.Method)
+	span.SetAttribute("url.path", ws.redactor().Text(request.HttpRequest.URL.Path))
+	defer span.End()
+
+	ws.measure(request, ws.handleHttpRequest)
 }
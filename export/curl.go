@@ -0,0 +1,86 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package export
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/wiretap/session"
+)
+
+// Curl converts exchanges into a shell script, a curl command per request, so a request can be reproduced outside
+// wiretap. Every argument is single quoted, so nothing in a URL, header or body is expanded by the shell.
+func Curl(exchanges []*session.Exchange) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n# exported by wiretap, a curl command per captured request.\nset -e\n")
+	for _, e := range exchanges {
+		b.WriteString("\n")
+		b.WriteString(curlComment(e))
+		b.WriteString(CurlCommand(e))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// CurlCommand renders a single request as a curl command, headers and body are sent exactly as captured.
+func CurlCommand(e *session.Exchange) string {
+	args := []string{"curl"}
+	switch method := strings.ToUpper(e.Method); {
+	case method == http.MethodHead:
+		args = append(args, "--head")
+	case method != http.MethodGet || len(e.Body) > 0:
+		args = append(args, "-X", method)
+	}
+	args = append(args, shellQuote(e.URL))
+
+	names := make([]string, 0, len(e.Header))
+	for name := range e.Header {
+		if !strings.EqualFold(name, "Content-Length") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range e.Header[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+	if len(e.Body) > 0 {
+		args = append(args, "--data-binary", shellQuote(string(e.Body)))
+	}
+
+	// an argument per line, so long requests stay readable.
+	var b strings.Builder
+	b.WriteString(args[0])
+	for i := 1; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "-") && args[i] != "-" {
+			b.WriteString(" \\\n  ")
+		} else {
+			b.WriteString(" ")
+		}
+		b.WriteString(args[i])
+	}
+	return b.String()
+}
+
+// curlComment describes a request, and what came back when it was captured.
+func curlComment(e *session.Exchange) string {
+	comment := "# " + strings.ToUpper(e.Method) + " " + itemPath(e.URL)
+	if e.StatusCode > 0 {
+		comment += fmt.Sprintf(" (%d)", e.StatusCode)
+	}
+	if len(e.Violations) > 0 {
+		comment += fmt.Sprintf(", %d violations", len(e.Violations))
+	}
+	return strings.ReplaceAll(comment, "\n", " ") + "\n"
+}
+
+// shellQuote single quotes a value, single quotes inside it are closed, escaped and reopened.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
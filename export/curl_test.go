@@ -0,0 +1,53 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package export
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pb33f/wiretap/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurlCommand(t *testing.T) {
+	e := &session.Exchange{
+		Method: "POST",
+		URL:    "http://localhost:9090/pets?name=o'neil&$x=1",
+		Header: http.Header{"Content-Type": {"application/json"}, "Content-Length": {"24"}},
+		Body:   []byte(`{"name":"it's $HOME"}`),
+	}
+	assert.Equal(t, `curl \
+  -X POST 'http://localhost:9090/pets?name=o'\''neil&$x=1' \
+  -H 'Content-Type: application/json' \
+  --data-binary '{"name":"it'\''s $HOME"}'`, CurlCommand(e))
+
+	assert.Equal(t, "curl 'http://localhost:9090/pets'", CurlCommand(&session.Exchange{Method: "GET", URL: "http://localhost:9090/pets"}))
+	assert.Equal(t, "curl \\\n  --head 'http://localhost:9090/pets'", CurlCommand(&session.Exchange{Method: "HEAD", URL: "http://localhost:9090/pets"}))
+}
+
+func TestCurl_Escaping(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell")
+	}
+	// swap curl for a function that prints its arguments, so the script shows what curl would be sent.
+	script := "curl() { for a in \"$@\"; do printf '%s\\n' \"$a\"; done; }\n" + Curl([]*session.Exchange{{
+		Method: "PUT",
+		URL:    "http://localhost:9090/pets/1",
+		Header: http.Header{"X-Note": {`"quoted" $(whoami) 'single'`}},
+		Body:   []byte("line one\nit's `two`"),
+	}})
+	file := filepath.Join(t.TempDir(), "curl.sh")
+	assert.NoError(t, os.WriteFile(file, []byte(script), 0755))
+
+	out, err := exec.Command(sh, file).Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "-X\nPUT\nhttp://localhost:9090/pets/1\n-H\nX-Note: \"quoted\" $(whoami) 'single'\n--data-binary\nline one\nit's `two`\n",
+		string(out))
+}
@@ -0,0 +1,96 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package export
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pb33f/harhar"
+	"github.com/pb33f/wiretap/session"
+)
+
+// HAR converts exchanges into a HAR file, so they can be opened in browser dev tools (or replayed by wiretap).
+// Bodies that aren't text are base64 encoded.
+func HAR(exchanges []*session.Exchange, version string) *harhar.HAR {
+	harFile := &harhar.HAR{Log: harhar.Log{
+		Version: "1.2",
+		Creator: harhar.Creator{Name: "wiretap", Version: version},
+		Entries: make([]harhar.Entry, 0, len(exchanges)),
+	}}
+	for _, e := range exchanges {
+		harFile.Log.Entries = append(harFile.Log.Entries, harEntry(e))
+	}
+	return harFile
+}
+
+func harEntry(e *session.Exchange) harhar.Entry {
+	request := harhar.Request{
+		Method:      e.Method,
+		URL:         e.URL,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harhar.Cookie{},
+		Headers:     harPairs(e.Header),
+		QueryParams: []harhar.NameValuePair{},
+		HeadersSize: -1,
+		BodySize:    len(e.Body),
+	}
+	if u, err := url.Parse(e.URL); err == nil {
+		request.QueryParams = harPairs(u.Query())
+	}
+	if len(e.Body) > 0 {
+		request.Body = harhar.BodyType{MIMEType: e.Header.Get("Content-Type"), Content: string(e.Body)}
+	}
+
+	content := harhar.BodyResponseType{Size: len(e.ResponseBody), MIMEType: e.ResponseHeader.Get("Content-Type")}
+	if utf8.Valid(e.ResponseBody) {
+		content.Content = string(e.ResponseBody)
+	} else {
+		content.Content, content.Encoding = base64.StdEncoding.EncodeToString(e.ResponseBody), "base64"
+	}
+
+	start := e.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	ms := float64(e.Duration.Microseconds()) / 1000
+	return harhar.Entry{
+		Start:   start.Format(time.RFC3339Nano),
+		Time:    ms,
+		Request: request,
+		Response: harhar.Response{
+			StatusCode:  e.StatusCode,
+			StatusText:  http.StatusText(e.StatusCode),
+			HTTPVersion: "HTTP/1.1",
+			RedirectURL: e.ResponseHeader.Get("Location"),
+			Cookies:     []harhar.Cookie{},
+			Headers:     harPairs(e.ResponseHeader),
+			Body:        content,
+			HeadersSize: -1,
+			BodySize:    len(e.ResponseBody),
+		},
+		Timings: harhar.Timings{Send: 0, Wait: ms, Receive: 0},
+	}
+}
+
+// harPairs lists headers or query parameters, sorted by name.
+func harPairs(values map[string][]string) []harhar.NameValuePair {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]harhar.NameValuePair, 0, len(values))
+	for _, name := range names {
+		for _, value := range values[name] {
+			pairs = append(pairs, harhar.NameValuePair{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,64 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package export
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pb33f/harhar"
+	"github.com/pb33f/wiretap/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHAR(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	exchanges := []*session.Exchange{
+		{
+			Method:         "POST",
+			URL:            "http://localhost:9090/pets?limit=10",
+			Header:         http.Header{"Content-Type": {"application/json"}, "Accept": {"*/*"}},
+			Body:           []byte(`{"name":"chicken"}`),
+			StatusCode:     201,
+			ResponseHeader: http.Header{"Content-Type": {"application/json"}},
+			ResponseBody:   []byte(`{"id":1}`),
+			Start:          start,
+			Duration:       25 * time.Millisecond,
+		},
+		{Method: "GET", URL: "http://localhost:9090/pets/1/photo", StatusCode: 200, ResponseBody: []byte{0xff, 0xd8, 0xff}},
+	}
+
+	harFile := HAR(exchanges, "1.2.3")
+	assert.Equal(t, "1.2", harFile.Log.Version)
+	assert.Equal(t, "wiretap", harFile.Log.Creator.Name)
+	assert.Equal(t, "1.2.3", harFile.Log.Creator.Version)
+	assert.Len(t, harFile.Log.Entries, 2)
+
+	post := harFile.Log.Entries[0]
+	assert.Equal(t, start.Format(time.RFC3339Nano), post.Start)
+	assert.Equal(t, float64(25), post.Time)
+	assert.Equal(t, "POST", post.Request.Method)
+	assert.Equal(t, []harhar.NameValuePair{{Name: "Accept", Value: "*/*"}, {Name: "Content-Type", Value: "application/json"}},
+		post.Request.Headers)
+	assert.Equal(t, []harhar.NameValuePair{{Name: "limit", Value: "10"}}, post.Request.QueryParams)
+	assert.Equal(t, `{"name":"chicken"}`, post.Request.Body.Content)
+	assert.Equal(t, "application/json", post.Request.Body.MIMEType)
+	assert.Equal(t, 201, post.Response.StatusCode)
+	assert.Equal(t, "Created", post.Response.StatusText)
+	assert.Equal(t, `{"id":1}`, post.Response.Body.Content)
+	assert.Empty(t, post.Response.Body.Encoding)
+
+	photo := harFile.Log.Entries[1]
+	assert.Equal(t, "base64", photo.Response.Body.Encoding)
+	assert.Equal(t, "/9j/", photo.Response.Body.Content)
+
+	// what is exported can be read back as a session.
+	read := session.FromHAR(harFile)
+	assert.Len(t, read, 2)
+	assert.Equal(t, exchanges[0].URL, read[0].URL)
+	assert.Equal(t, exchanges[1].ResponseBody, read[1].ResponseBody)
+	assert.True(t, start.Equal(read[0].Start))
+}
@@ -0,0 +1,282 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package export converts captured sessions into formats other tools can import, so interesting traffic can be
+// handed over and poked at by hand.
+package export
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/wiretap/session"
+)
+
+// PostmanSchema is the version of the collection format exported.
+const PostmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// BaseURLVariable holds the origin most requests were sent to, requests to it use {{baseUrl}}.
+const BaseURLVariable = "baseUrl"
+
+// PostmanCollection is a Postman (v2.1) collection, a request per captured exchange with the response as an
+// example.
+type PostmanCollection struct {
+	Info PostmanInfo    `json:"info"`
+	Item []*PostmanItem `json:"item"`
+}
+
+// PostmanInfo names a collection.
+type PostmanInfo struct {
+	ID     string `json:"_postman_id"`
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// PostmanItem is a request in a collection, with the responses that came back.
+type PostmanItem struct {
+	Name     string             `json:"name"`
+	Request  *PostmanRequest    `json:"request"`
+	Response []*PostmanResponse `json:"response"`
+}
+
+// PostmanRequest is a request, values of variables are replaced by references to them (e.g. {{apiKey}}).
+type PostmanRequest struct {
+	Method string         `json:"method"`
+	Header []*PostmanPair `json:"header"`
+	Body   *PostmanBody   `json:"body,omitempty"`
+	URL    *PostmanURL    `json:"url"`
+}
+
+// PostmanURL is the URL of a request, in pieces.
+type PostmanURL struct {
+	Raw   string         `json:"raw"`
+	Host  []string       `json:"host,omitempty"`
+	Path  []string       `json:"path,omitempty"`
+	Query []*PostmanPair `json:"query,omitempty"`
+}
+
+// PostmanPair is a header or query parameter.
+type PostmanPair struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// PostmanBody is a raw request body, the language tells Postman how to highlight it.
+type PostmanBody struct {
+	Mode    string              `json:"mode"`
+	Raw     string              `json:"raw"`
+	Options *PostmanBodyOptions `json:"options,omitempty"`
+}
+
+// PostmanBodyOptions holds the language of a raw body.
+type PostmanBodyOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+// PostmanResponse is an example response, saved with a request.
+type PostmanResponse struct {
+	Name            string          `json:"name"`
+	OriginalRequest *PostmanRequest `json:"originalRequest"`
+	Status          string          `json:"status"`
+	Code            int             `json:"code"`
+	Header          []*PostmanPair  `json:"header"`
+	Body            string          `json:"body"`
+}
+
+// PostmanEnvironment is a Postman environment, the values collections refer to as {{name}}.
+type PostmanEnvironment struct {
+	ID     string             `json:"id"`
+	Name   string             `json:"name"`
+	Values []*PostmanVariable `json:"values"`
+	Scope  string             `json:"_postman_variable_scope"`
+}
+
+// PostmanVariable is a value in an environment.
+type PostmanVariable struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Postman converts exchanges into a collection, and an environment derived from wiretap variables. The origin most
+// requests were sent to becomes {{baseUrl}}, and the values of variables found in URLs, headers and bodies are
+// replaced by references to them, so QA can point the collection somewhere else by switching environments.
+func Postman(name string, exchanges []*session.Exchange, variables map[string]string) (*PostmanCollection, *PostmanEnvironment) {
+	baseURL := commonOrigin(exchanges)
+	substitute := variableReplacer(variables)
+
+	collection := &PostmanCollection{
+		Info: PostmanInfo{ID: uuid.NewString(), Name: name, Schema: PostmanSchema},
+		Item: make([]*PostmanItem, 0, len(exchanges)),
+	}
+	for _, e := range exchanges {
+		request := postmanRequest(e, baseURL, substitute)
+		item := &PostmanItem{Name: strings.ToUpper(e.Method) + " " + itemPath(e.URL), Request: request,
+			Response: []*PostmanResponse{}}
+		if e.StatusCode > 0 {
+			item.Response = append(item.Response, &PostmanResponse{
+				Name:            http.StatusText(e.StatusCode),
+				OriginalRequest: request,
+				Status:          http.StatusText(e.StatusCode),
+				Code:            e.StatusCode,
+				Header:          postmanHeaders(e.ResponseHeader, nil),
+				Body:            string(e.ResponseBody),
+			})
+		}
+		collection.Item = append(collection.Item, item)
+	}
+
+	environment := &PostmanEnvironment{
+		ID:     uuid.NewString(),
+		Name:   name,
+		Values: []*PostmanVariable{{Key: BaseURLVariable, Value: baseURL, Type: "default", Enabled: true}},
+		Scope:  "environment",
+	}
+	names := make([]string, 0, len(variables))
+	for k := range variables {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		environment.Values = append(environment.Values,
+			&PostmanVariable{Key: k, Value: variables[k], Type: "default", Enabled: true})
+	}
+	return collection, environment
+}
+
+func postmanRequest(e *session.Exchange, baseURL string, substitute *strings.Replacer) *PostmanRequest {
+	request := &PostmanRequest{
+		Method: strings.ToUpper(e.Method),
+		Header: postmanHeaders(e.Header, substitute),
+		URL:    postmanURL(e.URL, baseURL, substitute),
+	}
+	if len(e.Body) > 0 {
+		body := &PostmanBody{Mode: "raw", Raw: substitute.Replace(string(e.Body)), Options: &PostmanBodyOptions{}}
+		body.Options.Raw.Language = bodyLanguage(e.Header.Get("Content-Type"))
+		request.Body = body
+	}
+	return request
+}
+
+// postmanURL splits a URL up, requests sent to the base URL refer to it as {{baseUrl}}.
+func postmanURL(raw, baseURL string, substitute *strings.Replacer) *PostmanURL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return &PostmanURL{Raw: substitute.Replace(raw)}
+	}
+	host := ""
+	if u.Host != "" {
+		host = u.Scheme + "://" + u.Host
+	}
+	if host == baseURL {
+		host = "{{" + BaseURLVariable + "}}"
+	} else {
+		host = substitute.Replace(host)
+	}
+
+	pu := &PostmanURL{Raw: host + substitute.Replace(u.EscapedPath())}
+	if host != "" {
+		pu.Host = []string{host}
+	}
+	for _, segment := range strings.Split(strings.Trim(u.EscapedPath(), "/"), "/") {
+		if segment != "" {
+			pu.Path = append(pu.Path, substitute.Replace(segment))
+		}
+	}
+	if u.RawQuery != "" {
+		pu.Raw += "?" + substitute.Replace(u.RawQuery)
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			key, value, _ := strings.Cut(param, "=")
+			pu.Query = append(pu.Query, &PostmanPair{Key: key, Value: substitute.Replace(value)})
+		}
+	}
+	return pu
+}
+
+// postmanHeaders lists headers by name, Content-Length is left out as Postman works it out.
+func postmanHeaders(headers http.Header, substitute *strings.Replacer) []*PostmanPair {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		if !strings.EqualFold(name, "Content-Length") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	pairs := make([]*PostmanPair, 0, len(names))
+	for _, name := range names {
+		for _, value := range headers[name] {
+			if substitute != nil {
+				value = substitute.Replace(value)
+			}
+			pairs = append(pairs, &PostmanPair{Key: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+// commonOrigin finds the origin (scheme and host) most requests were sent to.
+func commonOrigin(exchanges []*session.Exchange) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, e := range exchanges {
+		u, err := url.Parse(e.URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		origin := u.Scheme + "://" + u.Host
+		counts[origin]++
+		if counts[origin] > counts[best] || (counts[origin] == counts[best] && origin < best) {
+			best = origin
+		}
+	}
+	return best
+}
+
+// variableReplacer replaces the values of variables with references to them, longest values first so a value
+// containing another is replaced whole.
+func variableReplacer(variables map[string]string) *strings.Replacer {
+	names := make([]string, 0, len(variables))
+	for k, v := range variables {
+		if v != "" {
+			names = append(names, k)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(variables[names[i]]) != len(variables[names[j]]) {
+			return len(variables[names[i]]) > len(variables[names[j]])
+		}
+		return names[i] < names[j]
+	})
+	pairs := make([]string, 0, len(names)*2)
+	for _, k := range names {
+		pairs = append(pairs, variables[k], "{{"+k+"}}")
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func bodyLanguage(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return "json"
+	case strings.Contains(contentType, "xml"):
+		return "xml"
+	case strings.Contains(contentType, "html"):
+		return "html"
+	default:
+		return "text"
+	}
+}
+
+func itemPath(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Path != "" {
+		return u.Path
+	}
+	return raw
+}
@@ -0,0 +1,63 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package export
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/wiretap/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostman(t *testing.T) {
+	exchanges := []*session.Exchange{
+		{
+			Method:         "POST",
+			URL:            "https://api.pb33f.io/pets?owner=dave&limit=10",
+			Header:         http.Header{"Content-Type": {"application/json"}, "X-Api-Key": {"s3cr3t"}, "Content-Length": {"19"}},
+			Body:           []byte(`{"name":"chicken"}`),
+			StatusCode:     201,
+			ResponseHeader: http.Header{"Content-Type": {"application/json"}},
+			ResponseBody:   []byte(`{"id":1}`),
+		},
+		{Method: "GET", URL: "https://api.pb33f.io/pets/1", StatusCode: 200},
+		{Method: "GET", URL: "http://localhost:8080/health"},
+	}
+
+	collection, environment := Postman("pets", exchanges, map[string]string{"apiKey": "s3cr3t", "owner": "dave"})
+
+	assert.Equal(t, PostmanSchema, collection.Info.Schema)
+	assert.Len(t, collection.Item, 3)
+
+	post := collection.Item[0]
+	assert.Equal(t, "POST /pets", post.Name)
+	assert.Equal(t, "{{baseUrl}}/pets?owner={{owner}}&limit=10", post.Request.URL.Raw)
+	assert.Equal(t, []string{"{{baseUrl}}"}, post.Request.URL.Host)
+	assert.Equal(t, []string{"pets"}, post.Request.URL.Path)
+	assert.Equal(t, []*PostmanPair{{Key: "owner", Value: "{{owner}}"}, {Key: "limit", Value: "10"}}, post.Request.URL.Query)
+	assert.Equal(t, []*PostmanPair{{Key: "Content-Type", Value: "application/json"}, {Key: "X-Api-Key", Value: "{{apiKey}}"}},
+		post.Request.Header)
+	assert.Equal(t, `{"name":"chicken"}`, post.Request.Body.Raw)
+	assert.Equal(t, "json", post.Request.Body.Options.Raw.Language)
+	assert.Len(t, post.Response, 1)
+	assert.Equal(t, 201, post.Response[0].Code)
+	assert.Equal(t, "Created", post.Response[0].Status)
+	assert.Equal(t, `{"id":1}`, post.Response[0].Body)
+
+	assert.Equal(t, []string{"pets", "1"}, collection.Item[1].Request.URL.Path)
+	assert.Nil(t, collection.Item[1].Request.Body)
+
+	health := collection.Item[2]
+	assert.Equal(t, "http://localhost:8080/health", health.Request.URL.Raw)
+	assert.Empty(t, health.Response)
+
+	assert.Equal(t, "environment", environment.Scope)
+	assert.Equal(t, []*PostmanVariable{
+		{Key: "baseUrl", Value: "https://api.pb33f.io", Type: "default", Enabled: true},
+		{Key: "apiKey", Value: "s3cr3t", Type: "default", Enabled: true},
+		{Key: "owner", Value: "dave", Type: "default", Enabled: true},
+	}, environment.Values)
+}
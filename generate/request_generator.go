@@ -0,0 +1,316 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/pb33f/libopenapi/renderer"
+)
+
+// Vary modes determine how much of each request is synthesized.
+const (
+	// VaryNone re-uses examples from the specification where they exist, so every request is (mostly) the same.
+	VaryNone = "none"
+	// VaryRequired synthesizes new values for every request, only required properties and parameters are sent.
+	VaryRequired = "required"
+	// VaryAll synthesizes new values for every request, all properties and parameters are sent.
+	VaryAll = "all"
+)
+
+// Operation is an operation located in the specification, along with the path and method it lives under.
+type Operation struct {
+	Path      string
+	Method    string
+	PathItem  *v3.PathItem
+	Operation *v3.Operation
+}
+
+// RequestGenerator synthesizes valid requests for an operation, using the schema renderer.
+type RequestGenerator struct {
+	document *v3.Document
+	vary     string
+	schemas  *renderer.SchemaRenderer
+	mocks    *renderer.MockGenerator
+}
+
+// NewRequestGenerator creates a new request generator for a document, vary must be one of none, required or all.
+func NewRequestGenerator(document *v3.Document, vary string) (*RequestGenerator, error) {
+	switch vary {
+	case VaryNone, VaryRequired, VaryAll:
+	default:
+		return nil, fmt.Errorf("unknown vary mode '%s', must be one of: %s, %s, %s", vary, VaryNone, VaryRequired, VaryAll)
+	}
+	schemas := renderer.CreateRendererUsingDefaultDictionary()
+	if vary == VaryAll {
+		schemas.DisableRequiredCheck()
+	}
+	return &RequestGenerator{
+		document: document,
+		vary:     vary,
+		schemas:  schemas,
+		mocks:    renderer.NewMockGenerator(renderer.JSON),
+	}, nil
+}
+
+// LocateOperation finds an operation by its operationId.
+func (rg *RequestGenerator) LocateOperation(operationId string) (*Operation, error) {
+	if rg.document == nil || rg.document.Paths == nil {
+		return nil, fmt.Errorf("specification has no paths")
+	}
+	for pair := orderedmap.First(rg.document.Paths.PathItems); pair != nil; pair = pair.Next() {
+		for op := orderedmap.First(pair.Value().GetOperations()); op != nil; op = op.Next() {
+			if op.Value().OperationId == operationId {
+				return &Operation{
+					Path:      pair.Key(),
+					Method:    strings.ToUpper(op.Key()),
+					PathItem:  pair.Value(),
+					Operation: op.Value(),
+				}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("operation '%s' cannot be found in the specification", operationId)
+}
+
+// GenerateRequest synthesizes a new request for the operation, against the base URL.
+func (rg *RequestGenerator) GenerateRequest(operation *Operation, baseURL string) (*http.Request, error) {
+	path := operation.Path
+	query := url.Values{}
+	headers := http.Header{}
+
+	// path item parameters are overridden by operation parameters with the same name and location.
+	params := make(map[string]*v3.Parameter)
+	var order []string
+	for _, p := range append(operation.PathItem.Parameters, operation.Operation.Parameters...) {
+		key := p.In + ":" + p.Name
+		if _, ok := params[key]; !ok {
+			order = append(order, key)
+		}
+		params[key] = p
+	}
+
+	for _, key := range order {
+		p := params[key]
+		required := p.Required != nil && *p.Required
+		if p.In != "path" && !required && rg.vary != VaryAll {
+			continue
+		}
+		value := rg.renderParameter(p)
+		switch p.In {
+		case "path":
+			path = strings.ReplaceAll(path, fmt.Sprintf("{%s}", p.Name), url.PathEscape(value))
+		case "query":
+			query.Set(p.Name, value)
+		case "header":
+			headers.Set(p.Name, value)
+		case "cookie":
+			headers.Add("Cookie", fmt.Sprintf("%s=%s", p.Name, value))
+		}
+	}
+
+	target := strings.TrimSuffix(baseURL, "/") + path
+	if len(query) > 0 {
+		target = fmt.Sprintf("%s?%s", target, query.Encode())
+	}
+
+	var body []byte
+	if rb := operation.Operation.RequestBody; rb != nil && rb.Content != nil {
+		mediaTypeName, mediaType := rg.pickMediaType(rb.Content)
+		if mediaType != nil {
+			b, contentType, err := rg.renderMediaType(mediaTypeName, mediaType)
+			if err != nil {
+				return nil, err
+			}
+			body = b
+			headers.Set("Content-Type", contentType)
+		}
+	}
+
+	req, err := http.NewRequest(operation.Method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	return req, nil
+}
+
+// pickMediaType prefers JSON, because that's what the renderer is best at, then forms, which can be encoded from
+// what it renders.
+func (rg *RequestGenerator) pickMediaType(content *orderedmap.Map[string, *v3.MediaType]) (string, *v3.MediaType) {
+	var firstName, formName string
+	var first, form *v3.MediaType
+	for pair := orderedmap.First(content); pair != nil; pair = pair.Next() {
+		if isJSONMediaType(pair.Key()) {
+			return pair.Key(), pair.Value()
+		}
+		if mediaType, _, _ := mime.ParseMediaType(pair.Key()); form == nil &&
+			(mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data") {
+			formName, form = pair.Key(), pair.Value()
+		}
+		if first == nil {
+			firstName, first = pair.Key(), pair.Value()
+		}
+	}
+	if form != nil {
+		return formName, form
+	}
+	return firstName, first
+}
+
+// renderMediaType renders a body for a media type, encoded the way the media type says it is. The content type to
+// send the body with is returned too, multipart bodies have a boundary.
+func (rg *RequestGenerator) renderMediaType(name string, mediaType *v3.MediaType) ([]byte, string, error) {
+	var value any
+	var schema *base.Schema
+	if mediaType.Schema != nil {
+		schema = mediaType.Schema.Schema()
+	}
+	if rg.vary == VaryNone || schema == nil {
+		mock, err := rg.mocks.GenerateMock(mediaType, "")
+		if err != nil {
+			return nil, "", err
+		}
+		if isJSONMediaType(name) {
+			return mock, name, nil
+		}
+		if json.Unmarshal(mock, &value) != nil {
+			value = string(mock)
+		}
+	} else {
+		value = rg.renderSchema(schema)
+	}
+	return encodeBody(name, value)
+}
+
+func isJSONMediaType(name string) bool {
+	return strings.Contains(strings.ToLower(name), "json")
+}
+
+// encodeBody encodes a rendered value as JSON, or as a form. Nothing else can be synthesized.
+func encodeBody(name string, value any) ([]byte, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(name)
+	switch {
+	case isJSONMediaType(mediaType):
+		b, err := json.Marshal(value)
+		return b, name, err
+	case mediaType == "application/x-www-form-urlencoded":
+		form := url.Values{}
+		for field, v := range formFields(value) {
+			form[field] = v
+		}
+		return []byte(form.Encode()), name, nil
+	case mediaType == "multipart/form-data":
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		fields := formFields(value)
+		names := make([]string, 0, len(fields))
+		for field := range fields {
+			names = append(names, field)
+		}
+		sort.Strings(names)
+		for _, field := range names {
+			for _, v := range fields[field] {
+				if err := writer.WriteField(field, v); err != nil {
+					return nil, "", err
+				}
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), writer.FormDataContentType(), nil
+	}
+	return nil, "", fmt.Errorf("unable to synthesize a '%s' request body, only JSON and form bodies can be generated",
+		name)
+}
+
+// formFields flattens a rendered object into form fields, arrays are sent as a field per item.
+func formFields(value any) map[string][]string {
+	fields := make(map[string][]string)
+	object, ok := value.(map[string]any)
+	if !ok {
+		return fields
+	}
+	for field, v := range object {
+		if items, isArray := v.([]any); isArray {
+			for _, item := range items {
+				fields[field] = append(fields[field], formatParameterValue(item))
+			}
+			continue
+		}
+		fields[field] = []string{formatParameterValue(v)}
+	}
+	return fields
+}
+
+// renderSchema recovers from schemas the renderer cannot handle (no type for example).
+func (rg *RequestGenerator) renderSchema(schema *base.Schema) (rendered any) {
+	defer func() {
+		if r := recover(); r != nil {
+			rendered = nil
+		}
+	}()
+	return rg.schemas.RenderSchema(schema)
+}
+
+func (rg *RequestGenerator) renderParameter(p *v3.Parameter) string {
+	if rg.vary == VaryNone {
+		if p.Example != nil {
+			var example any
+			if p.Example.Decode(&example) == nil {
+				return fmt.Sprint(example)
+			}
+		}
+		for pair := orderedmap.First(p.Examples); pair != nil; pair = pair.Next() {
+			if pair.Value().Value != nil {
+				var example any
+				if pair.Value().Value.Decode(&example) == nil {
+					return fmt.Sprint(example)
+				}
+			}
+		}
+	}
+	var schema *base.Schema
+	if p.Schema != nil {
+		schema = p.Schema.Schema()
+	}
+	if schema == nil {
+		return rg.schemas.RandomWord(3, 10, 0)
+	}
+	return formatParameterValue(rg.renderSchema(schema))
+}
+
+// formatParameterValue flattens a rendered value into something that can live in a path, query or header.
+func formatParameterValue(value any) string {
+	switch v := value.(type) {
+	case []any:
+		var items []string
+		for _, i := range v {
+			items = append(items, formatParameterValue(i))
+		}
+		return strings.Join(items, ",")
+	case map[string]any:
+		b, _ := json.Marshal(v)
+		return string(b)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package generate
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var orderSpec = `openapi: 3.1.0
+paths:
+  /stores/{storeId}/orders:
+    parameters:
+      - name: storeId
+        in: path
+        required: true
+        schema:
+          type: integer
+    post:
+      operationId: createOrder
+      parameters:
+        - name: dryRun
+          in: query
+          schema:
+            type: boolean
+        - name: X-Tenant
+          in: header
+          required: true
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [item, quantity]
+              properties:
+                item:
+                  type: string
+                quantity:
+                  type: integer
+                notes:
+                  type: string`
+
+func TestRequestGenerator_GenerateRequest(t *testing.T) {
+	doc, _ := libopenapi.NewDocument([]byte(orderSpec))
+	m, _ := doc.BuildV3Model()
+
+	generator, err := NewRequestGenerator(&m.Model, VaryRequired)
+	assert.NoError(t, err)
+
+	op, err := generator.LocateOperation("createOrder")
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPost, op.Method)
+
+	req, err := generator.GenerateRequest(op, "http://localhost:9090/")
+	assert.NoError(t, err)
+	assert.NotContains(t, req.URL.Path, "{storeId}")
+	assert.Empty(t, req.URL.Query().Get("dryRun"))
+	assert.NotEmpty(t, req.Header.Get("X-Tenant"))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+
+	var body map[string]any
+	b, _ := io.ReadAll(req.Body)
+	assert.NoError(t, json.Unmarshal(b, &body))
+	assert.Contains(t, body, "item")
+	assert.Contains(t, body, "quantity")
+	assert.NotContains(t, body, "notes")
+
+	generator, _ = NewRequestGenerator(&m.Model, VaryAll)
+	req, _ = generator.GenerateRequest(op, "http://localhost:9090")
+	assert.NotEmpty(t, req.URL.Query().Get("dryRun"))
+	b, _ = io.ReadAll(req.Body)
+	_ = json.Unmarshal(b, &body)
+	assert.Contains(t, body, "notes")
+}
+
+var formSpec = `openapi: 3.1.0
+paths:
+  /login:
+    post:
+      operationId: login
+      requestBody:
+        content:
+          application/xml:
+            schema:
+              type: object
+          application/x-www-form-urlencoded:
+            schema:
+              type: object
+              required: [username, password, scopes]
+              properties:
+                username:
+                  type: string
+                password:
+                  type: string
+                scopes:
+                  type: array
+                  items:
+                    type: string
+  /avatars:
+    post:
+      operationId: uploadAvatar
+      requestBody:
+        content:
+          multipart/form-data:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+  /feeds:
+    post:
+      operationId: createFeed
+      requestBody:
+        content:
+          application/xml:
+            schema:
+              type: object
+              required: [title]
+              properties:
+                title:
+                  type: string`
+
+func TestRequestGenerator_FormBodies(t *testing.T) {
+	doc, _ := libopenapi.NewDocument([]byte(formSpec))
+	m, _ := doc.BuildV3Model()
+
+	for _, vary := range []string{VaryNone, VaryRequired, VaryAll} {
+		t.Run(vary, func(t *testing.T) {
+			generator, _ := NewRequestGenerator(&m.Model, vary)
+
+			// forms are picked over media types that can't be generated, and sent as forms.
+			op, _ := generator.LocateOperation("login")
+			req, err := generator.GenerateRequest(op, "http://localhost:9090")
+			assert.NoError(t, err)
+			assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+			assert.NoError(t, req.ParseForm())
+			assert.NotEmpty(t, req.PostForm.Get("username"))
+			assert.NotEmpty(t, req.PostForm.Get("password"))
+			assert.NotEmpty(t, req.PostForm["scopes"])
+
+			op, _ = generator.LocateOperation("uploadAvatar")
+			req, err = generator.GenerateRequest(op, "http://localhost:9090")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data; boundary="))
+			assert.NoError(t, req.ParseMultipartForm(1<<20))
+			assert.NotEmpty(t, req.FormValue("name"))
+		})
+	}
+}
+
+func TestRequestGenerator_UnsupportedBody(t *testing.T) {
+	doc, _ := libopenapi.NewDocument([]byte(formSpec))
+	m, _ := doc.BuildV3Model()
+
+	// a JSON body is never sent as XML.
+	generator, _ := NewRequestGenerator(&m.Model, VaryRequired)
+	op, _ := generator.LocateOperation("createFeed")
+	_, err := generator.GenerateRequest(op, "http://localhost:9090")
+	assert.EqualError(t, err,
+		"unable to synthesize a 'application/xml' request body, only JSON and form bodies can be generated")
+}
+
+func TestRequestGenerator_UnknownOperation(t *testing.T) {
+	doc, _ := libopenapi.NewDocument([]byte(orderSpec))
+	m, _ := doc.BuildV3Model()
+
+	generator, _ := NewRequestGenerator(&m.Model, VaryNone)
+	_, err := generator.LocateOperation("deleteOrder")
+	assert.Error(t, err)
+
+	_, err = NewRequestGenerator(&m.Model, "sometimes")
+	assert.Error(t, err)
+}
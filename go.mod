@@ -21,12 +21,12 @@ require (
 	github.com/dprotaso/go-yit v0.0.0-20220510233725-9ba8df137936 // indirect
 	github.com/fatih/color v1.14.1 // indirect
 	github.com/fsnotify/fsnotify v1.7.0
-	github.com/go-stomp/stomp/v3 v3.0.3 // indirect
+	github.com/go-stomp/stomp/v3 v3.0.3
 	github.com/gobwas/glob v0.2.3
 	github.com/gookit/color v1.5.4 // indirect
 	github.com/gorilla/handlers v1.4.2
 	github.com/gorilla/mux v1.8.0
-	github.com/gorilla/websocket v1.4.2 // indirect
+	github.com/gorilla/websocket v1.4.2
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/lithammer/fuzzysearch v1.1.8 // indirect
@@ -45,7 +45,7 @@ require (
 	github.com/spf13/viper v1.18.2
 	github.com/stretchr/testify v1.8.4
 	github.com/subosito/gotenv v1.6.0 // indirect
-	github.com/vmware-labs/yaml-jsonpath v0.3.2 // indirect
+	github.com/vmware-labs/yaml-jsonpath v0.3.2
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
 	golang.org/x/sync v0.6.0 // indirect
 	golang.org/x/sys v0.16.0 // indirect
@@ -55,10 +55,16 @@ require (
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require github.com/json-iterator/go v1.1.12
+require (
+	github.com/brianvoe/gofakeit/v6 v6.28.0
+	github.com/json-iterator/go v1.1.12
+	github.com/vektah/gqlparser/v2 v2.5.11
+	google.golang.org/protobuf v1.34.2
+)
 
 require (
 	atomicgo.dev/schedule v0.1.0 // indirect
+	github.com/agnivade/levenshtein v1.1.1 // indirect
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
 	github.com/buger/jsonparser v1.1.1 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.6 // indirect
@@ -72,5 +78,5 @@ require (
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.9.0 // indirect
-	golang.org/x/net v0.19.0 // indirect
+	golang.org/x/net v0.19.0
 )
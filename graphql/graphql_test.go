@@ -0,0 +1,160 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package graphql
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sdl = `type Query {
+  pet(id: ID!): Pet
+  pets(kind: Kind = DOG): [Pet!]!
+}
+
+enum Kind { DOG CAT }
+
+interface Pet {
+  id: ID!
+  name: String!
+}
+
+type Dog implements Pet {
+  id: ID!
+  name: String!
+  barks: Boolean!
+}
+
+type Cat implements Pet {
+  id: ID!
+  name: String!
+  lives: Int!
+}`
+
+func testValidator(t *testing.T) *Validator {
+	schema, err := LoadSchema([]byte(sdl), "pets.graphql")
+	require.NoError(t, err)
+	return NewValidator(schema)
+}
+
+func graphQLRequest(body string) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, "http://localhost/graphql", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func graphQLResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+	}
+}
+
+func TestValidator_ValidateHttpRequest(t *testing.T) {
+	v := testValidator(t)
+
+	valid, violations := v.ValidateHttpRequest(graphQLRequest(
+		`{"query":"query P($id: ID!) { pet(id: $id) { name } }","variables":{"id":"1"}}`))
+	assert.True(t, valid)
+	assert.Empty(t, violations)
+
+	// unknown field.
+	valid, violations = v.ValidateHttpRequest(graphQLRequest(`{"query":"{ pet(id: 1) { age } }"}`))
+	assert.False(t, valid)
+	require.Len(t, violations, 1)
+	assert.Equal(t, QueryValidation, violations[0].ValidationSubType)
+	assert.Contains(t, violations[0].Reason, "age")
+
+	// missing variable.
+	valid, violations = v.ValidateHttpRequest(graphQLRequest(`{"query":"query P($id: ID!) { pet(id: $id) { name } }"}`))
+	assert.False(t, valid)
+	require.Len(t, violations, 1)
+	assert.Equal(t, VariablesValidation, violations[0].ValidationSubType)
+
+	// batched, the second request is invalid.
+	valid, violations = v.ValidateHttpRequest(graphQLRequest(`[{"query":"{ pets { id } }"},{"query":"{ nope }"}]`))
+	assert.False(t, valid)
+	assert.Len(t, violations, 1)
+
+	// GET
+	r, _ := http.NewRequest(http.MethodGet, "http://localhost/graphql?query=%7B%20pets%20%7B%20id%20%7D%20%7D", nil)
+	valid, _ = v.ValidateHttpRequest(r)
+	assert.True(t, valid)
+}
+
+func TestValidator_ValidateHttpResponse(t *testing.T) {
+	v := testValidator(t)
+	query := `{"query":"{ pets { __typename id name ... on Dog { barks } ... on Cat { lives } } }"}`
+
+	valid, violations := v.ValidateHttpResponse(graphQLRequest(query), graphQLResponse(
+		`{"data":{"pets":[{"__typename":"Dog","id":"1","name":"Rex","barks":true},
+		{"__typename":"Cat","id":2,"name":"Tom","lives":9}]}}`))
+	assert.True(t, valid)
+	assert.Empty(t, violations)
+
+	valid, violations = v.ValidateHttpResponse(graphQLRequest(query), graphQLResponse(
+		`{"data":{"pets":[{"__typename":"Dog","id":"1","name":null,"barks":"woof","lives":9}]}}`))
+	assert.False(t, valid)
+	require.Len(t, violations, 3)
+	assert.Equal(t, "Field 'data.pets[0].barks' is not a valid Boolean", violations[0].Message)
+	assert.Equal(t, 16, violations[0].SpecLine)
+	assert.Equal(t, "Non-null field 'data.pets[0].name' is null", violations[1].Message)
+	assert.Equal(t, "Field 'data.pets[0].lives' was not selected", violations[2].Message)
+
+	// partial data is allowed when there are errors.
+	valid, _ = v.ValidateHttpResponse(graphQLRequest(query), graphQLResponse(
+		`{"data":{"pets":[{"__typename":"Cat","id":"1","name":null}]},"errors":[{"message":"boom"}]}`))
+	assert.True(t, valid)
+}
+
+func TestFromIntrospection(t *testing.T) {
+	result := `{"data":{"__schema":{
+  "queryType":{"name":"Query"},
+  "types":[
+    {"kind":"OBJECT","name":"Query","fields":[
+      {"name":"pets","args":[{"name":"kind","type":{"kind":"ENUM","name":"Kind"},"defaultValue":"DOG"}],
+       "type":{"kind":"NON_NULL","ofType":{"kind":"LIST","ofType":{"kind":"OBJECT","name":"Pet"}}}}]},
+    {"kind":"OBJECT","name":"Pet","fields":[{"name":"name","args":[],"type":{"kind":"SCALAR","name":"String"}}],"interfaces":[]},
+    {"kind":"ENUM","name":"Kind","enumValues":[{"name":"DOG"},{"name":"CAT"}]},
+    {"kind":"SCALAR","name":"String"},
+    {"kind":"OBJECT","name":"__Type","fields":[]}
+  ]}}}`
+
+	schema, err := FromIntrospection([]byte(result))
+	require.NoError(t, err)
+	require.NotNil(t, schema.Query)
+	assert.Equal(t, "[Pet]!", schema.Query.Fields.ForName("pets").Type.String())
+	assert.Len(t, schema.Types["Kind"].EnumValues, 2)
+
+	_, err = FromIntrospection([]byte(`{"errors":[{"message":"introspection disabled"}]}`))
+	assert.ErrorContains(t, err, "introspection disabled")
+}
+
+func TestReadOperations(t *testing.T) {
+	ops := ReadOperations(graphQLRequest(`{"query":"query GetPets { pets { name } }"}`))
+	require.Len(t, ops, 1)
+	assert.Equal(t, "query GetPets", ops[0].String())
+
+	ops = ReadOperations(graphQLRequest(`{"query":"{ pets { name } }"}`))
+	require.Len(t, ops, 1)
+	assert.Equal(t, "query (anonymous)", ops[0].String())
+
+	ops = ReadOperations(graphQLRequest(`{"query":"query A { pets { name } } mutation B { pets { name } }",` +
+		`"operationName":"B"}`))
+	require.Len(t, ops, 1)
+	assert.Equal(t, &Operation{Type: "mutation", Name: "B"}, ops[0])
+
+	ops = ReadOperations(graphQLRequest(`[{"query":"query A { pets { name } }"},{"query":"query B { pet(id: 1) { id } }"}]`))
+	assert.Equal(t, "query A, query B", DescribeOperations(ops))
+
+	assert.Empty(t, ReadOperations(graphQLRequest(`{"query":"query {"}`)))
+}
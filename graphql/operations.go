@@ -0,0 +1,57 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package graphql
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// Operation is an operation run by a GraphQL request, its type (query, mutation or subscription) and its name.
+// Anonymous operations have no name.
+type Operation struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// String describes an operation the way it's written, 'query GetPets'.
+func (o *Operation) String() string {
+	if o.Name == "" {
+		return o.Type + " (anonymous)"
+	}
+	return o.Type + " " + o.Name
+}
+
+// ReadOperations reads the operations run by a request, there is more than one for a batch. No schema is needed,
+// queries are only parsed. Queries that cannot be parsed (or don't define the operation asked for) are left out.
+func ReadOperations(r *http.Request) []*Operation {
+	requests, _, err := ReadRequests(r)
+	if err != nil {
+		return nil
+	}
+	var operations []*Operation
+	for _, request := range requests {
+		document, parseErr := parser.ParseQuery(&ast.Source{Input: request.Query})
+		if parseErr != nil {
+			continue
+		}
+		if op := document.Operations.ForName(request.OperationName); op != nil {
+			operations = append(operations, &Operation{Type: string(op.Operation), Name: op.Name})
+		}
+	}
+	return operations
+}
+
+// DescribeOperations describes the operations of a request, 'query GetPets, mutation AddPet'.
+func DescribeOperations(operations []*Operation) string {
+	described := make([]string, len(operations))
+	for i, op := range operations {
+		described[i] = op.String()
+	}
+	return strings.Join(described, ", ")
+}
@@ -0,0 +1,297 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// result is the result of a GraphQL operation.
+type result struct {
+	Data   *json.RawMessage  `json:"data"`
+	Errors []json.RawMessage `json:"errors"`
+}
+
+// validateResult checks the data of a result against the selection set of the operation it was returned for.
+// When the result carries errors, data can be partial: missing and null fields are not violations.
+func (v *Validator) validateResult(op *ast.OperationDefinition, body []byte) []*errors.ValidationError {
+	var r result
+	if err := json.Unmarshal(body, &r); err != nil {
+		return []*errors.ValidationError{responseViolation("GraphQL response cannot be read",
+			fmt.Sprintf("The response is not a GraphQL result: %s", err.Error()), 0, 0)}
+	}
+	if r.Data == nil || string(*r.Data) == "null" {
+		if len(r.Errors) == 0 {
+			return []*errors.ValidationError{responseViolation("GraphQL response has no data",
+				"The response has neither data nor errors", 0, 0)}
+		}
+		return nil
+	}
+	var data any
+	if err := json.Unmarshal(*r.Data, &data); err != nil {
+		return nil
+	}
+
+	var root *ast.Definition
+	switch op.Operation {
+	case ast.Mutation:
+		root = v.schema.Mutation
+	case ast.Subscription:
+		root = v.schema.Subscription
+	default:
+		root = v.schema.Query
+	}
+	if root == nil {
+		return nil
+	}
+	c := &resultChecker{schema: v.schema, partial: len(r.Errors) > 0}
+	c.checkValue("data", ast.NonNullNamedType(root.Name, nil), op.SelectionSet, data, nil)
+	return c.violations
+}
+
+type resultChecker struct {
+	schema     *ast.Schema
+	partial    bool
+	violations []*errors.ValidationError
+}
+
+// selectedField is a field of a selection set, it's optional when it may not apply to the object returned
+// (it's selected by a fragment on an abstract type), or if it's skipped or included conditionally.
+type selectedField struct {
+	field    *ast.Field
+	optional bool
+}
+
+func (c *resultChecker) checkValue(path string, typ *ast.Type, selection ast.SelectionSet, value any,
+	definition *ast.FieldDefinition) {
+
+	if value == nil {
+		if typ.NonNull && !c.partial {
+			c.violation(definition, fmt.Sprintf("Non-null field '%s' is null", path),
+				fmt.Sprintf("The field is defined as '%s', it cannot be null", typ.String()))
+		}
+		return
+	}
+	if typ.Elem != nil {
+		list, ok := value.([]any)
+		if !ok {
+			c.violation(definition, fmt.Sprintf("Field '%s' is not a list", path),
+				fmt.Sprintf("The field is defined as '%s', but the value is %s", typ.String(), describe(value)))
+			return
+		}
+		for i, item := range list {
+			c.checkValue(fmt.Sprintf("%s[%d]", path, i), typ.Elem, selection, item, definition)
+		}
+		return
+	}
+
+	def := c.schema.Types[typ.NamedType]
+	if def == nil {
+		return
+	}
+	switch def.Kind {
+	case ast.Scalar:
+		if !scalarMatches(def.Name, value) {
+			c.violation(definition, fmt.Sprintf("Field '%s' is not a valid %s", path, def.Name),
+				fmt.Sprintf("The field is defined as '%s', but the value is %s", typ.String(), describe(value)))
+		}
+	case ast.Enum:
+		s, ok := value.(string)
+		if !ok || def.EnumValues.ForName(s) == nil {
+			c.violation(definition, fmt.Sprintf("Field '%s' is not a valid %s", path, def.Name),
+				fmt.Sprintf("The value %s is not one of the values of the enum '%s'", describe(value), def.Name))
+		}
+	default:
+		object, ok := value.(map[string]any)
+		if !ok {
+			c.violation(definition, fmt.Sprintf("Field '%s' is not an object", path),
+				fmt.Sprintf("The field is defined as '%s', but the value is %s", typ.String(), describe(value)))
+			return
+		}
+		c.checkObject(path, def, selection, object)
+	}
+}
+
+func (c *resultChecker) checkObject(path string, def *ast.Definition, selection ast.SelectionSet, object map[string]any) {
+	// the concrete type of an abstract type is only known if __typename was selected.
+	concrete := def
+	if name, ok := object["__typename"].(string); ok {
+		if t := c.schema.Types[name]; t != nil && (t == def || c.implements(t, def)) {
+			concrete = t
+		} else {
+			c.violation(nil, fmt.Sprintf("Field '%s.__typename' is not a valid type", path),
+				fmt.Sprintf("'%s' is not a possible type of '%s'", name, def.Name))
+		}
+	}
+
+	fields := make(map[string]*selectedField)
+	c.collectFields(concrete, selection, false, fields)
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		selected := fields[key]
+		value, present := object[key]
+		fieldPath := path + "." + key
+		if !present {
+			if !selected.optional && !c.partial {
+				c.violation(selected.field.Definition, fmt.Sprintf("Field '%s' is missing", fieldPath),
+					"The field is selected by the query, but it's not in the response")
+			}
+			continue
+		}
+		if selected.field.Name == "__typename" {
+			if _, ok := value.(string); !ok {
+				c.violation(nil, fmt.Sprintf("Field '%s' is not a String", fieldPath),
+					fmt.Sprintf("The value is %s", describe(value)))
+			}
+			continue
+		}
+		if selected.field.Definition == nil {
+			continue
+		}
+		c.checkValue(fieldPath, selected.field.Definition.Type, selected.field.SelectionSet, value,
+			selected.field.Definition)
+	}
+
+	for key := range object {
+		if fields[key] == nil {
+			c.violation(nil, fmt.Sprintf("Field '%s.%s' was not selected", path, key),
+				"The response contains a field that is not selected by the query")
+		}
+	}
+}
+
+// collectFields collects the fields selected for an object, keyed by the name they are returned as. When the
+// type of the object is abstract, every fragment might apply, so their fields are optional.
+func (c *resultChecker) collectFields(def *ast.Definition, selection ast.SelectionSet, optional bool,
+	fields map[string]*selectedField) {
+
+	for _, s := range selection {
+		switch s := s.(type) {
+		case *ast.Field:
+			key := s.Alias
+			if key == "" {
+				key = s.Name
+			}
+			conditional := s.Directives.ForName("skip") != nil || s.Directives.ForName("include") != nil
+			if existing := fields[key]; existing != nil {
+				existing.optional = existing.optional && (optional || conditional)
+				continue
+			}
+			fields[key] = &selectedField{field: s, optional: optional || conditional}
+		case *ast.InlineFragment:
+			if applies, certain := c.fragmentApplies(def, s.TypeCondition); applies {
+				c.collectFields(def, s.SelectionSet, optional || !certain, fields)
+			}
+		case *ast.FragmentSpread:
+			if s.Definition == nil {
+				continue
+			}
+			if applies, certain := c.fragmentApplies(def, s.Definition.TypeCondition); applies {
+				c.collectFields(def, s.Definition.SelectionSet, optional || !certain, fields)
+			}
+		}
+	}
+}
+
+// fragmentApplies checks if a fragment applies to an object of a type. If the type is abstract, the fragment
+// might apply, but it's not certain.
+func (c *resultChecker) fragmentApplies(def *ast.Definition, condition string) (applies, certain bool) {
+	if condition == "" || condition == def.Name {
+		return true, true
+	}
+	target := c.schema.Types[condition]
+	if target == nil {
+		return false, false
+	}
+	if def.IsAbstractType() {
+		return true, false
+	}
+	return c.implements(def, target), true
+}
+
+// implements checks if an object type is one of the possible types of an interface or union.
+func (c *resultChecker) implements(object, abstract *ast.Definition) bool {
+	for _, possible := range c.schema.GetPossibleTypes(abstract) {
+		if possible.Name == object.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *resultChecker) violation(definition *ast.FieldDefinition, message, reason string) {
+	line, col := 0, 0
+	if definition != nil && definition.Position != nil {
+		line, col = definition.Position.Line, definition.Position.Column
+	}
+	c.violations = append(c.violations, responseViolation(message, reason, line, col))
+}
+
+func responseViolation(message, reason string, line, col int) *errors.ValidationError {
+	return &errors.ValidationError{
+		Message:           message,
+		Reason:            reason,
+		ValidationType:    ValidationType,
+		ValidationSubType: ResponseValidation,
+		SpecLine:          line,
+		SpecCol:           col,
+		HowToFix:          "Ensure the data returned matches the selection set of the query, and the types of the schema",
+	}
+}
+
+// scalarMatches checks a value against the built-in scalars, custom scalars accept anything.
+func scalarMatches(scalar string, value any) bool {
+	switch scalar {
+	case "Int":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n) && n >= math.MinInt32 && n <= math.MaxInt32
+	case "Float":
+		_, ok := value.(float64)
+		return ok
+	case "String":
+		_, ok := value.(string)
+		return ok
+	case "Boolean":
+		_, ok := value.(bool)
+		return ok
+	case "ID":
+		switch v := value.(type) {
+		case string:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+		return false
+	}
+	return true
+}
+
+func describe(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("the string '%s'", v)
+	case float64:
+		return fmt.Sprintf("the number %v", v)
+	case bool:
+		return fmt.Sprintf("%t", v)
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	}
+	return strings.ToLower(fmt.Sprintf("%T", value))
+}
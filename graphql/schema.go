@@ -0,0 +1,229 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package graphql validates GraphQL traffic against a schema: queries and mutations, their variables, and the
+// shape of the data returned for them. The schema is loaded from SDL, or from the result of an introspection query.
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// IntrospectionQuery is sent to a GraphQL API to read its schema.
+const IntrospectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types {
+      kind
+      name
+      fields(includeDeprecated: true) {
+        name
+        args { name type { ...TypeRef } defaultValue }
+        type { ...TypeRef }
+      }
+      inputFields { name type { ...TypeRef } defaultValue }
+      interfaces { name }
+      enumValues(includeDeprecated: true) { name }
+      possibleTypes { name }
+    }
+  }
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType {
+    kind
+    name
+    ofType {
+      kind
+      name
+      ofType {
+        kind
+        name
+        ofType {
+          kind
+          name
+          ofType {
+            kind
+            name
+            ofType {
+              kind
+              name
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+// LoadSchema reads a schema from SDL, the name is used when reporting errors.
+func LoadSchema(sdl []byte, name string) (*ast.Schema, error) {
+	return gqlparser.LoadSchema(&ast.Source{Name: name, Input: string(sdl)})
+}
+
+// FromIntrospection reads a schema from the result of the IntrospectionQuery.
+func FromIntrospection(result []byte) (*ast.Schema, error) {
+	var response struct {
+		Data struct {
+			Schema *introspectedSchema `json:"__schema"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("unable to read introspection result: %w", err)
+	}
+	if response.Data.Schema == nil {
+		if len(response.Errors) > 0 {
+			return nil, fmt.Errorf("introspection failed: %s", response.Errors[0].Message)
+		}
+		return nil, fmt.Errorf("introspection result does not contain a schema")
+	}
+	return LoadSchema([]byte(response.Data.Schema.sdl()), "introspection")
+}
+
+type introspectedSchema struct {
+	QueryType        *typeRef            `json:"queryType"`
+	MutationType     *typeRef            `json:"mutationType"`
+	SubscriptionType *typeRef            `json:"subscriptionType"`
+	Types            []*introspectedType `json:"types"`
+}
+
+type introspectedType struct {
+	Kind          string               `json:"kind"`
+	Name          string               `json:"name"`
+	Fields        []*introspectedField `json:"fields"`
+	InputFields   []*introspectedInput `json:"inputFields"`
+	Interfaces    []*typeRef           `json:"interfaces"`
+	EnumValues    []*introspectedEnum  `json:"enumValues"`
+	PossibleTypes []*typeRef           `json:"possibleTypes"`
+}
+
+type introspectedField struct {
+	Name string               `json:"name"`
+	Args []*introspectedInput `json:"args"`
+	Type *typeRef             `json:"type"`
+}
+
+type introspectedInput struct {
+	Name         string   `json:"name"`
+	Type         *typeRef `json:"type"`
+	DefaultValue *string  `json:"defaultValue"`
+}
+
+type introspectedEnum struct {
+	Name string `json:"name"`
+}
+
+type typeRef struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	OfType *typeRef `json:"ofType"`
+}
+
+func (t *typeRef) String() string {
+	switch {
+	case t == nil:
+		return ""
+	case t.Kind == "NON_NULL":
+		return t.OfType.String() + "!"
+	case t.Kind == "LIST":
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
+
+// builtInScalars are defined by the GraphQL prelude, they cannot be defined again.
+var builtInScalars = map[string]bool{"String": true, "Int": true, "Float": true, "Boolean": true, "ID": true}
+
+// sdl renders an introspected schema as SDL, so it can be loaded (and validated) like any other schema.
+func (s *introspectedSchema) sdl() string {
+	var b strings.Builder
+	b.WriteString("schema {\n")
+	for _, root := range []struct {
+		operation string
+		ref       *typeRef
+	}{{"query", s.QueryType}, {"mutation", s.MutationType}, {"subscription", s.SubscriptionType}} {
+		if root.ref != nil && root.ref.Name != "" {
+			fmt.Fprintf(&b, "  %s: %s\n", root.operation, root.ref.Name)
+		}
+	}
+	b.WriteString("}\n")
+
+	for _, t := range s.Types {
+		if strings.HasPrefix(t.Name, "__") || builtInScalars[t.Name] {
+			continue
+		}
+		b.WriteString("\n")
+		switch t.Kind {
+		case "SCALAR":
+			fmt.Fprintf(&b, "scalar %s\n", t.Name)
+		case "ENUM":
+			fmt.Fprintf(&b, "enum %s {\n", t.Name)
+			for _, v := range t.EnumValues {
+				fmt.Fprintf(&b, "  %s\n", v.Name)
+			}
+			b.WriteString("}\n")
+		case "UNION":
+			names := make([]string, 0, len(t.PossibleTypes))
+			for _, p := range t.PossibleTypes {
+				names = append(names, p.Name)
+			}
+			sort.Strings(names)
+			fmt.Fprintf(&b, "union %s = %s\n", t.Name, strings.Join(names, " | "))
+		case "INPUT_OBJECT":
+			fmt.Fprintf(&b, "input %s {\n", t.Name)
+			for _, f := range t.InputFields {
+				fmt.Fprintf(&b, "  %s\n", inputValue(f))
+			}
+			b.WriteString("}\n")
+		case "OBJECT", "INTERFACE":
+			keyword := "type"
+			if t.Kind == "INTERFACE" {
+				keyword = "interface"
+			}
+			fmt.Fprintf(&b, "%s %s", keyword, t.Name)
+			if len(t.Interfaces) > 0 {
+				names := make([]string, 0, len(t.Interfaces))
+				for _, i := range t.Interfaces {
+					names = append(names, i.Name)
+				}
+				fmt.Fprintf(&b, " implements %s", strings.Join(names, " & "))
+			}
+			b.WriteString(" {\n")
+			for _, f := range t.Fields {
+				b.WriteString("  " + f.Name)
+				if len(f.Args) > 0 {
+					args := make([]string, 0, len(f.Args))
+					for _, a := range f.Args {
+						args = append(args, inputValue(a))
+					}
+					fmt.Fprintf(&b, "(%s)", strings.Join(args, ", "))
+				}
+				fmt.Fprintf(&b, ": %s\n", f.Type)
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.String()
+}
+
+func inputValue(input *introspectedInput) string {
+	value := input.Name + ": " + input.Type.String()
+	if input.DefaultValue != nil {
+		value += " = " + *input.DefaultValue
+	}
+	return value
+}
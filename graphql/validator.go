@@ -0,0 +1,204 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// ValidationType is the validation type of GraphQL violations.
+const ValidationType = "graphql"
+
+// Validation sub-types of GraphQL violations.
+const (
+	RequestValidation   = "request"
+	QueryValidation     = "query"
+	VariablesValidation = "variables"
+	ResponseValidation  = "response"
+)
+
+// Validator validates GraphQL requests and responses sent over HTTP against a schema. It can be used anywhere
+// an OpenAPI validator is used.
+type Validator struct {
+	schema *ast.Schema
+}
+
+// NewValidator creates a validator for a schema.
+func NewValidator(schema *ast.Schema) *Validator {
+	return &Validator{schema: schema}
+}
+
+// Request is a GraphQL request, as sent over HTTP.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// ValidateHttpRequest validates the queries (or mutations) of a request, and their variables.
+func (v *Validator) ValidateHttpRequest(request *http.Request) (bool, []*errors.ValidationError) {
+	requests, _, err := ReadRequests(request)
+	if err != nil {
+		return false, []*errors.ValidationError{{
+			Message:           "GraphQL request cannot be read",
+			Reason:            err.Error(),
+			ValidationType:    ValidationType,
+			ValidationSubType: RequestValidation,
+			HowToFix:          "Send the query as JSON in the body of a POST request, or in the query string of a GET request",
+		}}
+	}
+	var violations []*errors.ValidationError
+	for _, r := range requests {
+		_, errs := v.operation(r)
+		violations = append(violations, errs...)
+	}
+	return len(violations) == 0, violations
+}
+
+// ValidateHttpResponse validates the data returned for the queries (or mutations) of a request, it must match
+// the selection set. Requests that are not valid are not checked, there is nothing to check against.
+func (v *Validator) ValidateHttpResponse(request *http.Request, response *http.Response) (bool, []*errors.ValidationError) {
+	requests, batched, err := ReadRequests(request)
+	if err != nil || response == nil || response.Body == nil || !isGraphQLMediaType(response.Header.Get("Content-Type")) {
+		return true, nil
+	}
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return true, nil
+	}
+
+	var results []json.RawMessage
+	if batched {
+		err = json.Unmarshal(body, &results)
+	} else {
+		results = []json.RawMessage{body}
+	}
+	if err != nil || len(results) != len(requests) {
+		return false, []*errors.ValidationError{responseViolation("GraphQL response cannot be read",
+			fmt.Sprintf("The response is not a list of %d results, one for each query", len(requests)), 0, 0)}
+	}
+
+	var violations []*errors.ValidationError
+	for i, r := range requests {
+		op, errs := v.operation(r)
+		if op == nil || len(errs) > 0 {
+			continue
+		}
+		violations = append(violations, v.validateResult(op, results[i])...)
+	}
+	return len(violations) == 0, violations
+}
+
+// operation parses and validates the query of a request against the schema, then validates its variables.
+func (v *Validator) operation(r *Request) (*ast.OperationDefinition, []*errors.ValidationError) {
+	document, errs := gqlparser.LoadQuery(v.schema, r.Query)
+	if len(errs) > 0 {
+		violations := make([]*errors.ValidationError, 0, len(errs))
+		for _, e := range errs {
+			violations = append(violations, gqlViolation(QueryValidation, "GraphQL query is not valid", e,
+				"Ensure the query only uses types, fields and arguments defined by the schema"))
+		}
+		return nil, violations
+	}
+	op := document.Operations.ForName(r.OperationName)
+	if op == nil {
+		return nil, []*errors.ValidationError{{
+			Message:           "GraphQL operation cannot be found",
+			Reason:            fmt.Sprintf("The operation '%s' is not defined by the query", r.OperationName),
+			ValidationType:    ValidationType,
+			ValidationSubType: QueryValidation,
+			HowToFix:          "Set the operationName to an operation defined by the query",
+		}}
+	}
+	if _, err := validator.VariableValues(v.schema, op, r.Variables); err != nil {
+		e, ok := err.(*gqlerror.Error)
+		if !ok {
+			e = gqlerror.Wrap(err)
+		}
+		return op, []*errors.ValidationError{gqlViolation(VariablesValidation, "GraphQL variables are not valid", e,
+			"Ensure the variables match the types declared by the operation")}
+	}
+	return op, nil
+}
+
+// ReadRequests reads the GraphQL requests sent in an HTTP request. POST requests can send a single request, or
+// a batch of them (a JSON array), GET requests send the query in the query string. The body of the request can
+// be read again afterward.
+func ReadRequests(r *http.Request) ([]*Request, bool, error) {
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		request := &Request{Query: query.Get("query"), OperationName: query.Get("operationName")}
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				return nil, false, fmt.Errorf("variables are not a JSON object: %s", err.Error())
+			}
+		}
+		return []*Request{request}, false, nil
+	}
+	if r.Method != http.MethodPost {
+		return nil, false, fmt.Errorf("GraphQL requests are sent using GET or POST, not %s", r.Method)
+	}
+	if r.Body == nil {
+		return nil, false, fmt.Errorf("the request has no body")
+	}
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/graphql" {
+		return []*Request{{Query: string(body), OperationName: r.URL.Query().Get("operationName")}}, false, nil
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var requests []*Request
+		if err = json.Unmarshal(body, &requests); err != nil {
+			return nil, true, fmt.Errorf("the body is not a list of GraphQL requests: %s", err.Error())
+		}
+		return requests, true, nil
+	}
+	var request Request
+	if err = json.Unmarshal(body, &request); err != nil {
+		return nil, false, fmt.Errorf("the body is not a GraphQL request: %s", err.Error())
+	}
+	return []*Request{&request}, false, nil
+}
+
+func gqlViolation(subType, message string, e *gqlerror.Error, howToFix string) *errors.ValidationError {
+	violation := &errors.ValidationError{
+		Message:           message,
+		Reason:            e.Error(),
+		ValidationType:    ValidationType,
+		ValidationSubType: subType,
+		HowToFix:          howToFix,
+	}
+	if len(e.Locations) > 0 {
+		violation.Reason = fmt.Sprintf("%s (line %d, column %d of the query)", e.Message,
+			e.Locations[0].Line, e.Locations[0].Column)
+	}
+	return violation
+}
+
+// isGraphQLMediaType checks if a content type is one GraphQL responses are sent with.
+func isGraphQLMediaType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
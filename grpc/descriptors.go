@@ -0,0 +1,89 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package grpc decodes gRPC traffic using the descriptors of the services being called, so messages can be shown
+// (as JSON) and checked against their types. Descriptors are loaded from a descriptor set (compiled by protoc with
+// --descriptor_set_out and --include_imports), or read from a server that supports server reflection.
+package grpc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// LoadDescriptorSet reads a descriptor set, it has to include every file imported by the services in it.
+func LoadDescriptorSet(data []byte) (*protoregistry.Files, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("not a protobuf descriptor set: %s", err.Error())
+	}
+	return buildFiles(set.File)
+}
+
+// fromFileDescriptors builds descriptors from serialized file descriptors, as sent by server reflection. Files
+// can be sent more than once, the first is used.
+func fromFileDescriptors(files [][]byte) (*protoregistry.Files, error) {
+	seen := make(map[string]bool)
+	var set []*descriptorpb.FileDescriptorProto
+	for _, b := range files {
+		var file descriptorpb.FileDescriptorProto
+		if err := proto.Unmarshal(b, &file); err != nil {
+			return nil, fmt.Errorf("not a protobuf file descriptor: %s", err.Error())
+		}
+		if !seen[file.GetName()] {
+			seen[file.GetName()] = true
+			set = append(set, &file)
+		}
+	}
+	return buildFiles(set)
+}
+
+func buildFiles(set []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: set})
+	if err != nil {
+		return nil, fmt.Errorf("descriptors cannot be read (are imports included?): %s", err.Error())
+	}
+	return files, nil
+}
+
+// Services lists the full names of the services described, sorted.
+func Services(files *protoregistry.Files) []string {
+	var services []string
+	files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		for i := 0; i < file.Services().Len(); i++ {
+			services = append(services, string(file.Services().Get(i).FullName()))
+		}
+		return true
+	})
+	sort.Strings(services)
+	return services
+}
+
+// FindMethod finds the method a request is calling from its path, '/package.Service/Method'.
+func FindMethod(files *protoregistry.Files, path string) (protoreflect.MethodDescriptor, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !ok || service == "" || method == "" || strings.Contains(method, "/") {
+		return nil, fmt.Errorf("the path '%s' is not a gRPC method, it should be '/package.Service/Method'", path)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("the service '%s' is not described", service)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("the service '%s' has no method '%s'", service, method)
+	}
+	return md, nil
+}
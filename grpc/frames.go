@@ -0,0 +1,103 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxMessageSize stops a broken length prefix from making wiretap allocate forever.
+const maxMessageSize = 64 << 20
+
+// IsGRPC checks if a content type is one gRPC messages are sent with (protobuf messages, not JSON).
+func IsGRPC(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/grpc", "application/grpc+proto", "application/grpc-web", "application/grpc-web+proto":
+		return true
+	}
+	return false
+}
+
+// ReadFrames splits the body of a gRPC request or response into its messages. Each message is prefixed with a
+// compressed flag and its length, compressed messages are decompressed using the encoding (grpc-encoding).
+func ReadFrames(body []byte, encoding string) ([][]byte, error) {
+	var messages [][]byte
+	for len(body) > 0 {
+		if len(body) < 5 {
+			return messages, fmt.Errorf("a message is cut short, %d bytes are left where a 5 byte prefix is expected", len(body))
+		}
+		compressed, size := body[0], binary.BigEndian.Uint32(body[1:5])
+		if size > maxMessageSize {
+			return messages, fmt.Errorf("a message is %d bytes, more than the %d bytes that can be read", size, maxMessageSize)
+		}
+		// gRPC-Web sends trailers as a frame of their own, they are not a message.
+		if compressed&0x80 != 0 {
+			return messages, nil
+		}
+		if uint32(len(body)-5) < size {
+			return messages, fmt.Errorf("a message is cut short, %d of %d bytes were sent", len(body)-5, size)
+		}
+		message := body[5 : 5+size]
+		body = body[5+size:]
+		if compressed == 1 {
+			decompressed, err := decompress(message, encoding)
+			if err != nil {
+				return messages, err
+			}
+			message = decompressed
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// writeFrame prefixes an uncompressed message with its length.
+func writeFrame(message []byte) []byte {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+func decompress(message []byte, encoding string) ([]byte, error) {
+	if encoding != "gzip" {
+		return nil, fmt.Errorf("a message is compressed using '%s', only gzip can be read", encoding)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(message))
+	if err != nil {
+		return nil, fmt.Errorf("a compressed message cannot be read: %s", err.Error())
+	}
+	return io.ReadAll(io.LimitReader(r, maxMessageSize))
+}
+
+// Status returns the status of a gRPC call, and its message. It's sent in the trailers, or in the headers when
+// there is nothing else to send (an error). Found is false if there is no status (the call hasn't finished).
+func Status(response *http.Response) (code int, message string, found bool) {
+	value, msg := response.Trailer.Get("Grpc-Status"), response.Trailer.Get("Grpc-Message")
+	if value == "" {
+		value, msg = response.Header.Get("Grpc-Status"), response.Header.Get("Grpc-Message")
+	}
+	if value == "" {
+		return 0, "", false
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, "", false
+	}
+	// messages are percent encoded.
+	if decoded, err := url.PathUnescape(msg); err == nil {
+		msg = decoded
+	}
+	return code, msg, true
+}
@@ -0,0 +1,309 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// httpRule encodes the google.api.http option of a method, 'GET /v1/pets/{name}' with a 'POST /v1/pets' binding.
+func httpRule() *descriptorpb.MethodOptions {
+	post := protowire.AppendString(protowire.AppendTag(nil, rulePost, protowire.BytesType), "/v1/pets")
+	post = protowire.AppendString(protowire.AppendTag(post, ruleBody, protowire.BytesType), "*")
+	rule := protowire.AppendString(protowire.AppendTag(nil, ruleGet, protowire.BytesType), "/v1/pets/{name}")
+	rule = protowire.AppendBytes(protowire.AppendTag(rule, ruleAdditionalBindings, protowire.BytesType), post)
+	options := &descriptorpb.MethodOptions{}
+	options.ProtoReflect().SetUnknown(protowire.AppendBytes(
+		protowire.AppendTag(nil, httpRuleExtension, protowire.BytesType), rule))
+	return options
+}
+
+func testDescriptorSet(t *testing.T) []byte {
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("pets.proto"),
+		Package: proto.String("pets"),
+		Syntax:  proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Pet"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:   proto.String("name"),
+				Number: proto.Int32(1),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum(),
+				Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}, {
+				Name:   proto.String("age"),
+				Number: proto.Int32(2),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:   descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+			}},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("PetService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("GetPet"),
+				InputType:  proto.String(".pets.Pet"),
+				OutputType: proto.String(".pets.Pet"),
+				Options:    httpRule(),
+			}, {
+				Name:            proto.String("ListPets"),
+				InputType:       proto.String(".pets.Pet"),
+				OutputType:      proto.String(".pets.Pet"),
+				ServerStreaming: proto.Bool(true),
+			}},
+		}},
+	}}}
+	b, err := proto.Marshal(set)
+	assert.NoError(t, err)
+	return b
+}
+
+func pet(name string, age int, extra bool) []byte {
+	var b []byte
+	if name != "" {
+		b = protowire.AppendString(protowire.AppendTag(b, 1, protowire.BytesType), name)
+	}
+	b = protowire.AppendVarint(protowire.AppendTag(b, 2, protowire.VarintType), uint64(age))
+	if extra {
+		b = protowire.AppendVarint(protowire.AppendTag(b, 9, protowire.VarintType), 1)
+	}
+	return b
+}
+
+func grpcRequest(path string, frames ...[]byte) *http.Request {
+	var body []byte
+	for _, frame := range frames {
+		body = append(body, writeFrame(frame)...)
+	}
+	r, _ := http.NewRequest(http.MethodPost, "http://localhost"+path, bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/grpc")
+	return r
+}
+
+func TestLoadDescriptorSet(t *testing.T) {
+	files, err := LoadDescriptorSet(testDescriptorSet(t))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pets.PetService"}, Services(files))
+
+	method, err := FindMethod(files, "/pets.PetService/ListPets")
+	assert.NoError(t, err)
+	assert.True(t, method.IsStreamingServer())
+
+	_, err = FindMethod(files, "/pets.PetService/DeletePet")
+	assert.Error(t, err)
+	_, err = FindMethod(files, "/pets.Nope/GetPet")
+	assert.Error(t, err)
+	_, err = FindMethod(files, "/pets")
+	assert.Error(t, err)
+
+	_, err = LoadDescriptorSet([]byte("not a descriptor set"))
+	assert.Error(t, err)
+}
+
+func TestReadFrames(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte("squeezed"))
+	_ = gz.Close()
+	frame := make([]byte, 5)
+	frame[0] = 1
+	binary.BigEndian.PutUint32(frame[1:], uint32(compressed.Len()))
+	body := append(writeFrame([]byte("plain")), append(frame, compressed.Bytes()...)...)
+
+	messages, err := ReadFrames(body, "gzip")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("plain"), []byte("squeezed")}, messages)
+
+	_, err = ReadFrames(body, "snappy")
+	assert.Error(t, err)
+
+	// a cut short message is reported, the messages before it are kept.
+	messages, err = ReadFrames(append(writeFrame([]byte("plain")), 0, 0, 0, 0, 9, 1), "")
+	assert.Error(t, err)
+	assert.Len(t, messages, 1)
+
+	// gRPC-Web trailers are not messages.
+	messages, err = ReadFrames(append(writeFrame([]byte("plain")), 0x80, 0, 0, 0, 0), "")
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	assert.True(t, IsGRPC("application/grpc+proto"))
+	assert.False(t, IsGRPC("application/grpc+json"))
+}
+
+func TestValidator(t *testing.T) {
+	files, _ := LoadDescriptorSet(testDescriptorSet(t))
+	v := NewValidator(files)
+
+	r := grpcRequest("/pets.PetService/GetPet", pet("chicken", 3, false))
+	payload := v.DecodeRequest(r)
+	assert.Equal(t, "pets.PetService", payload.Service)
+	assert.Equal(t, "GetPet", payload.Method)
+	assert.Len(t, payload.Messages, 1)
+	assert.JSONEq(t, `{"name":"chicken","age":3}`, string(payload.Messages[0]))
+
+	// the body can still be read.
+	valid, violations := v.ValidateHttpRequest(r)
+	assert.True(t, valid)
+	assert.Empty(t, violations)
+
+	// unknown and missing required fields.
+	r = grpcRequest("/pets.PetService/GetPet", pet("", 3, true))
+	valid, violations = v.ValidateHttpRequest(r)
+	assert.False(t, valid)
+	assert.Len(t, violations, 2)
+	assert.Contains(t, violations[0].Reason, ": 9")
+	assert.Equal(t, RequestValidation, violations[1].ValidationSubType)
+
+	// unary methods take a single message.
+	r = grpcRequest("/pets.PetService/GetPet", pet("a", 1, false), pet("b", 2, false))
+	valid, violations = v.ValidateHttpRequest(r)
+	assert.False(t, valid)
+	assert.Len(t, violations, 1)
+
+	r = grpcRequest("/pets.PetService/DeletePet", pet("a", 1, false))
+	valid, violations = v.ValidateHttpRequest(r)
+	assert.False(t, valid)
+	assert.Equal(t, MethodValidation, violations[0].ValidationSubType)
+
+	// streamed responses can have any number of messages, failed calls are not checked.
+	r = grpcRequest("/pets.PetService/ListPets", pet("a", 1, false))
+	response := &http.Response{
+		Header:  http.Header{"Content-Type": {"application/grpc"}},
+		Trailer: http.Header{"Grpc-Status": {"0"}},
+		Body:    io.NopCloser(bytes.NewReader(append(writeFrame(pet("a", 1, false)), writeFrame(pet("b", 2, false))...))),
+	}
+	valid, _ = v.ValidateHttpResponse(r, response)
+	assert.True(t, valid)
+	payload = v.DecodeResponse(r, response)
+	assert.Len(t, payload.Messages, 2)
+	assert.Equal(t, 0, *payload.Status)
+
+	response = &http.Response{
+		Header: http.Header{"Content-Type": {"application/grpc"}, "Grpc-Status": {"5"},
+			"Grpc-Message": {"no%20pets"}},
+		Body: io.NopCloser(bytes.NewReader(writeFrame(pet("", 1, true)))),
+	}
+	valid, _ = v.ValidateHttpResponse(r, response)
+	assert.True(t, valid)
+	code, message, found := Status(response)
+	assert.True(t, found)
+	assert.Equal(t, 5, code)
+	assert.Equal(t, "no pets", message)
+
+	// other traffic is left alone.
+	r, _ = http.NewRequest(http.MethodGet, "http://localhost/pets", nil)
+	assert.Nil(t, v.DecodeRequest(r))
+}
+
+func TestTranscoder(t *testing.T) {
+	files, _ := LoadDescriptorSet(testDescriptorSet(t))
+	transcoder, errs := NewTranscoder(files)
+	assert.Empty(t, errs)
+	assert.Len(t, transcoder.Routes(), 3)
+
+	// path variables and query parameters are set on the message.
+	r, _ := http.NewRequest(http.MethodGet, "http://localhost/v1/pets/chicken%20nugget?age=3&unknown=1", nil)
+	r.Header.Set("Authorization", "Bearer pet")
+	route, values := transcoder.Match(r)
+	assert.NotNil(t, route)
+	assert.Equal(t, map[string]string{"name": "chicken nugget"}, values)
+	call, err := transcoder.TranscodeRequest(r, route, values)
+	assert.NoError(t, err)
+	assert.Equal(t, "/pets.PetService/GetPet", call.URL.Path)
+	assert.Equal(t, "application/grpc", call.Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer pet", call.Header.Get("Authorization"))
+	payload := NewValidator(files).DecodeRequest(call)
+	assert.JSONEq(t, `{"name":"chicken nugget","age":3}`, string(payload.Messages[0]))
+
+	// the body is the message.
+	r, _ = http.NewRequest(http.MethodPost, "http://localhost/v1/pets", bytes.NewReader([]byte(`{"name":"cat","age":9}`)))
+	route, values = transcoder.Match(r)
+	call, err = transcoder.TranscodeRequest(r, route, values)
+	assert.NoError(t, err)
+	payload = NewValidator(files).DecodeRequest(call)
+	assert.JSONEq(t, `{"name":"cat","age":9}`, string(payload.Messages[0]))
+
+	r, _ = http.NewRequest(http.MethodPost, "http://localhost/v1/pets", bytes.NewReader([]byte(`{"colour":"tabby"}`)))
+	route, values = transcoder.Match(r)
+	_, err = transcoder.TranscodeRequest(r, route, values)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, ErrorResponse(r, err).StatusCode)
+
+	r, _ = http.NewRequest(http.MethodGet, "http://localhost/v1/pets/chicken/legs", nil)
+	route, _ = transcoder.Match(r)
+	assert.Nil(t, route)
+
+	// responses are sent as JSON, failed calls with the HTTP status their status maps to.
+	r, _ = http.NewRequest(http.MethodGet, "http://localhost/v1/pets/cat", nil)
+	route, _ = transcoder.Match(r)
+	response := transcoder.TranscodeResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/grpc"}, "X-Pet": {"yes"}},
+		Trailer:    http.Header{"Grpc-Status": {"0"}},
+		Body:       io.NopCloser(bytes.NewReader(writeFrame(pet("cat", 9, false)))),
+	}, route)
+	body, _ := io.ReadAll(response.Body)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+	assert.Equal(t, "yes", response.Header.Get("X-Pet"))
+	assert.JSONEq(t, `{"name":"cat","age":9}`, string(body))
+
+	response = transcoder.TranscodeResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/grpc"}, "Grpc-Status": {"5"}, "Grpc-Message": {"no%20cat"}},
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+	}, route)
+	body, _ = io.ReadAll(response.Body)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.JSONEq(t, `{"code":5,"message":"no cat","details":[]}`, string(body))
+
+	// methods without routes are bound to their gRPC path, streamed messages are sent as an array.
+	r, _ = http.NewRequest(http.MethodPost, "http://localhost/pets.PetService/ListPets", nil)
+	route, _ = transcoder.Match(r)
+	assert.Equal(t, "ListPets", string(route.Method.Name()))
+	response = transcoder.TranscodeResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/grpc"}},
+		Trailer:    http.Header{"Grpc-Status": {"0"}},
+		Body:       io.NopCloser(bytes.NewReader(append(writeFrame(pet("a", 1, false)), writeFrame(pet("b", 2, false))...))),
+	}, route)
+	body, _ = io.ReadAll(response.Body)
+	assert.JSONEq(t, `[{"name":"a","age":1},{"name":"b","age":2}]`, string(body))
+}
+
+func TestRouteTemplates(t *testing.T) {
+	route := &Route{}
+	var err error
+	route.segments, route.verb, err = parseTemplate("/v1/{name=shelves/*/books/*}:publish")
+	assert.NoError(t, err)
+	values, ok := route.match("/v1/shelves/1/books/2:publish")
+	assert.True(t, ok)
+	assert.Equal(t, "shelves/1/books/2", values["name"])
+	_, ok = route.match("/v1/shelves/1/books/2")
+	assert.False(t, ok)
+
+	route.segments, route.verb, err = parseTemplate("/files/{path=**}")
+	assert.NoError(t, err)
+	values, ok = route.match("/files/a/b/c.txt")
+	assert.True(t, ok)
+	assert.Equal(t, "a/b/c.txt", values["path"])
+
+	_, _, err = parseTemplate("/files/**/edit")
+	assert.Error(t, err)
+	_, _, err = parseTemplate("/files/{path")
+	assert.Error(t, err)
+	_, _, err = parseTemplate("files")
+	assert.Error(t, err)
+}
@@ -0,0 +1,195 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// reflectionMethods are the versions of the server reflection service, servers may only support the older one.
+var reflectionMethods = []string{
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+// statusUnimplemented is the gRPC status of a call to a method a server doesn't have.
+const statusUnimplemented = 12
+
+// field numbers of the server reflection messages, they are encoded by hand.
+const (
+	requestFileContainingSymbol = 4
+	requestListServices         = 7
+
+	responseFileDescriptors = 4
+	responseListServices    = 6
+	responseError           = 7
+)
+
+// Reflect reads the descriptors of every service a server offers, using server reflection. The server is called
+// over HTTP/2, with TLS for https:// targets and without it (h2c) for http:// targets.
+func Reflect(ctx context.Context, target string) (*protoregistry.Files, error) {
+	client := &http.Client{Transport: reflectionTransport(target)}
+	target = strings.TrimSuffix(target, "/")
+
+	var err error
+	for _, method := range reflectionMethods {
+		var services []string
+		services, err = listServices(ctx, client, target+method)
+		if err != nil {
+			if strings.Contains(err.Error(), "unimplemented") {
+				continue
+			}
+			return nil, err
+		}
+		var files [][]byte
+		files, err = fileDescriptors(ctx, client, target+method, services)
+		if err != nil {
+			return nil, err
+		}
+		return fromFileDescriptors(files)
+	}
+	return nil, err
+}
+
+func reflectionTransport(target string) http.RoundTripper {
+	if strings.HasPrefix(target, "https://") {
+		return &http2.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// listServices asks for the names of the services a server offers, the reflection service is left out.
+func listServices(ctx context.Context, client *http.Client, url string) ([]string, error) {
+	responses, err := reflectionCall(ctx, client, url, [][]byte{
+		protowire.AppendString(protowire.AppendTag(nil, requestListServices, protowire.BytesType), "*"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, response := range responses {
+		for _, list := range fieldValues(response, responseListServices) {
+			for _, service := range fieldValues(list, 1) {
+				for _, name := range fieldValues(service, 1) {
+					if !strings.HasPrefix(string(name), "grpc.reflection.") {
+						services = append(services, string(name))
+					}
+				}
+			}
+		}
+	}
+	return services, nil
+}
+
+// fileDescriptors asks for the file descriptors of services, and everything they import.
+func fileDescriptors(ctx context.Context, client *http.Client, url string, services []string) ([][]byte, error) {
+	if len(services) == 0 {
+		return nil, fmt.Errorf("the server doesn't offer any services")
+	}
+	requests := make([][]byte, 0, len(services))
+	for _, service := range services {
+		requests = append(requests, protowire.AppendString(
+			protowire.AppendTag(nil, requestFileContainingSymbol, protowire.BytesType), service))
+	}
+	responses, err := reflectionCall(ctx, client, url, requests)
+	if err != nil {
+		return nil, err
+	}
+	var files [][]byte
+	for _, response := range responses {
+		for _, descriptors := range fieldValues(response, responseFileDescriptors) {
+			files = append(files, fieldValues(descriptors, 1)...)
+		}
+	}
+	return files, nil
+}
+
+// reflectionCall sends requests to the reflection service, and reads its responses. The stream is closed once
+// every request is sent, the server responds to each of them before it finishes the call.
+func reflectionCall(ctx context.Context, client *http.Client, url string, requests [][]byte) ([][]byte, error) {
+	var body []byte
+	for _, request := range requests {
+		body = append(body, writeFrame(request)...)
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/grpc")
+	r.Header.Set("TE", "trailers")
+	resp, err := client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if code, message, found := Status(resp); found && code != 0 {
+		if code == statusUnimplemented {
+			return nil, fmt.Errorf("server reflection is unimplemented: %s", message)
+		}
+		return nil, fmt.Errorf("server reflection failed with status %d: %s", code, message)
+	}
+	responses, err := ReadFrames(data, resp.Header.Get("Grpc-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	for _, response := range responses {
+		for _, e := range fieldValues(response, responseError) {
+			message := fieldValues(e, 2)
+			if len(message) > 0 {
+				return nil, fmt.Errorf("server reflection failed: %s", message[0])
+			}
+			return nil, fmt.Errorf("server reflection failed")
+		}
+	}
+	return responses, nil
+}
+
+// fieldValues returns the values of a length delimited field (strings, bytes and messages) of a message.
+func fieldValues(message []byte, field protowire.Number) [][]byte {
+	var values [][]byte
+	for len(message) > 0 {
+		num, typ, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return values
+		}
+		message = message[n:]
+		if typ == protowire.BytesType && num == field {
+			value, m := protowire.ConsumeBytes(message)
+			if m < 0 {
+				return values
+			}
+			values = append(values, value)
+			message = message[m:]
+			continue
+		}
+		m := protowire.ConsumeFieldValue(num, typ, message)
+		if m < 0 {
+			return values
+		}
+		message = message[m:]
+	}
+	return values
+}
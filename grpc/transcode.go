@@ -0,0 +1,578 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package grpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// httpRuleExtension is the field number of the google.api.http option of a method. The option is read from the
+// encoded options, so the descriptors of google/api/annotations.proto don't have to be linked in.
+const httpRuleExtension = 72295728
+
+// field numbers of google.api.HttpRule, and google.api.CustomHttpPattern.
+const (
+	ruleGet                = 2
+	rulePut                = 3
+	rulePost               = 4
+	ruleDelete             = 5
+	rulePatch              = 6
+	ruleBody               = 7
+	ruleCustom             = 8
+	ruleAdditionalBindings = 11
+	ruleResponseBody       = 12
+
+	customKind = 1
+	customPath = 2
+)
+
+// statusInvalidArgument is the gRPC status of a request that cannot be transcoded.
+const statusInvalidArgument = 3
+
+// httpStatus maps gRPC status codes to HTTP status codes, the same way gRPC gateways do.
+var httpStatus = map[int]int{
+	0:  http.StatusOK,
+	1:  499,
+	2:  http.StatusInternalServerError,
+	3:  http.StatusBadRequest,
+	4:  http.StatusGatewayTimeout,
+	5:  http.StatusNotFound,
+	6:  http.StatusConflict,
+	7:  http.StatusForbidden,
+	8:  http.StatusTooManyRequests,
+	9:  http.StatusBadRequest,
+	10: http.StatusConflict,
+	11: http.StatusBadRequest,
+	12: http.StatusNotImplemented,
+	13: http.StatusInternalServerError,
+	14: http.StatusServiceUnavailable,
+	15: http.StatusInternalServerError,
+	16: http.StatusUnauthorized,
+}
+
+// Route is a RESTful binding of a gRPC method, read from its google.api.http option. Methods without the option
+// are bound to 'POST /package.Service/Method', with the request message as the body.
+type Route struct {
+	HTTPMethod   string
+	Template     string
+	Body         string
+	ResponseBody string
+	Method       protoreflect.MethodDescriptor
+	segments     []segment
+	verb         string
+}
+
+// segment is a segment of a path template, a literal, '*' or '**'. Segments captured by a variable have the path
+// of the field they set.
+type segment struct {
+	literal  string
+	variable string
+}
+
+// Transcoder turns RESTful JSON requests into gRPC calls, and their responses back into JSON.
+type Transcoder struct {
+	routes []*Route
+	types  *dynamicpb.Types
+}
+
+// NewTranscoder reads the routes of every method described. Templates that cannot be read are returned as errors,
+// the routes that can be read are still used.
+func NewTranscoder(files *protoregistry.Files) (*Transcoder, []error) {
+	t := &Transcoder{types: dynamicpb.NewTypes(files)}
+	var errs []error
+	files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		for i := 0; i < file.Services().Len(); i++ {
+			methods := file.Services().Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				routes, err := methodRoutes(methods.Get(j))
+				if err != nil {
+					errs = append(errs, err)
+				}
+				t.routes = append(t.routes, routes...)
+			}
+		}
+		return true
+	})
+	return t, errs
+}
+
+// Routes returns every route of the transcoder.
+func (t *Transcoder) Routes() []*Route {
+	return t.routes
+}
+
+// Match finds the route of a request, and the values of the variables in its path. Nil is returned if no route
+// matches, the request is not transcoded.
+func (t *Transcoder) Match(request *http.Request) (*Route, map[string]string) {
+	path := request.URL.EscapedPath()
+	for _, route := range t.routes {
+		if route.HTTPMethod != request.Method && !(route.HTTPMethod == http.MethodGet && request.Method == http.MethodHead) {
+			continue
+		}
+		if values, ok := route.match(path); ok {
+			return route, values
+		}
+	}
+	return nil, nil
+}
+
+// TranscodeRequest builds the gRPC call of a RESTful request. The message is read from the body, the variables of
+// the path and the query, as described by the route. The call is sent to the same host as the request.
+func (t *Transcoder) TranscodeRequest(request *http.Request, route *Route, values map[string]string) (*http.Request, error) {
+	message := dynamicpb.NewMessage(route.Method.Input())
+	unmarshal := protojson.UnmarshalOptions{Resolver: t.types}
+
+	var body []byte
+	if request.Body != nil {
+		body, _ = io.ReadAll(request.Body)
+		_ = request.Body.Close()
+	}
+	if route.Body != "" && len(bytes.TrimSpace(body)) > 0 {
+		if route.Body == "*" {
+			if err := unmarshal.Unmarshal(body, message); err != nil {
+				return nil, fmt.Errorf("the body is not a '%s': %s", route.Method.Input().FullName(), err.Error())
+			}
+		} else {
+			// the body is a single field, wrap it so it's read like the rest of the message.
+			wrapped, _ := json.Marshal(map[string]json.RawMessage{route.Body: body})
+			field := dynamicpb.NewMessage(route.Method.Input())
+			if err := unmarshal.Unmarshal(wrapped, field); err != nil {
+				return nil, fmt.Errorf("the body is not a valid '%s': %s", route.Body, err.Error())
+			}
+			proto.Merge(message, field)
+		}
+	}
+	for fieldPath, value := range values {
+		if err := setField(message, fieldPath, []string{value}); err != nil {
+			return nil, err
+		}
+	}
+	if route.Body != "*" {
+		for key, query := range request.URL.Query() {
+			if _, ok := values[key]; ok || key == route.Body {
+				continue
+			}
+			// parameters that are not fields are left out, like a gRPC gateway does.
+			if err := setField(message, key, query); err != nil && !strings.HasPrefix(err.Error(), "no field") {
+				return nil, err
+			}
+		}
+	}
+
+	encoded, err := proto.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	target := *request.URL
+	target.Path, target.RawPath, target.RawQuery = "/"+string(route.Method.Parent().FullName())+"/"+
+		string(route.Method.Name()), "", ""
+	call, err := http.NewRequestWithContext(request.Context(), http.MethodPost, target.String(),
+		bytes.NewReader(writeFrame(encoded)))
+	if err != nil {
+		return nil, err
+	}
+	for name, headerValues := range request.Header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Type", "Content-Length", "Accept", "Accept-Encoding", "Connection", "Te":
+			continue
+		}
+		call.Header[name] = headerValues
+	}
+	call.Header.Set("Content-Type", "application/grpc")
+	call.Header.Set("TE", "trailers")
+	call.Host = request.Host
+	return call, nil
+}
+
+// TranscodeResponse turns the response of a gRPC call into JSON. Calls that failed are sent as their status (and
+// message), with the HTTP status code it maps to. Messages streamed by the server are sent as a JSON array.
+func (t *Transcoder) TranscodeResponse(response *http.Response, route *Route) *http.Response {
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+
+	code, message, found := Status(response)
+	if !found && response.StatusCode != http.StatusOK {
+		code, message = 14, fmt.Sprintf("the gRPC call failed with HTTP status %d", response.StatusCode)
+	}
+	if code != 0 {
+		return t.jsonResponse(response, statusCode(code), statusBody(code, message))
+	}
+
+	messages, err := decodeMessages(t.types, body, response.Header.Get("Grpc-Encoding"), route.Method.Output())
+	if err != nil {
+		return t.jsonResponse(response, http.StatusInternalServerError, statusBody(13, err.Error()))
+	}
+	encoded := make([]json.RawMessage, 0, len(messages))
+	for _, m := range messages {
+		b, mErr := protojson.MarshalOptions{Resolver: t.types}.Marshal(m)
+		if mErr != nil {
+			return t.jsonResponse(response, http.StatusInternalServerError, statusBody(13, mErr.Error()))
+		}
+		encoded = append(encoded, responseField(b, route))
+	}
+
+	var out []byte
+	switch {
+	case route.Method.IsStreamingServer():
+		out, _ = json.Marshal(encoded)
+	case len(encoded) == 1:
+		out = encoded[0]
+	default:
+		return t.jsonResponse(response, http.StatusInternalServerError,
+			statusBody(13, fmt.Sprintf("the gRPC call responded with %d messages, not one", len(encoded))))
+	}
+	return t.jsonResponse(response, http.StatusOK, out)
+}
+
+// ErrorResponse is the response to a request that cannot be transcoded, it isn't sent to the API.
+func ErrorResponse(request *http.Request, err error) *http.Response {
+	body := statusBody(statusInvalidArgument, err.Error())
+	return &http.Response{
+		StatusCode:    http.StatusBadRequest,
+		Status:        http.StatusText(http.StatusBadRequest),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
+
+func (t *Transcoder) jsonResponse(response *http.Response, code int, body []byte) *http.Response {
+	header := make(http.Header)
+	for name, values := range response.Header {
+		canonical := http.CanonicalHeaderKey(name)
+		if canonical == "Content-Type" || canonical == "Content-Length" || canonical == "Trailer" ||
+			strings.HasPrefix(canonical, "Grpc-") {
+			continue
+		}
+		header[name] = values
+	}
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	return &http.Response{
+		StatusCode:    code,
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		Proto:         response.Proto,
+		ProtoMajor:    response.ProtoMajor,
+		ProtoMinor:    response.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       response.Request,
+		TLS:           response.TLS,
+	}
+}
+
+func statusCode(code int) int {
+	if status, ok := httpStatus[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+func statusBody(code int, message string) []byte {
+	b, _ := json.Marshal(map[string]any{"code": code, "message": message, "details": []any{}})
+	return b
+}
+
+// responseField picks the field of a response named by the route (response_body), the whole message is used if
+// there isn't one.
+func responseField(message []byte, route *Route) json.RawMessage {
+	if route.ResponseBody == "" {
+		return message
+	}
+	field := route.Method.Output().Fields().ByName(protoreflect.Name(route.ResponseBody))
+	if field == nil {
+		return message
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(message, &fields) != nil {
+		return message
+	}
+	if value, ok := fields[field.JSONName()]; ok {
+		return value
+	}
+	return json.RawMessage("null")
+}
+
+// methodRoutes reads the routes of a method from its google.api.http option.
+func methodRoutes(method protoreflect.MethodDescriptor) ([]*Route, error) {
+	var rules [][]byte
+	if options := method.Options(); options != nil {
+		if encoded, err := proto.Marshal(options); err == nil {
+			rules = fieldValues(encoded, httpRuleExtension)
+		}
+	}
+	if len(rules) == 0 {
+		template := "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+		route, err := newRoute(method, http.MethodPost, template, "*", "")
+		if err != nil {
+			return nil, err
+		}
+		return []*Route{route}, nil
+	}
+	var routes []*Route
+	for _, rule := range rules {
+		bindings := append([][]byte{rule}, fieldValues(rule, ruleAdditionalBindings)...)
+		for _, binding := range bindings {
+			route, err := ruleRoute(method, binding)
+			if err != nil {
+				return routes, err
+			}
+			if route != nil {
+				routes = append(routes, route)
+			}
+		}
+	}
+	return routes, nil
+}
+
+func ruleRoute(method protoreflect.MethodDescriptor, rule []byte) (*Route, error) {
+	body, responseBody := lastValue(rule, ruleBody), lastValue(rule, ruleResponseBody)
+	for field, httpMethod := range map[protowire.Number]string{
+		ruleGet: http.MethodGet, rulePut: http.MethodPut, rulePost: http.MethodPost,
+		ruleDelete: http.MethodDelete, rulePatch: http.MethodPatch,
+	} {
+		if template := fieldValues(rule, field); len(template) > 0 {
+			return newRoute(method, httpMethod, string(template[0]), body, responseBody)
+		}
+	}
+	if custom := fieldValues(rule, ruleCustom); len(custom) > 0 {
+		return newRoute(method, strings.ToUpper(lastValue(custom[0], customKind)), lastValue(custom[0], customPath),
+			body, responseBody)
+	}
+	return nil, nil
+}
+
+func lastValue(message []byte, field protowire.Number) string {
+	values := fieldValues(message, field)
+	if len(values) == 0 {
+		return ""
+	}
+	return string(values[len(values)-1])
+}
+
+func newRoute(method protoreflect.MethodDescriptor, httpMethod, template, body, responseBody string) (*Route, error) {
+	segments, verb, err := parseTemplate(template)
+	if err != nil {
+		return nil, fmt.Errorf("the route of '%s' cannot be read: %s", method.FullName(), err.Error())
+	}
+	return &Route{
+		HTTPMethod:   httpMethod,
+		Template:     template,
+		Body:         body,
+		ResponseBody: responseBody,
+		Method:       method,
+		segments:     segments,
+		verb:         verb,
+	}, nil
+}
+
+// parseTemplate reads a path template, '/v1/{name=shelves/*}/books/{book}:verb'. Variables without a pattern
+// capture a single segment.
+func parseTemplate(template string) ([]segment, string, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, "", fmt.Errorf("the template '%s' doesn't start with '/'", template)
+	}
+	rest := template[1:]
+	var verb string
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.Contains(rest[i:], "/") && !strings.Contains(rest[i:], "}") {
+		rest, verb = rest[:i], rest[i+1:]
+	}
+	var segments []segment
+	for len(rest) > 0 {
+		if rest[0] == '{' {
+			end := strings.Index(rest, "}")
+			if end < 0 {
+				return nil, "", fmt.Errorf("the template '%s' has a variable that isn't closed", template)
+			}
+			field, pattern, found := strings.Cut(rest[1:end], "=")
+			if !found {
+				pattern = "*"
+			}
+			for _, p := range strings.Split(pattern, "/") {
+				segments = append(segments, segment{literal: p, variable: field})
+			}
+			rest = strings.TrimPrefix(rest[end+1:], "/")
+			continue
+		}
+		p, next, _ := strings.Cut(rest, "/")
+		segments = append(segments, segment{literal: p})
+		rest = next
+	}
+	for i, s := range segments {
+		if s.literal == "**" && i != len(segments)-1 {
+			return nil, "", fmt.Errorf("the template '%s' has '**' before its last segment", template)
+		}
+	}
+	return segments, verb, nil
+}
+
+// match checks if a path matches the template of a route, and returns the values of its variables.
+func (route *Route) match(path string) (map[string]string, bool) {
+	path = strings.TrimPrefix(path, "/")
+	if route.verb != "" {
+		if !strings.HasSuffix(path, ":"+route.verb) {
+			return nil, false
+		}
+		path = strings.TrimSuffix(path, ":"+route.verb)
+	}
+	parts := strings.Split(path, "/")
+	if path == "" {
+		parts = nil
+	}
+	captured := make(map[string][]string)
+	for i, s := range route.segments {
+		if s.literal == "**" {
+			if s.variable != "" {
+				captured[s.variable] = append(captured[s.variable], parts[i:]...)
+			}
+			return joinValues(captured), true
+		}
+		if i >= len(parts) || (s.literal != "*" && s.literal != parts[i]) || (s.literal == "*" && parts[i] == "") {
+			return nil, false
+		}
+		if s.variable != "" {
+			captured[s.variable] = append(captured[s.variable], parts[i])
+		}
+	}
+	if len(parts) != len(route.segments) {
+		return nil, false
+	}
+	return joinValues(captured), true
+}
+
+func joinValues(captured map[string][]string) map[string]string {
+	values := make(map[string]string, len(captured))
+	for field, parts := range captured {
+		if len(parts) == 1 {
+			if unescaped, err := url.PathUnescape(parts[0]); err == nil {
+				parts[0] = unescaped
+			}
+		}
+		values[field] = strings.Join(parts, "/")
+	}
+	return values
+}
+
+// setField sets a field of a message from the values of a path variable or query parameter. The field is named
+// by its path, 'author.name', using proto or JSON names.
+func setField(message protoreflect.Message, fieldPath string, values []string) error {
+	names := strings.Split(fieldPath, ".")
+	for i, name := range names {
+		fields := message.Descriptor().Fields()
+		field := fields.ByName(protoreflect.Name(name))
+		if field == nil {
+			field = fields.ByJSONName(name)
+		}
+		if field == nil {
+			return fmt.Errorf("no field '%s' in '%s'", fieldPath, message.Descriptor().FullName())
+		}
+		if i < len(names)-1 {
+			if field.Message() == nil || field.IsList() || field.IsMap() {
+				return fmt.Errorf("the field '%s' of '%s' is not a message", name, fieldPath)
+			}
+			message = message.Mutable(field).Message()
+			continue
+		}
+		if field.IsMap() || (field.Message() != nil && !isWrapper(field.Message())) {
+			return fmt.Errorf("the field '%s' cannot be set from a string", fieldPath)
+		}
+		if !field.IsList() && len(values) > 0 {
+			values = values[len(values)-1:]
+		}
+		for _, value := range values {
+			v, err := scalarValue(message, field, value)
+			if err != nil {
+				return fmt.Errorf("the field '%s' cannot be set to '%s': %s", fieldPath, value, err.Error())
+			}
+			if field.IsList() {
+				message.Mutable(field).List().Append(v)
+			} else {
+				message.Set(field, v)
+			}
+		}
+	}
+	return nil
+}
+
+// isWrapper checks if a message is a well known wrapper of a scalar (google.protobuf.StringValue and friends).
+func isWrapper(desc protoreflect.MessageDescriptor) bool {
+	return desc.ParentFile().Package() == "google.protobuf" && strings.HasSuffix(string(desc.Name()), "Value") &&
+		desc.Fields().Len() == 1 && desc.Fields().Get(0).Name() == "value"
+}
+
+func scalarValue(message protoreflect.Message, field protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	if wrapper := field.Message(); wrapper != nil {
+		var m protoreflect.Message
+		if field.IsList() {
+			m = message.Mutable(field).List().NewElement().Message()
+		} else {
+			m = message.NewField(field).Message()
+		}
+		v, err := scalarValue(m, wrapper.Fields().Get(0), value)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		m.Set(wrapper.Fields().Get(0), v)
+		return protoreflect.ValueOfMessage(m), nil
+	}
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			b, err = base64.URLEncoding.DecodeString(value)
+		}
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.EnumKind:
+		if ev := field.Enum().Values().ByName(protoreflect.Name(value)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		n, err := strconv.ParseFloat(value, 32)
+		return protoreflect.ValueOfFloat32(float32(n)), err
+	case protoreflect.DoubleKind:
+		n, err := strconv.ParseFloat(value, 64)
+		return protoreflect.ValueOfFloat64(n), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported type %s", field.Kind())
+}
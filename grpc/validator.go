@@ -0,0 +1,273 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ValidationType is the validation type of gRPC violations.
+const ValidationType = "grpc"
+
+// Validation sub-types of gRPC violations.
+const (
+	MethodValidation   = "method"
+	RequestValidation  = "request"
+	ResponseValidation = "response"
+)
+
+// Validator decodes gRPC requests and responses using the descriptors of the services called, and validates
+// their messages against their types. It can be used anywhere an OpenAPI validator is used.
+type Validator struct {
+	files *protoregistry.Files
+	types *dynamicpb.Types
+}
+
+// NewValidator creates a validator for the services described.
+func NewValidator(files *protoregistry.Files) *Validator {
+	return &Validator{files: files, types: dynamicpb.NewTypes(files)}
+}
+
+// Payload is the messages of a gRPC request or response, decoded as JSON. A response has the status of the call.
+// Error is set if the messages could not be decoded, they are left out.
+type Payload struct {
+	Service       string            `json:"service,omitempty"`
+	Method        string            `json:"method,omitempty"`
+	Messages      []json.RawMessage `json:"messages,omitempty"`
+	Status        *int              `json:"status,omitempty"`
+	StatusMessage string            `json:"statusMessage,omitempty"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// DecodeRequest decodes the messages of a request, nil is returned if it's not a gRPC request.
+func (v *Validator) DecodeRequest(request *http.Request) *Payload {
+	if !IsGRPC(request.Header.Get("Content-Type")) {
+		return nil
+	}
+	payload := &Payload{}
+	method, err := FindMethod(v.files, request.URL.Path)
+	if err != nil {
+		payload.Error = err.Error()
+		return payload
+	}
+	payload.Service, payload.Method = string(method.Parent().FullName()), string(method.Name())
+	messages, err := v.readMessages(readBody(&request.Body), request.Header.Get("Grpc-Encoding"), method.Input())
+	payload.Messages = v.marshal(messages)
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	return payload
+}
+
+// DecodeResponse decodes the messages of a response, nil is returned if it's not a gRPC response.
+func (v *Validator) DecodeResponse(request *http.Request, response *http.Response) *Payload {
+	if response == nil || !IsGRPC(response.Header.Get("Content-Type")) {
+		return nil
+	}
+	payload := &Payload{}
+	if code, message, found := Status(response); found {
+		payload.Status, payload.StatusMessage = &code, message
+	}
+	method, err := FindMethod(v.files, request.URL.Path)
+	if err != nil {
+		payload.Error = err.Error()
+		return payload
+	}
+	payload.Service, payload.Method = string(method.Parent().FullName()), string(method.Name())
+	messages, err := v.readMessages(readBody(&response.Body), response.Header.Get("Grpc-Encoding"), method.Output())
+	payload.Messages = v.marshal(messages)
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	return payload
+}
+
+// ValidateHttpRequest validates the messages of a request against the input type of the method called.
+func (v *Validator) ValidateHttpRequest(request *http.Request) (bool, []*errors.ValidationError) {
+	if !IsGRPC(request.Header.Get("Content-Type")) {
+		return true, nil
+	}
+	method, err := FindMethod(v.files, request.URL.Path)
+	if err != nil {
+		return false, []*errors.ValidationError{{
+			Message:           "gRPC method cannot be found",
+			Reason:            err.Error(),
+			ValidationType:    ValidationType,
+			ValidationSubType: MethodValidation,
+			HowToFix:          "Ensure the method is described by the descriptors, or call a method that is",
+		}}
+	}
+	violations := v.validateMessages(readBody(&request.Body), request.Header.Get("Grpc-Encoding"), method,
+		RequestValidation)
+	return len(violations) == 0, violations
+}
+
+// ValidateHttpResponse validates the messages of a response against the output type of the method called. Calls
+// that failed (with a status other than OK) are not checked, they don't send messages.
+func (v *Validator) ValidateHttpResponse(request *http.Request, response *http.Response) (bool, []*errors.ValidationError) {
+	if response == nil || response.Body == nil || !IsGRPC(response.Header.Get("Content-Type")) {
+		return true, nil
+	}
+	method, err := FindMethod(v.files, request.URL.Path)
+	if err != nil {
+		return true, nil // reported for the request.
+	}
+	if code, _, found := Status(response); found && code != 0 {
+		return true, nil
+	}
+	violations := v.validateMessages(readBody(&response.Body), response.Header.Get("Grpc-Encoding"), method,
+		ResponseValidation)
+	return len(violations) == 0, violations
+}
+
+// validateMessages checks every message of a request (or response) can be decoded as its type, has no fields the
+// type doesn't define, and sets every required field. Methods that don't stream have to send a single message.
+func (v *Validator) validateMessages(body []byte, encoding string, method protoreflect.MethodDescriptor,
+	subType string) []*errors.ValidationError {
+	desc, streaming := method.Input(), method.IsStreamingClient()
+	if subType == ResponseValidation {
+		desc, streaming = method.Output(), method.IsStreamingServer()
+	}
+	messages, err := v.readMessages(body, encoding, desc)
+	if err != nil {
+		return []*errors.ValidationError{{
+			Message:           fmt.Sprintf("gRPC %s cannot be read", subType),
+			Reason:            err.Error(),
+			ValidationType:    ValidationType,
+			ValidationSubType: subType,
+			HowToFix:          fmt.Sprintf("Ensure every message is a '%s', framed as gRPC expects", desc.FullName()),
+		}}
+	}
+	var violations []*errors.ValidationError
+	if !streaming && len(messages) != 1 {
+		violations = append(violations, &errors.ValidationError{
+			Message: fmt.Sprintf("gRPC %s has %d messages", subType, len(messages)),
+			Reason: fmt.Sprintf("The method '%s' doesn't stream its %s, it has to be a single message",
+				method.FullName(), subType),
+			ValidationType:    ValidationType,
+			ValidationSubType: subType,
+			HowToFix:          fmt.Sprintf("Send a single message, or describe the %s of the method as a stream", subType),
+		})
+	}
+	for i, message := range messages {
+		if unknown := unknownFields(message.ProtoReflect()); len(unknown) > 0 {
+			violations = append(violations, &errors.ValidationError{
+				Message: fmt.Sprintf("gRPC %s message has unknown fields", subType),
+				Reason: fmt.Sprintf("Message %d of %d sets fields that '%s' doesn't define: %s", i+1, len(messages),
+					desc.FullName(), strings.Join(unknown, ", ")),
+				ValidationType:    ValidationType,
+				ValidationSubType: subType,
+				HowToFix:          "Ensure the message only sets fields defined by its type, or update the descriptors",
+			})
+		}
+		if err = proto.CheckInitialized(message); err != nil {
+			violations = append(violations, &errors.ValidationError{
+				Message:           fmt.Sprintf("gRPC %s message is missing required fields", subType),
+				Reason:            fmt.Sprintf("Message %d of %d: %s", i+1, len(messages), err.Error()),
+				ValidationType:    ValidationType,
+				ValidationSubType: subType,
+				HowToFix:          "Ensure every required field of the message is set",
+			})
+		}
+	}
+	return violations
+}
+
+// readMessages decodes the messages of a body as their type.
+func (v *Validator) readMessages(body []byte, encoding string, desc protoreflect.MessageDescriptor) ([]proto.Message, error) {
+	return decodeMessages(v.types, body, encoding, desc)
+}
+
+// decodeMessages decodes the messages of a body as their type, using types to resolve the messages inside them.
+func decodeMessages(types *dynamicpb.Types, body []byte, encoding string, desc protoreflect.MessageDescriptor) ([]proto.Message, error) {
+	frames, err := ReadFrames(body, encoding)
+	messages := make([]proto.Message, 0, len(frames))
+	for i, frame := range frames {
+		message := dynamicpb.NewMessage(desc)
+		if uErr := (proto.UnmarshalOptions{Resolver: types, AllowPartial: true}).Unmarshal(frame, message); uErr != nil {
+			return messages, fmt.Errorf("message %d of %d is not a '%s': %s", i+1, len(frames),
+				desc.FullName(), uErr.Error())
+		}
+		messages = append(messages, message)
+	}
+	return messages, err
+}
+
+// marshal encodes messages as JSON, with the names used by the descriptors.
+func (v *Validator) marshal(messages []proto.Message) []json.RawMessage {
+	encoded := make([]json.RawMessage, 0, len(messages))
+	for _, message := range messages {
+		b, err := protojson.MarshalOptions{Resolver: v.types, UseProtoNames: true}.Marshal(message)
+		if err != nil {
+			b, _ = json.Marshal(err.Error())
+		}
+		encoded = append(encoded, b)
+	}
+	return encoded
+}
+
+// unknownFields lists the numbers of fields set in a message (or the messages inside it) that its type doesn't
+// define.
+func unknownFields(message protoreflect.Message) []string {
+	var unknown []string
+	seen := make(map[string]bool)
+	var walk func(m protoreflect.Message, prefix string)
+	walk = func(m protoreflect.Message, prefix string) {
+		raw := m.GetUnknown()
+		for len(raw) > 0 {
+			num, _, n := protowire.ConsumeField(raw)
+			if n < 0 {
+				break
+			}
+			name := fmt.Sprintf("%s%d", prefix, num)
+			if !seen[name] {
+				seen[name] = true
+				unknown = append(unknown, name)
+			}
+			raw = raw[n:]
+		}
+		m.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if fd.Message() == nil || fd.IsMap() {
+				return true
+			}
+			if fd.IsList() {
+				for i := 0; i < value.List().Len(); i++ {
+					walk(value.List().Get(i).Message(), fmt.Sprintf("%s%s[%d].", prefix, fd.Name(), i))
+				}
+				return true
+			}
+			walk(value.Message(), prefix+string(fd.Name())+".")
+			return true
+		})
+	}
+	walk(message, "")
+	sort.Strings(unknown)
+	return unknown
+}
+
+// readBody reads a body, and puts it back so it can be read again.
+func readBody(body *io.ReadCloser) []byte {
+	if *body == nil {
+		return nil
+	}
+	b, _ := io.ReadAll(*body)
+	_ = (*body).Close()
+	*body = io.NopCloser(bytes.NewReader(b))
+	return b
+}
@@ -0,0 +1,315 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package issues
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// defaultUpdateInterval is how often (in minutes) an existing issue is updated with new occurrences.
+const defaultUpdateInterval = 60
+
+// defaultRetryInterval is how long to wait before filing an issue with a tracker that failed, it's doubled after
+// every failure (up to the update interval) so a tracker that's down or rate limiting isn't hammered.
+const defaultRetryInterval = time.Minute
+
+// IssueTracker is implemented by anything that can open and update issues.
+type IssueTracker interface {
+	// FindIssue looks up an open issue containing the fingerprint, returns an empty string if there isn't one.
+	FindIssue(fingerprint string) (string, error)
+	// CreateIssue opens a new issue and returns its identifier.
+	CreateIssue(issue *Issue) (string, error)
+	// UpdateIssue adds a comment to an existing issue.
+	UpdateIssue(id string, comment string) error
+	// Name is used for logging.
+	Name() string
+}
+
+// Issue is a tracker agnostic issue.
+type Issue struct {
+	Title       string
+	Body        string
+	Fingerprint string
+	Labels      []string
+}
+
+// Violation is a single violation seen by wiretap, along with everything needed to file it.
+type Violation struct {
+	Method            string
+	Path              string
+	OperationId       string
+	Error             *errors.ValidationError
+	SampleTransaction string
+}
+
+type violationGroup struct {
+	fingerprint string
+	issueIds    map[string]string
+	failures    map[string]int
+	retryAt     map[string]time.Time
+	count       int
+	pending     int
+	lastUpdate  time.Time
+}
+
+// IssueService groups violations by operation and rule, and makes sure each group has an issue in every
+// configured tracker. Groups are only filed once, after that the issue is updated with a new occurrence
+// count at most once per update interval.
+type IssueService struct {
+	trackers       []IssueTracker
+	groups         map[string]*violationGroup
+	lock           sync.Mutex
+	queue          chan *Violation
+	specBytes      []byte
+	updateInterval time.Duration
+	retryInterval  time.Duration
+	logger         *slog.Logger
+}
+
+// NewIssueService creates a new issue service from the configured trackers, spec bytes are used to render
+// excerpts of the contract into new issues.
+func NewIssueService(config *shared.WiretapConfiguration, specBytes []byte) *IssueService {
+	is := &IssueService{
+		groups:         make(map[string]*violationGroup),
+		queue:          make(chan *Violation, 500),
+		specBytes:      specBytes,
+		updateInterval: defaultUpdateInterval * time.Minute,
+		retryInterval:  defaultRetryInterval,
+		logger:         config.Logger,
+	}
+	for _, tc := range config.IssueTrackers {
+		token := os.ExpandEnv(config.ReplaceWithVariables(tc.Token))
+		switch strings.ToLower(tc.Type) {
+		case shared.IssueTrackerGitHub:
+			is.trackers = append(is.trackers, NewGitHubTracker(tc, token))
+		case shared.IssueTrackerJira:
+			is.trackers = append(is.trackers, NewJiraTracker(tc, token))
+		default:
+			if is.logger != nil {
+				is.logger.Warn("[wiretap] unknown issue tracker type, ignoring", "type", tc.Type)
+			}
+			continue
+		}
+		if tc.UpdateInterval > 0 {
+			is.updateInterval = time.Duration(tc.UpdateInterval) * time.Minute
+		}
+	}
+	go is.processQueue()
+	return is
+}
+
+// Report queues a violation to be filed, it never blocks the caller. If the queue is full, the violation is dropped.
+func (is *IssueService) Report(violation *Violation) {
+	if len(is.trackers) == 0 || violation == nil || violation.Error == nil {
+		return
+	}
+	select {
+	case is.queue <- violation:
+	default:
+		if is.logger != nil {
+			is.logger.Warn("[wiretap] issue queue is full, violation not filed", "path", violation.Path)
+		}
+	}
+}
+
+func (is *IssueService) processQueue() {
+	for violation := range is.queue {
+		is.handleViolation(violation)
+	}
+}
+
+func (is *IssueService) handleViolation(violation *Violation) {
+	fp := Fingerprint(violation)
+
+	is.lock.Lock()
+	group, seen := is.groups[fp]
+	if !seen {
+		group = &violationGroup{fingerprint: fp, issueIds: make(map[string]string),
+			failures: make(map[string]int), retryAt: make(map[string]time.Time)}
+		is.groups[fp] = group
+	}
+	group.count++
+	group.pending++
+	is.lock.Unlock()
+
+	// a group is filed until every tracker has an issue for it, trackers that failed are tried again (once they
+	// are due to be) the next time it's seen.
+	if !seen || is.unfiled(group) {
+		is.fileIssue(group, violation)
+		return
+	}
+
+	if time.Since(group.lastUpdate) < is.updateInterval {
+		return
+	}
+	comment := fmt.Sprintf("wiretap has seen this violation %d more %s (%d in total) since the last update.",
+		group.pending, shared.Pluralize(group.pending, "time", "times"), group.count)
+	for _, tracker := range is.trackers {
+		if id := group.issueIds[tracker.Name()]; id != "" {
+			if err := tracker.UpdateIssue(id, comment); err != nil {
+				is.logError(tracker, err)
+			}
+		}
+	}
+	group.pending = 0
+	group.lastUpdate = time.Now()
+}
+
+func (is *IssueService) fileIssue(group *violationGroup, violation *Violation) {
+	issue := &Issue{
+		Title:       buildTitle(violation),
+		Body:        buildBody(violation, group.fingerprint, is.specBytes),
+		Fingerprint: group.fingerprint,
+	}
+	for _, tracker := range is.trackers {
+		if !is.due(group, tracker) {
+			continue
+		}
+
+		// an issue may already exist from a previous session, if so, update it.
+		id, err := tracker.FindIssue(group.fingerprint)
+		if err != nil {
+			is.logError(tracker, err)
+		}
+		if id != "" {
+			if err = tracker.UpdateIssue(id, "wiretap has seen this violation again in a new session.\n\n"+
+				violation.SampleTransaction); err != nil {
+				is.logError(tracker, err)
+			}
+			group.issueIds[tracker.Name()] = id
+			continue
+		}
+		id, err = tracker.CreateIssue(issue)
+		if err != nil {
+			is.logError(tracker, err)
+			is.backOff(group, tracker)
+			continue
+		}
+		if is.logger != nil {
+			is.logger.Info("[wiretap] issue created for violation", "tracker", tracker.Name(), "issue", id)
+		}
+		group.issueIds[tracker.Name()] = id
+	}
+	group.pending = 0
+	group.lastUpdate = time.Now()
+}
+
+// unfiled checks if any tracker is still missing an issue for a group, and is due to be tried again.
+func (is *IssueService) unfiled(group *violationGroup) bool {
+	for _, tracker := range is.trackers {
+		if is.due(group, tracker) {
+			return true
+		}
+	}
+	return false
+}
+
+// due checks if a tracker is missing an issue for a group, and isn't waiting to be tried again after failing.
+func (is *IssueService) due(group *violationGroup, tracker IssueTracker) bool {
+	return group.issueIds[tracker.Name()] == "" && !time.Now().Before(group.retryAt[tracker.Name()])
+}
+
+// backOff holds off filing a group with a tracker that failed, for twice as long as last time.
+func (is *IssueService) backOff(group *violationGroup, tracker IssueTracker) {
+	group.failures[tracker.Name()]++
+	wait := is.retryInterval << min(group.failures[tracker.Name()]-1, 16)
+	if wait > is.updateInterval {
+		wait = is.updateInterval
+	}
+	group.retryAt[tracker.Name()] = time.Now().Add(wait)
+}
+
+func (is *IssueService) logError(tracker IssueTracker, err error) {
+	if is.logger != nil {
+		is.logger.Error("[wiretap] issue tracker failure", "tracker", tracker.Name(), "error", err.Error())
+	}
+}
+
+// Fingerprint generates a stable identifier for a violation group: the operation, the rule that was broken (its
+// validation type and subtype) and where the contract was broken (the line of the specification, and the schema
+// keywords that failed). Messages aren't used, they often contain the values that were sent.
+func Fingerprint(violation *Violation) string {
+	operation := violation.OperationId
+	if operation == "" {
+		operation = fmt.Sprintf("%s %s", strings.ToUpper(violation.Method), violation.Path)
+	}
+	locations := []string{fmt.Sprintf("%d:%d", violation.Error.SpecLine, violation.Error.SpecCol)}
+	for _, sve := range violation.Error.SchemaValidationErrors {
+		if sve.DeepLocation != "" && !slices.Contains(locations, sve.DeepLocation) {
+			locations = append(locations, sve.DeepLocation)
+		}
+	}
+	slices.Sort(locations[1:])
+	sum := sha256.Sum256([]byte(operation + "|" + configModel.ViolationRule(violation.Error) + "|" +
+		strings.Join(locations, ",")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func buildTitle(violation *Violation) string {
+	return fmt.Sprintf("[wiretap] %s (%s %s)", violation.Error.Message,
+		strings.ToUpper(violation.Method), violation.Path)
+}
+
+func buildBody(violation *Violation, fingerprint string, specBytes []byte) string {
+	var b strings.Builder
+	b.WriteString("wiretap detected a contract violation.\n\n")
+	if violation.OperationId != "" {
+		b.WriteString(fmt.Sprintf("**Operation**: `%s`\n", violation.OperationId))
+	}
+	b.WriteString(fmt.Sprintf("**Path**: `%s %s`\n", strings.ToUpper(violation.Method), violation.Path))
+	b.WriteString(fmt.Sprintf("**Violation**: %s\n", violation.Error.Message))
+	b.WriteString(fmt.Sprintf("**Reason**: %s\n", violation.Error.Reason))
+	if violation.Error.HowToFix != "" {
+		b.WriteString(fmt.Sprintf("**How to fix**: %s\n", violation.Error.HowToFix))
+	}
+	for _, sve := range violation.Error.SchemaValidationErrors {
+		b.WriteString(fmt.Sprintf("- %s (%s)\n", sve.Reason, sve.Location))
+	}
+	if excerpt := SpecExcerpt(specBytes, violation.Error.SpecLine, 5); excerpt != "" {
+		b.WriteString(fmt.Sprintf("\n**Specification (line %d)**\n```yaml\n%s\n```\n",
+			violation.Error.SpecLine, excerpt))
+	}
+	if violation.SampleTransaction != "" {
+		b.WriteString(fmt.Sprintf("\n**Sample transaction**\n```json\n%s\n```\n", violation.SampleTransaction))
+	}
+	b.WriteString(fmt.Sprintf("\nwiretap-fingerprint: %s\n", fingerprint))
+	return b.String()
+}
+
+// SpecExcerpt returns the lines surrounding a line in the specification, prefixed with line numbers.
+func SpecExcerpt(specBytes []byte, line, context int) string {
+	if line <= 0 || len(specBytes) == 0 {
+		return ""
+	}
+	lines := strings.Split(string(specBytes), "\n")
+	if line > len(lines) {
+		return ""
+	}
+	start := line - context
+	if start < 1 {
+		start = 1
+	}
+	end := line + context
+	if end > len(lines) {
+		end = len(lines)
+	}
+	var excerpt []string
+	for i := start; i <= end; i++ {
+		excerpt = append(excerpt, fmt.Sprintf("%4d | %s", i, lines[i-1]))
+	}
+	return strings.Join(excerpt, "\n")
+}
@@ -0,0 +1,221 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package issues
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssueService_GitHub_Deduplicates(t *testing.T) {
+
+	var lock sync.Mutex
+	var created []map[string]any
+	var comments int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		assert.Equal(t, "Bearer s3cr3t", r.Header.Get("Authorization"))
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/search/issues"):
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		case r.URL.Path == "/repos/pb33f/wiretap/issues":
+			var payload map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			created = append(created, payload)
+			_, _ = w.Write([]byte(`{"number":42}`))
+		case r.URL.Path == "/repos/pb33f/wiretap/issues/42/comments":
+			comments++
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := &shared.WiretapConfiguration{
+		IssueTrackers: []*shared.WiretapIssueTrackerConfig{
+			{Type: shared.IssueTrackerGitHub, URL: server.URL, Repository: "pb33f/wiretap", Token: "s3cr3t"},
+		},
+	}
+	is := NewIssueService(config, []byte("openapi: 3.1.0\npaths:\n  /pets:\n    get:\n      operationId: listPets"))
+	is.updateInterval = 0
+
+	violation := &Violation{
+		Method:      http.MethodGet,
+		Path:        "/pets",
+		OperationId: "listPets",
+		Error: &errors.ValidationError{
+			Message:           "GET response body for '/pets' failed to validate schema",
+			ValidationType:    "response",
+			ValidationSubType: "schema",
+			SpecLine:          4,
+		},
+		SampleTransaction: `{"id":"1234"}`,
+	}
+
+	// handle directly, so the test does not race the queue.
+	is.handleViolation(violation)
+	is.handleViolation(violation)
+	is.handleViolation(violation)
+
+	assert.Len(t, created, 1)
+	assert.Equal(t, 2, comments)
+	assert.Equal(t, "[wiretap] GET response body for '/pets' failed to validate schema (GET /pets)", created[0]["title"])
+
+	body := created[0]["body"].(string)
+	assert.Contains(t, body, "wiretap-fingerprint: "+Fingerprint(violation))
+	assert.Contains(t, body, "   4 |     get:")
+	assert.Contains(t, body, `{"id":"1234"}`)
+}
+
+func TestFingerprint_GroupsByOperationAndRule(t *testing.T) {
+	violation := func(method, message, subType string, line int, locations ...string) *Violation {
+		v := &Violation{Method: method, Path: "/pets", Error: &errors.ValidationError{Message: message,
+			ValidationType: "request", ValidationSubType: subType, SpecLine: line}}
+		for _, location := range locations {
+			v.Error.SchemaValidationErrors = append(v.Error.SchemaValidationErrors,
+				&errors.SchemaValidationFailure{DeepLocation: location, Location: "/" + message})
+		}
+		return v
+	}
+	a := violation("get", "bad", "body", 10, "/required", "/properties/age/type")
+	tests := []struct {
+		name string
+		b    *Violation
+		same bool
+	}{
+		{"method case", violation("GET", "bad", "body", 10, "/required", "/properties/age/type"), true},
+		{"message", violation("get", "value 'lots' is bad", "body", 10, "/required", "/properties/age/type"), true},
+		{"location order", violation("get", "bad", "body", 10, "/properties/age/type", "/required"), true},
+		{"method", violation("POST", "bad", "body", 10, "/required", "/properties/age/type"), false},
+		{"subtype", violation("get", "bad", "header", 10, "/required", "/properties/age/type"), false},
+		{"line", violation("get", "bad", "body", 12, "/required", "/properties/age/type"), false},
+		{"schema keyword", violation("get", "bad", "body", 10, "/required"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.same {
+				assert.Equal(t, Fingerprint(a), Fingerprint(tt.b))
+			} else {
+				assert.NotEqual(t, Fingerprint(a), Fingerprint(tt.b))
+			}
+		})
+	}
+}
+
+func TestIssueService_RetriesFailedIssues(t *testing.T) {
+	var lock sync.Mutex
+	failing := true
+	var created int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/search/issues"):
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		case r.URL.Path == "/repos/pb33f/wiretap/issues":
+			if failing {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			created++
+			_, _ = w.Write([]byte(`{"number":42}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	config := &shared.WiretapConfiguration{
+		IssueTrackers: []*shared.WiretapIssueTrackerConfig{
+			{Type: shared.IssueTrackerGitHub, URL: server.URL, Repository: "pb33f/wiretap", Token: "s3cr3t"},
+		},
+	}
+	is := NewIssueService(config, nil)
+	violation := &Violation{Method: http.MethodGet, Path: "/pets",
+		Error: &errors.ValidationError{Message: "bad", ValidationType: "response", ValidationSubType: "schema"}}
+
+	// the tracker is down, so nothing is filed, and it's tried again when the violation is seen again (once it's
+	// due to be).
+	is.handleViolation(violation)
+	group := is.groups[Fingerprint(violation)]
+	assert.False(t, is.unfiled(group))
+	group.retryAt = map[string]time.Time{}
+	assert.True(t, is.unfiled(group))
+
+	lock.Lock()
+	failing = false
+	lock.Unlock()
+	is.handleViolation(violation)
+	is.handleViolation(violation)
+	assert.False(t, is.unfiled(is.groups[Fingerprint(violation)]))
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 3, is.groups[Fingerprint(violation)].count)
+}
+
+// failingTracker is a tracker that's always down, it counts how often it's asked to file an issue.
+type failingTracker struct {
+	finds   int
+	creates int
+}
+
+func (ft *failingTracker) FindIssue(string) (string, error) {
+	ft.finds++
+	return "", fmt.Errorf("rate limited")
+}
+
+func (ft *failingTracker) CreateIssue(*Issue) (string, error) {
+	ft.creates++
+	return "", fmt.Errorf("rate limited")
+}
+
+func (ft *failingTracker) UpdateIssue(string, string) error { return nil }
+
+func (ft *failingTracker) Name() string { return "failing" }
+
+func TestIssueService_BacksOffFailingTrackers(t *testing.T) {
+	tracker := &failingTracker{}
+	is := &IssueService{trackers: []IssueTracker{tracker}, groups: make(map[string]*violationGroup),
+		updateInterval: time.Hour, retryInterval: time.Minute}
+	violation := &Violation{Method: http.MethodGet, Path: "/pets",
+		Error: &errors.ValidationError{Message: "bad", ValidationType: "response", ValidationSubType: "schema"}}
+
+	// seeing the violation again and again doesn't file it again and again.
+	for i := 0; i < 10; i++ {
+		is.handleViolation(violation)
+	}
+	assert.Equal(t, 1, tracker.finds)
+	assert.Equal(t, 1, tracker.creates)
+
+	group := is.groups[Fingerprint(violation)]
+	assert.Equal(t, 10, group.count)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), group.retryAt["failing"], time.Second)
+
+	// once it's due, it's tried again, and the wait doubles every time it fails (up to the update interval).
+	for _, wait := range []time.Duration{2 * time.Minute, 4 * time.Minute, 8 * time.Minute} {
+		group.retryAt["failing"] = time.Time{}
+		is.handleViolation(violation)
+		assert.WithinDuration(t, time.Now().Add(wait), group.retryAt["failing"], time.Second)
+	}
+	assert.Equal(t, 4, tracker.creates)
+
+	group.failures["failing"] = 20
+	group.retryAt["failing"] = time.Time{}
+	is.handleViolation(violation)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), group.retryAt["failing"], time.Second)
+}
@@ -0,0 +1,214 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package issues
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+var trackerClient = &http.Client{Timeout: 30 * time.Second}
+
+// GitHubTracker files issues using the GitHub Issues REST API.
+type GitHubTracker struct {
+	apiURL     string
+	repository string
+	token      string
+	labels     []string
+}
+
+func NewGitHubTracker(config *shared.WiretapIssueTrackerConfig, token string) *GitHubTracker {
+	apiURL := config.URL
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	return &GitHubTracker{
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		repository: config.Repository,
+		token:      token,
+		labels:     append([]string{"wiretap"}, config.Labels...),
+	}
+}
+
+func (gt *GitHubTracker) Name() string {
+	return fmt.Sprintf("github:%s", gt.repository)
+}
+
+func (gt *GitHubTracker) FindIssue(fingerprint string) (string, error) {
+	q := fmt.Sprintf("repo:%s is:issue is:open \"%s\" in:body", gt.repository, fingerprint)
+	var result struct {
+		Items []struct {
+			Number int `json:"number"`
+		} `json:"items"`
+	}
+	if err := gt.call(http.MethodGet, "/search/issues?q="+url.QueryEscape(q), nil, &result); err != nil {
+		return "", err
+	}
+	if len(result.Items) > 0 {
+		return fmt.Sprint(result.Items[0].Number), nil
+	}
+	return "", nil
+}
+
+func (gt *GitHubTracker) CreateIssue(issue *Issue) (string, error) {
+	payload := map[string]any{
+		"title":  issue.Title,
+		"body":   issue.Body,
+		"labels": append(gt.labels, issue.Labels...),
+	}
+	var result struct {
+		Number int `json:"number"`
+	}
+	if err := gt.call(http.MethodPost, fmt.Sprintf("/repos/%s/issues", gt.repository), payload, &result); err != nil {
+		return "", err
+	}
+	return fmt.Sprint(result.Number), nil
+}
+
+func (gt *GitHubTracker) UpdateIssue(id string, comment string) error {
+	return gt.call(http.MethodPost, fmt.Sprintf("/repos/%s/issues/%s/comments", gt.repository, id),
+		map[string]any{"body": comment}, nil)
+}
+
+func (gt *GitHubTracker) call(method, path string, payload any, result any) error {
+	req, err := buildTrackerRequest(method, gt.apiURL+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if gt.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", gt.token))
+	}
+	return doTrackerRequest(req, result)
+}
+
+// JiraTracker files issues using the Jira REST API (v2).
+type JiraTracker struct {
+	apiURL    string
+	project   string
+	issueType string
+	user      string
+	token     string
+	labels    []string
+}
+
+func NewJiraTracker(config *shared.WiretapIssueTrackerConfig, token string) *JiraTracker {
+	issueType := config.IssueType
+	if issueType == "" {
+		issueType = "Bug"
+	}
+	return &JiraTracker{
+		apiURL:    strings.TrimSuffix(config.URL, "/"),
+		project:   config.Project,
+		issueType: issueType,
+		user:      config.User,
+		token:     token,
+		labels:    append([]string{"wiretap"}, config.Labels...),
+	}
+}
+
+func (jt *JiraTracker) Name() string {
+	return fmt.Sprintf("jira:%s", jt.project)
+}
+
+func (jt *JiraTracker) FindIssue(fingerprint string) (string, error) {
+	payload := map[string]any{
+		"jql":        fmt.Sprintf("project = \"%s\" AND text ~ \"%s\" AND statusCategory != Done", jt.project, fingerprint),
+		"maxResults": 1,
+		"fields":     []string{"key"},
+	}
+	var result struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}
+	if err := jt.call(http.MethodPost, "/rest/api/2/search", payload, &result); err != nil {
+		return "", err
+	}
+	if len(result.Issues) > 0 {
+		return result.Issues[0].Key, nil
+	}
+	return "", nil
+}
+
+func (jt *JiraTracker) CreateIssue(issue *Issue) (string, error) {
+	payload := map[string]any{
+		"fields": map[string]any{
+			"project":     map[string]string{"key": jt.project},
+			"summary":     issue.Title,
+			"description": issue.Body,
+			"issuetype":   map[string]string{"name": jt.issueType},
+			"labels":      append(jt.labels, issue.Labels...),
+		},
+	}
+	var result struct {
+		Key string `json:"key"`
+	}
+	if err := jt.call(http.MethodPost, "/rest/api/2/issue", payload, &result); err != nil {
+		return "", err
+	}
+	return result.Key, nil
+}
+
+func (jt *JiraTracker) UpdateIssue(id string, comment string) error {
+	return jt.call(http.MethodPost, fmt.Sprintf("/rest/api/2/issue/%s/comment", id),
+		map[string]any{"body": comment}, nil)
+}
+
+func (jt *JiraTracker) call(method, path string, payload any, result any) error {
+	req, err := buildTrackerRequest(method, jt.apiURL+path, payload)
+	if err != nil {
+		return err
+	}
+	if jt.user != "" {
+		req.SetBasicAuth(jt.user, jt.token)
+	} else if jt.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jt.token))
+	}
+	return doTrackerRequest(req, result)
+}
+
+func buildTrackerRequest(method, target string, payload any) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+func doTrackerRequest(req *http.Request, result any) error {
+	resp, err := trackerClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s failed with code %d: %s", req.Method, req.URL.Path, resp.StatusCode, string(b))
+	}
+	if result != nil && len(b) > 0 {
+		return json.Unmarshal(b, result)
+	}
+	return nil
+}
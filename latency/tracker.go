@@ -0,0 +1,148 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package latency tracks how long the upstream API takes to respond, grouped by path, so contract testing runs
+// double as a performance baseline.
+package latency
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MaxSamples is the most durations kept for a path, beyond that a random sample is kept, so long runs don't grow
+// without bound and percentiles still cover the whole run.
+const MaxSamples = 10000
+
+// DefaultBuckets are the upper bounds (in milliseconds) of latency histograms.
+var DefaultBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// Tracker keeps the durations seen for every path.
+type Tracker struct {
+	buckets []float64
+	paths   map[string]*samples
+	lock    sync.Mutex
+}
+
+type samples struct {
+	count     int
+	total     time.Duration
+	min, max  time.Duration
+	durations []time.Duration
+	buckets   []int
+}
+
+// NewTracker creates a tracker with histogram bucket upper bounds (in milliseconds, sorted), DefaultBuckets if
+// there are none.
+func NewTracker(buckets ...float64) *Tracker {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Tracker{buckets: buckets, paths: make(map[string]*samples)}
+}
+
+// Record adds how long a request to a path took.
+func (t *Tracker) Record(path string, duration time.Duration) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	s, ok := t.paths[path]
+	if !ok {
+		s = &samples{min: duration, buckets: make([]int, len(t.buckets)+1)}
+		t.paths[path] = s
+	}
+	s.count++
+	s.total += duration
+	s.min = min(s.min, duration)
+	s.max = max(s.max, duration)
+	if len(s.durations) < MaxSamples {
+		s.durations = append(s.durations, duration)
+	} else if i := rand.Intn(s.count); i < MaxSamples {
+		s.durations[i] = duration
+	}
+	ms := milliseconds(duration)
+	s.buckets[sort.SearchFloat64s(t.buckets, ms)]++
+}
+
+// Bucket is the number of requests that took up to a number of milliseconds (and more than the previous bucket),
+// the last bucket has no upper bound.
+type Bucket struct {
+	UpTo  float64 `json:"upTo,omitempty"`
+	Count int     `json:"count"`
+}
+
+// PathReport is the latency of the requests to a path, in milliseconds.
+type PathReport struct {
+	Path      string    `json:"path"`
+	Requests  int       `json:"requests"`
+	Min       float64   `json:"min"`
+	Mean      float64   `json:"mean"`
+	Max       float64   `json:"max"`
+	P50       float64   `json:"p50"`
+	P95       float64   `json:"p95"`
+	P99       float64   `json:"p99"`
+	Histogram []*Bucket `json:"histogram"`
+}
+
+// Report is the latency of every path, in milliseconds.
+type Report struct {
+	Paths []*PathReport `json:"paths"`
+}
+
+// Report returns the latency so far, sorted by path.
+func (t *Tracker) Report() *Report {
+	report := &Report{Paths: []*PathReport{}}
+	if t == nil {
+		return report
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for path, s := range t.paths {
+		sorted := append([]time.Duration(nil), s.durations...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		pr := &PathReport{
+			Path:     path,
+			Requests: s.count,
+			Min:      round(milliseconds(s.min)),
+			Mean:     round(milliseconds(s.total) / float64(s.count)),
+			Max:      round(milliseconds(s.max)),
+			P50:      round(milliseconds(percentile(sorted, 50))),
+			P95:      round(milliseconds(percentile(sorted, 95))),
+			P99:      round(milliseconds(percentile(sorted, 99))),
+		}
+		for i, count := range s.buckets {
+			b := &Bucket{Count: count}
+			if i < len(t.buckets) {
+				b.UpTo = t.buckets[i]
+			}
+			pr.Histogram = append(pr.Histogram, b)
+		}
+		report.Paths = append(report.Paths, pr)
+	}
+	sort.Slice(report.Paths, func(i, j int) bool { return report.Paths[i].Path < report.Paths[j].Path })
+	return report
+}
+
+// percentile picks the nearest rank of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// round is to two decimal places.
+func round(ms float64) float64 {
+	return math.Round(ms*100) / 100
+}
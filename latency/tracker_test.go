@@ -0,0 +1,44 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package latency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker_Report(t *testing.T) {
+	tracker := NewTracker(10, 100)
+	for i := 1; i <= 100; i++ {
+		tracker.Record("/pets/*", time.Duration(i)*time.Millisecond)
+	}
+	tracker.Record("/api/*", 250*time.Millisecond)
+
+	report := tracker.Report()
+	assert.Len(t, report.Paths, 2)
+	assert.Equal(t, "/api/*", report.Paths[0].Path)
+	assert.Equal(t, []*Bucket{{UpTo: 10}, {UpTo: 100}, {Count: 1}}, report.Paths[0].Histogram)
+
+	pets := report.Paths[1]
+	assert.Equal(t, 100, pets.Requests)
+	assert.Equal(t, 1.0, pets.Min)
+	assert.Equal(t, 50.5, pets.Mean)
+	assert.Equal(t, 100.0, pets.Max)
+	assert.Equal(t, 50.0, pets.P50)
+	assert.Equal(t, 95.0, pets.P95)
+	assert.Equal(t, 99.0, pets.P99)
+	assert.Equal(t, []*Bucket{{UpTo: 10, Count: 10}, {UpTo: 100, Count: 90}, {}}, pets.Histogram)
+}
+
+func TestTracker_Sampled(t *testing.T) {
+	tracker := NewTracker()
+	for i := 0; i < MaxSamples*2; i++ {
+		tracker.Record("/", time.Millisecond)
+	}
+	assert.Len(t, tracker.paths["/"].durations, MaxSamples)
+	assert.Equal(t, MaxSamples*2, tracker.Report().Paths[0].Requests)
+}
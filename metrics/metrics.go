@@ -0,0 +1,249 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package metrics keeps counters and histograms, and exposes them in the Prometheus text format, so wiretap
+// can be scraped without pulling in a metrics client.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds (in seconds) of latency histograms.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// collector is a metric family, written as a block of the text format.
+type collector interface {
+	write(w io.Writer)
+}
+
+// Registry holds metrics, in the order they are registered.
+type Registry struct {
+	collectors []collector
+	sinks      []Sink
+	lock       sync.Mutex
+	sinkLock   sync.RWMutex
+}
+
+// Sink is told about every change to a metric as it happens, so metrics can be pushed (e.g. to StatsD) as well
+// as scraped. Label names and values are in the order the metric declares them.
+type Sink interface {
+	Count(name string, value float64, labels, values []string)
+	Observe(name string, value float64, labels, values []string)
+}
+
+// AddSink pushes every change to a metric to a sink, from now on.
+func (r *Registry) AddSink(sink Sink) {
+	r.sinkLock.Lock()
+	defer r.sinkLock.Unlock()
+	r.sinks = append(r.sinks, sink)
+}
+
+func (r *Registry) push(fn func(sink Sink)) {
+	if r == nil {
+		return
+	}
+	r.sinkLock.RLock()
+	defer r.sinkLock.RUnlock()
+	for _, sink := range r.sinks {
+		fn(sink)
+	}
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counter registers a counter, values are kept for every combination of label values.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{family: family{name: name, help: help, labels: labels, registry: r}, values: make(map[string]float64)}
+	r.register(c)
+	return c
+}
+
+// Histogram registers a histogram with bucket upper bounds (sorted), for every combination of label values.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{family: family{name: name, help: help, labels: labels, registry: r}, buckets: buckets,
+		values: make(map[string]*histogramValue)}
+	r.register(h)
+	return h
+}
+
+func (r *Registry) register(c collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Write writes every metric in the text format.
+func (r *Registry) Write(w io.Writer) {
+	r.lock.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.lock.Unlock()
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// ServeHTTP serves the metrics to a scraper.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	r.Write(w)
+}
+
+// family is the name, help and label names of a metric.
+type family struct {
+	name     string
+	help     string
+	labels   []string
+	registry *Registry
+	lock     sync.Mutex
+}
+
+func (f *family) header(w io.Writer, kind string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escape(f.help, false), f.name, kind)
+}
+
+// key renders label values as the label set of a sample, e.g. `method="GET",code="200"`.
+func (f *family) key(values []string) string {
+	pairs := make([]string, len(f.labels))
+	for i, label := range f.labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = fmt.Sprintf(`%s="%s"`, label, escape(value, true))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	family
+	values map[string]float64
+}
+
+// Inc adds one to the counter for the label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds to the counter for the label values, adding zero makes sure the series exists.
+func (c *Counter) Add(value float64, labelValues ...string) {
+	key := c.key(labelValues)
+	c.lock.Lock()
+	c.values[key] += value
+	c.lock.Unlock()
+	if value != 0 {
+		c.registry.push(func(sink Sink) { sink.Count(c.name, value, c.labels, labelValues) })
+	}
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.header(w, "counter")
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		_, _ = fmt.Fprintf(w, "%s %s\n", series(c.name, key), formatValue(c.values[key]))
+	}
+}
+
+// Histogram counts observations in buckets, with their sum and count.
+type Histogram struct {
+	family
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe records a value for the label values.
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.registry.push(func(sink Sink) { sink.Observe(h.name, value, h.labels, labelValues) })
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = v
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			v.counts[i]++
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.header(w, "histogram")
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+		for i, bound := range h.buckets {
+			_, _ = fmt.Fprintf(w, "%s %d\n", series(h.name+"_bucket", join(key, `le="`+formatValue(bound)+`"`)), v.counts[i])
+		}
+		_, _ = fmt.Fprintf(w, "%s %d\n", series(h.name+"_bucket", join(key, `le="+Inf"`)), v.count)
+		_, _ = fmt.Fprintf(w, "%s %s\n", series(h.name+"_sum", key), formatValue(v.sum))
+		_, _ = fmt.Fprintf(w, "%s %d\n", series(h.name+"_count", key), v.count)
+	}
+}
+
+func series(name, labels string) string {
+	if labels == "" {
+		return name
+	}
+	return name + "{" + labels + "}"
+}
+
+func join(labels, label string) string {
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// escape escapes help text (backslashes and line feeds), and label values (double quotes too).
+func escape(s string, quotes bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quotes {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+	requests := r.Counter("requests_total", "Requests handled.", "method", "path")
+	latency := r.Histogram("latency_seconds", "Time taken.", []float64{0.1, 1}, "source")
+
+	requests.Inc("GET", "/pets")
+	requests.Inc("GET", "/pets")
+	requests.Inc("POST", `/say "hi"`)
+	latency.Observe(0.05, "mock")
+	latency.Observe(0.5, "mock")
+
+	var buf bytes.Buffer
+	r.Write(&buf)
+	assert.Equal(t, `# HELP requests_total Requests handled.
+# TYPE requests_total counter
+requests_total{method="GET",path="/pets"} 2
+requests_total{method="POST",path="/say \"hi\""} 1
+# HELP latency_seconds Time taken.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{source="mock",le="0.1"} 1
+latency_seconds_bucket{source="mock",le="1"} 2
+latency_seconds_bucket{source="mock",le="+Inf"} 2
+latency_seconds_sum{source="mock"} 0.55
+latency_seconds_count{source="mock"} 2
+`, buf.String())
+}
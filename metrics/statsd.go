@@ -0,0 +1,156 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsD formats, DogStatsD carries labels as tags, plain StatsD has no tags so label values are added to names.
+const (
+	FormatDogStatsD = "dogstatsd"
+	FormatStatsD    = "statsd"
+)
+
+// maxPacket keeps a batch of metrics inside a single UDP packet on most networks.
+const maxPacket = 1432
+
+// statsdFlushInterval is the longest a metric waits in a batch before it's sent.
+const statsdFlushInterval = time.Second
+
+// StatsD is a sink that pushes metrics to a StatsD (or DogStatsD) agent over UDP. Counters are sent as counts,
+// histograms are durations in seconds and are sent as timings in milliseconds. Metrics are batched into packets
+// and sent at least every second.
+type StatsD struct {
+	conn   net.Conn
+	prefix string
+	tags   []string
+	format string
+	buffer bytes.Buffer
+	lock   sync.Mutex
+	done   chan struct{}
+	closed sync.Once
+}
+
+// NewStatsD connects to an agent (host:port), metric names are prefixed (e.g. 'myapp.') and tags (e.g. 'env:ci')
+// are sent with every metric, if the format supports them.
+func NewStatsD(address, prefix string, tags []string, format string) (*StatsD, error) {
+	format = strings.ToLower(format)
+	switch format {
+	case "":
+		format = FormatDogStatsD
+	case FormatDogStatsD, FormatStatsD:
+	default:
+		return nil, fmt.Errorf("unknown statsd format '%s', use '%s' or '%s'", format, FormatDogStatsD, FormatStatsD)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "8125")
+	}
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+	s := &StatsD{conn: conn, prefix: prefix, tags: tags, format: format, done: make(chan struct{})}
+	go s.flushEvery(statsdFlushInterval)
+	return s, nil
+}
+
+// Count sends an increase of a counter.
+func (s *StatsD) Count(name string, value float64, labels, values []string) {
+	s.send(name, strconv.FormatFloat(value, 'f', -1, 64), "c", labels, values)
+}
+
+// Observe sends a duration (in seconds) as a timing, in milliseconds.
+func (s *StatsD) Observe(name string, value float64, labels, values []string) {
+	s.send(strings.TrimSuffix(name, "_seconds"), strconv.FormatFloat(value*1000, 'f', 3, 64), "ms", labels, values)
+}
+
+// Close sends anything left in the batch, and disconnects.
+func (s *StatsD) Close() {
+	s.closed.Do(func() {
+		close(s.done)
+		s.flush()
+		_ = s.conn.Close()
+	})
+}
+
+// line renders a metric, e.g. 'wiretap_requests_total:1|c|#method:GET,env:ci'.
+func (s *StatsD) line(name, value, kind string, labels, values []string) string {
+	var tags []string
+	for i, label := range labels {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		if s.format == FormatStatsD {
+			name += "." + sanitize(v)
+		} else {
+			tags = append(tags, sanitize(label)+":"+sanitize(v))
+		}
+	}
+	line := s.prefix + name + ":" + value + "|" + kind
+	if s.format == FormatDogStatsD {
+		tags = append(tags, s.tags...)
+		if len(tags) > 0 {
+			line += "|#" + strings.Join(tags, ",")
+		}
+	}
+	return line
+}
+
+func (s *StatsD) send(name, value, kind string, labels, values []string) {
+	line := s.line(name, value, kind, labels, values)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.buffer.Len() > 0 && s.buffer.Len()+len(line)+1 > maxPacket {
+		s.write()
+	}
+	if s.buffer.Len() > 0 {
+		s.buffer.WriteByte('\n')
+	}
+	s.buffer.WriteString(line)
+}
+
+func (s *StatsD) flushEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *StatsD) flush() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.write()
+}
+
+// write sends the batch, metrics are dropped if the agent isn't there (it's UDP, nobody would know anyway).
+func (s *StatsD) write() {
+	if s.buffer.Len() == 0 {
+		return
+	}
+	_, _ = s.conn.Write(s.buffer.Bytes())
+	s.buffer.Reset()
+}
+
+// sanitize replaces the characters that have a meaning in the StatsD protocol.
+func sanitize(s string) string {
+	return strings.NewReplacer(":", "_", "|", "_", ",", "_", "#", "_", "@", "_", "\n", "_").Replace(s)
+}
@@ -0,0 +1,51 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsD(t *testing.T) {
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer agent.Close()
+
+	received := func() string {
+		buf := make([]byte, maxPacket)
+		_ = agent.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, _ := agent.ReadFrom(buf)
+		return string(buf[:n])
+	}
+
+	for _, tc := range []struct {
+		format, expected string
+	}{
+		{FormatDogStatsD, "wiretap.requests_total:1|c|#method:GET,code:200,env:ci\n" +
+			"wiretap.duration:250.000|ms|#source:proxy,env:ci"},
+		{FormatStatsD, "wiretap.requests_total.GET.200:1|c\nwiretap.duration.proxy:250.000|ms"},
+	} {
+		r := NewRegistry()
+		requests := r.Counter("requests_total", "Requests handled.", "method", "code")
+		duration := r.Histogram("duration_seconds", "Time taken.", DefaultBuckets, "source")
+
+		statsd, sErr := NewStatsD(agent.LocalAddr().String(), "wiretap", []string{"env:ci"}, tc.format)
+		assert.NoError(t, sErr)
+		r.AddSink(statsd)
+
+		requests.Add(0, "GET", "200") // nothing is sent for series that are only created.
+		requests.Inc("GET", "200")
+		duration.Observe(0.25, "proxy")
+		statsd.Close()
+		assert.Equal(t, tc.expected, received())
+	}
+
+	_, err = NewStatsD("localhost:8125", "", nil, "graphite")
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// ErrorRate returns the fraction (between 0 and 1) of mocked responses for a request that should be errors.
+type ErrorRate func(request *http.Request) float64
+
+// SetErrorRate enables error injection, a fraction of mocked responses are drawn from the 4xx and 5xx responses
+// of the operation, instead of the success response.
+func (rme *ResponseMockEngine) SetErrorRate(rate ErrorRate) {
+	rme.errorRate = rate
+}
+
+// injectError decides if a request gets an error, and if so, renders one of the error responses of the operation
+// picked at random. Returns false if no error was injected, or the operation has no error responses.
+func (rme *ResponseMockEngine) injectError(operation *v3.Operation, request *http.Request) ([]byte, int, bool) {
+	if rme.errorRate == nil || operation == nil || operation.Responses == nil {
+		return nil, 0, false
+	}
+	rate := rme.errorRate(request)
+	if rate <= 0 || rand.Float64() >= rate {
+		return nil, 0, false
+	}
+	var codes []string
+	for codePairs := operation.Responses.Codes.First(); codePairs != nil; codePairs = codePairs.Next() {
+		if code, err := strconv.Atoi(codePairs.Key()); err == nil && code >= 400 {
+			codes = append(codes, codePairs.Key())
+		}
+	}
+	if len(codes) == 0 {
+		return nil, 0, false
+	}
+	mock, code, found, err := rme.selectExample(operation, request, codes[rand.Intn(len(codes))])
+	if !found || err != nil {
+		return nil, 0, false
+	}
+	return mock, code, true
+}
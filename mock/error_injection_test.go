@@ -0,0 +1,99 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errorSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          content:
+            application/json:
+              example:
+                - name: dave
+        '404':
+          content:
+            application/json:
+              example:
+                message: no pets
+        '503':
+          content:
+            application/json:
+              example:
+                message: try later
+  /health:
+    get:
+      responses:
+        '200':
+          content:
+            application/json:
+              example:
+                healthy: true`
+
+func errorEngine(t *testing.T, rate float64) *ResponseMockEngine {
+	d, err := libopenapi.NewDocument([]byte(errorSpec))
+	require.NoError(t, err)
+	compiled, errs := d.BuildV3Model()
+	require.Empty(t, errs)
+	me := NewMockEngine(&compiled.Model, false)
+	me.SetSeed(42)
+	me.SetErrorRate(func(*http.Request) float64 { return rate })
+	return me
+}
+
+// mockCodes generates a mock for a path a number of times, and returns the status codes of the mocks.
+func mockCodes(t *testing.T, me *ResponseMockEngine, path string, times int) []int {
+	codes := make([]int, times)
+	for i := range codes {
+		request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io"+path, nil)
+		_, code, err := me.GenerateResponse(request)
+		require.NoError(t, err)
+		codes[i] = code
+	}
+	return codes
+}
+
+func TestResponseMockEngine_ErrorRate(t *testing.T) {
+	// no errors are injected at a rate of zero, or without a rate.
+	for _, code := range mockCodes(t, errorEngine(t, 0), "/pets", 50) {
+		assert.Equal(t, http.StatusOK, code)
+	}
+	me := errorEngine(t, 0)
+	me.SetErrorRate(nil)
+	for _, code := range mockCodes(t, me, "/pets", 50) {
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	// every response is one of the error responses of the operation at a rate of one, both get picked.
+	seen := make(map[int]bool)
+	for _, code := range mockCodes(t, errorEngine(t, 1), "/pets", 50) {
+		assert.Contains(t, []int{http.StatusNotFound, http.StatusServiceUnavailable}, code)
+		seen[code] = true
+	}
+	assert.Len(t, seen, 2)
+
+	// an operation without error responses always succeeds.
+	for _, code := range mockCodes(t, errorEngine(t, 1), "/health", 50) {
+		assert.Equal(t, http.StatusOK, code)
+	}
+}
+
+func TestResponseMockEngine_ErrorRateSeeded(t *testing.T) {
+	// the same seed injects the same errors.
+	first := mockCodes(t, errorEngine(t, 0.5), "/pets", 50)
+	assert.Equal(t, first, mockCodes(t, errorEngine(t, 0.5), "/pets", 50))
+	assert.Contains(t, first, http.StatusOK)
+	assert.Contains(t, first, http.StatusNotFound)
+}
@@ -0,0 +1,91 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// ExampleHeader allows clients to select a named example, or a status code, from the specification.
+const ExampleHeader = "X-Wiretap-Example"
+
+// ExampleQueryParam does the same as ExampleHeader, for clients that cannot set headers (like a browser).
+const ExampleQueryParam = "__example"
+
+// extractSelectedExample returns the example (or status code) requested by the client, the header wins.
+func (rme *ResponseMockEngine) extractSelectedExample(request *http.Request) string {
+	if selected := request.Header.Get(ExampleHeader); selected != "" {
+		return selected
+	}
+	return request.URL.Query().Get(ExampleQueryParam)
+}
+
+// selectExample renders the response for a selected example. A status code (e.g. 429) selects the response for
+// that code, anything else is treated as the name of an example, which is searched for across all responses
+// of the operation. Returns false if nothing in the operation matches the selection.
+func (rme *ResponseMockEngine) selectExample(operation *v3.Operation, request *http.Request,
+	selected string) ([]byte, int, bool, error) {
+
+	if operation == nil || operation.Responses == nil {
+		return nil, 0, false, nil
+	}
+
+	// is this a status code?
+	if code, err := strconv.Atoi(selected); err == nil && len(selected) == 3 {
+		if operation.Responses.Codes.GetOrZero(selected) == nil {
+			return nil, 0, false, nil
+		}
+		mt, _ := rme.lookForResponseCodes(operation, request, []string{selected})
+		if mt == nil {
+			return []byte{}, code, true, nil
+		}
+		mock, mockErr := rme.generateMock(mt, request)
+		return mock, code, true, mockErr
+	}
+
+	// search all responses for a named example, the request media type is checked first.
+	mediaType := rme.extractMediaTypeHeader(request)
+	for codePairs := operation.Responses.Codes.First(); codePairs != nil; codePairs = codePairs.Next() {
+		resp := codePairs.Value()
+		if resp == nil || resp.Content == nil {
+			continue
+		}
+		code, err := strconv.Atoi(codePairs.Key())
+		if err != nil {
+			continue // wildcard codes (4XX) cannot be returned.
+		}
+		candidates := []*v3.MediaType{resp.Content.GetOrZero(mediaType)}
+		for mtPairs := resp.Content.First(); mtPairs != nil; mtPairs = mtPairs.Next() {
+			candidates = append(candidates, mtPairs.Value())
+		}
+		for _, mt := range candidates {
+			if mt == nil || mt.Examples == nil {
+				continue
+			}
+			if example := mt.Examples.GetOrZero(selected); example != nil && example.Value != nil {
+				var value any
+				if err = example.Value.Decode(&value); err != nil {
+					return nil, code, true, err
+				}
+				return rme.render(value), code, true, nil
+			}
+		}
+	}
+	return nil, 0, false, nil
+}
+
+func (rme *ResponseMockEngine) buildExampleNotFound(request *http.Request, selected string) []byte {
+	return rme.buildError(
+		400,
+		"Requested example not found",
+		fmt.Sprintf("The example or status code '%s' requested via '%s' (or '%s') does not exist for '%s' on '%s'",
+			selected, ExampleHeader, ExampleQueryParam, request.Method, request.URL.Path),
+		"example_not_found",
+	)
+}
@@ -0,0 +1,59 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var exampleSpec = `openapi: 3.1.0
+paths:
+  /orders:
+    get:
+      responses:
+        '200':
+          content:
+            application/json:
+              examples:
+                happy:
+                  value:
+                    status: ok
+        '429':
+          content:
+            application/json:
+              examples:
+                rate-limited:
+                  value:
+                    message: slow down
+        '404':
+          description: not found`
+
+func TestResponseMockEngine_SelectExample(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(exampleSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/orders", nil)
+	request.Header.Set(ExampleHeader, "rate-limited")
+	b, status, err := me.GenerateResponse(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 429, status)
+	assert.JSONEq(t, `{"message":"slow down"}`, string(b))
+
+	request, _ = http.NewRequest(http.MethodGet, "https://api.pb33f.io/orders?__example=404", nil)
+	b, status, err = me.GenerateResponse(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 404, status)
+	assert.Empty(t, b)
+
+	request, _ = http.NewRequest(http.MethodGet, "https://api.pb33f.io/orders?__example=nope", nil)
+	_, status, err = me.GenerateResponse(request)
+	assert.Error(t, err)
+	assert.Equal(t, 400, status)
+}
@@ -0,0 +1,126 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// FakerExtension can be set on any schema to pick the faker function used to generate its value,
+// for example `x-faker: firstname` or `x-faker: address.city`.
+const FakerExtension = "x-faker"
+
+// fakeMock replaces the placeholder values generated for a media type schema with realistic values from the faker,
+// driven by `x-faker` extensions and `format` values. Mocks rendered from examples are left alone.
+func (rme *ResponseMockEngine) fakeMock(mt *v3.MediaType, mock []byte) []byte {
+	if rme.faker == nil || mt == nil || mt.Schema == nil || mt.Example != nil ||
+		(mt.Examples != nil && mt.Examples.Len() > 0) {
+		return mock
+	}
+	return rme.fakeSchemaMock(mt.Schema.Schema(), mock)
+}
+
+// fakeSchemaMock does the same as fakeMock, for a mock rendered straight from a schema.
+func (rme *ResponseMockEngine) fakeSchemaMock(schema *base.Schema, mock []byte) []byte {
+	if rme.faker == nil || schema == nil || len(mock) == 0 {
+		return mock
+	}
+	var value any
+	if err := json.Unmarshal(mock, &value); err != nil {
+		return mock // not JSON, nothing to do.
+	}
+	return rme.render(rme.fakeValue(schema, value, 0))
+}
+
+// fakeValue walks a rendered value alongside its schema, replacing values that have a faker hint.
+func (rme *ResponseMockEngine) fakeValue(schema *base.Schema, value any, depth int) any {
+	if schema == nil || depth > 50 || schema.Example != nil || len(schema.Examples) > 0 {
+		return value
+	}
+	if schema.Extensions != nil {
+		if hint := schema.Extensions.GetOrZero(FakerExtension); hint != nil {
+			if faked, ok := rme.fakeByName(hint.Value); ok {
+				return faked
+			}
+		}
+	}
+	for _, s := range schema.AllOf {
+		value = rme.fakeValue(s.Schema(), value, depth+1)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if schema.Properties != nil {
+			for pair := schema.Properties.First(); pair != nil; pair = pair.Next() {
+				if pv, ok := v[pair.Key()]; ok {
+					v[pair.Key()] = rme.fakeValue(pair.Value().Schema(), pv, depth+1)
+				}
+			}
+		}
+		return v
+	case []any:
+		if schema.Items != nil && schema.Items.IsA() {
+			for i := range v {
+				v[i] = rme.fakeValue(schema.Items.A.Schema(), v[i], depth+1)
+			}
+		}
+		return v
+	case string:
+		if faked, ok := rme.fakeByFormat(schema.Format); ok {
+			return faked
+		}
+	}
+	return value
+}
+
+// fakeByName looks up a faker function by name, dotted names (address.city) use the last segment.
+func (rme *ResponseMockEngine) fakeByName(name string) (any, bool) {
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	info := gofakeit.GetFuncLookup(strings.ToLower(name))
+	if info == nil {
+		return nil, false
+	}
+	value, err := info.Generate(rme.faker.Rand, nil, info)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func (rme *ResponseMockEngine) fakeByFormat(format string) (string, bool) {
+	switch strings.ToLower(format) {
+	case "email", "idn-email":
+		return rme.faker.Email(), true
+	case "uuid":
+		return rme.faker.UUID(), true
+	case "date-time":
+		return rme.faker.Date().Format(time.RFC3339), true
+	case "date":
+		return rme.faker.Date().Format(time.DateOnly), true
+	case "time":
+		return rme.faker.Date().Format(time.TimeOnly), true
+	case "uri", "url", "iri":
+		return rme.faker.URL(), true
+	case "hostname", "idn-hostname":
+		return rme.faker.DomainName(), true
+	case "ipv4":
+		return rme.faker.IPv4Address(), true
+	case "ipv6":
+		return rme.faker.IPv6Address(), true
+	case "phone", "tel":
+		return rme.faker.Phone(), true
+	case "name":
+		return rme.faker.Name(), true
+	}
+	return "", false
+}
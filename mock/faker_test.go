@@ -0,0 +1,86 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var fakerSpec = `openapi: 3.1.0
+paths:
+  /customers/{customerId}:
+    get:
+      parameters:
+        - name: customerId
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id, email, name, city, nickname]
+                properties:
+                  id:
+                    type: string
+                    format: uuid
+                  email:
+                    type: string
+                    format: email
+                  name:
+                    type: string
+                    x-faker: person.firstName
+                  city:
+                    type: string
+                    x-faker: address.city
+                  nickname:
+                    type: string
+                    example: chief`
+
+func TestResponseMockEngine_Faker(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(fakerSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/customers/abc", nil)
+	b, status, err := me.GenerateResponse(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, status)
+
+	var customer map[string]any
+	assert.NoError(t, json.Unmarshal(b, &customer))
+
+	_, err = uuid.Parse(customer["id"].(string))
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(customer["email"].(string), "@"))
+	assert.NotEmpty(t, customer["name"])
+	assert.NotEmpty(t, customer["city"])
+	assert.Equal(t, "chief", customer["nickname"])
+}
+
+func TestResponseMockEngine_Seed(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(fakerSpec))
+	compiled, _ := d.BuildV3Model()
+
+	generate := func() []byte {
+		me := NewMockEngine(&compiled.Model, false)
+		me.SetSeed(42)
+		request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/customers/abc", nil)
+		b, _, err := me.GenerateResponse(request)
+		assert.NoError(t, err)
+		return b
+	}
+	assert.Equal(t, string(generate()), string(generate()))
+}
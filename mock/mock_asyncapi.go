@@ -0,0 +1,31 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"fmt"
+
+	"github.com/pb33f/wiretap/asyncapi"
+)
+
+// GenerateMessage generates a message for an AsyncAPI channel. Messages with examples are picked from the examples,
+// otherwise the message is rendered from the payload schema, with fake data, like any other mock.
+func (rme *ResponseMockEngine) GenerateMessage(message *asyncapi.Message) ([]byte, error) {
+	if len(message.Examples) > 0 {
+		return message.Examples[rme.faker.Rand.Intn(len(message.Examples))], nil
+	}
+	if message.Payload == nil {
+		return nil, fmt.Errorf("message '%s' has no payload schema or examples", message.Name)
+	}
+	schema := message.Payload
+	if variant := rme.selectVariants(schema, ""); variant != nil {
+		schema = variant
+	}
+	mock, err := rme.mockEngine.GenerateMock(schema, "")
+	if err != nil {
+		return nil, err
+	}
+	return rme.fakeSchemaMock(schema, mock), nil
+}
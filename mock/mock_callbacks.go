@@ -0,0 +1,239 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// runtimeExpression matches the runtime expressions embedded in a callback URL, e.g. {$request.body#/callbackUrl}
+var runtimeExpression = regexp.MustCompile(`\{(\$[^}]+)}`)
+
+// callbackContext holds everything a runtime expression can refer to.
+type callbackContext struct {
+	request      *http.Request
+	requestBody  []byte
+	responseBody []byte
+	status       int
+	pathParams   map[string]string
+}
+
+// BuildCallbacks builds the requests for the callbacks of the operation a request was mocked for, with mocked
+// bodies. Callback URLs are resolved from the runtime expressions in the specification (for example
+// `{$request.body#/callbackUrl}`), callbacks that cannot be resolved to a URL are skipped.
+func (rme *ResponseMockEngine) BuildCallbacks(request *http.Request, requestBody, responseBody []byte,
+	status int) []*http.Request {
+
+	operation, _, ctx := rme.callbackContext(request, requestBody, responseBody, status)
+	if operation == nil || operation.Callbacks == nil {
+		return nil
+	}
+	var callbacks []*http.Request
+	for cbPairs := operation.Callbacks.First(); cbPairs != nil; cbPairs = cbPairs.Next() {
+		if cbPairs.Value() == nil || cbPairs.Value().Expression == nil {
+			continue
+		}
+		for exPairs := cbPairs.Value().Expression.First(); exPairs != nil; exPairs = exPairs.Next() {
+			if target, ok := ctx.resolve(exPairs.Key()); ok {
+				callbacks = append(callbacks, rme.buildPathItemRequests(target, exPairs.Value())...)
+			}
+		}
+	}
+	return callbacks
+}
+
+// BuildWebhooks builds the requests for the webhooks (keyed by the name of the webhook in the specification)
+// that are triggered by the operation a request was mocked for, with mocked bodies.
+func (rme *ResponseMockEngine) BuildWebhooks(request *http.Request, requestBody, responseBody []byte,
+	status int, webhooks map[string]*shared.WiretapMockWebhook) []*http.Request {
+
+	if rme.doc.Webhooks == nil || len(webhooks) == 0 {
+		return nil
+	}
+	operation, template, ctx := rme.callbackContext(request, requestBody, responseBody, status)
+	if operation == nil {
+		return nil
+	}
+	key := strings.ToUpper(request.Method) + " " + template
+	var requests []*http.Request
+	for name, webhook := range webhooks {
+		if webhook == nil || webhook.URL == "" ||
+			(webhook.Trigger != key && (operation.OperationId == "" || webhook.Trigger != operation.OperationId)) {
+			continue
+		}
+		if pathItem := rme.doc.Webhooks.GetOrZero(name); pathItem != nil {
+			if target, ok := ctx.resolve(webhook.URL); ok {
+				requests = append(requests, rme.buildPathItemRequests(target, pathItem)...)
+			}
+		}
+	}
+	return requests
+}
+
+// callbackContext locates the operation a request is for, and builds the context runtime expressions are
+// resolved from.
+func (rme *ResponseMockEngine) callbackContext(request *http.Request, requestBody, responseBody []byte,
+	status int) (*v3.Operation, string, *callbackContext) {
+
+	pathItem, _, template := paths.FindPath(request, rme.doc)
+	if pathItem == nil {
+		return nil, "", nil
+	}
+	return rme.findOperation(request, pathItem), template, &callbackContext{
+		request:      request,
+		requestBody:  requestBody,
+		responseBody: responseBody,
+		status:       status,
+		pathParams:   extractPathParams(template, request.URL.Path),
+	}
+}
+
+// buildPathItemRequests builds a request for every operation of a callback or webhook path item.
+func (rme *ResponseMockEngine) buildPathItemRequests(target string, pathItem *v3.PathItem) []*http.Request {
+	if pathItem == nil {
+		return nil
+	}
+	var requests []*http.Request
+	for opPairs := pathItem.GetOperations().First(); opPairs != nil; opPairs = opPairs.Next() {
+		var body []byte
+		contentType := ""
+		if rb := opPairs.Value().RequestBody; rb != nil && rb.Content != nil && rb.Content.First() != nil {
+			contentType = "application/json"
+			mt := rb.Content.GetOrZero(contentType)
+			if mt == nil {
+				contentType, mt = rb.Content.First().Key(), rb.Content.First().Value()
+			}
+			if mock, err := rme.mockEngine.GenerateMock(mt, ""); err == nil {
+				body = rme.fakeMock(mt, mock)
+			}
+		}
+		callback, err := http.NewRequest(strings.ToUpper(opPairs.Key()), target, bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		if contentType != "" {
+			callback.Header.Set("Content-Type", contentType)
+		}
+		requests = append(requests, callback)
+	}
+	return requests
+}
+
+// resolve replaces the runtime expressions in a callback URL, a URL is only returned if every expression resolved.
+func (ctx *callbackContext) resolve(expression string) (string, bool) {
+	resolved := true
+	target := runtimeExpression.ReplaceAllStringFunc(expression, func(match string) string {
+		value, ok := ctx.evaluate(match[1 : len(match)-1])
+		if !ok {
+			resolved = false
+		}
+		return value
+	})
+	if !resolved {
+		return "", false
+	}
+	if u, err := url.Parse(target); err != nil || u.Scheme == "" || u.Host == "" {
+		return "", false
+	}
+	return target, true
+}
+
+// evaluate returns the value of a single runtime expression.
+// https://spec.openapis.org/oas/v3.1.0#runtime-expressions
+func (ctx *callbackContext) evaluate(expression string) (string, bool) {
+	switch {
+	case expression == "$url":
+		scheme := "http"
+		if ctx.request.TLS != nil {
+			scheme = "https"
+		}
+		return scheme + "://" + ctx.request.Host + ctx.request.URL.RequestURI(), true
+	case expression == "$method":
+		return ctx.request.Method, true
+	case expression == "$statusCode":
+		return strconv.Itoa(ctx.status), true
+	case strings.HasPrefix(expression, "$request.header."):
+		value := ctx.request.Header.Get(strings.TrimPrefix(expression, "$request.header."))
+		return value, value != ""
+	case strings.HasPrefix(expression, "$request.query."):
+		value := ctx.request.URL.Query().Get(strings.TrimPrefix(expression, "$request.query."))
+		return value, value != ""
+	case strings.HasPrefix(expression, "$request.path."):
+		value := ctx.pathParams[strings.TrimPrefix(expression, "$request.path.")]
+		return value, value != ""
+	case strings.HasPrefix(expression, "$request.body"):
+		return jsonPointer(ctx.requestBody, strings.TrimPrefix(expression, "$request.body"))
+	case strings.HasPrefix(expression, "$response.body"):
+		return jsonPointer(ctx.responseBody, strings.TrimPrefix(expression, "$response.body"))
+	}
+	return "", false
+}
+
+// jsonPointer looks up a value in a JSON body using a pointer fragment (e.g. #/subscriber/url).
+func jsonPointer(body []byte, fragment string) (string, bool) {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", false
+	}
+	var tokens []string
+	if pointer := strings.TrimPrefix(strings.TrimPrefix(fragment, "#"), "/"); pointer != "" {
+		for _, token := range strings.Split(pointer, "/") {
+			tokens = append(tokens, strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~"))
+		}
+	}
+	value, ok := lookupJSON(value, tokens)
+	if !ok {
+		return "", false
+	}
+	text := templateText(value)
+	return text, text != ""
+}
+
+// lookupJSON walks a decoded JSON value, one object key or array index at a time.
+func lookupJSON(value any, tokens []string) (any, bool) {
+	for _, token := range tokens {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[token]
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// extractPathParams extracts the values of path parameters from a path, using the path template it matched.
+func extractPathParams(template, path string) map[string]string {
+	params := make(map[string]string)
+	templateSegments := strings.Split(strings.Trim(template, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	// the path may include a base path, so line the segments up from the end.
+	offset := len(pathSegments) - len(templateSegments)
+	if offset < 0 {
+		return params
+	}
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = pathSegments[i+offset]
+		}
+	}
+	return params
+}
@@ -7,6 +7,7 @@ import (
     "encoding/json"
     "errors"
     "fmt"
+    "github.com/brianvoe/gofakeit/v6"
     libopenapierrs "github.com/pb33f/libopenapi-validator/errors"
     "github.com/pb33f/libopenapi-validator/helpers"
     "github.com/pb33f/libopenapi-validator/paths"
@@ -14,16 +15,30 @@ import (
     "github.com/pb33f/libopenapi/renderer"
     "github.com/pb33f/wiretap/shared"
     "github.com/pb33f/wiretap/validation"
+    "math/rand"
     "net/http"
     "strconv"
     "strings"
+    "sync"
 )
 
 type ResponseMockEngine struct {
-    doc        *v3.Document
-    validator  validation.HttpValidator
-    mockEngine *renderer.MockGenerator
-    pretty     bool
+    doc            *v3.Document
+    validator      validation.HttpValidator
+    mockEngine     *renderer.MockGenerator
+    pretty         bool
+    state          *ResourceStore
+    faker          *gofakeit.Faker
+    sequences      *sequenceTracker
+    variants       *variantSelector
+    pagination     *paginator
+    overrides      *Overrides
+    errorRate      ErrorRate
+    validation     string
+    mediaTypes     map[*v3.MediaType]string
+    mediaTypesOnce sync.Once
+    contentTypes   sync.Map // content type of the mock generated for each in-flight request.
+    headers        sync.Map // extra headers to send with the mock for each in-flight request.
 }
 
 func NewMockEngine(document *v3.Document, pretty bool) *ResponseMockEngine {
@@ -36,11 +51,84 @@ func NewMockEngine(document *v3.Document, pretty bool) *ResponseMockEngine {
         validator:  validation.NewHttpValidator(document),
         mockEngine: me,
         pretty:     pretty,
+        faker:      gofakeit.New(0),
     }
 }
 
+// SetStateful enables stateful mocks, resources written with POST/PUT/PATCH/DELETE are persisted in memory
+// and returned by subsequent GET requests.
+func (rme *ResponseMockEngine) SetStateful() {
+    rme.state = NewResourceStore()
+}
+
+// SetRequestValidation sets how invalid requests are handled: `reject` (the default) responds with a 422 and the
+// violations, `warn` and `ignore` mock the response anyway, `ignore` doesn't even validate the request.
+func (rme *ResponseMockEngine) SetRequestValidation(mode string) {
+    rme.validation = strings.ToLower(mode)
+}
+
+// SetSeed makes mock generation reproducible, the same seed generates the same sequence of mocks and fake data.
+// The schema renderer uses the global random source, so that is seeded too.
+func (rme *ResponseMockEngine) SetSeed(seed int64) {
+    rand.Seed(seed)
+    rme.faker = gofakeit.New(seed)
+}
+
 func (rme *ResponseMockEngine) GenerateResponse(request *http.Request) ([]byte, int, error) {
-    return rme.runWorkflow(request)
+    mock, status, _, err := rme.GenerateResponseWithContentType(request)
+    return mock, status, err
+}
+
+// GenerateResponseWithContentType generates a mock response, along with the content type of the media type
+// the mock was generated from. Anything that isn't generated from a media type (errors, empty responses) is JSON.
+func (rme *ResponseMockEngine) GenerateResponseWithContentType(request *http.Request) ([]byte, int, string, error) {
+    mock, status, headers, err := rme.GenerateResponseWithHeaders(request)
+    return mock, status, headers.Get("Content-Type"), err
+}
+
+// GenerateResponseWithHeaders generates a mock response, along with the headers to send with it, which always
+// includes the content type.
+func (rme *ResponseMockEngine) GenerateResponseWithHeaders(request *http.Request) ([]byte, int, http.Header, error) {
+    defer rme.contentTypes.Delete(request)
+    defer rme.headers.Delete(request)
+    mock, status, err := rme.generateResponse(request)
+    headers := http.Header{}
+    if h, ok := rme.headers.Load(request); ok {
+        headers = h.(http.Header)
+    }
+    headers.Set("Content-Type", "application/json")
+    if ct, ok := rme.contentTypes.Load(request); ok {
+        headers.Set("Content-Type", ct.(string))
+    }
+    return mock, status, headers, err
+}
+
+// setResponseHeader sets a header to be sent with the mock generated for a request.
+func (rme *ResponseMockEngine) setResponseHeader(request *http.Request, name, value string) {
+    h, _ := rme.headers.LoadOrStore(request, http.Header{})
+    h.(http.Header).Set(name, value)
+}
+
+func (rme *ResponseMockEngine) generateResponse(request *http.Request) ([]byte, int, error) {
+    var requestBody []byte
+    if rme.state != nil {
+        requestBody = readRequestBody(request)
+    }
+    mock, status, selected, err := rme.runWorkflow(request)
+    if err == nil {
+        mock = rme.applyTemplates(request, mock)
+    }
+
+    // collections are paginated, unless they are stateful.
+    if err == nil && !selected && rme.state == nil && status >= 200 && status <= 299 {
+        mock = rme.paginate(request, mock, status)
+    }
+
+    // selected examples and sequences are always served as-is, they never touch the resource store.
+    if rme.state == nil || selected || err != nil || status < 200 || status > 299 {
+        return mock, status, err
+    }
+    return rme.applyState(request, requestBody, mock, status)
 }
 
 func (rme *ResponseMockEngine) ValidateSecurity(request *http.Request, operation *v3.Operation) error {
@@ -223,11 +311,45 @@ func (rme *ResponseMockEngine) buildErrorWithPayload(status int, title, msg, has
     return rme.render(wte)
 }
 
+// generateMock renders a mock for a media type, and then makes it look realistic with the faker.
+func (rme *ResponseMockEngine) generateMock(mt *v3.MediaType, request *http.Request) ([]byte, error) {
+    var mock []byte
+    var err error
+
+    // polymorphic schemas render the variant picked by the client, or the union strategy.
+    if variant := rme.selectMediaTypeVariants(mt, request); variant != nil {
+        mock, err = rme.mockEngine.GenerateMock(variant, rme.extractPreferred(request))
+        if err != nil {
+            return mock, err
+        }
+        mock = rme.fakeSchemaMock(variant, mock)
+    } else {
+        mock, err = rme.mockEngine.GenerateMock(mt, rme.extractPreferred(request))
+        if err != nil {
+            return mock, err
+        }
+        mock = rme.fakeMock(mt, mock)
+    }
+    name := rme.mediaTypeName(mt)
+    switch {
+    case isXMLMediaType(name):
+        mock = rme.renderXML(mt, mock)
+    case isMultipartMediaType(name):
+        mock, name = rme.renderMultipart(mt, name, mock)
+    case isBinaryMediaType(mt, name):
+        mock = rme.renderBinary(mt, mock)
+    }
+    if name != "" {
+        rme.contentTypes.Store(request, name)
+    }
+    return mock, nil
+}
+
 func (rme *ResponseMockEngine) extractPreferred(request *http.Request) string {
     return request.Header.Get(helpers.Preferred)
 }
 
-func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int, error) {
+func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int, bool, error) {
 
     // get path, not valid? return 404
     path, err := rme.findPath(request)
@@ -238,7 +360,7 @@ func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int,
             fmt.Sprintf("Unable to locate the path '%s' with the method '%s'. %s",
                 request.URL.Path, request.Method, err.Error()),
             "not_found",
-        ), 404, err
+        ), 404, false, err
 
     }
 
@@ -250,7 +372,7 @@ func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int,
     if err != nil {
         mt, _ := rme.lookForResponseCodes(operation, request, []string{"401"})
         if mt != nil {
-            mock, mockErr := rme.mockEngine.GenerateMock(mt, rme.extractPreferred(request))
+            mock, mockErr := rme.generateMock(mt, request)
             if mockErr != nil {
                 return rme.buildError(
                     500,
@@ -258,9 +380,9 @@ func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int,
                     fmt.Sprintf("Errors occurred while generating an error 401 mock response: %s",
                         errors.Join(err, mockErr)),
                     "build_mock_error",
-                ), 500, mockErr
+                ), 500, false, mockErr
             }
-            return mock, 401, err
+            return mock, 401, false, err
         } else {
             return rme.buildError(
                 401,
@@ -268,13 +390,16 @@ func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int,
                 fmt.Sprintf("Unable to call '%s' on '%s', you are not authorized to access this resource",
                     request.Method, request.URL.Path),
                 "build_mock_error",
-            ), 401, err
+            ), 401, false, err
         }
     }
 
     // validate the request against the document.
-    _, validationErrors := rme.validator.ValidateHttpRequest(request)
-    if len(validationErrors) > 0 {
+    var validationErrors []*libopenapierrs.ValidationError
+    if rme.validation != shared.MockValidationIgnore {
+        _, validationErrors = rme.validator.ValidateHttpRequest(request)
+    }
+    if len(validationErrors) > 0 && rme.validation != shared.MockValidationWarn {
         mt, _ := rme.lookForResponseCodes(operation, request, []string{"422", "400"})
         if mt == nil {
             // no default, no valid response, inform use with a 500
@@ -285,7 +410,7 @@ func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int,
                     "'422' or '400' response for this operation. Check payload for validation errors.",
                 "validation_failed_and_spec_insufficient_error",
                 validationErrors,
-            ), 500, rme.packErrors(validationErrors)
+            ), 500, false, rme.packErrors(validationErrors)
         }
         return rme.buildErrorWithPayload(
             422,
@@ -293,13 +418,57 @@ func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int,
             "The request failed validation, Check payload for validation errors.",
             "validation_failed_error",
             validationErrors,
-        ), 422, rme.packErrors(validationErrors)
+        ), 422, false, rme.packErrors(validationErrors)
 
     }
 
+    // has the client selected a specific example or status code? if not, is the operation sequenced?
+    selected := rme.extractSelectedExample(request)
+    if selected == "" {
+        selected = rme.nextInSequence(request)
+    }
+    if selected != "" {
+        mock, code, found, selectErr := rme.selectExample(operation, request, selected)
+        if !found {
+            return rme.buildExampleNotFound(request, selected), 400, true,
+                fmt.Errorf("example '%s' not found", selected)
+        }
+        if selectErr != nil {
+            return rme.buildError(
+                422,
+                "Unable to build mock (422)",
+                fmt.Sprintf("Errors occurred while generating the selected example '%s': %s", selected, selectErr),
+                "build_mock_error",
+            ), 422, true, selectErr
+        }
+        return mock, code, true, nil
+    }
+
+    // inject an error response instead of the success response?
+    if mock, code, injected := rme.injectError(operation, request); injected {
+        return mock, code, true, nil
+    }
+
     // get the lowest success code
     lo := rme.findLowestSuccessCode(operation)
 
+    // has a hand-crafted mock been provided for the operation?
+    if override, found := rme.findOverride(request); found {
+        c, _ := strconv.Atoi(lo)
+        return override, c, true, nil
+    }
+
+    // does the client accept any of the media types the operation returns?
+    if !acceptable(operation, request, lo) {
+        return rme.buildError(
+            406,
+            "Not acceptable",
+            fmt.Sprintf("None of the media types returned by '%s' on '%s' match the Accept header '%s'",
+                request.Method, request.URL.Path, request.Header.Get(acceptHeader)),
+            "not_acceptable",
+        ), 406, false, nil
+    }
+
     // find the lowest success code.
     mt, noMT := rme.lookForResponseCodes(operation, request, []string{lo})
     if mt == nil && noMT {
@@ -309,10 +478,10 @@ func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int,
             "Media type not supported",
             fmt.Sprintf("The media type requested '%s' is not supported by this operation", mtString),
             "build_mock_error",
-        ), 415, nil
+        ), 415, false, nil
     }
 
-    mock, mockErr := rme.mockEngine.GenerateMock(mt, rme.extractPreferred(request))
+    mock, mockErr := rme.generateMock(mt, request)
     if mockErr != nil {
         return rme.buildError(
             422,
@@ -320,10 +489,10 @@ func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int,
             fmt.Sprintf("Errors occurred while generating an error 422 mock response: %s",
                 errors.Join(err, mockErr)),
             "build_mock_error",
-        ), 422, mockErr
+        ), 422, false, mockErr
     }
     c, _ := strconv.Atoi(lo)
-    return mock, c, nil
+    return mock, c, false, nil
 }
 
 func (rme *ResponseMockEngine) findLowestSuccessCode(operation *v3.Operation) string {
@@ -356,12 +525,23 @@ func (rme *ResponseMockEngine) lookForResponseCodes(
             continue
         }
         if resp.Content != nil {
+            // the media type the client accepts wins, then the defaults.
+            if resp.Content.Len() > 1 {
+                rme.setResponseHeader(request, "Vary", acceptHeader)
+            }
+            if negotiated, _ := negotiate(resp.Content, request); negotiated != nil {
+                return negotiated, false
+            }
             responseBody := resp.Content.GetOrZero(mediaTypeString)
             if responseBody != nil {
                 // try and extract a default JSON response
                 return responseBody, false
             } else {
                 responseBody = resp.Content.GetOrZero("application/json")
+                if responseBody == nil && resp.Content.First() != nil {
+                    // no JSON either (e.g. XML only), so use whatever the operation does return.
+                    responseBody = resp.Content.First().Value()
+                }
                 return responseBody, false
             }
         } else {
@@ -371,6 +551,9 @@ func (rme *ResponseMockEngine) lookForResponseCodes(
     }
 
     if op.Responses.Default != nil && op.Responses.Default.Content != nil {
+        if negotiated, _ := negotiate(op.Responses.Default.Content, request); negotiated != nil {
+            return negotiated, false
+        }
         if op.Responses.Default.Content.GetOrZero(mediaTypeString) != nil {
             return op.Responses.Default.Content.GetOrZero(mediaTypeString), false
         }
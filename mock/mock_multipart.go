@@ -0,0 +1,158 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+const (
+	octetStream       = "application/octet-stream"
+	defaultBinarySize = 256
+)
+
+func isMultipartMediaType(mediaType string) bool {
+	return strings.HasPrefix(strings.ToLower(mediaType), "multipart/")
+}
+
+// isBinarySchema checks if a schema describes raw bytes (`format: binary`), media types that are just bytes
+// (application/octet-stream) with no schema are binary too.
+func isBinarySchema(schema *base.Schema) bool {
+	if schema == nil {
+		return false
+	}
+	for _, t := range schema.Type {
+		if t == "string" {
+			return schema.Format == "binary"
+		}
+	}
+	return false
+}
+
+func isBinaryMediaType(mt *v3.MediaType, mediaType string) bool {
+	if mt == nil {
+		return false
+	}
+	if mt.Schema == nil {
+		return strings.EqualFold(mediaType, octetStream)
+	}
+	return isBinarySchema(mt.Schema.Schema())
+}
+
+// renderBinary generates the body of a binary response. Examples are served as they are, otherwise random bytes
+// are generated, sized by the min and max length of the schema.
+func (rme *ResponseMockEngine) renderBinary(mt *v3.MediaType, mock []byte) []byte {
+	if mt.Example != nil || (mt.Examples != nil && mt.Examples.Len() > 0) {
+		var example string
+		if err := json.Unmarshal(mock, &example); err == nil {
+			return []byte(example)
+		}
+		return mock
+	}
+	var schema *base.Schema
+	if mt.Schema != nil {
+		schema = mt.Schema.Schema()
+	}
+	return rme.randomBytes(schema)
+}
+
+func (rme *ResponseMockEngine) randomBytes(schema *base.Schema) []byte {
+	size := defaultBinarySize
+	if schema != nil {
+		if schema.MaxLength != nil && int(*schema.MaxLength) < size {
+			size = int(*schema.MaxLength)
+		}
+		if schema.MinLength != nil && int(*schema.MinLength) > size {
+			size = int(*schema.MinLength)
+		}
+	}
+	b := make([]byte, size)
+	_, _ = rme.faker.Rand.Read(b)
+	return b
+}
+
+// renderMultipart turns a generated (JSON) mock object into a multipart body, each property is sent as a part.
+// Binary properties are sent as files filled with random bytes, objects and arrays are sent as JSON.
+// The content type, including the boundary, is returned with the body.
+func (rme *ResponseMockEngine) renderMultipart(mt *v3.MediaType, mediaType string, mock []byte) ([]byte, string) {
+	var value map[string]any
+	if err := json.Unmarshal(mock, &value); err != nil {
+		return mock, mediaType
+	}
+	var schema *base.Schema
+	if mt.Schema != nil {
+		schema = mt.Schema.Schema()
+	}
+
+	var keys []string
+	properties := make(map[string]*base.Schema)
+	if schema != nil && schema.Properties != nil {
+		for pair := schema.Properties.First(); pair != nil; pair = pair.Next() {
+			properties[pair.Key()] = pair.Value().Schema()
+			if _, ok := value[pair.Key()]; ok {
+				keys = append(keys, pair.Key())
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for _, key := range keys {
+		property := properties[key]
+		header := textproto.MIMEHeader{}
+		var body []byte
+		contentType := ""
+		if mt.Encoding != nil {
+			if encoding := mt.Encoding.GetOrZero(key); encoding != nil && encoding.ContentType != "" {
+				contentType = strings.TrimSpace(strings.Split(encoding.ContentType, ",")[0])
+				if strings.Contains(contentType, "*") {
+					contentType = octetStream // wildcards (image/*) cannot be sent.
+				}
+			}
+		}
+
+		switch v := value[key].(type) {
+		case string:
+			if isBinarySchema(property) {
+				if contentType == "" {
+					contentType = octetStream
+				}
+				header.Set("Content-Disposition",
+					fmt.Sprintf(`form-data; name="%s"; filename="%s"`, key, key))
+				body = rme.randomBytes(property)
+			} else {
+				body = []byte(v)
+			}
+		case map[string]any, []any:
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			body, _ = json.Marshal(v)
+		default:
+			body = []byte(fmt.Sprint(v))
+		}
+		if header.Get("Content-Disposition") == "" {
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, key))
+		}
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			continue
+		}
+		_, _ = part.Write(body)
+	}
+	_ = writer.Close()
+	return buf.Bytes(), fmt.Sprintf("%s; boundary=%s", mediaType, writer.Boundary())
+}
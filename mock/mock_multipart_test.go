@@ -0,0 +1,123 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var multipartSpec = `openapi: 3.1.0
+paths:
+  /files:
+    post:
+      requestBody:
+        content:
+          multipart/form-data:
+            schema:
+              type: object
+              required: [file, size]
+              properties:
+                file:
+                  type: string
+                  format: binary
+                size:
+                  type: integer
+      responses:
+        '200':
+          content:
+            multipart/form-data:
+              schema:
+                type: object
+                properties:
+                  name:
+                    type: string
+                  file:
+                    type: string
+                    format: binary
+                    maxLength: 16
+        '422':
+          content:
+            application/json:
+              schema:
+                type: object
+  /files/{fileId}:
+    get:
+      parameters:
+        - name: fileId
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          content:
+            application/octet-stream:
+              schema:
+                type: string
+                format: binary
+                minLength: 512
+                maxLength: 512`
+
+func multipartEngine() *ResponseMockEngine {
+	d, _ := libopenapi.NewDocument([]byte(multipartSpec))
+	compiled, _ := d.BuildV3Model()
+	return NewMockEngine(&compiled.Model, false)
+}
+
+func uploadRequest(size string) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fw, _ := w.CreateFormFile("file", "pb33f.png")
+	_, _ = fw.Write([]byte{0x89, 0x50, 0x4e, 0x47})
+	_ = w.WriteField("size", size)
+	_ = w.Close()
+	request, _ := http.NewRequest(http.MethodPost, "https://api.pb33f.io/files", &body)
+	request.Header.Set("Content-Type", w.FormDataContentType())
+	return request
+}
+
+func TestResponseMockEngine_MultipartUpload(t *testing.T) {
+	me := multipartEngine()
+
+	mock, status, contentType, err := me.GenerateResponseWithContentType(uploadRequest("4"))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, status)
+
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	assert.Equal(t, "multipart/form-data", mediaType)
+	reader := multipart.NewReader(bytes.NewReader(mock), params["boundary"])
+	parts := make(map[string][]byte)
+	for part, e := reader.NextPart(); e == nil; part, e = reader.NextPart() {
+		parts[part.FormName()], _ = io.ReadAll(part)
+		if part.FormName() == "file" {
+			assert.Equal(t, "application/octet-stream", part.Header.Get("Content-Type"))
+		}
+	}
+	assert.Len(t, parts["file"], 16)
+	assert.NotEmpty(t, parts["name"])
+
+	// size is not an integer.
+	_, status, _, err = me.GenerateResponseWithContentType(uploadRequest("big"))
+	assert.Error(t, err)
+	assert.Equal(t, 422, status)
+}
+
+func TestResponseMockEngine_BinaryDownload(t *testing.T) {
+	me := multipartEngine()
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/files/abc", nil)
+	mock, status, contentType, err := me.GenerateResponseWithContentType(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "application/octet-stream", contentType)
+	assert.Len(t, mock, 512)
+}
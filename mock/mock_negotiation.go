@@ -0,0 +1,152 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+const acceptHeader = "Accept"
+
+// mediaRange is a media range from an Accept header, e.g. `application/*;q=0.8`
+type mediaRange struct {
+	kind, subtype string
+	quality       float64
+}
+
+// parseAccept parses an Accept header into media ranges, ordered by preference: by quality, then by how
+// specific the range is. Ranges with the same preference keep the order the client sent them in.
+func parseAccept(header string) []*mediaRange {
+	var ranges []*mediaRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		kind, subtype, found := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !found || kind == "" || subtype == "" {
+			continue
+		}
+		r := &mediaRange{kind: kind, subtype: subtype, quality: 1}
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					r.quality = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].quality != ranges[j].quality {
+			return ranges[i].quality > ranges[j].quality
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+func (r *mediaRange) specificity() int {
+	switch {
+	case r.kind == "*":
+		return 0
+	case r.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+func (r *mediaRange) wildcard() bool {
+	return r.subtype == "*"
+}
+
+// matches checks if a media type (without parameters) is in the range.
+func (r *mediaRange) matches(mediaType string) bool {
+	kind, subtype, _ := strings.Cut(strings.ToLower(mediaType), "/")
+	return (r.kind == "*" || r.kind == kind) && (r.subtype == "*" || r.subtype == subtype)
+}
+
+// negotiate picks the media type of a response that best matches the Accept header of a request. Within a
+// wildcard range, JSON is preferred, then the order of the specification. Returns false if the client accepts
+// none of the media types. Without an Accept header, or for clients that accept anything, nothing is picked.
+func negotiate(content *orderedmap.Map[string, *v3.MediaType], request *http.Request) (*v3.MediaType, bool) {
+	accept := request.Header.Get(acceptHeader)
+	if content == nil || content.Len() == 0 || strings.TrimSpace(accept) == "" {
+		return nil, true
+	}
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return nil, true
+	}
+
+	// media types explicitly refused (q=0) are never picked, even if a wildcard range matches them.
+	refusals := false
+	refused := func(mediaType string) bool {
+		for _, r := range ranges {
+			if r.quality <= 0 && r.specificity() == 2 && r.matches(mediaType) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, r := range ranges {
+		refusals = refusals || (r.quality <= 0 && r.specificity() == 2)
+	}
+
+	for _, r := range ranges {
+		if r.quality <= 0 {
+			continue
+		}
+		if r.specificity() == 0 && !refusals {
+			return nil, true // the client accepts anything, let the defaults decide.
+		}
+		var match *v3.MediaType
+		for pair := content.First(); pair != nil; pair = pair.Next() {
+			mediaType := mediaTypeWithoutParams(pair.Key())
+			if !r.matches(mediaType) || refused(mediaType) {
+				continue
+			}
+			if match == nil {
+				match = pair.Value()
+			}
+			if r.wildcard() && isJSONMediaType(mediaType) {
+				match = pair.Value()
+				break
+			}
+		}
+		if match != nil {
+			return match, true
+		}
+	}
+	return nil, false
+}
+
+// acceptable checks if a client accepts any of the media types of the response an operation returns for a code.
+func acceptable(operation *v3.Operation, request *http.Request, code string) bool {
+	if operation == nil || operation.Responses == nil {
+		return true
+	}
+	resp := operation.Responses.Codes.GetOrZero(code)
+	if resp == nil {
+		return true
+	}
+	_, ok := negotiate(resp.Content, request)
+	return ok
+}
+
+func mediaTypeWithoutParams(mediaType string) string {
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	return strings.TrimSpace(mediaType)
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,73 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var negotiationSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          content:
+            application/xml:
+              example:
+                name: Fido
+            application/json:
+              example:
+                name: Fido
+            text/csv:
+              schema:
+                type: string
+              example: name
+`
+
+func TestParseAccept(t *testing.T) {
+	ranges := parseAccept("text/*;q=0.5, */*;q=0.1, application/xml, application/json;q=0.9, bad")
+	assert.Len(t, ranges, 4)
+	assert.Equal(t, "xml", ranges[0].subtype)
+	assert.Equal(t, "json", ranges[1].subtype)
+	assert.Equal(t, "text", ranges[2].kind)
+	assert.Equal(t, "*", ranges[3].kind)
+}
+
+func TestResponseMockEngine_Negotiation(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(negotiationSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"", 200, "application/json"},
+		{"*/*", 200, "application/json"},
+		{"application/xml", 200, "application/xml"},
+		{"text/csv;q=0.2, application/xml;q=0.8", 200, "application/xml"},
+		{"application/*", 200, "application/json"},
+		{"application/json;q=0, */*", 200, "application/xml"},
+		{"text/*", 200, "text/csv"},
+		{"image/png", 406, ""},
+	}
+	for _, tt := range tests {
+		request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets", nil)
+		if tt.accept != "" {
+			request.Header.Set("Accept", tt.accept)
+		}
+		_, status, contentType, _ := me.GenerateResponseWithContentType(request)
+		assert.Equal(t, tt.status, status, tt.accept)
+		if tt.contentType != "" {
+			assert.Equal(t, tt.contentType, contentType, tt.accept)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/paths"
+)
+
+var overrideMethods = []string{
+	http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete,
+	http.MethodOptions, http.MethodHead, http.MethodPatch, http.MethodTrace,
+}
+
+// Overrides are hand-crafted mock bodies, loaded from a directory, that replace generated mocks.
+//
+// Files at the top of the directory are named by operationId (e.g. `listPets.json`), files in a directory named
+// after a method are named by path (e.g. `GET/pets/{petId}.json`). The extension sets the content type.
+type Overrides struct {
+	dir   string
+	files map[string]*overrideFile
+	lock  sync.RWMutex
+}
+
+type overrideFile struct {
+	body        []byte
+	contentType string
+}
+
+// LoadOverrides loads every override in a directory.
+func LoadOverrides(dir string) (*Overrides, error) {
+	o := &Overrides{dir: dir}
+	return o, o.Reload()
+}
+
+// Dir returns the directory overrides are loaded from.
+func (o *Overrides) Dir() string {
+	return o.dir
+}
+
+// Len returns the number of overrides loaded.
+func (o *Overrides) Len() int {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	return len(o.files)
+}
+
+// Reload loads every override in the directory again, replacing what was loaded before.
+func (o *Overrides) Reload() error {
+	files := make(map[string]*overrideFile)
+	err := filepath.WalkDir(o.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, _ := filepath.Rel(o.dir, path)
+		key := overrideKey(filepath.ToSlash(rel))
+		if key == "" {
+			return nil
+		}
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		contentType := mime.TypeByExtension(filepath.Ext(path))
+		if i := strings.Index(contentType, ";"); i > 0 {
+			contentType = contentType[:i] // drop the charset.
+		}
+		files[key] = &overrideFile{body: body, contentType: contentType}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	o.lock.Lock()
+	o.files = files
+	o.lock.Unlock()
+	return nil
+}
+
+// overrideKey turns the path of a file (relative to the overrides directory) into the key it's looked up by,
+// an operationId, or a method and path (e.g. `GET /pets/{petId}`).
+func overrideKey(rel string) string {
+	rel = strings.TrimSuffix(rel, filepath.Ext(rel))
+	method, path, nested := strings.Cut(rel, "/")
+	if !nested {
+		return rel
+	}
+	for _, m := range overrideMethods {
+		if strings.EqualFold(m, method) {
+			return m + " /" + path
+		}
+	}
+	return ""
+}
+
+func (o *Overrides) find(keys ...string) *overrideFile {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	for _, key := range keys {
+		if f := o.files[key]; f != nil && key != "" {
+			return f
+		}
+	}
+	return nil
+}
+
+// SetOverrides sets the overrides that replace the generated mocks for operations.
+func (rme *ResponseMockEngine) SetOverrides(overrides *Overrides) {
+	rme.overrides = overrides
+}
+
+// findOverride returns the override for the operation a request is for, if there is one.
+func (rme *ResponseMockEngine) findOverride(request *http.Request) ([]byte, bool) {
+	if rme.overrides == nil {
+		return nil, false
+	}
+	pathItem, _, template := paths.FindPath(request, rme.doc)
+	operation := rme.findOperation(request, pathItem)
+	if operation == nil {
+		return nil, false
+	}
+	f := rme.overrides.find(operation.OperationId, strings.ToUpper(request.Method)+" "+template)
+	if f == nil {
+		return nil, false
+	}
+	if f.contentType != "" {
+		rme.contentTypes.Store(request, f.contentType)
+	}
+	return f.body, true
+}
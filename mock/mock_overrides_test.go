@@ -0,0 +1,49 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseMockEngine_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "getJob.json"), []byte(`{"status":"hand-crafted"}`), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "DELETE", "jobs"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "DELETE", "jobs", "{jobId}.xml"), []byte(`<gone/>`), 0644))
+
+	overrides, err := LoadOverrides(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, overrides.Len())
+
+	me := sequenceEngine(nil)
+	me.SetOverrides(overrides)
+
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/jobs/abc", nil)
+	mock, status, contentType, err := me.GenerateResponseWithContentType(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, `{"status":"hand-crafted"}`, string(mock))
+
+	request, _ = http.NewRequest(http.MethodDelete, "https://api.pb33f.io/jobs/abc", nil)
+	mock, status, contentType, err = me.GenerateResponseWithContentType(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 204, status)
+	assert.Equal(t, "text/xml", contentType)
+	assert.Equal(t, `<gone/>`, string(mock))
+
+	// overrides are reloaded.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "getJob.json"), []byte(`{"status":"edited"}`), 0644))
+	assert.NoError(t, overrides.Reload())
+	request, _ = http.NewRequest(http.MethodGet, "https://api.pb33f.io/jobs/abc", nil)
+	mock, _, _ = me.GenerateResponse(request)
+	assert.Equal(t, `{"status":"edited"}`, string(mock))
+}
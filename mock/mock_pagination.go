@@ -0,0 +1,320 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// DefaultPaginationTotal is the size of the synthetic collection served by paginated operations.
+const DefaultPaginationTotal = 100
+
+const defaultPageSize = 20
+
+// the names of query parameters that are recognized as pagination parameters.
+var (
+	pageParams   = []string{"page", "pagenumber", "page_number"}
+	limitParams  = []string{"limit", "per_page", "perpage", "page_size", "pagesize", "size", "count"}
+	offsetParams = []string{"offset", "skip", "start"}
+	cursorParams = []string{"cursor", "after", "page_token", "pagetoken", "next"}
+)
+
+// the names of fields in a response that describe the page.
+var (
+	nextCursorFields = []string{"nextCursor", "next_cursor", "cursor", "nextPageToken", "next_page_token"}
+	totalFields      = []string{"total", "totalCount", "total_count", "totalItems", "total_items"}
+	hasMoreFields    = []string{"hasMore", "has_more", "hasNext", "has_next"}
+)
+
+// paginator serves pages of a stable synthetic collection, generated once per operation.
+type paginator struct {
+	total       int
+	collections map[string][]any
+	lock        sync.Mutex
+}
+
+// paginationParams are the pagination parameters an operation accepts, by their name in the specification.
+type paginationParams struct {
+	page, limit, offset, cursor string
+}
+
+// SetPagination enables pagination of collections. Operations with page, limit, offset or cursor query parameters
+// serve pages of a synthetic collection of `total` items, which stays the same for the whole session. Pages link
+// to each other with a `Link` header, and next-cursor / total fields in the response are filled in.
+// Pagination is not applied when mocks are stateful, collections are then made of the resources that were stored.
+func (rme *ResponseMockEngine) SetPagination(total int) {
+	if total <= 0 {
+		total = DefaultPaginationTotal
+	}
+	rme.pagination = &paginator{total: total, collections: make(map[string][]any)}
+}
+
+// paginate replaces the collection in a mock with the page requested.
+func (rme *ResponseMockEngine) paginate(request *http.Request, mock []byte, status int) []byte {
+	if rme.pagination == nil || request.Method != http.MethodGet {
+		return mock
+	}
+	pathItem, _, template := paths.FindPath(request, rme.doc)
+	operation := rme.findOperation(request, pathItem)
+	if operation == nil {
+		return mock
+	}
+	params := findPaginationParams(pathItem, operation)
+	if params == nil {
+		return mock
+	}
+	mt, _ := rme.lookForResponseCodes(operation, request, []string{strconv.Itoa(status)})
+	if mt == nil || mt.Schema == nil {
+		return mock
+	}
+
+	var body any
+	if err := json.Unmarshal(mock, &body); err != nil {
+		return mock
+	}
+	field, itemSchema := collectionSchema(mt.Schema.Schema())
+	if itemSchema == nil {
+		return mock
+	}
+	collection := rme.pagination.collection(rme, request.Method+" "+template, itemSchema)
+
+	start, end, size := pageBounds(request.URL.Query(), params, len(collection))
+	page := append([]any{}, collection[start:end]...)
+	more := end < len(collection)
+
+	// fill in the page.
+	if field == "" {
+		body = page
+	} else if obj, ok := body.(map[string]any); ok {
+		obj[field] = page
+		setPageField(obj, totalFields, float64(len(collection)))
+		setPageField(obj, hasMoreFields, more)
+		if params.page != "" {
+			setPageField(obj, []string{"page"}, float64(start/size+1))
+		}
+		next := any(nil)
+		if more {
+			next = encodeCursor(end)
+		}
+		setPageField(obj, nextCursorFields, next)
+	}
+
+	rme.setResponseHeader(request, "Link", paginationLinks(request, params, start, size, len(collection)))
+	rme.setResponseHeader(request, "X-Total-Count", strconv.Itoa(len(collection)))
+	return rme.render(body)
+}
+
+// pageBounds works out where the requested page of a collection of `total` items starts and ends, and how big
+// pages are. Sizes are capped at the size of the collection, so huge limits, pages or offsets cannot overflow.
+func pageBounds(query url.Values, params *paginationParams, total int) (start, end, size int) {
+	size = defaultPageSize
+	if params.limit != "" {
+		if l, err := strconv.Atoi(query.Get(params.limit)); err == nil && l > 0 {
+			size = l
+		}
+	}
+	if total > 0 && size > total {
+		size = total
+	}
+	switch {
+	case params.cursor != "" && query.Get(params.cursor) != "":
+		start = decodeCursor(query.Get(params.cursor))
+	case params.offset != "" && query.Get(params.offset) != "":
+		start, _ = strconv.Atoi(query.Get(params.offset))
+	case params.page != "" && query.Get(params.page) != "":
+		pageNumber, _ := strconv.Atoi(query.Get(params.page))
+		if pageNumber < 1 {
+			pageNumber = 1
+		}
+		if pageNumber-1 > total/size {
+			start = total
+		} else {
+			start = (pageNumber - 1) * size
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > total {
+		start = total
+	}
+	end = total
+	if size < total-start {
+		end = start + size
+	}
+	return start, end, size
+}
+
+// collection returns the synthetic collection for an operation, it's generated the first time it's needed.
+func (p *paginator) collection(rme *ResponseMockEngine, key string, itemSchema *base.Schema) []any {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if c, ok := p.collections[key]; ok {
+		return c
+	}
+	c := make([]any, 0, p.total)
+	for i := 0; i < p.total; i++ {
+		mock, err := rme.mockEngine.GenerateMock(itemSchema, "")
+		if err != nil {
+			break
+		}
+		var item any
+		if err = json.Unmarshal(rme.fakeSchemaMock(itemSchema, mock), &item); err != nil {
+			break
+		}
+		// numeric ids are numbered, so every item in the collection is unique.
+		if obj, ok := item.(map[string]any); ok {
+			if _, numeric := obj["id"].(float64); numeric {
+				obj["id"] = float64(i + 1)
+			}
+		}
+		c = append(c, item)
+	}
+	p.collections[key] = c
+	return c
+}
+
+// findPaginationParams looks for the pagination query parameters of an operation, nil if there are none.
+func findPaginationParams(pathItem *v3.PathItem, operation *v3.Operation) *paginationParams {
+	params := &paginationParams{}
+	found := false
+	for _, param := range append(append([]*v3.Parameter{}, pathItem.Parameters...), operation.Parameters...) {
+		if param == nil || param.In != "query" {
+			continue
+		}
+		name := strings.ToLower(param.Name)
+		switch {
+		case slices.Contains(pageParams, name):
+			params.page = param.Name
+		case slices.Contains(limitParams, name):
+			params.limit = param.Name
+		case slices.Contains(offsetParams, name):
+			params.offset = param.Name
+		case slices.Contains(cursorParams, name):
+			params.cursor = param.Name
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return params
+}
+
+// collectionSchema finds the collection in a response schema. Either the response is an array, or an object with
+// an array property (the field). Returns the schema of the items in the collection.
+func collectionSchema(schema *base.Schema) (string, *base.Schema) {
+	if schema == nil {
+		return "", nil
+	}
+	if itemSchema := arrayItems(schema); itemSchema != nil {
+		return "", itemSchema
+	}
+	if schema.Properties != nil {
+		for pair := schema.Properties.First(); pair != nil; pair = pair.Next() {
+			if itemSchema := arrayItems(pair.Value().Schema()); itemSchema != nil {
+				return pair.Key(), itemSchema
+			}
+		}
+	}
+	return "", nil
+}
+
+func arrayItems(schema *base.Schema) *base.Schema {
+	if schema == nil || schema.Items == nil || !schema.Items.IsA() {
+		return nil
+	}
+	for _, t := range schema.Type {
+		if t == "array" {
+			return schema.Items.A.Schema()
+		}
+	}
+	return nil
+}
+
+// setPageField sets the first of the named fields that exists in the response.
+func setPageField(obj map[string]any, names []string, value any) {
+	for _, name := range names {
+		if _, ok := obj[name]; ok {
+			obj[name] = value
+			return
+		}
+	}
+}
+
+// paginationLinks builds a Link header (RFC 8288) with the first, previous, next and last pages.
+func paginationLinks(request *http.Request, params *paginationParams, start, size, total int) string {
+	link := func(rel string, offset int) string {
+		u := *request.URL
+		query := u.Query()
+		switch {
+		case params.cursor != "":
+			query.Set(params.cursor, encodeCursor(offset))
+		case params.offset != "":
+			query.Set(params.offset, strconv.Itoa(offset))
+		case params.page != "":
+			query.Set(params.page, strconv.Itoa(offset/size+1))
+		}
+		if params.limit != "" {
+			query.Set(params.limit, strconv.Itoa(size))
+		}
+		u.RawQuery = query.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, requestURL(request, &u), rel)
+	}
+
+	links := []string{link("first", 0)}
+	if start > 0 {
+		prev := start - size
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, link("prev", prev))
+	}
+	if size < total-start {
+		links = append(links, link("next", start+size))
+	}
+	if params.cursor == "" && total > 0 {
+		links = append(links, link("last", ((total-1)/size)*size))
+	}
+	return strings.Join(links, ", ")
+}
+
+func requestURL(request *http.Request, u *url.URL) string {
+	if u.Host != "" {
+		return u.String()
+	}
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + request.Host + u.RequestURI()
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) int {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0
+	}
+	offset, _ := strconv.Atoi(strings.TrimPrefix(string(b), "offset:"))
+	return offset
+}
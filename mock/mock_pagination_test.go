@@ -0,0 +1,203 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var paginationSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: page
+          in: query
+          schema:
+            type: integer
+        - name: limit
+          in: query
+          schema:
+            type: integer
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [pets, total, hasMore]
+                properties:
+                  pets:
+                    type: array
+                    items:
+                      type: object
+                      required: [id]
+                      properties:
+                        id:
+                          type: integer
+                  total:
+                    type: integer
+                  hasMore:
+                    type: boolean`
+
+func TestPageBounds(t *testing.T) {
+	pages := &paginationParams{page: "page", limit: "limit"}
+	offsets := &paginationParams{offset: "offset", limit: "limit"}
+	cursors := &paginationParams{cursor: "cursor", limit: "limit"}
+
+	tests := []struct {
+		name             string
+		params           *paginationParams
+		query            string
+		total            int
+		start, end, size int
+	}{
+		{"first page by default", pages, "", 100, 0, 20, 20},
+		{"page and limit", pages, "page=3&limit=10", 100, 20, 30, 10},
+		{"last partial page", pages, "page=4&limit=30", 100, 90, 100, 30},
+		{"page past the end", pages, "page=50&limit=10", 100, 100, 100, 10},
+		{"page zero", pages, "page=0&limit=10", 100, 0, 10, 10},
+		{"negative limit", pages, "page=2&limit=-5", 100, 20, 40, 20},
+		{"huge limit", pages, "page=2&limit=9223372036854775807", 100, 100, 100, 100},
+		{"huge page", pages, "page=9223372036854775807&limit=10", 100, 100, 100, 10},
+		{"huge page and limit", pages, "page=9223372036854775807&limit=9223372036854775807", 100, 100, 100, 100},
+		{"empty collection", pages, "page=2&limit=10", 0, 0, 0, 10},
+		{"offset and limit", offsets, "offset=15&limit=10", 100, 15, 25, 10},
+		{"negative offset", offsets, "offset=-15&limit=10", 100, 0, 10, 10},
+		{"offset past the end", offsets, "offset=500&limit=10", 100, 100, 100, 10},
+		{"huge offset and limit", offsets, "offset=9223372036854775807&limit=9223372036854775807", 100, 100, 100, 100},
+		{"cursor", cursors, "cursor=" + encodeCursor(40) + "&limit=25", 100, 40, 65, 25},
+		{"cursor near the end", cursors, "cursor=" + encodeCursor(90) + "&limit=25", 100, 90, 100, 25},
+		{"huge cursor", cursors, "cursor=" + encodeCursor(int(^uint(0)>>1)) + "&limit=25", 100, 100, 100, 25},
+		{"garbage cursor", cursors, "cursor=%%%&limit=25", 100, 0, 25, 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			start, end, size := pageBounds(query, tt.params, tt.total)
+			assert.Equal(t, tt.start, start, "start")
+			assert.Equal(t, tt.end, end, "end")
+			assert.Equal(t, tt.size, size, "size")
+		})
+	}
+}
+
+func TestPaginationLinks(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets?page=2&limit=10", nil)
+	links := paginationLinks(request, &paginationParams{page: "page", limit: "limit"}, 10, 10, 35)
+	assert.Equal(t, `<https://api.pb33f.io/pets?limit=10&page=1>; rel="first", `+
+		`<https://api.pb33f.io/pets?limit=10&page=1>; rel="prev", `+
+		`<https://api.pb33f.io/pets?limit=10&page=3>; rel="next", `+
+		`<https://api.pb33f.io/pets?limit=10&page=4>; rel="last"`, links)
+
+	// the last page has no next page, a cursor has no last page.
+	links = paginationLinks(request, &paginationParams{cursor: "cursor", limit: "limit"}, 30, 10, 35)
+	assert.NotContains(t, links, `rel="next"`)
+	assert.NotContains(t, links, `rel="last"`)
+}
+
+func TestPaginationLinks_Pages(t *testing.T) {
+	pages := &paginationParams{page: "page", limit: "limit"}
+	offsets := &paginationParams{offset: "offset", limit: "limit"}
+
+	tests := []struct {
+		name   string
+		params *paginationParams
+		start  int
+		links  map[string]string
+	}{
+		{"first page", pages, 0, map[string]string{
+			"first": "limit=10&page=1", "next": "limit=10&page=2", "last": "limit=10&page=4"}},
+		{"last page", pages, 30, map[string]string{
+			"first": "limit=10&page=1", "prev": "limit=10&page=3", "last": "limit=10&page=4"}},
+		{"first offset", offsets, 0, map[string]string{
+			"first": "limit=10&offset=0", "next": "limit=10&offset=10", "last": "limit=10&offset=30"}},
+		{"between offsets", offsets, 5, map[string]string{
+			"first": "limit=10&offset=0", "prev": "limit=10&offset=0", "next": "limit=10&offset=15",
+			"last": "limit=10&offset=30"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets", nil)
+			links := make(map[string]string)
+			for _, link := range strings.Split(paginationLinks(request, tt.params, tt.start, 10, 35), ", ") {
+				target, rel, _ := strings.Cut(link, "; ")
+				u, err := url.Parse(strings.Trim(target, "<>"))
+				require.NoError(t, err)
+				links[strings.Trim(strings.TrimPrefix(rel, "rel="), `"`)] = u.RawQuery
+			}
+			assert.Equal(t, tt.links, links)
+		})
+	}
+}
+
+func TestResponseMockEngine_Paginate(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(paginationSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+	me.SetPagination(35)
+
+	call := func(query string) (map[string]any, http.Header) {
+		request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets?"+query, nil)
+		mock, status, headers, err := me.GenerateResponseWithHeaders(request)
+		require.NoError(t, err)
+		require.Equal(t, 200, status)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(mock, &body))
+		return body, headers
+	}
+
+	body, headers := call("page=2&limit=10")
+	pets := body["pets"].([]any)
+	assert.Len(t, pets, 10)
+	assert.Equal(t, float64(11), pets[0].(map[string]any)["id"])
+	assert.Equal(t, float64(35), body["total"])
+	assert.Equal(t, true, body["hasMore"])
+	assert.Equal(t, "35", headers.Get("X-Total-Count"))
+	assert.True(t, strings.Contains(headers.Get("Link"), `rel="next"`))
+
+	// huge values are an empty page, not a panic.
+	body, _ = call("page=2&limit=9223372036854775807")
+	assert.Empty(t, body["pets"])
+	assert.Equal(t, false, body["hasMore"])
+	body, _ = call("page=9223372036854775807&limit=9223372036854775807")
+	assert.Empty(t, body["pets"])
+}
+
+func TestResponseMockEngine_PaginateStable(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(paginationSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+	me.SetPagination(35)
+
+	page := func(number string) []any {
+		request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets?limit=10&page="+number, nil)
+		mock, _, err := me.GenerateResponse(request)
+		require.NoError(t, err)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(mock, &body))
+		return body["pets"].([]any)
+	}
+
+	// paging through the collection sees every item once, and a page is the same every time it's requested.
+	var pets []any
+	for _, number := range []string{"1", "2", "3", "4"} {
+		pets = append(pets, page(number)...)
+	}
+	require.Len(t, pets, 35)
+	for i, pet := range pets {
+		assert.Equal(t, float64(i+1), pet.(map[string]any)["id"])
+	}
+	assert.Equal(t, pets[10:20], page("2"))
+	assert.Empty(t, page("5"))
+}
@@ -0,0 +1,90 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// SequenceClientHeader identifies a client for sequences scoped per client, if not set the remote address is used.
+const SequenceClientHeader = "X-Wiretap-Client"
+
+// sequenceTracker keeps track of how far through each sequence every client (or the whole session) has got.
+type sequenceTracker struct {
+	sequences map[string]*shared.WiretapMockSequence
+	positions map[string]int
+	lock      sync.Mutex
+}
+
+// SetSequences configures ordered sequences of responses, keyed by operationId or by method and path
+// (e.g. `GET /jobs/{jobId}`). Each call to a sequenced operation returns the next response in the sequence.
+func (rme *ResponseMockEngine) SetSequences(sequences map[string]*shared.WiretapMockSequence) {
+	rme.sequences = &sequenceTracker{
+		sequences: sequences,
+		positions: make(map[string]int),
+	}
+}
+
+// locateSequence returns the sequence configured for the operation a request is for, and the key it's stored under.
+func (rme *ResponseMockEngine) locateSequence(request *http.Request) (string, *shared.WiretapMockSequence) {
+	if rme.sequences == nil || len(rme.sequences.sequences) == 0 {
+		return "", nil
+	}
+	pathItem, _, template := paths.FindPath(request, rme.doc)
+	if pathItem == nil {
+		return "", nil
+	}
+	if operation := rme.findOperation(request, pathItem); operation != nil && operation.OperationId != "" {
+		if seq := rme.sequences.sequences[operation.OperationId]; seq != nil && len(seq.Responses) > 0 {
+			return operation.OperationId, seq
+		}
+	}
+	key := strings.ToUpper(request.Method) + " " + template
+	if seq := rme.sequences.sequences[key]; seq != nil && len(seq.Responses) > 0 {
+		return key, seq
+	}
+	return "", nil
+}
+
+// nextInSequence returns the next response in the sequence for a request, and moves the sequence on.
+// Once the end of a sequence is reached, the last response is repeated, unless the sequence loops.
+func (rme *ResponseMockEngine) nextInSequence(request *http.Request) string {
+	key, seq := rme.locateSequence(request)
+	if seq == nil {
+		return ""
+	}
+	if strings.ToLower(seq.Scope) == shared.MockSequenceScopeClient {
+		key += "|" + sequenceClient(request)
+	}
+
+	rme.sequences.lock.Lock()
+	defer rme.sequences.lock.Unlock()
+	position := rme.sequences.positions[key]
+	if position >= len(seq.Responses) {
+		if seq.Loop {
+			position = 0
+		} else {
+			position = len(seq.Responses) - 1
+		}
+	}
+	rme.sequences.positions[key] = position + 1
+	return seq.Responses[position]
+}
+
+func sequenceClient(request *http.Request) string {
+	if client := request.Header.Get(SequenceClientHeader); client != "" {
+		return client
+	}
+	if host, _, err := net.SplitHostPort(request.RemoteAddr); err == nil {
+		return host
+	}
+	return request.RemoteAddr
+}
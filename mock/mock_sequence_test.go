@@ -0,0 +1,120 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+var sequenceSpec = `openapi: 3.1.0
+paths:
+  /jobs/{jobId}:
+    get:
+      operationId: getJob
+      parameters:
+        - name: jobId
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  status:
+                    type: string
+              examples:
+                complete:
+                  value:
+                    status: complete
+        '202':
+          content:
+            application/json:
+              examples:
+                pending:
+                  value:
+                    status: pending
+        '409':
+          content:
+            application/json:
+              schema:
+                type: object
+    delete:
+      parameters:
+        - name: jobId
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '204':
+          description: deleted
+        '404':
+          description: gone`
+
+func sequenceEngine(sequences map[string]*shared.WiretapMockSequence) *ResponseMockEngine {
+	d, _ := libopenapi.NewDocument([]byte(sequenceSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+	me.SetSequences(sequences)
+	return me
+}
+
+func callSequence(t *testing.T, me *ResponseMockEngine, method, client string) int {
+	request, _ := http.NewRequest(method, "https://api.pb33f.io/jobs/abc", nil)
+	if client != "" {
+		request.Header.Set(SequenceClientHeader, client)
+	}
+	_, status, err := me.GenerateResponse(request)
+	assert.NoError(t, err)
+	return status
+}
+
+func TestResponseMockEngine_Sequence(t *testing.T) {
+	me := sequenceEngine(map[string]*shared.WiretapMockSequence{
+		"getJob":               {Responses: []string{"pending", "complete", "409"}},
+		"DELETE /jobs/{jobId}": {Responses: []string{"204", "404"}, Loop: true},
+	})
+
+	// the last response repeats once the sequence is done.
+	assert.Equal(t, 202, callSequence(t, me, http.MethodGet, ""))
+	assert.Equal(t, 200, callSequence(t, me, http.MethodGet, ""))
+	assert.Equal(t, 409, callSequence(t, me, http.MethodGet, ""))
+	assert.Equal(t, 409, callSequence(t, me, http.MethodGet, "someone-else"))
+
+	// looping sequences start again.
+	assert.Equal(t, 204, callSequence(t, me, http.MethodDelete, ""))
+	assert.Equal(t, 404, callSequence(t, me, http.MethodDelete, ""))
+	assert.Equal(t, 204, callSequence(t, me, http.MethodDelete, ""))
+}
+
+func TestResponseMockEngine_SequencePerClient(t *testing.T) {
+	me := sequenceEngine(map[string]*shared.WiretapMockSequence{
+		"getJob": {Responses: []string{"202", "200"}, Scope: shared.MockSequenceScopeClient},
+	})
+	assert.Equal(t, 202, callSequence(t, me, http.MethodGet, "one"))
+	assert.Equal(t, 202, callSequence(t, me, http.MethodGet, "two"))
+	assert.Equal(t, 200, callSequence(t, me, http.MethodGet, "one"))
+	assert.Equal(t, 200, callSequence(t, me, http.MethodGet, "two"))
+}
+
+func TestResponseMockEngine_SequenceKeepState(t *testing.T) {
+	sequences := map[string]*shared.WiretapMockSequence{"getJob": {Responses: []string{"pending", "complete"}}}
+	me := sequenceEngine(sequences)
+	assert.Equal(t, 202, callSequence(t, me, http.MethodGet, ""))
+
+	// a reloaded specification carries on where the sequence got to.
+	reloaded := sequenceEngine(sequences)
+	reloaded.KeepState(me)
+	assert.Equal(t, 200, callSequence(t, reloaded, http.MethodGet, ""))
+}
@@ -0,0 +1,296 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/paths"
+)
+
+// resourceCollection holds the resources created under a single collection path (e.g. /pets).
+type resourceCollection struct {
+	items   map[string]map[string]any
+	order   []string
+	deleted map[string]bool
+}
+
+// ResourceStore is an in-memory store of resources created by stateful mocks. Resources are grouped by their
+// collection path, and keyed by the value of the trailing `{id}` parameter of the path.
+type ResourceStore struct {
+	collections map[string]*resourceCollection
+	sequence    int
+	lock        sync.Mutex
+}
+
+func NewResourceStore() *ResourceStore {
+	return &ResourceStore{collections: make(map[string]*resourceCollection)}
+}
+
+// KeepState carries what a previous engine remembers over to this one, when the specification is reloaded: the
+// resources stored by stateful mocks, and how far clients have got through each sequence. Both are shared with the
+// previous engine, so requests it is still serving aren't lost.
+func (rme *ResponseMockEngine) KeepState(previous *ResponseMockEngine) {
+	if previous == nil {
+		return
+	}
+	if rme.state != nil && previous.state != nil {
+		rme.state = previous.state
+	}
+	if rme.sequences != nil && previous.sequences != nil {
+		rme.sequences = previous.sequences
+	}
+}
+
+func (rs *ResourceStore) collection(key string) *resourceCollection {
+	c := rs.collections[key]
+	if c == nil {
+		c = &resourceCollection{items: make(map[string]map[string]any), deleted: make(map[string]bool)}
+		rs.collections[key] = c
+	}
+	return c
+}
+
+func (rs *ResourceStore) put(collection, id string, resource map[string]any) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	c := rs.collection(collection)
+	if _, ok := c.items[id]; !ok {
+		c.order = append(c.order, id)
+	}
+	c.items[id] = resource
+	delete(c.deleted, id)
+}
+
+func (rs *ResourceStore) get(collection, id string) (map[string]any, bool) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	c := rs.collections[collection]
+	if c == nil {
+		return nil, false
+	}
+	if c.deleted[id] {
+		return nil, true
+	}
+	r, ok := c.items[id]
+	return r, ok
+}
+
+func (rs *ResourceStore) delete(collection, id string) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	c := rs.collection(collection)
+	delete(c.items, id)
+	c.deleted[id] = true
+	for i := range c.order {
+		if c.order[i] == id {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (rs *ResourceStore) list(collection string) ([]any, bool) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	c := rs.collections[collection]
+	if c == nil {
+		return nil, false
+	}
+	items := make([]any, 0, len(c.order))
+	for _, id := range c.order {
+		items = append(items, c.items[id])
+	}
+	return items, true
+}
+
+func (rs *ResourceStore) nextId() int {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.sequence++
+	return rs.sequence
+}
+
+// resourcePath splits a request into the collection it belongs to, and the id of the resource (if the path
+// template ends with a parameter). For /pets/{petId} and a request to /pets/123, that's /pets and 123.
+func resourcePath(request *http.Request, template string) (string, string) {
+	path := strings.TrimSuffix(request.URL.Path, "/")
+	template = strings.TrimSuffix(template, "/")
+	segments := strings.Split(template, "/")
+	last := segments[len(segments)-1]
+	if strings.HasPrefix(last, "{") && strings.HasSuffix(last, "}") {
+		idx := strings.LastIndex(path, "/")
+		return path[:idx], path[idx+1:]
+	}
+	return path, ""
+}
+
+// applyState runs a request against the resource store, once the mock engine has decided the request is good.
+// Writes persist the request body (merged over the generated mock), and reads return what has been persisted.
+func (rme *ResponseMockEngine) applyState(request *http.Request, requestBody []byte, mock []byte,
+	status int) ([]byte, int, error) {
+
+	_, _, template := paths.FindPath(request, rme.doc)
+	if template == "" {
+		return mock, status, nil
+	}
+	collection, id := resourcePath(request, template)
+
+	switch request.Method {
+	case http.MethodPost:
+		if id != "" {
+			return mock, status, nil // not a collection, so nothing to create.
+		}
+		resource := mergeResource(mock, requestBody, false)
+		if resource == nil {
+			return mock, status, nil
+		}
+		if resource["id"] == nil {
+			resource["id"] = rme.state.nextId()
+		}
+		rme.state.put(collection, fmt.Sprint(resource["id"]), resource)
+		return rme.render(resource), status, nil
+
+	case http.MethodPut, http.MethodPatch:
+		if id == "" {
+			return mock, status, nil
+		}
+		existing, _ := rme.state.get(collection, id)
+		var resource map[string]any
+		if existing != nil && request.Method == http.MethodPatch {
+			resource = mergeResource(rme.render(existing), requestBody, false)
+		} else {
+			resource = mergeResource(mock, requestBody, true)
+		}
+		if resource == nil {
+			return mock, status, nil
+		}
+		if existing != nil && existing["id"] != nil {
+			resource["id"] = existing["id"]
+		} else if current, ok := resource["id"]; ok {
+			resource["id"] = typedId(current, id)
+		}
+		rme.state.put(collection, id, resource)
+		return rme.render(resource), status, nil
+
+	case http.MethodDelete:
+		if id == "" {
+			return mock, status, nil
+		}
+		if resource, found := rme.state.get(collection, id); found && resource == nil {
+			return rme.notFound(request), http.StatusNotFound, nil
+		}
+		rme.state.delete(collection, id)
+		return mock, status, nil
+
+	case http.MethodGet:
+		if id != "" {
+			resource, found := rme.state.get(collection, id)
+			if !found {
+				return mock, status, nil
+			}
+			if resource == nil {
+				return rme.notFound(request), http.StatusNotFound, nil
+			}
+			return rme.render(resource), status, nil
+		}
+		items, found := rme.state.list(collection)
+		if !found {
+			return mock, status, nil
+		}
+		return rme.render(replaceCollection(mock, items)), status, nil
+	}
+	return mock, status, nil
+}
+
+func (rme *ResponseMockEngine) notFound(request *http.Request) []byte {
+	return rme.buildError(
+		404,
+		"Resource not found",
+		fmt.Sprintf("The resource '%s' has been deleted", request.URL.Path),
+		"resource_not_found",
+	)
+}
+
+// typedId converts the id from the path into the same type as the id generated by the mock.
+func typedId(current any, id string) any {
+	if _, ok := current.(float64); ok {
+		if n, err := strconv.ParseFloat(id, 64); err == nil {
+			return n
+		}
+	}
+	return id
+}
+
+// mergeResource overlays the request body onto a base object. If the request body is not a JSON object,
+// nil is returned. If replace is true, only the id of the base object is retained.
+func mergeResource(base []byte, requestBody []byte, replace bool) map[string]any {
+	var body map[string]any
+	if err := json.Unmarshal(requestBody, &body); err != nil || body == nil {
+		return nil
+	}
+	var resource map[string]any
+	if err := json.Unmarshal(base, &resource); err != nil || resource == nil {
+		resource = make(map[string]any)
+	}
+	if replace {
+		// only keep read-only style properties (like id) from the generated mock.
+		for k := range resource {
+			if k != "id" {
+				delete(resource, k)
+			}
+		}
+	}
+	for k, v := range body {
+		resource[k] = v
+	}
+	return resource
+}
+
+// replaceCollection puts stored items into a generated collection response. If the mock is an array, it's replaced,
+// if it's an object containing a single array (a page of results for example), that array is replaced.
+func replaceCollection(mock []byte, items []any) any {
+	var generated any
+	if err := json.Unmarshal(mock, &generated); err != nil {
+		return items
+	}
+	switch g := generated.(type) {
+	case []any:
+		return items
+	case map[string]any:
+		var arrayKey string
+		for k, v := range g {
+			if _, ok := v.([]any); ok {
+				if arrayKey != "" {
+					return generated // more than one array, no idea which one to use.
+				}
+				arrayKey = k
+			}
+		}
+		if arrayKey != "" {
+			g[arrayKey] = items
+			return g
+		}
+	}
+	return generated
+}
+
+// readRequestBody reads the body of a request, and puts it back so it can be read again.
+func readRequestBody(request *http.Request) []byte {
+	if request.Body == nil {
+		return nil
+	}
+	b, _ := io.ReadAll(request.Body)
+	_ = request.Body.Close()
+	request.Body = io.NopCloser(bytes.NewReader(b))
+	return b
+}
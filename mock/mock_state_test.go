@@ -0,0 +1,151 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var statefulSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Pet'
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        '201':
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+    put:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+    delete:
+      responses:
+        '204':
+          description: deleted
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string`
+
+func statefulRequest(method, path string, body any) *http.Request {
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	request, _ := http.NewRequest(method, "https://api.pb33f.io"+path, bytes.NewReader(b))
+	request.Header.Set("Content-Type", "application/json")
+	return request
+}
+
+func TestResponseMockEngine_Stateful_CRUD(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(statefulSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+	me.SetStateful()
+
+	// create
+	b, status, err := me.GenerateResponse(statefulRequest(http.MethodPost, "/pets", map[string]any{"id": 12, "name": "fluffy"}))
+	assert.NoError(t, err)
+	assert.Equal(t, 201, status)
+	assert.JSONEq(t, `{"id":12,"name":"fluffy"}`, string(b))
+
+	// read
+	b, status, _ = me.GenerateResponse(statefulRequest(http.MethodGet, "/pets/12", nil))
+	assert.Equal(t, 200, status)
+	assert.JSONEq(t, `{"id":12,"name":"fluffy"}`, string(b))
+
+	// edit
+	b, status, _ = me.GenerateResponse(statefulRequest(http.MethodPut, "/pets/12", map[string]any{"id": 12, "name": "fuzzy"}))
+	assert.Equal(t, 200, status)
+	assert.JSONEq(t, `{"id":12,"name":"fuzzy"}`, string(b))
+
+	b, _, _ = me.GenerateResponse(statefulRequest(http.MethodGet, "/pets", nil))
+	assert.JSONEq(t, `[{"id":12,"name":"fuzzy"}]`, string(b))
+
+	// delete
+	_, status, _ = me.GenerateResponse(statefulRequest(http.MethodDelete, "/pets/12", nil))
+	assert.Equal(t, 204, status)
+
+	_, status, _ = me.GenerateResponse(statefulRequest(http.MethodGet, "/pets/12", nil))
+	assert.Equal(t, 404, status)
+
+	b, _, _ = me.GenerateResponse(statefulRequest(http.MethodGet, "/pets", nil))
+	assert.JSONEq(t, `[]`, string(b))
+}
+
+func TestResponseMockEngine_KeepState(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(statefulSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+	me.SetStateful()
+	_, status, err := me.GenerateResponse(statefulRequest(http.MethodPost, "/pets", map[string]any{"id": 12, "name": "fluffy"}))
+	require.NoError(t, err)
+	require.Equal(t, 201, status)
+
+	// a reloaded specification still has the resources that were stored.
+	reloaded := NewMockEngine(&compiled.Model, false)
+	reloaded.SetStateful()
+	reloaded.KeepState(me)
+	b, status, _ := reloaded.GenerateResponse(statefulRequest(http.MethodGet, "/pets/12", nil))
+	assert.Equal(t, 200, status)
+	assert.JSONEq(t, `{"id":12,"name":"fluffy"}`, string(b))
+
+	// unless mocks aren't stateful any more.
+	stateless := NewMockEngine(&compiled.Model, false)
+	stateless.KeepState(me)
+	assert.Nil(t, stateless.state)
+	stateless.KeepState(nil)
+}
@@ -0,0 +1,142 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/paths"
+)
+
+// templateExpression matches a template in a mock, e.g. {{request.path.id}} or {{ request.body.user.name }}
+var templateExpression = regexp.MustCompile(`\{\{\s*(request\.[^}\s]+)\s*}}`)
+
+// applyTemplates replaces templates in a mock with values from the request, so mocks can echo what the client sent.
+//
+//   - {{request.path.<name>}}: a path parameter.
+//   - {{request.query.<name>}}: a query parameter.
+//   - {{request.header.<name>}}: a request header.
+//   - {{request.body.<field>.<field>}}: a field from a JSON request body, {{request.body}} is the whole body.
+//   - {{request.method}} and {{request.url}}.
+//
+// If a JSON value is nothing but a template, it's replaced by the (typed) value, so `id: '{{request.path.id}}'`
+// renders as a number if the id is numeric. Templates that cannot be resolved are left alone.
+func (rme *ResponseMockEngine) applyTemplates(request *http.Request, mock []byte) []byte {
+	if !bytes.Contains(mock, []byte("{{")) {
+		return mock
+	}
+	tc := &templateContext{request: request, requestBody: readRequestBody(request)}
+	if pathItem, _, template := paths.FindPath(request, rme.doc); pathItem != nil {
+		tc.pathParams = extractPathParams(template, request.URL.Path)
+	}
+
+	var value any
+	if err := json.Unmarshal(mock, &value); err != nil {
+		// not JSON, so the templates are replaced as text.
+		return templateExpression.ReplaceAllFunc(mock, func(match []byte) []byte {
+			if v, ok := tc.lookup(string(templateExpression.FindSubmatch(match)[1])); ok {
+				return []byte(templateText(v))
+			}
+			return match
+		})
+	}
+	return rme.render(tc.apply(value))
+}
+
+type templateContext struct {
+	request     *http.Request
+	requestBody []byte
+	pathParams  map[string]string
+}
+
+// apply walks a JSON value, replacing templates in every string.
+func (tc *templateContext) apply(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = tc.apply(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = tc.apply(item)
+		}
+		return v
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v
+		}
+		if m := templateExpression.FindStringSubmatchIndex(v); m != nil && m[0] == 0 && m[1] == len(v) {
+			if resolved, ok := tc.lookup(v[m[2]:m[3]]); ok {
+				return resolved
+			}
+			return v
+		}
+		return templateExpression.ReplaceAllStringFunc(v, func(match string) string {
+			if resolved, ok := tc.lookup(templateExpression.FindStringSubmatch(match)[1]); ok {
+				return templateText(resolved)
+			}
+			return match
+		})
+	}
+	return value
+}
+
+// lookup resolves a template expression (without the braces) to a value.
+func (tc *templateContext) lookup(expression string) (any, bool) {
+	switch {
+	case expression == "request.method":
+		return tc.request.Method, true
+	case expression == "request.url":
+		return tc.request.URL.String(), true
+	case strings.HasPrefix(expression, "request.path."):
+		value, ok := tc.pathParams[strings.TrimPrefix(expression, "request.path.")]
+		return typedValue(value), ok
+	case strings.HasPrefix(expression, "request.query."):
+		query := tc.request.URL.Query()
+		name := strings.TrimPrefix(expression, "request.query.")
+		return typedValue(query.Get(name)), query.Has(name)
+	case strings.HasPrefix(expression, "request.header."):
+		value := tc.request.Header.Get(strings.TrimPrefix(expression, "request.header."))
+		return value, value != ""
+	case expression == "request.body" || strings.HasPrefix(expression, "request.body."):
+		var body any
+		if err := json.Unmarshal(tc.requestBody, &body); err != nil {
+			return nil, false
+		}
+		var fields []string
+		if expression != "request.body" {
+			fields = strings.Split(strings.TrimPrefix(expression, "request.body."), ".")
+		}
+		return lookupJSON(body, fields)
+	}
+	return nil, false
+}
+
+// typedValue turns a path or query value into a number or boolean, if it looks like one.
+func typedValue(value string) any {
+	var typed any
+	if err := json.Unmarshal([]byte(value), &typed); err == nil {
+		switch typed.(type) {
+		case float64, bool:
+			return typed
+		}
+	}
+	return value
+}
+
+func templateText(value any) string {
+	switch v := value.(type) {
+	case map[string]any, []any:
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return formatScalar(v)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var templateSpec = `openapi: 3.1.0
+paths:
+  /users/{userId}:
+    put:
+      parameters:
+        - name: userId
+          in: path
+          required: true
+          schema:
+            type: integer
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+      responses:
+        '200':
+          content:
+            application/json:
+              example:
+                id: '{{request.path.userId}}'
+                name: '{{request.body.profile.name}}'
+                greeting: 'hello {{request.body.profile.name}}, from {{request.query.from}}'
+                missing: '{{request.body.nope}}'`
+
+func TestResponseMockEngine_Templates(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(templateSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+
+	request, _ := http.NewRequest(http.MethodPut, "https://api.pb33f.io/users/42?from=wiretap",
+		strings.NewReader(`{"profile":{"name":"dave"}}`))
+	request.Header.Set("Content-Type", "application/json")
+	mock, status, err := me.GenerateResponse(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.JSONEq(t, `{"id":42,"name":"dave","greeting":"hello dave, from wiretap",`+
+		`"missing":"{{request.body.nope}}"}`, string(mock))
+}
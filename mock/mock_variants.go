@@ -0,0 +1,229 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/pb33f/wiretap/shared"
+	"gopkg.in/yaml.v3"
+)
+
+// VariantHeader allows clients to select the variant of a oneOf / anyOf schema, by discriminator value or by the
+// name of the schema component.
+const VariantHeader = "X-Wiretap-Variant"
+
+// maxVariantDepth limits how deep into a schema unions are looked for, which also stops circular schemas.
+const maxVariantDepth = 10
+
+// variantSelector picks the variant rendered for every oneOf / anyOf in a schema.
+type variantSelector struct {
+	strategy  string
+	rotations map[*base.Schema]int
+	lock      sync.Mutex
+}
+
+// SetUnionStrategy sets how variants of oneOf / anyOf schemas are picked: `first` (the default), `random`, or
+// `rotate` (each union cycles through its variants). A client can always pick a variant with the VariantHeader.
+func (rme *ResponseMockEngine) SetUnionStrategy(strategy string) {
+	rme.variants = &variantSelector{
+		strategy:  strings.ToLower(strategy),
+		rotations: make(map[*base.Schema]int),
+	}
+}
+
+// selectMediaTypeVariants picks the variants of the schema of a media type, media types with examples are
+// rendered from the examples, so there is nothing to pick.
+func (rme *ResponseMockEngine) selectMediaTypeVariants(mt *v3.MediaType, request *http.Request) *base.Schema {
+	if mt == nil || mt.Schema == nil || mt.Example != nil || (mt.Examples != nil && mt.Examples.Len() > 0) {
+		return nil
+	}
+	return rme.selectVariants(mt.Schema.Schema(), request.Header.Get(VariantHeader))
+}
+
+// selectVariants returns a copy of a schema, where every oneOf / anyOf only contains the variant that was picked
+// (or requested), so that is what gets rendered. Returns nil if the schema contains no unions.
+func (rme *ResponseMockEngine) selectVariants(schema *base.Schema, requested string) *base.Schema {
+	strategy := shared.MockUnionFirst
+	if rme.variants != nil && rme.variants.strategy != "" {
+		strategy = rme.variants.strategy
+	}
+	if schema == nil || !containsUnion(schema, 0) {
+		return nil
+	}
+	return rme.rewriteSchema(schema, strategy, requested, 0)
+}
+
+func containsUnion(schema *base.Schema, depth int) bool {
+	if schema == nil || depth > maxVariantDepth {
+		return false
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		return true
+	}
+	for _, s := range schema.AllOf {
+		if containsUnion(s.Schema(), depth+1) {
+			return true
+		}
+	}
+	if schema.Properties != nil {
+		for pair := schema.Properties.First(); pair != nil; pair = pair.Next() {
+			if containsUnion(pair.Value().Schema(), depth+1) {
+				return true
+			}
+		}
+	}
+	if schema.Items != nil && schema.Items.IsA() {
+		return containsUnion(schema.Items.A.Schema(), depth+1)
+	}
+	return false
+}
+
+// rewriteSchema copies a schema, picking a variant for every union found on the way down.
+func (rme *ResponseMockEngine) rewriteSchema(schema *base.Schema, strategy, requested string, depth int) *base.Schema {
+	if schema == nil || depth > maxVariantDepth || !containsUnion(schema, 0) {
+		return schema
+	}
+	rewritten := *schema
+	if len(schema.OneOf) > 0 {
+		variant := rme.pickVariant(schema, schema.OneOf, strategy, requested, depth)
+		if isBareUnion(schema) {
+			return variant // the renderer only renders unions of objects, so a bare union is replaced by the variant.
+		}
+		rewritten.OneOf = []*base.SchemaProxy{base.CreateSchemaProxy(variant)}
+	}
+	if len(schema.AnyOf) > 0 {
+		variant := rme.pickVariant(schema, schema.AnyOf, strategy, requested, depth)
+		if isBareUnion(schema) {
+			return variant
+		}
+		rewritten.AnyOf = []*base.SchemaProxy{base.CreateSchemaProxy(variant)}
+	}
+	if len(schema.AllOf) > 0 {
+		rewritten.AllOf = make([]*base.SchemaProxy, len(schema.AllOf))
+		for i, s := range schema.AllOf {
+			rewritten.AllOf[i] = base.CreateSchemaProxy(rme.rewriteSchema(s.Schema(), strategy, requested, depth+1))
+		}
+	}
+	if schema.Properties != nil {
+		rewritten.Properties = orderedmap.New[string, *base.SchemaProxy]()
+		for pair := schema.Properties.First(); pair != nil; pair = pair.Next() {
+			rewritten.Properties.Set(pair.Key(),
+				base.CreateSchemaProxy(rme.rewriteSchema(pair.Value().Schema(), strategy, requested, depth+1)))
+		}
+	}
+	if schema.Items != nil && schema.Items.IsA() {
+		rewritten.Items = &base.DynamicValue[*base.SchemaProxy, bool]{
+			A: base.CreateSchemaProxy(rme.rewriteSchema(schema.Items.A.Schema(), strategy, requested, depth+1)),
+		}
+	}
+	return &rewritten
+}
+
+// pickVariant picks one variant of a union, the variant requested by the client wins, then the strategy is used.
+// If the union has a discriminator, the discriminator property of the variant is set to match.
+func (rme *ResponseMockEngine) pickVariant(union *base.Schema, variants []*base.SchemaProxy,
+	strategy, requested string, depth int) *base.Schema {
+
+	index := -1
+	if requested != "" {
+		index = matchVariant(union, variants, requested)
+	}
+	if index < 0 {
+		switch strategy {
+		case shared.MockUnionRandom:
+			index = rand.Intn(len(variants))
+		case shared.MockUnionRotate:
+			rme.variants.lock.Lock()
+			index = rme.variants.rotations[union] % len(variants)
+			rme.variants.rotations[union] = index + 1
+			rme.variants.lock.Unlock()
+		default:
+			index = 0
+		}
+	}
+
+	variant := rme.rewriteSchema(variants[index].Schema(), strategy, requested, depth+1)
+	if union.Discriminator != nil && union.Discriminator.PropertyName != "" && variant != nil {
+		variant = setDiscriminator(variant, union.Discriminator.PropertyName, discriminatorValue(union, variants[index]))
+	}
+	return variant
+}
+
+// isBareUnion checks if a schema is nothing but a union (no type, properties or allOf of its own).
+func isBareUnion(schema *base.Schema) bool {
+	return len(schema.Type) == 0 && schema.Properties == nil && len(schema.AllOf) == 0
+}
+
+// matchVariant finds the variant a client asked for, by discriminator mapping, component name or title.
+func matchVariant(union *base.Schema, variants []*base.SchemaProxy, requested string) int {
+	ref := ""
+	if union.Discriminator != nil && union.Discriminator.Mapping != nil {
+		ref = union.Discriminator.Mapping.GetOrZero(requested)
+	}
+	for i, v := range variants {
+		if ref != "" && v.IsReference() && v.GetReference() == ref {
+			return i
+		}
+	}
+	for i, v := range variants {
+		if strings.EqualFold(componentName(v), requested) {
+			return i
+		}
+		if s := v.Schema(); s != nil && strings.EqualFold(s.Title, requested) {
+			return i
+		}
+	}
+	return -1
+}
+
+// discriminatorValue returns the value of the discriminator property for a variant, the key in the mapping,
+// otherwise the name of the schema component.
+func discriminatorValue(union *base.Schema, variant *base.SchemaProxy) string {
+	if union.Discriminator.Mapping != nil && variant.IsReference() {
+		for pair := union.Discriminator.Mapping.First(); pair != nil; pair = pair.Next() {
+			if pair.Value() == variant.GetReference() {
+				return pair.Key()
+			}
+		}
+	}
+	return componentName(variant)
+}
+
+func setDiscriminator(variant *base.Schema, property, value string) *base.Schema {
+	if value == "" {
+		return variant
+	}
+	var prop base.Schema
+	if variant.Properties != nil {
+		if existing := variant.Properties.GetOrZero(property); existing != nil && existing.Schema() != nil {
+			prop = *existing.Schema()
+		}
+	}
+	prop.Example = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	withDiscriminator := *variant
+	withDiscriminator.Properties = orderedmap.New[string, *base.SchemaProxy]()
+	if variant.Properties != nil {
+		for pair := variant.Properties.First(); pair != nil; pair = pair.Next() {
+			withDiscriminator.Properties.Set(pair.Key(), pair.Value())
+		}
+	}
+	withDiscriminator.Properties.Set(property, base.CreateSchemaProxy(&prop))
+	return &withDiscriminator
+}
+
+func componentName(proxy *base.SchemaProxy) string {
+	if proxy == nil || !proxy.IsReference() {
+		return ""
+	}
+	ref := proxy.GetReference()
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
@@ -0,0 +1,105 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+var variantSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [pet]
+                properties:
+                  pet:
+                    oneOf:
+                      - $ref: '#/components/schemas/Cat'
+                      - $ref: '#/components/schemas/Dog'
+                    discriminator:
+                      propertyName: kind
+                      mapping:
+                        cat: '#/components/schemas/Cat'
+                        dog: '#/components/schemas/Dog'
+components:
+  schemas:
+    Cat:
+      type: object
+      required: [kind, meows]
+      properties:
+        kind:
+          type: string
+        meows:
+          type: boolean
+    Dog:
+      type: object
+      required: [kind, barks]
+      properties:
+        kind:
+          type: string
+        barks:
+          type: boolean`
+
+func variantEngine(strategy string) *ResponseMockEngine {
+	d, _ := libopenapi.NewDocument([]byte(variantSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+	if strategy != "" {
+		me.SetUnionStrategy(strategy)
+	}
+	return me
+}
+
+func mockedPet(t *testing.T, me *ResponseMockEngine, variant string) map[string]any {
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets", nil)
+	if variant != "" {
+		request.Header.Set(VariantHeader, variant)
+	}
+	mock, status, err := me.GenerateResponse(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, status)
+	var body map[string]map[string]any
+	assert.NoError(t, json.Unmarshal(mock, &body))
+	return body["pet"]
+}
+
+func TestResponseMockEngine_UnionStrategy(t *testing.T) {
+	// first is the default.
+	me := variantEngine("")
+	assert.Contains(t, mockedPet(t, me, ""), "meows")
+	assert.Contains(t, mockedPet(t, me, ""), "meows")
+
+	// rotate cycles through every variant, and sets the discriminator.
+	me = variantEngine(shared.MockUnionRotate)
+	cat := mockedPet(t, me, "")
+	assert.Equal(t, "cat", cat["kind"])
+	assert.Contains(t, cat, "meows")
+	dog := mockedPet(t, me, "")
+	assert.Equal(t, "dog", dog["kind"])
+	assert.Contains(t, dog, "barks")
+	assert.Contains(t, mockedPet(t, me, ""), "meows")
+}
+
+func TestResponseMockEngine_VariantHeader(t *testing.T) {
+	me := variantEngine("")
+	dog := mockedPet(t, me, "dog")
+	assert.Equal(t, "dog", dog["kind"])
+	assert.Contains(t, dog, "barks")
+
+	// component names work too.
+	assert.Contains(t, mockedPet(t, me, "Cat"), "meows")
+}
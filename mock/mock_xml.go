@@ -0,0 +1,219 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+func isXMLMediaType(mediaType string) bool {
+	return strings.Contains(strings.ToLower(mediaType), "xml")
+}
+
+// mediaTypeName looks up the name a media type is registered under (e.g. application/xml), the names of every
+// response media type in the document are indexed the first time this is called.
+func (rme *ResponseMockEngine) mediaTypeName(mt *v3.MediaType) string {
+	rme.mediaTypesOnce.Do(func() {
+		rme.mediaTypes = make(map[*v3.MediaType]string)
+		if rme.doc == nil || rme.doc.Paths == nil {
+			return
+		}
+		for pathPairs := orderedmap.First(rme.doc.Paths.PathItems); pathPairs != nil; pathPairs = pathPairs.Next() {
+			for opPairs := orderedmap.First(pathPairs.Value().GetOperations()); opPairs != nil; opPairs = opPairs.Next() {
+				responses := opPairs.Value().Responses
+				if responses == nil {
+					continue
+				}
+				all := []*v3.Response{responses.Default}
+				for codePairs := orderedmap.First(responses.Codes); codePairs != nil; codePairs = codePairs.Next() {
+					all = append(all, codePairs.Value())
+				}
+				for _, resp := range all {
+					if resp == nil {
+						continue
+					}
+					for mtPairs := orderedmap.First(resp.Content); mtPairs != nil; mtPairs = mtPairs.Next() {
+						rme.mediaTypes[mtPairs.Value()] = mtPairs.Key()
+					}
+				}
+			}
+		}
+	})
+	return rme.mediaTypes[mt]
+}
+
+// renderXML converts a generated (JSON) mock into XML, using the `xml` hints in the schema for element names,
+// attributes, namespaces and wrapped arrays. Examples that are already XML strings are returned as they are.
+func (rme *ResponseMockEngine) renderXML(mt *v3.MediaType, mock []byte) []byte {
+	var value any
+	if err := json.Unmarshal(mock, &value); err != nil {
+		return mock
+	}
+	if s, ok := value.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "<") {
+		return []byte(s)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xmlHeader)
+	proxy := mt.Schema
+	schema := proxySchema(proxy)
+	if _, ok := value.([]any); ok && (schema == nil || schema.XML == nil || !schema.XML.Wrapped) {
+		// a document can only have a single root, so arrays at the root are always wrapped.
+		name := xmlName(proxy, "items")
+		buf.WriteString("<" + name + xmlNamespace(schema) + ">")
+		writeXMLItems(&buf, xmlItemName(schema, "item"), schema, value.([]any), 0)
+		buf.WriteString("</" + name + ">")
+		return buf.Bytes()
+	}
+	writeXMLElement(&buf, xmlName(proxy, "root"), proxy, value, 0)
+	return buf.Bytes()
+}
+
+func writeXMLElement(buf *bytes.Buffer, name string, proxy *base.SchemaProxy, value any, depth int) {
+	schema := proxySchema(proxy)
+	if depth > 50 {
+		return
+	}
+	if items, ok := value.([]any); ok {
+		if schema != nil && schema.XML != nil && schema.XML.Wrapped {
+			buf.WriteString("<" + name + xmlNamespace(schema) + ">")
+			writeXMLItems(buf, xmlItemName(schema, name), schema, items, depth)
+			buf.WriteString("</" + name + ">")
+			return
+		}
+		writeXMLItems(buf, xmlItemName(schema, name), schema, items, depth)
+		return
+	}
+
+	obj, isObject := value.(map[string]any)
+	if !isObject {
+		if value == nil {
+			buf.WriteString("<" + name + xmlNamespace(schema) + "/>")
+			return
+		}
+		buf.WriteString("<" + name + xmlNamespace(schema) + ">")
+		_ = xml.EscapeText(buf, []byte(formatScalar(value)))
+		buf.WriteString("</" + name + ">")
+		return
+	}
+
+	// properties are written in the order of the schema, anything else is sorted.
+	var keys []string
+	props := make(map[string]*base.SchemaProxy)
+	if schema != nil && schema.Properties != nil {
+		for pair := schema.Properties.First(); pair != nil; pair = pair.Next() {
+			props[pair.Key()] = pair.Value()
+			if _, ok := obj[pair.Key()]; ok {
+				keys = append(keys, pair.Key())
+			}
+		}
+	}
+	var extra []string
+	for k := range obj {
+		if _, ok := props[k]; !ok {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	keys = append(keys, extra...)
+
+	buf.WriteString("<" + name + xmlNamespace(schema))
+	for _, k := range keys {
+		if ps := proxySchema(props[k]); ps != nil && ps.XML != nil && ps.XML.Attribute {
+			buf.WriteString(" " + xmlName(props[k], k) + `="`)
+			_ = xml.EscapeText(buf, []byte(formatScalar(obj[k])))
+			buf.WriteString(`"`)
+		}
+	}
+	buf.WriteString(">")
+	for _, k := range keys {
+		if ps := proxySchema(props[k]); ps != nil && ps.XML != nil && ps.XML.Attribute {
+			continue
+		}
+		writeXMLElement(buf, xmlName(props[k], k), props[k], obj[k], depth+1)
+	}
+	buf.WriteString("</" + name + ">")
+}
+
+func writeXMLItems(buf *bytes.Buffer, itemName string, schema *base.Schema, items []any, depth int) {
+	var itemProxy *base.SchemaProxy
+	if schema != nil && schema.Items != nil && schema.Items.IsA() {
+		itemProxy = schema.Items.A
+	}
+	for _, item := range items {
+		writeXMLElement(buf, itemName, itemProxy, item, depth+1)
+	}
+}
+
+// xmlName returns the element name for a schema, the xml name wins, then the name of the referenced component.
+func xmlName(proxy *base.SchemaProxy, fallback string) string {
+	name := fallback
+	schema := proxySchema(proxy)
+	if schema != nil && schema.XML != nil && schema.XML.Name != "" {
+		name = schema.XML.Name
+	} else if proxy != nil && proxy.IsReference() {
+		ref := proxy.GetReference()
+		name = ref[strings.LastIndex(ref, "/")+1:]
+	}
+	if schema != nil && schema.XML != nil && schema.XML.Prefix != "" {
+		name = schema.XML.Prefix + ":" + name
+	}
+	return name
+}
+
+// xmlItemName returns the element name for the items of an array, which defaults to the name of the array.
+func xmlItemName(schema *base.Schema, fallback string) string {
+	if schema != nil && schema.Items != nil && schema.Items.IsA() {
+		if is := proxySchema(schema.Items.A); is != nil && is.XML != nil && is.XML.Name != "" {
+			return xmlName(schema.Items.A, fallback)
+		}
+	}
+	return fallback
+}
+
+func xmlNamespace(schema *base.Schema) string {
+	if schema == nil || schema.XML == nil || schema.XML.Namespace == "" {
+		return ""
+	}
+	attr := "xmlns"
+	if schema.XML.Prefix != "" {
+		attr += ":" + schema.XML.Prefix
+	}
+	var escaped bytes.Buffer
+	_ = xml.EscapeText(&escaped, []byte(schema.XML.Namespace))
+	return fmt.Sprintf(` %s="%s"`, attr, escaped.String())
+}
+
+func formatScalar(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func proxySchema(proxy *base.SchemaProxy) *base.Schema {
+	if proxy == nil {
+		return nil
+	}
+	return proxy.Schema()
+}
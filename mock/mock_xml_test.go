@@ -0,0 +1,66 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var xmlSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          content:
+            application/xml:
+              schema:
+                $ref: '#/components/schemas/Pet'
+              example:
+                id: 1
+                name: Fido & Co
+                tags:
+                  - good
+                  - fluffy
+components:
+  schemas:
+    Pet:
+      type: object
+      xml:
+        name: pet
+        namespace: https://pb33f.io/pets
+      properties:
+        id:
+          type: integer
+          xml:
+            attribute: true
+        name:
+          type: string
+        tags:
+          type: array
+          xml:
+            wrapped: true
+          items:
+            type: string
+            xml:
+              name: tag`
+
+func TestResponseMockEngine_XML(t *testing.T) {
+	d, _ := libopenapi.NewDocument([]byte(xmlSpec))
+	compiled, _ := d.BuildV3Model()
+	me := NewMockEngine(&compiled.Model, false)
+
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets", nil)
+	mock, status, contentType, err := me.GenerateResponseWithContentType(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "application/xml", contentType)
+	assert.Equal(t, xmlHeader+`<pet xmlns="https://pb33f.io/pets" id="1"><name>Fido &amp; Co</name>`+
+		`<tags><tag>good</tag><tag>fluffy</tag></tags></pet>`, string(mock))
+}
@@ -0,0 +1,186 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package notify posts a summary of violations to webhooks (Slack, or anything that accepts a POST) as they
+// happen, at most once per interval for each webhook.
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// defaultInterval is the minimum time (in seconds) between two notifications to the same webhook.
+const defaultInterval = 60
+
+// maxItems is the most violations listed in a notification, the rest are only counted.
+const maxItems = 10
+
+// Item is a violation in a notification.
+type Item struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Summary is what a notification is rendered from, the violations seen since the last notification.
+type Summary struct {
+	Violations int            `json:"violations"`
+	Severities map[string]int `json:"severities"`
+	Items      []*Item        `json:"items"`
+	Omitted    int            `json:"omitted,omitempty"`
+	Since      time.Time      `json:"since"`
+	Until      time.Time      `json:"until"`
+}
+
+// webhook batches violations for a single endpoint, between notifications.
+type webhook struct {
+	config    *shared.WiretapNotificationConfig
+	url       string
+	headers   map[string]string
+	minimum   int
+	interval  time.Duration
+	template  *template.Template
+	pending   *Summary
+	lastSent  time.Time
+	scheduled bool
+	lock      sync.Mutex
+}
+
+// Notifier sends violations to every configured webhook.
+type Notifier struct {
+	webhooks []*webhook
+	client   *http.Client
+	logger   *slog.Logger
+}
+
+// severityRanks orders severities, a webhook is notified of violations at (or above) its severity.
+var severityRanks = map[string]int{shared.SeverityInfo: 0, shared.SeverityWarn: 1, shared.SeverityError: 2}
+
+// NewNotifier creates a notifier for the configured webhooks. Webhooks with a template that can't be loaded
+// are skipped.
+func NewNotifier(config *shared.WiretapConfiguration) *Notifier {
+	n := &Notifier{client: &http.Client{Timeout: 10 * time.Second}, logger: config.Logger}
+	for _, nc := range config.Notifications {
+		if nc == nil || nc.URL == "" {
+			continue
+		}
+		tmpl, err := loadTemplate(nc.Template)
+		if err != nil {
+			if n.logger != nil {
+				n.logger.Warn("[wiretap] unable to load notification template, ignoring webhook",
+					"template", nc.Template, "error", err.Error())
+			}
+			continue
+		}
+		wh := &webhook{
+			config:   nc,
+			url:      os.ExpandEnv(config.ReplaceWithVariables(nc.URL)),
+			headers:  make(map[string]string, len(nc.Headers)),
+			interval: defaultInterval * time.Second,
+			template: tmpl,
+		}
+		for k, v := range nc.Headers {
+			wh.headers[k] = os.ExpandEnv(config.ReplaceWithVariables(v))
+		}
+		if nc.Interval > 0 {
+			wh.interval = time.Duration(nc.Interval) * time.Second
+		}
+		if rank, ok := severityRanks[strings.ToLower(nc.Severity)]; ok {
+			wh.minimum = rank
+		} else {
+			wh.minimum = severityRanks[shared.SeverityError]
+		}
+		n.webhooks = append(n.webhooks, wh)
+	}
+	return n
+}
+
+// Notify adds violations to the next notification of every webhook that wants them. A notification is sent
+// straight away if the webhook hasn't been notified within its interval, otherwise when the interval is up.
+func (n *Notifier) Notify(items []*Item) {
+	if n == nil || len(items) == 0 {
+		return
+	}
+	now := time.Now()
+	for _, wh := range n.webhooks {
+		wh.lock.Lock()
+		added := false
+		for _, item := range items {
+			if severityRanks[item.Severity] < wh.minimum {
+				continue
+			}
+			if wh.pending == nil {
+				wh.pending = &Summary{Severities: make(map[string]int), Since: now}
+			}
+			wh.pending.Violations++
+			wh.pending.Severities[item.Severity]++
+			if len(wh.pending.Items) < maxItems {
+				wh.pending.Items = append(wh.pending.Items, item)
+			} else {
+				wh.pending.Omitted++
+			}
+			added = true
+		}
+		if added && !wh.scheduled {
+			wh.scheduled = true
+			wait := wh.interval - now.Sub(wh.lastSent)
+			if wait < 0 {
+				wait = 0
+			}
+			time.AfterFunc(wait, func() { n.flush(wh) })
+		}
+		wh.lock.Unlock()
+	}
+}
+
+// flush sends the pending notification of a webhook.
+func (n *Notifier) flush(wh *webhook) {
+	wh.lock.Lock()
+	summary := wh.pending
+	wh.pending, wh.scheduled, wh.lastSent = nil, false, time.Now()
+	wh.lock.Unlock()
+	if summary == nil {
+		return
+	}
+	summary.Until = time.Now()
+	if err := n.send(wh, summary); err != nil && n.logger != nil {
+		n.logger.Error("[wiretap] unable to send notification", "url", wh.config.URL, "error", err.Error())
+	}
+}
+
+func (n *Notifier) send(wh *webhook, summary *Summary) error {
+	var body bytes.Buffer
+	if err := wh.template.Execute(&body, summary); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, wh.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range wh.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier_RateLimited(t *testing.T) {
+	var lock sync.Mutex
+	var received []*Summary
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var summary Summary
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &summary)
+		lock.Lock()
+		received = append(received, &summary)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	n := NewNotifier(&shared.WiretapConfiguration{Notifications: []*shared.WiretapNotificationConfig{
+		{URL: server.URL, Severity: shared.SeverityWarn},
+	}})
+	require.Len(t, n.webhooks, 1)
+	n.webhooks[0].interval = 200 * time.Millisecond
+
+	n.Notify([]*Item{{Method: "GET", Path: "/pets", Severity: shared.SeverityError, Message: "first"}})
+	time.Sleep(50 * time.Millisecond)
+	n.Notify([]*Item{
+		{Method: "GET", Path: "/pets", Severity: shared.SeverityWarn, Message: "second"},
+		{Method: "GET", Path: "/pets", Severity: shared.SeverityInfo, Message: "ignored"},
+	})
+	n.Notify([]*Item{{Method: "POST", Path: "/pets", Severity: shared.SeverityError, Message: "third"}})
+
+	time.Sleep(50 * time.Millisecond)
+	lock.Lock()
+	assert.Len(t, received, 1)
+	lock.Unlock()
+
+	time.Sleep(250 * time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, received, 2)
+	assert.Equal(t, 1, received[0].Violations)
+	assert.Equal(t, 2, received[1].Violations)
+	assert.Equal(t, "second", received[1].Items[0].Message)
+	assert.Equal(t, map[string]int{"warn": 1, "error": 1}, received[1].Severities)
+}
+
+func TestSlackTemplate(t *testing.T) {
+	tmpl, err := loadTemplate(TemplateSlack)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	require.NoError(t, tmpl.Execute(&b, &Summary{Violations: 12, Omitted: 11, Items: []*Item{
+		{Method: "GET", Path: "/pets", Severity: "error", Message: `Query parameter "limit" is missing`},
+	}}))
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal([]byte(b.String()), &payload))
+	assert.Equal(t, "wiretap found 12 API violations", payload["text"])
+	assert.Contains(t, b.String(), `…and 11 more`)
+}
@@ -0,0 +1,72 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// Built in notification templates, anything else is the path to a template file.
+const (
+	TemplateJSON  = "json"
+	TemplateSlack = "slack"
+)
+
+// jsonTemplate posts the summary as it is.
+const jsonTemplate = `{{ json . }}`
+
+// slackTemplate posts a message to a Slack incoming webhook.
+const slackTemplate = `{
+  "text": {{ json (printf "wiretap found %d API %s" .Violations (plural .Violations "violation" "violations")) }},
+  "blocks": [
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{ json (printf ":rotating_light: *wiretap found %d API %s*" .Violations (plural .Violations "violation" "violations")) }}}},
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{ json (list .) }}}}
+  ]
+}`
+
+// templateFuncs are available to every template. json renders a value as JSON, plural picks the singular or plural
+// of a word, list renders the violations of a summary as markdown lines.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"plural": func(count int, singular, plural string) string {
+		return shared.Pluralize(count, singular, plural)
+	},
+	"list": func(summary *Summary) string {
+		lines := make([]string, 0, len(summary.Items)+1)
+		for _, item := range summary.Items {
+			lines = append(lines, fmt.Sprintf("• `%s %s` [%s] %s", item.Method, item.Path, item.Severity, item.Message))
+		}
+		if summary.Omitted > 0 {
+			lines = append(lines, fmt.Sprintf("…and %d more", summary.Omitted))
+		}
+		return strings.Join(lines, "\n")
+	},
+}
+
+// loadTemplate returns a built-in template, or reads a template file.
+func loadTemplate(name string) (*template.Template, error) {
+	text := jsonTemplate
+	switch strings.ToLower(name) {
+	case "", TemplateJSON:
+	case TemplateSlack:
+		text = slackTemplate
+	default:
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		text = string(b)
+	}
+	return template.New("notification").Funcs(templateFuncs).Parse(text)
+}
@@ -0,0 +1,183 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package overlay applies OpenAPI Overlay documents to specifications, so a specification can be patched (examples
+// added, types relaxed, operations removed) without changing the original file.
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+// Overlay is an OpenAPI Overlay document, its actions are applied in order.
+type Overlay struct {
+	Version string    `yaml:"overlay"`
+	Info    Info      `yaml:"info"`
+	Extends string    `yaml:"extends,omitempty"`
+	Actions []*Action `yaml:"actions"`
+}
+
+// Info describes an overlay.
+type Info struct {
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+}
+
+// Action updates or removes the nodes of a specification selected by a JSONPath target.
+type Action struct {
+	Target      string    `yaml:"target"`
+	Description string    `yaml:"description,omitempty"`
+	Update      yaml.Node `yaml:"update,omitempty"`
+	Remove      bool      `yaml:"remove,omitempty"`
+}
+
+// Parse reads an overlay document, every action must have a target.
+func Parse(overlayBytes []byte) (*Overlay, error) {
+	var o Overlay
+	if err := yaml.Unmarshal(overlayBytes, &o); err != nil {
+		return nil, err
+	}
+	if o.Version == "" {
+		return nil, fmt.Errorf("not an overlay document, there is no 'overlay' version")
+	}
+	if len(o.Actions) == 0 {
+		return nil, fmt.Errorf("overlay has no actions")
+	}
+	for i, action := range o.Actions {
+		if action.Target == "" {
+			return nil, fmt.Errorf("overlay action %d has no target", i+1)
+		}
+		if _, err := yamlpath.NewPath(action.Target); err != nil {
+			return nil, fmt.Errorf("overlay action %d has an invalid target '%s': %w", i+1, action.Target, err)
+		}
+	}
+	return &o, nil
+}
+
+// Apply runs the actions of the overlay against a specification and returns the result (as YAML). Removing a node
+// drops it from its parent. Updating merges the update into the node: objects are merged recursively, updates to
+// arrays are appended, anything else is replaced. Targets that select nothing are returned, they aren't errors.
+func (o *Overlay) Apply(specBytes []byte) ([]byte, []string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(specBytes, &root); err != nil {
+		return nil, nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, nil, fmt.Errorf("specification is empty")
+	}
+
+	var unmatched []string
+	for _, action := range o.Actions {
+		path, err := yamlpath.NewPath(action.Target)
+		if err != nil {
+			return nil, nil, err
+		}
+		nodes, err := path.Find(root.Content[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(nodes) == 0 {
+			unmatched = append(unmatched, action.Target)
+			continue
+		}
+		if action.Remove {
+			parents := make(map[*yaml.Node]*yaml.Node)
+			mapParents(root.Content[0], parents)
+			for _, node := range nodes {
+				remove(parents[node], node)
+			}
+			continue
+		}
+		if action.Update.Kind == 0 {
+			continue
+		}
+		for _, node := range nodes {
+			merge(node, &action.Update)
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), unmatched, nil
+}
+
+func mapParents(node *yaml.Node, parents map[*yaml.Node]*yaml.Node) {
+	for _, child := range node.Content {
+		parents[child] = node
+		mapParents(child, parents)
+	}
+}
+
+func remove(parent, node *yaml.Node) {
+	if parent == nil {
+		return // the root can't be removed.
+	}
+	switch parent.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(parent.Content); i += 2 {
+			if parent.Content[i] == node {
+				parent.Content = append(parent.Content[:i-1], parent.Content[i+1:]...)
+				return
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range parent.Content {
+			if child == node {
+				parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func merge(target, update *yaml.Node) {
+	switch {
+	case target.Kind == yaml.MappingNode && update.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(update.Content); i += 2 {
+			key, value := update.Content[i], update.Content[i+1]
+			if existing := lookup(target, key.Value); existing != nil {
+				merge(existing, value)
+				continue
+			}
+			target.Content = append(target.Content, clone(key), clone(value))
+		}
+	case target.Kind == yaml.SequenceNode:
+		if update.Kind == yaml.SequenceNode {
+			for _, item := range update.Content {
+				target.Content = append(target.Content, clone(item))
+			}
+		} else {
+			target.Content = append(target.Content, clone(update))
+		}
+	default:
+		*target = *clone(update)
+	}
+}
+
+func lookup(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// clone copies a node, so an update applied to several targets doesn't share nodes between them.
+func clone(node *yaml.Node) *yaml.Node {
+	c := *node
+	c.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		c.Content[i] = clone(child)
+	}
+	return &c
+}
@@ -0,0 +1,90 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package overlay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+var spec = `openapi: 3.1.0
+info:
+  title: Vendor API
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  age:
+                    type: integer
+    delete:
+      responses:
+        '204':
+          description: Deleted`
+
+func TestApply(t *testing.T) {
+	o, err := Parse([]byte(`overlay: 1.0.0
+info:
+  title: Patch the vendor
+  version: 1.0.0
+actions:
+  - target: $.info
+    update:
+      title: Patched API
+  - target: $.paths['/pets'].get.tags
+    update: animals
+  - target: $.paths['/pets'].get.responses['200'].content['application/json'].schema.properties.age
+    update:
+      type: [integer, string]
+      example: 3
+  - target: $.paths['/pets'].delete
+    remove: true
+  - target: $.paths['/cats']
+    remove: true`))
+	assert.NoError(t, err)
+
+	patched, unmatched, err := o.Apply([]byte(spec))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"$.paths['/cats']"}, unmatched)
+
+	var result map[string]any
+	assert.NoError(t, yaml.Unmarshal(patched, &result))
+	assert.Equal(t, "Patched API", result["info"].(map[string]any)["title"])
+
+	pets := result["paths"].(map[string]any)["/pets"].(map[string]any)
+	assert.NotContains(t, pets, "delete")
+
+	get := pets["get"].(map[string]any)
+	assert.Equal(t, []any{"pets", "animals"}, get["tags"])
+
+	age := get["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)["properties"].(map[string]any)["age"].(map[string]any)
+	assert.Equal(t, []any{"integer", "string"}, age["type"])
+	assert.Equal(t, 3, age["example"])
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte(`info: {title: nope}`))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte(`overlay: 1.0.0
+actions:
+  - update: {}`))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte(`overlay: 1.0.0
+actions:
+  - target: $.paths[
+    remove: true`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,223 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package redact masks secrets and personal data in captured traffic, so it never leaves wiretap: headers by name,
+// JSON bodies by JSONPath, and anything else by regular expression.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+// Mask replaces everything that is redacted.
+const Mask = "[REDACTED]"
+
+// Redactor masks header values, values in JSON bodies, and text matching patterns. A nil redactor masks nothing.
+type Redactor struct {
+	headers  map[string]bool
+	paths    []*yamlpath.Path
+	patterns []*regexp.Regexp
+}
+
+// New creates a redactor for header names (case-insensitive), JSONPath expressions (matched against JSON bodies)
+// and regular expressions (matched against headers, bodies, URLs and violations). When a pattern has capture
+// groups only the groups are masked, e.g. `token=([^&]+)` keeps the parameter name. Nil is returned when there is
+// nothing to redact.
+func New(headers, paths, patterns []string) (*Redactor, error) {
+	if len(headers) == 0 && len(paths) == 0 && len(patterns) == 0 {
+		return nil, nil
+	}
+	r := &Redactor{headers: make(map[string]bool)}
+	for _, h := range headers {
+		r.headers[strings.ToLower(strings.TrimSpace(h))] = true
+	}
+	for _, p := range paths {
+		path, err := yamlpath.NewPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction path '%s': %w", p, err)
+		}
+		r.paths = append(r.paths, path)
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern '%s': %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Header masks the value of a header if it's redacted by name, otherwise any patterns it matches.
+func (r *Redactor) Header(name, value string) string {
+	if r == nil {
+		return value
+	}
+	if r.headers[strings.ToLower(name)] {
+		return Mask
+	}
+	return r.Text(value)
+}
+
+// HeaderRedacted checks if a header is redacted by name, e.g. cookies are when Cookie is.
+func (r *Redactor) HeaderRedacted(name string) bool {
+	return r != nil && r.headers[strings.ToLower(name)]
+}
+
+// Headers returns a copy of headers, with the values redacted.
+func (r *Redactor) Headers(headers http.Header) http.Header {
+	if r == nil || headers == nil {
+		return headers
+	}
+	redacted := make(http.Header, len(headers))
+	for name, values := range headers {
+		for _, v := range values {
+			redacted[name] = append(redacted[name], r.Header(name, v))
+		}
+	}
+	return redacted
+}
+
+// Text masks anything that matches a pattern.
+func (r *Redactor) Text(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+	for _, re := range r.patterns {
+		text = maskMatches(re, text)
+	}
+	return text
+}
+
+// Body masks the values selected by the JSONPath expressions (if the body is JSON), then anything that matches a
+// pattern. A body that isn't JSON is only matched against patterns.
+func (r *Redactor) Body(body []byte) []byte {
+	if r == nil || len(body) == 0 {
+		return body
+	}
+	if len(r.paths) > 0 {
+		if redacted, ok := r.redactJSON(body); ok {
+			body = redacted
+		}
+	}
+	if len(r.patterns) > 0 {
+		body = []byte(r.Text(string(body)))
+	}
+	return body
+}
+
+// redactJSON masks the nodes selected by every path, the body is only re-encoded if something was masked, and
+// keys keep their order.
+func (r *Redactor) redactJSON(body []byte) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') || !json.Valid(trimmed) {
+		return nil, false
+	}
+	var root yaml.Node
+	if yaml.Unmarshal(trimmed, &root) != nil || len(root.Content) == 0 {
+		return nil, false
+	}
+	masked := false
+	for _, path := range r.paths {
+		nodes, err := path.Find(root.Content[0])
+		if err != nil {
+			continue
+		}
+		for _, n := range nodes {
+			*n = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: Mask}
+			masked = true
+		}
+	}
+	if !masked {
+		return nil, false
+	}
+	var b bytes.Buffer
+	if writeJSON(&b, root.Content[0]) != nil {
+		return nil, false
+	}
+	return b.Bytes(), true
+}
+
+// writeJSON writes a node decoded from JSON back out as JSON.
+func writeJSON(b *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(n.Content[i].Value)
+			b.Write(key)
+			b.WriteByte(':')
+			if err := writeJSON(b, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, c := range n.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJSON(b, c); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case "!!int", "!!float", "!!bool":
+			b.WriteString(n.Value)
+		case "!!null":
+			b.WriteString("null")
+		default:
+			s, _ := json.Marshal(n.Value)
+			b.Write(s)
+		}
+	default:
+		return fmt.Errorf("unexpected node kind %d", n.Kind)
+	}
+	return nil
+}
+
+// maskMatches replaces the capture groups of every match, or the whole match if there are no groups.
+func maskMatches(re *regexp.Regexp, text string) string {
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		spans := [][2]int{{m[0], m[1]}}
+		if len(m) > 2 {
+			spans = spans[:0]
+			for g := 2; g+1 < len(m); g += 2 {
+				if m[g] >= last && m[g+1] > m[g] {
+					spans = append(spans, [2]int{m[g], m[g+1]})
+				}
+			}
+		}
+		for _, s := range spans {
+			if s[0] < last {
+				continue
+			}
+			b.WriteString(text[last:s[0]])
+			b.WriteString(Mask)
+			last = s[1]
+		}
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
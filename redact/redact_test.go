@@ -0,0 +1,46 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package redact
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	r, err := New([]string{"Authorization"}, []string{"$.password", "$.cards[*].number"},
+		[]string{`token=([^&]+)`, `[\w.]+@example\.com`})
+	assert.NoError(t, err)
+
+	assert.Equal(t, Mask, r.Header("authorization", "Bearer abc"))
+	assert.Equal(t, "user="+Mask, r.Header("X-Note", "user=bob@example.com"))
+	assert.Equal(t, http.Header{"Authorization": {Mask}, "Accept": {"*/*"}},
+		r.Headers(http.Header{"Authorization": {"Bearer abc"}, "Accept": {"*/*"}}))
+
+	assert.Equal(t, "/pets?token="+Mask+"&limit=1", r.Text("/pets?token=s3cr3t&limit=1"))
+
+	body := r.Body([]byte(`{"name":"bob","password":"hunter2","age":4,"cards":[{"number":"4111","cvc":null}]}`))
+	assert.Equal(t, `{"name":"bob","password":"[REDACTED]","age":4,"cards":[{"number":"[REDACTED]","cvc":null}]}`,
+		string(body))
+
+	// bodies that aren't JSON, or don't have anything selected, are only matched against patterns.
+	assert.Equal(t, "email: "+Mask, string(r.Body([]byte("email: bob@example.com"))))
+	assert.Equal(t, `{ "name": "bob" }`, string(r.Body([]byte(`{ "name": "bob" }`))))
+}
+
+func TestRedactor_Nothing(t *testing.T) {
+	r, err := New(nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+	assert.Equal(t, "secret", r.Text("secret"))
+	assert.Equal(t, []byte("secret"), r.Body([]byte("secret")))
+
+	_, err = New(nil, []string{"$[?"}, nil)
+	assert.Error(t, err)
+	_, err = New(nil, nil, []string{"("})
+	assert.Error(t, err)
+}
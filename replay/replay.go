@@ -0,0 +1,215 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package replay re-sends captured requests against a target, at the pace they were captured (or faster), and
+// validates what comes back.
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pb33f/harhar"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// Request is a captured request, and when it was captured.
+type Request struct {
+	Method  string
+	URL     string
+	Headers http.Header
+	Body    []byte
+	Start   time.Time
+}
+
+// Result is the outcome of replaying a request, either the status code returned and the violations found, or
+// the error that stopped the request from being sent.
+type Result struct {
+	Request    *Request
+	URL        string
+	StatusCode int
+	Duration   time.Duration
+	Violations []*errors.ValidationError
+	Err        error
+}
+
+// skippedHeaders are set by the client sending the request, they are not copied from captured requests.
+var skippedHeaders = map[string]bool{
+	"Host": true, "Content-Length": true, "Connection": true, "Transfer-Encoding": true, "Accept-Encoding": true,
+}
+
+// FromHAR reads the requests captured in a HAR file.
+func FromHAR(har *harhar.HAR) []*Request {
+	var requests []*Request
+	for _, entry := range har.Log.Entries {
+		if entry.Request.Method == "" {
+			continue
+		}
+		r := &Request{Method: entry.Request.Method, URL: entry.Request.URL, Headers: make(http.Header),
+			Body: []byte(entry.Request.Body.Content)}
+		r.Start, _ = time.Parse(time.RFC3339Nano, entry.Start)
+		for _, h := range entry.Request.Headers {
+			if !strings.HasPrefix(h.Name, ":") {
+				r.Headers.Add(h.Name, h.Value)
+			}
+		}
+		requests = append(requests, r)
+	}
+	return requests
+}
+
+// FromNDJSON reads the requests captured in an NDJSON-HAR file (a HAR entry per line), false is returned if the
+// data isn't NDJSON-HAR.
+func FromNDJSON(data []byte) ([]*Request, bool) {
+	har := &harhar.HAR{}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry harhar.Entry
+		if json.Unmarshal(line, &entry) != nil || entry.Request.Method == "" {
+			return nil, false
+		}
+		har.Log.Entries = append(har.Log.Entries, entry)
+	}
+	return FromHAR(har), len(har.Log.Entries) > 0
+}
+
+// FromTransactions reads the requests of transactions kept in a transaction store.
+func FromTransactions(transactions []*daemon.HttpTransaction) []*Request {
+	var requests []*Request
+	for _, transaction := range transactions {
+		if transaction.Request == nil || transaction.Request.Method == "" {
+			continue
+		}
+		captured := transaction.Request
+		r := &Request{Method: captured.Method, URL: captured.URL, Headers: make(http.Header),
+			Body: []byte(captured.Body), Start: time.UnixMilli(captured.Timestamp)}
+		if r.URL == "" {
+			r.URL = captured.Path
+			if captured.Query != "" {
+				r.URL += "?" + captured.Query
+			}
+		}
+		for name, value := range captured.Headers {
+			if s, ok := value.(string); ok {
+				r.Headers.Set(name, s)
+			}
+		}
+		requests = append(requests, r)
+	}
+	return requests
+}
+
+// Retarget swaps the scheme and host of a captured URL for the target, the path of the target is a prefix
+// to the captured path.
+func Retarget(captured string, target *url.URL) (string, error) {
+	u, err := url.Parse(captured)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme, u.Host = target.Scheme, target.Host
+	u.Path = strings.TrimSuffix(target.Path, "/") + u.Path
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// Delay is how long to wait between sending two requests, so the gap between them is kept (divided by the
+// speed). A speed of zero or less sends requests as fast as possible.
+func Delay(previous, next time.Time, speed float64) time.Duration {
+	if speed <= 0 || previous.IsZero() || next.IsZero() || !next.After(previous) {
+		return 0
+	}
+	return time.Duration(float64(next.Sub(previous)) / speed)
+}
+
+// Replayer sends requests to a target, and validates them (and the responses) if there is a validator.
+type Replayer struct {
+	Target    *url.URL
+	Speed     float64
+	Validator validation.HttpValidator
+	Client    *http.Client
+}
+
+// Run replays requests in order, calling result with the outcome of each. Replaying stops if the context is done.
+func (rp *Replayer) Run(ctx context.Context, requests []*Request, result func(*Result)) {
+	client := rp.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	var previous time.Time
+	for _, r := range requests {
+		if wait := Delay(previous, r.Start, rp.Speed); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		previous = r.Start
+		result(rp.send(ctx, client, r))
+	}
+}
+
+func (rp *Replayer) send(ctx context.Context, client *http.Client, r *Request) *Result {
+	result := &Result{Request: r}
+	target, err := Retarget(r.URL, rp.Target)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.URL = target
+	req, err := http.NewRequestWithContext(ctx, r.Method, target, bytes.NewReader(r.Body))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	for name, values := range r.Headers {
+		if !skippedHeaders[http.CanonicalHeaderKey(name)] {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	if rp.Validator == nil {
+		return result
+	}
+
+	// validate against the path that was captured, the target may have a prefix of its own.
+	validated, _ := http.NewRequest(r.Method, r.URL, bytes.NewReader(r.Body))
+	if validated == nil {
+		return result
+	}
+	validated.Header = req.Header.Clone()
+	_, result.Violations = rp.Validator.ValidateHttpRequest(validated)
+	validated.Body = io.NopCloser(bytes.NewReader(r.Body))
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	_, responseViolations := rp.Validator.ValidateHttpResponse(validated, resp)
+	for _, v := range responseViolations {
+		if !v.IsPathMissingError() {
+			result.Violations = append(result.Violations, v)
+		}
+	}
+	return result
+}
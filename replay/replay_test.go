@@ -0,0 +1,66 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package replay
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pb33f/harhar"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetarget(t *testing.T) {
+	target, _ := url.Parse("http://localhost:8080/v2/")
+	u, err := Retarget("https://api.pb33f.io/pets?limit=1", target)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/v2/pets?limit=1", u)
+
+	u, _ = Retarget("/pets/1", target)
+	assert.Equal(t, "http://localhost:8080/v2/pets/1", u)
+}
+
+func TestDelay(t *testing.T) {
+	start := time.Now()
+	assert.Equal(t, 2*time.Second, Delay(start, start.Add(2*time.Second), 1))
+	assert.Equal(t, 500*time.Millisecond, Delay(start, start.Add(2*time.Second), 4))
+	assert.Zero(t, Delay(start, start.Add(2*time.Second), 0))
+	assert.Zero(t, Delay(time.Time{}, start, 1))
+	assert.Zero(t, Delay(start, start.Add(-time.Second), 1))
+}
+
+func TestReplayer_Run(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.String()+" "+r.Header.Get("X-Pet")+string(body))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	requests := FromHAR(&harhar.HAR{Log: harhar.Log{Entries: []harhar.Entry{
+		{Start: start.Format(time.RFC3339Nano), Request: harhar.Request{Method: "GET",
+			URL: "https://api.pb33f.io/pets?limit=1", Headers: []harhar.NameValuePair{{Name: "X-Pet", Value: "cat"}}}},
+		{Start: start.Add(time.Hour).Format(time.RFC3339Nano), Request: harhar.Request{Method: "POST",
+			URL: "https://api.pb33f.io/pets", Body: harhar.BodyType{Content: `{"name":"dog"}`}}},
+	}}})
+
+	target, _ := url.Parse(server.URL)
+	var results []*Result
+	(&Replayer{Target: target}).Run(context.Background(), requests, func(r *Result) {
+		results = append(results, r)
+	})
+
+	assert.Equal(t, []string{"GET /pets?limit=1 cat", `POST /pets {"name":"dog"}`}, received)
+	assert.Len(t, results, 2)
+	assert.Equal(t, http.StatusCreated, results[1].StatusCode)
+	assert.NoError(t, results[1].Err)
+}
@@ -0,0 +1,106 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pb33f/wiretap/coverage"
+)
+
+// coverageTemplate is a self-contained page, so it can be attached to a CI run or opened from disk.
+const coverageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2rem; color: #222; }
+  h1 { font-size: 1.4rem; } h2 { font-size: 1.1rem; margin-top: 2rem; }
+  .summary { display: flex; gap: 1rem; }
+  .summary div { border: 1px solid #ddd; border-radius: 4px; padding: 0.75rem 1rem; min-width: 10rem; }
+  .summary strong { display: block; font-size: 1.5rem; }
+  table { border-collapse: collapse; width: 100%; margin-top: 0.5rem; }
+  th, td { text-align: left; padding: 0.35rem 0.6rem; border-bottom: 1px solid #eee; vertical-align: top; }
+  th { background: #f6f6f6; }
+  code { font-size: 0.9em; }
+  .untouched td { color: #a00; }
+  .missing { color: #a00; }
+  .undefined { color: #b60; }
+  .muted { color: #888; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+<p class="muted">Generated {{ .Generated }}</p>
+<div class="summary">
+  <div>Operations<strong>{{ percent .Report.Operations }}</strong>{{ .Report.Operations.Covered }} of {{ .Report.Operations.Total }}</div>
+  <div>Responses<strong>{{ percent .Report.Responses }}</strong>{{ .Report.Responses.Covered }} of {{ .Report.Responses.Total }}</div>
+  <div>Parameters<strong>{{ percent .Report.Parameters }}</strong>{{ .Report.Parameters.Covered }} of {{ .Report.Parameters.Total }}</div>
+</div>
+{{ if .Report.Untouched }}
+<h2>Untouched operations ({{ len .Report.Untouched }})</h2>
+<ul>{{ range .Report.Untouched }}<li><code>{{ . }}</code></li>{{ end }}</ul>
+{{ end }}
+<h2>Operations</h2>
+<table>
+  <tr><th>Operation</th><th>Requests</th><th>Status codes observed</th><th>Responses</th><th>Parameters not sent</th></tr>
+  {{ range .Report.Details }}
+  <tr{{ if eq .Requests 0 }} class="untouched"{{ end }}>
+    <td><code>{{ .Method }} {{ .Path }}</code>{{ if .OperationId }}<br><span class="muted">{{ .OperationId }}</span>{{ end }}</td>
+    <td>{{ .Requests }}</td>
+    <td>{{ codes .StatusCodes }}</td>
+    <td>{{ range .Responses }}{{ if eq .Hits 0 }}<span class="missing">{{ .Name }}</span>{{ else }}{{ .Name }} &times; {{ .Hits }}{{ end }}<br>{{ end }}
+      {{ if .UndefinedResponses }}<span class="undefined">undefined: {{ codes .UndefinedResponses }}</span>{{ end }}</td>
+    <td>{{ range .UncoveredParameters }}<span class="missing">{{ . }}</span><br>{{ end }}</td>
+  </tr>
+  {{ end }}
+</table>
+</body>
+</html>
+`
+
+var coverageFuncs = template.FuncMap{
+	"percent": func(s coverage.Summary) string {
+		return fmt.Sprintf("%.2f%%", s.Percent)
+	},
+	"codes": func(codes map[string]int) string {
+		if len(codes) == 0 {
+			return "-"
+		}
+		sorted := make([]string, 0, len(codes))
+		for code := range codes {
+			sorted = append(sorted, code)
+		}
+		sort.Strings(sorted)
+		for i, code := range sorted {
+			sorted[i] = fmt.Sprintf("%s × %d", code, codes[code])
+		}
+		return strings.Join(sorted, ", ")
+	},
+}
+
+var coveragePage = template.Must(template.New("coverage").Funcs(coverageFuncs).Parse(coverageTemplate))
+
+// RenderCoverageHTML renders a human-readable summary of the coverage of a specification (the contract), with
+// the hits and status codes seen for every operation, and the operations that were never touched.
+func RenderCoverageHTML(report *coverage.Report, contract string) ([]byte, error) {
+	title := "wiretap coverage"
+	if contract != "" {
+		title = "wiretap coverage: " + contract
+	}
+	var b bytes.Buffer
+	err := coveragePage.Execute(&b, struct {
+		Title     string
+		Generated string
+		Report    *coverage.Report
+	}{title, time.Now().Format(time.RFC1123), report})
+	return b.Bytes(), err
+}
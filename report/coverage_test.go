@@ -0,0 +1,32 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package report
+
+import (
+	"testing"
+
+	"github.com/pb33f/wiretap/coverage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderCoverageHTML(t *testing.T) {
+	page, err := RenderCoverageHTML(&coverage.Report{
+		Operations: coverage.Summary{Total: 2, Covered: 1, Percent: 50},
+		Untouched:  []string{"POST /pets"},
+		Details: []*coverage.OperationReport{
+			{Method: "GET", Path: "/pets", Requests: 3, StatusCodes: map[string]int{"200": 2, "404": 1},
+				Responses: []*coverage.Item{{Name: "200", Hits: 2}}, UndefinedResponses: map[string]int{"404": 1}},
+			{Method: "POST", Path: "/pets", UncoveredParameters: []string{"<X-Trace> (header)"}},
+		},
+	}, "petstore.yaml")
+
+	assert.NoError(t, err)
+	html := string(page)
+	assert.Contains(t, html, "<title>wiretap coverage: petstore.yaml</title>")
+	assert.Contains(t, html, "50.00%")
+	assert.Contains(t, html, "Untouched operations (1)")
+	assert.Contains(t, html, "200 × 2, 404 × 1")
+	assert.Contains(t, html, "&lt;X-Trace&gt; (header)")
+}
@@ -0,0 +1,116 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// JUnitTestSuites is the root of a JUnit XML report.
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite is a suite of test cases, wiretap reports a single suite for the specification.
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase is an operation, it fails if any of its requests or responses broke the contract.
+type JUnitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []JUnitFailure `xml:"failure,omitempty"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+// JUnitFailure is a violation with an error severity.
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// BuildJUnit builds a JUnit report with a test case for every operation exercised, and a failure for every
+// distinct violation with an error severity. Warnings and info are not failures, they are listed in the output
+// of the test case.
+func BuildJUnit(results []*daemon.OperationResult, contract string) *JUnitTestSuites {
+	name := "wiretap"
+	if contract != "" {
+		name = "wiretap: " + contract
+	}
+	suite := JUnitTestSuite{Name: name}
+	for _, result := range results {
+		testCase := JUnitTestCase{
+			Name:      result.Method + " " + result.Path,
+			ClassName: result.Path,
+		}
+		var output []string
+		for _, v := range result.Violations {
+			if v.Severity != shared.SeverityError {
+				output = append(output, fmt.Sprintf("[%s] %s", v.Severity, describeViolation(v, contract)))
+				continue
+			}
+			testCase.Failures = append(testCase.Failures, JUnitFailure{
+				Message: v.Message,
+				Type:    configModel.ViolationRule(v.ValidationError),
+				Text:    describeViolation(v, contract),
+			})
+		}
+		testCase.SystemOut = strings.Join(output, "\n\n")
+		if len(testCase.Failures) > 0 {
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	return &JUnitTestSuites{Name: "wiretap", Tests: suite.Tests, Failures: suite.Failures,
+		Suites: []JUnitTestSuite{suite}}
+}
+
+// MarshalJUnit renders a JUnit report as XML.
+func MarshalJUnit(report *JUnitTestSuites) ([]byte, error) {
+	b, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
+
+// describeViolation explains a violation, where it is in the specification, how to fix it, and how often it happened.
+func describeViolation(v *shared.Violation, contract string) string {
+	lines := []string{v.Message}
+	if v.Reason != "" && v.Reason != v.Message {
+		lines = append(lines, "Reason: "+v.Reason)
+	}
+	for _, failure := range v.SchemaValidationErrors {
+		lines = append(lines, fmt.Sprintf("Schema: %s (%s)", failure.Reason, failure.Location))
+	}
+	if v.SpecLine > 0 {
+		lines = append(lines, fmt.Sprintf("Location: %s:%d:%d", contract, v.SpecLine, v.SpecCol))
+	}
+	if v.HowToFix != "" {
+		lines = append(lines, "How to fix: "+v.HowToFix)
+	}
+	if v.Count > 1 {
+		lines = append(lines, fmt.Sprintf("Seen %d times", v.Count))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,50 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package report
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildJUnit(t *testing.T) {
+	results := []*daemon.OperationResult{
+		{Method: "GET", Path: "/pets", Requests: 3, Violations: []*shared.Violation{
+			{ValidationError: &errors.ValidationError{Message: "Query parameter 'limit' is missing",
+				Reason: "limit is required", ValidationType: "parameter", ValidationSubType: "query",
+				SpecLine: 12, SpecCol: 7, HowToFix: "send limit"}, Severity: shared.SeverityError, Count: 2},
+			{ValidationError: &errors.ValidationError{Message: "Header 'X-Pet' is not defined",
+				ValidationType: "parameter", ValidationSubType: "unknownHeader"}, Severity: shared.SeverityWarn, Count: 1},
+		}},
+		{Method: "POST", Path: "/pets", Requests: 1},
+	}
+
+	suites := BuildJUnit(results, "pets.yaml")
+	assert.Equal(t, 2, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	require.Len(t, suites.Suites, 1)
+
+	cases := suites.Suites[0].TestCases
+	require.Len(t, cases, 2)
+	assert.Equal(t, "GET /pets", cases[0].Name)
+	require.Len(t, cases[0].Failures, 1)
+	assert.Equal(t, "parameter/query", cases[0].Failures[0].Type)
+	assert.Contains(t, cases[0].Failures[0].Text, "Location: pets.yaml:12:7")
+	assert.Contains(t, cases[0].Failures[0].Text, "Seen 2 times")
+	assert.Contains(t, cases[0].SystemOut, "[warn] Header 'X-Pet' is not defined")
+	assert.Empty(t, cases[1].Failures)
+
+	b, err := MarshalJUnit(suites)
+	require.NoError(t, err)
+	var parsed JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(b, &parsed))
+	assert.Equal(t, "wiretap: pets.yaml", parsed.Suites[0].Name)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/pb33f/ranch/model"
 	"github.com/pb33f/ranch/service"
 	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
 )
 
 const (
@@ -18,20 +19,29 @@ const (
 
 type ReportService struct {
 	transactionStore bus.BusStore
+	wiretapService   *daemon.WiretapService
 }
 
+// GenerateReport asks for a report, it can be narrowed to transactions with violations, violations of some
+// severities, or requests to some paths (globs). The report filter configured is used if it isn't narrowed.
 type GenerateReport struct {
+	ViolationsOnly bool     `mapstructure:"violationsOnly"`
+	Severities     []string `mapstructure:"severities"`
+	Paths          []string `mapstructure:"paths"`
 }
 
 type ReportResponse struct {
 	Transactions []*daemon.HttpTransaction `json:"transactions,omitempty"`
 }
 
-func NewReportService() *ReportService {
+// NewReportService creates a report service. Reports are built from the transaction store of the wiretap service,
+// if it has one, so they include transactions captured before wiretap was restarted.
+func NewReportService(wiretapService *daemon.WiretapService) *ReportService {
 	storeManager := bus.GetBus().GetStoreManager()
 	transactionStore := storeManager.GetStore(daemon.WiretapServiceChan)
 	return &ReportService{
 		transactionStore: transactionStore,
+		wiretapService:   wiretapService,
 	}
 }
 
@@ -52,6 +62,25 @@ func (rs *ReportService) buildReport(request *model.Request, core service.Fabric
 		var r GenerateReport
 		_ = mapstructure.Decode(dl, &r)
 
+		// stored transactions go back further than the ones in memory.
+		if rs.wiretapService != nil {
+			var filter *shared.WiretapReportFilter
+			if r.ViolationsOnly || len(r.Severities) > 0 || len(r.Paths) > 0 {
+				filter = &shared.WiretapReportFilter{ViolationsOnly: r.ViolationsOnly, Severities: r.Severities, Paths: r.Paths}
+				if err := filter.Compile(); err != nil {
+					core.SendErrorResponse(request, 400, err.Error())
+					return
+				}
+			}
+			transactions, err := rs.wiretapService.ReportTransactions(filter)
+			if err != nil {
+				core.SendErrorResponse(request, 500, err.Error())
+				return
+			}
+			core.SendResponse(request, &ReportResponse{transactions})
+			return
+		}
+
 		// extract state from store.
 		storeData := rs.transactionStore.AllValues()
 		var transactions []*daemon.HttpTransaction
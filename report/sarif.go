@@ -0,0 +1,147 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// SARIF version and schema of reports.
+const (
+	SARIFVersion = "2.1.0"
+	SARIFSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIFLog is the root of a SARIF report.
+type SARIFLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun is a run of a tool, wiretap reports a single run.
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule is a rule broken by a violation, e.g. parameter/query.
+type SARIFRule struct {
+	ID               string       `json:"id"`
+	ShortDescription SARIFMessage `json:"shortDescription"`
+}
+
+// SARIFResult is a violation, located in the specification.
+type SARIFResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   SARIFMessage    `json:"message"`
+	Locations []SARIFLocation `json:"locations"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           SARIFRegion           `json:"region"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SARIFRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifLevels maps violation severities to SARIF levels.
+var sarifLevels = map[string]string{
+	shared.SeverityError: "error",
+	shared.SeverityWarn:  "warning",
+	shared.SeverityInfo:  "note",
+}
+
+// BuildSARIF builds a SARIF report with a result for every distinct violation, located at the line of the
+// specification that was violated, so code scanning can annotate the specification.
+func BuildSARIF(results []*daemon.OperationResult, contract, version string) *SARIFLog {
+	run := SARIFRun{
+		Tool: SARIFTool{Driver: SARIFDriver{Name: "wiretap", Version: version,
+			InformationURI: "https://pb33f.io/wiretap/", Rules: []SARIFRule{}}},
+		Results: []SARIFResult{},
+	}
+	rules := make(map[string]string)
+	for _, result := range results {
+		for _, v := range result.Violations {
+			rule := configModel.ViolationRule(v.ValidationError)
+			if _, ok := rules[rule]; !ok {
+				rules[rule] = fmt.Sprintf("%s violation", rule)
+			}
+			level, ok := sarifLevels[v.Severity]
+			if !ok {
+				level = "error"
+			}
+			message := fmt.Sprintf("%s %s: %s", result.Method, result.Path, v.Message)
+			if v.Reason != "" && v.Reason != v.Message {
+				message += ". " + v.Reason
+			}
+			if v.Count > 1 {
+				message += fmt.Sprintf(" (seen %d times)", v.Count)
+			}
+			line, column := v.SpecLine, v.SpecCol
+			if line < 1 && len(v.SchemaValidationErrors) > 0 {
+				line, column = v.SchemaValidationErrors[0].Line, v.SchemaValidationErrors[0].Column
+			}
+			if line < 1 {
+				line, column = 1, 0
+			}
+			run.Results = append(run.Results, SARIFResult{
+				RuleID:  rule,
+				Level:   level,
+				Message: SARIFMessage{Text: message},
+				Locations: []SARIFLocation{{PhysicalLocation: SARIFPhysicalLocation{
+					ArtifactLocation: SARIFArtifactLocation{URI: contract},
+					Region:           SARIFRegion{StartLine: line, StartColumn: column},
+				}}},
+			})
+		}
+	}
+	for rule, description := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SARIFRule{ID: rule,
+			ShortDescription: SARIFMessage{Text: description}})
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+	return &SARIFLog{Version: SARIFVersion, Schema: SARIFSchema, Runs: []SARIFRun{run}}
+}
+
+// MarshalSARIF renders a SARIF report as JSON.
+func MarshalSARIF(report *SARIFLog) ([]byte, error) {
+	return json.MarshalIndent(report, "", "  ")
+}
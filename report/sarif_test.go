@@ -0,0 +1,73 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package report
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata with the current output")
+
+func TestBuildSARIF(t *testing.T) {
+	results := []*daemon.OperationResult{
+		{Method: "GET", Path: "/pets", Requests: 3, Violations: []*shared.Violation{
+			// located by the specification.
+			{ValidationError: &errors.ValidationError{Message: "Query parameter 'limit' is missing",
+				Reason: "limit is required", ValidationType: "parameter", ValidationSubType: "query",
+				SpecLine: 12, SpecCol: 7}, Severity: shared.SeverityError, Count: 2},
+			// located by the schema that failed.
+			{ValidationError: &errors.ValidationError{Message: "200 response body failed to validate",
+				Reason: "200 response body failed to validate", ValidationType: "response", ValidationSubType: "schema",
+				SchemaValidationErrors: []*errors.SchemaValidationFailure{{Reason: "name is required", Line: 30,
+					Column: 11}}}, Severity: shared.SeverityWarn, Count: 1},
+		}},
+		{Method: "POST", Path: "/pets", Requests: 1, Violations: []*shared.Violation{
+			// not located at all, and a severity SARIF doesn't know.
+			{ValidationError: &errors.ValidationError{Message: "Query parameter 'sort' is not defined",
+				ValidationType: "parameter", ValidationSubType: "query"}, Severity: "loud", Count: 1},
+			{ValidationError: &errors.ValidationError{Message: "Path '/pets' is deprecated",
+				ValidationType: "path", ValidationSubType: "deprecated", SpecLine: 20}, Severity: shared.SeverityInfo},
+		}},
+		{Method: "DELETE", Path: "/pets/{id}", Requests: 1},
+	}
+
+	b, err := MarshalSARIF(BuildSARIF(results, "specs/pets.yaml", "v1.2.3"))
+	require.NoError(t, err)
+
+	golden := filepath.Join("testdata", "report.sarif.json")
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(golden, b, 0644))
+	}
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(b), "run 'go test ./report -update' if the change is intended")
+
+	// it's still a SARIF log once read back.
+	var parsed SARIFLog
+	require.NoError(t, json.Unmarshal(b, &parsed))
+	assert.Equal(t, SARIFVersion, parsed.Version)
+}
+
+func TestBuildSARIF_Empty(t *testing.T) {
+	b, err := MarshalSARIF(BuildSARIF(nil, "pets.yaml", ""))
+	require.NoError(t, err)
+
+	// code scanning needs the lists, even when there is nothing in them.
+	var parsed map[string]any
+	require.NoError(t, json.Unmarshal(b, &parsed))
+	run := parsed["runs"].([]any)[0].(map[string]any)
+	assert.Equal(t, []any{}, run["results"])
+	assert.Equal(t, []any{}, run["tool"].(map[string]any)["driver"].(map[string]any)["rules"])
+}
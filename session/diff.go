@@ -0,0 +1,183 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package session
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	configModel "github.com/pb33f/wiretap/config"
+)
+
+// latencyNoiseFloor is the smallest slowdown reported as a regression, anything less is noise.
+const latencyNoiseFloor = 5 * time.Millisecond
+
+// Latency is the median and 95th percentile duration of the requests for an operation, in milliseconds.
+type Latency struct {
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+}
+
+// OperationDiff compares the traffic for an operation in two sessions.
+type OperationDiff struct {
+	Operation          string      `json:"operation"`
+	RequestsA          int         `json:"requestsA"`
+	RequestsB          int         `json:"requestsB"`
+	StatusCodesA       map[int]int `json:"statusCodesA,omitempty"`
+	StatusCodesB       map[int]int `json:"statusCodesB,omitempty"`
+	StatusChanged      bool        `json:"statusChanged,omitempty"`
+	LatencyA           Latency     `json:"latencyA"`
+	LatencyB           Latency     `json:"latencyB"`
+	LatencyRegression  bool        `json:"latencyRegression,omitempty"`
+	NewViolations      []string    `json:"newViolations,omitempty"`
+	ResolvedViolations []string    `json:"resolvedViolations,omitempty"`
+}
+
+// Diff compares two sessions, operation by operation.
+type Diff struct {
+	Operations         []*OperationDiff `json:"operations"`
+	NewViolations      int              `json:"newViolations"`
+	ResolvedViolations int              `json:"resolvedViolations"`
+	StatusChanges      int              `json:"statusChanges"`
+	LatencyRegressions int              `json:"latencyRegressions"`
+}
+
+// Regressed checks if session b introduced new violations, changed status codes or slowed down.
+func (d *Diff) Regressed() bool {
+	return d.NewViolations > 0 || d.StatusChanges > 0 || d.LatencyRegressions > 0
+}
+
+// operationTraffic is the traffic for an operation in a session.
+type operationTraffic struct {
+	requests   int
+	codes      map[int]int
+	durations  []time.Duration
+	violations map[string]bool
+}
+
+// Compare compares session b with session a. Exchanges are grouped by the operation they map to, an operation
+// regresses in latency when its 95th percentile is slower by more than the threshold (a percentage).
+func Compare(a, b []*Exchange, operation func(*Exchange) string, threshold float64) *Diff {
+	trafficA, trafficB := group(a, operation), group(b, operation)
+	names := make(map[string]bool)
+	for name := range trafficA {
+		names[name] = true
+	}
+	for name := range trafficB {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	diff := &Diff{Operations: []*OperationDiff{}}
+	for _, name := range sorted {
+		ta, tb := trafficA[name], trafficB[name]
+		od := &OperationDiff{Operation: name}
+		if ta != nil {
+			od.RequestsA, od.StatusCodesA, od.LatencyA = ta.requests, ta.codes, latency(ta.durations)
+		}
+		if tb != nil {
+			od.RequestsB, od.StatusCodesB, od.LatencyB = tb.requests, tb.codes, latency(tb.durations)
+		}
+		if ta != nil && tb != nil {
+			od.StatusChanged = !sameCodes(ta.codes, tb.codes)
+			slower := od.LatencyB.P95 - od.LatencyA.P95
+			od.LatencyRegression = len(ta.durations) > 0 && len(tb.durations) > 0 &&
+				slower >= float64(latencyNoiseFloor.Milliseconds()) && slower > od.LatencyA.P95*threshold/100
+		}
+		if tb != nil {
+			od.NewViolations = missing(tb.violations, ta)
+		}
+		if ta != nil {
+			od.ResolvedViolations = missing(ta.violations, tb)
+		}
+
+		diff.NewViolations += len(od.NewViolations)
+		diff.ResolvedViolations += len(od.ResolvedViolations)
+		if od.StatusChanged {
+			diff.StatusChanges++
+		}
+		if od.LatencyRegression {
+			diff.LatencyRegressions++
+		}
+		diff.Operations = append(diff.Operations, od)
+	}
+	return diff
+}
+
+func group(exchanges []*Exchange, operation func(*Exchange) string) map[string]*operationTraffic {
+	traffic := make(map[string]*operationTraffic)
+	for _, e := range exchanges {
+		name := operation(e)
+		t, ok := traffic[name]
+		if !ok {
+			t = &operationTraffic{codes: make(map[int]int), violations: make(map[string]bool)}
+			traffic[name] = t
+		}
+		t.requests++
+		if e.StatusCode > 0 {
+			t.codes[e.StatusCode]++
+		}
+		if e.Duration > 0 {
+			t.durations = append(t.durations, e.Duration)
+		}
+		for _, v := range e.Violations {
+			t.violations[configModel.ViolationRule(v.ValidationError)+": "+v.Message] = true
+		}
+	}
+	return traffic
+}
+
+// missing returns the violations that aren't in the other traffic (sorted), all of them if there is no other traffic.
+func missing(violations map[string]bool, other *operationTraffic) []string {
+	var found []string
+	for v := range violations {
+		if other == nil || !other.violations[v] {
+			found = append(found, v)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+func sameCodes(a, b map[int]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for code := range a {
+		if _, ok := b[code]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func latency(durations []time.Duration) Latency {
+	if len(durations) == 0 {
+		return Latency{}
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	ms := func(d time.Duration) float64 {
+		return math.Round(float64(d.Microseconds())/10) / 100
+	}
+	return Latency{
+		Median: ms(percentile(sorted, 50)),
+		P95:    ms(percentile(sorted, 95)),
+	}
+}
+
+// percentile picks the nearest rank of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
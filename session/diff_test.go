@@ -0,0 +1,61 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func exchange(method, url string, code int, duration time.Duration, messages ...string) *Exchange {
+	e := &Exchange{Method: method, URL: url, StatusCode: code, Duration: duration}
+	for _, m := range messages {
+		e.Violations = append(e.Violations, &shared.Violation{
+			ValidationError: &errors.ValidationError{ValidationType: "parameter", ValidationSubType: "query", Message: m},
+		})
+	}
+	return e
+}
+
+func TestCompare(t *testing.T) {
+	byURL := func(e *Exchange) string { return e.Method + " " + e.URL }
+	a := []*Exchange{
+		exchange("GET", "/pets", 200, 10*time.Millisecond, "limit is missing"),
+		exchange("GET", "/pets", 200, 12*time.Millisecond),
+		exchange("POST", "/pets", 201, 20*time.Millisecond),
+		exchange("GET", "/toys", 200, 10*time.Millisecond),
+	}
+	b := []*Exchange{
+		exchange("GET", "/pets", 200, 40*time.Millisecond),
+		exchange("GET", "/pets", 200, 42*time.Millisecond),
+		exchange("POST", "/pets", 500, 21*time.Millisecond, "body is invalid"),
+		exchange("GET", "/toys", 200, 13*time.Millisecond),
+	}
+
+	diff := Compare(a, b, byURL, 20)
+	assert.Len(t, diff.Operations, 3)
+	assert.Equal(t, 1, diff.NewViolations)
+	assert.Equal(t, 1, diff.ResolvedViolations)
+	assert.Equal(t, 1, diff.StatusChanges)
+	assert.Equal(t, 1, diff.LatencyRegressions)
+	assert.True(t, diff.Regressed())
+
+	pets, toys, post := diff.Operations[0], diff.Operations[1], diff.Operations[2]
+	assert.Equal(t, "GET /pets", pets.Operation)
+	assert.True(t, pets.LatencyRegression)
+	assert.Equal(t, 12.0, pets.LatencyA.P95)
+	assert.Equal(t, []string{"parameter/query: limit is missing"}, pets.ResolvedViolations)
+
+	assert.True(t, post.StatusChanged)
+	assert.Equal(t, []string{"parameter/query: body is invalid"}, post.NewViolations)
+
+	// 3ms slower is 30% slower, but below the noise floor.
+	assert.False(t, toys.LatencyRegression)
+	assert.False(t, Compare(a, a, byURL, 20).Regressed())
+}
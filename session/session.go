@@ -0,0 +1,200 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package session reads captured sessions (HAR files, NDJSON-HAR files and transaction stores) as a list of
+// exchanges, and compares sessions with each other.
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pb33f/harhar"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/har"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/store"
+	"github.com/pb33f/wiretap/validation"
+)
+
+// Exchange is a captured request, the response that came back, how long it took, and the violations found.
+type Exchange struct {
+	Method         string
+	URL            string
+	Header         http.Header
+	Body           []byte
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	Start          time.Time
+	Duration       time.Duration
+	Violations     []*shared.Violation
+}
+
+// Load reads the exchanges of a session from a HAR (or NDJSON-HAR) file, or a transaction store.
+func Load(filename string) ([]*Exchange, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if harFile, hErr := har.BuildHAR(data); hErr == nil && len(harFile.Log.Entries) > 0 {
+		return FromHAR(harFile), nil
+	}
+	if exchanges, ok := FromNDJSON(data); ok {
+		return exchanges, nil
+	}
+	s, err := store.OpenReadOnly(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	transactions, err := daemon.ReadTransactions(s)
+	if err != nil {
+		return nil, err
+	}
+	return FromTransactions(transactions), nil
+}
+
+// FromHAR reads the exchanges in a HAR file, HAR files don't have violations until they are validated.
+func FromHAR(harFile *harhar.HAR) []*Exchange {
+	var exchanges []*Exchange
+	for _, entry := range harFile.Log.Entries {
+		if entry.Request.Method == "" {
+			continue
+		}
+		e := &Exchange{
+			Method:         entry.Request.Method,
+			URL:            entry.Request.URL,
+			Header:         harHeaders(entry.Request.Headers),
+			Body:           []byte(entry.Request.Body.Content),
+			StatusCode:     entry.Response.StatusCode,
+			ResponseHeader: harHeaders(entry.Response.Headers),
+			ResponseBody:   []byte(entry.Response.Body.Content),
+			Duration:       time.Duration(entry.Time * float64(time.Millisecond)),
+		}
+		e.Start, _ = time.Parse(time.RFC3339Nano, entry.Start)
+		if entry.Response.Body.Encoding == "base64" {
+			e.ResponseBody, _ = base64.StdEncoding.DecodeString(entry.Response.Body.Content)
+		}
+		exchanges = append(exchanges, e)
+	}
+	return exchanges
+}
+
+// FromNDJSON reads the exchanges in an NDJSON-HAR file (a HAR entry per line), false is returned if the data
+// isn't NDJSON-HAR.
+func FromNDJSON(data []byte) ([]*Exchange, bool) {
+	harFile := &harhar.HAR{}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry harhar.Entry
+		if json.Unmarshal(line, &entry) != nil || entry.Request.Method == "" {
+			return nil, false
+		}
+		harFile.Log.Entries = append(harFile.Log.Entries, entry)
+	}
+	return FromHAR(harFile), len(harFile.Log.Entries) > 0
+}
+
+// FromTransactions reads the exchanges of transactions kept in a transaction store, with the violations found
+// when they were captured.
+func FromTransactions(transactions []*daemon.HttpTransaction) []*Exchange {
+	var exchanges []*Exchange
+	for _, transaction := range transactions {
+		if transaction.Request == nil || transaction.Request.Method == "" {
+			continue
+		}
+		captured := transaction.Request
+		e := &Exchange{
+			Method: captured.Method,
+			URL:    captured.URL,
+			Header: transactionHeaders(captured.Headers),
+			Body:   []byte(captured.Body),
+		}
+		if captured.Timestamp > 0 {
+			e.Start = time.UnixMilli(captured.Timestamp)
+		}
+		if e.URL == "" {
+			e.URL = captured.Path
+			if captured.Query != "" {
+				e.URL += "?" + captured.Query
+			}
+		}
+		e.Violations = append(e.Violations, transaction.RequestValidation...)
+		if response := transaction.Response; response != nil {
+			e.StatusCode = response.StatusCode
+			e.ResponseHeader = transactionHeaders(response.Headers)
+			e.ResponseBody = []byte(response.Body)
+			if response.Timestamp > 0 && captured.Timestamp > 0 && response.Timestamp >= captured.Timestamp {
+				e.Duration = time.Duration(response.Timestamp-captured.Timestamp) * time.Millisecond
+			}
+			e.Violations = append(e.Violations, transaction.ResponseValidation...)
+		}
+		exchanges = append(exchanges, e)
+	}
+	return exchanges
+}
+
+// Request rebuilds the captured request.
+func (e *Exchange) Request() (*http.Request, error) {
+	req, err := http.NewRequest(e.Method, e.URL, bytes.NewReader(e.Body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = e.Header.Clone()
+	return req, nil
+}
+
+// Validate replaces the violations of an exchange with the violations found by a validator.
+func (e *Exchange) Validate(validator validation.HttpValidator, severities map[string]string) {
+	req, err := e.Request()
+	if err != nil {
+		return
+	}
+	_, violations := validator.ValidateHttpRequest(req)
+	if e.StatusCode > 0 {
+		req.Body = io.NopCloser(bytes.NewReader(e.Body))
+		resp := &http.Response{StatusCode: e.StatusCode, Header: e.ResponseHeader.Clone(),
+			Body: io.NopCloser(bytes.NewReader(e.ResponseBody)), Request: req}
+		if resp.Header == nil {
+			resp.Header = make(http.Header)
+		}
+		_, responseViolations := validator.ValidateHttpResponse(req, resp)
+		for _, v := range responseViolations {
+			if !v.IsPathMissingError() {
+				violations = append(violations, v)
+			}
+		}
+	}
+	e.Violations = configModel.ClassifyViolations(violations, severities)
+}
+
+func harHeaders(pairs []harhar.NameValuePair) http.Header {
+	headers := make(http.Header)
+	for _, h := range pairs {
+		if !strings.HasPrefix(h.Name, ":") {
+			headers.Add(h.Name, h.Value)
+		}
+	}
+	return headers
+}
+
+func transactionHeaders(captured map[string]any) http.Header {
+	headers := make(http.Header)
+	for name, value := range captured {
+		if s, ok := value.(string); ok {
+			headers.Set(name, s)
+		}
+	}
+	return headers
+}
@@ -4,62 +4,193 @@
 package shared
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"embed"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"github.com/gobwas/glob"
 	"github.com/pb33f/harhar"
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/wiretap/asyncapi"
+	"github.com/pb33f/wiretap/audit"
+	"github.com/pb33f/wiretap/overlay"
+	"github.com/pb33f/wiretap/redact"
+	"github.com/vektah/gqlparser/v2/ast"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"log/slog"
+	"math/rand"
+	"net"
+	"net/url"
+	"os"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 )
 
 type WiretapConfiguration struct {
-	Contract            string                        `json:"-" yaml:"-"`
-	RedirectHost        string                        `json:"redirectHost,omitempty" yaml:"redirectHost,omitempty"`
-	RedirectPort        string                        `json:"redirectPort,omitempty" yaml:"redirectPort,omitempty"`
-	RedirectBasePath    string                        `json:"redirectBasePath,omitempty" yaml:"redirectBasePath,omitempty"`
-	RedirectProtocol    string                        `json:"redirectProtocol,omitempty" yaml:"redirectProtocol,omitempty"`
-	RedirectURL         string                        `json:"redirectURL,omitempty" yaml:"redirectURL,omitempty"`
-	Port                string                        `json:"port,omitempty" yaml:"port,omitempty"`
-	MonitorPort         string                        `json:"monitorPort,omitempty" yaml:"monitorPort,omitempty"`
-	WebSocketHost       string                        `json:"webSocketHost,omitempty" yaml:"webSocketHost,omitempty"`
-	WebSocketPort       string                        `json:"webSocketPort,omitempty" yaml:"webSocketPort,omitempty"`
-	GlobalAPIDelay      int                           `json:"globalAPIDelay,omitempty" yaml:"globalAPIDelay,omitempty"`
-	StaticDir           string                        `json:"staticDir,omitempty" yaml:"staticDir,omitempty"`
-	StaticIndex         string                        `json:"staticIndex,omitempty" yaml:"staticIndex,omitempty"`
-	PathConfigurations  map[string]*WiretapPathConfig `json:"paths,omitempty" yaml:"paths,omitempty"`
-	Headers             *WiretapHeaderConfig          `json:"headers,omitempty" yaml:"headers,omitempty"`
-	StaticPaths         []string                      `json:"staticPaths,omitempty" yaml:"staticPaths,omitempty"`
-	Variables           map[string]string             `json:"variables,omitempty" yaml:"variables,omitempty"`
-	Spec                string                        `json:"contract,omitempty" yaml:"contract,omitempty"`
-	Certificate         string                        `json:"certificate,omitempty" yaml:"certificate,omitempty"`
-	CertificateKey      string                        `json:"certificateKey,omitempty" yaml:"certificateKey,omitempty"`
-	HardErrors          bool                          `json:"hardValidation,omitempty" yaml:"hardValidation,omitempty"`
-	HardErrorCode       int                           `json:"hardValidationCode,omitempty" yaml:"hardValidationCode,omitempty"`
-	HardErrorReturnCode int                           `json:"hardValidationReturnCode,omitempty" yaml:"hardValidationReturnCode,omitempty"`
-	PathDelays          map[string]int                `json:"pathDelays,omitempty" yaml:"pathDelays,omitempty"`
-	MockMode            bool                          `json:"mockMode,omitempty" yaml:"mockMode,omitempty"`
-	MockModePretty      bool                          `json:"mockModePretty,omitempty" yaml:"mockModePretty,omitempty"`
-	Base                string                        `json:"base,omitempty" yaml:"base,omitempty"`
-	HAR                 string                        `json:"har,omitempty" yaml:"har,omitempty"`
-	HARValidate         bool                          `json:"harValidate,omitempty" yaml:"harValidate,omitempty"`
-	HARPathAllowList    []string                      `json:"harPathAllowList,omitempty" yaml:"harPathAllowList,omitempty"`
-	StreamReport        bool                          `json:"streamReport,omitempty" yaml:"streamReport,omitempty"`
-	ReportFile          string                        `json:"reportFilename,omitempty" yaml:"reportFilename,omitempty"`
-	HARFile             *harhar.HAR                   `json:"-" yaml:"-"`
-	CompiledPathDelays  map[string]*CompiledPathDelay `json:"-" yaml:"-"`
-	CompiledVariables   map[string]*CompiledVariable  `json:"-" yaml:"-"`
-	Version             string                        `json:"-" yaml:"-"`
-	StaticPathsCompiled []glob.Glob                   `json:"-" yaml:"-"`
-	CompiledPaths       map[string]*CompiledPath      `json:"-"`
-	FS                  embed.FS                      `json:"-"`
-	Logger              *slog.Logger
+	Contract              string                              `json:"-" yaml:"-"`
+	RedirectHost          string                              `json:"redirectHost,omitempty" yaml:"redirectHost,omitempty"`
+	RedirectPort          string                              `json:"redirectPort,omitempty" yaml:"redirectPort,omitempty"`
+	RedirectBasePath      string                              `json:"redirectBasePath,omitempty" yaml:"redirectBasePath,omitempty"`
+	RedirectProtocol      string                              `json:"redirectProtocol,omitempty" yaml:"redirectProtocol,omitempty"`
+	RedirectURL           string                              `json:"redirectURL,omitempty" yaml:"redirectURL,omitempty"`
+	UpstreamH2C           bool                                `json:"upstreamH2C,omitempty" yaml:"upstreamH2C,omitempty"`
+	UpstreamProxy         *WiretapUpstreamProxyConfig         `json:"upstreamProxy,omitempty" yaml:"upstreamProxy,omitempty"`
+	ClientCertificates    map[string]*WiretapClientCertConfig `json:"clientCertificates,omitempty" yaml:"clientCertificates,omitempty"`
+	BindAddress           string                              `json:"bindAddress,omitempty" yaml:"bindAddress,omitempty"`
+	Port                  string                              `json:"port,omitempty" yaml:"port,omitempty"`
+	MonitorPort           string                              `json:"monitorPort,omitempty" yaml:"monitorPort,omitempty"`
+	WebSocketHost         string                              `json:"webSocketHost,omitempty" yaml:"webSocketHost,omitempty"`
+	WebSocketPort         string                              `json:"webSocketPort,omitempty" yaml:"webSocketPort,omitempty"`
+	MetricsPort           string                              `json:"metricsPort,omitempty" yaml:"metricsPort,omitempty"`
+	AdminPort             string                              `json:"adminPort,omitempty" yaml:"adminPort,omitempty"`
+	AdminAddress          string                              `json:"adminAddress,omitempty" yaml:"adminAddress,omitempty"`
+	OTLPEndpoint          string                              `json:"otlpEndpoint,omitempty" yaml:"otlpEndpoint,omitempty"`
+	StatsDAddress         string                              `json:"statsdAddress,omitempty" yaml:"statsdAddress,omitempty"`
+	StatsDPrefix          string                              `json:"statsdPrefix,omitempty" yaml:"statsdPrefix,omitempty"`
+	StatsDTags            []string                            `json:"statsdTags,omitempty" yaml:"statsdTags,omitempty"`
+	StatsDFormat          string                              `json:"statsdFormat,omitempty" yaml:"statsdFormat,omitempty"`
+	LogFormat             string                              `json:"logFormat,omitempty" yaml:"logFormat,omitempty"`
+	LogLevel              string                              `json:"logLevel,omitempty" yaml:"logLevel,omitempty"`
+	GlobalAPIDelay        int                                 `json:"globalAPIDelay,omitempty" yaml:"globalAPIDelay,omitempty"`
+	StaticDir             string                              `json:"staticDir,omitempty" yaml:"staticDir,omitempty"`
+	StaticIndex           string                              `json:"staticIndex,omitempty" yaml:"staticIndex,omitempty"`
+	PathConfigurations    map[string]*WiretapPathConfig       `json:"paths,omitempty" yaml:"paths,omitempty"`
+	Headers               *WiretapHeaderConfig                `json:"headers,omitempty" yaml:"headers,omitempty"`
+	StaticPaths           []string                            `json:"staticPaths,omitempty" yaml:"staticPaths,omitempty"`
+	Variables             map[string]string                   `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Spec                  string                              `json:"contract,omitempty" yaml:"contract,omitempty"`
+	Certificate           string                              `json:"certificate,omitempty" yaml:"certificate,omitempty"`
+	CertificateKey        string                              `json:"certificateKey,omitempty" yaml:"certificateKey,omitempty"`
+	MonitorCertificate    string                              `json:"monitorCertificate,omitempty" yaml:"monitorCertificate,omitempty"`
+	MonitorCertificateKey string                              `json:"monitorCertificateKey,omitempty" yaml:"monitorCertificateKey,omitempty"`
+	HardErrors            bool                                `json:"hardValidation,omitempty" yaml:"hardValidation,omitempty"`
+	HardErrorCode         int                                 `json:"hardValidationCode,omitempty" yaml:"hardValidationCode,omitempty"`
+	HardErrorReturnCode   int                                 `json:"hardValidationReturnCode,omitempty" yaml:"hardValidationReturnCode,omitempty"`
+	StrictRequests        bool                                `json:"strictRequests,omitempty" yaml:"strictRequests,omitempty"`
+	StrictResponses       bool                                `json:"strictResponses,omitempty" yaml:"strictResponses,omitempty"`
+	StrictResponseCode    int                                 `json:"strictResponseCode,omitempty" yaml:"strictResponseCode,omitempty"`
+	Suppress              []string                            `json:"suppress,omitempty" yaml:"suppress,omitempty"`
+	Severity              map[string]string                   `json:"severity,omitempty" yaml:"severity,omitempty"`
+	ValidationMode        string                              `json:"validationMode,omitempty" yaml:"validationMode,omitempty"`
+	ValidationWorkers     int                                 `json:"validationWorkers,omitempty" yaml:"validationWorkers,omitempty"`
+	ValidationQueue       int                                 `json:"validationQueue,omitempty" yaml:"validationQueue,omitempty"`
+	ValidatorHooks        []string                            `json:"validatorHooks,omitempty" yaml:"validatorHooks,omitempty"`
+	NoValidationCache     bool                                `json:"noValidationCache,omitempty" yaml:"noValidationCache,omitempty"`
+	ValidationCacheSize   int                                 `json:"validationCacheSize,omitempty" yaml:"validationCacheSize,omitempty"`
+	PathDelays            map[string]int                      `json:"pathDelays,omitempty" yaml:"pathDelays,omitempty"`
+	MockLatency           map[string]*WiretapLatencyConfig    `json:"mockLatency,omitempty" yaml:"mockLatency,omitempty"`
+	MockMode              bool                                `json:"mockMode,omitempty" yaml:"mockMode,omitempty"`
+	MockPaths             map[string]bool                     `json:"mockPaths,omitempty" yaml:"mockPaths,omitempty"`
+	Intercept             []string                            `json:"intercept,omitempty" yaml:"intercept,omitempty"`
+	InterceptTimeout      int                                 `json:"interceptTimeout,omitempty" yaml:"interceptTimeout,omitempty"`
+	MockModePretty        bool                                `json:"mockModePretty,omitempty" yaml:"mockModePretty,omitempty"`
+	MockModeStateful      bool                                `json:"mockModeStateful,omitempty" yaml:"mockModeStateful,omitempty"`
+	MockErrorRate         float64                             `json:"mockErrorRate,omitempty" yaml:"mockErrorRate,omitempty"`
+	MockSeed              int64                               `json:"mockSeed,omitempty" yaml:"mockSeed,omitempty"`
+	MockSequences         map[string]*WiretapMockSequence     `json:"mockSequences,omitempty" yaml:"mockSequences,omitempty"`
+	MockOverrides         string                              `json:"mockOverrides,omitempty" yaml:"mockOverrides,omitempty"`
+	MockPagination        bool                                `json:"mockPagination,omitempty" yaml:"mockPagination,omitempty"`
+	MockPaginationTotal   int                                 `json:"mockPaginationTotal,omitempty" yaml:"mockPaginationTotal,omitempty"`
+	MockUnionStrategy     string                              `json:"mockUnionStrategy,omitempty" yaml:"mockUnionStrategy,omitempty"`
+	MockCallbacks         bool                                `json:"mockCallbacks,omitempty" yaml:"mockCallbacks,omitempty"`
+	MockCallbackDelay     int                                 `json:"mockCallbackDelay,omitempty" yaml:"mockCallbackDelay,omitempty"`
+	MockWebhooks          map[string]*WiretapMockWebhook      `json:"mockWebhooks,omitempty" yaml:"mockWebhooks,omitempty"`
+	MockFallback          bool                                `json:"mockFallback,omitempty" yaml:"mockFallback,omitempty"`
+	MockValidation        string                              `json:"mockValidation,omitempty" yaml:"mockValidation,omitempty"`
+	AsyncAPI              string                              `json:"asyncapi,omitempty" yaml:"asyncapi,omitempty"`
+	AsyncAPIInterval      int                                 `json:"asyncapiInterval,omitempty" yaml:"asyncapiInterval,omitempty"`
+	GraphQL               string                              `json:"graphql,omitempty" yaml:"graphql,omitempty"`
+	GraphQLPath           string                              `json:"graphqlPath,omitempty" yaml:"graphqlPath,omitempty"`
+	GraphQLDelays         map[string]int                      `json:"graphqlDelays,omitempty" yaml:"graphqlDelays,omitempty"`
+	GRPC                  string                              `json:"grpc,omitempty" yaml:"grpc,omitempty"`
+	GRPCValidate          bool                                `json:"grpcValidate,omitempty" yaml:"grpcValidate,omitempty"`
+	GRPCTranscode         bool                                `json:"grpcTranscode,omitempty" yaml:"grpcTranscode,omitempty"`
+	WatchSpec             bool                                `json:"watchSpec,omitempty" yaml:"watchSpec,omitempty"`
+	SpecPollInterval      int                                 `json:"specPollInterval,omitempty" yaml:"specPollInterval,omitempty"`
+	Overlays              []string                            `json:"overlays,omitempty" yaml:"overlays,omitempty"`
+	ServerVariables       map[string]string                   `json:"serverVariables,omitempty" yaml:"serverVariables,omitempty"`
+	Base                  string                              `json:"base,omitempty" yaml:"base,omitempty"`
+	HAR                   string                              `json:"har,omitempty" yaml:"har,omitempty"`
+	HARValidate           bool                                `json:"harValidate,omitempty" yaml:"harValidate,omitempty"`
+	HARPathAllowList      []string                            `json:"harPathAllowList,omitempty" yaml:"harPathAllowList,omitempty"`
+	HARPlayback           bool                                `json:"harPlayback,omitempty" yaml:"harPlayback,omitempty"`
+	HARRecord             string                              `json:"harRecord,omitempty" yaml:"harRecord,omitempty"`
+	HARRecordFormat       string                              `json:"harRecordFormat,omitempty" yaml:"harRecordFormat,omitempty"`
+	HARRecordMaxSize      int                                 `json:"harRecordMaxSize,omitempty" yaml:"harRecordMaxSize,omitempty"`
+	HARRecordRotate       int                                 `json:"harRecordRotate,omitempty" yaml:"harRecordRotate,omitempty"`
+	TransactionStore      string                              `json:"transactionStore,omitempty" yaml:"transactionStore,omitempty"`
+	AccessLog             string                              `json:"accessLog,omitempty" yaml:"accessLog,omitempty"`
+	AuditLog              string                              `json:"auditLog,omitempty" yaml:"auditLog,omitempty"`
+	CaptureSampleRate     float64                             `json:"captureSampleRate,omitempty" yaml:"captureSampleRate,omitempty"`
+	MaxTransactions       int                                 `json:"maxTransactions,omitempty" yaml:"maxTransactions,omitempty"`
+	MaxCaptureMemoryMB    int                                 `json:"maxCaptureMemoryMB,omitempty" yaml:"maxCaptureMemoryMB,omitempty"`
+	APIToken              string                              `json:"-" yaml:"apiToken,omitempty"`
+	MonitorAuth           *WiretapMonitorAuth                 `json:"-" yaml:"monitorAuth,omitempty"`
+	MonitorMaxBodyKB      int                                 `json:"monitorMaxBodyKB,omitempty" yaml:"monitorMaxBodyKB,omitempty"`
+	CapturePaused         bool                                `json:"capturePaused,omitempty" yaml:"capturePaused,omitempty"`
+	JUnitReport           bool                                `json:"junitReport,omitempty" yaml:"junitReport,omitempty"`
+	SARIFReport           bool                                `json:"sarifReport,omitempty" yaml:"sarifReport,omitempty"`
+	StreamReport          bool                                `json:"streamReport,omitempty" yaml:"streamReport,omitempty"`
+	ViolationWindow       int                                 `json:"violationWindow,omitempty" yaml:"violationWindow,omitempty"`
+	ReportRotation        string                              `json:"reportRotation,omitempty" yaml:"reportRotation,omitempty"`
+	ReportRetention       int                                 `json:"reportRetention,omitempty" yaml:"reportRetention,omitempty"`
+	ReportFile            string                              `json:"reportFilename,omitempty" yaml:"reportFilename,omitempty"`
+	ReportFilter          *WiretapReportFilter                `json:"reportFilter,omitempty" yaml:"reportFilter,omitempty"`
+	Tail                  bool                                `json:"tail,omitempty" yaml:"tail,omitempty"`
+	TailFilters           []string                            `json:"tailFilters,omitempty" yaml:"tailFilters,omitempty"`
+	CI                    bool                                `json:"ci,omitempty" yaml:"ci,omitempty"`
+	CICommand             string                              `json:"ciCommand,omitempty" yaml:"ciCommand,omitempty"`
+	CIThresholds          map[string]int                      `json:"ciThresholds,omitempty" yaml:"ciThresholds,omitempty"`
+	CISummaryFile         string                              `json:"ciSummaryFilename,omitempty" yaml:"ciSummaryFilename,omitempty"`
+	IssueTrackers         []*WiretapIssueTrackerConfig        `json:"issueTrackers,omitempty" yaml:"issueTrackers,omitempty"`
+	Notifications         []*WiretapNotificationConfig        `json:"notifications,omitempty" yaml:"notifications,omitempty"`
+	Redact                *WiretapRedactConfig                `json:"redact,omitempty" yaml:"redact,omitempty"`
+	Hosts                 map[string]*WiretapHostConfig       `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	Contracts             map[string]string                   `json:"contracts,omitempty" yaml:"contracts,omitempty"`
+	Candidate             string                              `json:"candidate,omitempty" yaml:"candidate,omitempty"`
+	StrictParameters      bool                                `json:"strictParameters,omitempty" yaml:"strictParameters,omitempty"`
+	AllowedHeaders        []string                            `json:"allowedHeaders,omitempty" yaml:"allowedHeaders,omitempty"`
+	WebhookPaths          map[string]string                   `json:"webhookPaths,omitempty" yaml:"webhookPaths,omitempty"`
+	ContractDocuments     map[string]libopenapi.Document      `json:"-" yaml:"-"`
+	CandidateDocument     libopenapi.Document                 `json:"-" yaml:"-"`
+	HARFile               *harhar.HAR                         `json:"-" yaml:"-"`
+	AsyncAPIDocument      *asyncapi.Document                  `json:"-" yaml:"-"`
+	OverlayDocuments      []*overlay.Overlay                  `json:"-" yaml:"-"`
+	Redactor              *redact.Redactor                    `json:"-" yaml:"-"`
+	Auditor               *audit.Log                          `json:"-" yaml:"-"`
+	GraphQLSchema         *ast.Schema                         `json:"-" yaml:"-"`
+	GRPCDescriptors       *protoregistry.Files                `json:"-" yaml:"-"`
+	CompiledPathDelays    map[string]*CompiledPathDelay       `json:"-" yaml:"-"`
+	CompiledMockLatency   map[string]*CompiledPathDelay       `json:"-" yaml:"-"`
+	CompiledMockPaths     map[string]*CompiledMockPath        `json:"-" yaml:"-"`
+	CompiledIntercepts    []*CompiledIntercept                `json:"-" yaml:"-"`
+	CompiledVariables     map[string]*CompiledVariable        `json:"-" yaml:"-"`
+	Version               string                              `json:"-" yaml:"-"`
+	StaticPathsCompiled   []glob.Glob                         `json:"-" yaml:"-"`
+	CompiledPaths         map[string]*CompiledPath            `json:"-"`
+	CompiledHosts         map[string]*CompiledHost            `json:"-" yaml:"-"`
+	FS                    embed.FS                            `json:"-"`
+	Logger                *slog.Logger
 }
 
+// compiledLock guards swapping compiled configuration, which controls do at runtime while requests are being
+// handled. Compiled maps are built on the side and swapped in whole, they are never changed once they are in use,
+// so readers can range over what the Get... methods return without holding the lock. The settings controls change
+// at runtime (delays, mock paths, intercept rules and pausing capture) are guarded by it too.
+var compiledLock sync.RWMutex
+
 func (wtc *WiretapConfiguration) CompilePaths() {
-	wtc.CompiledPaths = make(map[string]*CompiledPath)
+	compiled := make(map[string]*CompiledPath)
 	for x := range wtc.PathConfigurations {
-		wtc.CompiledPaths[x] = wtc.PathConfigurations[x].Compile(x)
+		compiled[x] = wtc.PathConfigurations[x].Compile(x)
 	}
+	compiledLock.Lock()
+	wtc.CompiledPaths = compiled
+	compiledLock.Unlock()
 	if len(wtc.StaticPaths) > 0 {
 		comp := make([]glob.Glob, len(wtc.StaticPaths))
 		for x, path := range wtc.StaticPaths {
@@ -69,46 +200,307 @@ func (wtc *WiretapConfiguration) CompilePaths() {
 	}
 }
 
+// CompileHosts compiles host patterns (e.g. *.api.example.com) and any path configurations bundled with them.
+// Host patterns use '.' as a separator, so '*' matches a single subdomain, and '**' matches any number of them.
+func (wtc *WiretapConfiguration) CompileHosts() {
+	compiled := make(map[string]*CompiledHost)
+	hostPaths := make(map[*WiretapHostConfig]map[string]*CompiledPath)
+	for k, v := range wtc.Hosts {
+		paths := make(map[string]*CompiledPath)
+		for x := range v.PathConfigurations {
+			paths[x] = v.PathConfigurations[x].Compile(x)
+		}
+		hostPaths[v] = paths
+		compiled[k] = &CompiledHost{
+			CompiledHost: glob.MustCompile(strings.ToLower(wtc.ReplaceWithVariables(k)), '.'),
+			HostConfig:   v,
+		}
+	}
+	compiledLock.Lock()
+	for host, paths := range hostPaths {
+		host.CompiledPaths = paths
+	}
+	wtc.CompiledHosts = compiled
+	compiledLock.Unlock()
+}
+
 func (wtc *WiretapConfiguration) CompilePathDelays() {
-	wtc.CompiledPathDelays = make(map[string]*CompiledPathDelay)
+	delays := make(map[string]*CompiledPathDelay)
 	for k, v := range wtc.PathDelays {
-		compiled := &CompiledPathDelay{
+		delays[k] = &CompiledPathDelay{
 			CompiledPathDelay: glob.MustCompile(wtc.ReplaceWithVariables(k)),
 			PathDelayValue:    v,
 		}
-		wtc.CompiledPathDelays[k] = compiled
 	}
+
+	// mock latency keys are path globs, optionally prefixed with a method (e.g. 'GET /pets/*').
+	latency := make(map[string]*CompiledPathDelay)
+	for k, v := range wtc.MockLatency {
+		method, path := "", k
+		if m, p, ok := strings.Cut(strings.TrimSpace(k), " "); ok {
+			method, path = strings.ToUpper(m), strings.TrimSpace(p)
+		}
+		latency[k] = &CompiledPathDelay{
+			CompiledPathDelay: glob.MustCompile(wtc.ReplaceWithVariables(path)),
+			PathDelayValue:    v.Delay,
+			Method:            method,
+			Latency:           v,
+		}
+	}
+	compiledLock.Lock()
+	wtc.CompiledPathDelays, wtc.CompiledMockLatency = delays, latency
+	compiledLock.Unlock()
+}
+
+// CompileMockPaths compiles the paths that are mocked (true) or proxied (false), whatever the mock mode is.
+func (wtc *WiretapConfiguration) CompileMockPaths() {
+	compiled := make(map[string]*CompiledMockPath)
+	for k, v := range wtc.MockPaths {
+		compiled[k] = &CompiledMockPath{CompiledMockPath: glob.MustCompile(wtc.ReplaceWithVariables(k)), Mock: v}
+	}
+	compiledLock.Lock()
+	wtc.CompiledMockPaths = compiled
+	compiledLock.Unlock()
+}
+
+// CompileIntercepts compiles the requests that are held until they are approved, path globs optionally prefixed with
+// a method (e.g. 'POST /pets/*').
+func (wtc *WiretapConfiguration) CompileIntercepts() {
+	compiled := make([]*CompiledIntercept, 0, len(wtc.Intercept))
+	for _, rule := range wtc.Intercept {
+		method, path := SplitInterceptRule(rule)
+		compiled = append(compiled, &CompiledIntercept{
+			Rule:         rule,
+			Method:       method,
+			CompiledPath: glob.MustCompile(wtc.ReplaceWithVariables(path)),
+		})
+	}
+	compiledLock.Lock()
+	wtc.CompiledIntercepts = compiled
+	compiledLock.Unlock()
+}
+
+// CheckInterceptRule returns an error if an intercept rule isn't a path glob, optionally after a method.
+func (wtc *WiretapConfiguration) CheckInterceptRule(rule string) error {
+	_, path := SplitInterceptRule(rule)
+	if _, err := glob.Compile(wtc.ReplaceWithVariables(path)); err != nil || path == "" {
+		return fmt.Errorf("intercept rule '%s' isn't a path glob, optionally after a method", rule)
+	}
+	return nil
+}
+
+// CheckMockErrorRates returns an error if the mock error rate, or that of any path, isn't a fraction between 0 and 1.
+func (wtc *WiretapConfiguration) CheckMockErrorRates() error {
+	if wtc.MockErrorRate < 0 || wtc.MockErrorRate > 1 {
+		return fmt.Errorf("the mock error rate has to be between 0 and 1, %g is not valid", wtc.MockErrorRate)
+	}
+	for path, pathConfig := range wtc.PathConfigurations {
+		if rate := pathConfig.MockErrorRate; rate != nil && (*rate < 0 || *rate > 1) {
+			return fmt.Errorf("the mock error rate of path '%s' has to be between 0 and 1, %g is not valid", path, *rate)
+		}
+	}
+	return nil
 }
 
 func (wtc *WiretapConfiguration) CompileVariables() {
-	wtc.CompiledVariables = make(map[string]*CompiledVariable)
+	compiled := make(map[string]*CompiledVariable)
 	for x := range wtc.Variables {
-		compiled := &CompiledVariable{
+		compiled[x] = &CompiledVariable{
 			CompiledVariable: regexp.MustCompile(fmt.Sprintf("\\${(%s)}", x)),
 			VariableValue:    wtc.Variables[x],
 		}
-		wtc.CompiledVariables[x] = compiled
 	}
+	compiledLock.Lock()
+	wtc.CompiledVariables = compiled
+	compiledLock.Unlock()
 }
 
 func (wtc *WiretapConfiguration) ReplaceWithVariables(input string) string {
-	for x := range wtc.Variables {
-		if wtc.Variables[x] != "" && wtc.CompiledVariables[x] != nil {
-			input = wtc.CompiledVariables[x].CompiledVariable.
-				ReplaceAllString(input, wtc.CompiledVariables[x].VariableValue)
+	for _, variable := range wtc.GetCompiledVariables() {
+		if variable.VariableValue != "" {
+			input = variable.CompiledVariable.ReplaceAllString(input, variable.VariableValue)
 		}
 	}
 	return input
 }
 
+// GetCompiledPaths returns the compiled path configurations, safe to use while they are being recompiled.
+func (wtc *WiretapConfiguration) GetCompiledPaths() map[string]*CompiledPath {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.CompiledPaths
+}
+
+// GetCompiledHosts returns the compiled host patterns, safe to use while they are being recompiled.
+func (wtc *WiretapConfiguration) GetCompiledHosts() map[string]*CompiledHost {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.CompiledHosts
+}
+
+// GetCompiledPathDelays returns the compiled path delays, safe to use while they are being recompiled.
+func (wtc *WiretapConfiguration) GetCompiledPathDelays() map[string]*CompiledPathDelay {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.CompiledPathDelays
+}
+
+// GetCompiledMockLatency returns the compiled mock latencies, safe to use while they are being recompiled.
+func (wtc *WiretapConfiguration) GetCompiledMockLatency() map[string]*CompiledPathDelay {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.CompiledMockLatency
+}
+
+// GetCompiledMockPaths returns the compiled mock paths, safe to use while they are being recompiled.
+func (wtc *WiretapConfiguration) GetCompiledMockPaths() map[string]*CompiledMockPath {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.CompiledMockPaths
+}
+
+// GetCompiledIntercepts returns the compiled intercept rules, safe to use while they are being recompiled.
+func (wtc *WiretapConfiguration) GetCompiledIntercepts() []*CompiledIntercept {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.CompiledIntercepts
+}
+
+// GetCompiledVariables returns the compiled variables, safe to use while they are being recompiled.
+func (wtc *WiretapConfiguration) GetCompiledVariables() map[string]*CompiledVariable {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.CompiledVariables
+}
+
+// GetGlobalAPIDelay returns the delay (in milliseconds) applied to every response, safe to use while it's changed.
+func (wtc *WiretapConfiguration) GetGlobalAPIDelay() int {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.GlobalAPIDelay
+}
+
+// SetGlobalAPIDelay changes the delay (in milliseconds) applied to every response, while requests are handled.
+func (wtc *WiretapConfiguration) SetGlobalAPIDelay(delay int) {
+	compiledLock.Lock()
+	wtc.GlobalAPIDelay = delay
+	compiledLock.Unlock()
+}
+
+// GetPathDelays returns the delays of paths, safe to use while they are changed. The map is never changed once it's
+// in use.
+func (wtc *WiretapConfiguration) GetPathDelays() map[string]int {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.PathDelays
+}
+
+// SetPathDelays replaces the delays of paths (and compiles them), while requests are handled.
+func (wtc *WiretapConfiguration) SetPathDelays(delays map[string]int) {
+	compiledLock.Lock()
+	wtc.PathDelays = delays
+	compiledLock.Unlock()
+	wtc.CompilePathDelays()
+}
+
+// GetMockPaths returns the paths that are mocked (true) or proxied (false), safe to use while they are changed. The
+// map is never changed once it's in use.
+func (wtc *WiretapConfiguration) GetMockPaths() map[string]bool {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.MockPaths
+}
+
+// SetMockPaths replaces the paths that are mocked or proxied (and compiles them), while requests are handled.
+func (wtc *WiretapConfiguration) SetMockPaths(paths map[string]bool) {
+	compiledLock.Lock()
+	wtc.MockPaths = paths
+	compiledLock.Unlock()
+	wtc.CompileMockPaths()
+}
+
+// GetIntercept returns the intercept rules and timeout (in seconds), safe to use while they are changed.
+func (wtc *WiretapConfiguration) GetIntercept() ([]string, int) {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.Intercept, wtc.InterceptTimeout
+}
+
+// SetIntercept replaces the intercept rules (and compiles them) and timeout, while requests are handled.
+func (wtc *WiretapConfiguration) SetIntercept(rules []string, timeout int) {
+	compiledLock.Lock()
+	wtc.Intercept, wtc.InterceptTimeout = rules, timeout
+	compiledLock.Unlock()
+	wtc.CompileIntercepts()
+}
+
+// IsCapturePaused checks if capturing transactions has been paused, safe to use while it's changed.
+func (wtc *WiretapConfiguration) IsCapturePaused() bool {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return wtc.CapturePaused
+}
+
+// SetCapturePaused pauses (or resumes) capturing transactions, while requests are handled.
+func (wtc *WiretapConfiguration) SetCapturePaused(paused bool) {
+	compiledLock.Lock()
+	wtc.CapturePaused = paused
+	compiledLock.Unlock()
+}
+
+// GetCompiledPaths returns the compiled path configurations of a host, safe to use while they are being recompiled.
+func (whc *WiretapHostConfig) GetCompiledPaths() map[string]*CompiledPath {
+	compiledLock.RLock()
+	defer compiledLock.RUnlock()
+	return whc.CompiledPaths
+}
+
+// MonitorCertificates are the certificate and key the monitor UI (and its websocket) are served with, the proxy's
+// unless the monitor has its own. Both are empty if the monitor isn't served over TLS.
+func (wtc *WiretapConfiguration) MonitorCertificates() (certificate, key string) {
+	if wtc.MonitorCertificate != "" && wtc.MonitorCertificateKey != "" {
+		return wtc.MonitorCertificate, wtc.MonitorCertificateKey
+	}
+	if wtc.Certificate != "" && wtc.CertificateKey != "" {
+		return wtc.Certificate, wtc.CertificateKey
+	}
+	return "", ""
+}
+
+// MonitorTLS checks if the monitor UI (and its websocket) are served over TLS.
+func (wtc *WiretapConfiguration) MonitorTLS() bool {
+	certificate, _ := wtc.MonitorCertificates()
+	return certificate != ""
+}
+
+// ListenAddress is the address to listen on a port at, every interface unless a bind address has been configured.
+func (wtc *WiretapConfiguration) ListenAddress(port string) string {
+	return net.JoinHostPort(wtc.BindAddress, port)
+}
+
+// LocalHost is the host wiretap's listeners can be reached at from the machine it's running on.
+func (wtc *WiretapConfiguration) LocalHost() string {
+	if ip := net.ParseIP(wtc.BindAddress); wtc.BindAddress == "" || (ip != nil && ip.IsUnspecified()) {
+		return "localhost"
+	}
+	return wtc.BindAddress
+}
+
 type WiretapPathConfig struct {
-	Target       string               `json:"target,omitempty" yaml:"target,omitempty"`
-	PathRewrite  map[string]string    `json:"pathRewrite,omitempty" yaml:"pathRewrite,omitempty"`
-	ChangeOrigin bool                 `json:"changeOrigin,omitempty" yaml:"changeOrigin,omitempty"`
-	Headers      *WiretapHeaderConfig `json:"headers,omitempty" yaml:"headers,omitempty"`
-	Secure       bool                 `json:"secure,omitempty" yaml:"secure,omitempty"`
-	Auth         string               `json:"auth,omitempty" yaml:"auth,omitempty"`
-	CompiledPath *CompiledPath        `json:"-"`
+	Target        string               `json:"target,omitempty" yaml:"target,omitempty"`
+	PathRewrite   map[string]string    `json:"pathRewrite,omitempty" yaml:"pathRewrite,omitempty"`
+	ChangeOrigin  bool                 `json:"changeOrigin,omitempty" yaml:"changeOrigin,omitempty"`
+	Headers       *WiretapHeaderConfig `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Secure        bool                 `json:"secure,omitempty" yaml:"secure,omitempty"`
+	Auth          string               `json:"auth,omitempty" yaml:"auth,omitempty"`
+	WebSocket     string               `json:"websocket,omitempty" yaml:"websocket,omitempty"`
+	Cache         *WiretapCacheConfig  `json:"cache,omitempty" yaml:"cache,omitempty"`
+	MockErrorRate *float64             `json:"mockErrorRate,omitempty" yaml:"mockErrorRate,omitempty"`
+	Validation    string               `json:"validation,omitempty" yaml:"validation,omitempty"`
+	Suppress      []string             `json:"suppress,omitempty" yaml:"suppress,omitempty"`
+	Spec          string               `json:"contract,omitempty" yaml:"contract,omitempty"`
+	Document      libopenapi.Document  `json:"-" yaml:"-"`
+	CompiledPath  *CompiledPath        `json:"-"`
 }
 
 type CompiledPath struct {
@@ -121,6 +513,73 @@ type CompiledPath struct {
 type CompiledPathDelay struct {
 	CompiledPathDelay glob.Glob
 	PathDelayValue    int
+	Method            string
+	Latency           *WiretapLatencyConfig
+}
+
+type CompiledMockPath struct {
+	CompiledMockPath glob.Glob
+	Mock             bool
+}
+
+type CompiledIntercept struct {
+	Rule         string
+	Method       string
+	CompiledPath glob.Glob
+}
+
+// SplitInterceptRule splits an intercept rule into the method (if it has one) and the path glob.
+func SplitInterceptRule(rule string) (method, path string) {
+	path = strings.TrimSpace(rule)
+	if m, p, ok := strings.Cut(path, " "); ok {
+		method, path = strings.ToUpper(m), strings.TrimSpace(p)
+	}
+	return method, path
+}
+
+// WiretapLatencyConfig describes how long a mock response takes, as a distribution of delays in milliseconds.
+// A fixed distribution always waits for Delay, uniform picks a delay between Min and Max, and normal picks a
+// delay around Delay (the mean) with a standard deviation of StdDev, kept between Min and Max if they are set.
+type WiretapLatencyConfig struct {
+	Distribution string `json:"distribution,omitempty" yaml:"distribution,omitempty"`
+	Delay        int    `json:"delay,omitempty" yaml:"delay,omitempty"`
+	Min          int    `json:"min,omitempty" yaml:"min,omitempty"`
+	Max          int    `json:"max,omitempty" yaml:"max,omitempty"`
+	StdDev       int    `json:"stdDev,omitempty" yaml:"stdDev,omitempty"`
+}
+
+// Delay returns the delay for a path, sampled from the latency distribution if there is one.
+func (cpd *CompiledPathDelay) Delay() int {
+	if cpd.Latency == nil {
+		return cpd.PathDelayValue
+	}
+	return cpd.Latency.Sample()
+}
+
+// Sample picks a delay (in milliseconds) from the distribution.
+func (wlc *WiretapLatencyConfig) Sample() int {
+	var delay int
+	switch strings.ToLower(wlc.Distribution) {
+	case LatencyUniform:
+		if wlc.Max <= wlc.Min {
+			return wlc.Min
+		}
+		return wlc.Min + rand.Intn(wlc.Max-wlc.Min+1)
+	case LatencyNormal:
+		delay = wlc.Delay + int(rand.NormFloat64()*float64(wlc.StdDev))
+	default:
+		delay = wlc.Delay
+	}
+	if delay < wlc.Min {
+		delay = wlc.Min
+	}
+	if wlc.Max > 0 && delay > wlc.Max {
+		delay = wlc.Max
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
 }
 
 type CompiledVariable struct {
@@ -135,6 +594,209 @@ type CompiledPathRewrite struct {
 	CompiledTarget glob.Glob
 }
 
+// WiretapHostConfig attaches a label, a specification and path configurations to a host pattern. Traffic sent
+// to a matching host (as a forward or transparent proxy) is sent on to that host, and labeled for the monitor.
+type WiretapHostConfig struct {
+	Label              string                        `json:"label,omitempty" yaml:"label,omitempty"`
+	Spec               string                        `json:"contract,omitempty" yaml:"contract,omitempty"`
+	Secure             bool                          `json:"secure,omitempty" yaml:"secure,omitempty"`
+	PathConfigurations map[string]*WiretapPathConfig `json:"paths,omitempty" yaml:"paths,omitempty"`
+	CompiledPaths      map[string]*CompiledPath      `json:"-" yaml:"-"`
+	Document           libopenapi.Document           `json:"-" yaml:"-"`
+}
+
+type CompiledHost struct {
+	CompiledHost glob.Glob
+	HostConfig   *WiretapHostConfig
+}
+
+// WiretapIssueTrackerConfig configures an issue tracker (GitHub Issues or Jira) that will have issues opened
+// (or updated) for every new group of violations.
+type WiretapIssueTrackerConfig struct {
+	Type           string   `json:"type,omitempty" yaml:"type,omitempty"`
+	URL            string   `json:"url,omitempty" yaml:"url,omitempty"`
+	Repository     string   `json:"repository,omitempty" yaml:"repository,omitempty"`
+	Project        string   `json:"project,omitempty" yaml:"project,omitempty"`
+	IssueType      string   `json:"issueType,omitempty" yaml:"issueType,omitempty"`
+	User           string   `json:"user,omitempty" yaml:"user,omitempty"`
+	Token          string   `json:"-" yaml:"token,omitempty"`
+	Labels         []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	UpdateInterval int      `json:"updateInterval,omitempty" yaml:"updateInterval,omitempty"`
+}
+
+// WiretapNotificationConfig configures a webhook that is sent a summary of violations (at or above a severity) as
+// they happen, at most once per interval (in seconds). The template is 'json' (the summary as it is), 'slack'
+// (for a Slack incoming webhook) or the path to a Go template file.
+type WiretapNotificationConfig struct {
+	URL      string            `json:"url,omitempty" yaml:"url,omitempty"`
+	Template string            `json:"template,omitempty" yaml:"template,omitempty"`
+	Severity string            `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Interval int               `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Headers  map[string]string `json:"-" yaml:"headers,omitempty"`
+}
+
+// WiretapRedactConfig masks secrets and personal data before transactions are shown in the monitor, recorded,
+// stored or reported. Headers are redacted by name, JSON bodies by JSONPath (e.g. $.user.password) and anything
+// else (headers, bodies, URLs and violations) by regular expression. Only the capture groups of a pattern are
+// masked, if it has any.
+type WiretapRedactConfig struct {
+	Headers  []string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Paths    []string `json:"paths,omitempty" yaml:"paths,omitempty"`
+	Patterns []string `json:"patterns,omitempty" yaml:"patterns,omitempty"`
+}
+
+// WiretapReportFilter narrows what reports include, so reports of big load tests aren't mostly healthy traffic.
+// ViolationsOnly leaves out transactions without violations, severities (error, warn, info) keep only the violations
+// (and transactions) of those severities, and paths (globs, e.g. /pets/*) keep only the requests to those paths.
+type WiretapReportFilter struct {
+	ViolationsOnly bool        `json:"violationsOnly,omitempty" yaml:"violationsOnly,omitempty"`
+	Severities     []string    `json:"severities,omitempty" yaml:"severities,omitempty"`
+	Paths          []string    `json:"paths,omitempty" yaml:"paths,omitempty"`
+	CompiledPaths  []glob.Glob `json:"-" yaml:"-"`
+}
+
+// Compile compiles the path globs of a report filter.
+func (rf *WiretapReportFilter) Compile() error {
+	rf.CompiledPaths = nil
+	for _, p := range rf.Paths {
+		g, err := glob.Compile(p)
+		if err != nil {
+			return fmt.Errorf("invalid report path '%s': %w", p, err)
+		}
+		rf.CompiledPaths = append(rf.CompiledPaths, g)
+	}
+	return nil
+}
+
+// MatchPath checks if requests to a path are reported, every path is if there are no path globs.
+func (rf *WiretapReportFilter) MatchPath(path string) bool {
+	if rf == nil || len(rf.CompiledPaths) == 0 {
+		return true
+	}
+	for _, g := range rf.CompiledPaths {
+		if g.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchSeverity checks if violations of a severity are reported, every severity is if none are listed.
+func (rf *WiretapReportFilter) MatchSeverity(severity string) bool {
+	if rf == nil || len(rf.Severities) == 0 {
+		return true
+	}
+	for _, s := range rf.Severities {
+		if strings.EqualFold(s, severity) {
+			return true
+		}
+	}
+	return false
+}
+
+// Empty checks if a report filter leaves everything in.
+func (rf *WiretapReportFilter) Empty() bool {
+	return rf == nil || (!rf.ViolationsOnly && len(rf.Severities) == 0 && len(rf.Paths) == 0)
+}
+
+// WiretapCacheConfig enables caching of upstream responses to GET requests for a path. Keys determine what makes
+// a request unique, they can be 'path', 'query' or 'header:<name>' (default is path and query).
+type WiretapCacheConfig struct {
+	TTL               string        `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Keys              []string      `json:"keys,omitempty" yaml:"keys,omitempty"`
+	HonorCacheControl bool          `json:"honorCacheControl,omitempty" yaml:"honorCacheControl,omitempty"`
+	CompiledTTL       time.Duration `json:"-" yaml:"-"`
+}
+
+// WiretapUpstreamProxyConfig is a forward proxy the target API is called through, the URL is http://, https://
+// or socks5://. Credentials can be part of the URL or set on their own. Hosts in NoProxy (the same form as the
+// NO_PROXY environment variable) are called directly.
+type WiretapUpstreamProxyConfig struct {
+	URL      string   `json:"url" yaml:"url"`
+	Username string   `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string   `json:"-" yaml:"password,omitempty"`
+	NoProxy  []string `json:"noProxy,omitempty" yaml:"noProxy,omitempty"`
+}
+
+// MarshalJSON writes the proxy with the password in its URL redacted, the configuration is sent to every monitor.
+func (upc WiretapUpstreamProxyConfig) MarshalJSON() ([]byte, error) {
+	type upstreamProxy WiretapUpstreamProxyConfig
+	redacted := upstreamProxy(upc)
+	redacted.URL = ""
+	if u, err := url.Parse(upc.URL); err == nil {
+		redacted.URL = u.Redacted()
+	}
+	return json.Marshal(redacted)
+}
+
+// ProxyURL parses the URL of the proxy, with the credentials set on their own added to it.
+func (upc *WiretapUpstreamProxyConfig) ProxyURL() (*url.URL, error) {
+	u, err := url.Parse(upc.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy '%s' is not an http://, https:// or socks5:// URL", upc.URL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy '%s' has no host", upc.URL)
+	}
+	if upc.Username != "" {
+		u.User = url.UserPassword(upc.Username, upc.Password)
+	}
+	return u, nil
+}
+
+// WiretapClientCertConfig is the client certificate presented to a target that requires mutual TLS. Targets are
+// keyed by host, or host and port. An encrypted key is decrypted using the passphrase held by the environment
+// variable named by PassphraseEnv.
+type WiretapClientCertConfig struct {
+	ClientCert    string `json:"clientCert" yaml:"clientCert"`
+	ClientKey     string `json:"clientKey" yaml:"clientKey"`
+	PassphraseEnv string `json:"passphraseEnv,omitempty" yaml:"passphraseEnv,omitempty"`
+}
+
+// LoadCertificate reads the certificate and key, decrypting the key if needed. Only keys encrypted the way
+// OpenSSL does it for PEM files (Proc-Type: 4,ENCRYPTED) can be decrypted.
+func (wcc *WiretapClientCertConfig) LoadCertificate() (tls.Certificate, error) {
+	certPEM, err := os.ReadFile(wcc.ClientCert)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := os.ReadFile(wcc.ClientKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("client key '%s' is not a PEM file", wcc.ClientKey)
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return tls.Certificate{}, fmt.Errorf("client key '%s' is an encrypted PKCS#8 key, which cannot be read; "+
+			"convert it with 'openssl rsa' or 'openssl ec' using a cipher like -aes256", wcc.ClientKey)
+	}
+	// legacy PEM encryption is insecure by modern standards, but it's what 'openssl rsa -aes256' still writes.
+	if x509.IsEncryptedPEMBlock(block) {
+		if wcc.PassphraseEnv == "" {
+			return tls.Certificate{}, fmt.Errorf("client key '%s' is encrypted, but there is no passphraseEnv "+
+				"to read its passphrase from", wcc.ClientKey)
+		}
+		passphrase, ok := os.LookupEnv(wcc.PassphraseEnv)
+		if !ok {
+			return tls.Certificate{}, fmt.Errorf("client key '%s' is encrypted, but %s is not set",
+				wcc.ClientKey, wcc.PassphraseEnv)
+		}
+		der, dErr := x509.DecryptPEMBlock(block, []byte(passphrase))
+		if dErr != nil {
+			return tls.Certificate{}, fmt.Errorf("client key '%s' cannot be decrypted: %s", wcc.ClientKey, dErr.Error())
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
 type WiretapHeaderConfig struct {
 	DropHeaders    []string          `json:"drop,omitempty" yaml:"drop,omitempty"`
 	InjectHeaders  map[string]string `json:"inject,omitempty" yaml:"inject,omitempty"`
@@ -152,11 +814,39 @@ func (wpc *WiretapPathConfig) Compile(key string) *CompiledPath {
 	for x := range wpc.PathRewrite {
 		cp.CompiledPathRewrite[x] = regexp.MustCompile(x)
 	}
+	if wpc.Cache != nil {
+		ttl, err := time.ParseDuration(wpc.Cache.TTL)
+		if err != nil || ttl <= 0 {
+			ttl = DefaultCacheTTL
+		}
+		wpc.Cache.CompiledTTL = ttl
+		if len(wpc.Cache.Keys) == 0 {
+			wpc.Cache.Keys = []string{CacheKeyPath, CacheKeyQuery}
+		}
+	}
 	return cp
 }
 
+// WiretapMockSequence is an ordered sequence of mock responses for an operation. Each step is a status code or the
+// name of an example, the same values accepted by the X-Wiretap-Example header.
+type WiretapMockSequence struct {
+	Responses []string `json:"responses,omitempty" yaml:"responses,omitempty"`
+	Scope     string   `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Loop      bool     `json:"loop,omitempty" yaml:"loop,omitempty"`
+}
+
+// WiretapMockWebhook configures a webhook (defined in the `webhooks` of an OpenAPI 3.1 specification) to be fired
+// when an operation is mocked. The trigger is the operationId, or the method and path (e.g. `POST /orders`), of the
+// operation that fires the webhook. The URL may contain runtime expressions, like a callback.
+type WiretapMockWebhook struct {
+	URL     string `json:"url,omitempty" yaml:"url,omitempty"`
+	Trigger string `json:"trigger,omitempty" yaml:"trigger,omitempty"`
+}
+
 const ConfigKey = "config"
 const HARKey = "har"
+const SpecStatusKey = "spec-status"
+const RetentionStatusKey = "retention-status"
 const WiretapHostPlaceholder = "%WIRETAP_HOST%"
 const WiretapPortPlaceholder = "%WIRETAP_PORT%"
 const WiretapTLSPlaceholder = "%WIRETAP_TLS%"
@@ -164,3 +854,71 @@ const WiretapVersionPlaceholder = "%WIRETAP_VERSION%"
 const IndexFile = "index.html"
 const UILocation = "ui/dist"
 const UIAssetsLocation = "ui/dist/assets"
+
+// WebSocket upgrade modes for a path configuration.
+const WebSocketAllow = "allow"
+const WebSocketDeny = "deny"
+const WebSocketProxy = "proxy"
+
+// Validation scopes for a path configuration, what is validated for traffic on the path.
+const ValidationBoth = "both"
+const ValidationRequest = "request"
+const ValidationResponse = "response"
+const ValidationNone = "none"
+
+// Issue tracker types.
+const IssueTrackerGitHub = "github"
+const IssueTrackerJira = "jira"
+
+// Mock sequence scopes, a sequence is shared by every client for the whole session, or kept per client.
+const MockSequenceScopeSession = "session"
+const MockSequenceScopeClient = "client"
+
+// Mock union strategies, how the variant of a oneOf / anyOf schema is picked when mocking.
+const MockUnionFirst = "first"
+const MockUnionRandom = "random"
+const MockUnionRotate = "rotate"
+
+// Mock validation modes, how requests that fail validation are handled in mock mode.
+const MockValidationReject = "reject"
+const MockValidationWarn = "warn"
+const MockValidationIgnore = "ignore"
+
+// DefaultAsyncAPIInterval is how often (in milliseconds) mocked AsyncAPI channels emit a message.
+const DefaultAsyncAPIInterval = 1000
+
+// Validation modes, inline validation holds up traffic until it's done, async validation runs in the background.
+const ValidationModeInline = "inline"
+const ValidationModeAsync = "async"
+
+// HAR recording formats, a HAR file (kept valid after every entry) or an entry per line.
+const HARFormatHAR = "har"
+const HARFormatNDJSON = "ndjson"
+
+// Report rotation schedules, the streamed report and recorded HAR file are rotated every hour or day.
+const RotateHourly = "hourly"
+const RotateDaily = "daily"
+
+// Log formats, text is for the terminal, JSON is one event per line for log shippers.
+const LogFormatText = "text"
+const LogFormatJSON = "json"
+
+// DefaultGraphQLPath is the path GraphQL requests are sent to, unless configured otherwise.
+const DefaultGraphQLPath = "/graphql"
+
+// DefaultSpecPollInterval is how often (in seconds) a remote specification is polled for changes.
+const DefaultSpecPollInterval = 60
+
+// DefaultCISummaryFile is where the outcome of a CI run is written, unless configured otherwise.
+const DefaultCISummaryFile = "wiretap-ci-summary.json"
+
+// Mock latency distributions.
+const LatencyFixed = "fixed"
+const LatencyUniform = "uniform"
+const LatencyNormal = "normal"
+
+// Response cache keys and defaults.
+const CacheKeyPath = "path"
+const CacheKeyQuery = "query"
+const CacheKeyHeaderPrefix = "header:"
+const DefaultCacheTTL = 30 * time.Second
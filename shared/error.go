@@ -3,7 +3,10 @@
 
 package shared
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // WiretapError is an rfc7807 compliant error struct
 type WiretapError struct {
@@ -33,3 +36,27 @@ func MarshalError(err *WiretapError) []byte {
 	b, _ := json.Marshal(err)
 	return b
 }
+
+// WiretapSpecStatus reports the health of the specification being served. When a reloaded specification cannot be
+// compiled, wiretap keeps serving the previous one and the compile errors are reported here until a reload succeeds.
+type WiretapSpecStatus struct {
+	Healthy  bool       `json:"healthy"`
+	Errors   []string   `json:"errors,omitempty"`
+	FailedAt *time.Time `json:"failedAt,omitempty"`
+}
+
+// reasons transactions are evicted from memory.
+const (
+	EvictedMaxTransactions  = "maxTransactions"
+	EvictedMaxCaptureMemory = "maxCaptureMemoryMB"
+)
+
+// WiretapRetentionStatus reports transactions being evicted from memory, when there are more than wiretap has been
+// configured to hold on to. The oldest are evicted first, so the history seen by the monitor has been truncated.
+type WiretapRetentionStatus struct {
+	Evicted       int       `json:"evicted"`
+	Retained      int       `json:"retained"`
+	RetainedBytes int       `json:"retainedBytes"`
+	Reason        string    `json:"reason,omitempty"`
+	EvictedAt     time.Time `json:"evictedAt"`
+}
@@ -0,0 +1,75 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package shared
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// MonitorSessionCookie is set once a browser has authenticated with the monitor. Cookies are shared by every port
+// on a host, so the session also authenticates the websocket the monitor connects to.
+const MonitorSessionCookie = "wiretap-monitor-session"
+
+// WiretapMonitorAuth protects the monitor (its endpoints and websocket) with a token, basic auth or both. None of it
+// is ever sent to the monitor.
+type WiretapMonitorAuth struct {
+	Token    string `json:"-" yaml:"token,omitempty"`
+	Username string `json:"-" yaml:"username,omitempty"`
+	Password string `json:"-" yaml:"password,omitempty"`
+}
+
+// Enabled checks if the monitor is protected at all.
+func (ma *WiretapMonitorAuth) Enabled() bool {
+	return ma != nil && (ma.Token != "" || ma.BasicAuth())
+}
+
+// BasicAuth checks if a username and password have been configured.
+func (ma *WiretapMonitorAuth) BasicAuth() bool {
+	return ma != nil && ma.Username != "" && ma.Password != ""
+}
+
+// Authorized checks if a request carries credentials for the monitor: the token (as a bearer token, or a 'token'
+// query parameter, for links and websocket clients that can't set headers), the username and password, or the
+// session cookie of a browser that has already authenticated. Everything is authorized if auth isn't enabled.
+func (ma *WiretapMonitorAuth) Authorized(r *http.Request) bool {
+	if !ma.Enabled() {
+		return true
+	}
+	if ma.Token != "" {
+		bearer, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		for _, candidate := range []string{bearer, r.URL.Query().Get("token")} {
+			if candidate != "" && equalSecret(candidate, ma.Token) {
+				return true
+			}
+		}
+	}
+	if ma.BasicAuth() {
+		if username, password, ok := r.BasicAuth(); ok &&
+			equalSecret(username, ma.Username) && equalSecret(password, ma.Password) {
+			return true
+		}
+	}
+	if cookie, err := r.Cookie(MonitorSessionCookie); err == nil {
+		return equalSecret(cookie.Value, ma.Session())
+	}
+	return false
+}
+
+// Session is the value of the session cookie, it's derived from the configured credentials so it changes with them,
+// and can't be turned back into them.
+func (ma *WiretapMonitorAuth) Session() string {
+	mac := hmac.New(sha256.New, []byte(ma.Token+"\x00"+ma.Username+"\x00"+ma.Password))
+	mac.Write([]byte(MonitorSessionCookie))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func equalSecret(candidate, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(candidate), []byte(secret)) == 1
+}
@@ -0,0 +1,27 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package shared
+
+import (
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+)
+
+// Violation severities, violations are errors unless configured otherwise.
+const SeverityError = "error"
+const SeverityWarn = "warn"
+const SeverityInfo = "info"
+
+// Violation is a validation error, classified by severity. It serializes exactly like the validation error,
+// with an added severity. Violations aggregated over a window also have the number of times they were seen,
+// and when they were first and last seen.
+type Violation struct {
+	*errors.ValidationError
+	Severity  string     `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Count     int        `json:"count,omitempty" yaml:"count,omitempty"`
+	FirstSeen *time.Time `json:"firstSeen,omitempty" yaml:"firstSeen,omitempty"`
+	LastSeen  *time.Time `json:"lastSeen,omitempty" yaml:"lastSeen,omitempty"`
+}
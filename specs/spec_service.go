@@ -8,6 +8,7 @@ import (
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
 	"github.com/pb33f/ranch/model"
 	"github.com/pb33f/ranch/service"
+	"sync"
 )
 
 const (
@@ -19,6 +20,7 @@ type SpecService struct {
 	document    libopenapi.Document
 	docModel    *v3.Document
 	serviceCore service.FabricServiceCore
+	lock        sync.RWMutex
 }
 
 func NewSpecService(document libopenapi.Document) *SpecService {
@@ -31,6 +33,17 @@ func NewSpecService(document libopenapi.Document) *SpecService {
 	return ss
 }
 
+// SetDocument replaces the current specification, used when the specification is reloaded.
+func (ss *SpecService) SetDocument(document libopenapi.Document) {
+	m, _ := document.BuildV3Model()
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	ss.document = document
+	if m != nil {
+		ss.docModel = &m.Model
+	}
+}
+
 func (ss *SpecService) HandleServiceRequest(request *model.Request, core service.FabricServiceCore) {
 	switch request.RequestCommand {
 	case GetCurrentSpecRequest:
@@ -41,6 +54,8 @@ func (ss *SpecService) HandleServiceRequest(request *model.Request, core service
 }
 
 func (ss *SpecService) handleGetCurrentSpec(request *model.Request, core service.FabricServiceCore) {
+	ss.lock.RLock()
+	defer ss.lock.RUnlock()
 	if ss.document != nil {
 		core.SendResponse(request, ss.document.GetSpecInfo().SpecBytes)
 	} else {
@@ -0,0 +1,284 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package store is an embedded database for captured transactions. Values are appended to a single file as JSON
+// lines, and only an index of where they are is kept in memory, so history can be much larger than RAM, and
+// survives wiretap being restarted. It has no dependencies (wiretap is a single static binary), and a store can be
+// read, or repaired, with any text editor.
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// record is a line of the store file, a value stored against an ID.
+type record struct {
+	ID    string          `json:"id"`
+	Value json.RawMessage `json:"value"`
+}
+
+// location is where the latest line for an ID is in the file.
+type location struct {
+	offset int64
+	length int
+}
+
+// Store keeps values against IDs, in the order the IDs were first stored. Storing an ID again replaces its value,
+// the new value is appended and the old one is left in the file until it's compacted.
+type Store struct {
+	filename string
+	file     *os.File
+	index    map[string]location
+	order    []string
+	size     int64
+	stale    int
+	readOnly bool
+	lock     sync.RWMutex
+}
+
+// Open opens the store in a file, creating it if it doesn't exist. Existing values are indexed, a line left
+// half-written (because wiretap was stopped mid-write) is cut off.
+func Open(filename string) (*Store, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{filename: filename, file: f, index: make(map[string]location)}
+	if err = s.load(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("cannot read store '%s': %w", filename, err)
+	}
+	return s, nil
+}
+
+// OpenReadOnly opens an existing store for reading, the file is never changed.
+func OpenReadOnly(filename string) (*Store, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{filename: filename, file: f, index: make(map[string]location), readOnly: true}
+	if err = s.load(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("cannot read store '%s': %w", filename, err)
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	reader := bufio.NewReader(s.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var r record
+		if json.Unmarshal(line, &r) == nil && r.ID != "" {
+			s.put(r.ID, location{offset: offset, length: len(line)})
+		}
+		offset += int64(len(line))
+	}
+	s.size = offset
+	if s.readOnly {
+		return nil
+	}
+	if err := s.file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := s.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+func (s *Store) put(id string, loc location) {
+	if _, ok := s.index[id]; ok {
+		s.stale++
+	} else {
+		s.order = append(s.order, id)
+	}
+	s.index[id] = loc
+}
+
+// Put stores a value (marshalled to JSON) against an ID, replacing any value already stored.
+func (s *Store) Put(id string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(&record{ID: id, Value: data})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return fmt.Errorf("store '%s' is closed", s.filename)
+	}
+	if s.readOnly {
+		return fmt.Errorf("store '%s' is read only", s.filename)
+	}
+	if _, err = s.file.Write(line); err != nil {
+		return err
+	}
+	s.put(id, location{offset: s.size, length: len(line)})
+	s.size += int64(len(line))
+	return nil
+}
+
+// Get reads the value stored against an ID into value, false is returned if nothing is stored against it.
+func (s *Store) Get(id string, value any) (bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	loc, ok := s.index[id]
+	if !ok {
+		return false, nil
+	}
+	data, err := s.read(loc)
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, value)
+}
+
+// Count is the number of IDs stored.
+func (s *Store) Count() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.order)
+}
+
+// Page returns up to limit values, skipping the first offset, in the order they were first stored.
+func (s *Store) Page(offset, limit int) ([]json.RawMessage, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(s.order) || limit <= 0 {
+		return nil, nil
+	}
+	end := min(offset+limit, len(s.order))
+	values := make([]json.RawMessage, 0, end-offset)
+	for _, id := range s.order[offset:end] {
+		data, err := s.read(s.index[id])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, data)
+	}
+	return values, nil
+}
+
+// Each calls fn with every value, in the order they were first stored. Iteration stops at the first error.
+func (s *Store) Each(fn func(id string, value json.RawMessage) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, id := range s.order {
+		data, err := s.read(s.index[id])
+		if err != nil {
+			return err
+		}
+		if err = fn(id, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) read(loc location) (json.RawMessage, error) {
+	if s.file == nil {
+		return nil, fmt.Errorf("store '%s' is closed", s.filename)
+	}
+	line := make([]byte, loc.length)
+	if _, err := s.file.ReadAt(line, loc.offset); err != nil {
+		return nil, err
+	}
+	var r record
+	if err := json.Unmarshal(bytes.TrimSpace(line), &r); err != nil {
+		return nil, err
+	}
+	return r.Value, nil
+}
+
+// Compact rewrites the file with only the latest value of every ID, if any values have been replaced. The file is
+// only replaced once the compacted copy has been written (and synced) in full, if anything goes wrong the store
+// carries on with the file it had.
+func (s *Store) Compact() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil || s.readOnly || s.stale == 0 {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.filename), ".wiretap-store-*")
+	if err != nil {
+		return err
+	}
+	compacted := false
+	defer func() {
+		if !compacted {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	_ = tmp.Chmod(0644)
+
+	index := make(map[string]location, len(s.index))
+	var offset int64
+	writer := bufio.NewWriter(tmp)
+	for _, id := range s.order {
+		loc := s.index[id]
+		line := make([]byte, loc.length)
+		if _, err = s.file.ReadAt(line, loc.offset); err != nil {
+			return err
+		}
+		if _, err = writer.Write(line); err != nil {
+			return err
+		}
+		index[id] = location{offset: offset, length: loc.length}
+		offset += int64(loc.length)
+	}
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), s.filename); err != nil {
+		return err
+	}
+
+	// the compacted copy is already open (for reading and writing), and at its end.
+	compacted = true
+	_ = s.file.Close()
+	s.file, s.index, s.size, s.stale = tmp, index, offset, 0
+	return nil
+}
+
+// Close compacts the store, and syncs and closes the file.
+func (s *Store) Close() error {
+	err := s.Compact()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file != nil {
+		if !s.readOnly {
+			err = errors.Join(err, s.file.Sync())
+		}
+		err = errors.Join(err, s.file.Close())
+		s.file = nil
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pet struct {
+	Name string `json:"name"`
+}
+
+func TestStore_PutGetPage(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "wiretap.db"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, name := range []string{"one", "two", "three"} {
+		require.NoError(t, s.Put(name, &pet{Name: name}))
+	}
+	require.NoError(t, s.Put("two", &pet{Name: "two again"}))
+
+	var p pet
+	found, err := s.Get("two", &p)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "two again", p.Name)
+
+	found, _ = s.Get("four", &p)
+	assert.False(t, found)
+
+	assert.Equal(t, 3, s.Count())
+	page, err := s.Page(1, 5)
+	require.NoError(t, err)
+	assert.Len(t, page, 2)
+	assert.JSONEq(t, `{"name":"two again"}`, string(page[0]))
+	assert.JSONEq(t, `{"name":"three"}`, string(page[1]))
+}
+
+func TestStore_SurvivesRestart(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "wiretap.db")
+	s, err := Open(filename)
+	require.NoError(t, err)
+	require.NoError(t, s.Put("one", &pet{Name: "one"}))
+	require.NoError(t, s.Put("one", &pet{Name: "one again"}))
+	require.NoError(t, s.Put("two", &pet{Name: "two"}))
+	require.NoError(t, s.Close())
+
+	// wiretap stopped half way through writing a line.
+	f, _ := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	_, _ = f.WriteString(`{"id":"three","val`)
+	_ = f.Close()
+
+	s, err = Open(filename)
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, 2, s.Count())
+
+	var p pet
+	_, _ = s.Get("one", &p)
+	assert.Equal(t, "one again", p.Name)
+
+	require.NoError(t, s.Put("three", &pet{Name: "three"}))
+	var names []string
+	require.NoError(t, s.Each(func(id string, _ json.RawMessage) error {
+		names = append(names, id)
+		return nil
+	}))
+	assert.Equal(t, []string{"one", "two", "three"}, names)
+}
+
+func TestStore_CompactFailure(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "wiretap.db")
+	s, err := Open(filename)
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Put("one", &pet{Name: "one"}))
+	require.NoError(t, s.Put("one", &pet{Name: "one again"}))
+
+	// the compacted copy can't replace the file (there's a directory in the way), the store carries on with the
+	// file it had.
+	s.filename = filepath.Join(dir, "in-the-way")
+	require.NoError(t, os.MkdirAll(filepath.Join(s.filename, "pets"), 0755))
+	assert.Error(t, s.Compact())
+	s.filename = filename
+
+	require.NoError(t, s.Put("two", &pet{Name: "two"}))
+	var p pet
+	found, err := s.Get("one", &p)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "one again", p.Name)
+	assert.Equal(t, 2, s.Count())
+
+	// nothing is left behind, and it can be compacted once it's possible again.
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 2)
+	require.NoError(t, s.Compact())
+	require.NoError(t, s.Put("three", &pet{Name: "three"}))
+	require.NoError(t, s.Close())
+
+	s, err = Open(filename)
+	require.NoError(t, err)
+	defer s.Close()
+	var names []string
+	require.NoError(t, s.Each(func(id string, _ json.RawMessage) error {
+		names = append(names, id)
+		return nil
+	}))
+	assert.Equal(t, []string{"one", "two", "three"}, names)
+	_, _ = s.Get("one", &p)
+	assert.Equal(t, "one again", p.Name)
+}
@@ -0,0 +1,638 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package swagger converts Swagger 2.0 documents into OpenAPI 3 documents, so services that only publish a
+// swagger.json can be validated and mocked like any other.
+package swagger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIVersion is the version of OpenAPI Swagger documents are converted to.
+const OpenAPIVersion = "3.0.3"
+
+// IsSwagger checks if a specification is a Swagger 2.0 document.
+func IsSwagger(specBytes []byte) bool {
+	var probe struct {
+		Swagger string `yaml:"swagger"`
+	}
+	if err := yaml.Unmarshal(specBytes, &probe); err != nil {
+		return false
+	}
+	return strings.HasPrefix(probe.Swagger, "2")
+}
+
+// converter holds what's needed from the whole document while operations are converted.
+type converter struct {
+	doc      *yaml.Node
+	consumes []string
+	produces []string
+}
+
+// Convert turns a Swagger 2.0 document into an OpenAPI 3 document (as YAML). The host, base path and schemes become
+// servers, definitions and the shared parameters, responses and security definitions move into components, body and
+// form parameters become request bodies (using the media types the operation consumes), and responses get content
+// for the media types the operation produces. References are rewritten to match. Paths stay in their original order.
+func Convert(specBytes []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(specBytes, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("swagger document is empty")
+	}
+	doc := root.Content[0]
+	if version := get(doc, "swagger"); version == nil || !strings.HasPrefix(version.Value, "2") {
+		return nil, fmt.Errorf("not a Swagger 2.0 document")
+	}
+	c := &converter{doc: doc, consumes: stringList(get(doc, "consumes")), produces: stringList(get(doc, "produces"))}
+
+	out := mapping()
+	set(out, "openapi", scalar(OpenAPIVersion))
+	for _, key := range []string{"info", "externalDocs", "tags", "security"} {
+		if v := get(doc, key); v != nil {
+			set(out, key, v)
+		}
+	}
+	copyExtensions(doc, out)
+	if servers := c.servers(); servers != nil {
+		set(out, "servers", servers)
+	}
+
+	paths := mapping()
+	if swaggerPaths := get(doc, "paths"); swaggerPaths != nil {
+		for i := 0; i+1 < len(swaggerPaths.Content); i += 2 {
+			path, item := swaggerPaths.Content[i], swaggerPaths.Content[i+1]
+			if strings.HasPrefix(path.Value, "x-") {
+				set(paths, path.Value, item)
+				continue
+			}
+			paths.Content = append(paths.Content, path, c.pathItem(item))
+		}
+	}
+	set(out, "paths", paths)
+
+	if components := c.components(); len(components.Content) > 0 {
+		set(out, "components", components)
+	}
+	rewriteRefs(out)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *converter) servers() *yaml.Node {
+	host, basePath := get(c.doc, "host"), get(c.doc, "basePath")
+	if host == nil && basePath == nil {
+		return nil
+	}
+	path := ""
+	if basePath != nil {
+		path = strings.TrimSuffix(basePath.Value, "/")
+	}
+	servers := sequence()
+	if host == nil {
+		if path == "" {
+			path = "/"
+		}
+		servers.Content = append(servers.Content, mappingOf("url", scalar(path)))
+		return servers
+	}
+	schemes := stringList(get(c.doc, "schemes"))
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	for _, scheme := range schemes {
+		servers.Content = append(servers.Content,
+			mappingOf("url", scalar(fmt.Sprintf("%s://%s%s", scheme, host.Value, path))))
+	}
+	return servers
+}
+
+func (c *converter) components() *yaml.Node {
+	components := mapping()
+	if definitions := get(c.doc, "definitions"); definitions != nil {
+		schemas := mapping()
+		for i := 0; i+1 < len(definitions.Content); i += 2 {
+			schemas.Content = append(schemas.Content, definitions.Content[i], convertSchema(definitions.Content[i+1]))
+		}
+		set(components, "schemas", schemas)
+	}
+	if parameters := get(c.doc, "parameters"); parameters != nil {
+		shared, bodies := mapping(), mapping()
+		for i := 0; i+1 < len(parameters.Content); i += 2 {
+			name, p := parameters.Content[i], parameters.Content[i+1]
+			switch value(p, "in") {
+			case "body":
+				bodies.Content = append(bodies.Content, name, bodyRequest(p, c.consumes))
+			case "formData":
+				// form parameters are folded into the request bodies of the operations that use them.
+			default:
+				shared.Content = append(shared.Content, name, convertParameter(p))
+			}
+		}
+		if len(shared.Content) > 0 {
+			set(components, "parameters", shared)
+		}
+		if len(bodies.Content) > 0 {
+			set(components, "requestBodies", bodies)
+		}
+	}
+	if responses := get(c.doc, "responses"); responses != nil {
+		converted := mapping()
+		for i := 0; i+1 < len(responses.Content); i += 2 {
+			converted.Content = append(converted.Content, responses.Content[i],
+				convertResponse(responses.Content[i+1], c.produces))
+		}
+		set(components, "responses", converted)
+	}
+	if definitions := get(c.doc, "securityDefinitions"); definitions != nil {
+		schemes := mapping()
+		for i := 0; i+1 < len(definitions.Content); i += 2 {
+			schemes.Content = append(schemes.Content, definitions.Content[i],
+				convertSecurityScheme(definitions.Content[i+1]))
+		}
+		set(components, "securitySchemes", schemes)
+	}
+	return components
+}
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+func (c *converter) pathItem(item *yaml.Node) *yaml.Node {
+	if ref := get(item, "$ref"); ref != nil {
+		return mappingOf("$ref", ref)
+	}
+	out := mapping()
+	copyExtensions(item, out)
+
+	// body and form parameters can't be shared by a path in OpenAPI 3, every operation gets them instead.
+	var inherited []*yaml.Node
+	if parameters := get(item, "parameters"); parameters != nil {
+		shared := sequence()
+		for _, p := range parameters.Content {
+			switch c.parameterKind(p) {
+			case "body", "formData":
+				inherited = append(inherited, p)
+			default:
+				shared.Content = append(shared.Content, c.parameter(p))
+			}
+		}
+		if len(shared.Content) > 0 {
+			set(out, "parameters", shared)
+		}
+	}
+	for _, method := range methods {
+		if op := get(item, method); op != nil {
+			set(out, method, c.operation(op, inherited))
+		}
+	}
+	return out
+}
+
+func (c *converter) operation(op *yaml.Node, inherited []*yaml.Node) *yaml.Node {
+	out := mapping()
+	for _, key := range []string{"tags", "summary", "description", "externalDocs", "operationId", "deprecated",
+		"security"} {
+		if v := get(op, key); v != nil {
+			set(out, key, v)
+		}
+	}
+	copyExtensions(op, out)
+
+	consumes, produces := c.consumes, c.produces
+	if v := get(op, "consumes"); v != nil {
+		consumes = stringList(v)
+	}
+	if v := get(op, "produces"); v != nil {
+		produces = stringList(v)
+	}
+
+	var requestBody *yaml.Node
+	var form []*yaml.Node
+	parameters := sequence()
+	for _, p := range append(append([]*yaml.Node(nil), inherited...), nodes(get(op, "parameters"))...) {
+		resolved := c.resolveParameter(p)
+		switch value(resolved, "in") {
+		case "body":
+			if ref := get(p, "$ref"); ref != nil {
+				requestBody = mappingOf("$ref", scalar(strings.Replace(ref.Value, "#/parameters/",
+					"#/components/requestBodies/", 1)))
+			} else {
+				requestBody = bodyRequest(p, consumes)
+			}
+		case "formData":
+			form = append(form, resolved)
+		default:
+			parameters.Content = append(parameters.Content, c.parameter(p))
+		}
+	}
+	if len(parameters.Content) > 0 {
+		set(out, "parameters", parameters)
+	}
+	if len(form) > 0 {
+		requestBody = formRequest(form, consumes)
+	}
+	if requestBody != nil {
+		set(out, "requestBody", requestBody)
+	}
+
+	if responses := get(op, "responses"); responses != nil {
+		converted := mapping()
+		for i := 0; i+1 < len(responses.Content); i += 2 {
+			code, response := responses.Content[i], responses.Content[i+1]
+			if strings.HasPrefix(code.Value, "x-") {
+				converted.Content = append(converted.Content, code, response)
+				continue
+			}
+			converted.Content = append(converted.Content, scalar(code.Value), convertResponse(response, produces))
+		}
+		set(out, "responses", converted)
+	}
+	return out
+}
+
+// resolveParameter follows a reference to a shared parameter, so its location is known.
+func (c *converter) resolveParameter(p *yaml.Node) *yaml.Node {
+	ref := get(p, "$ref")
+	if ref == nil || !strings.HasPrefix(ref.Value, "#/parameters/") {
+		return p
+	}
+	if shared := get(get(c.doc, "parameters"), strings.TrimPrefix(ref.Value, "#/parameters/")); shared != nil {
+		return shared
+	}
+	return p
+}
+
+func (c *converter) parameterKind(p *yaml.Node) string {
+	return value(c.resolveParameter(p), "in")
+}
+
+// parameter converts a parameter that isn't a body or form parameter, references are kept.
+func (c *converter) parameter(p *yaml.Node) *yaml.Node {
+	if ref := get(p, "$ref"); ref != nil {
+		return mappingOf("$ref", ref)
+	}
+	return convertParameter(p)
+}
+
+// schemaKeys are the keys of a Swagger parameter, header or items object that describe its schema.
+var schemaKeys = []string{"type", "format", "items", "default", "maximum", "exclusiveMaximum", "minimum",
+	"exclusiveMinimum", "maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems", "enum",
+	"multipleOf"}
+
+func convertParameter(p *yaml.Node) *yaml.Node {
+	out := mapping()
+	for _, key := range []string{"name", "in", "description", "required", "allowEmptyValue"} {
+		if v := get(p, key); v != nil {
+			set(out, key, v)
+		}
+	}
+	copyExtensions(p, out)
+	if value(p, "type") == "array" {
+		style, explode := collectionStyle(value(p, "in"), value(p, "collectionFormat"))
+		if style != "" {
+			set(out, "style", scalar(style))
+			set(out, "explode", boolean(explode))
+		}
+	}
+	set(out, "schema", parameterSchema(p))
+	return out
+}
+
+// collectionStyle maps the collection format of an array parameter onto a style, Swagger defaults to csv.
+func collectionStyle(in, format string) (string, bool) {
+	switch format {
+	case "", "csv":
+		if in == "query" || in == "cookie" {
+			return "form", false
+		}
+		return "simple", false
+	case "ssv":
+		return "spaceDelimited", false
+	case "pipes":
+		return "pipeDelimited", false
+	case "multi":
+		return "form", true
+	}
+	return "", false
+}
+
+// parameterSchema builds a schema from the schema keys of a parameter, header or items object.
+func parameterSchema(p *yaml.Node) *yaml.Node {
+	schema := mapping()
+	for _, key := range schemaKeys {
+		v := get(p, key)
+		if v == nil {
+			continue
+		}
+		switch {
+		case key == "items":
+			set(schema, key, parameterSchema(v))
+		case key == "type" && v.Value == "file":
+			set(schema, "type", scalar("string"))
+			set(schema, "format", scalar("binary"))
+		default:
+			set(schema, key, v)
+		}
+	}
+	return schema
+}
+
+func bodyRequest(p *yaml.Node, consumes []string) *yaml.Node {
+	out := mapping()
+	if v := get(p, "description"); v != nil {
+		set(out, "description", v)
+	}
+	content := mapping()
+	if len(consumes) == 0 {
+		consumes = []string{"application/json"}
+	}
+	for _, mediaType := range consumes {
+		mt := mapping()
+		if schema := get(p, "schema"); schema != nil {
+			set(mt, "schema", convertSchema(schema))
+		}
+		set(content, mediaType, mt)
+	}
+	set(out, "content", content)
+	if v := get(p, "required"); v != nil {
+		set(out, "required", v)
+	}
+	copyExtensions(p, out)
+	return out
+}
+
+func formRequest(form []*yaml.Node, consumes []string) *yaml.Node {
+	var mediaTypes []string
+	for _, mediaType := range consumes {
+		if mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded" {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	if len(mediaTypes) == 0 {
+		mediaTypes = []string{"application/x-www-form-urlencoded"}
+		for _, p := range form {
+			if value(p, "type") == "file" {
+				mediaTypes = []string{"multipart/form-data"}
+			}
+		}
+	}
+
+	schema := mappingOf("type", scalar("object"))
+	properties, required := mapping(), sequence()
+	for _, p := range form {
+		property := parameterSchema(p)
+		if v := get(p, "description"); v != nil {
+			set(property, "description", v)
+		}
+		set(properties, value(p, "name"), property)
+		if value(p, "required") == "true" {
+			required.Content = append(required.Content, scalar(value(p, "name")))
+		}
+	}
+	set(schema, "properties", properties)
+	if len(required.Content) > 0 {
+		set(schema, "required", required)
+	}
+
+	content := mapping()
+	for _, mediaType := range mediaTypes {
+		set(content, mediaType, mappingOf("schema", schema))
+	}
+	out := mappingOf("content", content)
+	if len(required.Content) > 0 {
+		set(out, "required", boolean(true))
+	}
+	return out
+}
+
+func convertResponse(response *yaml.Node, produces []string) *yaml.Node {
+	if ref := get(response, "$ref"); ref != nil {
+		return mappingOf("$ref", ref)
+	}
+	out := mapping()
+	description := get(response, "description")
+	if description == nil {
+		description = scalar("")
+	}
+	set(out, "description", description)
+	if headers := get(response, "headers"); headers != nil {
+		converted := mapping()
+		for i := 0; i+1 < len(headers.Content); i += 2 {
+			header := mapping()
+			if v := get(headers.Content[i+1], "description"); v != nil {
+				set(header, "description", v)
+			}
+			set(header, "schema", parameterSchema(headers.Content[i+1]))
+			converted.Content = append(converted.Content, headers.Content[i], header)
+		}
+		set(out, "headers", converted)
+	}
+
+	schema, examples := get(response, "schema"), get(response, "examples")
+	if schema != nil || examples != nil {
+		if len(produces) == 0 {
+			produces = []string{"application/json"}
+		}
+		content := mapping()
+		for _, mediaType := range produces {
+			mt := mapping()
+			if schema != nil {
+				set(mt, "schema", convertSchema(schema))
+			}
+			if example := get(examples, mediaType); example != nil {
+				set(mt, "example", example)
+			}
+			set(content, mediaType, mt)
+		}
+		// examples for media types the operation doesn't declare are kept too.
+		for i := 0; examples != nil && i+1 < len(examples.Content); i += 2 {
+			if get(content, examples.Content[i].Value) == nil {
+				mt := mappingOf("example", examples.Content[i+1])
+				if schema != nil {
+					set(mt, "schema", convertSchema(schema))
+				}
+				set(content, examples.Content[i].Value, mt)
+			}
+		}
+		set(out, "content", content)
+	}
+	copyExtensions(response, out)
+	return out
+}
+
+func convertSecurityScheme(scheme *yaml.Node) *yaml.Node {
+	out := mapping()
+	switch value(scheme, "type") {
+	case "basic":
+		set(out, "type", scalar("http"))
+		set(out, "scheme", scalar("basic"))
+	case "apiKey":
+		set(out, "type", scalar("apiKey"))
+		set(out, "name", scalar(value(scheme, "name")))
+		set(out, "in", scalar(value(scheme, "in")))
+	case "oauth2":
+		set(out, "type", scalar("oauth2"))
+		flow := mapping()
+		if v := get(scheme, "authorizationUrl"); v != nil {
+			set(flow, "authorizationUrl", v)
+		}
+		if v := get(scheme, "tokenUrl"); v != nil {
+			set(flow, "tokenUrl", v)
+		}
+		scopes := get(scheme, "scopes")
+		if scopes == nil {
+			scopes = mapping()
+		}
+		set(flow, "scopes", scopes)
+		name := map[string]string{"implicit": "implicit", "password": "password",
+			"application": "clientCredentials", "accessCode": "authorizationCode"}[value(scheme, "flow")]
+		if name == "" {
+			name = "implicit"
+		}
+		set(out, "flows", mappingOf(name, flow))
+	default:
+		return scheme
+	}
+	if v := get(scheme, "description"); v != nil {
+		set(out, "description", v)
+	}
+	copyExtensions(scheme, out)
+	return out
+}
+
+// convertSchema copies a schema, replacing what OpenAPI 3 does differently: file types, x-nullable and
+// discriminators that are only a property name.
+func convertSchema(schema *yaml.Node) *yaml.Node {
+	if schema == nil {
+		return nil
+	}
+	out := *schema
+	out.Content = make([]*yaml.Node, 0, len(schema.Content))
+	if schema.Kind != yaml.MappingNode {
+		for _, child := range schema.Content {
+			out.Content = append(out.Content, convertSchema(child))
+		}
+		return &out
+	}
+	for i := 0; i+1 < len(schema.Content); i += 2 {
+		key, v := schema.Content[i], schema.Content[i+1]
+		switch {
+		case key.Value == "type" && v.Value == "file":
+			out.Content = append(out.Content, key, scalar("string"), scalar("format"), scalar("binary"))
+		case key.Value == "x-nullable":
+			out.Content = append(out.Content, scalar("nullable"), v)
+		case key.Value == "discriminator" && v.Kind == yaml.ScalarNode:
+			out.Content = append(out.Content, key, mappingOf("propertyName", v))
+		default:
+			out.Content = append(out.Content, key, convertSchema(v))
+		}
+	}
+	return &out
+}
+
+// rewriteRefs points local references at the components they moved to.
+func rewriteRefs(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "$ref" && node.Content[i+1].Kind == yaml.ScalarNode {
+				ref := node.Content[i+1]
+				for from, to := range map[string]string{"#/definitions/": "#/components/schemas/",
+					"#/parameters/": "#/components/parameters/", "#/responses/": "#/components/responses/"} {
+					if strings.HasPrefix(ref.Value, from) {
+						node.Content[i+1] = scalar(to + strings.TrimPrefix(ref.Value, from))
+					}
+				}
+			}
+		}
+	}
+	for _, child := range node.Content {
+		rewriteRefs(child)
+	}
+}
+
+func copyExtensions(from, to *yaml.Node) {
+	for i := 0; i+1 < len(from.Content); i += 2 {
+		if strings.HasPrefix(from.Content[i].Value, "x-") {
+			set(to, from.Content[i].Value, from.Content[i+1])
+		}
+	}
+}
+
+func get(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func value(node *yaml.Node, key string) string {
+	if v := get(node, key); v != nil {
+		return v.Value
+	}
+	return ""
+}
+
+func set(node *yaml.Node, key string, v *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = v
+			return
+		}
+	}
+	node.Content = append(node.Content, scalar(key), v)
+}
+
+func nodes(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+func stringList(node *yaml.Node) []string {
+	var values []string
+	for _, n := range nodes(node) {
+		values = append(values, n.Value)
+	}
+	return values
+}
+
+func mapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func mappingOf(key string, v *yaml.Node) *yaml.Node {
+	m := mapping()
+	set(m, key, v)
+	return m
+}
+
+func sequence() *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+}
+
+func scalar(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+}
+
+func boolean(b bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(b)}
+}
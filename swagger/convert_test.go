@@ -0,0 +1,131 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package swagger
+
+import (
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var petstore = `{
+  "swagger": "2.0",
+  "info": {"title": "Petstore", "version": "1.0"},
+  "host": "pets.example.com",
+  "basePath": "/v1",
+  "schemes": ["https"],
+  "consumes": ["application/json"],
+  "produces": ["application/json"],
+  "paths": {
+    "/pets": {
+      "get": {
+        "operationId": "listPets",
+        "parameters": [
+          {"name": "tags", "in": "query", "type": "array", "items": {"type": "string"}, "collectionFormat": "multi"},
+          {"$ref": "#/parameters/limit"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Pets",
+            "headers": {"X-Total": {"type": "integer"}},
+            "schema": {"type": "array", "items": {"$ref": "#/definitions/Pet"}},
+            "examples": {"application/json": [{"name": "fluffy"}]}
+          },
+          "default": {"$ref": "#/responses/Error"}
+        }
+      },
+      "post": {
+        "parameters": [{"name": "pet", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Pet"}}],
+        "responses": {"201": {"description": "Created"}}
+      }
+    },
+    "/pets/{id}/photo": {
+      "parameters": [{"name": "id", "in": "path", "required": true, "type": "string"}],
+      "put": {
+        "consumes": ["multipart/form-data"],
+        "parameters": [
+          {"name": "photo", "in": "formData", "type": "file", "required": true},
+          {"name": "caption", "in": "formData", "type": "string"}
+        ],
+        "responses": {"204": {"description": "Uploaded"}}
+      }
+    }
+  },
+  "definitions": {
+    "Pet": {
+      "type": "object",
+      "required": ["name"],
+      "discriminator": "kind",
+      "properties": {
+        "name": {"type": "string"},
+        "kind": {"type": "string"},
+        "owner": {"type": "string", "x-nullable": true}
+      }
+    },
+    "Error": {"type": "object", "properties": {"message": {"type": "string"}}}
+  },
+  "parameters": {"limit": {"name": "limit", "in": "query", "type": "integer", "maximum": 100}},
+  "responses": {"Error": {"description": "Error", "schema": {"$ref": "#/definitions/Error"}}},
+  "securityDefinitions": {
+    "oauth": {"type": "oauth2", "flow": "accessCode", "authorizationUrl": "https://auth.example.com/authorize",
+      "tokenUrl": "https://auth.example.com/token", "scopes": {"read": "Read pets"}},
+    "basic": {"type": "basic"}
+  }
+}`
+
+func TestConvert(t *testing.T) {
+	assert.True(t, IsSwagger([]byte(petstore)))
+
+	converted, err := Convert([]byte(petstore))
+	assert.NoError(t, err)
+	assert.False(t, IsSwagger(converted))
+
+	doc, err := libopenapi.NewDocument(converted)
+	assert.NoError(t, err)
+	m, errs := doc.BuildV3Model()
+	assert.Empty(t, errs)
+	model := m.Model
+
+	assert.Equal(t, OpenAPIVersion, model.Version)
+	assert.Equal(t, "https://pets.example.com/v1", model.Servers[0].URL)
+
+	pets := model.Paths.PathItems.GetOrZero("/pets")
+	list := pets.Get
+	assert.Equal(t, "listPets", list.OperationId)
+	assert.Equal(t, "form", list.Parameters[0].Style)
+	assert.True(t, *list.Parameters[0].Explode)
+	assert.Equal(t, "limit", list.Parameters[1].Name)
+	assert.Equal(t, []string{"integer"}, list.Parameters[1].Schema.Schema().Type)
+
+	ok := list.Responses.Codes.GetOrZero("200")
+	assert.Equal(t, []string{"integer"}, ok.Headers.GetOrZero("X-Total").Schema.Schema().Type)
+	listed := ok.Content.GetOrZero("application/json")
+	assert.Equal(t, []string{"name"}, listed.Schema.Schema().Items.A.Schema().Required)
+	assert.NotNil(t, listed.Example)
+	assert.Equal(t, "Error", list.Responses.Default.Description)
+
+	create := pets.Post.RequestBody
+	assert.True(t, *create.Required)
+	pet := create.Content.GetOrZero("application/json").Schema.Schema()
+	assert.Equal(t, "kind", pet.Discriminator.PropertyName)
+	assert.True(t, *pet.Properties.GetOrZero("owner").Schema().Nullable)
+
+	photo := model.Paths.PathItems.GetOrZero("/pets/{id}/photo")
+	assert.Equal(t, "id", photo.Parameters[0].Name)
+	form := photo.Put.RequestBody.Content.GetOrZero("multipart/form-data").Schema.Schema()
+	assert.Equal(t, []string{"photo"}, form.Required)
+	assert.Equal(t, "binary", form.Properties.GetOrZero("photo").Schema().Format)
+
+	oauth := model.Components.SecuritySchemes.GetOrZero("oauth")
+	assert.Equal(t, "https://auth.example.com/token", oauth.Flows.AuthorizationCode.TokenUrl)
+	assert.Equal(t, "http", model.Components.SecuritySchemes.GetOrZero("basic").Type)
+}
+
+func TestConvert_NotSwagger(t *testing.T) {
+	_, err := Convert([]byte("openapi: 3.1.0"))
+	assert.Error(t, err)
+	assert.False(t, IsSwagger([]byte("openapi: 3.1.0")))
+}
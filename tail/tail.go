@@ -0,0 +1,255 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package tail prints a line per transaction to the terminal as it happens, for anyone who never opens the monitor.
+// Lines can be filtered by expressions, e.g. 'status >= 400', 'path ~ /pets/*' or 'violations > 0'.
+package tail
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pterm/pterm"
+)
+
+// Entry is what tail prints about a transaction.
+type Entry struct {
+	Time       time.Time
+	Method     string
+	Path       string
+	Status     int
+	Latency    time.Duration
+	Violations int
+}
+
+// FromTransaction reads an entry from a completed transaction, latency is how long the upstream API took (or the
+// whole round trip, if it didn't respond).
+func FromTransaction(transaction *daemon.HttpTransaction) *Entry {
+	e := &Entry{Time: time.Now()}
+	if req := transaction.Request; req != nil {
+		e.Method = req.Method
+		e.Path = req.Path
+		if req.Query != "" {
+			e.Path += "?" + req.Query
+		}
+		if req.Timestamp > 0 {
+			e.Time = time.UnixMilli(req.Timestamp)
+		}
+	}
+	if resp := transaction.Response; resp != nil {
+		e.Status = resp.StatusCode
+		e.Latency = time.Duration(resp.Latency * float64(time.Millisecond))
+		if e.Latency == 0 && transaction.Request != nil && resp.Timestamp >= transaction.Request.Timestamp {
+			e.Latency = time.Duration(resp.Timestamp-transaction.Request.Timestamp) * time.Millisecond
+		}
+	}
+	e.Violations = len(transaction.RequestValidation) + len(transaction.ResponseValidation)
+	return e
+}
+
+// Format renders an entry as a single colored line, e.g. '12:01:02  GET     200    12ms  /pets/1  2 violations'.
+func Format(e *Entry) string {
+	status := fmt.Sprintf("%-5d", e.Status)
+	switch {
+	case e.Status >= 500:
+		status = pterm.LightRed(status)
+	case e.Status >= 400:
+		status = pterm.LightYellow(status)
+	case e.Status >= 300:
+		status = pterm.LightCyan(status)
+	default:
+		status = pterm.LightGreen(status)
+	}
+	violations := pterm.Gray("✓")
+	if e.Violations == 1 {
+		violations = pterm.LightRed("1 violation")
+	} else if e.Violations > 1 {
+		violations = pterm.LightRed(fmt.Sprintf("%d violations", e.Violations))
+	}
+	return fmt.Sprintf("%s  %s %s %s  %s  %s", pterm.Gray(e.Time.Format("15:04:05")),
+		pterm.LightMagenta(fmt.Sprintf("%-7s", e.Method)), status,
+		fmt.Sprintf("%7s", formatLatency(e.Latency)), e.Path, violations)
+}
+
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	case d < 10*time.Millisecond:
+		return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+	default:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+}
+
+// Filter selects the entries tail prints, every condition has to match.
+type Filter struct {
+	conditions []*condition
+}
+
+type condition struct {
+	field    string
+	operator string
+	value    string
+	number   float64
+	glob     glob.Glob
+}
+
+var conditionPattern = regexp.MustCompile(`^\s*(\w+)\s*(==|!=|>=|<=|=|>|<|~)\s*(.*?)\s*$`)
+
+var conjunction = regexp.MustCompile(`\s*(?:&&|\band\b)\s*`)
+
+// fields that can be filtered on, numbers can be compared, method and path can be matched with globs (~).
+var numericFields = map[string]bool{"status": true, "latency": true, "violations": true}
+
+// ParseFilter parses filter expressions, an expression is one or more conditions joined by '&&' (or 'and'), e.g.
+// 'method = POST && status >= 400'. Fields are method, path, status, latency (milliseconds, or a duration such as
+// 1.5s) and violations. Operators are =, !=, >, >=, <, <= and ~ (glob match, e.g. path ~ /pets/*). A status can be
+// a class, e.g. status = 5xx.
+func ParseFilter(expressions ...string) (*Filter, error) {
+	f := &Filter{}
+	for _, expression := range expressions {
+		for _, part := range conjunction.Split(expression, -1) {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			c, err := parseCondition(part)
+			if err != nil {
+				return nil, err
+			}
+			f.conditions = append(f.conditions, c)
+		}
+	}
+	return f, nil
+}
+
+func parseCondition(expression string) (*condition, error) {
+	m := conditionPattern.FindStringSubmatch(expression)
+	if m == nil {
+		return nil, fmt.Errorf("cannot understand filter '%s', try something like 'status >= 400'", expression)
+	}
+	c := &condition{field: strings.ToLower(m[1]), operator: m[2], value: strings.Trim(m[3], `"'`)}
+	if c.operator == "==" {
+		c.operator = "="
+	}
+	switch {
+	case c.field == "method" || c.field == "path":
+		if c.field == "method" {
+			c.value = strings.ToUpper(c.value)
+		}
+		if c.operator != "=" && c.operator != "!=" && c.operator != "~" {
+			return nil, fmt.Errorf("%s can only be matched with =, != or ~", c.field)
+		}
+		if c.operator == "~" {
+			g, err := glob.Compile(c.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid glob '%s': %w", c.value, err)
+			}
+			c.glob = g
+		}
+	case numericFields[c.field]:
+		if c.operator == "~" {
+			return nil, fmt.Errorf("%s can only be compared with =, !=, >, >=, < or <=", c.field)
+		}
+		if c.field == "status" && isStatusClass(c.value) {
+			if c.operator != "=" && c.operator != "!=" {
+				return nil, fmt.Errorf("a status class (%s) can only be matched with = or !=", c.value)
+			}
+			return c, nil
+		}
+		n, err := parseNumber(c.field, c.value)
+		if err != nil {
+			return nil, err
+		}
+		c.number = n
+	default:
+		return nil, fmt.Errorf("unknown filter field '%s', use method, path, status, latency or violations", c.field)
+	}
+	return c, nil
+}
+
+func parseNumber(field, value string) (float64, error) {
+	if field == "latency" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return float64(d) / float64(time.Millisecond), nil
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s has to be compared with a number, not '%s'", field, value)
+	}
+	return n, nil
+}
+
+func isStatusClass(value string) bool {
+	v := strings.ToLower(value)
+	return len(v) == 3 && v[0] >= '1' && v[0] <= '5' && v[1:] == "xx"
+}
+
+// Match checks if an entry is printed, an empty filter matches everything.
+func (f *Filter) Match(e *Entry) bool {
+	if f == nil {
+		return true
+	}
+	for _, c := range f.conditions {
+		if !c.match(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *condition) match(e *Entry) bool {
+	switch c.field {
+	case "method":
+		return c.matchText(strings.ToUpper(e.Method))
+	case "path":
+		path, _, _ := strings.Cut(e.Path, "?")
+		return c.matchText(path)
+	case "status":
+		if isStatusClass(c.value) {
+			same := e.Status/100 == int(c.value[0]-'0')
+			return same == (c.operator == "=")
+		}
+		return c.compare(float64(e.Status))
+	case "latency":
+		return c.compare(float64(e.Latency) / float64(time.Millisecond))
+	case "violations":
+		return c.compare(float64(e.Violations))
+	}
+	return false
+}
+
+func (c *condition) matchText(actual string) bool {
+	switch c.operator {
+	case "~":
+		return c.glob.Match(actual)
+	case "!=":
+		return actual != c.value
+	default:
+		return actual == c.value
+	}
+}
+
+func (c *condition) compare(actual float64) bool {
+	switch c.operator {
+	case "!=":
+		return actual != c.number
+	case ">":
+		return actual > c.number
+	case ">=":
+		return actual >= c.number
+	case "<":
+		return actual < c.number
+	case "<=":
+		return actual <= c.number
+	default:
+		return actual == c.number
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package tail
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	entry := &Entry{Method: "POST", Path: "/pets/1?expand=true", Status: 422, Latency: 250 * time.Millisecond, Violations: 2}
+
+	matches := func(expressions ...string) bool {
+		f, err := ParseFilter(expressions...)
+		assert.NoError(t, err)
+		return f.Match(entry)
+	}
+
+	assert.True(t, matches())
+	assert.True(t, matches("status >= 400"))
+	assert.False(t, matches("status < 400"))
+	assert.True(t, matches("status = 4xx"))
+	assert.False(t, matches("status != 4xx"))
+	assert.True(t, matches("method = post", "path ~ /pets/*"))
+	assert.False(t, matches("path = /pets"))
+	assert.True(t, matches("path == /pets/1"))
+	assert.True(t, matches("violations > 0 && latency >= 200"))
+	assert.True(t, matches("latency > 0.2s and method != GET"))
+	assert.False(t, matches("latency > 1s"))
+	assert.False(t, matches("violations = 0", "status = 422"))
+
+	for _, bad := range []string{"colour = red", "status ~ 4*", "latency > soon", "method > GET", "status >= 4xx", "status"} {
+		_, err := ParseFilter(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestFromTransaction(t *testing.T) {
+	e := FromTransaction(&daemon.HttpTransaction{
+		Request:            &daemon.HttpRequest{Method: "GET", Path: "/pets", Query: "limit=1", Timestamp: 1000},
+		Response:           &daemon.HttpResponse{StatusCode: 200, Timestamp: 1040},
+		ResponseValidation: []*shared.Violation{{}},
+	})
+	assert.Equal(t, "GET", e.Method)
+	assert.Equal(t, "/pets?limit=1", e.Path)
+	assert.Equal(t, 200, e.Status)
+	assert.Equal(t, 40*time.Millisecond, e.Latency)
+	assert.Equal(t, 1, e.Violations)
+
+	e = FromTransaction(&daemon.HttpTransaction{
+		Request:  &daemon.HttpRequest{Method: "GET", Path: "/pets", Timestamp: 1000},
+		Response: &daemon.HttpResponse{StatusCode: 200, Timestamp: 1040, Latency: 12.5},
+	})
+	assert.Equal(t, 12500*time.Microsecond, e.Latency)
+	assert.Contains(t, Format(e), "/pets")
+}
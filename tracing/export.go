@@ -0,0 +1,161 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	exportBatchSize = 512
+	exportInterval  = 5 * time.Second
+	exportQueueSize = 4096
+)
+
+// exporter sends spans to an OTLP/HTTP endpoint in batches, when a batch is full or every few seconds. Spans are
+// dropped if the queue is full, tracing never slows traffic down.
+type exporter struct {
+	url         string
+	serviceName string
+	version     string
+	client      *http.Client
+	queue       chan *Span
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+func newExporter(endpoint, serviceName, version string) *exporter {
+	e := &exporter{
+		url:         strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		version:     version,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan *Span, exportQueueSize),
+		done:        make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *exporter) export(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+	}
+}
+
+func (e *exporter) close() {
+	e.closeOnce.Do(func() {
+		close(e.queue)
+		<-e.done
+	})
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	var batch []*Span
+	for {
+		select {
+		case span, ok := <-e.queue:
+			if !ok {
+				e.send(batch)
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= exportBatchSize {
+				e.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			e.send(batch)
+			batch = nil
+		}
+	}
+}
+
+func (e *exporter) send(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	payload, err := json.Marshal(e.request(batch))
+	if err != nil {
+		return
+	}
+	response, err := e.client.Post(e.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+	_ = response.Body.Close()
+}
+
+// request builds an OTLP export request, https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+func (e *exporter) request(batch []*Span) map[string]any {
+	spans := make([]map[string]any, 0, len(batch))
+	for _, span := range batch {
+		spans = append(spans, otlpSpan(span))
+	}
+	return map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{
+				"attributes": otlpAttributes(map[string]any{"service.name": e.serviceName, "service.version": e.version}),
+			},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": "wiretap", "version": e.version},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+func otlpSpan(span *Span) map[string]any {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	s := map[string]any{
+		"traceId":           span.context.TraceID.String(),
+		"spanId":            span.context.SpanID.String(),
+		"name":              span.name,
+		"kind":              int(span.kind),
+		"startTimeUnixNano": strconv.FormatInt(span.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.end.UnixNano(), 10),
+		"attributes":        otlpAttributes(span.attributes),
+	}
+	if span.parent.IsValid() {
+		s["parentSpanId"] = span.parent.String()
+	}
+	if span.err != "" {
+		s["status"] = map[string]any{"code": 2, "message": span.err}
+	}
+	return s
+}
+
+func otlpAttributes(attributes map[string]any) []map[string]any {
+	values := make([]map[string]any, 0, len(attributes))
+	for key, value := range attributes {
+		var v map[string]any
+		switch value := value.(type) {
+		case bool:
+			v = map[string]any{"boolValue": value}
+		case int:
+			v = map[string]any{"intValue": strconv.Itoa(value)}
+		case int64:
+			v = map[string]any{"intValue": strconv.FormatInt(value, 10)}
+		case float64:
+			v = map[string]any{"doubleValue": value}
+		default:
+			v = map[string]any{"stringValue": fmt.Sprint(value)}
+		}
+		values = append(values, map[string]any{"key": key, "value": v})
+	}
+	return values
+}
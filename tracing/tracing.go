@@ -0,0 +1,212 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package tracing creates spans for the work wiretap does, joins the traces of incoming requests (W3C trace
+// context) and exports spans to an OpenTelemetry collector (OTLP over HTTP, JSON encoded).
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentHeader carries the trace context of a request, https://www.w3.org/TR/trace-context/
+const TraceparentHeader = "traceparent"
+
+// TracestateHeader carries vendor specific trace context, it's passed on as is.
+const TracestateHeader = "tracestate"
+
+// SpanKind is the role of a span in a trace, values are the OTLP ones.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span in a trace.
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// IsValid checks the trace id isn't all zeros.
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// IsValid checks the span id isn't all zeros.
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext is what's propagated to other services, the trace and span ids and the trace flags.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+}
+
+// ParseTraceparent reads a traceparent header, e.g. `00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01`.
+func ParseTraceparent(header string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 ||
+		len(parts[3]) != 2 {
+		return sc, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.Flags = flags[0]
+	return sc, sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent renders the span context as a traceparent header.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// Span is a unit of work in a trace. Spans of a nil tracer are nil, and every method of a nil span does nothing,
+// so work can be traced without checking if tracing is on.
+type Span struct {
+	tracer     *Tracer
+	name       string
+	context    SpanContext
+	parent     SpanID
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	attributes map[string]any
+	err        string
+	lock       sync.Mutex
+}
+
+// SetAttribute records an attribute (a string, bool, int or float) on the span.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	s.attributes[key] = value
+	s.lock.Unlock()
+}
+
+// SetError marks the span as failed.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.lock.Lock()
+	s.err = err.Error()
+	s.lock.Unlock()
+}
+
+// Inject sets the traceparent header of a request to the span, so the next service joins the trace as its child.
+func (s *Span) Inject(header http.Header) {
+	if s == nil {
+		return
+	}
+	header.Set(TraceparentHeader, s.context.Traceparent())
+}
+
+// End finishes the span, and queues it for export, unless the trace isn't sampled.
+func (s *Span) End() {
+	if s == nil || s.context.Flags&1 == 0 {
+		return
+	}
+	s.lock.Lock()
+	s.end = time.Now()
+	s.lock.Unlock()
+	s.tracer.exporter.export(s)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a context carrying a span, spans started from it are its children.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by a context, if any.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Tracer starts spans, and exports them when they end.
+type Tracer struct {
+	exporter *exporter
+}
+
+// NewTracer creates a tracer that exports spans to an OTLP/HTTP endpoint (e.g. http://localhost:4318), spans are
+// sent in batches.
+func NewTracer(endpoint, serviceName, version string) *Tracer {
+	return &Tracer{exporter: newExporter(endpoint, serviceName, version)}
+}
+
+// Start starts a span, as a child of the span in the context (if there is one), and returns a context carrying it.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	var parent SpanContext
+	if p := SpanFromContext(ctx); p != nil {
+		parent = p.context
+	}
+	span := t.newSpan(name, kind, parent)
+	return ContextWithSpan(ctx, span), span
+}
+
+// StartRequest starts a server span for an incoming request, joining the trace of the caller if the request has a
+// traceparent header, the context of the request is replaced with one carrying the span.
+func (t *Tracer) StartRequest(request *http.Request, name string) (*http.Request, *Span) {
+	if t == nil {
+		return request, nil
+	}
+	parent, _ := ParseTraceparent(request.Header.Get(TraceparentHeader))
+	span := t.newSpan(name, SpanKindServer, parent)
+	return request.WithContext(ContextWithSpan(request.Context(), span)), span
+}
+
+func (t *Tracer) newSpan(name string, kind SpanKind, parent SpanContext) *Span {
+	span := &Span{tracer: t, name: name, kind: kind, start: time.Now(), attributes: make(map[string]any)}
+	if parent.TraceID.IsValid() {
+		span.context.TraceID = parent.TraceID
+		span.context.Flags = parent.Flags
+		span.parent = parent.SpanID
+	} else {
+		_, _ = rand.Read(span.context.TraceID[:])
+		span.context.Flags = 1 // sampled
+	}
+	_, _ = rand.Read(span.context.SpanID[:])
+	return span
+}
+
+// Close exports any spans that haven't been sent yet, and stops exporting.
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+	t.exporter.close()
+}
@@ -0,0 +1,56 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.Equal(t, byte(1), sc.Flags)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	for _, invalid := range []string{"", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", "00-xyz92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"} {
+		_, ok = ParseTraceparent(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestTracer_StartRequest(t *testing.T) {
+	tracer := &Tracer{}
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/pets", nil)
+	request.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	request, server := tracer.StartRequest(request, "wiretap GET")
+	assert.Equal(t, server, SpanFromContext(request.Context()))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.context.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", server.parent.String())
+
+	_, upstream := tracer.Start(request.Context(), "wiretap upstream", SpanKindClient)
+	assert.Equal(t, server.context.TraceID, upstream.context.TraceID)
+	assert.Equal(t, server.context.SpanID, upstream.parent)
+
+	header := http.Header{}
+	upstream.Inject(header)
+	assert.Equal(t, upstream.context.Traceparent(), header.Get(TraceparentHeader))
+
+	// without a tracer, there are no spans.
+	var none *Tracer
+	ctx, span := none.Start(context.Background(), "nothing", SpanKindInternal)
+	assert.Nil(t, span)
+	assert.Nil(t, SpanFromContext(ctx))
+	span.SetAttribute("ignored", true)
+	span.End()
+}
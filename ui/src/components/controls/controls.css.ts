@@ -40,6 +40,17 @@ export default css`
         border-radius: 0;
     }
 
+    .held {
+        position: relative;
+    }
+
+    .held-badge::part(base) {
+        font-size: 0.5rem;
+        position: absolute;
+        top: -8px;
+        left: 25px;
+    }
+
     .filters-badge::part(base) {
         font-size: 0.5rem;
         background-color: var(--primary-color);
@@ -7,9 +7,11 @@ import controlsComponentCss from "./controls.css";
 import {SlDrawer, SlInput} from "@shoelace-style/shoelace";
 import {Bag, BagManager, GetBagManager} from "@pb33f/saddlebag";
 import {WipeDataEvent} from "@/model/events";
+import {InterceptDecision, InterceptedRequest, InterceptEvent} from "@/model/intercept";
 import sharedCss from "@/components/shared.css";
 import {
     ChangeDelayCommand,
+    ReleaseInterceptedCommand,
     RequestReportCommand,
     WiretapControlsChannel,
     WiretapControlsKey,
@@ -17,7 +19,9 @@ import {
     WiretapFiltersKey,
     WiretapFiltersStore,
     WiretapHttpTransactionStore,
-    WiretapReportChannel
+    WiretapInterceptChannel,
+    WiretapReportChannel,
+    WiretapServiceChannel
 } from "@/model/constants";
 
 @customElement('wiretap-controls')
@@ -36,6 +40,12 @@ export class WiretapControlsComponent extends LitElement {
     @query('#filters-drawer')
     filtersDrawer: SlDrawer;
 
+    @query('#intercepted-drawer')
+    interceptedDrawer: SlDrawer;
+
+    @state()
+    held: InterceptedRequest[] = [];
+
     @state()
     numFilters: number = 0;
 
@@ -50,6 +60,8 @@ export class WiretapControlsComponent extends LitElement {
 
     private readonly _wiretapControlsSubscription: Subscription;
     private readonly _wiretapReportSubscription: Subscription;
+    private readonly _wiretapInterceptSubscription: Subscription;
+    private readonly _wiretapServiceSubscription: Subscription;
     private readonly _wiretapControlsChannel: Channel;
     private readonly _wiretapReportChannel: Channel;
     private readonly _storeManager: BagManager;
@@ -71,6 +83,10 @@ export class WiretapControlsComponent extends LitElement {
         this._wiretapReportChannel = this._bus.getChannel(WiretapReportChannel);
         this._wiretapControlsSubscription = this._wiretapControlsChannel.subscribe(this.controlUpdateHandler());
         this._wiretapReportSubscription = this._wiretapReportChannel.subscribe(this.reportHandler());
+        this._wiretapInterceptSubscription =
+            this._bus.getChannel(WiretapInterceptChannel).subscribe(this.interceptHandler());
+        this._wiretapServiceSubscription =
+            this._bus.getChannel(WiretapServiceChannel).subscribe(this.interceptedHandler());
 
         this.loadControlStateFromStorage().then((controls: WiretapControls) => {
             if (!controls) {
@@ -105,6 +121,9 @@ export class WiretapControlsComponent extends LitElement {
 
     controlUpdateHandler(): BusCallback<CommandResponse> {
         return (msg: Message<CommandResponse<ControlsResponse>>) => {
+            if (!msg.payload.payload?.config) {
+                return; // not a change to the configuration, like the status of the specification.
+            }
             const delay = msg.payload.payload?.config.globalAPIDelay;
             const existingDelay = this._controls?.globalDelay;
 
@@ -137,6 +156,43 @@ export class WiretapControlsComponent extends LitElement {
         }
     }
 
+    // requests are added to the held list when wiretap holds them, and removed once they are released.
+    interceptHandler(): BusCallback<CommandResponse> {
+        return (msg: CommandResponse) => {
+            const event = msg.payload as InterceptEvent;
+            if (event?.held) {
+                this.held = [...this.held.filter((r) => r.id !== event.held.id), event.held];
+            }
+            if (event?.released) {
+                this.held = this.held.filter((r) => r.id !== event.released);
+            }
+        }
+    }
+
+    // the held requests are listed by wiretap when asked for, and after one is released. These are the only
+    // responses on the service channel that are lists, transactions are sent one at a time.
+    interceptedHandler(): BusCallback<CommandResponse> {
+        return (msg: Message<CommandResponse<InterceptedRequest[]>>) => {
+            if (Array.isArray(msg.payload?.payload)) {
+                this.held = msg.payload.payload;
+            }
+        }
+    }
+
+    releaseIntercepted(event: CustomEvent) {
+        const decision = event.detail as InterceptDecision;
+        this._bus.publish({
+            destination: "/pub/queue/" + WiretapServiceChannel,
+            body: JSON.stringify(
+                {
+                    id: RanchUtils.genUUID(),
+                    request: ReleaseInterceptedCommand,
+                    payload: decision
+                }
+            ),
+        });
+    }
+
     changeGlobalDelay(delay: number) {
         if (this._bus.getClient()?.connected) {
             this._bus.publish({
@@ -162,6 +218,10 @@ export class WiretapControlsComponent extends LitElement {
         this.filtersDrawer.show();
     }
 
+    openIntercepted() {
+        this.interceptedDrawer.show();
+    }
+
     sendReportRequest() {
         this._bus.publish({
             destination: "/pub/queue/report",
@@ -178,6 +238,7 @@ export class WiretapControlsComponent extends LitElement {
     closeControls() {
         this.controlsDrawer.hide()
         this.filtersDrawer.hide()
+        this.interceptedDrawer.hide()
     }
 
     handleGlobalDelayChange(event: CustomEvent) {
@@ -201,12 +262,23 @@ export class WiretapControlsComponent extends LitElement {
                 <sl-badge class="filters-badge" pill pulse>${this.numFilters}</sl-badge>`
         }
 
+        let heldBadge: TemplateResult;
+        if (this.held.length > 0) {
+            heldBadge = html`
+                <sl-badge class="held-badge" variant="warning" pill pulse>${this.held.length}</sl-badge>`
+        }
+
         return html`
             ${filtersBadge}
             <sl-icon-button @click=${this.openFilters} name="funnel" label="filers">
             </sl-icon-button>
             <sl-icon-button @click=${this.openSettings} name="gear" label="controls">
             </sl-icon-button>
+            <span class="held">
+                ${heldBadge}
+                <sl-icon-button @click=${this.openIntercepted} name="sign-stop" label="held requests">
+                </sl-icon-button>
+            </span>
             <pb33f-theme-switcher></pb33f-theme-switcher>
             <sl-drawer label="wiretap controls" class="drawer-focus" id="controls-drawer">
                 <a id="downloadReport" style="display:none"></a>
@@ -221,6 +293,13 @@ export class WiretapControlsComponent extends LitElement {
                 <wiretap-controls-filters filters=${this.filters}></wiretap-controls-filters>
                 <sl-button @click=${this.closeControls} slot="footer" variant="primary" outline>Close</sl-button>
             </sl-drawer>
+
+            <sl-drawer label="held requests" class="drawer-focus" id="intercepted-drawer">
+                <wiretap-controls-intercepted .held=${this.held}
+                                              @interceptDecision=${this.releaseIntercepted}>
+                </wiretap-controls-intercepted>
+                <sl-button @click=${this.closeControls} slot="footer" variant="primary" outline>Close</sl-button>
+            </sl-drawer>
         `
     }
 }
\ No newline at end of file
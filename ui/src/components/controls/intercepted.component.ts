@@ -0,0 +1,69 @@
+import {customElement, property} from "lit/decorators.js";
+import {html, LitElement} from "lit";
+import sharedCss from "@/components/shared.css";
+import interceptedComponentCss from "./intercepted.css";
+import {ExchangeMethod} from "@pb33f/cowboy-components/model/exchange_method.js";
+import {SlInput, SlTextarea} from "@shoelace-style/shoelace";
+import {InterceptDecision, InterceptedRequest} from "@/model/intercept";
+import {InterceptDecisionEvent} from "@/model/events";
+
+@customElement('wiretap-controls-intercepted')
+export class WiretapControlsInterceptedComponent extends LitElement {
+
+    static styles = [sharedCss, interceptedComponentCss]
+
+    @property({type: Array})
+    held: InterceptedRequest[] = [];
+
+    // approving sends the request on, with the path and body as they have been edited.
+    approve(request: InterceptedRequest) {
+        const decision: InterceptDecision = {id: request.id};
+        const path = this.shadowRoot.querySelector<SlInput>('#path-' + request.id)?.value;
+        if (path && path !== request.path) {
+            decision.path = path;
+        }
+        const body = this.shadowRoot.querySelector<SlTextarea>('#body-' + request.id)?.value;
+        if (body != undefined && body !== (request.body ?? '')) {
+            decision.body = body;
+        }
+        this.dispatchEvent(new CustomEvent(InterceptDecisionEvent, {detail: decision}))
+    }
+
+    drop(request: InterceptedRequest) {
+        this.dispatchEvent(new CustomEvent(InterceptDecisionEvent, {detail: {id: request.id, drop: true}}))
+    }
+
+    render() {
+        if (!this.held || this.held.length == 0) {
+            return html`
+                <p>
+                    No requests are being held. Requests that match an intercept rule are held here, until
+                    they are approved (or changed), or dropped.
+                </p>`
+        }
+        return html`
+            ${this.held.map((request: InterceptedRequest) => {
+                return html`
+                    <div class="held">
+                        <div class="held-request">
+                            <sl-tag variant="${ExchangeMethod(request.method)}" class="method" size="small">
+                                ${request.method}
+                            </sl-tag>
+                            <span>${request.path}</span>
+                        </div>
+                        <div class="held-rule">
+                            held by '${request.rule}' until ${new Date(request.expires).toLocaleTimeString()}
+                        </div>
+                        <sl-input id="path-${request.id}" label="Path" size="small" value=${request.path}></sl-input>
+                        <sl-textarea id="body-${request.id}" label="Body" size="small" resize="auto"
+                                     value=${request.body ?? ''}></sl-textarea>
+                        <sl-button @click=${() => this.approve(request)} size="small" variant="success" outline>
+                            Approve
+                        </sl-button>
+                        <sl-button @click=${() => this.drop(request)} size="small" variant="danger" outline>
+                            Drop
+                        </sl-button>
+                    </div>`
+            })}`
+    }
+}
@@ -0,0 +1,45 @@
+import {css} from "lit";
+
+export default css`
+
+    .held {
+        border: 1px dashed var(--secondary-color-dimmer);
+        padding: 10px;
+        margin-bottom: 20px;
+    }
+
+    .held-request {
+        font-family: var(--font-stack), monospace;
+        display: flex;
+        align-items: center;
+        gap: 10px;
+        word-break: break-all;
+    }
+
+    .held-rule {
+        font-family: var(--font-stack), monospace;
+        font-size: 0.8rem;
+        color: var(--font-color-sub2);
+        margin-top: 5px;
+        margin-bottom: 10px;
+    }
+
+    .method::part(base) {
+        border-radius: 0;
+    }
+
+    sl-input, sl-textarea {
+        margin-bottom: 10px;
+    }
+
+    sl-input::part(base), sl-textarea::part(base) {
+        border-radius: 0;
+        font-family: var(--font-stack), monospace;
+    }
+
+    sl-button::part(base) {
+        font-family: var(--font-stack), monospace;
+        border: 1px dashed;
+        border-radius: 0;
+    }
+`
@@ -63,6 +63,16 @@ export class RequestBodyViewComponent extends LitElement {
             jsonBody += ": " + e.message;
         }
 
+        // gRPC messages are decoded by wiretap, the body is binary.
+        if (req.grpc) {
+            const call = req.grpc.service ? html`<span class="contentType">
+                gRPC: <strong>${req.grpc.service}/${req.grpc.method}</strong></span>` : null;
+            return html`${ct}${call}
+            ${req.grpc.error ? html`<div class="empty-data">${req.grpc.error}</div>` : null}
+            <pre><code>${unsafeHTML(Prism.highlight(JSON.stringify(req.grpc.messages ?? [], null, 2),
+                    Prism.languages.json, 'json'))}</code></pre>`;
+        }
+
         switch (exct) {
             case ContentTypeJSON:
                 return html`${ct}
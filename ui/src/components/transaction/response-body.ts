@@ -33,6 +33,40 @@ export class ResponseBodyViewComponent extends LitElement {
             Content Type: <strong>${exct}</strong>
         </span>`;
 
+        // gRPC messages are decoded by wiretap, the body is binary.
+        const grpc = this._httpResponse.grpc;
+        if (grpc) {
+            const status = grpc.status != undefined ? html`<span class="contentType">
+                gRPC Status: <strong>${grpc.status}</strong> ${grpc.statusMessage}</span>` : null;
+            return html`${ct}${status}
+            ${grpc.error ? html`<div class="empty-data">${grpc.error}</div>` : null}
+            <pre><code>${unsafeHTML(Prism.highlight(JSON.stringify(grpc.messages ?? [], null, 2),
+                    Prism.languages.json, 'json'))}</code></pre>`;
+        }
+
+        // websocket messages are shown in the order they were relayed.
+        const frames = this._httpResponse.frames;
+        if (frames) {
+            return html`${ct}
+            ${frames.map((f) => html`
+                <span class="contentType">
+                    ${f.direction == 'client' ? 'Client → API' : 'API → Client'}: <strong>${f.type}</strong>
+                    (${f.size} bytes${f.truncated ? ', truncated' : ''})
+                </span>
+                <pre><code>${f.data}</code></pre>`)}`;
+        }
+
+        // event streams are shown event by event, as they arrive.
+        const events = this._httpResponse.events;
+        if (events) {
+            return html`${ct}
+            ${events.map((e) => html`
+                <span class="contentType">
+                    Event: <strong>${e.event ?? 'message'}</strong>${e.id ? html` (id: ${e.id})` : null}
+                </span>
+                <pre><code>${e.data}</code></pre>`)}`;
+        }
+
         switch (exct) {
             case ContentTypeXML:
                 return html`
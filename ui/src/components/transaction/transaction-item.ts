@@ -147,6 +147,8 @@ export class HttpTransactionItemComponent extends LitElement {
                 <header>
                    <pb33f-http-method method="${req.method}"></pb33f-http-method>
                     ${decodeURI(req.path)}
+                    ${req.webhook ? html`<sl-tag size="small" variant="neutral" class="webhook">webhook: ${req.webhook}</sl-tag>` : null}
+                    ${req.graphql?.map(op => html`<sl-tag size="small" variant="primary" class="graphql">${op.type} ${op.name || '(anonymous)'}</sl-tag>`)}
               
                 </header>
                 ${delay}
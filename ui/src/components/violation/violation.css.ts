@@ -7,6 +7,14 @@ export default css`
         vertical-align: bottom;
     }
 
+    .severity-error::part(base) {
+        color: var(--error-color);
+    }
+
+    .severity-warn::part(base) {
+        color: var(--warn-color);
+    }
+
     p.reason {
         margin-top: 0;
     }
@@ -84,6 +84,8 @@ export class ViolationViewComponent extends LitElement {
                         <sl-tag size="small" class="validation-type">${this.violation?.validationType}</sl-tag>
                         /
                         <sl-tag size="small" class="validation-subtype">${this.violation?.validationSubType}</sl-tag>
+                        ${this.violation?.severity ? html`
+                            <sl-tag size="small" class="severity-${this.violation.severity}">${this.violation.severity}</sl-tag>` : html``}
                     </div>
                     ${specMeta}
                 </div>
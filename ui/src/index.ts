@@ -12,6 +12,7 @@ import '@shoelace-style/shoelace/dist/components/spinner/spinner.js';
 import '@shoelace-style/shoelace/dist/components/drawer/drawer.js';
 import '@shoelace-style/shoelace/dist/components/button/button.js';
 import '@shoelace-style/shoelace/dist/components/input/input.js';
+import '@shoelace-style/shoelace/dist/components/textarea/textarea.js';
 import '@shoelace-style/shoelace/dist/components/dropdown/dropdown.js';
 import '@shoelace-style/shoelace/dist/components/badge/badge.js';
 import '@shoelace-style/shoelace/dist/components/menu/menu.js';
@@ -24,6 +25,7 @@ import '@shoelace-style/shoelace/dist/components/switch/switch.js';
 import '@shoelace-style/shoelace/dist/components/radio-button/radio-button.js';
 import '@shoelace-style/shoelace/dist/components/radio-group/radio-group.js';
 import '@shoelace-style/shoelace/dist/components/icon-button/icon-button.js';
+import '@shoelace-style/shoelace/dist/components/alert/alert.js';
 
 
 import '@pb33f/cowboy-components/cowboy-components.css';
@@ -56,6 +58,7 @@ import './components/wiretap-header/metric';
 import './components/controls/controls';
 import './components/controls/settings.component';
 import './components/controls/filters.component';
+import './components/controls/intercepted.component';
 
 // models
 import './model/http_transaction';
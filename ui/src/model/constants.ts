@@ -1,4 +1,4 @@
-export const WiretapChannel = "wiretap-broadcast";
+export const WiretapServiceChannel = "wiretap";
 export const SpecChannel = "specs";
 export const WiretapControlsChannel = "controls";
 
@@ -6,6 +6,9 @@ export const WiretapReportChannel = "report";
 
 export const WiretapConfigurationChannel = "configuration";
 export const WiretapStaticChannel = "wiretap-static-change";
+export const WiretapSpecStatusChannel = "wiretap-spec-status";
+export const WiretapRetentionChannel = "wiretap-retention";
+export const WiretapInterceptChannel = "wiretap-intercept";
 
 export const WiretapHttpTransactionStore = "http-transaction-store";
 export const WiretapSelectedTransactionStore = "selected-transaction-store";
@@ -25,12 +28,21 @@ export const WiretapControlsKey = "wiretap-controls";
 export const WiretapCurrentSpec = "current-spec";
 export const GetCurrentSpecCommand = "get-current-spec";
 export const ChangeDelayCommand = "change-delay-request";
+export const GetInterceptedCommand = "get-intercepted";
+export const ReleaseInterceptedCommand = "release-intercepted";
+export const SubscribeTransactionsCommand = "subscribe-transactions";
+export const SpecStatusCommand = "get-spec-status";
+export const ReloadSpecCommand = "reload-spec";
 export const StartTheHARCommand = "start-the-har";
+export const TransactionBackfill = 250;
 
 export const RequestReportCommand = "generate-report-request";
 
 export const WiretapLocalStorage = "wiretap-transactions";
 
+export const TransactionStreamURL = "/api/stream";
+export const TransactionStreamEvent = "transaction";
+
 export const NoSpec = "no-spec";
 
 
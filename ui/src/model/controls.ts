@@ -33,6 +33,22 @@ export function AreFiltersActive(filters: WiretapFilters): boolean {
 }
 
 
+// TransactionFilter is sent to wiretap, so only the transactions that match it are sent to the monitor.
+export interface TransactionFilter {
+    methods?: string[];
+    backfill?: number;
+}
+
+// keywords and chains are matched by the monitor (against history it already has), only the method is left to wiretap.
+export function ToTransactionFilter(filters: WiretapFilters): TransactionFilter {
+    const filter: TransactionFilter = {};
+    if (filters?.filterMethod?.keyword?.length > 0) {
+        filter.methods = [filters.filterMethod.keyword];
+    }
+    return filter;
+}
+
+
 export interface Filter {
     id?: string;
     keyword: string;
@@ -52,6 +68,25 @@ export interface ReportResponse {
 }
 
 
+// SpecStatus is sent by wiretap when the specification fails to compile (it carries on with the previous one), and
+// once it compiles again.
+export interface SpecStatus {
+    healthy: boolean;
+    errors?: string[];
+    failedAt?: string;
+}
+
+// RetentionStatus is sent by wiretap when it drops its oldest transactions, to stay within maxTransactions or
+// maxCaptureMemoryMB.
+export interface RetentionStatus {
+    evicted: number;
+    retained: number;
+    retainedBytes: number;
+    reason?: string;
+    evictedAt: string;
+}
+
+
 export interface WiretapConfig {
     redirectHost:   string;
     port:           string;
@@ -2,6 +2,7 @@ export const HttpTransactionSelectedEvent = "httpTransactionSelected";
 export const ViolationLocationSelectionEvent = "violationLocationSelected";
 export const GlobalDelayChangedEvent = "globalDelayChanged";
 export const RequestReportEvent = "requestReport";
+export const InterceptDecisionEvent = "interceptDecision";
 
 export const ToggleSpecificationEvent = "toggleSpecification";
 
@@ -34,6 +34,42 @@ export interface ValidationError {
     howToFix: string;
     validationErrors?: SchemaValidationFailure[];
     context?: any;
+    severity?: string;
+}
+
+// GRPCPayload is a gRPC request or response, decoded as JSON by wiretap.
+export interface GRPCPayload {
+    service?: string;
+    method?: string;
+    messages?: any[];
+    status?: number;
+    statusMessage?: string;
+    error?: string;
+}
+
+// GraphQLOperation is an operation run by a GraphQL request, anonymous operations have no name.
+export interface GraphQLOperation {
+    type: string;
+    name?: string;
+}
+
+// ServerSentEvent is an event of a text/event-stream response, captured by wiretap.
+export interface ServerSentEvent {
+    id?: string;
+    event?: string;
+    data: string;
+    retry?: number;
+}
+
+// WebSocketFrame is a message relayed over a proxied websocket, binary messages are base64 encoded.
+export interface WebSocketFrame {
+    timestamp: number;
+    direction: string;
+    type: string;
+    data?: string;
+    encoding?: string;
+    size: number;
+    truncated?: boolean;
 }
 
 export class HttpRequest {
@@ -47,8 +83,13 @@ export class HttpRequest {
     requestBody?: string;
     timestamp?: number;
     originalPath?: string;
+    destination?: string;
+    label?: string;
+    webhook?: string;
     droppedHeaders?: string[];
     injectedHeaders?: any
+    grpc?: GRPCPayload;
+    graphql?: GraphQLOperation[];
 
     constructor() {
         this.headers = {};
@@ -84,6 +125,9 @@ export class HttpResponse {
     statusCode?: number;
     responseBody?: string;
     timestamp?: number;
+    grpc?: GRPCPayload;
+    events?: ServerSentEvent[];
+    frames?: WebSocketFrame[];
 
     constructor() {
         this.headers = {}
@@ -0,0 +1,27 @@
+// InterceptedRequest is a request wiretap is holding, until it's approved or dropped (or it expires).
+export interface InterceptedRequest {
+    id: string;
+    rule: string;
+    method: string;
+    path: string;
+    headers?: Record<string, string>;
+    body?: string;
+    heldAt: string;
+    expires: string;
+}
+
+// InterceptDecision approves a held request (with any changes made to its path or body), or drops it.
+export interface InterceptDecision {
+    id: string;
+    drop?: boolean;
+    path?: string;
+    body?: string;
+}
+
+// InterceptEvent is sent by wiretap when a request is held, or released.
+export interface InterceptEvent {
+    held?: InterceptedRequest;
+    released?: string;
+    dropped?: boolean;
+    timedOut?: boolean;
+}
@@ -1,22 +1,22 @@
-import {customElement, property, query} from "lit/decorators.js";
-import {html, LitElement, PropertyValues} from "lit";
+import {customElement, property, query, state} from "lit/decorators.js";
+import {html, LitElement, PropertyValues, TemplateResult} from "lit";
 import {HttpRequest, HttpResponse, HttpTransaction, HttpTransactionBase} from "./model/http_transaction";
 import {Bag, BagManager, CreateBagManager} from "@pb33f/saddlebag";
-import {Bus, BusCallback, Channel, CommandResponse, CreateBus, Subscription} from "@pb33f/ranch";
+import {Bus, BusCallback, Channel, CommandResponse, CreateBus, RanchUtils, Subscription} from "@pb33f/ranch";
 import {HttpTransactionContainerComponent} from "./components/transaction/transaction-container";
 import * as localforage from "localforage";
 import {HeaderComponent} from "@/components/wiretap-header/header";
-import {WiretapControls, WiretapFilters} from "@/model/controls";
+import {RetentionStatus, SpecStatus, ToTransactionFilter, WiretapControls, WiretapFilters} from "@/model/controls";
 import {
-    GetCurrentSpecCommand, NoSpec, QueuePrefix,
-    SpecChannel, StartTheHARCommand, TopicPrefix,
-    WiretapChannel, WiretapConfigurationChannel,
-    WiretapControlsChannel, WiretapControlsKey, WiretapControlsStore,
-    WiretapCurrentSpec, WiretapFiltersStore,
+    GetCurrentSpecCommand, GetInterceptedCommand, NoSpec, QueuePrefix, ReloadSpecCommand,
+    SpecChannel, SpecStatusCommand, StartTheHARCommand, SubscribeTransactionsCommand, TopicPrefix, TransactionBackfill,
+    TransactionStreamEvent, TransactionStreamURL, WiretapConfigurationChannel,
+    WiretapControlsChannel, WiretapControlsKey, WiretapControlsStore, WiretapInterceptChannel, WiretapRetentionChannel,
+    WiretapCurrentSpec, WiretapFiltersKey, WiretapFiltersStore,
     WiretapHttpTransactionStore, WiretapLinkCacheKey, WiretapLinkCacheStore,
     WiretapLocalStorage, WiretapReportChannel,
     WiretapSelectedTransactionStore,
-    WiretapSpecStore, WiretapStaticChannel,
+    WiretapServiceChannel, WiretapSpecStatusChannel, WiretapSpecStore, WiretapStaticChannel,
 } from "@/model/constants";
 
 declare global {
@@ -36,12 +36,15 @@ export class WiretapComponent extends LitElement {
     private readonly _linkCacheStore: Bag<Map<string, Map<string, HttpTransactionBase[]>>>;
     private readonly _specStore: Bag<string>;
     private readonly _bus: Bus;
-    private readonly _wiretapChannel: Channel;
+    private readonly _wiretapServiceChannel: Channel;
     private readonly _wiretapSpecChannel: Channel;
     private readonly _wiretapControlsChannel: Channel;
     private readonly _wiretapReportChannel: Channel;
     private readonly _wiretapConfigChannel: Channel;
     private readonly _staticNotificationChannel: Channel;
+    private readonly _wiretapInterceptChannel: Channel;
+    private readonly _wiretapRetentionChannel: Channel;
+    private readonly _wiretapSpecStatusChannel: Channel;
     private readonly _wiretapPort: string;
     private readonly _wiretapHost: string;
     private readonly _wiretapVersion: string;
@@ -49,6 +52,11 @@ export class WiretapComponent extends LitElement {
     private _specChannelSubscription: Subscription;
     private _configChannelSubscription: Subscription;
     private _staticChannelSubscription: Subscription;
+    private _retentionChannelSubscription: Subscription;
+    private _specStatusChannelSubscription: Subscription;
+    private _controlsChannelSubscription: Subscription;
+    private _transactionStream: EventSource;
+    private _transactionFilter: string;
     private _useTLS: boolean = false;
     private _headerStatsDefaultPrecision: number = 0;
     private _complianceStatPrecision: number = 2;
@@ -76,6 +84,12 @@ export class WiretapComponent extends LitElement {
     @property({type: Number})
     complianceLevel: number = 100.0;
 
+    @state()
+    private _retention: RetentionStatus;
+
+    @state()
+    private _specStatus: SpecStatus;
+
     constructor() {
         super();
         //configure local storage
@@ -137,32 +151,48 @@ export class WiretapComponent extends LitElement {
 
         // filters store & subscribe to filter changes.
         this._filtersStore = this._storeManager.createBag(WiretapFiltersStore);
+        this._filtersStore.subscribe(WiretapFiltersKey, (filters: WiretapFilters) => {
+            this.subscribeTransactions(filters, false);
+        });
 
         // link cache store
         this._linkCacheStore =
             this._storeManager.createBag<Map<string, Map<string, HttpTransactionBase[]>>>(WiretapLinkCacheStore);
 
         // set up wiretap channels
-        this._wiretapChannel = this._bus.createChannel(WiretapChannel);
+        this._wiretapServiceChannel = this._bus.createChannel(WiretapServiceChannel);
         this._wiretapSpecChannel = this._bus.createChannel(SpecChannel);
         this._wiretapControlsChannel = this._bus.createChannel(WiretapControlsChannel);
         this._wiretapReportChannel = this._bus.createChannel(WiretapReportChannel);
         this._wiretapConfigChannel = this._bus.createChannel(WiretapConfigurationChannel);
         this._staticNotificationChannel = this._bus.createChannel(WiretapStaticChannel);
+        this._wiretapInterceptChannel = this._bus.createChannel(WiretapInterceptChannel);
+        this._wiretapRetentionChannel = this._bus.createChannel(WiretapRetentionChannel);
+        this._wiretapSpecStatusChannel = this._bus.createChannel(WiretapSpecStatusChannel);
 
         // map local bus channels to broker destinations.
-        this._bus.mapChannelToBrokerDestination(TopicPrefix + WiretapChannel, WiretapChannel);
+        this._bus.mapChannelToBrokerDestination(QueuePrefix + WiretapServiceChannel, WiretapServiceChannel);
         this._bus.mapChannelToBrokerDestination(QueuePrefix + SpecChannel, SpecChannel);
         this._bus.mapChannelToBrokerDestination(QueuePrefix + WiretapControlsChannel, WiretapControlsChannel);
         this._bus.mapChannelToBrokerDestination(QueuePrefix + WiretapReportChannel, WiretapReportChannel);
         this._bus.mapChannelToBrokerDestination(QueuePrefix + WiretapConfigurationChannel, WiretapConfigurationChannel);
         this._bus.mapChannelToBrokerDestination(TopicPrefix + WiretapStaticChannel, WiretapStaticChannel);
+        this._bus.mapChannelToBrokerDestination(TopicPrefix + WiretapInterceptChannel, WiretapInterceptChannel);
+        this._bus.mapChannelToBrokerDestination(TopicPrefix + WiretapRetentionChannel, WiretapRetentionChannel);
+        this._bus.mapChannelToBrokerDestination(TopicPrefix + WiretapSpecStatusChannel, WiretapSpecStatusChannel);
 
         // handle incoming messages on different channels.
-        this._transactionChannelSubscription = this._wiretapChannel.subscribe(this.wireTransactionHandler());
+        this._transactionChannelSubscription = this._wiretapServiceChannel.subscribe(this.subscriptionHandler());
         this._specChannelSubscription = this._wiretapSpecChannel.subscribe(this.specHandler());
         this._configChannelSubscription = this._wiretapConfigChannel.subscribe(this.configHandler());
         this._staticChannelSubscription = this._staticNotificationChannel.subscribe(this.staticHandler());
+        this._retentionChannelSubscription = this._wiretapRetentionChannel.subscribe(this.retentionHandler());
+        // the status of the specification is broadcast when it changes, and sent as a response when asked for.
+        const specStatusHandler = this.specStatusHandler();
+        this._specStatusChannelSubscription = this._wiretapSpecStatusChannel.subscribe(specStatusHandler);
+        this._controlsChannelSubscription = this._wiretapControlsChannel.subscribe((msg: CommandResponse) => {
+            specStatusHandler({payload: msg.payload?.payload} as CommandResponse);
+        });
 
 
         // load previous transactions from local storage.
@@ -190,8 +220,15 @@ export class WiretapComponent extends LitElement {
             heartbeatIncoming: 0,
             heartbeatOutgoing: 0,
             onConnect: () => {
+                this.closeTransactionStream();
                 this.requestSpec();
                 this.startTheHar();
+                this.subscribeTransactions(this._filtersStore.get(WiretapFiltersKey), true);
+                this.requestIntercepted();
+                this.requestSpecStatus();
+            },
+            onWebSocketError: () => {
+                this.openTransactionStream();
             }
         }
 
@@ -229,6 +266,26 @@ export class WiretapComponent extends LitElement {
         this.calcComplianceLevel();
     }
 
+    // some proxies refuse websocket upgrades, so transactions are streamed as server-sent events until the
+    // websocket connects.
+    openTransactionStream() {
+        if (this._transactionStream) {
+            return;
+        }
+        const handler = this.wireTransactionHandler();
+        this._transactionStream = new EventSource(TransactionStreamURL);
+        this._transactionStream.addEventListener(TransactionStreamEvent, (e: MessageEvent) => {
+            handler({payload: JSON.parse(e.data)} as CommandResponse);
+        });
+    }
+
+    closeTransactionStream() {
+        if (this._transactionStream) {
+            this._transactionStream.close();
+            this._transactionStream = null;
+        }
+    }
+
     requestSpec() {
         this._bus.publish({
             destination: "/pub/queue/specs",
@@ -243,6 +300,49 @@ export class WiretapComponent extends LitElement {
         })
     }
 
+    // a specification that failed to compile before the monitor connected is still shown.
+    requestSpecStatus() {
+        this._bus.publish({
+            destination: "/pub/queue/" + WiretapControlsChannel,
+            body: JSON.stringify({id: RanchUtils.genUUID(), request: SpecStatusCommand}),
+        })
+    }
+
+    reloadSpec() {
+        this._bus.publish({
+            destination: "/pub/queue/" + WiretapServiceChannel,
+            body: JSON.stringify({id: RanchUtils.genUUID(), request: ReloadSpecCommand}),
+        })
+    }
+
+    // requests already being held are listed, ones held after are announced on the intercept channel.
+    requestIntercepted() {
+        this._bus.publish({
+            destination: "/pub/queue/" + WiretapServiceChannel,
+            body: JSON.stringify({id: RanchUtils.genUUID(), request: GetInterceptedCommand}),
+        })
+    }
+
+    // wiretap only sends the transactions that match the filters, it's told again whenever they change. The most
+    // recent ones are sent first, so anything missed while disconnected (or filtered out) shows up.
+    subscribeTransactions(filters: WiretapFilters, force: boolean) {
+        const filter = ToTransactionFilter(filters);
+        const encoded = JSON.stringify(filter);
+        if (!this._bus.getClient()?.connected || (encoded == this._transactionFilter && !force)) {
+            return;
+        }
+        this._transactionFilter = encoded;
+        filter.backfill = TransactionBackfill;
+        this._bus.publish({
+            destination: "/pub/queue/" + WiretapServiceChannel,
+            body: JSON.stringify({
+                id: RanchUtils.genUUID(),
+                request: SubscribeTransactionsCommand,
+                payload: filter,
+            }),
+        })
+    }
+
     async loadHistoryFromLocalStorage(): Promise<Map<string, HttpTransaction>> {
         return localforage.getItem<Map<string, HttpTransaction>>(WiretapLocalStorage);
     }
@@ -272,6 +372,70 @@ export class WiretapComponent extends LitElement {
         }
     }
 
+    // transactions arrive as responses to the subscription.
+    subscriptionHandler(): BusCallback<CommandResponse> {
+        const handler = this.wireTransactionHandler();
+        return (msg: CommandResponse) => {
+            if (msg.payload?.payload?.id) {
+                handler({payload: msg.payload.payload} as CommandResponse);
+            }
+        }
+    }
+
+    // a failed specification is shown until it compiles again, the monitor then picks up the new specification.
+    specStatusHandler(): BusCallback<CommandResponse> {
+        return (msg: CommandResponse) => {
+            const status = msg.payload as SpecStatus;
+            if (status?.healthy == undefined) {
+                return; // not a specification status, like a change to the configuration.
+            }
+            const failed = this._specStatus && !this._specStatus.healthy;
+            this._specStatus = status;
+            if (status.healthy && failed) {
+                this.requestSpec();
+            }
+        }
+    }
+
+    specStatusNotice(): TemplateResult {
+        if (!this._specStatus || this._specStatus.healthy) {
+            return html``;
+        }
+        return html`
+            <sl-alert variant="danger" open>
+                <sl-icon slot="icon" name="exclamation-octagon"></sl-icon>
+                The specification failed to compile, wiretap is still using the previous one.
+                ${this._specStatus.errors?.map((e: string) => html`<br/><code>${e}</code>`)}
+                <br/>
+                <sl-button @click=${this.reloadSpec} size="small" variant="danger" outline>Reload</sl-button>
+            </sl-alert>`
+    }
+
+    // wiretap drops its oldest transactions once it keeps too many, so reports, exports and backfill are
+    // missing them. The monitor is told, it keeps its own history.
+    retentionHandler(): BusCallback<CommandResponse> {
+        return (msg: CommandResponse) => {
+            this._retention = msg.payload as RetentionStatus;
+        }
+    }
+
+    retentionNotice(): TemplateResult {
+        if (!this._retention || this._retention.evicted <= 0) {
+            return html``;
+        }
+        let reason = "to stay within its limits";
+        if (this._retention.reason) {
+            reason = "to stay within '" + this._retention.reason + "'";
+        }
+        return html`
+            <sl-alert variant="warning" open closable @sl-after-hide=${() => this._retention = null}>
+                <sl-icon slot="icon" name="exclamation-triangle"></sl-icon>
+                wiretap has dropped its ${this._retention.evicted} oldest transactions ${reason}, and is keeping
+                the last ${this._retention.retained}. Reports, exports and newly connected monitors only have what
+                is left.
+            </sl-alert>`
+    }
+
     wireTransactionHandler(): BusCallback {
         return (msg: CommandResponse) => {
             const wiretapMessage = msg.payload as HttpTransaction
@@ -308,11 +472,19 @@ export class WiretapComponent extends LitElement {
                 return constructedTransaction
             }
 
-            if (existingTransaction && wiretapMessage.httpResponse) {
+            // responses can arrive with their request, and transactions already in history can arrive again
+            // (backfilled), so a response is only counted once.
+            const countResponse = () => {
                 this.responseCount++;
                 if (wiretapMessage.responseValidation && wiretapMessage.responseValidation.length > 0) {
                     this.violatedTransactions++
                 }
+            }
+
+            if (existingTransaction && wiretapMessage.httpResponse) {
+                if (!existingTransaction.httpResponse) {
+                    countResponse();
+                }
                 existingTransaction.httpResponse = Object.assign(new HttpResponse(), wiretapMessage?.httpResponse);
                 existingTransaction.responseValidation = wiretapMessage.responseValidation;
                 this._httpTransactionStore.set(existingTransaction.id, existingTransaction)
@@ -327,6 +499,11 @@ export class WiretapComponent extends LitElement {
             } else if (!existingTransaction && wiretapMessage.httpRequest) {
                 this.requestCount++;
                 const constructedTransaction = createTransaction();
+                if (wiretapMessage.httpResponse) {
+                    countResponse();
+                    constructedTransaction.httpResponse = Object.assign(new HttpResponse(), wiretapMessage.httpResponse);
+                    constructedTransaction.responseValidation = wiretapMessage.responseValidation;
+                }
                 this._httpTransactionStore.set(constructedTransaction.id, constructedTransaction)
 
             }
@@ -393,6 +570,8 @@ export class WiretapComponent extends LitElement {
                             noSpec>
                     </wiretap-header>
                 </pb33f-header>
+                ${this.specStatusNotice()}
+                ${this.retentionNotice()}
                 ${transaction}`
         }
         return html`
@@ -407,7 +586,8 @@ export class WiretapComponent extends LitElement {
                 </wiretap-header>
 
             </pb33f-header>
-
+            ${this.specStatusNotice()}
+            ${this.retentionNotice()}
             ${transaction}`
     }
 }
\ No newline at end of file
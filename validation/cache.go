@@ -0,0 +1,162 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// DefaultCacheSize is the number of request validation results kept by a caching validator.
+const DefaultCacheSize = 4096
+
+// cachingValidator remembers the results of request validation, so identical requests are only validated once.
+// Responses are always validated.
+type cachingValidator struct {
+	HttpValidator
+	doc     *v3.Document
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	lock    sync.Mutex
+}
+
+type cachedResult struct {
+	key        string
+	valid      bool
+	violations []*errors.ValidationError
+}
+
+// NewCachingValidator wraps a validator with a cache of request validation results, keyed by the fingerprint
+// of a request. The least recently used results are dropped when the cache is full.
+func NewCachingValidator(validator HttpValidator, doc *v3.Document, size int) HttpValidator {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &cachingValidator{
+		HttpValidator: validator,
+		doc:           doc,
+		size:          size,
+		entries:       make(map[string]*list.Element),
+		order:         list.New(),
+	}
+}
+
+func (cv *cachingValidator) ValidateHttpRequest(request *http.Request) (bool, []*errors.ValidationError) {
+	key := RequestFingerprint(request, cv.doc)
+	if key == "" {
+		return cv.HttpValidator.ValidateHttpRequest(request)
+	}
+
+	cv.lock.Lock()
+	if element, ok := cv.entries[key]; ok {
+		cv.order.MoveToFront(element)
+		result := element.Value.(*cachedResult)
+		cv.lock.Unlock()
+		return result.valid, append([]*errors.ValidationError(nil), result.violations...)
+	}
+	cv.lock.Unlock()
+
+	valid, violations := cv.HttpValidator.ValidateHttpRequest(request)
+
+	cv.lock.Lock()
+	defer cv.lock.Unlock()
+	if _, ok := cv.entries[key]; !ok {
+		cv.entries[key] = cv.order.PushFront(&cachedResult{key: key, valid: valid,
+			violations: append([]*errors.ValidationError(nil), violations...)})
+		if cv.order.Len() > cv.size {
+			oldest := cv.order.Back()
+			cv.order.Remove(oldest)
+			delete(cv.entries, oldest.Value.(*cachedResult).key)
+		}
+	}
+	return valid, violations
+}
+
+// RequestFingerprint creates a key for everything about a request that validation looks at: the operation, the
+// path, the query, the values of the header and cookie parameters of the operation, the credentials sent for
+// security schemes, which headers and cookies are present and the body. Requests that don't map to an operation have no
+// fingerprint, an empty string is returned.
+func RequestFingerprint(request *http.Request, doc *v3.Document) string {
+	if doc == nil || request == nil {
+		return ""
+	}
+	pathItem, _, template := paths.FindPath(request, doc)
+	if pathItem == nil {
+		return ""
+	}
+	operation := helpers.ExtractOperation(request, pathItem)
+	if operation == nil {
+		return ""
+	}
+
+	var key strings.Builder
+	key.WriteString(request.Method + " " + template + "|" + request.URL.Path + "|" + request.URL.Query().Encode())
+
+	// the values of declared header and cookie parameters.
+	parameters := append(append([]*v3.Parameter(nil), pathItem.Parameters...), operation.Parameters...)
+	for _, p := range parameters {
+		switch strings.ToLower(p.In) {
+		case helpers.Header:
+			key.WriteString("|h:" + strings.ToLower(p.Name) + "=" + strings.Join(request.Header.Values(p.Name), ","))
+		case helpers.Cookie:
+			if c, err := request.Cookie(p.Name); err == nil {
+				key.WriteString("|c:" + p.Name + "=" + c.Value)
+			}
+		}
+	}
+	key.WriteString("|ct=" + request.Header.Get(helpers.ContentTypeHeader))
+
+	// credentials decide if security requirements are met, and which scopes a token grants.
+	credentials := request.Header.Get("Authorization")
+	if doc.Components != nil && doc.Components.SecuritySchemes != nil {
+		for pair := doc.Components.SecuritySchemes.First(); pair != nil; pair = pair.Next() {
+			if scheme := pair.Value(); strings.EqualFold(scheme.Type, "apiKey") {
+				switch strings.ToLower(scheme.In) {
+				case helpers.Header:
+					credentials += "|" + request.Header.Get(scheme.Name)
+				case helpers.Cookie:
+					if c, err := request.Cookie(scheme.Name); err == nil {
+						credentials += "|" + c.Value
+					}
+				}
+			}
+		}
+	}
+	credentialSum := sha256.Sum256([]byte(credentials))
+	key.WriteString("|cr=" + hex.EncodeToString(credentialSum[:]))
+
+	// the shape of the headers and cookies.
+	names := make([]string, 0, len(request.Header))
+	for name := range request.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	for _, c := range request.Cookies() {
+		names = append(names, "cookie:"+c.Name)
+	}
+	sort.Strings(names)
+	key.WriteString("|" + strings.Join(names, ","))
+
+	if request.Body != nil {
+		body, _ := io.ReadAll(request.Body)
+		_ = request.Body.Close()
+		request.Body = io.NopCloser(bytes.NewBuffer(body))
+		sum := sha256.Sum256(body)
+		key.WriteString("|" + hex.EncodeToString(sum[:]))
+	}
+	return key.String()
+}
@@ -0,0 +1,177 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var cacheSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    post:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+        - name: X-Tenant
+          in: header
+          schema:
+            type: string
+            enum: [cats, dogs]
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+      responses:
+        '200':
+          description: OK`
+
+// countingValidator counts the requests it's asked to validate.
+type countingValidator struct {
+	HttpValidator
+	calls atomic.Int32
+}
+
+func (cv *countingValidator) ValidateHttpRequest(request *http.Request) (bool, []*errors.ValidationError) {
+	cv.calls.Add(1)
+	return cv.HttpValidator.ValidateHttpRequest(request)
+}
+
+func cacheDocument(t *testing.T) *v3.Document {
+	d, err := libopenapi.NewDocument([]byte(cacheSpec))
+	require.NoError(t, err)
+	compiled, errs := d.BuildV3Model()
+	require.Empty(t, errs)
+	return &compiled.Model
+}
+
+func cacheRequest(query, tenant, body string) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, "https://api.pb33f.io/pets"+query, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if tenant != "" {
+		r.Header.Set("X-Tenant", tenant)
+	}
+	return r
+}
+
+func TestCachingValidator_Hit(t *testing.T) {
+	doc := cacheDocument(t)
+	counting := &countingValidator{HttpValidator: NewHttpValidator(doc)}
+	cache := NewCachingValidator(counting, doc, 0)
+
+	valid, violations := cache.ValidateHttpRequest(cacheRequest("?limit=lots", "cats", `{"age":3}`))
+	assert.False(t, valid)
+	require.NotEmpty(t, violations)
+
+	// an identical request is answered from the cache, with the same violations.
+	request := cacheRequest("?limit=lots", "cats", `{"age":3}`)
+	cachedValid, cached := cache.ValidateHttpRequest(request)
+	assert.Equal(t, int32(1), counting.calls.Load())
+	assert.Equal(t, valid, cachedValid)
+	assert.Equal(t, violations, cached)
+
+	// the body is still there for whatever comes next.
+	body, _ := io.ReadAll(request.Body)
+	assert.Equal(t, `{"age":3}`, string(body))
+
+	// handing out results doesn't let callers change what's cached.
+	cached[0] = nil
+	_, again := cache.ValidateHttpRequest(cacheRequest("?limit=lots", "cats", `{"age":3}`))
+	assert.Equal(t, violations, again)
+}
+
+func TestCachingValidator_Miss(t *testing.T) {
+	doc := cacheDocument(t)
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		valid   bool
+	}{
+		{"different body", cacheRequest("?limit=1", "cats", `{"name":"dave"}`), true},
+		{"different header", cacheRequest("?limit=1", "birds", `{"age":3}`), false},
+		{"different query", cacheRequest("?limit=2", "cats", `{"age":3}`), false},
+		{"extra header", func() *http.Request {
+			r := cacheRequest("?limit=1", "cats", `{"age":3}`)
+			r.Header.Set("X-Extra", "yes")
+			return r
+		}(), false},
+		{"different content type", func() *http.Request {
+			r := cacheRequest("?limit=1", "cats", `{"age":3}`)
+			r.Header.Set("Content-Type", "text/plain")
+			return r
+		}(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counting := &countingValidator{HttpValidator: NewHttpValidator(doc)}
+			cache := NewCachingValidator(counting, doc, 0)
+			valid, _ := cache.ValidateHttpRequest(cacheRequest("?limit=1", "cats", `{"age":3}`))
+			assert.False(t, valid)
+
+			valid, _ = cache.ValidateHttpRequest(tt.request)
+			assert.Equal(t, int32(2), counting.calls.Load())
+			assert.Equal(t, tt.valid, valid)
+		})
+	}
+}
+
+func TestCachingValidator_Eviction(t *testing.T) {
+	doc := cacheDocument(t)
+	counting := &countingValidator{HttpValidator: NewHttpValidator(doc)}
+	cache := NewCachingValidator(counting, doc, 2)
+
+	for _, limit := range []string{"1", "2", "3", "1"} {
+		cache.ValidateHttpRequest(cacheRequest("?limit="+limit, "cats", `{"name":"dave"}`))
+	}
+	// the first request was dropped to make room for the third.
+	assert.Equal(t, int32(4), counting.calls.Load())
+}
+
+func TestCachingValidator_Concurrent(t *testing.T) {
+	doc := cacheDocument(t)
+	counting := &countingValidator{HttpValidator: NewHttpValidator(doc)}
+	cache := NewCachingValidator(counting, doc, 8)
+
+	// more distinct requests than the cache holds, from many workers at once.
+	var wg sync.WaitGroup
+	for worker := 0; worker < 16; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				limit := (worker + i) % 12
+				body := fmt.Sprintf(`{"name":"pet-%d"}`, limit)
+				if limit%2 == 1 {
+					body = `{"age":3}`
+				}
+				valid, violations := cache.ValidateHttpRequest(cacheRequest(fmt.Sprintf("?limit=%d", limit), "cats", body))
+				assert.Equal(t, limit%2 == 0, valid)
+				assert.Equal(t, limit%2 == 1, len(violations) > 0)
+			}
+		}(worker)
+	}
+	wg.Wait()
+	assert.Less(t, counting.calls.Load(), int32(16*50))
+}
@@ -0,0 +1,128 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// CustomValidationType is the validation type given to violations from custom validators that don't set one.
+const CustomValidationType = "custom"
+
+// MatchedOperation is the operation in the specification a request maps to. Operation is nil when the request
+// does not map to anything.
+type MatchedOperation struct {
+	Path      string
+	Method    string
+	Operation *v3.Operation
+}
+
+// CustomValidator adds rules of its own to validation, on top of the specification. For example, rules specific
+// to an organization, like "all list endpoints must paginate". Violations are reported like any other.
+//
+// Requests and responses can be read, their bodies are reset after every validator.
+type CustomValidator interface {
+	ValidateRequest(request *http.Request, operation *MatchedOperation) []*errors.ValidationError
+	ValidateResponse(request *http.Request, response *http.Response, operation *MatchedOperation) []*errors.ValidationError
+}
+
+var customValidators = struct {
+	validators map[string]CustomValidator
+	order      []string
+	lock       sync.RWMutex
+}{validators: make(map[string]CustomValidator)}
+
+// RegisterCustomValidator registers a validator under a name, call it from an init function of a package that's
+// compiled into wiretap. Registering a name again replaces the validator.
+func RegisterCustomValidator(name string, validator CustomValidator) {
+	customValidators.lock.Lock()
+	defer customValidators.lock.Unlock()
+	if _, ok := customValidators.validators[name]; !ok {
+		customValidators.order = append(customValidators.order, name)
+	}
+	customValidators.validators[name] = validator
+}
+
+// RegisteredCustomValidators returns every registered validator, in the order they were registered.
+func RegisteredCustomValidators() []CustomValidator {
+	customValidators.lock.RLock()
+	defer customValidators.lock.RUnlock()
+	validators := make([]CustomValidator, 0, len(customValidators.order))
+	for _, name := range customValidators.order {
+		validators = append(validators, customValidators.validators[name])
+	}
+	return validators
+}
+
+// MatchOperation finds the operation a request maps to.
+func MatchOperation(request *http.Request, doc *v3.Document) *MatchedOperation {
+	path, operation := LocateOperation(request, doc)
+	return &MatchedOperation{Path: path, Method: request.Method, Operation: operation}
+}
+
+// RunCustomRequestValidators runs validators against a request, and collects their violations.
+func RunCustomRequestValidators(validators []CustomValidator, request *http.Request,
+	operation *MatchedOperation) []*errors.ValidationError {
+
+	var violations []*errors.ValidationError
+	body := readBody(&request.Body)
+	for _, validator := range validators {
+		resetBody(&request.Body, body)
+		violations = append(violations, validator.ValidateRequest(request, operation)...)
+	}
+	resetBody(&request.Body, body)
+	return withCustomType(violations)
+}
+
+// RunCustomResponseValidators runs validators against a response, and collects their violations.
+func RunCustomResponseValidators(validators []CustomValidator, request *http.Request, response *http.Response,
+	operation *MatchedOperation) []*errors.ValidationError {
+
+	if response == nil {
+		return nil
+	}
+	var violations []*errors.ValidationError
+	requestBody := readBody(&request.Body)
+	responseBody := readBody(&response.Body)
+	for _, validator := range validators {
+		resetBody(&request.Body, requestBody)
+		resetBody(&response.Body, responseBody)
+		violations = append(violations, validator.ValidateResponse(request, response, operation)...)
+	}
+	resetBody(&request.Body, requestBody)
+	resetBody(&response.Body, responseBody)
+	return withCustomType(violations)
+}
+
+func withCustomType(violations []*errors.ValidationError) []*errors.ValidationError {
+	for _, v := range violations {
+		if v.ValidationType == "" {
+			v.ValidationType = CustomValidationType
+		}
+	}
+	return violations
+}
+
+func readBody(body *io.ReadCloser) []byte {
+	if *body == nil {
+		return nil
+	}
+	b, _ := io.ReadAll(*body)
+	_ = (*body).Close()
+	return b
+}
+
+func resetBody(body *io.ReadCloser, b []byte) {
+	if b == nil && *body == nil {
+		return
+	}
+	*body = io.NopCloser(bytes.NewReader(b))
+}
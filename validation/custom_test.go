@@ -0,0 +1,179 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hookHelperEnv makes the test binary act as a validator hook, see TestHookHelper.
+const hookHelperEnv = "WIRETAP_TEST_HOOK"
+
+// TestHookHelper isn't a test, it's a validator hook. Hook tests run the test binary again as a hook, the way it
+// behaves is given after '--' on the command line.
+func TestHookHelper(t *testing.T) {
+	if os.Getenv(hookHelperEnv) == "" {
+		return
+	}
+	mode := os.Args[len(os.Args)-1]
+	var input HookInput
+	_ = json.NewDecoder(os.Stdin).Decode(&input)
+	switch mode {
+	case "echo":
+		operation := ""
+		if input.Operation != nil {
+			operation = input.Operation.OperationId
+		}
+		body := input.Request.Body
+		if input.Response != nil {
+			body = fmt.Sprintf("%d %s", input.Response.StatusCode, input.Response.Body)
+		}
+		_ = json.NewEncoder(os.Stdout).Encode([]*errors.ValidationError{{
+			Message: fmt.Sprintf("%s %s", input.Phase, operation),
+			Reason:  body,
+		}})
+	case "valid":
+	case "fail":
+		_, _ = fmt.Fprint(os.Stderr, "the hook broke")
+		os.Exit(3)
+	case "garbage":
+		_, _ = fmt.Fprint(os.Stdout, "everything is fine")
+	case "slow":
+		time.Sleep(10 * time.Second)
+	}
+	os.Exit(0)
+}
+
+// helperHook creates a hook that runs the test binary as a hook, behaving as asked.
+func helperHook(t *testing.T, mode string, timeout time.Duration) CustomValidator {
+	t.Setenv(hookHelperEnv, "1")
+	hook, err := NewExecHook(fmt.Sprintf("%s -test.run=^TestHookHelper$ -- %s", os.Args[0], mode), timeout)
+	require.NoError(t, err)
+	return hook
+}
+
+func hookOperationMatch() *MatchedOperation {
+	return &MatchedOperation{Path: "/pets", Method: http.MethodPost, Operation: &v3.Operation{OperationId: "createPet"}}
+}
+
+func TestNewExecHook(t *testing.T) {
+	_, err := NewExecHook("   ", 0)
+	assert.EqualError(t, err, "validator hook command is empty")
+
+	_, err = NewExecHook("wiretap-no-such-hook --strict", 0)
+	assert.Error(t, err)
+
+	hook, err := NewExecHook(os.Args[0], 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultHookTimeout, hook.(*execHook).timeout)
+}
+
+func TestExecHook(t *testing.T) {
+	request := func() *http.Request {
+		r, _ := http.NewRequest(http.MethodPost, "https://api.pb33f.io/pets", strings.NewReader(`{"name":"dave"}`))
+		return r
+	}
+
+	tests := []struct {
+		mode    string
+		message string
+		reason  string
+	}{
+		{"echo", "request createPet", `{"name":"dave"}`},
+		{"valid", "", ""},
+		{"fail", "failed", "exit status 3 the hook broke"},
+		{"garbage", "failed", "the output is not a list of violations"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			hook := helperHook(t, tt.mode, 0)
+			violations := RunCustomRequestValidators([]CustomValidator{hook}, request(), hookOperationMatch())
+			if tt.message == "" {
+				assert.Empty(t, violations)
+				return
+			}
+			require.Len(t, violations, 1)
+			assert.Contains(t, violations[0].Message, tt.message)
+			assert.Contains(t, violations[0].Reason, tt.reason)
+			assert.Equal(t, CustomValidationType, violations[0].ValidationType)
+			if tt.message == "failed" {
+				assert.Equal(t, "hook", violations[0].ValidationSubType)
+			}
+		})
+	}
+}
+
+func TestExecHook_Response(t *testing.T) {
+	hook := helperHook(t, "echo", 0)
+	r, _ := http.NewRequest(http.MethodPost, "https://api.pb33f.io/pets", strings.NewReader(`{"name":"dave"}`))
+	response := &http.Response{StatusCode: http.StatusCreated, Header: http.Header{},
+		Body: io.NopCloser(strings.NewReader(`{"id":1}`))}
+
+	violations := RunCustomResponseValidators([]CustomValidator{hook}, r, response, hookOperationMatch())
+	require.Len(t, violations, 1)
+	assert.Equal(t, "response createPet", violations[0].Message)
+	assert.Equal(t, `201 {"id":1}`, violations[0].Reason)
+
+	// both bodies are still there for whatever comes next.
+	body, _ := io.ReadAll(response.Body)
+	assert.Equal(t, `{"id":1}`, string(body))
+	body, _ = io.ReadAll(r.Body)
+	assert.Equal(t, `{"name":"dave"}`, string(body))
+}
+
+func TestExecHook_Timeout(t *testing.T) {
+	hook := helperHook(t, "slow", 200*time.Millisecond)
+	r, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets", nil)
+
+	started := time.Now()
+	violations := hook.ValidateRequest(r, hookOperationMatch())
+	assert.Less(t, time.Since(started), 5*time.Second, "slow hooks are killed")
+	require.Len(t, violations, 1)
+	assert.Equal(t, "hook", violations[0].ValidationSubType)
+	assert.Contains(t, violations[0].Message, "failed")
+}
+
+// bodyRule is a compiled in validator, it reports requests without a body and reads the whole body to do it.
+type bodyRule struct{}
+
+func (bodyRule) ValidateRequest(request *http.Request, _ *MatchedOperation) []*errors.ValidationError {
+	body, _ := io.ReadAll(request.Body)
+	if len(body) == 0 {
+		return []*errors.ValidationError{{Message: "a body is required"}}
+	}
+	return nil
+}
+
+func (bodyRule) ValidateResponse(*http.Request, *http.Response, *MatchedOperation) []*errors.ValidationError {
+	return nil
+}
+
+func TestRegisterCustomValidator(t *testing.T) {
+	RegisterCustomValidator("test-body", bodyRule{})
+	RegisterCustomValidator("test-body", bodyRule{}) // registered again, it's replaced rather than run twice.
+	validators := RegisteredCustomValidators()
+	require.Len(t, validators, 1)
+
+	// every validator sees the whole body, even after another has read it.
+	r, _ := http.NewRequest(http.MethodPost, "https://api.pb33f.io/pets", strings.NewReader(`{"name":"dave"}`))
+	assert.Empty(t, RunCustomRequestValidators(append(validators, bodyRule{}), r, hookOperationMatch()))
+
+	r, _ = http.NewRequest(http.MethodPost, "https://api.pb33f.io/pets", strings.NewReader(""))
+	violations := RunCustomRequestValidators(validators, r, hookOperationMatch())
+	require.Len(t, violations, 1)
+	assert.Equal(t, CustomValidationType, violations[0].ValidationType)
+}
@@ -0,0 +1,318 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/schema_validation"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// MultipartFormData is the media type of multipart form bodies, used for file uploads.
+const MultipartFormData = "multipart/form-data"
+
+// FormURLEncoded is the media type of URL encoded form bodies.
+const FormURLEncoded = "application/x-www-form-urlencoded"
+
+// formValidator adds validation of multipart/form-data and application/x-www-form-urlencoded request bodies,
+// which are not validated by libopenapi-validator (only JSON bodies are).
+type formValidator struct {
+	HttpValidator
+	doc *v3.Document
+}
+
+func (fv *formValidator) ValidateHttpRequest(request *http.Request) (bool, []*errors.ValidationError) {
+	valid, validationErrors := fv.HttpValidator.ValidateHttpRequest(request)
+	if formErrors := ValidateFormRequest(request, fv.doc); len(formErrors) > 0 {
+		return false, append(validationErrors, formErrors...)
+	}
+	return valid, validationErrors
+}
+
+// formField is a single value of a form, a part of a multipart body or a URL encoded key / value pair.
+type formField struct {
+	filename    string
+	contentType string
+	value       string
+}
+
+// ValidateFormRequest validates a form body against the schema of the operation it is sent to. Fields are decoded
+// into the types of their properties (values of array properties are collected), and the decoded form is validated
+// against the schema like a JSON body. Parts of a multipart body are also checked against the content types of their
+// `encoding`, and properties that are binary strings must be sent as files. Anything that isn't a form is ignored.
+func ValidateFormRequest(request *http.Request, doc *v3.Document) []*errors.ValidationError {
+	if request == nil || request.Body == nil {
+		return nil
+	}
+	contentType, params, err := mime.ParseMediaType(request.Header.Get(helpers.ContentTypeHeader))
+	if err != nil || (contentType != MultipartFormData && contentType != FormURLEncoded) {
+		return nil
+	}
+	_, operation := LocateOperation(request, doc)
+	if operation == nil || operation.RequestBody == nil || operation.RequestBody.Content == nil {
+		return nil
+	}
+	mt := operation.RequestBody.Content.GetOrZero(contentType)
+	if mt == nil || mt.Schema == nil {
+		return nil
+	}
+	schema := mt.Schema.Schema()
+	if schema == nil {
+		return nil
+	}
+
+	body, _ := io.ReadAll(request.Body)
+	_ = request.Body.Close()
+	request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	var fields map[string][]*formField
+	if contentType == MultipartFormData {
+		fields, err = readMultipartParts(body, params["boundary"])
+	} else {
+		fields, err = readURLEncodedFields(body)
+	}
+	if err != nil {
+		return []*errors.ValidationError{formError(request, mt,
+			fmt.Sprintf("%s request body cannot be read", contentType),
+			fmt.Sprintf("The body sent to '%s' is malformed: %s", request.URL.Path, err.Error()),
+			fmt.Sprintf("Ensure the body is encoded as %s", contentType))}
+	}
+
+	var validationErrors []*errors.ValidationError
+	decoded := make(map[string]any, len(fields))
+	for name, sent := range fields {
+		var property *base.Schema
+		if schema.Properties != nil {
+			if proxy := schema.Properties.GetOrZero(name); proxy != nil {
+				property = proxy.Schema()
+			}
+		}
+		var encoding *v3.Encoding
+		if mt.Encoding != nil {
+			encoding = mt.Encoding.GetOrZero(name)
+		}
+		if contentType == MultipartFormData {
+			validationErrors = append(validationErrors, checkParts(request, mt, name, property, encoding, sent)...)
+		}
+		value, duplicated := decodeField(property, encoding, sent)
+		if duplicated {
+			validationErrors = append(validationErrors, formError(request, mt,
+				fmt.Sprintf("request body contains the field '%s' more than once", name),
+				fmt.Sprintf("The field '%s' was sent %d times, but the schema is not an array", name, len(sent)),
+				fmt.Sprintf("Send the field '%s' once, or change the schema to an array", name)))
+		}
+		decoded[name] = value
+	}
+
+	_, schemaErrors := schema_validation.NewSchemaValidator().ValidateSchemaObject(schema, decoded)
+	for _, v := range schemaErrors {
+		v.ValidationType = helpers.RequestBodyValidation
+		v.ValidationSubType = helpers.Schema
+		v.Message = fmt.Sprintf("%s request body for '%s' failed to validate schema", request.Method, request.URL.Path)
+		v.Reason = fmt.Sprintf("The %s body does not match the schema of the operation", contentType)
+		if len(v.SchemaValidationErrors) > 0 {
+			v.Reason = fmt.Sprintf("%s: %s", v.Reason, v.SchemaValidationErrors[0].Reason)
+		}
+		validationErrors = append(validationErrors, v)
+	}
+	return validationErrors
+}
+
+// checkParts checks the parts of a multipart body sent for a property: their content types must be allowed by the
+// encoding of the property, and binary properties must be sent as files.
+func checkParts(request *http.Request, mt *v3.MediaType, name string, property *base.Schema,
+	encoding *v3.Encoding, sent []*formField) []*errors.ValidationError {
+
+	var validationErrors []*errors.ValidationError
+	itemSchema := property
+	if schemaType(property) == helpers.Array {
+		itemSchema = nil
+		if property.Items != nil && property.Items.IsA() {
+			itemSchema = property.Items.A.Schema()
+		}
+	}
+	var allowed string
+	if encoding != nil {
+		allowed = encoding.ContentType
+	}
+	for _, part := range sent {
+		if allowed != "" && !contentTypeAllowed(part.contentType, allowed) {
+			validationErrors = append(validationErrors, formError(request, mt,
+				fmt.Sprintf("multipart part '%s' has an unsupported content type", name),
+				fmt.Sprintf("The part '%s' was sent as '%s', but only '%s' is allowed",
+					name, part.contentType, allowed),
+				fmt.Sprintf("Send the part '%s' as one of '%s'", name, allowed)))
+		}
+		if isBinary(itemSchema) && part.filename == "" {
+			validationErrors = append(validationErrors, formError(request, mt,
+				fmt.Sprintf("multipart part '%s' is not a file", name),
+				fmt.Sprintf("The part '%s' is binary, but it was sent without a filename", name),
+				fmt.Sprintf("Send the part '%s' as a file, with a filename in its Content-Disposition", name)))
+		}
+	}
+	return validationErrors
+}
+
+// decodeField converts the values sent for a field into the type of its property, so the form can be validated
+// like JSON. Values that can't be converted are left as strings, schema validation reports them. If a field that
+// isn't an array was sent more than once, the first value is used.
+func decodeField(property *base.Schema, encoding *v3.Encoding, sent []*formField) (any, bool) {
+	if schemaType(property) != helpers.Array {
+		return decodeValue(property, sent[0]), len(sent) > 1
+	}
+	var itemSchema *base.Schema
+	if property.Items != nil && property.Items.IsA() {
+		itemSchema = property.Items.A.Schema()
+	}
+	items := make([]any, 0, len(sent))
+	for _, field := range sent {
+		for _, value := range splitValue(encoding, field) {
+			items = append(items, decodeValue(itemSchema, &formField{
+				filename: field.filename, contentType: field.contentType, value: value}))
+		}
+	}
+	return items, false
+}
+
+// splitValue splits a value of an array that isn't exploded, using the delimiter of its style.
+func splitValue(encoding *v3.Encoding, field *formField) []string {
+	if field.filename != "" || encoding == nil || encoding.Explode == nil || *encoding.Explode {
+		return []string{field.value}
+	}
+	delimiter := ","
+	switch encoding.Style {
+	case helpers.SpaceDelimited:
+		delimiter = " "
+	case helpers.PipeDelimited:
+		delimiter = "|"
+	}
+	return strings.Split(field.value, delimiter)
+}
+
+func decodeValue(schema *base.Schema, field *formField) any {
+	if field.filename != "" {
+		return field.filename // files are binary, the name stands in for the content.
+	}
+	switch schemaType(schema) {
+	case helpers.Integer:
+		if n, err := strconv.ParseInt(field.value, 10, 64); err == nil {
+			return float64(n)
+		}
+	case helpers.Number:
+		if n, err := strconv.ParseFloat(field.value, 64); err == nil {
+			return n
+		}
+	case helpers.Boolean:
+		if b, err := strconv.ParseBool(field.value); err == nil {
+			return b
+		}
+	case helpers.Object, helpers.Array:
+		var v any
+		if err := json.Unmarshal([]byte(field.value), &v); err == nil {
+			return v
+		}
+	}
+	return field.value
+}
+
+func readMultipartParts(body []byte, boundary string) (map[string][]*formField, error) {
+	if boundary == "" {
+		return nil, fmt.Errorf("no boundary is set")
+	}
+	parts := make(map[string][]*formField)
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := &formField{filename: part.FileName(), contentType: part.Header.Get(helpers.ContentTypeHeader)}
+		if field.filename == "" {
+			value, _ := io.ReadAll(io.LimitReader(part, 1<<20))
+			field.value = string(value)
+		}
+		if field.contentType == "" {
+			field.contentType = "text/plain"
+			if field.filename != "" {
+				field.contentType = "application/octet-stream"
+			}
+		}
+		parts[part.FormName()] = append(parts[part.FormName()], field)
+	}
+}
+
+func readURLEncodedFields(body []byte) (map[string][]*formField, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string][]*formField, len(values))
+	for name, sent := range values {
+		for _, value := range sent {
+			fields[name] = append(fields[name], &formField{contentType: "text/plain", value: value})
+		}
+	}
+	return fields, nil
+}
+
+func schemaType(schema *base.Schema) string {
+	if schema == nil || len(schema.Type) == 0 {
+		return ""
+	}
+	return schema.Type[0]
+}
+
+// isBinary checks if a schema describes file content, a string with a binary format.
+func isBinary(schema *base.Schema) bool {
+	return schemaType(schema) == helpers.String && schema.Format == "binary"
+}
+
+// contentTypeAllowed checks a content type against a comma separated list of content types, which may contain
+// wildcards (e.g. image/*).
+func contentTypeAllowed(contentType, allowed string) bool {
+	ct, _, _ := mime.ParseMediaType(contentType)
+	for _, a := range strings.Split(allowed, ",") {
+		a = strings.TrimSpace(a)
+		if a == "*/*" || strings.EqualFold(a, ct) {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(ct, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func formError(request *http.Request, mt *v3.MediaType, message, reason, howToFix string) *errors.ValidationError {
+	ve := &errors.ValidationError{
+		ValidationType:    helpers.RequestBodyValidation,
+		ValidationSubType: helpers.Schema,
+		Message:           fmt.Sprintf("%s request body for '%s' failed to validate: %s", request.Method, request.URL.Path, message),
+		Reason:            reason,
+		HowToFix:          howToFix,
+		Context:           mt.Schema,
+	}
+	if low := mt.GoLow(); low != nil && low.Schema.KeyNode != nil {
+		ve.SpecLine = low.Schema.KeyNode.Line
+		ve.SpecCol = low.Schema.KeyNode.Column
+	}
+	return ve
+}
@@ -0,0 +1,238 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var formSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    post:
+      requestBody:
+        content:
+          application/x-www-form-urlencoded:
+            schema:
+              type: object
+              required: [name, age]
+              properties:
+                name:
+                  type: string
+                age:
+                  type: integer
+                vaccinated:
+                  type: boolean
+                weight:
+                  type: number
+                tags:
+                  type: array
+                  items:
+                    type: string
+                toys:
+                  type: array
+                  items:
+                    type: integer
+            encoding:
+              toys:
+                style: form
+                explode: false
+      responses:
+        '200':
+          description: created
+  /photos:
+    post:
+      requestBody:
+        content:
+          multipart/form-data:
+            schema:
+              type: object
+              required: [photo]
+              properties:
+                caption:
+                  type: string
+                photo:
+                  type: string
+                  format: binary
+                thumbnails:
+                  type: array
+                  items:
+                    type: string
+                    format: binary
+                meta:
+                  type: object
+                  properties:
+                    width:
+                      type: integer
+            encoding:
+              photo:
+                contentType: image/png, image/jpeg
+              thumbnails:
+                contentType: image/*
+      responses:
+        '200':
+          description: uploaded`
+
+func formDocument(t *testing.T) *v3.Document {
+	d, err := libopenapi.NewDocument([]byte(formSpec))
+	require.NoError(t, err)
+	compiled, errs := d.BuildV3Model()
+	require.Empty(t, errs)
+	return &compiled.Model
+}
+
+func formRequest(body string) *http.Request {
+	request, _ := http.NewRequest(http.MethodPost, "https://api.pb33f.io/pets", strings.NewReader(body))
+	request.Header.Set("Content-Type", FormURLEncoded)
+	return request
+}
+
+// formPart is a part of a multipart body, a file if it has a filename.
+type formPart struct {
+	name, filename, contentType, value string
+}
+
+func multipartRequest(t *testing.T, parts ...formPart) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, p := range parts {
+		header := make(textproto.MIMEHeader)
+		disposition := `form-data; name="` + p.name + `"`
+		if p.filename != "" {
+			disposition += `; filename="` + p.filename + `"`
+		}
+		header.Set("Content-Disposition", disposition)
+		if p.contentType != "" {
+			header.Set("Content-Type", p.contentType)
+		}
+		w, err := writer.CreatePart(header)
+		require.NoError(t, err)
+		_, _ = w.Write([]byte(p.value))
+	}
+	require.NoError(t, writer.Close())
+	request, _ := http.NewRequest(http.MethodPost, "https://api.pb33f.io/photos", &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func formReasons(t *testing.T, request *http.Request, doc *v3.Document) []string {
+	var reasons []string
+	for _, e := range ValidateFormRequest(request, doc) {
+		reasons = append(reasons, e.Reason)
+	}
+	return reasons
+}
+
+func TestValidateFormRequest_URLEncoded(t *testing.T) {
+	doc := formDocument(t)
+
+	tests := []struct {
+		name    string
+		body    string
+		invalid string
+	}{
+		{"valid", "name=fido&age=3&vaccinated=true&weight=12.5", ""},
+		{"required field missing", "name=fido", "missing properties: 'age'"},
+		{"integer", "name=fido&age=three", "expected integer, but got string"},
+		{"boolean", "name=fido&age=3&vaccinated=maybe", "expected boolean, but got string"},
+		{"number", "name=fido&age=3&weight=heavy", "expected number, but got string"},
+		{"exploded array", "name=fido&age=3&tags=good&tags=small", ""},
+		{"array sent once", "name=fido&age=3&tags=good", ""},
+		{"array not exploded", "name=fido&age=3&toys=1,2,3", ""},
+		{"array not exploded, wrong item", "name=fido&age=3&toys=1,ball", "expected integer, but got string"},
+		{"field sent twice", "name=fido&name=rex&age=3", "The field 'name' was sent 2 times"},
+		{"malformed", "name=%zz&age=3", "is malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons := formReasons(t, formRequest(tt.body), doc)
+			if tt.invalid == "" {
+				assert.Empty(t, reasons)
+				return
+			}
+			require.Len(t, reasons, 1)
+			assert.Contains(t, reasons[0], tt.invalid)
+		})
+	}
+
+	// the body can still be read once it's been validated.
+	request := formRequest("name=fido&age=3")
+	ValidateFormRequest(request, doc)
+	assert.NoError(t, request.ParseForm())
+	assert.Equal(t, "fido", request.PostForm.Get("name"))
+}
+
+func TestValidateFormRequest_Multipart(t *testing.T) {
+	doc := formDocument(t)
+	photo := formPart{name: "photo", filename: "fido.png", contentType: "image/png", value: "\x89PNG"}
+
+	tests := []struct {
+		name    string
+		parts   []formPart
+		invalid []string
+	}{
+		{"valid", []formPart{photo, {name: "caption", value: "fido"}}, nil},
+		{"required part missing", []formPart{{name: "caption", value: "fido"}}, []string{"missing properties: 'photo'"}},
+		{"binary sent as a field", []formPart{{name: "photo", contentType: "image/png", value: "fido"}},
+			[]string{"The part 'photo' is binary, but it was sent without a filename"}},
+		{"content type not allowed", []formPart{{name: "photo", filename: "fido.gif", contentType: "image/gif"}},
+			[]string{"The part 'photo' was sent as 'image/gif', but only 'image/png, image/jpeg' is allowed"}},
+		{"files without a content type are octet streams", []formPart{{name: "photo", filename: "fido.png"}},
+			[]string{"The part 'photo' was sent as 'application/octet-stream'"}},
+		{"array of files", []formPart{photo,
+			{name: "thumbnails", filename: "small.png", contentType: "image/png"},
+			{name: "thumbnails", filename: "tiny.webp", contentType: "image/webp"}}, nil},
+		{"array of files, wildcard not matched", []formPart{photo,
+			{name: "thumbnails", filename: "small.txt", contentType: "text/plain"}},
+			[]string{"The part 'thumbnails' was sent as 'text/plain', but only 'image/*' is allowed"}},
+		{"object part", []formPart{photo, {name: "meta", contentType: "application/json", value: `{"width":10}`}}, nil},
+		{"object part, wrong type", []formPart{photo,
+			{name: "meta", contentType: "application/json", value: `{"width":"wide"}`}},
+			[]string{"expected integer, but got string"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons := formReasons(t, multipartRequest(t, tt.parts...), doc)
+			require.Len(t, reasons, len(tt.invalid), reasons)
+			for i := range tt.invalid {
+				assert.Contains(t, reasons[i], tt.invalid[i])
+			}
+		})
+	}
+
+	// a multipart body without a boundary can't be read.
+	request := multipartRequest(t, photo)
+	request.Header.Set("Content-Type", MultipartFormData)
+	reasons := formReasons(t, request, doc)
+	require.Len(t, reasons, 1)
+	assert.Contains(t, reasons[0], "no boundary is set")
+}
+
+func TestValidateFormRequest_Ignored(t *testing.T) {
+	doc := formDocument(t)
+
+	// bodies that aren't forms, or aren't described as forms, are left alone.
+	request, _ := http.NewRequest(http.MethodPost, "https://api.pb33f.io/pets", strings.NewReader(`{"name":1}`))
+	request.Header.Set("Content-Type", "application/json")
+	assert.Empty(t, ValidateFormRequest(request, doc))
+
+	request, _ = http.NewRequest(http.MethodPost, "https://api.pb33f.io/photos", strings.NewReader("name=fido"))
+	request.Header.Set("Content-Type", FormURLEncoded)
+	assert.Empty(t, ValidateFormRequest(request, doc))
+
+	request, _ = http.NewRequest(http.MethodPost, "https://api.pb33f.io/toys", strings.NewReader("name=fido"))
+	request.Header.Set("Content-Type", FormURLEncoded)
+	assert.Empty(t, ValidateFormRequest(request, doc))
+}
@@ -0,0 +1,144 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/schema_validation"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// Response header violation subtypes.
+const (
+	ResponseHeaderMissing = "headerMissing"
+	ResponseHeaderSchema  = "headerSchema"
+)
+
+// responseHeaderValidator adds validation of the headers of responses, libopenapi-validator only checks bodies.
+type responseHeaderValidator struct {
+	HttpValidator
+	doc *v3.Document
+}
+
+func (hv *responseHeaderValidator) ValidateHttpResponse(request *http.Request, response *http.Response) (bool, []*errors.ValidationError) {
+	valid, validationErrors := hv.HttpValidator.ValidateHttpResponse(request, response)
+	if headerErrors := ValidateResponseHeaders(request, response, hv.doc); len(headerErrors) > 0 {
+		return false, append(validationErrors, headerErrors...)
+	}
+	return valid, validationErrors
+}
+
+// ValidateResponseHeaders checks a response carries the headers declared by the response it maps to. Required
+// headers must be present, and every header that is sent must match its schema (arrays and objects use the
+// 'simple' style). Content-Type is ignored, as the OpenAPI specification requires.
+func ValidateResponseHeaders(request *http.Request, response *http.Response, doc *v3.Document) []*errors.ValidationError {
+	if request == nil || response == nil {
+		return nil
+	}
+	_, operation := LocateOperation(request, doc)
+	definition := LocateResponse(operation, response.StatusCode)
+	if definition == nil || definition.Headers == nil {
+		return nil
+	}
+
+	var validationErrors []*errors.ValidationError
+	for pair := definition.Headers.First(); pair != nil; pair = pair.Next() {
+		name, header := pair.Key(), pair.Value()
+		if header == nil || strings.EqualFold(name, helpers.ContentTypeHeader) {
+			continue
+		}
+		values := response.Header.Values(name)
+		if len(values) == 0 {
+			if header.Required {
+				ve := &errors.ValidationError{
+					ValidationType:    helpers.ResponseBodyValidation,
+					ValidationSubType: ResponseHeaderMissing,
+					Message: fmt.Sprintf("%d response for '%s' is missing the header '%s'",
+						response.StatusCode, request.URL.Path, name),
+					Reason:   fmt.Sprintf("The header '%s' is required, but it was not sent", name),
+					HowToFix: fmt.Sprintf("Add the header '%s' to the response", name),
+					Context:  header,
+				}
+				if low := definition.GoLow(); low != nil && low.Headers.KeyNode != nil {
+					ve.SpecLine = low.Headers.KeyNode.Line
+					ve.SpecCol = low.Headers.KeyNode.Column
+				}
+				validationErrors = append(validationErrors, ve)
+			}
+			continue
+		}
+		if header.Schema == nil {
+			continue
+		}
+		schema := header.Schema.Schema()
+		if schema == nil {
+			continue
+		}
+		value := strings.Join(values, ",")
+		_, schemaErrors := schema_validation.NewSchemaValidator().
+			ValidateSchemaObject(schema, decodeHeader(schema, header.Explode, value))
+		for _, v := range schemaErrors {
+			v.ValidationType = helpers.ResponseBodyValidation
+			v.ValidationSubType = ResponseHeaderSchema
+			v.Message = fmt.Sprintf("%d response header '%s' for '%s' failed to validate schema",
+				response.StatusCode, name, request.URL.Path)
+			v.Reason = fmt.Sprintf("The value '%s' of the header '%s' does not match its schema", value, name)
+			if len(v.SchemaValidationErrors) > 0 {
+				v.Reason = fmt.Sprintf("%s: %s", v.Reason, v.SchemaValidationErrors[0].Reason)
+			}
+			v.HowToFix = fmt.Sprintf("Ensure the header '%s' matches its schema", name)
+			if low := header.GoLow(); low != nil && low.Schema.KeyNode != nil {
+				v.SpecLine = low.Schema.KeyNode.Line
+				v.SpecCol = low.Schema.KeyNode.Column
+			}
+			validationErrors = append(validationErrors, v)
+		}
+	}
+	return validationErrors
+}
+
+// decodeHeader converts a header value into the type of its schema, using the 'simple' style: arrays are comma
+// separated, objects are comma separated keys and values, or key=value pairs when exploded.
+func decodeHeader(schema *base.Schema, explode bool, value string) any {
+	switch schemaType(schema) {
+	case helpers.Array:
+		items := arrayItems(schema)
+		var decoded []any
+		for _, item := range strings.Split(value, ",") {
+			decoded = append(decoded, decodeValue(items, &formField{value: strings.TrimSpace(item)}))
+		}
+		return decoded
+	case helpers.Object:
+		parts := strings.Split(value, ",")
+		decoded := make(map[string]any)
+		property := func(key string) *base.Schema {
+			if schema.Properties != nil {
+				if proxy := schema.Properties.GetOrZero(key); proxy != nil {
+					return proxy.Schema()
+				}
+			}
+			return nil
+		}
+		if explode {
+			for _, part := range parts {
+				key, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+				decoded[key] = decodeValue(property(key), &formField{value: v})
+			}
+		} else {
+			for i := 0; i+1 < len(parts); i += 2 {
+				key := strings.TrimSpace(parts[i])
+				decoded[key] = decodeValue(property(key), &formField{value: strings.TrimSpace(parts[i+1])})
+			}
+		}
+		return decoded
+	}
+	return decodeValue(schema, &formField{value: strings.TrimSpace(value)})
+}
@@ -0,0 +1,127 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var headersSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: pets
+          headers:
+            X-Rate-Limit:
+              required: true
+              schema:
+                type: integer
+                maximum: 100
+            Content-Type:
+              required: true
+              schema:
+                type: integer
+            X-Tags:
+              schema:
+                type: array
+                items:
+                  type: integer
+            X-Page:
+              schema:
+                type: object
+                properties:
+                  size:
+                    type: integer
+                  last:
+                    type: boolean
+            X-Page-Exploded:
+              explode: true
+              schema:
+                type: object
+                properties:
+                  size:
+                    type: integer
+                  last:
+                    type: boolean
+        '404':
+          description: not found`
+
+func headersDocument(t *testing.T) *v3.Document {
+	d, err := libopenapi.NewDocument([]byte(headersSpec))
+	require.NoError(t, err)
+	compiled, errs := d.BuildV3Model()
+	require.Empty(t, errs)
+	return &compiled.Model
+}
+
+func TestValidateResponseHeaders(t *testing.T) {
+	doc := headersDocument(t)
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets", nil)
+
+	tests := []struct {
+		name    string
+		headers http.Header
+		subType string
+		reason  string
+	}{
+		{"valid", http.Header{"X-Rate-Limit": {"10"}}, "", ""},
+		{"required header missing", http.Header{}, ResponseHeaderMissing,
+			"The header 'X-Rate-Limit' is required, but it was not sent"},
+		{"integer", http.Header{"X-Rate-Limit": {"lots"}}, ResponseHeaderSchema,
+			"The value 'lots' of the header 'X-Rate-Limit' does not match its schema"},
+		{"integer out of range", http.Header{"X-Rate-Limit": {"500"}}, ResponseHeaderSchema,
+			"The value '500' of the header 'X-Rate-Limit' does not match its schema"},
+		{"content type ignored", http.Header{"X-Rate-Limit": {"10"}, "Content-Type": {"application/json"}}, "", ""},
+		{"array", http.Header{"X-Rate-Limit": {"10"}, "X-Tags": {"1, 2,3"}}, "", ""},
+		{"array, wrong item", http.Header{"X-Rate-Limit": {"10"}, "X-Tags": {"1,two"}}, ResponseHeaderSchema,
+			"The value '1,two' of the header 'X-Tags' does not match its schema"},
+		{"repeated header lines are joined", http.Header{"X-Rate-Limit": {"10"}, "X-Tags": {"1", "2"}}, "", ""},
+		{"repeated header lines, wrong item", http.Header{"X-Rate-Limit": {"10"}, "X-Tags": {"1", "two"}},
+			ResponseHeaderSchema, "The value '1,two' of the header 'X-Tags' does not match its schema"},
+		{"object", http.Header{"X-Rate-Limit": {"10"}, "X-Page": {"size,20,last,true"}}, "", ""},
+		{"object, wrong type", http.Header{"X-Rate-Limit": {"10"}, "X-Page": {"size,big,last,true"}},
+			ResponseHeaderSchema, "The value 'size,big,last,true' of the header 'X-Page' does not match its schema"},
+		{"object exploded", http.Header{"X-Rate-Limit": {"10"}, "X-Page-Exploded": {"size=20,last=false"}}, "", ""},
+		{"object exploded, wrong type", http.Header{"X-Rate-Limit": {"10"}, "X-Page-Exploded": {"size=20,last=maybe"}},
+			ResponseHeaderSchema, "expected boolean, but got string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &http.Response{StatusCode: http.StatusOK, Header: tt.headers}
+			violations := ValidateResponseHeaders(request, response, doc)
+			if tt.subType == "" {
+				assert.Empty(t, violations)
+				return
+			}
+			require.Len(t, violations, 1)
+			assert.Equal(t, tt.subType, violations[0].ValidationSubType)
+			assert.Contains(t, violations[0].Reason, tt.reason)
+		})
+	}
+
+	// responses without headers declare nothing to check.
+	assert.Empty(t, ValidateResponseHeaders(request, &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}}, doc))
+}
+
+func TestDecodeHeader(t *testing.T) {
+	doc := headersDocument(t)
+	headers := doc.Paths.PathItems.GetOrZero("/pets").Get.Responses.Codes.GetOrZero("200").Headers
+	schema := func(name string) *v3.Header { return headers.GetOrZero(name) }
+
+	assert.Equal(t, float64(10), decodeHeader(schema("X-Rate-Limit").Schema.Schema(), false, " 10 "))
+	assert.Equal(t, []any{float64(1), float64(2)}, decodeHeader(schema("X-Tags").Schema.Schema(), false, "1, 2"))
+	assert.Equal(t, map[string]any{"size": float64(20), "last": true},
+		decodeHeader(schema("X-Page").Schema.Schema(), false, "size,20,last,true"))
+	assert.Equal(t, map[string]any{"size": float64(20), "last": true},
+		decodeHeader(schema("X-Page-Exploded").Schema.Schema(), true, "size=20, last=true"))
+}
@@ -0,0 +1,153 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+)
+
+// DefaultHookTimeout is how long a validator hook has to respond, before it's killed.
+const DefaultHookTimeout = 5 * time.Second
+
+// Validation phases, sent to validator hooks.
+const (
+	HookPhaseRequest  = "request"
+	HookPhaseResponse = "response"
+)
+
+// HookInput is written (as JSON) to the standard input of a validator hook.
+type HookInput struct {
+	Phase     string         `json:"phase"`
+	Operation *HookOperation `json:"operation,omitempty"`
+	Request   *HookMessage   `json:"request"`
+	Response  *HookMessage   `json:"response,omitempty"`
+}
+
+// HookOperation is the operation a request maps to.
+type HookOperation struct {
+	Path        string `json:"path"`
+	Method      string `json:"method"`
+	OperationId string `json:"operationId,omitempty"`
+}
+
+// HookMessage is a request or a response.
+type HookMessage struct {
+	Method     string              `json:"method,omitempty"`
+	URL        string              `json:"url,omitempty"`
+	StatusCode int                 `json:"statusCode,omitempty"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       string              `json:"body,omitempty"`
+}
+
+// execHook is a custom validator that runs a command for every request and response. The command reads a
+// HookInput from its standard input, and writes a JSON array of violations to its standard output.
+type execHook struct {
+	command []string
+	timeout time.Duration
+}
+
+// NewExecHook creates a custom validator that runs a command, the command line is split into arguments on
+// whitespace. It's killed if it takes longer than the timeout.
+func NewExecHook(command string, timeout time.Duration) (CustomValidator, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("validator hook command is empty")
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	return &execHook{command: args, timeout: timeout}, nil
+}
+
+func (h *execHook) ValidateRequest(request *http.Request, operation *MatchedOperation) []*errors.ValidationError {
+	return h.run(&HookInput{
+		Phase:     HookPhaseRequest,
+		Operation: hookOperation(operation),
+		Request:   hookRequest(request),
+	})
+}
+
+func (h *execHook) ValidateResponse(request *http.Request, response *http.Response,
+	operation *MatchedOperation) []*errors.ValidationError {
+
+	input := &HookInput{
+		Phase:     HookPhaseResponse,
+		Operation: hookOperation(operation),
+		Request:   hookRequest(request),
+	}
+	if response != nil {
+		input.Response = &HookMessage{StatusCode: response.StatusCode, Headers: response.Header}
+		if response.Body != nil {
+			b, _ := io.ReadAll(response.Body)
+			input.Response.Body = string(b)
+		}
+	}
+	return h.run(input)
+}
+
+func (h *execHook) run(input *HookInput) []*errors.ValidationError {
+	payload, _ := json.Marshal(input)
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.command[0], h.command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return []*errors.ValidationError{h.failure(fmt.Sprintf("%s %s", err.Error(), strings.TrimSpace(stderr.String())))}
+	}
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil
+	}
+	var violations []*errors.ValidationError
+	if err = json.Unmarshal(out, &violations); err != nil {
+		return []*errors.ValidationError{h.failure(fmt.Sprintf("the output is not a list of violations: %s", err.Error()))}
+	}
+	return violations
+}
+
+// failure is reported when a hook cannot be run, or its output cannot be read. Failing quietly would look
+// like everything is valid.
+func (h *execHook) failure(reason string) *errors.ValidationError {
+	return &errors.ValidationError{
+		Message:           fmt.Sprintf("Validator hook '%s' failed", strings.Join(h.command, " ")),
+		Reason:            strings.TrimSpace(reason),
+		ValidationType:    CustomValidationType,
+		ValidationSubType: "hook",
+		HowToFix:          "Ensure the hook writes a JSON array of violations to its standard output, and exits with 0",
+	}
+}
+
+func hookOperation(operation *MatchedOperation) *HookOperation {
+	if operation == nil || operation.Operation == nil {
+		return nil
+	}
+	return &HookOperation{Path: operation.Path, Method: operation.Method, OperationId: operation.Operation.OperationId}
+}
+
+func hookRequest(request *http.Request) *HookMessage {
+	message := &HookMessage{Method: request.Method, URL: request.URL.String(), Headers: request.Header}
+	if request.Body != nil {
+		b, _ := io.ReadAll(request.Body)
+		message.Body = string(b)
+	}
+	return message
+}
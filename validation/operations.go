@@ -0,0 +1,43 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"net/http"
+	"strconv"
+)
+
+// LocateOperation finds the path template (e.g. /pets/{petId}) and the operation a request maps to in the
+// specification. If the request does not map to anything, an empty string and nil are returned.
+func LocateOperation(request *http.Request, doc *v3.Document) (string, *v3.Operation) {
+	if doc == nil || request == nil {
+		return "", nil
+	}
+	pathItem, _, pathValue := paths.FindPath(request, doc)
+	if pathItem == nil {
+		return "", nil
+	}
+	return pathValue, helpers.ExtractOperation(request, pathItem)
+}
+
+// LocateResponse finds the response defined for a status code by an operation, codes are matched exactly first,
+// then by range (e.g. 2XX), then by the default response. If nothing matches, nil is returned.
+func LocateResponse(operation *v3.Operation, statusCode int) *v3.Response {
+	if operation == nil || operation.Responses == nil {
+		return nil
+	}
+	code := strconv.Itoa(statusCode)
+	if operation.Responses.Codes != nil {
+		for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx"} {
+			if r := operation.Responses.Codes.GetOrZero(key); r != nil {
+				return r
+			}
+		}
+	}
+	return operation.Responses.Default
+}
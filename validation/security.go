@@ -0,0 +1,263 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// SecurityValidationType is the validation type of security violations, it's the same one libopenapi-validator uses.
+const SecurityValidationType = "security"
+
+// Security violation subtypes.
+const (
+	SecurityMissing = "missing"
+	SecurityScopes  = "scopes"
+)
+
+// securityValidator replaces the security checks of libopenapi-validator, which require every security requirement
+// of an operation to be met, only look for the presence of an Authorization header, and ignore scopes.
+type securityValidator struct {
+	HttpValidator
+	doc *v3.Document
+}
+
+func (sv *securityValidator) ValidateHttpRequest(request *http.Request) (bool, []*errors.ValidationError) {
+	_, validationErrors := sv.HttpValidator.ValidateHttpRequest(request)
+	var kept []*errors.ValidationError
+	for _, v := range validationErrors {
+		if v.ValidationType != SecurityValidationType {
+			kept = append(kept, v)
+		}
+	}
+	kept = append(kept, ValidateSecurity(request, sv.doc)...)
+	return len(kept) == 0, kept
+}
+
+// ValidateSecurity checks a request meets the security requirements of the operation it's sent to, or the
+// requirements of the specification if the operation has none of its own. Any one of the requirements must be met,
+// and every scheme of a requirement must be satisfied: API keys must be sent, HTTP schemes need an Authorization
+// header with the right scheme, and OAuth2 / OpenID Connect need a bearer token. When the token is a JWT, it must
+// carry the scopes (in its 'scope' or 'scp' claim) the requirement asks for.
+func ValidateSecurity(request *http.Request, doc *v3.Document) []*errors.ValidationError {
+	_, operation := LocateOperation(request, doc)
+	if operation == nil {
+		return nil
+	}
+	requirements := operation.Security
+	if requirements == nil {
+		requirements = doc.Security
+	}
+	if len(requirements) == 0 {
+		return nil
+	}
+
+	var failures [][]*errors.ValidationError
+	for _, requirement := range requirements {
+		if requirement == nil || requirement.ContainsEmptyRequirement ||
+			requirement.Requirements == nil || requirement.Requirements.Len() == 0 {
+			return nil // security is optional.
+		}
+		var failed []*errors.ValidationError
+		for pair := requirement.Requirements.First(); pair != nil; pair = pair.Next() {
+			var scheme *v3.SecurityScheme
+			if doc.Components != nil && doc.Components.SecuritySchemes != nil {
+				scheme = doc.Components.SecuritySchemes.GetOrZero(pair.Key())
+			}
+			if scheme == nil {
+				continue // an undefined scheme is a problem with the specification, not the request.
+			}
+			if v := checkScheme(request, pair.Key(), scheme, pair.Value(), requirement); v != nil {
+				failed = append(failed, v)
+			}
+		}
+		if len(failed) == 0 {
+			return nil
+		}
+		failures = append(failures, failed)
+	}
+
+	if len(failures) == 1 {
+		return failures[0]
+	}
+	var reasons []string
+	for _, failed := range failures {
+		var messages []string
+		for _, v := range failed {
+			messages = append(messages, v.Message)
+		}
+		reasons = append(reasons, strings.Join(messages, " and "))
+	}
+	line, col := requirementLocation(requirements[0])
+	return []*errors.ValidationError{{
+		Message:           "Request does not meet any of the security requirements",
+		Reason:            fmt.Sprintf("None of the %d security requirements are met: %s", len(failures), strings.Join(reasons, "; or ")),
+		ValidationType:    SecurityValidationType,
+		ValidationSubType: SecurityMissing,
+		SpecLine:          line,
+		SpecCol:           col,
+		HowToFix:          "Authenticate the request using one of the security requirements of the operation",
+	}}
+}
+
+func checkScheme(request *http.Request, name string, scheme *v3.SecurityScheme, scopes []string,
+	requirement *base.SecurityRequirement) *errors.ValidationError {
+
+	line, col := requirementLocation(requirement)
+	missing := func(message, howToFix string) *errors.ValidationError {
+		return &errors.ValidationError{
+			Message:           message,
+			Reason:            fmt.Sprintf("The request does not satisfy the '%s' security scheme", name),
+			ValidationType:    SecurityValidationType,
+			ValidationSubType: SecurityMissing,
+			SpecLine:          line,
+			SpecCol:           col,
+			HowToFix:          howToFix,
+		}
+	}
+
+	authorization := strings.TrimSpace(request.Header.Get("Authorization"))
+	authScheme, credentials, _ := strings.Cut(authorization, " ")
+	credentials = strings.TrimSpace(credentials)
+
+	var token string
+	switch strings.ToLower(scheme.Type) {
+	case "apikey":
+		found := false
+		switch strings.ToLower(scheme.In) {
+		case "header":
+			found = request.Header.Get(scheme.Name) != ""
+		case "query":
+			found = request.URL.Query().Get(scheme.Name) != ""
+		case "cookie":
+			_, err := request.Cookie(scheme.Name)
+			found = err == nil
+		}
+		if !found {
+			return missing(fmt.Sprintf("API key '%s' not found in %s", scheme.Name, scheme.In),
+				fmt.Sprintf("Send the API key '%s' in the %s of the request", scheme.Name, scheme.In))
+		}
+		return nil
+	case "http":
+		expected := strings.ToLower(scheme.Scheme)
+		if authorization == "" || !strings.EqualFold(authScheme, expected) || credentials == "" {
+			return missing(fmt.Sprintf("Authorization header with a '%s' scheme not found", scheme.Scheme),
+				fmt.Sprintf("Add an 'Authorization: %s <credentials>' header to the request", httpSchemeName(expected)))
+		}
+		if expected == "basic" {
+			if decoded, err := base64.StdEncoding.DecodeString(credentials); err != nil || !strings.Contains(string(decoded), ":") {
+				return missing("Basic credentials are not valid",
+					"Encode the credentials as base64 'username:password'")
+			}
+		}
+		if expected != "bearer" {
+			return nil
+		}
+		token = credentials
+	case "oauth2", "openidconnect":
+		if authorization == "" || !strings.EqualFold(authScheme, "bearer") || credentials == "" {
+			return missing(fmt.Sprintf("Bearer token for '%s' not found", name),
+				"Add an 'Authorization: Bearer <token>' header to the request")
+		}
+		token = credentials
+	case "mutualtls":
+		if request.TLS == nil || len(request.TLS.PeerCertificates) == 0 {
+			return missing("Client certificate not found", "Send a client certificate with the request")
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	// scopes can only be checked when the token carries them.
+	if len(scopes) == 0 {
+		return nil
+	}
+	granted, ok := tokenScopes(token)
+	if !ok {
+		return nil
+	}
+	var absent []string
+	for _, scope := range scopes {
+		if !granted[scope] {
+			absent = append(absent, scope)
+		}
+	}
+	if len(absent) == 0 {
+		return nil
+	}
+	return &errors.ValidationError{
+		Message: fmt.Sprintf("Token is missing %s for '%s'", shared.Pluralize(len(absent), "a scope", "scopes"), name),
+		Reason: fmt.Sprintf("The token does not grant the %s required by the operation: %s",
+			shared.Pluralize(len(absent), "scope", "scopes"), strings.Join(absent, ", ")),
+		ValidationType:    SecurityValidationType,
+		ValidationSubType: SecurityScopes,
+		SpecLine:          line,
+		SpecCol:           col,
+		HowToFix:          fmt.Sprintf("Request a token that grants: %s", strings.Join(scopes, ", ")),
+	}
+}
+
+// tokenScopes reads the scopes granted by a JWT, from a space separated 'scope' claim or a 'scp' claim (a list or
+// a space separated string). Opaque tokens, and tokens carrying neither claim, say nothing about their scopes,
+// false is returned.
+func tokenScopes(token string) (map[string]bool, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, false
+	}
+	var claims map[string]any
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, false
+	}
+	granted := make(map[string]bool)
+	found := false
+	for _, claim := range []string{"scope", "scp"} {
+		if _, ok := claims[claim]; ok {
+			found = true
+		}
+		switch v := claims[claim].(type) {
+		case string:
+			for _, s := range strings.Fields(v) {
+				granted[s] = true
+			}
+		case []any:
+			for _, s := range v {
+				if str, ok := s.(string); ok {
+					granted[str] = true
+				}
+			}
+		}
+	}
+	return granted, found
+}
+
+func httpSchemeName(scheme string) string {
+	if scheme == "" {
+		return "<scheme>"
+	}
+	return strings.ToUpper(scheme[:1]) + scheme[1:]
+}
+
+func requirementLocation(requirement *base.SecurityRequirement) (int, int) {
+	if requirement == nil || requirement.GoLow() == nil || requirement.GoLow().Requirements.ValueNode == nil {
+		return 0, 0
+	}
+	node := requirement.GoLow().Requirements.ValueNode
+	return node.Line, node.Column
+}
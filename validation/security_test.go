@@ -0,0 +1,196 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var securitySpec = `openapi: 3.1.0
+security:
+  - bearer: []
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: pets
+    post:
+      security:
+        - oauth: [pets:write]
+      responses:
+        '200':
+          description: created
+  /toys:
+    get:
+      security:
+        - headerKey: []
+        - queryKey: []
+          cookieKey: []
+      responses:
+        '200':
+          description: toys
+  /public:
+    get:
+      security: []
+      responses:
+        '200':
+          description: public
+  /optional:
+    get:
+      security:
+        - {}
+        - basic: []
+      responses:
+        '200':
+          description: optional
+  /admin:
+    get:
+      security:
+        - basic: []
+      responses:
+        '200':
+          description: admin
+    delete:
+      security:
+        - oauth: [pets:write, pets:admin]
+      responses:
+        '200':
+          description: removed
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+    basic:
+      type: http
+      scheme: basic
+    oauth:
+      type: oauth2
+      flows:
+        clientCredentials:
+          tokenUrl: https://api.pb33f.io/token
+          scopes:
+            pets:write: write pets
+            pets:admin: administer pets
+    headerKey:
+      type: apiKey
+      in: header
+      name: X-API-Key
+    queryKey:
+      type: apiKey
+      in: query
+      name: api_key
+    cookieKey:
+      type: apiKey
+      in: cookie
+      name: session`
+
+func securityDocument(t *testing.T) *v3.Document {
+	d, err := libopenapi.NewDocument([]byte(securitySpec))
+	require.NoError(t, err)
+	compiled, errs := d.BuildV3Model()
+	require.Empty(t, errs)
+	return &compiled.Model
+}
+
+// jwt builds an unsigned token carrying the claims, signatures aren't checked.
+func jwt(claims map[string]any) string {
+	payload, _ := json.Marshal(claims)
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+func TestValidateSecurity(t *testing.T) {
+	doc := securityDocument(t)
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("fido:s3cret"))
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		headers map[string]string
+		subType string
+		reason  string
+	}{
+		{"document requirement", http.MethodGet, "/pets", map[string]string{"Authorization": "Bearer abc"}, "", ""},
+		{"document requirement missing", http.MethodGet, "/pets", nil, SecurityMissing,
+			"does not satisfy the 'bearer' security scheme"},
+		{"wrong http scheme", http.MethodGet, "/pets", map[string]string{"Authorization": basic}, SecurityMissing,
+			"does not satisfy the 'bearer' security scheme"},
+		{"no security", http.MethodGet, "/public", nil, "", ""},
+		{"optional, not sent", http.MethodGet, "/optional", nil, "", ""},
+		{"basic", http.MethodGet, "/admin", map[string]string{"Authorization": basic}, "", ""},
+		{"basic, malformed base64", http.MethodGet, "/admin", map[string]string{"Authorization": "Basic %%%"},
+			SecurityMissing, "does not satisfy the 'basic' security scheme"},
+		{"basic, no password", http.MethodGet, "/admin",
+			map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("fido"))},
+			SecurityMissing, "does not satisfy the 'basic' security scheme"},
+		{"api key in a header", http.MethodGet, "/toys", map[string]string{"X-API-Key": "abc"}, "", ""},
+		{"api keys in query and cookie", http.MethodGet, "/toys?api_key=abc",
+			map[string]string{"Cookie": "session=abc"}, "", ""},
+		{"api key in query, cookie missing", http.MethodGet, "/toys?api_key=abc", nil, SecurityMissing,
+			"None of the 2 security requirements are met: API key 'X-API-Key' not found in header; " +
+				"or API key 'session' not found in cookie"},
+		{"api keys missing", http.MethodGet, "/toys", nil, SecurityMissing,
+			"API key 'api_key' not found in query and API key 'session' not found in cookie"},
+		{"opaque token", http.MethodPost, "/pets", map[string]string{"Authorization": "Bearer abc"}, "", ""},
+		{"scope claim", http.MethodPost, "/pets",
+			map[string]string{"Authorization": "Bearer " + jwt(map[string]any{"scope": "pets:read pets:write"})}, "", ""},
+		{"scp claim list", http.MethodPost, "/pets",
+			map[string]string{"Authorization": "Bearer " + jwt(map[string]any{"scp": []string{"pets:write"}})}, "", ""},
+		{"scp claim string", http.MethodPost, "/pets",
+			map[string]string{"Authorization": "Bearer " + jwt(map[string]any{"scp": "pets:write"})}, "", ""},
+		{"scope missing", http.MethodPost, "/pets",
+			map[string]string{"Authorization": "Bearer " + jwt(map[string]any{"scope": "pets:read"})},
+			SecurityScopes, "does not grant the scope required by the operation: pets:write"},
+		{"scopes from both claims", http.MethodDelete, "/admin",
+			map[string]string{"Authorization": "Bearer " + jwt(map[string]any{"scope": "pets:write", "scp": []string{"pets:admin"}})},
+			"", ""},
+		{"one of two scopes missing", http.MethodDelete, "/admin",
+			map[string]string{"Authorization": "Bearer " + jwt(map[string]any{"scope": "pets:write"})},
+			SecurityScopes, "does not grant the scope required by the operation: pets:admin"},
+		{"token without scope claims", http.MethodDelete, "/admin",
+			map[string]string{"Authorization": "Bearer " + jwt(map[string]any{"sub": "fido"})}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, _ := http.NewRequest(tt.method, "https://api.pb33f.io"+tt.url, nil)
+			for k, v := range tt.headers {
+				request.Header.Set(k, v)
+			}
+			violations := ValidateSecurity(request, doc)
+			if tt.subType == "" {
+				assert.Empty(t, violations)
+				return
+			}
+			require.Len(t, violations, 1)
+			assert.Equal(t, SecurityValidationType, violations[0].ValidationType)
+			assert.Equal(t, tt.subType, violations[0].ValidationSubType)
+			assert.Contains(t, violations[0].Reason, tt.reason)
+		})
+	}
+}
+
+func TestTokenScopes(t *testing.T) {
+	granted, ok := tokenScopes(jwt(map[string]any{"scope": "a b", "scp": []any{"c", 1}}))
+	assert.True(t, ok)
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, granted)
+
+	// tokens that say nothing about scopes can't be checked.
+	_, ok = tokenScopes(jwt(map[string]any{"sub": "fido"}))
+	assert.False(t, ok)
+	_, ok = tokenScopes("opaque")
+	assert.False(t, ok)
+	_, ok = tokenScopes("a.%%%.c")
+	assert.False(t, ok)
+}
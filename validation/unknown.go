@@ -0,0 +1,164 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// Unknown parameter violation subtypes.
+const (
+	UnknownQueryParameter = "unknownQuery"
+	UnknownHeader         = "unknownHeader"
+)
+
+// StandardHeaders are sent by clients, proxies and browsers without being declared by specifications, they are
+// never reported as unknown. Headers starting with Sec- (set by browsers) or X-Wiretap- (set to control wiretap)
+// aren't either.
+var StandardHeaders = []string{
+	"Accept", "Accept-Charset", "Accept-Encoding", "Accept-Language", "Access-Control-Request-Headers",
+	"Access-Control-Request-Method", "Authorization", "Baggage", "Cache-Control", "Connection", "Content-Encoding",
+	"Content-Language", "Content-Length", "Content-Type", "Cookie", "Date", "DNT", "Expect", "Forwarded", "Host",
+	"If-Match", "If-Modified-Since", "If-None-Match", "If-Range", "If-Unmodified-Since", "Keep-Alive", "Origin",
+	"Pragma", "Proxy-Authorization", "Proxy-Connection", "Range", "Referer", "TE", "Traceparent", "Tracestate",
+	"Transfer-Encoding", "Upgrade", "User-Agent", "Via", "X-Forwarded-For", "X-Forwarded-Host",
+	"X-Forwarded-Proto", "X-Real-IP", "X-Request-ID",
+}
+
+// WiretapQueryParameters control wiretap (like picking the example to mock), for clients that cannot set headers.
+// They are never reported as unknown.
+var WiretapQueryParameters = []string{"__example"}
+
+// unknownParameterValidator adds detection of query parameters and headers a specification doesn't declare.
+type unknownParameterValidator struct {
+	HttpValidator
+	doc     *v3.Document
+	allowed map[string]bool
+}
+
+// NewUnknownParameterValidator wraps a validator, so requests are also checked for query parameters and headers
+// the operation doesn't declare. The standard headers, and any headers allowed on top of them, are ignored.
+func NewUnknownParameterValidator(validator HttpValidator, doc *v3.Document, allowedHeaders []string) HttpValidator {
+	allowed := make(map[string]bool)
+	for _, h := range append(append([]string(nil), StandardHeaders...), allowedHeaders...) {
+		allowed[strings.ToLower(h)] = true
+	}
+	return &unknownParameterValidator{HttpValidator: validator, doc: doc, allowed: allowed}
+}
+
+func (uv *unknownParameterValidator) ValidateHttpRequest(request *http.Request) (bool, []*errors.ValidationError) {
+	valid, validationErrors := uv.HttpValidator.ValidateHttpRequest(request)
+	if unknown := ValidateUnknownParameters(request, uv.doc, uv.allowed); len(unknown) > 0 {
+		return false, append(validationErrors, unknown...)
+	}
+	return valid, validationErrors
+}
+
+// ValidateUnknownParameters reports the query parameters and headers of a request that aren't declared by the
+// operation it maps to (or its path). Header names in the allowed set (lower case) are ignored, as are API keys
+// used by security schemes, and anything sent to control wiretap. Query parameters of a deepObject or array (e.g. filter[name]) match the declared name.
+func ValidateUnknownParameters(request *http.Request, doc *v3.Document, allowed map[string]bool) []*errors.ValidationError {
+	if request == nil || doc == nil {
+		return nil
+	}
+	pathItem, _, _ := paths.FindPath(request, doc)
+	if pathItem == nil {
+		return nil
+	}
+	operation := helpers.ExtractOperation(request, pathItem)
+	if operation == nil {
+		return nil
+	}
+
+	queries, headers := make(map[string]bool), make(map[string]bool)
+	for _, p := range append(append([]*v3.Parameter(nil), pathItem.Parameters...), operation.Parameters...) {
+		if p == nil {
+			continue
+		}
+		switch strings.ToLower(p.In) {
+		case helpers.Query:
+			queries[p.Name] = true
+		case helpers.Header:
+			headers[strings.ToLower(p.Name)] = true
+		}
+	}
+	if doc.Components != nil && doc.Components.SecuritySchemes != nil {
+		for pair := doc.Components.SecuritySchemes.First(); pair != nil; pair = pair.Next() {
+			if scheme := pair.Value(); scheme != nil && strings.EqualFold(scheme.Type, "apiKey") {
+				switch strings.ToLower(scheme.In) {
+				case helpers.Query:
+					queries[scheme.Name] = true
+				case helpers.Header:
+					headers[strings.ToLower(scheme.Name)] = true
+				}
+			}
+		}
+	}
+
+	var line, col int
+	if low := operation.GoLow(); low != nil {
+		if low.Parameters.KeyNode != nil {
+			line, col = low.Parameters.KeyNode.Line, low.Parameters.KeyNode.Column
+		} else if low.KeyNode != nil {
+			line, col = low.KeyNode.Line, low.KeyNode.Column
+		}
+	}
+
+	var validationErrors []*errors.ValidationError
+	for _, name := range sortedKeys(request.URL.Query()) {
+		declared := queries[name]
+		if base, _, ok := strings.Cut(name, "["); ok {
+			declared = declared || queries[base]
+		}
+		if !declared && !slices.Contains(WiretapQueryParameters, name) {
+			validationErrors = append(validationErrors, &errors.ValidationError{
+				ValidationType:    helpers.ParameterValidation,
+				ValidationSubType: UnknownQueryParameter,
+				Message:           fmt.Sprintf("Query parameter '%s' is not defined", name),
+				Reason: fmt.Sprintf("The query parameter '%s' is not declared by the %s operation for '%s'",
+					name, request.Method, request.URL.Path),
+				SpecLine: line,
+				SpecCol:  col,
+				HowToFix: fmt.Sprintf("Stop sending the query parameter '%s', or declare it in the specification", name),
+			})
+		}
+	}
+	for _, name := range sortedKeys(request.Header) {
+		lower := strings.ToLower(name)
+		if headers[lower] || allowed[lower] || strings.HasPrefix(lower, "sec-") ||
+			strings.HasPrefix(lower, "x-wiretap-") {
+			continue
+		}
+		validationErrors = append(validationErrors, &errors.ValidationError{
+			ValidationType:    helpers.ParameterValidation,
+			ValidationSubType: UnknownHeader,
+			Message:           fmt.Sprintf("Header '%s' is not defined", name),
+			Reason: fmt.Sprintf("The header '%s' is not declared by the %s operation for '%s'",
+				name, request.Method, request.URL.Path),
+			SpecLine: line,
+			SpecCol:  col,
+			HowToFix: fmt.Sprintf("Stop sending the header '%s', declare it in the specification, or allow it", name),
+		})
+	}
+	return validationErrors
+}
+
+func sortedKeys(values map[string][]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
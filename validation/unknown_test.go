@@ -0,0 +1,121 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var unknownSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    parameters:
+      - name: X-Tenant
+        in: header
+        schema:
+          type: string
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+        - name: filter
+          in: query
+          style: deepObject
+          schema:
+            type: object
+        - name: X-Trace-Id
+          in: header
+          schema:
+            type: string
+      responses:
+        '200':
+          description: pets
+components:
+  securitySchemes:
+    headerKey:
+      type: apiKey
+      in: header
+      name: X-API-Key
+    queryKey:
+      type: apiKey
+      in: query
+      name: api_key`
+
+func unknownDocument(t *testing.T) *v3.Document {
+	d, err := libopenapi.NewDocument([]byte(unknownSpec))
+	require.NoError(t, err)
+	compiled, errs := d.BuildV3Model()
+	require.Empty(t, errs)
+	return &compiled.Model
+}
+
+func TestValidateUnknownParameters(t *testing.T) {
+	doc := unknownDocument(t)
+	allowed := map[string]bool{"accept": true, "x-allowed": true}
+
+	tests := []struct {
+		name    string
+		query   string
+		headers map[string]string
+		unknown []string
+	}{
+		{"declared", "limit=10", map[string]string{"X-Trace-Id": "1", "X-Tenant": "pb33f"}, nil},
+		{"declared deep object", "filter[name]=fido&filter[age]=2", nil, nil},
+		{"undeclared query", "limit=10&sort=name", nil, []string{"Query parameter 'sort' is not defined"}},
+		{"undeclared header", "", map[string]string{"X-Color": "red"}, []string{"Header 'X-Color' is not defined"}},
+		{"api keys", "api_key=s3cret", map[string]string{"X-API-Key": "s3cret"}, nil},
+		{"browser headers", "", map[string]string{"Sec-Fetch-Mode": "cors", "Sec-Ch-Ua": "wiretap"}, nil},
+		{"allowed headers", "", map[string]string{"Accept": "*/*", "X-Allowed": "yes"}, nil},
+		{"wiretap headers", "", map[string]string{"X-Wiretap-Example": "fido", "X-Wiretap-Variant": "b"}, nil},
+		{"wiretap query", "__example=fido", nil, nil},
+		{"many unknown", "b=1&a=2", map[string]string{"X-Color": "red"}, []string{
+			"Query parameter 'a' is not defined", "Query parameter 'b' is not defined", "Header 'X-Color' is not defined"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets?"+tt.query, nil)
+			for k, v := range tt.headers {
+				request.Header.Set(k, v)
+			}
+			var messages []string
+			for _, e := range ValidateUnknownParameters(request, doc, allowed) {
+				messages = append(messages, e.Message)
+			}
+			assert.Equal(t, tt.unknown, messages)
+		})
+	}
+
+	// paths and operations the specification doesn't have are left for the contract to report.
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/toys?sort=name", nil)
+	assert.Empty(t, ValidateUnknownParameters(request, doc, allowed))
+	request, _ = http.NewRequest(http.MethodDelete, "https://api.pb33f.io/pets?sort=name", nil)
+	assert.Empty(t, ValidateUnknownParameters(request, doc, allowed))
+}
+
+func TestNewUnknownParameterValidator(t *testing.T) {
+	doc := unknownDocument(t)
+	validator := NewUnknownParameterValidator(NewHttpValidator(doc), doc, []string{"X-Allowed"})
+
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/pets?limit=10", nil)
+	request.Header.Set("User-Agent", "wiretap")
+	request.Header.Set("X-Allowed", "yes")
+	valid, errs := validator.ValidateHttpRequest(request)
+	assert.True(t, valid)
+	assert.Empty(t, errs)
+
+	request.Header.Set("X-Color", "red")
+	valid, errs = validator.ValidateHttpRequest(request)
+	assert.False(t, valid)
+	require.Len(t, errs, 1)
+	assert.Equal(t, UnknownHeader, errs[0].ValidationSubType)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/pb33f/libopenapi-validator/errors"
 	"github.com/pb33f/libopenapi/datamodel/high/v3"
 	"net/http"
+	"sync"
 )
 
 type HttpValidator interface {
@@ -15,6 +16,32 @@ type HttpValidator interface {
 	ValidateHttpResponse(request *http.Request, response *http.Response) (bool, []*errors.ValidationError)
 }
 
+// NewHttpValidator creates the validator for a specification, libopenapi-validator with wiretap's own checks
+// layered on top of it.
 func NewHttpValidator(doc *v3.Document) HttpValidator {
-	return validator.NewValidatorFromV3Model(doc)
+	var v HttpValidator = &lockedValidator{HttpValidator: validator.NewValidatorFromV3Model(doc)}
+	v = &responseHeaderValidator{HttpValidator: v, doc: doc}
+	v = &xmlValidator{HttpValidator: v, doc: doc}
+	v = &formValidator{HttpValidator: v, doc: doc}
+	return &securityValidator{HttpValidator: v, doc: doc}
+}
+
+// lockedValidator lets one request or response through libopenapi-validator at a time, it keeps the path it found
+// for the request being validated on the validator, so it cannot be used by the validation pool all at once.
+type lockedValidator struct {
+	HttpValidator
+	lock sync.Mutex
+}
+
+func (lv *lockedValidator) ValidateHttpRequest(request *http.Request) (bool, []*errors.ValidationError) {
+	lv.lock.Lock()
+	defer lv.lock.Unlock()
+	return lv.HttpValidator.ValidateHttpRequest(request)
+}
+
+func (lv *lockedValidator) ValidateHttpResponse(request *http.Request,
+	response *http.Response) (bool, []*errors.ValidationError) {
+	lv.lock.Lock()
+	defer lv.lock.Unlock()
+	return lv.HttpValidator.ValidateHttpResponse(request, response)
 }
@@ -0,0 +1,71 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"net/http"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+// WebhookValidator validates calls made to webhook consumers against the webhooks of a specification (OpenAPI 3.1).
+// Webhooks are named rather than addressed by a path, so every webhook is validated as if it was a path of its own.
+type WebhookValidator struct {
+	validator HttpValidator
+	webhooks  *orderedmap.Map[string, *v3.PathItem]
+}
+
+// NewWebhookValidator creates a validator for the webhooks of a specification, nil if it doesn't define any.
+func NewWebhookValidator(doc *v3.Document) *WebhookValidator {
+	if doc == nil || doc.Webhooks == nil || doc.Webhooks.Len() == 0 {
+		return nil
+	}
+	webhooks := orderedmap.New[string, *v3.PathItem]()
+	for pair := doc.Webhooks.First(); pair != nil; pair = pair.Next() {
+		webhooks.Set("/"+pair.Key(), pair.Value())
+	}
+	webhookDoc := *doc
+	webhookDoc.Servers = nil
+	webhookDoc.Paths = &v3.Paths{PathItems: webhooks}
+	return &WebhookValidator{validator: NewHttpValidator(&webhookDoc), webhooks: doc.Webhooks}
+}
+
+// Has checks if a webhook is defined.
+func (wv *WebhookValidator) Has(name string) bool {
+	return wv != nil && wv.webhooks.GetOrZero(name) != nil
+}
+
+// For returns a validator for calls to a webhook.
+func (wv *WebhookValidator) For(name string) HttpValidator {
+	return &webhookCall{validator: wv.validator, path: "/" + name}
+}
+
+// webhookCall validates requests as calls to a single webhook, by validating them against its path.
+type webhookCall struct {
+	validator HttpValidator
+	path      string
+}
+
+func (wc *webhookCall) ValidateHttpRequest(request *http.Request) (bool, []*errors.ValidationError) {
+	r := wc.rewrite(request)
+	valid, validationErrors := wc.validator.ValidateHttpRequest(r)
+	request.Body = r.Body // validation reads the body, and puts back a copy.
+	return valid, validationErrors
+}
+
+func (wc *webhookCall) ValidateHttpResponse(request *http.Request, response *http.Response) (bool, []*errors.ValidationError) {
+	return wc.validator.ValidateHttpResponse(wc.rewrite(request), response)
+}
+
+// rewrite copies a request, addressed to the path of the webhook.
+func (wc *webhookCall) rewrite(request *http.Request) *http.Request {
+	r := request.Clone(request.Context())
+	r.Body = request.Body
+	r.URL.Path = wc.path
+	r.URL.RawPath = ""
+	return r
+}
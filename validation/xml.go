@@ -0,0 +1,333 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/schema_validation"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// xmlValidator adds validation of XML request and response bodies, libopenapi-validator only validates JSON.
+type xmlValidator struct {
+	HttpValidator
+	doc *v3.Document
+}
+
+func (xv *xmlValidator) ValidateHttpRequest(request *http.Request) (bool, []*errors.ValidationError) {
+	valid, validationErrors := xv.HttpValidator.ValidateHttpRequest(request)
+	if xmlErrors := ValidateXMLRequest(request, xv.doc); len(xmlErrors) > 0 {
+		return false, append(validationErrors, xmlErrors...)
+	}
+	return valid, validationErrors
+}
+
+func (xv *xmlValidator) ValidateHttpResponse(request *http.Request, response *http.Response) (bool, []*errors.ValidationError) {
+	valid, validationErrors := xv.HttpValidator.ValidateHttpResponse(request, response)
+	if xmlErrors := ValidateXMLResponse(request, response, xv.doc); len(xmlErrors) > 0 {
+		return false, append(validationErrors, xmlErrors...)
+	}
+	return valid, validationErrors
+}
+
+// IsXML checks if a media type is XML: application/xml, text/xml or a structured syntax like application/atom+xml.
+func IsXML(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// ValidateXMLRequest validates an XML request body against the schema of the operation it is sent to. The body is
+// converted into the structure the schema describes (following its `xml` hints) and validated like a JSON body.
+// Anything that isn't XML is ignored.
+func ValidateXMLRequest(request *http.Request, doc *v3.Document) []*errors.ValidationError {
+	if request == nil || request.Body == nil {
+		return nil
+	}
+	contentType, _, err := mime.ParseMediaType(request.Header.Get(helpers.ContentTypeHeader))
+	if err != nil || !IsXML(contentType) {
+		return nil
+	}
+	_, operation := LocateOperation(request, doc)
+	if operation == nil || operation.RequestBody == nil || operation.RequestBody.Content == nil {
+		return nil
+	}
+	mt := operation.RequestBody.Content.GetOrZero(contentType)
+	if mt == nil || mt.Schema == nil {
+		return nil
+	}
+
+	body, _ := io.ReadAll(request.Body)
+	_ = request.Body.Close()
+	request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	return validateXMLBody(body, mt, helpers.RequestBodyValidation,
+		fmt.Sprintf("%s request body for '%s'", request.Method, request.URL.Path))
+}
+
+// ValidateXMLResponse validates an XML response body against the schema of the response it maps to, responses are
+// matched by code first, then by range (e.g. 2XX), then by the default response. Anything that isn't XML is ignored.
+func ValidateXMLResponse(request *http.Request, response *http.Response, doc *v3.Document) []*errors.ValidationError {
+	if request == nil || response == nil || response.Body == nil {
+		return nil
+	}
+	contentType, _, err := mime.ParseMediaType(response.Header.Get(helpers.ContentTypeHeader))
+	if err != nil || !IsXML(contentType) {
+		return nil
+	}
+	_, operation := LocateOperation(request, doc)
+	definition := LocateResponse(operation, response.StatusCode)
+	if definition == nil || definition.Content == nil {
+		return nil
+	}
+	mt := definition.Content.GetOrZero(contentType)
+	if mt == nil || mt.Schema == nil {
+		return nil
+	}
+
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	return validateXMLBody(body, mt, helpers.ResponseBodyValidation,
+		fmt.Sprintf("%d response body for '%s'", response.StatusCode, request.URL.Path))
+}
+
+func validateXMLBody(body []byte, mt *v3.MediaType, validationType, subject string) []*errors.ValidationError {
+	schema := mt.Schema.Schema()
+	if schema == nil {
+		return nil
+	}
+	xmlError := func(message, reason, howToFix string) *errors.ValidationError {
+		ve := &errors.ValidationError{
+			ValidationType:    validationType,
+			ValidationSubType: helpers.Schema,
+			Message:           fmt.Sprintf("%s failed to validate: %s", subject, message),
+			Reason:            reason,
+			HowToFix:          howToFix,
+			Context:           mt.Schema,
+		}
+		if low := mt.GoLow(); low != nil && low.Schema.KeyNode != nil {
+			ve.SpecLine = low.Schema.KeyNode.Line
+			ve.SpecCol = low.Schema.KeyNode.Column
+		}
+		return ve
+	}
+
+	root, err := parseXML(body)
+	if err != nil {
+		return []*errors.ValidationError{xmlError("XML cannot be read",
+			fmt.Sprintf("The body is not well formed XML: %s", err.Error()), "Ensure the body is well formed XML")}
+	}
+
+	var validationErrors []*errors.ValidationError
+	if schema.XML != nil && schema.XML.Name != "" && schema.XML.Name != root.name {
+		validationErrors = append(validationErrors, xmlError(
+			fmt.Sprintf("root element '%s' is not expected", root.name),
+			fmt.Sprintf("The root element is '%s', but the schema names it '%s'", root.name, schema.XML.Name),
+			fmt.Sprintf("Rename the root element to '%s'", schema.XML.Name)))
+	}
+
+	_, schemaErrors := schema_validation.NewSchemaValidator().ValidateSchemaObject(schema, root.decode(schema))
+	for _, v := range schemaErrors {
+		v.ValidationType = validationType
+		v.ValidationSubType = helpers.Schema
+		v.Message = fmt.Sprintf("%s failed to validate schema", subject)
+		v.Reason = "The XML body does not match the schema"
+		if len(v.SchemaValidationErrors) > 0 {
+			v.Reason = fmt.Sprintf("%s: %s", v.Reason, v.SchemaValidationErrors[0].Reason)
+		}
+		validationErrors = append(validationErrors, v)
+	}
+	return validationErrors
+}
+
+// xmlElement is a parsed XML element, names are local (namespace prefixes are dropped).
+type xmlElement struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlElement
+	text     string
+}
+
+func parseXML(body []byte) (*xmlElement, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	var stack []*xmlElement
+	var root *xmlElement
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := &xmlElement{name: t.Name.Local, attrs: make(map[string]string)}
+			for _, attr := range t.Attr {
+				if attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" {
+					element.attrs[attr.Name.Local] = attr.Value
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, element)
+			} else if root == nil {
+				root = element
+			}
+			stack = append(stack, element)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("there is no root element")
+	}
+	return root, nil
+}
+
+// decode converts an element into the structure its schema describes, so it can be validated like JSON. Properties
+// are read from attributes or child elements using the names in their `xml` hints, arrays are read from repeated
+// elements, or from the children of a wrapping element when they are `wrapped`. Anything the schema doesn't
+// describe is kept, so it can be caught by `additionalProperties`.
+func (e *xmlElement) decode(schema *base.Schema) any {
+	switch schemaType(schema) {
+	case helpers.Object:
+		return e.decodeObject(schema)
+	case helpers.Array:
+		return decodeXMLArray(e.children, schema)
+	case "":
+		if len(e.children) > 0 || len(e.attrs) > 0 {
+			return e.decodeObject(schema)
+		}
+		return strings.TrimSpace(e.text)
+	}
+	return decodeValue(schema, &formField{value: strings.TrimSpace(e.text)})
+}
+
+func (e *xmlElement) decodeObject(schema *base.Schema) map[string]any {
+	object := make(map[string]any)
+	consumed := make(map[*xmlElement]bool)
+	usedAttrs := make(map[string]bool)
+
+	if schema != nil && schema.Properties != nil {
+		for pair := schema.Properties.First(); pair != nil; pair = pair.Next() {
+			property := pair.Value().Schema()
+			if property == nil {
+				continue
+			}
+			name := xmlName(pair.Key(), property)
+			if property.XML != nil && property.XML.Attribute {
+				if value, ok := e.attrs[name]; ok {
+					object[pair.Key()] = decodeValue(property, &formField{value: value})
+					usedAttrs[name] = true
+				}
+				continue
+			}
+			if schemaType(property) == helpers.Array {
+				var elements []*xmlElement
+				if property.XML != nil && property.XML.Wrapped {
+					wrapper := e.child(name, consumed)
+					if wrapper == nil {
+						continue
+					}
+					consumed[wrapper] = true
+					elements = wrapper.children
+				} else {
+					itemName := name
+					if items := arrayItems(property); items != nil && items.XML != nil && items.XML.Name != "" {
+						itemName = items.XML.Name
+					}
+					for _, child := range e.children {
+						if child.name == itemName && !consumed[child] {
+							consumed[child] = true
+							elements = append(elements, child)
+						}
+					}
+					if len(elements) == 0 {
+						continue
+					}
+				}
+				object[pair.Key()] = decodeXMLArray(elements, property)
+				continue
+			}
+			if child := e.child(name, consumed); child != nil {
+				consumed[child] = true
+				object[pair.Key()] = child.decode(property)
+			}
+		}
+	}
+
+	// whatever is left over isn't described by the schema.
+	for name, value := range e.attrs {
+		if !usedAttrs[name] {
+			if _, exists := object[name]; !exists {
+				object[name] = value
+			}
+		}
+	}
+	for _, child := range e.children {
+		if consumed[child] {
+			continue
+		}
+		value := child.decode(nil)
+		switch existing := object[child.name].(type) {
+		case nil:
+			object[child.name] = value
+		case []any:
+			object[child.name] = append(existing, value)
+		default:
+			object[child.name] = []any{existing, value}
+		}
+	}
+	return object
+}
+
+// child finds the first child element with a name that hasn't been read yet.
+func (e *xmlElement) child(name string, consumed map[*xmlElement]bool) *xmlElement {
+	for _, child := range e.children {
+		if child.name == name && !consumed[child] {
+			return child
+		}
+	}
+	return nil
+}
+
+func decodeXMLArray(elements []*xmlElement, schema *base.Schema) []any {
+	items := arrayItems(schema)
+	values := make([]any, 0, len(elements))
+	for _, element := range elements {
+		values = append(values, element.decode(items))
+	}
+	return values
+}
+
+func arrayItems(schema *base.Schema) *base.Schema {
+	if schema != nil && schema.Items != nil && schema.Items.IsA() {
+		return schema.Items.A.Schema()
+	}
+	return nil
+}
+
+// xmlName is the name of the element (or attribute) a property is read from, its `xml` name if it has one.
+func xmlName(property string, schema *base.Schema) string {
+	if schema != nil && schema.XML != nil && schema.XML.Name != "" {
+		return schema.XML.Name
+	}
+	return property
+}
@@ -0,0 +1,159 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package validation
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var xmlSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    post:
+      requestBody:
+        content:
+          application/xml:
+            schema:
+              type: object
+              xml:
+                name: pet
+              required: [id, name]
+              additionalProperties: false
+              properties:
+                id:
+                  type: integer
+                  xml:
+                    attribute: true
+                name:
+                  type: string
+                vaccinated:
+                  type: boolean
+                photoUrls:
+                  type: array
+                  xml:
+                    wrapped: true
+                    name: photos
+                  items:
+                    type: string
+                    xml:
+                      name: photo
+                tags:
+                  type: array
+                  items:
+                    type: string
+                    xml:
+                      name: tag
+      responses:
+        '200':
+          description: created
+          content:
+            application/atom+xml:
+              schema:
+                type: object
+                xml:
+                  name: feed
+                properties:
+                  title:
+                    type: string
+                  count:
+                    type: integer`
+
+func xmlDocument(t *testing.T) *v3.Document {
+	d, err := libopenapi.NewDocument([]byte(xmlSpec))
+	require.NoError(t, err)
+	compiled, errs := d.BuildV3Model()
+	require.Empty(t, errs)
+	return &compiled.Model
+}
+
+func xmlRequest(body string) *http.Request {
+	request, _ := http.NewRequest(http.MethodPost, "https://api.pb33f.io/pets", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	return request
+}
+
+func TestValidateXMLRequest(t *testing.T) {
+	doc := xmlDocument(t)
+
+	tests := []struct {
+		name    string
+		body    string
+		invalid []string
+	}{
+		{"valid", `<pet id="1"><name>fido</name><vaccinated>true</vaccinated></pet>`, nil},
+		{"root name", `<dog id="1"><name>fido</name></dog>`,
+			[]string{"The root element is 'dog', but the schema names it 'pet'"}},
+		{"attribute", `<pet id="one"><name>fido</name></pet>`, []string{"expected integer, but got string"}},
+		{"attribute missing", `<pet><name>fido</name></pet>`, []string{"missing properties: 'id'"}},
+		{"attribute sent as an element", `<pet><id>1</id><name>fido</name></pet>`,
+			[]string{"expected integer, but got string"}}, // read as text, it isn't described as an element.
+		{"wrapped array", `<pet id="1"><name>fido</name><photos><photo>a.png</photo><photo>b.png</photo></photos></pet>`, nil},
+		{"wrapped array, unwrapped", `<pet id="1"><name>fido</name><photo>a.png</photo></pet>`,
+			[]string{"additionalProperties 'photo' not allowed"}},
+		{"unwrapped array", `<pet id="1"><name>fido</name><tag>good</tag><tag>small</tag></pet>`, nil},
+		{"unwrapped array, wrong item name", `<pet id="1"><name>fido</name><tags>good</tags></pet>`,
+			[]string{"expected array, but got string"}},
+		{"namespaces", `<p:pet xmlns:p="https://pb33f.io/pets" xmlns="https://pb33f.io" p:id="1"><p:name>fido</p:name></p:pet>`, nil},
+		{"additional properties", `<pet id="1" color="brown"><name>fido</name></pet>`,
+			[]string{"additionalProperties 'color' not allowed"}},
+		{"wrong type", `<pet id="1"><name>fido</name><vaccinated>maybe</vaccinated></pet>`,
+			[]string{"expected boolean, but got string"}},
+		{"malformed", `<pet id="1"><name>fido</pet>`, []string{"The body is not well formed XML"}},
+		{"empty", ``, []string{"The body is not well formed XML: there is no root element"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reasons []string
+			for _, e := range ValidateXMLRequest(xmlRequest(tt.body), doc) {
+				reasons = append(reasons, e.Reason)
+			}
+			require.Len(t, reasons, len(tt.invalid), reasons)
+			for i := range tt.invalid {
+				assert.Contains(t, reasons[i], tt.invalid[i])
+			}
+		})
+	}
+
+	// JSON is left to the contract, and the body can still be read once it's been validated.
+	request, _ := http.NewRequest(http.MethodPost, "https://api.pb33f.io/pets", strings.NewReader(`{}`))
+	request.Header.Set("Content-Type", "application/json")
+	assert.Empty(t, ValidateXMLRequest(request, doc))
+
+	request = xmlRequest(`<pet id="1"><name>fido</name></pet>`)
+	ValidateXMLRequest(request, doc)
+	body, _ := io.ReadAll(request.Body)
+	assert.Equal(t, `<pet id="1"><name>fido</name></pet>`, string(body))
+}
+
+func TestValidateXMLResponse(t *testing.T) {
+	doc := xmlDocument(t)
+	respond := func(body string) *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/atom+xml"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+	}
+	request := xmlRequest(``)
+
+	assert.Empty(t, ValidateXMLResponse(request, respond(`<feed><title>pets</title><count>2</count></feed>`), doc))
+
+	violations := ValidateXMLResponse(request, respond(`<feed><title>pets</title><count>two</count></feed>`), doc)
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0].Reason, "expected integer, but got string")
+	assert.Contains(t, violations[0].Message, "200 response body for '/pets'")
+
+	violations = ValidateXMLResponse(request, respond(`<entries/>`), doc)
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0].Reason, "The root element is 'entries', but the schema names it 'feed'")
+}
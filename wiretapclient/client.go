@@ -0,0 +1,510 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+// Package wiretapclient provides typed access to the wiretap control API, so test harnesses can drive a running
+// wiretap instance (read transactions, push specifications, change variables and delays) from Go.
+//
+// The control API is served by the ranch broker (STOMP over WebSockets) on the wiretap websocket port (9092 by
+// default), the same API used by the monitor UI.
+package wiretapclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/ranch/bridge"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/controls"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/har"
+	"github.com/pb33f/wiretap/report"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/specs"
+)
+
+const (
+	// DefaultHost is where wiretap serves the control API, unless configured otherwise.
+	DefaultHost = "localhost:9092"
+	// DefaultTimeout is how long the client waits for a response to a request.
+	DefaultTimeout = 10 * time.Second
+
+	fabricEndpoint = "/ranch"
+	requestPrefix  = "/pub/queue/"
+	responsePrefix = "/queue/"
+)
+
+// Config configures the connection to a running wiretap instance.
+type Config struct {
+	Host      string        // host and websocket port of wiretap, defaults to DefaultHost.
+	UseTLS    bool          // set if wiretap is running with a certificate.
+	TLSConfig *tls.Config   // optional, used when UseTLS is set.
+	Timeout   time.Duration // how long to wait for each response, defaults to DefaultTimeout.
+	Token     string        // monitor token, if the monitor is protected by one.
+	Username  string        // monitor username, if the monitor is protected by basic auth.
+	Password  string        // monitor password, if the monitor is protected by basic auth.
+}
+
+// Client is a typed client for the wiretap control API. A client is safe for concurrent use. Every channel is
+// subscribed to once, responses on it are handed to whichever request (or watch) has the same id.
+type Client struct {
+	conn          bridge.Connection
+	timeout       time.Duration
+	lock          sync.Mutex
+	subscriptions map[string]bridge.Subscription
+	waiting       map[uuid.UUID]*waiter
+}
+
+// waiter receives the responses to a request, until it's done.
+type waiter struct {
+	messages chan *model.Message
+	done     chan struct{}
+}
+
+// NewClient connects to a running wiretap instance.
+func NewClient(cfg *Config) (*Client, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	host := cfg.Host
+	if host == "" {
+		host = DefaultHost
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	conn, err := bridge.NewBrokerConnector().Connect(&bridge.BrokerConnectorConfig{
+		ServerAddr: host,
+		UseWS:      true,
+		HttpHeader: authHeader(cfg),
+		WebSocketConfig: &bridge.WebSocketConfig{
+			WSPath:    fabricEndpoint,
+			UseTLS:    cfg.UseTLS,
+			TLSConfig: cfg.TLSConfig,
+		},
+	}, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to wiretap at '%s': %w", host, err)
+	}
+	return newClient(conn, timeout), nil
+}
+
+// authHeader carries the credentials for a protected monitor, the token if there is one, otherwise basic auth.
+func authHeader(cfg *Config) http.Header {
+	header := http.Header{}
+	if cfg.Token != "" {
+		header.Set("Authorization", "Bearer "+cfg.Token)
+	} else if cfg.Username != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(cfg.Username + ":" + cfg.Password))
+		header.Set("Authorization", "Basic "+auth)
+	}
+	return header
+}
+
+func newClient(conn bridge.Connection, timeout time.Duration) *Client {
+	return &Client{
+		conn:          conn,
+		timeout:       timeout,
+		subscriptions: make(map[string]bridge.Subscription),
+		waiting:       make(map[uuid.UUID]*waiter),
+	}
+}
+
+// Close disconnects from wiretap.
+func (c *Client) Close() error {
+	c.lock.Lock()
+	for channel, sub := range c.subscriptions {
+		_ = sub.Unsubscribe()
+		delete(c.subscriptions, channel)
+	}
+	c.lock.Unlock()
+	return c.conn.Disconnect()
+}
+
+// Transactions returns every transaction wiretap has captured, along with any violations.
+func (c *Client) Transactions(ctx context.Context) ([]*daemon.HttpTransaction, error) {
+	var r report.ReportResponse
+	if err := c.request(ctx, report.ReportServiceChan, report.GenerateReportRequest, struct{}{}, &r); err != nil {
+		return nil, err
+	}
+	return r.Transactions, nil
+}
+
+// Violations returns only the transactions that failed request or response validation.
+func (c *Client) Violations(ctx context.Context) ([]*daemon.HttpTransaction, error) {
+	transactions, err := c.Transactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var violated []*daemon.HttpTransaction
+	for _, t := range transactions {
+		if len(t.RequestValidation) > 0 || len(t.ResponseValidation) > 0 {
+			violated = append(violated, t)
+		}
+	}
+	return violated, nil
+}
+
+// SearchTransactions returns a page of the captured transactions that match a search expression, such as
+// `method:POST path:/users/* status:>=500 body~"quota"`. A limit of zero returns the default page size.
+func (c *Client) SearchTransactions(ctx context.Context, search string, offset, limit int) (*daemon.TransactionPageResponse, error) {
+	var page daemon.TransactionPageResponse
+	payload := &daemon.TransactionSearch{Query: search, TransactionPage: daemon.TransactionPage{Offset: offset, Limit: limit}}
+	if err := c.request(ctx, daemon.WiretapServiceChan, daemon.SearchTransactionsRequest, payload, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Resend sends a captured request again, with any changes set in resend (the id of the transaction is required).
+// The request is captured as a new transaction, the response says which.
+func (c *Client) Resend(ctx context.Context, resend *daemon.ResendTransaction) (*daemon.ResendResponse, error) {
+	var r daemon.ResendResponse
+	if err := c.request(ctx, daemon.WiretapServiceChan, daemon.ResendTransactionRequest, resend, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Configuration returns the configuration wiretap is running with.
+func (c *Client) Configuration(ctx context.Context) (*shared.WiretapConfiguration, error) {
+	var cfg shared.WiretapConfiguration
+	if err := c.request(ctx, config.ConfigurationServiceChan, config.GetConfigurationRequest, struct{}{}, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Variables returns the variables wiretap is using.
+func (c *Client) Variables(ctx context.Context) (map[string]string, error) {
+	cfg, err := c.Configuration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Variables, nil
+}
+
+// SetVariables replaces the variables wiretap is using, returns the updated configuration.
+func (c *Client) SetVariables(ctx context.Context, variables map[string]string) (*shared.WiretapConfiguration, error) {
+	var r controls.ControlResponse
+	if err := c.request(ctx, controls.ControlServiceChan, controls.ChangeVariablesRequest,
+		&controls.ChangeGlobalVariablesRequest{Variables: variables}, &r); err != nil {
+		return nil, err
+	}
+	return r.Config, nil
+}
+
+// SetGlobalDelay sets the delay (in milliseconds) applied to every response, returns the updated configuration.
+func (c *Client) SetGlobalDelay(ctx context.Context, delay int) (*shared.WiretapConfiguration, error) {
+	var r controls.ControlResponse
+	if err := c.request(ctx, controls.ControlServiceChan, controls.ChangeDelayRequest,
+		&controls.ChangeGlobalDelayRequest{Delay: delay}, &r); err != nil {
+		return nil, err
+	}
+	return r.Config, nil
+}
+
+// SetPathDelays sets or changes the delays (in milliseconds) of paths, a delay of zero removes the delay of a path.
+// Replace removes the delays of every other path. Returns the updated configuration.
+func (c *Client) SetPathDelays(ctx context.Context, delays map[string]int, replace bool) (*shared.WiretapConfiguration, error) {
+	var r controls.ControlResponse
+	if err := c.request(ctx, controls.ControlServiceChan, controls.ChangePathDelaysRequest,
+		&controls.PathDelaysChange{PathDelays: delays, Replace: replace}, &r); err != nil {
+		return nil, err
+	}
+	return r.Config, nil
+}
+
+// ClearDelays removes the global delay and every path delay, returns the updated configuration.
+func (c *Client) ClearDelays(ctx context.Context) (*shared.WiretapConfiguration, error) {
+	var r controls.ControlResponse
+	if err := c.request(ctx, controls.ControlServiceChan, controls.ClearDelaysRequest, struct{}{}, &r); err != nil {
+		return nil, err
+	}
+	return r.Config, nil
+}
+
+// SetMockPaths switches paths to mock (true) or proxy (false) mode, whatever the mock mode of everything else is.
+// Paths in reset go back to the mock mode of everything else. Returns the updated configuration.
+func (c *Client) SetMockPaths(ctx context.Context, paths map[string]bool, reset ...string) (*shared.WiretapConfiguration, error) {
+	var r controls.ControlResponse
+	if err := c.request(ctx, controls.ControlServiceChan, controls.ChangeMockPathsRequest,
+		&controls.MockPathsChange{MockPaths: paths, Reset: reset}, &r); err != nil {
+		return nil, err
+	}
+	return r.Config, nil
+}
+
+// PauseCapture stops wiretap capturing transactions, traffic is still proxied and validated. Returns the updated
+// configuration.
+func (c *Client) PauseCapture(ctx context.Context) (*shared.WiretapConfiguration, error) {
+	var r controls.ControlResponse
+	if err := c.request(ctx, controls.ControlServiceChan, controls.PauseCaptureRequest, struct{}{}, &r); err != nil {
+		return nil, err
+	}
+	return r.Config, nil
+}
+
+// ResumeCapture starts wiretap capturing transactions again, returns the updated configuration.
+func (c *Client) ResumeCapture(ctx context.Context) (*shared.WiretapConfiguration, error) {
+	var r controls.ControlResponse
+	if err := c.request(ctx, controls.ControlServiceChan, controls.ResumeCaptureRequest, struct{}{}, &r); err != nil {
+		return nil, err
+	}
+	return r.Config, nil
+}
+
+// SetIntercept replaces the rules of requests held until they are approved, path globs optionally after a method
+// (e.g. 'POST /pets/*'). A timeout (in seconds) is only changed if it's not nil. Returns the updated configuration.
+func (c *Client) SetIntercept(ctx context.Context, rules []string, timeout *int) (*shared.WiretapConfiguration, error) {
+	var r controls.ControlResponse
+	if err := c.request(ctx, controls.ControlServiceChan, controls.ChangeInterceptRequest,
+		&controls.InterceptChange{Intercept: rules, Timeout: timeout}, &r); err != nil {
+		return nil, err
+	}
+	return r.Config, nil
+}
+
+// Intercepted returns the requests being held, the longest held first.
+func (c *Client) Intercepted(ctx context.Context) ([]*daemon.InterceptedRequest, error) {
+	var held []*daemon.InterceptedRequest
+	if err := c.request(ctx, daemon.WiretapServiceChan, daemon.GetInterceptedRequest, struct{}{}, &held); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+// ReleaseIntercepted sends a held request on, with any changes set in decision, or drops it (the id of the request
+// is required). Returns the requests still being held.
+func (c *Client) ReleaseIntercepted(ctx context.Context, decision *daemon.InterceptDecision) ([]*daemon.InterceptedRequest, error) {
+	var held []*daemon.InterceptedRequest
+	if err := c.request(ctx, daemon.WiretapServiceChan, daemon.ReleaseInterceptedRequest, decision, &held); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+// Spec returns the content of the specification wiretap is serving, nil if no specification is loaded.
+func (c *Client) Spec(ctx context.Context) ([]byte, error) {
+	var spec []byte
+	if err := c.request(ctx, specs.SpecServiceChan, specs.GetCurrentSpecRequest, struct{}{}, &spec); err != nil {
+		return nil, err
+	}
+	if string(spec) == "no-spec" {
+		return nil, nil
+	}
+	return spec, nil
+}
+
+// PushSpec replaces the specification wiretap is serving. If the specification cannot be compiled, wiretap keeps
+// serving the previous one and an error is returned.
+func (c *Client) PushSpec(ctx context.Context, spec []byte) error {
+	return c.request(ctx, daemon.WiretapServiceChan, daemon.PushSpecRequest,
+		&daemon.PushSpecification{Spec: string(spec)}, nil)
+}
+
+// ReloadSpec asks wiretap to load its specification again, from the file or URL it was started with.
+func (c *Client) ReloadSpec(ctx context.Context) error {
+	return c.request(ctx, daemon.WiretapServiceChan, daemon.ReloadSpecRequest, struct{}{}, nil)
+}
+
+// SpecStatus returns the health of the specification, including any compile errors from the last reload.
+func (c *Client) SpecStatus(ctx context.Context) (*shared.WiretapSpecStatus, error) {
+	var status shared.WiretapSpecStatus
+	if err := c.request(ctx, controls.ControlServiceChan, controls.SpecStatusRequest, struct{}{}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// ReplayHAR asks wiretap to validate the HAR file it was started with. Violations are reported as transactions.
+func (c *Client) ReplayHAR(ctx context.Context) error {
+	b, err := encodeRequest(nil, har.StartTheHARRequest, struct{}{})
+	if err != nil {
+		return err
+	}
+	return c.conn.SendJSONMessage("/pub/"+har.HARServiceChan, b)
+}
+
+// WatchTransactions calls handler with every transaction wiretap captures that matches the filter (everything, if
+// the filter is empty), as it happens. It blocks until the context is done, or wiretap rejects the filter.
+func (c *Client) WatchTransactions(ctx context.Context, filter *daemon.TransactionFilter,
+	handler func(transaction *daemon.HttpTransaction)) error {
+	if filter == nil {
+		filter = &daemon.TransactionFilter{}
+	}
+	id := uuid.New()
+	w, err := c.send(daemon.WiretapServiceChan, &id, daemon.SubscribeTransactionsRequest, filter)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		c.forget(&id, w)
+		_ = c.request(context.Background(), daemon.WiretapServiceChan, daemon.UnsubscribeTransactionsRequest, struct{}{}, nil)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-w.messages:
+			var transaction daemon.HttpTransaction
+			matched, e := decodeResponse(msg, &id, &transaction)
+			if e != nil {
+				return e
+			}
+			if matched {
+				handler(&transaction)
+			}
+		}
+	}
+}
+
+// request sends a command to a service, and waits for the response with the same id.
+func (c *Client) request(ctx context.Context, channel, command string, payload any, result any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	id := uuid.New()
+	w, err := c.send(channel, &id, command, payload)
+	if err != nil {
+		return err
+	}
+	defer c.forget(&id, w)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no response from wiretap for '%s' on '%s': %w", command, channel, ctx.Err())
+		case msg := <-w.messages:
+			matched, e := decodeResponse(msg, &id, result)
+			if matched {
+				return e
+			}
+		}
+	}
+}
+
+// send sends a command to a service, responses to it are handed to the waiter returned until it's forgotten.
+func (c *Client) send(channel string, id *uuid.UUID, command string, payload any) (*waiter, error) {
+	b, err := encodeRequest(id, command, payload)
+	if err != nil {
+		return nil, err
+	}
+	w := &waiter{messages: make(chan *model.Message, 16), done: make(chan struct{})}
+	c.lock.Lock()
+	if err = c.listen(channel); err != nil {
+		c.lock.Unlock()
+		return nil, err
+	}
+	c.waiting[*id] = w
+	c.lock.Unlock()
+
+	if err = c.conn.SendJSONMessage(requestPrefix+channel, b); err != nil {
+		c.forget(id, w)
+		return nil, err
+	}
+	return w, nil
+}
+
+// forget stops handing responses to a waiter, responses that arrive later are dropped.
+func (c *Client) forget(id *uuid.UUID, w *waiter) {
+	c.lock.Lock()
+	delete(c.waiting, *id)
+	c.lock.Unlock()
+	close(w.done)
+}
+
+// listen subscribes to the responses of a channel, the first time it's used. Responses are read as soon as they
+// arrive, so a late (or unexpected) response never holds up the connection. The lock must be held.
+func (c *Client) listen(channel string) error {
+	if _, ok := c.subscriptions[channel]; ok {
+		return nil
+	}
+	sub, err := c.conn.Subscribe(responsePrefix + channel)
+	if err != nil {
+		return err
+	}
+	c.subscriptions[channel] = sub
+	go func() {
+		for msg := range sub.GetMsgChannel() {
+			id := responseId(msg)
+			if id == nil {
+				continue
+			}
+			c.lock.Lock()
+			w := c.waiting[*id]
+			c.lock.Unlock()
+			if w == nil {
+				continue // nobody is waiting for it anymore.
+			}
+			select {
+			case w.messages <- msg:
+			case <-w.done:
+			}
+		}
+	}()
+	return nil
+}
+
+func encodeRequest(id *uuid.UUID, command string, payload any) ([]byte, error) {
+	return json.Marshal(&model.Request{Id: id, RequestCommand: command, Payload: payload})
+}
+
+// responseId returns the id of the request a message is a response to, nil if it isn't a response.
+func responseId(msg *model.Message) *uuid.UUID {
+	if msg == nil {
+		return nil
+	}
+	body, ok := msg.Payload.([]byte)
+	if !ok {
+		return nil
+	}
+	var resp struct {
+		Id *uuid.UUID `json:"id"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+	return resp.Id
+}
+
+// decodeResponse decodes a response into result, if the message is a response to the request with the given id.
+func decodeResponse(msg *model.Message, id *uuid.UUID, result any) (bool, error) {
+	if msg == nil {
+		return false, nil
+	}
+	body, ok := msg.Payload.([]byte)
+	if !ok {
+		return false, nil
+	}
+	var resp struct {
+		Id           *uuid.UUID      `json:"id"`
+		Payload      json.RawMessage `json:"payload"`
+		Error        bool            `json:"error"`
+		ErrorCode    int             `json:"errorCode"`
+		ErrorMessage string          `json:"errorMessage"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return false, nil
+	}
+	if resp.Id == nil || *resp.Id != *id {
+		return false, nil // not ours.
+	}
+	if resp.Error {
+		return true, fmt.Errorf("wiretap error %d: %s", resp.ErrorCode, resp.ErrorMessage)
+	}
+	if result == nil || len(resp.Payload) == 0 || string(resp.Payload) == "null" {
+		return true, nil
+	}
+	return true, json.Unmarshal(resp.Payload, result)
+}
@@ -0,0 +1,134 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+// SPDX-License-Identifier: AGPL
+
+package wiretapclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/google/uuid"
+	"github.com/pb33f/ranch/bridge"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeResponse(t *testing.T) {
+	id := uuid.New()
+	other := uuid.New()
+
+	message := func(r *model.Response) *model.Message {
+		b, _ := json.Marshal(r)
+		return &model.Message{Payload: b}
+	}
+
+	// responses to other requests are ignored.
+	var status shared.WiretapSpecStatus
+	matched, err := decodeResponse(message(&model.Response{Id: &other, Payload: &shared.WiretapSpecStatus{}}), &id, &status)
+	assert.False(t, matched)
+	assert.NoError(t, err)
+
+	matched, err = decodeResponse(message(&model.Response{Id: &id,
+		Payload: &shared.WiretapSpecStatus{Errors: []string{"broken"}}}), &id, &status)
+	assert.True(t, matched)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"broken"}, status.Errors)
+
+	matched, err = decodeResponse(message(&model.Response{Id: &id, Error: true, ErrorCode: 422,
+		ErrorMessage: "cannot compile"}), &id, &status)
+	assert.True(t, matched)
+	assert.EqualError(t, err, "wiretap error 422: cannot compile")
+}
+
+// fakeBroker answers every request with its command, twice, along with a response to a request nobody made.
+// Like the ranch bridge, it delivers responses on an unbuffered channel.
+type fakeBroker struct {
+	bridge.Connection
+	lock       sync.Mutex
+	subscribed map[string]int
+	subs       map[string]*fakeSubscription
+}
+
+type fakeSubscription struct {
+	bridge.Subscription
+	c chan *model.Message
+}
+
+func (s *fakeSubscription) GetMsgChannel() chan *model.Message { return s.c }
+func (s *fakeSubscription) Unsubscribe() error                 { return nil }
+
+func (b *fakeBroker) Subscribe(destination string) (bridge.Subscription, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscribed[destination]++
+	sub := &fakeSubscription{c: make(chan *model.Message)}
+	b.subs[destination] = sub
+	return sub, nil
+}
+
+func (b *fakeBroker) Disconnect() error { return nil }
+
+func (b *fakeBroker) SendJSONMessage(destination string, payload []byte, _ ...func(*frame.Frame) error) error {
+	var request model.Request
+	_ = json.Unmarshal(payload, &request)
+	b.lock.Lock()
+	sub := b.subs[responsePrefix+destination[len(requestPrefix):]]
+	b.lock.Unlock()
+	stray := uuid.New()
+	go func() {
+		for _, id := range []*uuid.UUID{&stray, request.Id, request.Id} {
+			body, _ := json.Marshal(&model.Response{Id: id, Payload: request.RequestCommand})
+			sub.c <- &model.Message{Payload: body}
+		}
+	}()
+	return nil
+}
+
+func TestClient_ConcurrentRequests(t *testing.T) {
+	broker := &fakeBroker{subscribed: make(map[string]int), subs: make(map[string]*fakeSubscription)}
+	c := newClient(broker, 2*time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			channel := daemon.WiretapServiceChan
+			if i%2 == 0 {
+				channel = "controls"
+			}
+			var command string
+			err := c.request(context.Background(), channel, fmt.Sprintf("command-%d", i), struct{}{}, &command)
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("command-%d", i), command)
+		}(i)
+	}
+	wg.Wait()
+
+	// every channel is subscribed to once, late and stray responses are dropped without holding anything up.
+	assert.Equal(t, map[string]int{"/queue/" + daemon.WiretapServiceChan: 1, "/queue/controls": 1}, broker.subscribed)
+	var command string
+	assert.NoError(t, c.request(context.Background(), "controls", "again", struct{}{}, &command))
+	assert.Equal(t, "again", command)
+	assert.Empty(t, c.waiting)
+	assert.NoError(t, c.Close())
+}
+
+func TestAuthHeader(t *testing.T) {
+	assert.Empty(t, authHeader(&Config{}))
+	assert.Equal(t, "Bearer secret", authHeader(&Config{Token: "secret"}).Get("Authorization"))
+	assert.Equal(t, "Basic cGlwOnNxdWVhaw==",
+		authHeader(&Config{Username: "pip", Password: "squeak"}).Get("Authorization"))
+
+	// the token wins when both are configured, the monitor accepts either.
+	assert.Equal(t, "Bearer secret",
+		authHeader(&Config{Token: "secret", Username: "pip", Password: "squeak"}).Get("Authorization"))
+}